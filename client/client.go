@@ -0,0 +1,340 @@
+// Package client resolves prefixed ids with a running node-resolver, over
+// its /api/v1/resolve api, for services that would otherwise hand-roll the
+// request:
+//
+//	c := client.New("http://node-resolver:7904", client.WithHTTPClient(authenticated))
+//
+//	typeName, err := c.ResolveNode(ctx, "loadbal-123")
+//
+// Requests failing with transient errors are retried, the trace context of
+// ctx is propagated to node-resolver, and resolved ids are cached in memory.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+const (
+	// DefaultTimeout bounds each request made to node-resolver when no
+	// http client is given
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxRetries is the number of times failed requests are retried
+	DefaultMaxRetries = 2
+	// DefaultBackoff is how long the first retry waits, doubling after
+	// each retry
+	DefaultBackoff = 100 * time.Millisecond
+	// DefaultCacheSize is the number of resolved ids cached
+	DefaultCacheSize = 10000
+	// DefaultCacheTTL is how long resolved ids are cached for
+	DefaultCacheTTL = 5 * time.Minute
+)
+
+// resolvePath is the path of the resolve api
+const resolvePath = "/api/v1/resolve"
+
+// attrIDCount is the number of ids resolved by a request span
+const attrIDCount = attribute.Key("node_resolver.client.id_count")
+
+// TypeName is the name of the graphql type an id resolves to
+type TypeName string
+
+// Service is the service owning the nodes of a type, declared by its
+// @service directive
+type Service = graphapi.Service
+
+// Code identifies why an id or request failed, one of the error codes of
+// node-resolver
+type Code = errcode.Code
+
+// The codes ids fail to resolve with
+const (
+	CodeInvalidID     = errcode.InvalidID
+	CodeUnknownPrefix = errcode.UnknownPrefix
+	CodeUnauthorized  = errcode.Unauthorized
+	CodeNotFound      = errcode.NotFound
+	CodeDeleted       = errcode.Deleted
+	CodeDenied        = errcode.Denied
+)
+
+// Error is returned for ids and requests node-resolver failed
+type Error struct {
+	// ID is the id that failed, empty when the whole request failed
+	ID      string
+	Code    Code
+	Message string
+	// StatusCode is the http status of failed requests
+	StatusCode int
+}
+
+func (e *Error) Error() string {
+	if e.ID != "" {
+		return fmt.Sprintf("resolving %s: %s: %s", e.ID, e.Code, e.Message)
+	}
+
+	return fmt.Sprintf("resolving ids: %s: %s", e.Code, e.Message)
+}
+
+// CodeOf returns the code of err, or an empty code when it isn't an Error
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return ""
+}
+
+// Result is the outcome of resolving a single id
+type Result struct {
+	ID         string
+	TypeName   TypeName
+	Prefix     string
+	Interfaces []string
+	// Service is the service owning the node, when its type declares one
+	Service *Service
+	// Err is set for ids that didn't resolve
+	Err error
+}
+
+// Option configures optional behavior of a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the client making the requests, such as one adding
+// the credentials node-resolver authenticates
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithRetries retries requests failing with network errors or a transient
+// status up to maxRetries times, waiting backoff before the first retry and
+// twice as long before each of the next. Zero disables retries.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// WithCache caches up to size resolved ids for ttl. A size of zero disables
+// the cache. Only ids that resolved are cached.
+func WithCache(size int, ttl time.Duration) Option {
+	return func(c *Client) {
+		if size <= 0 {
+			c.cache = nil
+
+			return
+		}
+
+		c.cache = cache.New(size, ttl)
+	}
+}
+
+// Client resolves ids with a running node-resolver. It's safe for
+// concurrent use.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	cache      *cache.Cache
+	tracer     trace.Tracer
+}
+
+// New returns a Client of the node-resolver at baseURL, such as
+// http://node-resolver:7904
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		url:        strings.TrimSuffix(baseURL, "/") + resolvePath,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+		cache:      cache.New(DefaultCacheSize, DefaultCacheTTL),
+		tracer:     otel.GetTracerProvider().Tracer("go.infratographer.com/node-resolver/client"),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// ResolveNode returns the name of the type id resolves to. Ids that don't
+// resolve return an *Error with their code.
+func (c *Client) ResolveNode(ctx context.Context, id string) (TypeName, error) {
+	results, err := c.ResolveNodes(ctx, []string{id})
+	if err != nil {
+		return "", err
+	}
+
+	return results[0].TypeName, results[0].Err
+}
+
+// ResolveNodes resolves ids, returning a result for each of them in the same
+// order. Ids that don't resolve have the *Error of their code in their
+// result; an error is returned when the request itself failed. Ids that
+// aren't cached are resolved in requests of up to graphapi.MaxResolveIDs.
+func (c *Client) ResolveNodes(ctx context.Context, ids []string) ([]Result, error) {
+	results := make([]Result, len(ids))
+	pending := map[string][]int{}
+	missing := []string{}
+
+	for i, id := range ids {
+		if cached, ok := c.cache.Get(id); ok {
+			results[i] = cached.(Result)
+
+			continue
+		}
+
+		if _, ok := pending[id]; !ok {
+			missing = append(missing, id)
+		}
+
+		pending[id] = append(pending[id], i)
+	}
+
+	for len(missing) > 0 {
+		n := len(missing)
+		if n > graphapi.MaxResolveIDs {
+			n = graphapi.MaxResolveIDs
+		}
+
+		resolved, err := c.resolve(ctx, missing[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range resolved {
+			if result.Err == nil {
+				c.cache.Set(result.ID, result.ID, result)
+			}
+
+			for _, i := range pending[result.ID] {
+				results[i] = result
+			}
+		}
+
+		missing = missing[n:]
+	}
+
+	return results, nil
+}
+
+// resolve resolves ids with a single request, retrying transient failures
+func (c *Client) resolve(ctx context.Context, ids []string) (results []Result, err error) {
+	ctx, span := c.tracer.Start(ctx, "resolve ids", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrIDCount.Int(len(ids))))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}()
+
+	body, err := json.Marshal(graphapi.ResolveRequest{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+
+		results, retryable, err = c.post(ctx, body)
+		if err == nil || !retryable || attempt >= c.maxRetries {
+			return results, err
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+// post sends a resolve request, reporting whether its failure may succeed
+// when sent again
+func (c *Client) post(ctx context.Context, body []byte) ([]Result, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	var rr graphapi.ResolveResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&rr); err != nil || resp.StatusCode != http.StatusOK {
+		e := &Error{Code: errcode.Internal, Message: resp.Status, StatusCode: resp.StatusCode}
+
+		if rr.Error != nil {
+			e.Code, e.Message = rr.Error.Code, rr.Error.Message
+		}
+
+		return nil, retryableStatus(resp.StatusCode), e
+	}
+
+	results := make([]Result, len(rr.Results))
+
+	for i, r := range rr.Results {
+		results[i] = Result{
+			ID:         r.ID,
+			TypeName:   TypeName(r.Type),
+			Prefix:     r.Prefix,
+			Interfaces: r.Interfaces,
+			Service:    r.Service,
+		}
+
+		if r.Error != nil {
+			results[i].Err = &Error{ID: r.ID, Code: r.Error.Code, Message: r.Error.Message}
+		}
+	}
+
+	return results, false, nil
+}
+
+// retryableStatus returns true for statuses of requests that may succeed
+// when sent again, such as those shed by an overloaded replica
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,155 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/client"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+const testSchema = `directive @prefixedID(prefix: String!) on OBJECT
+
+type User implements Node & Actor @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+interface Actor @key(fields: "id") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}`
+
+// testServer serves the resolve api of testSchema, counting the requests
+// it's sent. The first failures requests fail with a 503.
+type testServer struct {
+	*httptest.Server
+	requests atomic.Int32
+	failures int32
+	header   http.Header
+}
+
+func newTestServer(t *testing.T, failures int32) *testServer {
+	t.Helper()
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), testSchema)
+	require.NoError(t, err)
+
+	e := echo.New()
+	graphapi.NewHandler(r).Routes(e.Group(""))
+
+	s := &testServer{failures: failures}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.header = req.Header.Clone()
+
+		if s.requests.Add(1) <= s.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		e.ServeHTTP(w, req)
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func TestResolveNode(t *testing.T) {
+	s := newTestServer(t, 0)
+	c := client.New(s.URL)
+
+	typeName, err := c.ResolveNode(context.Background(), "testusr-abc")
+	require.NoError(t, err)
+	assert.Equal(t, client.TypeName("User"), typeName)
+
+	_, err = c.ResolveNode(context.Background(), "unknown-abc")
+	require.Error(t, err)
+	assert.Equal(t, client.CodeUnknownPrefix, client.CodeOf(err))
+
+	_, err = c.ResolveNode(context.Background(), "notanid")
+	assert.Equal(t, client.CodeInvalidID, client.CodeOf(err))
+
+	// resolved ids are cached, failed ids aren't
+	_, err = c.ResolveNode(context.Background(), "testusr-abc")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), s.requests.Load())
+}
+
+func TestResolveNodes(t *testing.T) {
+	s := newTestServer(t, 0)
+	c := client.New(s.URL, client.WithCache(0, 0))
+
+	ids := []string{"testusr-abc", "unknown-abc", "testusr-abc"}
+	for i := 0; i < graphapi.MaxResolveIDs; i++ {
+		ids = append(ids, fmt.Sprintf("testusr-%d", i))
+	}
+
+	results, err := c.ResolveNodes(context.Background(), ids)
+	require.NoError(t, err)
+	require.Len(t, results, len(ids))
+
+	assert.Equal(t, client.Result{ID: "testusr-abc", TypeName: "User", Prefix: "testusr", Interfaces: []string{"Actor", "Node"}}, results[0])
+	assert.Equal(t, results[0], results[2])
+	assert.Equal(t, client.CodeUnknownPrefix, client.CodeOf(results[1].Err))
+	assert.Equal(t, "testusr-99", results[len(results)-1].ID)
+
+	// ids are sent at most once, in requests of up to MaxResolveIDs
+	assert.Equal(t, int32(2), s.requests.Load())
+}
+
+func TestRetries(t *testing.T) {
+	s := newTestServer(t, 2)
+	c := client.New(s.URL, client.WithRetries(2, time.Millisecond))
+
+	typeName, err := c.ResolveNode(context.Background(), "testusr-abc")
+	require.NoError(t, err)
+	assert.Equal(t, client.TypeName("User"), typeName)
+	assert.Equal(t, int32(3), s.requests.Load())
+
+	s = newTestServer(t, 2)
+	c = client.New(s.URL, client.WithRetries(1, time.Millisecond))
+
+	_, err = c.ResolveNode(context.Background(), "testusr-abc")
+
+	var e *client.Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, http.StatusServiceUnavailable, e.StatusCode)
+	assert.Equal(t, int32(2), s.requests.Load())
+}
+
+func TestTracePropagation(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	s := newTestServer(t, 0)
+	c := client.New(s.URL)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	_, err := c.ResolveNode(trace.ContextWithSpanContext(context.Background(), sc), "testusr-abc")
+	require.NoError(t, err)
+
+	assert.Contains(t, s.header.Get("traceparent"), sc.TraceID().String())
+}
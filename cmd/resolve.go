@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// errUnresolved is returned by resolve when some ids didn't resolve, their
+// errors have already been printed
+var errUnresolved = errors.New("unresolved ids")
+
+var (
+	resolveSchemaFiles      []string
+	resolveWildcardPrefixes bool
+	resolveOutput           string
+)
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <id>...",
+	Short: "Resolve ids against a graphql schema locally",
+	Long: `resolve reads the schema files the way serve does, or uses the default schema,
+and prints the type every id resolves to along with the interfaces it
+implements and the service owning it, without a running server. Ids that
+don't resolve are printed with their error code and the command fails.`,
+	Args:          cobra.MinimumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return resolve(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+
+	resolveCmd.Flags().StringSliceVar(&resolveSchemaFiles, "schema", nil, "paths to graphql schema files, or directories of them, merged into one schema; may be repeated (default is the built in schema)")
+	resolveCmd.Flags().BoolVar(&resolveWildcardPrefixes, "wildcard-prefixes", false, "match @prefixedID prefixes ending in * against every prefix starting with them")
+	resolveCmd.Flags().StringVarP(&resolveOutput, "output", "o", "text", "output format, text or json")
+}
+
+func resolve(cmd *cobra.Command, ids []string) error {
+	if resolveOutput != "text" && resolveOutput != "json" {
+		return fmt.Errorf("unknown output format %q, expected text or json", resolveOutput)
+	}
+
+	schema := defaultSchema
+
+	if len(resolveSchemaFiles) != 0 {
+		var err error
+
+		schema, err = graphapi.LoadSchemaFiles(resolveSchemaFiles)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts := []graphapi.Option{}
+	if resolveWildcardPrefixes {
+		opts = append(opts, graphapi.WithWildcardPrefixes())
+	}
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), schema, opts...)
+	if err != nil {
+		return err
+	}
+
+	results, err := r.ResolveIDs(context.Background(), ids)
+	if err != nil {
+		return err
+	}
+
+	if resolveOutput == "json" {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else if err := printResolveResults(cmd, results); err != nil {
+		return err
+	}
+
+	unresolved := 0
+
+	for _, result := range results {
+		if !result.Resolved {
+			unresolved++
+		}
+	}
+
+	if unresolved != 0 {
+		return fmt.Errorf("%w: %d of %d ids didn't resolve", errUnresolved, unresolved, len(results))
+	}
+
+	return nil
+}
+
+// printResolveResults prints results as a table, with the errors of the ids
+// that didn't resolve in place of their interfaces
+func printResolveResults(cmd *cobra.Command, results []graphapi.ResolveResult) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0) //nolint:gomnd
+
+	fmt.Fprintln(w, "ID\tTYPE\tINTERFACES\tSERVICE")
+
+	for _, result := range results {
+		typ := result.Type
+		if typ == "" {
+			typ = "-"
+		}
+
+		if result.Error != nil {
+			fmt.Fprintf(w, "%s\t%s\t%s: %s\t-\n", result.ID, typ, result.Error.Code, result.Error.Message)
+			continue
+		}
+
+		service := "-"
+		if result.Service != nil {
+			service = result.Service.Name
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.ID, typ, strings.Join(result.Interfaces, ","), service)
+	}
+
+	return w.Flush()
+}
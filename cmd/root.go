@@ -17,6 +17,7 @@ import (
 	"go.uber.org/zap"
 
 	"go.infratographer.com/node-resolver/internal/config"
+	"go.infratographer.com/node-resolver/internal/tracing"
 )
 
 const appName = "node-resolver"
@@ -53,6 +54,7 @@ func init() {
 	// Register version command
 	versionx.RegisterCobraCommand(rootCmd, func() { versionx.PrintVersion(logger) })
 	otelx.MustViperFlags(viper.GetViper(), rootCmd.Flags())
+	tracing.MustViperFlags(viper.GetViper(), rootCmd.Flags())
 	crdbx.MustViperFlags(viper.GetViper(), rootCmd.Flags())
 }
 
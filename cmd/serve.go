@@ -2,23 +2,60 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
+	"github.com/labstack/echo/v4"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.infratographer.com/x/crdbx"
 	"go.infratographer.com/x/echox"
-	"go.infratographer.com/x/otelx"
 	"go.infratographer.com/x/versionx"
 	"go.infratographer.com/x/viperx"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/announce"
+	"go.infratographer.com/node-resolver/internal/audit"
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/breaker"
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/chaos"
 	"go.infratographer.com/node-resolver/internal/config"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/featureflags"
 	"go.infratographer.com/node-resolver/internal/graphapi"
+	"go.infratographer.com/node-resolver/internal/grpcapi"
+	"go.infratographer.com/node-resolver/internal/hedge"
+	"go.infratographer.com/node-resolver/internal/metrics"
+	"go.infratographer.com/node-resolver/internal/oidc"
+	"go.infratographer.com/node-resolver/internal/policy"
+	"go.infratographer.com/node-resolver/internal/ratelimit"
+	"go.infratographer.com/node-resolver/internal/registry"
+	"go.infratographer.com/node-resolver/internal/schemaurl"
+	"go.infratographer.com/node-resolver/internal/schemawatch"
+	"go.infratographer.com/node-resolver/internal/shed"
+	"go.infratographer.com/node-resolver/internal/spiffex"
+	"go.infratographer.com/node-resolver/internal/supergraph"
+	"go.infratographer.com/node-resolver/internal/tenant"
+	"go.infratographer.com/node-resolver/internal/tracing"
+	"go.infratographer.com/node-resolver/internal/vault"
 )
 
 var (
 	defaultListenAddr = ":7904"
-	schemaFile        = ""
+	schemaFiles       []string
+	// chaosEnabled is only set by its flag, so faults can't be injected by a
+	// config file or environment variable leaking into production
+	chaosEnabled = false
 )
 
 var serveCmd = &cobra.Command{
@@ -34,21 +71,191 @@ func init() {
 
 	echox.MustViperFlags(viper.GetViper(), serveCmd.Flags(), defaultListenAddr)
 
-	serveCmd.Flags().StringVar(&schemaFile, "schema", "", "path to graphql schema file")
+	serveCmd.Flags().StringSliceVar(&schemaFiles, "schema", nil, "paths to graphql schema files, or directories of them, merged into one schema; may be repeated")
 	viperx.MustBindFlag(viper.GetViper(), "schema", serveCmd.Flags().Lookup("schema"))
+
+	serveCmd.Flags().Int("entities-concurrency", 8, "number of chunks of large _entities batches authorized concurrently")
+	viperx.MustBindFlag(viper.GetViper(), "entities.concurrency", serveCmd.Flags().Lookup("entities-concurrency"))
+
+	serveCmd.Flags().Int("entities-max-concurrency", 0, "most chunks of large _entities batches authorized concurrently as the workers adapt to load, 0 to keep --entities-concurrency fixed")
+	viperx.MustBindFlag(viper.GetViper(), "entities.max-concurrency", serveCmd.Flags().Lookup("entities-max-concurrency"))
+
+	serveCmd.Flags().Int("query-cache-size", 1000, "number of parsed and validated queries to cache, 0 to disable")
+	viperx.MustBindFlag(viper.GetViper(), "query-cache.size", serveCmd.Flags().Lookup("query-cache-size"))
+
+	serveCmd.Flags().Int("persisted-queries-size", 1000, "number of automatic persisted queries to keep, 0 to disable persisted queries")
+	viperx.MustBindFlag(viper.GetViper(), "persisted-queries.size", serveCmd.Flags().Lookup("persisted-queries-size"))
+
+	serveCmd.Flags().Int("max-id-length", graphapi.DefaultMaxIDLength, "maximum length of ids in bytes, longer ids are rejected before they're parsed or logged; 0 disables the limit")
+	viperx.MustBindFlag(viper.GetViper(), "ids.max-length", serveCmd.Flags().Lookup("max-id-length"))
+
+	serveCmd.Flags().Duration("request-timeout", 0, "how long the lookups of a graphql request may take in total before the remaining lookups fail, 0 for no limit")
+	viperx.MustBindFlag(viper.GetViper(), "lookups.request-timeout", serveCmd.Flags().Lookup("request-timeout"))
+
+	serveCmd.Flags().Duration("lookup-timeout", 0, "maximum duration of each lookup, such as an authorization check, 0 disables lookup timeouts")
+	viperx.MustBindFlag(viper.GetViper(), "lookups.timeout", serveCmd.Flags().Lookup("lookup-timeout"))
+
+	serveCmd.Flags().Duration("lookup-timeout-floor", graphapi.DefaultLookupTimeoutFloor, "lookups aren't started with less than this left before the request deadline")
+	viperx.MustBindFlag(viper.GetViper(), "lookups.timeout-floor", serveCmd.Flags().Lookup("lookup-timeout-floor"))
+
+	serveCmd.Flags().Bool("wildcard-prefixes", false, "match @prefixedID prefixes ending in * against every prefix starting with them")
+	viperx.MustBindFlag(viper.GetViper(), "wildcard-prefixes", serveCmd.Flags().Lookup("wildcard-prefixes"))
+
+	serveCmd.Flags().String("unknown-prefix-type", "", "name of a type implementing Node, such as UnknownNode, that well-formed ids with an unknown prefix resolve to instead of failing; empty disables it")
+	viperx.MustBindFlag(viper.GetViper(), "unknown-prefix-type", serveCmd.Flags().Lookup("unknown-prefix-type"))
+
+	serveCmd.Flags().Bool("report-instance", false, "report the instance id and schema checksum in response headers and graphql extensions")
+	viperx.MustBindFlag(viper.GetViper(), "instance.report", serveCmd.Flags().Lookup("report-instance"))
+
+	serveCmd.Flags().String("instance-id", "", "instance id to report, defaults to the hostname")
+	viperx.MustBindFlag(viper.GetViper(), "instance.id", serveCmd.Flags().Lookup("instance-id"))
+
+	serveCmd.Flags().Bool("canonical-responses", false, "write responses in a canonical form that can be compared byte for byte")
+	viperx.MustBindFlag(viper.GetViper(), "canonical-responses", serveCmd.Flags().Lookup("canonical-responses"))
+
+	serveCmd.Flags().Bool("strict-requests", false, "reject graphql requests with unknown or duplicate fields instead of ignoring them")
+	viperx.MustBindFlag(viper.GetViper(), "strict-requests", serveCmd.Flags().Lookup("strict-requests"))
+
+	serveCmd.Flags().Bool("prefixed-id-scalar", false, "type the id arguments of the node queries as the PrefixedID scalar rather than ID")
+	viperx.MustBindFlag(viper.GetViper(), "prefixed-id-scalar", serveCmd.Flags().Lookup("prefixed-id-scalar"))
+
+	serveCmd.Flags().Bool("strict-prefixes", false, "refuse schemas in which several types declare the same @prefixedID prefix instead of using the last one")
+	viperx.MustBindFlag(viper.GetViper(), "strict-prefixes", serveCmd.Flags().Lookup("strict-prefixes"))
+
+	serveCmd.Flags().Bool("schema-strict", false, "refuse schemas with types implementing interfaces but missing a @prefixedID prefix or declaring an invalid one instead of only logging them")
+	viperx.MustBindFlag(viper.GetViper(), "schema-strict", serveCmd.Flags().Lookup("schema-strict"))
+
+	serveCmd.Flags().Bool("source-lookups", false, "serve the scalar fields of types with a @source directive by querying their subgraph")
+	viperx.MustBindFlag(viper.GetViper(), "source-lookups.enabled", serveCmd.Flags().Lookup("source-lookups"))
+
+	serveCmd.Flags().Duration("source-lookups-timeout", graphapi.DefaultSourceTimeout, "timeout of the requests made to the subgraphs of @source types")
+	viperx.MustBindFlag(viper.GetViper(), "source-lookups.timeout", serveCmd.Flags().Lookup("source-lookups-timeout"))
+
+	serveCmd.Flags().Int("max-query-depth", 0, "maximum depth of the fields of graphql operations, 0 disables the limit")
+	viperx.MustBindFlag(viper.GetViper(), "query-limits.max-depth", serveCmd.Flags().Lookup("max-query-depth"))
+
+	serveCmd.Flags().Int("max-query-aliases", 0, "maximum number of aliased fields in graphql operations, 0 disables the limit")
+	viperx.MustBindFlag(viper.GetViper(), "query-limits.max-aliases", serveCmd.Flags().Lookup("max-query-aliases"))
+
+	serveCmd.Flags().Int("max-query-complexity", 0, "maximum number of fields selected by graphql operations, counting fragments every time they're spread, 0 disables the limit")
+	viperx.MustBindFlag(viper.GetViper(), "query-limits.max-complexity", serveCmd.Flags().Lookup("max-query-complexity"))
+
+	serveCmd.Flags().Bool("disable-introspection", false, "reject graphql requests selecting __schema or __type, except from the introspection allowed cidrs")
+	viperx.MustBindFlag(viper.GetViper(), "introspection.disabled", serveCmd.Flags().Lookup("disable-introspection"))
+
+	serveCmd.Flags().StringSlice("introspection-allowed-cidrs", []string{}, "networks allowed to introspect the schema when introspection is disabled, such as 10.0.0.0/8")
+	viperx.MustBindFlag(viper.GetViper(), "introspection.allowed-cidrs", serveCmd.Flags().Lookup("introspection-allowed-cidrs"))
+
+	serveCmd.Flags().String("operation-selection", string(graphapi.OperationSelectionError), "operation to execute when a document has several and the request doesn't name one: error or first")
+	viperx.MustBindFlag(viper.GetViper(), "operation-selection", serveCmd.Flags().Lookup("operation-selection"))
+
+	serveCmd.Flags().String("request-logging", string(graphapi.RequestLoggingSummary), "how much of each graphql request to log: none, summary or full")
+	viperx.MustBindFlag(viper.GetViper(), "request-logging", serveCmd.Flags().Lookup("request-logging"))
+
+	serveCmd.Flags().String("graph-header", graphapi.DefaultGraphHeader, "request header selecting the graph to serve, when graphs.schemas is set")
+	viperx.MustBindFlag(viper.GetViper(), "graphs.header", serveCmd.Flags().Lookup("graph-header"))
+
+	serveCmd.Flags().String("graph-claim", "", "jwt claim selecting the graph to serve instead of the header, when graphs.schemas is set")
+	viperx.MustBindFlag(viper.GetViper(), "graphs.claim", serveCmd.Flags().Lookup("graph-claim"))
+
+	serveCmd.Flags().String("graph-default", "default", "name of the graph served by the main schema, used by requests that don't select a graph")
+	viperx.MustBindFlag(viper.GetViper(), "graphs.default", serveCmd.Flags().Lookup("graph-default"))
+
+	serveCmd.Flags().String("shadow-schema", "", "path to a candidate graphql schema that requests are also resolved against in the background, logging and counting differences")
+	viperx.MustBindFlag(viper.GetViper(), "shadow.schema", serveCmd.Flags().Lookup("shadow-schema"))
+
+	serveCmd.Flags().Float64("shadow-sample-rate", 1, "fraction of requests resolved against the shadow schema")
+	viperx.MustBindFlag(viper.GetViper(), "shadow.sample-rate", serveCmd.Flags().Lookup("shadow-sample-rate"))
+
+	serveCmd.Flags().Int("shadow-concurrency", 4, "most requests resolved against the shadow schema at once, further requests aren't compared")
+	viperx.MustBindFlag(viper.GetViper(), "shadow.concurrency", serveCmd.Flags().Lookup("shadow-concurrency"))
+
+	supergraph.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	oidc.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	ratelimit.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	authz.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	backend.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	policy.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	featureflags.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	errcode.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	tenant.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	cache.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	metrics.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	spiffex.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	vault.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	audit.MustViperFlags(viper.GetViper(), serveCmd.Flags(), appName)
+	admin.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	registry.MustViperFlags(viper.GetViper(), serveCmd.Flags(), appName)
+	shed.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	breaker.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	hedge.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	chaos.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	schemawatch.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	schemaurl.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	announce.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+	grpcapi.MustViperFlags(viper.GetViper(), serveCmd.Flags())
+
+	serveCmd.Flags().BoolVar(&chaosEnabled, "chaos-enabled", false, "inject the configured chaos faults into requests, for testing gateways; never use in production")
 }
 
 func serve(ctx context.Context) {
-	err := otelx.InitTracer(config.AppConfig.Tracing, appName, logger)
+	var (
+		tlsConfig *tls.Config
+		transport http.RoundTripper
+	)
+
+	if config.AppConfig.Vault.Enabled {
+		tlsConfig = setupVault(ctx)
+	}
+
+	err := tracing.InitTracer(config.AppConfig.Tracing, appName, logger)
 	if err != nil {
 		logger.Fatalw("failed to initialize tracer", "error", err)
 	}
 
+	if config.AppConfig.SPIFFE.Enabled {
+		if tlsConfig != nil {
+			logger.Fatal("tls certificates can't be provided by both vault and spiffe")
+		}
+
+		source, err := spiffex.NewSource(ctx, config.AppConfig.SPIFFE, logger.Named("spiffe"))
+		if err != nil {
+			logger.Fatalw("failed to obtain workload identity", "error", err)
+		}
+
+		defer source.Close() //nolint:errcheck // shutting down, nothing to do with the error
+
+		tlsConfig = source.ServerTLSConfig()
+		transport = source.Transport()
+	}
+
+	config.AppConfig.Supergraph.Transport = transport
+	config.AppConfig.Authz.OpenFGA.Transport = transport
+	config.AppConfig.Authz.PermissionsAPI.Transport = transport
+	config.AppConfig.Audit.CloudEvents.Transport = transport
+	config.AppConfig.Policy.Transport = transport
+	config.AppConfig.FeatureFlags.Transport = transport
+	config.AppConfig.Tenant.Transport = transport
+	config.AppConfig.Backend.KeyLookup.Transport = transport
+
+	// the registry and schema url are usually outside the mesh, where
+	// servers don't accept SVIDs, so they only present one when asked to
+	if config.AppConfig.Registry.SPIFFE {
+		config.AppConfig.Registry.Transport = transport
+	}
+
+	if config.AppConfig.SchemaURL.SPIFFE {
+		config.AppConfig.SchemaURL.Transport = transport
+	}
+
+	adminHandler := admin.NewHandler(config.AppConfig.Admin, logger.Named("admin"))
+
 	srv, err := echox.NewServer(
 		logger.Desugar(),
 		echox.Config{
 			Listen:              viper.GetString("server.listen"),
 			ShutdownGracePeriod: viper.GetDuration("server.shutdown-grace-period"),
+			Middleware:          []echo.MiddlewareFunc{adminHandler.Middleware()},
 		},
 		versionx.BuildDetails(),
 	)
@@ -57,25 +264,588 @@ func serve(ctx context.Context) {
 	}
 
 	schema := defaultSchema
-	if schemaFile == "" {
+	source := "default schema"
+
+	// load reads the schema again from its source for POST /admin/reload
+	var load admin.SchemaLoader
+
+	switch {
+	case config.AppConfig.Supergraph.Enabled():
+		source = "supergraph " + redactURL(config.AppConfig.Supergraph.URL)
+
+		client := supergraph.NewClient(config.AppConfig.Supergraph, logger.Named("supergraph"))
+		load = client.Schema
+
+		schema, err = load(ctx)
+		if err != nil {
+			logger.Fatalw("failed to build graphql schema from supergraph", "error", err)
+		}
+	case config.AppConfig.SchemaURL.Enabled():
+		if len(schemaFiles) != 0 {
+			logger.Fatal("the schema can't be read from both a file and a url")
+		}
+
+		source = "url " + redactURL(config.AppConfig.SchemaURL.URL)
+		load = func(ctx context.Context) (string, error) { return schemaurl.Fetch(ctx, config.AppConfig.SchemaURL) }
+
+		schema, err = load(ctx)
+		if err != nil {
+			logger.Fatalw("failed to fetch graphql schema", "url", config.AppConfig.SchemaURL.URL, "error", err)
+		}
+	case len(schemaFiles) == 0:
 		logger.Warn("no schema file provided, starting with default schema")
-	} else {
-		schemaBytes, err := os.ReadFile(schemaFile)
+	default:
+		source = "files " + strings.Join(schemaFiles, ", ")
+		load = func(context.Context) (string, error) { return graphapi.LoadSchemaFiles(schemaFiles) }
+
+		schema, err = load(ctx)
+		if err != nil {
+			logger.Fatalw("failed to read graphql schema files", "error", err)
+		}
+	}
+
+	metricsSink, err := metrics.New(config.AppConfig.Metrics, logger.Named("metrics"))
+	if err != nil {
+		logger.Fatalw("failed to create metrics sinks", "error", err)
+	}
+
+	authorizer, err := authz.NewAuthorizer(config.AppConfig.Authz, logger.Named("authz"))
+	if err != nil {
+		logger.Fatalw("failed to create authorizer", "error", err)
+	}
+
+	// the hedging budget is shared by every backend
+	hedgeBudget := hedge.NewBudget(config.AppConfig.Hedge.Budget)
+
+	// ids the provider doesn't check aren't counted as lookups by the breaker
+	authorizer = authz.ForPrefixes(wrapBackend(authorizer, string(config.AppConfig.Authz.Provider), hedgeBudget, metricsSink), config.AppConfig.Authz.Prefixes)
+
+	opts := []graphapi.Option{}
+
+	// the jwt is validated before any middleware reading its claims
+	if config.AppConfig.OIDC.Enabled() {
+		authMiddleware, err := oidc.NewMiddleware(ctx, config.AppConfig.OIDC, logger.Named("oidc"))
+		if err != nil {
+			logger.Fatalw("failed to create oidc middleware", "error", err)
+		}
+
+		opts = append(opts, graphapi.WithMiddleware(authMiddleware))
+	}
+
+	// clients are throttled once they're authenticated so they can be told
+	// apart by subject
+	if config.AppConfig.RateLimit.Enabled() {
+		rateLimiter, err := ratelimit.Middleware(config.AppConfig.RateLimit)
+		if err != nil {
+			logger.Fatalw("invalid rate limit config", "error", err)
+		}
+
+		opts = append(opts, graphapi.WithMiddleware(rateLimiter))
+	}
+
+	if config.AppConfig.Tenant.Enabled {
+		checker, err := tenant.NewChecker(config.AppConfig.Tenant, logger.Named("tenant"))
+		if err != nil {
+			logger.Fatalw("failed to create tenant checker", "error", err)
+		}
+
+		authorizer = authz.All(authorizer, wrapBackend(checker, "tenant", hedgeBudget, metricsSink))
+
+		opts = append(opts, graphapi.WithMiddleware(tenant.Middleware(config.AppConfig.Tenant.Claim)))
+	}
+
+	resultCache := cache.NewFromConfig(config.AppConfig.Cache)
+
+	if resultCache != nil && config.AppConfig.Cache.Invalidation.Enabled() {
+		invalidator, err := cache.NewInvalidator(config.AppConfig.Cache.Invalidation, resultCache, appName, logger.Named("cache"))
+		if err != nil {
+			logger.Fatalw("failed to subscribe to cache invalidations", "error", err)
+		}
+
+		defer invalidator.Close()
+
+		opts = append(opts, graphapi.WithCacheInvalidator(invalidator))
+	}
+
+	// the cache is also created for responses alone, so grants are only
+	// cached when results caching is enabled
+	if config.AppConfig.Cache.Enabled {
+		authorizer = authz.Cached(authorizer, resultCache, metricsSink)
+	}
+
+	opts = append(opts, graphapi.WithAuthorizer(authorizer))
+
+	if config.AppConfig.Cache.Responses {
+		opts = append(opts, graphapi.WithResponseCache(resultCache))
+	}
+
+	if config.AppConfig.Policy.Enabled() {
+		evaluator, err := policy.NewOPA(config.AppConfig.Policy, logger.Named("policy"))
+		if err != nil {
+			logger.Fatalw("failed to create policy evaluator", "error", err)
+		}
+
+		opts = append(opts, graphapi.WithPolicy(evaluator))
+	}
+
+	if config.AppConfig.Errors.Enabled() {
+		catalog, err := errcode.NewCatalog(config.AppConfig.Errors)
+		if err != nil {
+			logger.Fatalw("invalid error message catalog", "error", err)
+		}
+
+		if config.AppConfig.Errors.Localize {
+			opts = append(opts, graphapi.WithErrorCatalog(catalog))
+		}
+
+		if config.AppConfig.Errors.Mask {
+			opts = append(opts, graphapi.WithErrorMasking(catalog))
+		}
+
+		if config.AppConfig.Errors.Verbose {
+			opts = append(opts, graphapi.WithVerboseErrors())
+		}
+	}
+
+	if config.AppConfig.FeatureFlags.Enabled() {
+		provider, err := featureflags.NewProvider(config.AppConfig.FeatureFlags)
+		if err != nil {
+			logger.Fatalw("failed to create feature flag provider", "error", err)
+		}
+
+		gate := featureflags.NewGate(config.AppConfig.FeatureFlags, provider, logger.Named("featureflags"))
+
+		opts = append(opts, graphapi.WithFeatureFlags(gate))
+	}
+
+	var db *sql.DB
+
+	if (config.AppConfig.Audit.Auditing() && config.AppConfig.Audit.CRDB.Enabled) || config.AppConfig.Backend.Provider == backend.ProviderCRDB {
+		db, err = crdbx.NewDB(config.AppConfig.CRDB, config.AppConfig.Tracing.Enabled)
+		if err != nil {
+			logger.Fatalw("failed to connect to database", "error", err)
+		}
+
+		defer db.Close() //nolint:errcheck // shutting down, nothing to do with the error
+	}
+
+	auditor, err := audit.NewFromConfig(ctx, config.AppConfig.Audit, db, logger.Named("audit"))
+	if err != nil {
+		logger.Fatalw("failed to create auditor", "error", err)
+	}
+
+	if auditor != nil {
+		auditor.Start(ctx)
+
+		defer auditor.Close() //nolint:errcheck // shutting down, nothing to do with the error
+	}
+
+	nodeBackend, err := backend.NewFromConfig(config.AppConfig.Backend, db, logger.Named("backend"))
+	if err != nil {
+		logger.Fatalw("failed to create node backend", "error", err)
+	}
+
+	if nodeBackend != nil {
+		opts = append(opts, graphapi.WithNodeBackend(nodeBackend))
+	}
+
+	if config.AppConfig.Backend.KeyLookup.Enabled() {
+		keyLookup, err := backend.NewHTTPKeyLookup(config.AppConfig.Backend.KeyLookup, logger.Named("key-lookup"))
+		if err != nil {
+			logger.Fatalw("failed to create key lookup", "error", err)
+		}
+
+		opts = append(opts, graphapi.WithKeyLookup(keyLookup))
+	}
+
+	requestLogging, err := graphapi.ParseRequestLogging(viper.GetString("request-logging"))
+	if err != nil {
+		logger.Fatalw("invalid request logging mode", "error", err)
+	}
+
+	operationSelection, err := graphapi.ParseOperationSelection(viper.GetString("operation-selection"))
+	if err != nil {
+		logger.Fatalw("invalid operation selection", "error", err)
+	}
+
+	opts = append(opts,
+		graphapi.WithAuditor(auditor),
+		graphapi.WithMetrics(metricsSink),
+		graphapi.WithAdaptiveEntityConcurrency(viper.GetInt("entities.concurrency"), viper.GetInt("entities.max-concurrency")),
+		graphapi.WithDocumentCacheSize(viper.GetInt("query-cache.size")),
+		graphapi.WithPersistedQueries(viper.GetInt("persisted-queries.size")),
+		graphapi.WithMaxIDLength(viper.GetInt("ids.max-length")),
+		graphapi.WithRequestTimeout(viper.GetDuration("lookups.request-timeout")),
+		graphapi.WithLookupTimeouts(viper.GetDuration("lookups.timeout-floor"), viper.GetDuration("lookups.timeout")),
+		graphapi.WithRequestLogging(requestLogging),
+		graphapi.WithOperationSelection(operationSelection),
+	)
+
+	if viper.GetBool("wildcard-prefixes") {
+		opts = append(opts, graphapi.WithWildcardPrefixes())
+	}
+
+	if name := viper.GetString("unknown-prefix-type"); name != "" {
+		opts = append(opts, graphapi.WithUnknownPrefixType(name))
+	}
+
+	if config.AppConfig.Shed.Enabled() {
+		shedder := shed.New(config.AppConfig.Shed, metricsSink, logger.Named("shed"))
+		shedder.Start(ctx)
+
+		opts = append(opts, graphapi.WithLoadShedder(shedder))
+	}
+
+	if config.AppConfig.Breaker.Enabled() && config.AppConfig.Breaker.FallbackPrefixOnly {
+		opts = append(opts, graphapi.WithBreakerFallback())
+	}
+
+	if viper.GetBool("canonical-responses") {
+		opts = append(opts, graphapi.WithCanonicalResponses())
+	}
+
+	if viper.GetBool("strict-requests") {
+		opts = append(opts, graphapi.WithStrictRequests())
+	}
+
+	if viper.GetBool("prefixed-id-scalar") {
+		opts = append(opts, graphapi.WithPrefixedIDScalar())
+	}
+
+	if viper.GetBool("strict-prefixes") {
+		opts = append(opts, graphapi.WithStrictPrefixes())
+	}
+
+	if viper.GetBool("schema-strict") {
+		opts = append(opts, graphapi.WithStrictSchema())
+	}
+
+	if viper.GetBool("source-lookups.enabled") {
+		opts = append(opts, graphapi.WithSourceLookups(&http.Client{Timeout: viper.GetDuration("source-lookups.timeout")}))
+	}
+
+	opts = append(opts, graphapi.WithQueryLimits(graphapi.QueryLimits{
+		MaxDepth:      viper.GetInt("query-limits.max-depth"),
+		MaxAliases:    viper.GetInt("query-limits.max-aliases"),
+		MaxComplexity: viper.GetInt("query-limits.max-complexity"),
+	}))
+
+	if viper.GetBool("introspection.disabled") {
+		allowed, err := graphapi.ParseCIDRs(viper.GetStringSlice("introspection.allowed-cidrs"))
 		if err != nil {
-			logger.Fatalw("failed to read graphql schema file", "error", err)
+			logger.Fatalw("invalid introspection allowed cidrs", "error", err)
 		}
 
-		schema = string(schemaBytes)
+		opts = append(opts, graphapi.WithIntrospectionDisabled(allowed))
+	}
+
+	if viper.GetBool("instance.report") {
+		opts = append(opts, graphapi.WithInstanceID(instanceID()))
 	}
 
-	r, err := graphapi.NewResolver(logger.Named("resolvers"), schema)
+	opts = append(opts, graphapi.WithUnknownPrefixLog(graphapi.NewUnknownPrefixLog(graphapi.DefaultUnknownPrefixLogSize)))
+
+	if file := viper.GetString("shadow.schema"); file != "" {
+		opts = append(opts, graphapi.WithShadow(newShadow(file, opts)))
+	}
+
+	// faults are injected after the shadow is created, so the candidate
+	// isn't compared against injected failures
+	if chaosEnabled {
+		config.AppConfig.Chaos.Inject = true
+
+		injector, err := chaos.New(config.AppConfig.Chaos)
+		if err != nil {
+			logger.Fatalw("invalid chaos config", "error", err)
+		}
+
+		logger.Warnw("injecting faults into requests", "config", config.AppConfig.Chaos)
+
+		opts = append(opts, graphapi.WithFaultInjection(injector))
+	}
+
+	r, err := graphapi.NewResolver(logger.Named("resolvers"), schema, opts...)
 	if err != nil {
 		logger.Fatalw("failed to create graphql resolver", "error", err)
 	}
 
-	srv.AddHandler(r)
+	if config.AppConfig.Registry.Enabled() {
+		publishSubgraph(ctx, r)
+	}
+
+	handler := graphapi.NewHandler(r)
+
+	adminHandler.WithResolverStats(handler).WithSchemaSource(source)
+
+	var reloader schemawatch.Reloader = handler
+
+	if config.AppConfig.Admin.SchemaAPI || config.AppConfig.Announce.Enabled() {
+		namespaces := config.AppConfig.Admin.Namespaces
+
+		if namespaces.Enabled() {
+			if _, err := admin.ParseConflictPolicy(string(namespaces.Policy)); err != nil {
+				logger.Fatalw("invalid namespace conflict policy", "error", err)
+			}
+		}
+
+		schemas := admin.NewSchemas(schema, handler).WithNamespaces(namespaces, metricsSink)
+
+		if config.AppConfig.Admin.SchemaAPI {
+			if config.AppConfig.Admin.Token == "" {
+				logger.Fatal("the admin schema api requires an admin token")
+			}
+
+			adminHandler.WithSchemas(schemas)
+		}
+
+		// announced types are pushed schemas named by their service
+		if config.AppConfig.Announce.Enabled() {
+			subscriber, err := announce.NewSubscriber(config.AppConfig.Announce, schemas, appName, logger.Named("announce"))
+			if err != nil {
+				logger.Fatalw("failed to subscribe to type announcements", "error", err)
+			}
+
+			defer subscriber.Close()
+		}
+
+		// pushed schemas are merged with the reloaded schema
+		reloader = schemawatch.ReloaderFunc(schemas.SetBase)
+	}
+
+	if load != nil {
+		adminHandler.WithReload(load, reloader)
+	}
+
+	srv.AddReadinessCheck("schema", handler.ReadinessCheck)
+
+	if config.AppConfig.SchemaWatch.Enabled() {
+		watchSchema(ctx, srv, schema, reloader)
+	}
 
-	if err := srv.RunWithContext(ctx); err != nil {
+	if graphs := viper.GetStringMapString("graphs.schemas"); len(graphs) != 0 {
+		srv.AddHandler(serveGraphs(ctx, srv, handler, graphs, opts, adminHandler))
+	} else {
+		srv.AddHandler(handler)
+	}
+
+	srv.AddHandler(adminHandler)
+	srv.AddReadinessCheck("drain", adminHandler.ReadinessCheck)
+
+	if config.AppConfig.GRPC.Enabled() {
+		go serveGRPC(ctx, handler, tlsConfig)
+	}
+
+	if err := runServer(ctx, srv, tlsConfig); err != nil {
 		logger.Errorw("failed to run server", "error", zap.Error(err))
 	}
 }
+
+// newShadow returns a Shadow comparing requests with their results from the
+// schema in file. The candidate is configured with opts but doesn't audit,
+// record metrics or log unknown prefixes, so shadowed resolutions aren't
+// recorded twice.
+func newShadow(file string, opts []graphapi.Option) *graphapi.Shadow {
+	schema, err := os.ReadFile(file)
+	if err != nil {
+		logger.Fatalw("failed to read shadow schema file", "error", err)
+	}
+
+	candidateOpts := append(append([]graphapi.Option{}, opts...), graphapi.WithAuditor(nil), graphapi.WithMetrics(nil), graphapi.WithUnknownPrefixLog(nil))
+
+	candidate, err := graphapi.NewResolver(logger.Named("shadow"), string(schema), candidateOpts...)
+	if err != nil {
+		logger.Fatalw("failed to create shadow resolver", "error", err)
+	}
+
+	logger.Infow("shadowing requests with candidate schema", "checksum", candidate.SDLChecksum())
+
+	return graphapi.NewShadow(candidate, viper.GetFloat64("shadow.sample-rate"), viper.GetInt("shadow.concurrency"))
+}
+
+// watchSchema reloads the schema files with reloader when they change, and
+// reports the replica not ready while they're reloaded or after they failed
+// to reload. schema is the schema being served.
+func watchSchema(ctx context.Context, srv *echox.Server, schema string, reloader schemawatch.Reloader) {
+	if len(schemaFiles) == 0 || config.AppConfig.Supergraph.Enabled() {
+		logger.Warn("schema watching requires a schema file, not reloading the schema")
+
+		return
+	}
+
+	load := func() (string, error) { return graphapi.LoadSchemaFiles(schemaFiles) }
+
+	w := schemawatch.New(config.AppConfig.SchemaWatch, schemaFiles, load, schema, reloader, logger.Named("schemawatch"))
+	w.Start(ctx)
+
+	srv.AddReadinessCheck("schema-watch", w.ReadinessCheck)
+}
+
+// serveGraphs returns Graphs serving handler as the default graph along with
+// a graph for each schema file in schemas, keyed by graph name. Every graph
+// is configured with opts, and its file is reloaded when it changes if
+// schema watching is enabled.
+func serveGraphs(ctx context.Context, srv *echox.Server, handler *graphapi.Handler, schemas map[string]string, opts []graphapi.Option, adminHandler *admin.Handler) *graphapi.Graphs {
+	graphs := graphapi.NewGraphs(graphapi.GraphSelector{
+		Header: viper.GetString("graphs.header"),
+		Claim:  viper.GetString("graphs.claim"),
+	}, viper.GetString("graphs.default"), handler)
+
+	for name, file := range schemas {
+		paths := []string{file}
+		load := func() (string, error) { return graphapi.LoadSchemaFiles(paths) }
+
+		schema, err := load()
+		if err != nil {
+			logger.Fatalw("failed to read graph schema file", "graph", name, "error", err)
+		}
+
+		r, err := graphapi.NewResolver(logger.Named("resolvers").With("graph", name), schema, opts...)
+		if err != nil {
+			logger.Fatalw("failed to create graph resolver", "graph", name, "error", err)
+		}
+
+		h := graphapi.NewHandler(r)
+		graphs.Set(name, h)
+
+		if config.AppConfig.SchemaWatch.Enabled() {
+			w := schemawatch.New(config.AppConfig.SchemaWatch, paths, load, schema, h, logger.Named("schemawatch").With("graph", name))
+			w.Start(ctx)
+
+			srv.AddReadinessCheck("schema-watch-"+name, w.ReadinessCheck)
+		}
+	}
+
+	// replacing a graph's schema is only allowed with an admin token
+	if config.AppConfig.Admin.Token != "" {
+		adminHandler.WithGraphs(graphs)
+	}
+
+	return graphs
+}
+
+// publishSubgraph publishes the resolver's sdl to the configured schema
+// registry. Failures are logged so a registry outage doesn't prevent startup.
+func publishSubgraph(ctx context.Context, r *graphapi.Resolver) {
+	publisher, err := registry.NewPublisher(config.AppConfig.Registry, logger.Named("registry"))
+	if err != nil {
+		logger.Fatalw("failed to create schema registry publisher", "error", err)
+	}
+
+	err = publisher.Publish(ctx, registry.Subgraph{
+		Name:       config.AppConfig.Registry.SubgraphName,
+		RoutingURL: config.AppConfig.Registry.RoutingURL,
+		SDL:        r.SDL(),
+		Checksum:   r.SDLChecksum(),
+	})
+	if err != nil {
+		logger.Errorw("failed to publish subgraph to schema registry", "error", err)
+	}
+}
+
+// wrapBackend returns a checking with the backend called name through a
+// hedger and a circuit breaker, when they're enabled. Hedged checks count as
+// a single check for the breaker.
+func wrapBackend(a authz.Authorizer, name string, budget *hedge.Budget, sink metrics.Sink) authz.Authorizer {
+	if config.AppConfig.Hedge.Enabled() {
+		a = authz.Hedged(a, hedge.New(name, config.AppConfig.Hedge, budget, sink))
+	}
+
+	if config.AppConfig.Breaker.Enabled() {
+		a = authz.WithBreaker(a, breaker.New(name, config.AppConfig.Breaker, sink, logger.Named("breaker")))
+	}
+
+	return a
+}
+
+// redactURL returns raw with any password replaced, so it can be reported
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	return u.Redacted()
+}
+
+// instanceID returns the configured instance id, defaulting to the hostname
+// which is the pod name in kubernetes
+func instanceID() string {
+	if id := viper.GetString("instance.id"); id != "" {
+		return id
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Fatalw("failed to get hostname for the instance id, set --instance-id", "error", err)
+	}
+
+	return hostname
+}
+
+// setupVault replaces vault secret references in the config with their values
+// and returns a tls config using certificates issued by vault when configured
+func setupVault(ctx context.Context) *tls.Config {
+	client, err := vault.NewClient(ctx, config.AppConfig.Vault, logger.Named("vault"))
+	if err != nil {
+		logger.Fatalw("failed to create vault client", "error", err)
+	}
+
+	go client.RenewToken(ctx)
+
+	if err := client.ResolveReferences(ctx, viper.GetViper()); err != nil {
+		logger.Fatalw("failed to resolve vault secrets", "error", err)
+	}
+
+	setupAppConfig()
+
+	if config.AppConfig.Vault.TLS.PKIPath == "" {
+		return nil
+	}
+
+	certs, err := vault.NewCertificates(ctx, client)
+	if err != nil {
+		logger.Fatalw("failed to issue tls certificate from vault", "error", err)
+	}
+
+	go certs.Renew(ctx)
+
+	return certs.ServerTLSConfig()
+}
+
+// serveGRPC serves the grpc resolution service with the resolver of handler
+// until ctx is done, using tls when a config is provided
+func serveGRPC(ctx context.Context, handler *graphapi.Handler, tlsConfig *tls.Config) {
+	var opts []grpc.ServerOption
+
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	listener, err := net.Listen("tcp", config.AppConfig.GRPC.Listen)
+	if err != nil {
+		logger.Fatalw("failed to listen for grpc", "error", err)
+	}
+
+	s := grpcapi.NewServer(handler, logger.Named("grpc"), opts...)
+
+	if err := s.Serve(ctx, listener, config.AppConfig.GRPC.ShutdownGracePeriod); err != nil {
+		logger.Errorw("failed to serve grpc", "error", err)
+	}
+}
+
+// runServer serves srv on the configured listen address, using tls when a
+// config is provided
+func runServer(ctx context.Context, srv *echox.Server, tlsConfig *tls.Config) error {
+	if tlsConfig == nil {
+		return srv.RunWithContext(ctx)
+	}
+
+	listener, err := net.Listen("tcp", viper.GetString("server.listen"))
+	if err != nil {
+		return err
+	}
+
+	defer listener.Close() //nolint:errcheck // No need to check error.
+
+	return srv.ServeWithContext(ctx, tls.NewListener(listener, tlsConfig))
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"go.infratographer.com/node-resolver/internal/config"
+	"go.infratographer.com/node-resolver/internal/schemasync"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Push a subgraph schema to a central node-resolver",
+	Long: `sync runs next to a subgraph service, watches its sdl and pushes changes
+to the admin schema api of a central node-resolver`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runSync(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	schemasync.MustViperFlags(viper.GetViper(), syncCmd.Flags())
+}
+
+func runSync(ctx context.Context) {
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	syncer, err := schemasync.NewSyncer(config.AppConfig.Sync, logger.Named("sync"))
+	if err != nil {
+		logger.Fatalw("failed to create schema syncer", "error", err)
+	}
+
+	if err := syncer.Run(ctx); err != nil {
+		logger.Errorw("failed to sync schema", "error", err)
+	}
+}
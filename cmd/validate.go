@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// errInvalidSchema is returned by validate when the schema has problems,
+// which have already been printed
+var errInvalidSchema = errors.New("invalid schema")
+
+var (
+	validateSchemaFiles      []string
+	validateWildcardPrefixes bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check graphql schema files",
+	Long: `validate reads the schema files the way serve does and prints the prefix
+of every type and the type it resolves to. Problems, such as types without a
+@prefixedID directive or prefixes used by several types, are printed instead
+and the command fails, so broken schemas can be caught before they're deployed.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return validate(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringSliceVar(&validateSchemaFiles, "schema", nil, "paths to graphql schema files, or directories of them, merged into one schema; may be repeated")
+	validateCmd.Flags().BoolVar(&validateWildcardPrefixes, "wildcard-prefixes", false, "match @prefixedID prefixes ending in * against every prefix starting with them")
+
+	cobra.CheckErr(validateCmd.MarkFlagRequired("schema"))
+}
+
+func validate(cmd *cobra.Command) error {
+	sources, err := graphapi.ReadSchemaFiles(validateSchemaFiles)
+	if err != nil {
+		return err
+	}
+
+	opts := []graphapi.Option{}
+	if validateWildcardPrefixes {
+		opts = append(opts, graphapi.WithWildcardPrefixes())
+	}
+
+	prefixes, problems := graphapi.CheckSchema(sources, opts...)
+
+	if len(problems) != 0 {
+		for _, p := range problems {
+			fmt.Fprintln(cmd.ErrOrStderr(), p)
+		}
+
+		return fmt.Errorf("%w: %d problems found", errInvalidSchema, len(problems))
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0) //nolint:gomnd
+
+	fmt.Fprintln(w, "PREFIX\tTYPE")
+
+	for _, p := range prefixes {
+		fmt.Fprintf(w, "%s\t%s\n", p.Prefix, p.Type)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,144 @@
+// Package admin provides the operational endpoints used to manage a running
+// node-resolver
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// ErrDraining is returned by the readiness check once draining has started
+var ErrDraining = errors.New("server is draining")
+
+// Handler serves the admin endpoints
+type Handler struct {
+	cfg      Config
+	logger   *zap.SugaredLogger
+	draining atomic.Bool
+	schemas  *Schemas
+	resolver *graphapi.Handler
+	graphs   *graphapi.Graphs
+	// source describes where the base schema was loaded from
+	source string
+	reload *schemaReload
+}
+
+// NewHandler returns the admin endpoints for the given config
+func NewHandler(cfg Config, logger *zap.SugaredLogger) *Handler {
+	if cfg.DrainDuration < 0 {
+		cfg.DrainDuration = 0
+	}
+
+	return &Handler{
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Routes adds the admin endpoints to e
+func (h *Handler) Routes(e *echo.Group) {
+	g := e.Group("/admin", h.authenticate)
+
+	g.POST("/drain", h.drainHandler)
+
+	if h.schemas != nil {
+		h.schemaRoutes(g)
+	}
+
+	if h.graphs != nil {
+		h.graphRoutes(g)
+	}
+
+	if h.resolver != nil {
+		g.GET("/prefixes", h.prefixMapHandler)
+
+		if h.reload != nil {
+			g.POST("/reload", h.reloadHandler)
+		}
+
+		if h.cfg.UI {
+			h.uiRoutes(e)
+		}
+	}
+
+	e.GET("/debug/runtime", h.runtimeHandler, h.authenticate)
+}
+
+// ReadinessCheck fails once draining has started so load balancers stop
+// sending new requests
+func (h *Handler) ReadinessCheck(_ context.Context) error {
+	if h.draining.Load() {
+		return ErrDraining
+	}
+
+	return nil
+}
+
+// Middleware asks clients to close their connections once draining has
+// started, so keep-alive connections move to other replicas before shutdown
+func (h *Handler) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if h.draining.Load() {
+				c.Response().Header().Set(echo.HeaderConnection, "close")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// drainHandler starts draining and waits for the drain duration before
+// responding, so it can be used as a preStop hook that delays SIGTERM until
+// load balancers have deregistered the replica. Requests are still served
+// while draining.
+func (h *Handler) drainHandler(c echo.Context) error {
+	if h.draining.CompareAndSwap(false, true) {
+		h.logger.Warnw("draining started", "duration", h.cfg.DrainDuration)
+	}
+
+	timer := time.NewTimer(h.cfg.DrainDuration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-c.Request().Context().Done():
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": "DRAINING",
+	})
+}
+
+// authenticate requires the configured admin token as a bearer token, or as
+// the basic auth password so the admin ui can be used from a browser. When no
+// token is configured the endpoints are unauthenticated and should only be
+// reachable from inside the pod.
+func (h *Handler) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.cfg.Token == "" {
+			return next(c)
+		}
+
+		token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if _, password, ok := c.Request().BasicAuth(); ok {
+			token = password
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.Token)) != 1 {
+			return echo.ErrUnauthorized
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,51 @@
+package admin_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+)
+
+func TestDrain(t *testing.T) {
+	h := admin.NewHandler(admin.Config{
+		Token:         "secret",
+		DrainDuration: 10 * time.Millisecond,
+	}, zap.NewNop().Sugar())
+
+	e := echo.New()
+	e.Use(h.Middleware())
+	e.GET("/query", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	h.Routes(e.Group(""))
+
+	assert.NoError(t, h.ReadinessCheck(context.Background()))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/drain", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NoError(t, h.ReadinessCheck(context.Background()))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/drain", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+
+	start := time.Now()
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	assert.ErrorIs(t, h.ReadinessCheck(context.Background()), admin.ErrDraining)
+
+	// requests are still served but clients are asked to reconnect elsewhere
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/query", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "close", rec.Header().Get(echo.HeaderConnection))
+}
@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// ErrCanaryInProgress is returned when changing the pushed schemas while a
+// canary is being rolled out
+var ErrCanaryInProgress = errors.New("a canary schema rollout is in progress; promote or abort it first")
+
+// CanaryInfo describes a canary schema rollout
+type CanaryInfo struct {
+	graphapi.CanaryStatus
+	// Name is the pushed schema being rolled out
+	Name string `json:"name"`
+}
+
+// PutCanary builds the merged schema with the named schema replaced by sdl
+// and serves it to percent of clients, leaving the current schema to the
+// others until the canary is promoted or aborted. Only one canary is rolled
+// out at a time.
+func (s *Schemas) PutCanary(name, sdl string, percent int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.canary != nil {
+		return ErrCanaryInProgress
+	}
+
+	v, err := s.build(s.withSchema(name, sdl))
+	if err != nil {
+		return err
+	}
+
+	s.handler.StartCanary(v.resolver, percent)
+	s.canary = v
+
+	return nil
+}
+
+// SetCanaryPercent changes the percentage of clients served the canary,
+// returning false when no canary is being rolled out
+func (s *Schemas) SetCanaryPercent(percent int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.canary != nil && s.handler.SetCanaryPercent(percent)
+}
+
+// PromoteCanary serves the canary to every client and makes its schema the
+// pushed schema, returning false when no canary is being rolled out
+func (s *Schemas) PromoteCanary() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.canary == nil || !s.handler.PromoteCanary() {
+		return false
+	}
+
+	s.commit(s.canary)
+	s.canary = nil
+
+	return true
+}
+
+// AbortCanary stops serving the canary, discarding its schema, and returns
+// false when no canary is being rolled out
+func (s *Schemas) AbortCanary() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.canary == nil {
+		return false
+	}
+
+	s.handler.AbortCanary()
+	s.canary = nil
+
+	return true
+}
+
+// Canary returns the canary being rolled out, if any
+func (s *Schemas) Canary() (CanaryInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.canary == nil {
+		return CanaryInfo{}, false
+	}
+
+	status, ok := s.handler.Canary()
+
+	return CanaryInfo{CanaryStatus: status, Name: s.canary.name}, ok
+}
+
+func (h *Handler) canaryRoutes(g *echo.Group) {
+	g.GET("/canary", h.getCanaryHandler)
+	g.PUT("/canary", h.setCanaryHandler)
+	g.POST("/canary/promote", h.promoteCanaryHandler)
+	g.DELETE("/canary", h.abortCanaryHandler)
+}
+
+// canaryPercent parses the canary percentage of a request
+func canaryPercent(raw string) (int, error) {
+	percent, err := strconv.Atoi(raw)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "canary percent must be between 0 and 100")
+	}
+
+	return percent, nil
+}
+
+func (h *Handler) getCanaryHandler(c echo.Context) error {
+	info, ok := h.schemas.Canary()
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "no canary in progress")
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+// setCanaryHandler changes the percentage of clients served the canary to
+// the percent query parameter
+func (h *Handler) setCanaryHandler(c echo.Context) error {
+	percent, err := canaryPercent(c.QueryParam("percent"))
+	if err != nil {
+		return err
+	}
+
+	if !h.schemas.SetCanaryPercent(percent) {
+		return echo.NewHTTPError(http.StatusNotFound, "no canary in progress")
+	}
+
+	h.logger.Infow("canary percent changed", "percent", percent)
+
+	return h.getCanaryHandler(c)
+}
+
+func (h *Handler) promoteCanaryHandler(c echo.Context) error {
+	info, ok := h.schemas.Canary()
+	if !ok || !h.schemas.PromoteCanary() {
+		return echo.NewHTTPError(http.StatusNotFound, "no canary in progress")
+	}
+
+	h.logger.Infow("canary promoted", "name", info.Name, "checksum", info.CanaryChecksum)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) abortCanaryHandler(c echo.Context) error {
+	if !h.schemas.AbortCanary() {
+		return echo.NewHTTPError(http.StatusNotFound, "no canary in progress")
+	}
+
+	h.logger.Infow("canary aborted")
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+)
+
+func TestCanary(t *testing.T) {
+	e := namespaceServer(t, admin.NamespaceConfig{})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+		return rec
+	}
+
+	listed := func() []admin.SchemaInfo {
+		var resp struct {
+			Schemas []admin.SchemaInfo `json:"schemas"`
+		}
+
+		require.NoError(t, json.Unmarshal(serve(http.MethodGet, "/admin/schemas", "").Body.Bytes(), &resp))
+
+		return resp.Schemas
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/admin/canary", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/admin/schemas/widgets?canary=150", widgetSchema).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPut, "/admin/schemas/widgets?canary=100", "type {").Code)
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPut, "/admin/schemas/widgets?canary=100", widgetSchema).Code)
+
+	// every client is served the canary, but the schema isn't pushed until
+	// it's promoted
+	assert.Equal(t, "Widget", typeOf(t, e, "testwdg-abc"))
+	assert.Empty(t, listed())
+
+	rec := serve(http.MethodGet, "/admin/canary", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var info admin.CanaryInfo
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, "widgets", info.Name)
+	assert.Equal(t, 100, info.Percent)
+	assert.NotEqual(t, info.StableChecksum, info.CanaryChecksum)
+
+	assert.Equal(t, http.StatusConflict, push(e, "gadgets", gadgetSchema("Gadget", "testgdt")), "schemas can't change during a rollout")
+	assert.Equal(t, http.StatusOK, push(e, "widgets", widgetSchema), "unchanged schemas can be pushed again")
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPut, "/admin/schemas/gadgets?canary=10", gadgetSchema("Gadget", "testgdt")).Code)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/admin/canary?percent=0", "").Code)
+	assert.Empty(t, typeOf(t, e, "testwdg-abc"))
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, "/admin/canary/promote", "").Code)
+	assert.Equal(t, "Widget", typeOf(t, e, "testwdg-abc"))
+	assert.Equal(t, []admin.SchemaInfo{{Name: "widgets", Checksum: admin.Checksum(widgetSchema)}}, listed())
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/admin/canary/promote", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/admin/canary?percent=10", "").Code)
+
+	// an aborted canary is discarded
+	require.Equal(t, http.StatusOK, serve(http.MethodPut, "/admin/schemas/gadgets?canary=100", gadgetSchema("Gadget", "testgdt")).Code)
+	assert.Equal(t, "Gadget", typeOf(t, e, "testgdt-abc"))
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/admin/canary", "").Code)
+	assert.Empty(t, typeOf(t, e, "testgdt-abc"))
+	assert.Equal(t, "Widget", typeOf(t, e, "testwdg-abc"))
+	assert.Len(t, listed(), 1)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/admin/canary", "").Code)
+	assert.Equal(t, http.StatusOK, push(e, "gadgets", gadgetSchema("Gadget", "testgdt")))
+}
+
+func TestCanaryDelete(t *testing.T) {
+	e := namespaceServer(t, admin.NamespaceConfig{})
+
+	require.Equal(t, http.StatusOK, push(e, "widgets", widgetSchema))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/schemas/gadgets?canary=50", strings.NewReader(gadgetSchema("Gadget", "testgdt"))))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/schemas/widgets", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultDrainDuration = 15 * time.Second
+
+// Config stores the settings for the admin endpoints
+type Config struct {
+	Token         string          `mapstructure:"token"`
+	DrainDuration time.Duration   `mapstructure:"drain-duration"`
+	SchemaAPI     bool            `mapstructure:"schema-api"`
+	UI            bool            `mapstructure:"ui"`
+	Namespaces    NamespaceConfig `mapstructure:"namespaces"`
+}
+
+// NamespaceConfig stores the prefix namespace ownership rules of pushed
+// schemas
+type NamespaceConfig struct {
+	// Policy decides conflicting namespace claims
+	Policy ConflictPolicy `mapstructure:"policy"`
+	// Owners maps namespaces to the name of the only schema allowed to
+	// claim them, BaseSource for the base schema
+	Owners map[string]string `mapstructure:"owners"`
+}
+
+// Enabled returns true when pushed schemas claim prefix namespaces
+func (c NamespaceConfig) Enabled() bool {
+	return c.Policy != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Duration("drain-duration", defaultDrainDuration, "how long POST /admin/drain waits for load balancers to stop sending requests")
+	viperx.MustBindFlag(v, "admin.drain-duration", flags.Lookup("drain-duration"))
+
+	flags.Bool("admin-schema-api", false, "accept subgraph schemas pushed to /admin/schemas, requires an admin token")
+	viperx.MustBindFlag(v, "admin.schema-api", flags.Lookup("admin-schema-api"))
+
+	flags.Bool("admin-ui", false, "serve a page showing the prefix map and reloading schemas at /admin/ui")
+	viperx.MustBindFlag(v, "admin.ui", flags.Lookup("admin-ui"))
+
+	flags.String("namespace-conflict-policy", "", "how prefix namespaces claimed by several pushed schemas are decided: reject, prefer-source or prefer-newest; namespaces aren't checked when unset")
+	viperx.MustBindFlag(v, "admin.namespaces.policy", flags.Lookup("namespace-conflict-policy"))
+
+	v.MustBindEnv("admin.token")
+}
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// GraphInfo describes a graph served by the resolver
+type GraphInfo struct {
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+}
+
+// WithGraphs enables the admin api replacing the schemas of the graphs
+// served by g
+func (h *Handler) WithGraphs(g *graphapi.Graphs) *Handler {
+	h.graphs = g
+
+	return h
+}
+
+func (h *Handler) graphRoutes(g *echo.Group) {
+	g.GET("/graphs", h.listGraphsHandler)
+	g.PUT("/graphs/:graph", h.putGraphHandler)
+}
+
+func (h *Handler) listGraphsHandler(c echo.Context) error {
+	names := h.graphs.Names()
+	graphs := make([]GraphInfo, 0, len(names))
+
+	for _, name := range names {
+		if g, ok := h.graphs.Graph(name); ok {
+			graphs = append(graphs, GraphInfo{Name: name, Checksum: g.Resolver().SDLChecksum()})
+		}
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"graphs": graphs,
+	})
+}
+
+// putGraphHandler replaces the schema of a graph with the sdl in the request
+// body, leaving the other graphs unchanged
+func (h *Handler) putGraphHandler(c echo.Context) error {
+	name := c.Param("graph")
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read schema").SetInternal(err)
+	}
+
+	err = h.graphs.Reload(name, string(body))
+
+	switch {
+	case errors.Is(err, graphapi.ErrUnknownGraph):
+		return echo.NewHTTPError(http.StatusNotFound, "graph not found")
+	case err != nil:
+		h.logger.Warnw("rejected graph schema", "graph", name, "error", err)
+
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "invalid schema: "+err.Error())
+	}
+
+	g, _ := h.graphs.Graph(name)
+	checksum := g.Resolver().SDLChecksum()
+
+	h.logger.Infow("graph schema updated", "graph", name, "checksum", checksum)
+
+	return c.JSON(http.StatusOK, GraphInfo{Name: name, Checksum: checksum})
+}
@@ -0,0 +1,58 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestGraphs(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema)
+	require.NoError(t, err)
+
+	graphs := graphapi.NewGraphs(graphapi.GraphSelector{Header: graphapi.DefaultGraphHeader}, "default", graphapi.NewHandler(r))
+
+	h := admin.NewHandler(admin.Config{Token: "secret"}, zap.NewNop().Sugar()).WithGraphs(graphs)
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := serve(http.MethodPut, "/admin/graphs/default", baseSchema+"\n"+widgetSchema)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var info admin.GraphInfo
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, "default", info.Name)
+	assert.Equal(t, []string{"default"}, graphs.Names())
+
+	g, _ := graphs.Graph("default")
+	assert.Equal(t, g.Resolver().SDLChecksum(), info.Checksum)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPut, "/admin/graphs/default", "type Query {").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/admin/graphs/gadgets", baseSchema).Code)
+
+	rec = serve(http.MethodGet, "/admin/graphs", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"graphs":[{"name":"default","checksum":"`+info.Checksum+`"}]}`, rec.Body.String())
+}
@@ -0,0 +1,296 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/parser"
+
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+// namespaceLength is the length of the namespace part of a prefix, the part
+// naming the service owning the type
+const namespaceLength = 4
+
+// BaseSource is the source name of the base schema in namespace decisions
+const BaseSource = "base"
+
+var (
+	// ErrInvalidConflictPolicy is returned when parsing an unknown conflict
+	// policy
+	ErrInvalidConflictPolicy = errors.New("invalid namespace conflict policy")
+	// ErrNamespaceConflict is returned when a pushed schema claims a prefix
+	// namespace it may not
+	ErrNamespaceConflict = errors.New("prefix namespace conflict")
+)
+
+// ConflictPolicy decides which source keeps a prefix namespace claimed by
+// several schema sources
+type ConflictPolicy string
+
+// Conflict policies
+const (
+	// ConflictReject rejects schemas claiming a namespace another source
+	// has, or that's owned by another source
+	ConflictReject ConflictPolicy = "reject"
+	// ConflictPreferSource keeps the namespace with its owner, or with the
+	// source that claimed it first when it has none
+	ConflictPreferSource ConflictPolicy = "prefer-source"
+	// ConflictPreferNewest gives the namespace to the source whose schema
+	// changed most recently, unless it has an owner
+	ConflictPreferNewest ConflictPolicy = "prefer-newest"
+)
+
+// ParseConflictPolicy returns the conflict policy named s
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictReject, ConflictPreferSource, ConflictPreferNewest:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidConflictPolicy, s)
+	}
+}
+
+// Namespace conflict outcomes, used as the metrics label
+const (
+	NamespaceRejected = "rejected"
+	NamespaceResolved = "resolved"
+)
+
+// NamespaceDecision records which source kept a prefix namespace claimed by
+// several sources, or by a source that doesn't own it
+type NamespaceDecision struct {
+	Namespace string         `json:"namespace"`
+	Owner     string         `json:"owner,omitempty"`
+	Claimants []string       `json:"claimants"`
+	Policy    ConflictPolicy `json:"policy"`
+	// Winner is the source whose types are served with the namespace. The
+	// types of the other claimants in the namespace aren't resolvable.
+	Winner string `json:"winner,omitempty"`
+}
+
+// WithNamespaces resolves prefix namespace conflicts between the pushed
+// schemas with cfg, counting decisions with sink, which may be nil
+func (s *Schemas) WithNamespaces(cfg NamespaceConfig, sink metrics.Sink) *Schemas {
+	s.namespaces = cfg
+	s.metrics = sink
+
+	return s
+}
+
+// Decisions returns the current namespace decisions sorted by namespace
+func (s *Schemas) Decisions() []NamespaceDecision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	decisions := make([]NamespaceDecision, 0, len(s.decisions))
+	for _, d := range s.decisions {
+		decisions = append(decisions, d)
+	}
+
+	sort.Slice(decisions, func(i, j int) bool { return decisions[i].Namespace < decisions[j].Namespace })
+
+	return decisions
+}
+
+// source is a schema claiming prefix namespaces
+type source struct {
+	name string
+	sdl  string
+	// order is when the schema last changed, the base schema first
+	order uint64
+	doc   *ast.SchemaDocument
+}
+
+// resolveNamespaces returns the sdl of each source with the prefixes of the
+// namespaces it lost removed, along with the decisions made. Sources are
+// returned in the order given.
+func (s *Schemas) resolveNamespaces(sources []*source) ([]string, map[string]NamespaceDecision, error) {
+	claims := map[string][]*source{}
+
+	for _, src := range sources {
+		doc, err := parser.ParseSchema(&ast.Source{Name: src.name, Input: src.sdl})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		src.doc = doc
+
+		for _, ns := range namespacesOf(doc) {
+			claims[ns] = append(claims[ns], src)
+		}
+	}
+
+	decisions := map[string]NamespaceDecision{}
+	losers := map[*source]map[string]bool{}
+
+	for ns, claimants := range claims {
+		owner := s.namespaces.Owners[ns]
+
+		if len(claimants) < 2 && (owner == "" || claimants[0].name == owner) {
+			continue
+		}
+
+		decision := NamespaceDecision{Namespace: ns, Owner: owner, Policy: s.namespaces.Policy}
+		for _, src := range claimants {
+			decision.Claimants = append(decision.Claimants, src.name)
+		}
+
+		winner, err := s.namespaceWinner(ns, owner, claimants)
+		if err != nil {
+			s.recordNamespace(ns, NamespaceRejected)
+
+			return nil, nil, err
+		}
+
+		decision.Winner = winner
+		decisions[ns] = decision
+
+		for _, src := range claimants {
+			if src.name == winner {
+				continue
+			}
+
+			if losers[src] == nil {
+				losers[src] = map[string]bool{}
+			}
+
+			losers[src][ns] = true
+		}
+	}
+
+	sdls := make([]string, len(sources))
+
+	for i, src := range sources {
+		sdls[i] = src.sdl
+
+		if lost := losers[src]; lost != nil {
+			sdls[i] = withoutNamespaces(src.doc, lost)
+		}
+	}
+
+	return sdls, decisions, nil
+}
+
+// namespaceWinner returns the source keeping the namespace ns claimed by
+// claimants, or an error when the policy rejects the claims
+func (s *Schemas) namespaceWinner(ns, owner string, claimants []*source) (string, error) {
+	if s.namespaces.Policy == ConflictReject {
+		if owner != "" {
+			return "", fmt.Errorf("%w: namespace %s is owned by %s", ErrNamespaceConflict, ns, owner)
+		}
+
+		names := make([]string, len(claimants))
+		for i, src := range claimants {
+			names[i] = src.name
+		}
+
+		return "", fmt.Errorf("%w: namespace %s is claimed by %s", ErrNamespaceConflict, ns, strings.Join(names, ", "))
+	}
+
+	if owner != "" {
+		return owner, nil
+	}
+
+	winner := claimants[0]
+
+	for _, src := range claimants[1:] {
+		newer := src.order > winner.order
+		if newer == (s.namespaces.Policy == ConflictPreferNewest) {
+			winner = src
+		}
+	}
+
+	return winner.name, nil
+}
+
+// recordNamespace counts a namespace decision
+func (s *Schemas) recordNamespace(ns, outcome string) {
+	if s.metrics != nil {
+		s.metrics.NamespaceConflict(ns, outcome)
+	}
+}
+
+// namespacesOf returns the prefix namespaces claimed by the types of doc.
+// Wildcard prefixes shorter than a namespace don't claim one.
+func namespacesOf(doc *ast.SchemaDocument) []string {
+	seen := map[string]bool{}
+	namespaces := []string{}
+
+	for _, def := range doc.Definitions {
+		ns, ok := namespaceOf(def)
+		if ok && !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	return namespaces
+}
+
+// namespaceOf returns the namespace of the @prefixedID prefix of def
+func namespaceOf(def *ast.Definition) (string, bool) {
+	pd := def.Directives.ForName("prefixedID")
+	if pd == nil {
+		return "", false
+	}
+
+	pa := pd.Arguments.ForName("prefix")
+	if pa == nil {
+		// prefix patterns such as "load..." have a namespace too when the
+		// pattern fixes its characters
+		pa = pd.Arguments.ForName("prefixPattern")
+	}
+
+	if pa == nil || pa.Value == nil {
+		return "", false
+	}
+
+	prefix := pa.Value.Raw
+	if len(prefix) < namespaceLength || strings.ContainsAny(prefix[:namespaceLength], "*.") {
+		return "", false
+	}
+
+	return prefix[:namespaceLength], true
+}
+
+// withoutNamespaces returns doc as sdl with the @prefixedID directives of the
+// types in the given namespaces removed, so they aren't resolved by prefix
+func withoutNamespaces(doc *ast.SchemaDocument, namespaces map[string]bool) string {
+	for _, def := range doc.Definitions {
+		ns, ok := namespaceOf(def)
+		if !ok || !namespaces[ns] {
+			continue
+		}
+
+		directives := make(ast.DirectiveList, 0, len(def.Directives))
+
+		for _, d := range def.Directives {
+			if d.Name != "prefixedID" {
+				directives = append(directives, d)
+			}
+		}
+
+		def.Directives = directives
+	}
+
+	var sb strings.Builder
+
+	formatter.NewFormatter(&sb).FormatSchemaDocument(doc)
+
+	return sb.String()
+}
+
+func (h *Handler) listNamespacesHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"policy":    h.schemas.namespaces.Policy,
+		"decisions": h.schemas.Decisions(),
+	})
+}
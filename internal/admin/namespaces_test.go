@@ -0,0 +1,147 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// gadgetSchema returns a schema with a type called name claiming the gdgt
+// namespace with prefix
+func gadgetSchema(name, prefix string) string {
+	return `type ` + name + ` implements Node @key(fields: "id") @prefixedID(prefix: "` + prefix + `") {
+	id: ID!
+}`
+}
+
+// namespaceServer serves the admin api and the graph of a schema store with
+// the given namespace config
+func namespaceServer(t *testing.T, cfg admin.NamespaceConfig) *echo.Echo {
+	t.Helper()
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema)
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+
+	h := admin.NewHandler(admin.Config{}, zap.NewNop().Sugar()).
+		WithSchemas(admin.NewSchemas(baseSchema, handler).WithNamespaces(cfg, nil))
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+	handler.Routes(e.Group(""))
+
+	return e
+}
+
+func push(e *echo.Echo, name, sdl string) int {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/schemas/"+name, strings.NewReader(sdl)))
+
+	return rec.Code
+}
+
+// typeOf returns the type id resolves to, or an empty string
+func typeOf(t *testing.T, e *echo.Echo, id string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query":"{ node(id: \"`+id+`\") { __typename } }"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var resp struct {
+		Data struct {
+			Node struct {
+				Typename string `json:"__typename"`
+			} `json:"node"`
+		} `json:"data"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	return resp.Data.Node.Typename
+}
+
+func TestNamespacesReject(t *testing.T) {
+	e := namespaceServer(t, admin.NamespaceConfig{
+		Policy: admin.ConflictReject,
+		Owners: map[string]string{"wdgt": "widgets"},
+	})
+
+	assert.Equal(t, http.StatusOK, push(e, "gadgets", gadgetSchema("Gadget", "gdgtabc")))
+	assert.Equal(t, http.StatusConflict, push(e, "others", gadgetSchema("Other", "gdgtxyz")))
+	assert.Equal(t, "Gadget", typeOf(t, e, "gdgtabc-1"))
+	assert.Empty(t, typeOf(t, e, "gdgtxyz-1"))
+
+	// owned namespaces can only be claimed by their owner
+	assert.Equal(t, http.StatusConflict, push(e, "gadgets", gadgetSchema("Gadget", "gdgtabc")+"\n"+gadgetSchema("Widget", "wdgtabc")))
+	assert.Equal(t, http.StatusOK, push(e, "widgets", gadgetSchema("Widget", "wdgtabc")))
+	assert.Equal(t, "Widget", typeOf(t, e, "wdgtabc-1"))
+}
+
+func TestNamespacesPreferSource(t *testing.T) {
+	e := namespaceServer(t, admin.NamespaceConfig{
+		Policy: admin.ConflictPreferSource,
+		Owners: map[string]string{"wdgt": "widgets"},
+	})
+
+	// without an owner the first source keeps the namespace
+	assert.Equal(t, http.StatusOK, push(e, "gadgets", gadgetSchema("Gadget", "gdgtabc")))
+	assert.Equal(t, http.StatusOK, push(e, "others", gadgetSchema("Other", "gdgtxyz")))
+	assert.Equal(t, "Gadget", typeOf(t, e, "gdgtabc-1"))
+	assert.Empty(t, typeOf(t, e, "gdgtxyz-1"))
+
+	// the owner keeps its namespace even when it pushed last
+	assert.Equal(t, http.StatusOK, push(e, "others", gadgetSchema("Other", "gdgtxyz")+"\n"+gadgetSchema("Fake", "wdgtabc")))
+	assert.Equal(t, http.StatusOK, push(e, "widgets", gadgetSchema("Widget", "wdgtabc")))
+	assert.Equal(t, "Widget", typeOf(t, e, "wdgtabc-1"))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/namespaces", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"policy": "prefer-source",
+		"decisions": [
+			{"namespace": "gdgt", "claimants": ["gadgets", "others"], "policy": "prefer-source", "winner": "gadgets"},
+			{"namespace": "wdgt", "owner": "widgets", "claimants": ["others", "widgets"], "policy": "prefer-source", "winner": "widgets"}
+		]
+	}`, rec.Body.String())
+}
+
+func TestNamespacesPreferNewest(t *testing.T) {
+	e := namespaceServer(t, admin.NamespaceConfig{Policy: admin.ConflictPreferNewest})
+
+	assert.Equal(t, http.StatusOK, push(e, "gadgets", gadgetSchema("Gadget", "gdgtabc")))
+	assert.Equal(t, http.StatusOK, push(e, "others", gadgetSchema("Other", "gdgtxyz")))
+	assert.Empty(t, typeOf(t, e, "gdgtabc-1"))
+	assert.Equal(t, "Other", typeOf(t, e, "gdgtxyz-1"))
+
+	// pushing an unchanged schema again doesn't make it newer
+	assert.Equal(t, http.StatusOK, push(e, "gadgets", gadgetSchema("Gadget", "gdgtabc")))
+	assert.Equal(t, "Other", typeOf(t, e, "gdgtxyz-1"))
+
+	assert.Equal(t, http.StatusOK, push(e, "gadgets", gadgetSchema("Gadget", "gdgtdef")))
+	assert.Equal(t, "Gadget", typeOf(t, e, "gdgtdef-1"))
+	assert.Empty(t, typeOf(t, e, "gdgtxyz-1"))
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	policy, err := admin.ParseConflictPolicy("prefer-newest")
+	require.NoError(t, err)
+	assert.Equal(t, admin.ConflictPreferNewest, policy)
+
+	_, err = admin.ParseConflictPolicy("prefer-oldest")
+	assert.ErrorIs(t, err, admin.ErrInvalidConflictPolicy)
+}
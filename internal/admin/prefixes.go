@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// PrefixMapInfo describes the prefixes being served and how they got there,
+// returned by GET /admin/prefixes
+type PrefixMapInfo struct {
+	Checksum string                `json:"checksum"`
+	Prefixes []graphapi.PrefixType `json:"prefixes"`
+	// Interfaces are the interfaces implemented by each type, by type name
+	Interfaces map[string][]string `json:"interfaces"`
+	// Source is where the base schema was loaded from, and LoadedAt when
+	// the schema being served was loaded
+	Source   string    `json:"source,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+	// History is the schemas served, most recent first
+	History []graphapi.SchemaVersion `json:"history"`
+	// UnknownPrefixes is the recent requests for ids with unknown prefixes,
+	// most recent first
+	UnknownPrefixes []graphapi.UnknownPrefixEvent `json:"unknown_prefixes"`
+	Canary          *graphapi.CanaryStatus        `json:"canary,omitempty"`
+	// SchemaAPI is set when schemas are pushed to /admin/schemas, and
+	// Graphs are the graphs that can be reloaded from /admin/graphs
+	SchemaAPI bool     `json:"schema_api"`
+	Graphs    []string `json:"graphs,omitempty"`
+}
+
+// WithSchemaSource reports source, describing where the base schema was
+// loaded from such as its files or url, from GET /admin/prefixes
+func (h *Handler) WithSchemaSource(source string) *Handler {
+	h.source = source
+
+	return h
+}
+
+// prefixMapHandler serves the prefix map of the running instance, so
+// operators looking into unknown prefix errors can see what it loaded
+func (h *Handler) prefixMapHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.prefixMap())
+}
+
+func (h *Handler) prefixMap() PrefixMapInfo {
+	r := h.resolver.Resolver()
+
+	info := PrefixMapInfo{
+		Checksum:        r.SDLChecksum(),
+		Prefixes:        r.Prefixes(),
+		Interfaces:      r.Interfaces(),
+		Source:          h.source,
+		LoadedAt:        r.LoadedAt(),
+		History:         h.resolver.History(),
+		UnknownPrefixes: r.UnknownPrefixes(),
+		SchemaAPI:       h.schemas != nil,
+	}
+
+	if info.UnknownPrefixes == nil {
+		info.UnknownPrefixes = []graphapi.UnknownPrefixEvent{}
+	}
+
+	if status, ok := h.resolver.Canary(); ok {
+		info.Canary = &status
+	}
+
+	if h.graphs != nil {
+		info.Graphs = h.graphs.Names()
+	}
+
+	return info
+}
@@ -0,0 +1,49 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestPrefixMap(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema)
+	require.NoError(t, err)
+
+	h := admin.NewHandler(admin.Config{Token: "secret"}, zap.NewNop().Sugar()).
+		WithResolverStats(graphapi.NewHandler(r)).
+		WithSchemaSource("files schema.graphql")
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/prefixes", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/prefixes", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var info admin.PrefixMapInfo
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, r.SDLChecksum(), info.Checksum)
+	assert.Equal(t, []graphapi.PrefixType{{Prefix: "testusr", Type: "User"}}, info.Prefixes)
+	assert.Equal(t, map[string][]string{"User": {"Node"}}, info.Interfaces)
+	assert.Equal(t, "files schema.graphql", info.Source)
+	assert.True(t, r.LoadedAt().Equal(info.LoadedAt))
+	assert.False(t, info.SchemaAPI)
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// SchemaLoader reads the schema from the source it was loaded from at
+// startup, such as its files or url
+type SchemaLoader func(ctx context.Context) (string, error)
+
+// Reloader serves a new schema, rejecting invalid schemas without changing
+// what's served
+type Reloader interface {
+	Reload(rawSchema string) error
+}
+
+// ReloadResult is returned by POST /admin/reload
+type ReloadResult struct {
+	// Checksum is the checksum of the schema served after the reload, and
+	// Changed is set when it's a different schema than before
+	Checksum string `json:"checksum"`
+	Changed  bool   `json:"changed"`
+	// Added are the prefixes served after the reload that weren't before,
+	// and Removed those no longer served. A prefix resolving to another
+	// type is both removed and added.
+	Added   []graphapi.PrefixType `json:"added"`
+	Removed []graphapi.PrefixType `json:"removed"`
+}
+
+// schemaReload reloads the schema from its source
+type schemaReload struct {
+	load     SchemaLoader
+	reloader Reloader
+	// mu serializes reloads, so the prefix diff of one isn't mixed up
+	// with another
+	mu sync.Mutex
+}
+
+// WithReload enables POST /admin/reload, which reads the schema with load
+// and serves it with reloader. The prefix map is reported from the resolver
+// given to WithResolverStats, which is required.
+func (h *Handler) WithReload(load SchemaLoader, reloader Reloader) *Handler {
+	h.reload = &schemaReload{load: load, reloader: reloader}
+
+	return h
+}
+
+// reloadHandler reloads the schema from its source, returning the prefixes
+// added and removed
+func (h *Handler) reloadHandler(c echo.Context) error {
+	h.reload.mu.Lock()
+	defer h.reload.mu.Unlock()
+
+	before := h.resolver.Resolver()
+
+	schema, err := h.reload.load(c.Request().Context())
+	if err != nil {
+		h.logger.Errorw("failed to load schema", "error", err)
+
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to load schema: "+err.Error())
+	}
+
+	if err := h.reload.reloader.Reload(schema); err != nil {
+		h.logger.Warnw("rejected reloaded schema", "error", err)
+
+		if errors.Is(err, ErrCanaryInProgress) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "invalid schema: "+err.Error())
+	}
+
+	after := h.resolver.Resolver()
+
+	result := ReloadResult{
+		Checksum: after.SDLChecksum(),
+		Changed:  after.SDLChecksum() != before.SDLChecksum(),
+		Added:    prefixDiff(after.Prefixes(), before.Prefixes()),
+		Removed:  prefixDiff(before.Prefixes(), after.Prefixes()),
+	}
+
+	h.logger.Infow("schema reloaded", "checksum", result.Checksum, "changed", result.Changed,
+		"added", len(result.Added), "removed", len(result.Removed))
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// prefixDiff returns the prefixes of a that aren't in b
+func prefixDiff(a, b []graphapi.PrefixType) []graphapi.PrefixType {
+	in := make(map[graphapi.PrefixType]bool, len(b))
+	for _, p := range b {
+		in[p] = true
+	}
+
+	diff := []graphapi.PrefixType{}
+
+	for _, p := range a {
+		if !in[p] {
+			diff = append(diff, p)
+		}
+	}
+
+	return diff
+}
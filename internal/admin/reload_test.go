@@ -0,0 +1,224 @@
+package admin_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestReload(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema)
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+
+	source := baseSchema
+	load := func(context.Context) (string, error) {
+		if source == "" {
+			return "", errors.New("file not found")
+		}
+
+		return source, nil
+	}
+
+	h := admin.NewHandler(admin.Config{Token: "secret"}, zap.NewNop().Sugar()).
+		WithResolverStats(handler).
+		WithReload(load, handler)
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+
+	reload := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := reload()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"checksum":"`+r.SDLChecksum()+`","changed":false,"added":[],"removed":[]}`, rec.Body.String())
+
+	// User moves to a new prefix and Widget is added
+	source = strings.Replace(baseSchema, "testusr", "testacc", 1) + "\n" + widgetSchema
+
+	rec = reload()
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result admin.ReloadResult
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.Changed)
+	assert.Equal(t, handler.Resolver().SDLChecksum(), result.Checksum)
+	assert.Equal(t, []graphapi.PrefixType{{Prefix: "testacc", Type: "User"}, {Prefix: "testwdg", Type: "Widget"}}, result.Added)
+	assert.Equal(t, []graphapi.PrefixType{{Prefix: "testusr", Type: "User"}}, result.Removed)
+
+	// invalid schemas and failed loads leave the schema being served alone
+	checksum := handler.Resolver().SDLChecksum()
+
+	source = "type {"
+	assert.Equal(t, http.StatusUnprocessableEntity, reload().Code)
+
+	source = ""
+	assert.Equal(t, http.StatusBadGateway, reload().Code)
+
+	assert.Equal(t, checksum, handler.Resolver().SDLChecksum())
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// natsMsg is a message published to the fake NATS server
+type natsMsg struct {
+	subject string
+	data    []byte
+}
+
+// newFakeNATS starts a server speaking enough of the NATS protocol for a
+// client to connect and publish, returning its url and the messages
+// published to it
+func newFakeNATS(t *testing.T) (string, <-chan natsMsg) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = ln.Close() })
+
+	msgs := make(chan natsMsg, 10)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveFakeNATS(conn, msgs)
+		}
+	}()
+
+	return "nats://" + ln.Addr().String(), msgs
+}
+
+func serveFakeNATS(conn net.Conn, msgs chan<- natsMsg) {
+	defer conn.Close()
+
+	_, _ = io.WriteString(conn, `INFO {"server_id":"test","version":"2.9.0","proto":1,"max_payload":1048576}`+"\r\n")
+
+	rd := bufio.NewReader(conn)
+
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			_, _ = io.WriteString(conn, "PONG\r\n")
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+
+			data := make([]byte, size+2) // the payload is followed by \r\n
+			if _, err := io.ReadFull(rd, data); err != nil {
+				return
+			}
+
+			msgs <- natsMsg{subject: fields[1], data: data[:size]}
+		}
+	}
+}
+
+func TestReloadPurgesCache(t *testing.T) {
+	url, msgs := newFakeNATS(t)
+
+	resultCache := cache.New(10, time.Minute)
+	resultCache.Set("a", "testusr-1", true)
+
+	invalidator, err := cache.NewInvalidator(cache.InvalidationConfig{
+		NATSURL: url,
+		Subject: "node-resolver.invalidate",
+	}, resultCache, "node-resolver-test", zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	defer invalidator.Close()
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema, graphapi.WithCacheInvalidator(invalidator))
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+
+	source := baseSchema
+	load := func(context.Context) (string, error) { return source, nil }
+
+	h := admin.NewHandler(admin.Config{Token: "secret"}, zap.NewNop().Sugar()).
+		WithResolverStats(handler).
+		WithReload(load, handler)
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+
+	reload := func() {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// reloading the same schema keeps the cached results
+	reload()
+	assert.Equal(t, 1, resultCache.Len())
+
+	source = strings.Replace(baseSchema, "testusr", "testacc", 1)
+	reload()
+
+	assert.Equal(t, 0, resultCache.Len())
+
+	select {
+	case msg := <-msgs:
+		assert.Equal(t, "node-resolver.invalidate", msg.subject)
+
+		var inv cache.Invalidation
+
+		require.NoError(t, json.Unmarshal(msg.data, &inv))
+		assert.True(t, inv.Purge)
+		assert.Empty(t, inv.IDs)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no purge was published")
+	}
+
+	select {
+	case msg := <-msgs:
+		t.Fatalf("unexpected message published: %s", msg.data)
+	default:
+	}
+}
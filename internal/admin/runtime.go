@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// maxGCPauses is the number of recent garbage collection pauses reported
+const maxGCPauses = 16
+
+// RuntimeInfo describes the runtime state of the process and the resolver
+// being served, returned by GET /debug/runtime
+type RuntimeInfo struct {
+	Goroutines int             `json:"goroutines"`
+	GOMAXPROCS int             `json:"gomaxprocs"`
+	Heap       HeapInfo        `json:"heap"`
+	GC         GCInfo          `json:"gc"`
+	Resolver   *graphapi.Stats `json:"resolver,omitempty"`
+}
+
+// HeapInfo describes the heap, in bytes
+type HeapInfo struct {
+	Alloc    uint64 `json:"alloc_bytes"`
+	InUse    uint64 `json:"in_use_bytes"`
+	Idle     uint64 `json:"idle_bytes"`
+	Released uint64 `json:"released_bytes"`
+	Sys      uint64 `json:"sys_bytes"`
+	Objects  uint64 `json:"objects"`
+}
+
+// GCInfo describes the garbage collector. Pauses are in nanoseconds and
+// RecentPauses are the latest pauses, most recent first.
+type GCInfo struct {
+	Cycles         uint32   `json:"cycles"`
+	NextGC         uint64   `json:"next_gc_bytes"`
+	PauseTotal     uint64   `json:"pause_total_ns"`
+	RecentPauses   []uint64 `json:"recent_pauses_ns"`
+	CPUFraction    float64  `json:"cpu_fraction"`
+	LastGCUnixNano uint64   `json:"last_gc_unix_ns"`
+}
+
+// WithResolverStats reports the stats of the resolver served by handler from
+// GET /debug/runtime
+func (h *Handler) WithResolverStats(handler *graphapi.Handler) *Handler {
+	h.resolver = handler
+
+	return h
+}
+
+// runtimeHandler reports the runtime state, so capacity issues can be
+// inspected without profiling tools. Reading the memory stats briefly stops
+// the world, so it shouldn't be polled frequently.
+func (h *Handler) runtimeHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.runtimeInfo())
+}
+
+func (h *Handler) runtimeInfo() RuntimeInfo {
+	var mem runtime.MemStats
+
+	runtime.ReadMemStats(&mem)
+
+	info := RuntimeInfo{
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Heap: HeapInfo{
+			Alloc:    mem.HeapAlloc,
+			InUse:    mem.HeapInuse,
+			Idle:     mem.HeapIdle,
+			Released: mem.HeapReleased,
+			Sys:      mem.HeapSys,
+			Objects:  mem.HeapObjects,
+		},
+		GC: GCInfo{
+			Cycles:         mem.NumGC,
+			NextGC:         mem.NextGC,
+			PauseTotal:     mem.PauseTotalNs,
+			RecentPauses:   recentPauses(&mem),
+			CPUFraction:    mem.GCCPUFraction,
+			LastGCUnixNano: mem.LastGC,
+		},
+	}
+
+	if h.resolver != nil {
+		stats := h.resolver.Resolver().Stats()
+		info.Resolver = &stats
+	}
+
+	return info
+}
+
+// recentPauses returns the latest garbage collection pauses from the
+// circular buffer in mem, most recent first
+func recentPauses(mem *runtime.MemStats) []uint64 {
+	n := int(mem.NumGC)
+	if n > maxGCPauses {
+		n = maxGCPauses
+	}
+
+	pauses := make([]uint64, n)
+
+	for i := range pauses {
+		pauses[i] = mem.PauseNs[(int(mem.NumGC)-1-i+len(mem.PauseNs))%len(mem.PauseNs)]
+	}
+
+	return pauses
+}
@@ -0,0 +1,48 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestRuntime(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema, graphapi.WithAdaptiveEntityConcurrency(4, 16))
+	require.NoError(t, err)
+
+	h := admin.NewHandler(admin.Config{Token: "secret"}, zap.NewNop().Sugar()).
+		WithResolverStats(graphapi.NewHandler(r))
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var info admin.RuntimeInfo
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Positive(t, info.Goroutines)
+	assert.Positive(t, info.Heap.Alloc)
+	assert.LessOrEqual(t, len(info.GC.RecentPauses), int(info.GC.Cycles))
+
+	require.NotNil(t, info.Resolver)
+	assert.Equal(t, &graphapi.EntityPoolStats{MinWorkers: 4, MaxWorkers: 16, Workers: 4}, info.Resolver.EntityPool)
+}
@@ -0,0 +1,367 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+var schemaNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,62}$`)
+
+// ValidSchemaName returns true when name can be used for a pushed schema
+func ValidSchemaName(name string) bool {
+	return schemaNameRegexp.MatchString(name) && name != BaseSource
+}
+
+// SchemaInfo describes a schema pushed to the admin schema api
+type SchemaInfo struct {
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+}
+
+// Schemas merges schemas pushed by subgraphs with the base schema and serves
+// the result, so subgraphs can maintain their own types. Pushed schemas are
+// kept in memory; agents are expected to push again periodically.
+//
+// With namespaces enabled each schema claims the prefix namespaces of its
+// types. Namespaces claimed by several schemas, or by a schema other than
+// their configured owner, are decided by the conflict policy: the schema is
+// rejected, or only one source keeps the namespace and the types of the
+// others in it aren't resolvable.
+type Schemas struct {
+	mu      sync.Mutex
+	base    string
+	pushed  map[string]string
+	handler *graphapi.Handler
+
+	namespaces NamespaceConfig
+	metrics    metrics.Sink
+	// changed is when each pushed schema last changed, counted by seq
+	changed   map[string]uint64
+	seq       uint64
+	decisions map[string]NamespaceDecision
+
+	// canary is the push being rolled out to a percentage of clients,
+	// applied once it's promoted
+	canary *version
+}
+
+// version is the merged schema of a set of pushed schemas, built before
+// it's served
+type version struct {
+	// name is the schema pushed to build the version
+	name      string
+	pushed    map[string]string
+	changed   map[string]uint64
+	decisions map[string]NamespaceDecision
+	resolver  *graphapi.Resolver
+}
+
+// NewSchemas returns a schema store serving its merged schema with handler
+func NewSchemas(base string, handler *graphapi.Handler) *Schemas {
+	return &Schemas{
+		base:    base,
+		pushed:  map[string]string{},
+		handler: handler,
+		changed: map[string]uint64{},
+	}
+}
+
+// WithSchemas enables the admin schema api
+func (h *Handler) WithSchemas(s *Schemas) *Handler {
+	h.schemas = s
+
+	return h
+}
+
+// Put adds or replaces the named schema. The merged schema is validated
+// before it's served; invalid schemas are rejected and nothing changes.
+func (s *Schemas) Put(name, sdl string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.canary != nil {
+		// schemas pushed again unchanged, as the sync sidecar does, are
+		// accepted without a change
+		if current, ok := s.pushed[name]; ok && current == sdl {
+			return nil
+		}
+
+		if canary, ok := s.canary.pushed[name]; ok && canary == sdl {
+			return nil
+		}
+
+		return ErrCanaryInProgress
+	}
+
+	v, err := s.build(s.withSchema(name, sdl))
+	if err != nil {
+		return err
+	}
+
+	s.handler.Swap(v.resolver)
+	s.commit(v)
+
+	return nil
+}
+
+// SetBase replaces the base schema the pushed schemas are merged with. The
+// merged schema is validated before it's served; an invalid base schema is
+// rejected and nothing changes.
+func (s *Schemas) SetBase(sdl string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sdl == s.base {
+		return nil
+	}
+
+	if s.canary != nil {
+		return ErrCanaryInProgress
+	}
+
+	prev := s.base
+	s.base = sdl
+
+	v, err := s.build(BaseSource, s.pushed, nil)
+	if err != nil {
+		s.base = prev
+
+		return err
+	}
+
+	s.handler.Swap(v.resolver)
+	s.commit(v)
+
+	return nil
+}
+
+// withSchema returns the pushed schemas with the named schema replaced by sdl
+// and when it last changed
+func (s *Schemas) withSchema(name, sdl string) (string, map[string]string, map[string]uint64) {
+	pushed := make(map[string]string, len(s.pushed)+1)
+	for k, v := range s.pushed {
+		pushed[k] = v
+	}
+
+	pushed[name] = sdl
+
+	changed := s.changed[name]
+	if s.pushed[name] != sdl {
+		changed = s.seq + 1
+	}
+
+	return name, pushed, map[string]uint64{name: changed}
+}
+
+// Delete removes the named schema, returning false when it doesn't exist
+func (s *Schemas) Delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pushed[name]; !ok {
+		return false, nil
+	}
+
+	if s.canary != nil {
+		return true, ErrCanaryInProgress
+	}
+
+	pushed := make(map[string]string, len(s.pushed))
+
+	for k, v := range s.pushed {
+		if k != name {
+			pushed[k] = v
+		}
+	}
+
+	v, err := s.build(name, pushed, nil)
+	if err != nil {
+		return true, err
+	}
+
+	s.handler.Swap(v.resolver)
+	s.commit(v)
+
+	delete(s.changed, name)
+
+	return true, nil
+}
+
+// List returns the pushed schemas sorted by name
+func (s *Schemas) List() []SchemaInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]SchemaInfo, 0, len(s.pushed))
+	for name, sdl := range s.pushed {
+		infos = append(infos, SchemaInfo{Name: name, Checksum: Checksum(sdl)})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	return infos
+}
+
+// build returns the version merging the base schema with pushed, resolving
+// namespace conflicts. changed overrides when pushed schemas last changed.
+func (s *Schemas) build(name string, pushed map[string]string, changed map[string]uint64) (*version, error) {
+	names := make([]string, 0, len(pushed))
+	for name := range pushed {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	sources := []*source{{name: BaseSource, sdl: s.base}}
+
+	for _, name := range names {
+		order, ok := changed[name]
+		if !ok {
+			order = s.changed[name]
+		}
+
+		sources = append(sources, &source{name: name, sdl: pushed[name], order: order})
+	}
+
+	parts := make([]string, len(sources))
+	for i, src := range sources {
+		parts[i] = src.sdl
+	}
+
+	var decisions map[string]NamespaceDecision
+
+	if s.namespaces.Enabled() {
+		var err error
+
+		parts, decisions, err = s.resolveNamespaces(sources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := s.handler.Resolver().WithSchema(strings.Join(parts, "\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &version{name: name, pushed: pushed, changed: changed, decisions: decisions, resolver: r}, nil
+}
+
+// commit records v as the served version once its resolver is served
+func (s *Schemas) commit(v *version) {
+	s.pushed = v.pushed
+
+	for name, changed := range v.changed {
+		s.changed[name] = changed
+
+		if changed > s.seq {
+			s.seq = changed
+		}
+	}
+
+	// decisions are counted when they change rather than on every push
+	for ns, d := range v.decisions {
+		if prev, ok := s.decisions[ns]; !ok || prev.Winner != d.Winner {
+			s.recordNamespace(ns, NamespaceResolved)
+		}
+	}
+
+	s.decisions = v.decisions
+}
+
+// Checksum returns the checksum used to identify a pushed schema
+func Checksum(sdl string) string {
+	sum := sha256.Sum256([]byte(sdl))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (h *Handler) schemaRoutes(g *echo.Group) {
+	g.GET("/schemas", h.listSchemasHandler)
+	g.PUT("/schemas/:name", h.putSchemaHandler)
+	g.DELETE("/schemas/:name", h.deleteSchemaHandler)
+	g.GET("/namespaces", h.listNamespacesHandler)
+
+	h.canaryRoutes(g)
+}
+
+func (h *Handler) listSchemasHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"schemas": h.schemas.List(),
+	})
+}
+
+// putSchemaHandler replaces the named schema with the sdl in the request body
+func (h *Handler) putSchemaHandler(c echo.Context) error {
+	name := c.Param("name")
+	if !ValidSchemaName(name) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid schema name")
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read schema").SetInternal(err)
+	}
+
+	put := h.schemas.Put
+
+	if raw := c.QueryParam("canary"); raw != "" {
+		percent, err := canaryPercent(raw)
+		if err != nil {
+			return err
+		}
+
+		put = func(name, sdl string) error { return h.schemas.PutCanary(name, sdl, percent) }
+	}
+
+	if err := put(name, string(body)); err != nil {
+		h.logger.Warnw("rejected pushed schema", "name", name, "error", err)
+
+		if errors.Is(err, ErrNamespaceConflict) || errors.Is(err, ErrCanaryInProgress) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "invalid schema: "+err.Error())
+	}
+
+	if raw := c.QueryParam("canary"); raw != "" {
+		h.logger.Infow("schema canary started", "name", name, "checksum", Checksum(string(body)), "percent", raw)
+	} else {
+		h.logger.Infow("schema updated", "name", name, "checksum", Checksum(string(body)))
+	}
+
+	return c.JSON(http.StatusOK, SchemaInfo{Name: name, Checksum: Checksum(string(body))})
+}
+
+func (h *Handler) deleteSchemaHandler(c echo.Context) error {
+	name := c.Param("name")
+
+	ok, err := h.schemas.Delete(name)
+	if errors.Is(err, ErrCanaryInProgress) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "invalid schema: "+err.Error())
+	}
+
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "schema not found")
+	}
+
+	h.logger.Infow("schema removed", "name", name)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+const baseSchema = `directive @prefixedID(prefix: String!) on OBJECT
+
+type User implements Node @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+const widgetSchema = `type Widget implements Node @key(fields: "id") @prefixedID(prefix: "testwdg") {
+	id: ID!
+}`
+
+func TestSchemas(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema)
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+
+	h := admin.NewHandler(admin.Config{Token: "secret"}, zap.NewNop().Sugar()).
+		WithSchemas(admin.NewSchemas(baseSchema, handler))
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	nodeType := func(id string) string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query":"{ node(id: \"`+id+`\") { __typename } }"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		e := echo.New()
+		handler.Routes(e.Group(""))
+		e.ServeHTTP(rec, req)
+
+		var resp struct {
+			Data struct {
+				Node struct {
+					Typename string `json:"__typename"`
+				} `json:"node"`
+			} `json:"data"`
+		}
+
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+		return resp.Data.Node.Typename
+	}
+
+	assert.Empty(t, nodeType("testwdg-abc"))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/schemas/widgets", strings.NewReader(widgetSchema)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = serve(http.MethodPut, "/admin/schemas/widgets", widgetSchema)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Widget", nodeType("testwdg-abc"))
+
+	rec = serve(http.MethodPut, "/admin/schemas/broken", "type Broken {")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "Widget", nodeType("testwdg-abc"), "rejected schemas must not replace the served schema")
+
+	rec = serve(http.MethodPut, "/admin/schemas/..%2Fdrain", widgetSchema)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodGet, "/admin/schemas", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"schemas":[{"name":"widgets","checksum":"`+admin.Checksum(widgetSchema)+`"}]}`, rec.Body.String())
+
+	rec = serve(http.MethodDelete, "/admin/schemas/widgets", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, nodeType("testwdg-abc"))
+	assert.Equal(t, "User", nodeType("testusr-abc"))
+
+	rec = serve(http.MethodDelete, "/admin/schemas/widgets", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSchemasSetBase(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema)
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+	schemas := admin.NewSchemas(baseSchema, handler)
+
+	h := admin.NewHandler(admin.Config{}, zap.NewNop().Sugar()).WithSchemas(schemas)
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+	handler.Routes(e.Group(""))
+
+	require.Equal(t, http.StatusOK, push(e, "widgets", widgetSchema))
+
+	// pushed schemas are kept when the base schema changes
+	require.NoError(t, schemas.SetBase(strings.Replace(baseSchema, "testusr", "testacc", 1)))
+	assert.Equal(t, "User", typeOf(t, e, "testacc-abc"))
+	assert.Empty(t, typeOf(t, e, "testusr-abc"))
+	assert.Equal(t, "Widget", typeOf(t, e, "testwdg-abc"))
+
+	// invalid base schemas are rejected without changing what's served
+	assert.Error(t, schemas.SetBase("type Broken {"))
+	assert.Equal(t, "User", typeOf(t, e, "testacc-abc"))
+	assert.Equal(t, "Widget", typeOf(t, e, "testwdg-abc"))
+}
@@ -0,0 +1,38 @@
+package admin
+
+import (
+	_ "embed" // embeds the admin ui page
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed ui/index.html
+var uiPage []byte
+
+func (h *Handler) uiRoutes(e *echo.Group) {
+	e.GET("/admin/ui", h.uiHandler, h.challenge, h.authenticate)
+}
+
+// uiHandler serves the admin ui, a page showing the prefix map that reloads
+// schemas with the admin endpoints
+func (h *Handler) uiHandler(c echo.Context) error {
+	c.Response().Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+
+	return c.Blob(http.StatusOK, echo.MIMETextHTMLCharsetUTF8, uiPage)
+}
+
+// challenge asks browsers for the admin token when a request is
+// unauthorized. Browsers send the token as the basic auth password with the
+// requests the page makes.
+func (h *Handler) challenge(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		if errors.Is(err, echo.ErrUnauthorized) {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="node-resolver admin"`)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,98 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestUI(t *testing.T) {
+	log := graphapi.NewUnknownPrefixLog(graphapi.DefaultUnknownPrefixLogSize)
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema, graphapi.WithUnknownPrefixLog(log))
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+
+	h := admin.NewHandler(admin.Config{Token: "secret", UI: true}, zap.NewNop().Sugar()).
+		WithResolverStats(handler).
+		WithSchemas(admin.NewSchemas(baseSchema, handler))
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+	handler.Routes(e.Group(""))
+
+	// browsers are asked for the token
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/ui", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderWWWAuthenticate), "Basic")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/ui", nil)
+	req.SetBasicAuth("admin", "secret")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/admin/prefixes")
+
+	assert.Empty(t, typeOf(t, e, "unknown-abc"))
+
+	req = httptest.NewRequest(http.MethodPut, "/admin/schemas/widgets", strings.NewReader(widgetSchema))
+	req.SetBasicAuth("admin", "secret")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/prefixes", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var info admin.PrefixMapInfo
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, handler.Resolver().SDLChecksum(), info.Checksum)
+	assert.Equal(t, []graphapi.PrefixType{{Prefix: "testusr", Type: "User"}, {Prefix: "testwdg", Type: "Widget"}}, info.Prefixes)
+	assert.True(t, info.SchemaAPI)
+	assert.Nil(t, info.Canary)
+
+	require.Len(t, info.History, 2)
+	assert.Equal(t, info.Checksum, info.History[0].Checksum)
+	assert.Equal(t, r.SDLChecksum(), info.History[1].Checksum)
+
+	require.Len(t, info.UnknownPrefixes, 1)
+	assert.Equal(t, "unknown", info.UnknownPrefixes[0].Prefix)
+}
+
+func TestUIDisabled(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema)
+	require.NoError(t, err)
+
+	h := admin.NewHandler(admin.Config{}, zap.NewNop().Sugar()).WithResolverStats(graphapi.NewHandler(r))
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/ui", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// the prefix map is served without the ui
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/prefixes", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
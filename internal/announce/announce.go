@@ -0,0 +1,208 @@
+// Package announce incorporates the prefixed id types that subgraph services
+// announce over NATS into the served schema, so the resolver learns new
+// prefixes at runtime rather than only from its schema file.
+package announce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+)
+
+// ErrInvalidAnnouncement is returned for announcements that can't be
+// incorporated
+var ErrInvalidAnnouncement = errors.New("invalid announcement")
+
+var graphqlNameRegexp = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// Announcement is the message a subgraph service publishes with its prefixed
+// id types, either as sdl or as a list of types. Each announcement replaces
+// the service's previous one; Removed withdraws its types.
+type Announcement struct {
+	// Service names the announcing service, defaulting to the last token of
+	// the subject the announcement was published on
+	Service string `json:"service"`
+	SDL     string `json:"sdl,omitempty"`
+	Types   []Type `json:"types,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+}
+
+// Type is a prefixed id type in an announcement. Types always implement
+// Node, along with any other interfaces given.
+type Type struct {
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Interfaces []string `json:"interfaces,omitempty"`
+}
+
+// Registry stores the schemas of the announcing services, merged with the
+// served schema. It's implemented by admin.Schemas.
+type Registry interface {
+	Put(name, sdl string) error
+	Delete(name string) (bool, error)
+}
+
+// Subscriber applies the announcements published on the configured subject
+// to a Registry
+type Subscriber struct {
+	registry Registry
+	logger   *zap.SugaredLogger
+	conn     *nats.Conn
+	sub      *nats.Subscription
+	// applied is the sdl last applied for each service, so services can
+	// announce periodically without rebuilding the schema. Messages of a
+	// subscription are handled one at a time, so it isn't locked.
+	applied map[string]string
+}
+
+// NewSubscriber connects to NATS and subscribes to announcements
+func NewSubscriber(cfg Config, registry Registry, appName string, logger *zap.SugaredLogger) (*Subscriber, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	opts := []nats.Option{nats.Name(appName), nats.Timeout(cfg.Timeout)}
+
+	switch {
+	case cfg.NATSCredsFile != "":
+		opts = append(opts, nats.UserCredentials(cfg.NATSCredsFile))
+	case cfg.NATSToken != "":
+		opts = append(opts, nats.Token(cfg.NATSToken))
+	}
+
+	conn, err := nats.Connect(cfg.NATSURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Subscriber{
+		registry: registry,
+		logger:   logger,
+		conn:     conn,
+		applied:  map[string]string{},
+	}
+
+	s.sub, err = conn.Subscribe(cfg.Subject, s.handleAnnouncement)
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close unsubscribes and closes the NATS connection
+func (s *Subscriber) Close() {
+	_ = s.sub.Unsubscribe()
+
+	s.conn.Close()
+}
+
+func (s *Subscriber) handleAnnouncement(msg *nats.Msg) {
+	var a Announcement
+	if err := json.Unmarshal(msg.Data, &a); err != nil {
+		s.logger.Warnw("ignoring invalid announcement", "subject", msg.Subject, "error", err)
+
+		return
+	}
+
+	if a.Service == "" {
+		a.Service = msg.Subject[strings.LastIndexByte(msg.Subject, '.')+1:]
+	}
+
+	if err := s.apply(a); err != nil {
+		s.logger.Warnw("rejected announcement", "subject", msg.Subject, "service", a.Service, "error", err)
+	}
+}
+
+// apply replaces the schema of the announcing service, or removes it
+func (s *Subscriber) apply(a Announcement) error {
+	if !admin.ValidSchemaName(a.Service) {
+		return fmt.Errorf("%w: invalid service name", ErrInvalidAnnouncement)
+	}
+
+	if a.Removed {
+		ok, err := s.registry.Delete(a.Service)
+		if err != nil {
+			return err
+		}
+
+		delete(s.applied, a.Service)
+
+		if !ok {
+			return nil
+		}
+
+		s.logger.Infow("service types withdrawn", "service", a.Service)
+
+		return nil
+	}
+
+	sdl, err := a.schema()
+	if err != nil {
+		return err
+	}
+
+	if s.applied[a.Service] == sdl {
+		return nil
+	}
+
+	if err := s.registry.Put(a.Service, sdl); err != nil {
+		return err
+	}
+
+	s.applied[a.Service] = sdl
+
+	s.logger.Infow("service types announced", "service", a.Service, "checksum", admin.Checksum(sdl))
+
+	return nil
+}
+
+// schema returns the sdl of the announced types
+func (a Announcement) schema() (string, error) {
+	switch {
+	case a.SDL != "" && len(a.Types) != 0:
+		return "", fmt.Errorf("%w: only one of sdl and types can be given", ErrInvalidAnnouncement)
+	case a.SDL != "":
+		return a.SDL, nil
+	case len(a.Types) == 0:
+		return "", fmt.Errorf("%w: no types announced", ErrInvalidAnnouncement)
+	}
+
+	var sb strings.Builder
+
+	for _, t := range a.Types {
+		if !graphqlNameRegexp.MatchString(t.Name) {
+			return "", fmt.Errorf("%w: invalid type name %q", ErrInvalidAnnouncement, t.Name)
+		}
+
+		if t.Prefix == "" {
+			return "", fmt.Errorf("%w: type %s has no prefix", ErrInvalidAnnouncement, t.Name)
+		}
+
+		ifaces := []string{"Node"}
+
+		for _, iface := range t.Interfaces {
+			if !graphqlNameRegexp.MatchString(iface) {
+				return "", fmt.Errorf("%w: invalid interface name %q", ErrInvalidAnnouncement, iface)
+			}
+
+			if iface != "Node" {
+				ifaces = append(ifaces, iface)
+			}
+		}
+
+		fmt.Fprintf(&sb, "type %s implements %s @key(fields: \"id\") @prefixedID(prefix: %q) {\n  id: ID!\n}\n",
+			t.Name, strings.Join(ifaces, " & "), t.Prefix)
+	}
+
+	return sb.String(), nil
+}
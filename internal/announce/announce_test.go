@@ -0,0 +1,117 @@
+package announce
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+const baseSchema = `directive @prefixedID(prefix: String!) on OBJECT
+type User implements Node @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+interface Actor @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+type countingRegistry struct {
+	Registry
+	puts int
+}
+
+func (r *countingRegistry) Put(name, sdl string) error {
+	r.puts++
+
+	return r.Registry.Put(name, sdl)
+}
+
+func newTestSubscriber(t *testing.T) (*Subscriber, *graphapi.Handler, *countingRegistry) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), baseSchema)
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+	registry := &countingRegistry{Registry: admin.NewSchemas(baseSchema, handler)}
+
+	return &Subscriber{registry: registry, logger: zap.NewNop().Sugar(), applied: map[string]string{}}, handler, registry
+}
+
+func TestHandleAnnouncement(t *testing.T) {
+	s, handler, registry := newTestSubscriber(t)
+
+	prefixes := func() []graphapi.PrefixType { return handler.Resolver().Prefixes() }
+
+	s.handleAnnouncement(&nats.Msg{
+		Subject: "com.infratographer.schema.widgets",
+		Data:    []byte(`{"types":[{"name":"Widget","prefix":"testwdg","interfaces":["Actor"]}]}`),
+	})
+
+	assert.Equal(t, []graphapi.PrefixType{{Prefix: "testusr", Type: "User"}, {Prefix: "testwdg", Type: "Widget"}}, prefixes())
+	assert.Equal(t, []admin.SchemaInfo{{Name: "widgets", Checksum: admin.Checksum(s.applied["widgets"])}}, registry.Registry.(*admin.Schemas).List())
+
+	// announcing the same types again doesn't rebuild the schema
+	s.handleAnnouncement(&nats.Msg{
+		Subject: "com.infratographer.schema.widgets",
+		Data:    []byte(`{"types":[{"name":"Widget","prefix":"testwdg","interfaces":["Actor"]}]}`),
+	})
+	assert.Equal(t, 1, registry.puts)
+
+	s.handleAnnouncement(&nats.Msg{
+		Subject: "com.infratographer.schema.announce",
+		Data:    []byte(`{"service":"gadgets","sdl":"type Gadget implements Node @prefixedID(prefix: \"testgdg\") { id: ID! }"}`),
+	})
+
+	assert.Len(t, prefixes(), 3)
+
+	s.handleAnnouncement(&nats.Msg{Subject: "com.infratographer.schema.widgets", Data: []byte(`{"removed":true}`)})
+
+	assert.Equal(t, []graphapi.PrefixType{{Prefix: "testgdg", Type: "Gadget"}, {Prefix: "testusr", Type: "User"}}, prefixes())
+}
+
+func TestHandleInvalidAnnouncement(t *testing.T) {
+	s, handler, _ := newTestSubscriber(t)
+
+	for _, data := range []string{
+		`not json`,
+		`{"types":[]}`,
+		`{"types":[{"name":"Widget { id: ID! } type Other","prefix":"testwdg"}]}`,
+		`{"types":[{"name":"Widget"}]}`,
+		`{"sdl":"type Widget","types":[{"name":"Widget","prefix":"testwdg"}]}`,
+		`{"service":"-bad","types":[{"name":"Widget","prefix":"testwdg"}]}`,
+	} {
+		s.handleAnnouncement(&nats.Msg{Subject: "com.infratographer.schema.widgets", Data: []byte(data)})
+	}
+
+	assert.Len(t, handler.Resolver().Prefixes(), 1)
+	assert.Empty(t, s.applied)
+}
+
+func TestAnnouncementSchema(t *testing.T) {
+	sdl, err := Announcement{Types: []Type{
+		{Name: "Widget", Prefix: "testwdg"},
+		{Name: "Gadget", Prefix: "testgdg", Interfaces: []string{"Node", "Actor"}},
+	}}.schema()
+	require.NoError(t, err)
+
+	assert.Equal(t, `type Widget implements Node @key(fields: "id") @prefixedID(prefix: "testwdg") {
+  id: ID!
+}
+type Gadget implements Node & Actor @key(fields: "id") @prefixedID(prefix: "testgdg") {
+  id: ID!
+}
+`, sdl)
+
+	_, err = Announcement{}.schema()
+	assert.ErrorIs(t, err, ErrInvalidAnnouncement)
+}
@@ -0,0 +1,41 @@
+package announce
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 5 * time.Second
+
+// Config stores the settings for incorporating the types subgraph services
+// announce over NATS
+type Config struct {
+	NATSURL       string        `mapstructure:"nats-url"`
+	NATSToken     string        `mapstructure:"nats-token"`
+	NATSCredsFile string        `mapstructure:"nats-creds-file"`
+	Subject       string        `mapstructure:"subject"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+}
+
+// Enabled returns true when a NATS server has been configured
+func (c Config) Enabled() bool {
+	return c.NATSURL != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("announce-nats-url", "", "nats server subgraph services announce their prefixed id types on")
+	viperx.MustBindFlag(v, "announce.nats-url", flags.Lookup("announce-nats-url"))
+
+	flags.String("announce-subject", "com.infratographer.schema.>", "nats subject subgraph services announce their prefixed id types on")
+	viperx.MustBindFlag(v, "announce.subject", flags.Lookup("announce-subject"))
+
+	v.MustBindEnv("announce.nats-token")
+	v.MustBindEnv("announce.nats-creds-file")
+	v.MustBindEnv("announce.timeout")
+
+	v.SetDefault("announce.timeout", defaultTimeout)
+}
@@ -0,0 +1,213 @@
+// Package audit records the ids resolved by node-resolver and who resolved
+// them, emitting the records to the configured sinks
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+// Outcome describes the result of resolving an id. Failures use the error
+// code of the failure where there is one.
+type Outcome string
+
+const (
+	// OutcomeResolved is recorded when the id was resolved to a type
+	OutcomeResolved Outcome = "resolved"
+	// OutcomeInvalidID is recorded when the id couldn't be parsed
+	OutcomeInvalidID = Outcome(errcode.InvalidID)
+	// OutcomeUnknownPrefix is recorded when the id prefix isn't in the schema
+	OutcomeUnknownPrefix = Outcome(errcode.UnknownPrefix)
+	// OutcomeUnauthorized is recorded when the subject isn't allowed to resolve the id
+	OutcomeUnauthorized = Outcome(errcode.Unauthorized)
+	// OutcomeDeleted is recorded when the id is of a deleted node
+	OutcomeDeleted = Outcome(errcode.Deleted)
+	// OutcomeNotFound is recorded when the node of the id doesn't exist
+	OutcomeNotFound = Outcome(errcode.NotFound)
+	// OutcomeFailed is recorded when the id couldn't be resolved for any other reason
+	OutcomeFailed Outcome = "failed"
+)
+
+const defaultBufferSize = 1024
+
+// ErrMissingDatabase is returned when the CRDB sink is enabled without a database
+var ErrMissingDatabase = errors.New("crdb audit sink enabled without a database")
+
+// Record is a single resolution of an id
+type Record struct {
+	Time      time.Time `json:"time"`
+	Subject   string    `json:"subject,omitempty"`
+	Operation string    `json:"operation"`
+	ID        string    `json:"id"`
+	Prefix    string    `json:"prefix,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	Outcome   Outcome   `json:"outcome"`
+}
+
+// Sink receives audit records
+type Sink interface {
+	Emit(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// Auditor buffers records and emits them to its sinks in the background so
+// auditing doesn't add latency to resolution
+type Auditor struct {
+	logger  *zap.SugaredLogger
+	sinks   []Sink
+	records chan Record
+	done    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// New returns an Auditor emitting to the given sinks. Start must be called to
+// begin emitting records.
+func New(logger *zap.SugaredLogger, sinks ...Sink) *Auditor {
+	return &Auditor{
+		logger:  logger,
+		sinks:   sinks,
+		records: make(chan Record, defaultBufferSize),
+		done:    make(chan struct{}),
+	}
+}
+
+// Record queues a record for emission. Records are dropped, with a warning,
+// when the buffer is full rather than blocking the request.
+func (a *Auditor) Record(rec Record) {
+	if a == nil {
+		return
+	}
+
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+
+	select {
+	case a.records <- rec:
+	default:
+		a.logger.Warnw("audit buffer full, dropping record", "id", rec.ID, "outcome", rec.Outcome)
+	}
+}
+
+// Start emits queued records in the background until ctx is canceled or
+// Close is called
+func (a *Auditor) Start(ctx context.Context) {
+	a.wg.Add(1)
+
+	go func() {
+		defer a.wg.Done()
+
+		a.run(ctx)
+	}()
+}
+
+func (a *Auditor) run(ctx context.Context) {
+	for {
+		select {
+		case rec := <-a.records:
+			a.emit(ctx, a.drain(rec))
+		case <-ctx.Done():
+			a.flush()
+			return
+		case <-a.done:
+			a.flush()
+			return
+		}
+	}
+}
+
+// drain collects rec and any other records already queued into one batch
+func (a *Auditor) drain(rec Record) []Record {
+	batch := []Record{rec}
+
+	for {
+		select {
+		case r := <-a.records:
+			batch = append(batch, r)
+		default:
+			return batch
+		}
+	}
+}
+
+func (a *Auditor) flush() {
+	select {
+	case rec := <-a.records:
+		a.emit(context.Background(), a.drain(rec))
+	default:
+	}
+}
+
+func (a *Auditor) emit(ctx context.Context, batch []Record) {
+	for _, s := range a.sinks {
+		if err := s.Emit(ctx, batch); err != nil {
+			a.logger.Errorw("failed to emit audit records", "error", err, "records", len(batch))
+		}
+	}
+}
+
+// Close stops emitting, waiting for buffered records to be emitted, and closes the sinks
+func (a *Auditor) Close() error {
+	a.once.Do(func() { close(a.done) })
+
+	a.wg.Wait()
+
+	for _, s := range a.sinks {
+		if err := s.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewFromConfig returns an Auditor emitting to the sinks enabled in the config,
+// or nil when auditing is disabled, which enabling the log sink also does. db
+// is only used by the CRDB sink and may be nil when it's disabled.
+func NewFromConfig(ctx context.Context, cfg Config, db *sql.DB, logger *zap.SugaredLogger) (*Auditor, error) {
+	if !cfg.Auditing() {
+		return nil, nil
+	}
+
+	sinks := []Sink{}
+
+	if cfg.Log {
+		sinks = append(sinks, NewLogSink(logger.Named("log")))
+	}
+
+	if cfg.CRDB.Enabled {
+		if db == nil {
+			return nil, ErrMissingDatabase
+		}
+
+		s, err := NewCRDBSink(ctx, cfg.CRDB, db, logger.Named("crdb"))
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, s)
+	}
+
+	if cfg.CloudEvents.Enabled() {
+		s, err := NewCloudEventsSink(cfg.CloudEvents)
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, s)
+	}
+
+	if len(sinks) == 0 {
+		logger.Warn("auditing enabled without any sinks configured")
+	}
+
+	return New(logger, sinks...), nil
+}
@@ -0,0 +1,162 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"go.infratographer.com/node-resolver/internal/audit"
+)
+
+func TestCloudEventsHTTP(t *testing.T) {
+	testCases := []struct {
+		TestName    string
+		batch       bool
+		contentType string
+		requests    int
+	}{
+		{
+			TestName:    "structured mode",
+			contentType: "application/cloudevents+json",
+			requests:    2,
+		},
+		{
+			TestName:    "batched mode",
+			batch:       true,
+			contentType: "application/cloudevents-batch+json",
+			requests:    1,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				requests int
+				events   []audit.CloudEvent
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				requests++
+
+				assert.Equal(t, tt.contentType, r.Header.Get("Content-Type"))
+
+				if tt.batch {
+					var batch []audit.CloudEvent
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+
+					events = append(events, batch...)
+				} else {
+					var e audit.CloudEvent
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+
+					events = append(events, e)
+				}
+
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			sink, err := audit.NewCloudEventsSink(audit.CloudEventsConfig{
+				Source:    "node-resolver-test",
+				HTTPURL:   srv.URL,
+				HTTPBatch: tt.batch,
+				Timeout:   time.Second,
+			})
+			require.NoError(t, err)
+
+			err = sink.Emit(context.Background(), []audit.Record{
+				{Subject: "idntusr-abc", Operation: "node", ID: "testsrv-123", Prefix: "testsrv", Type: "Server", Outcome: audit.OutcomeResolved},
+				{Operation: "node", ID: "unknown-123", Prefix: "unknown", Outcome: audit.OutcomeUnknownPrefix},
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.requests, requests)
+			require.Len(t, events, 2)
+
+			assert.Equal(t, "1.0", events[0].SpecVersion)
+			assert.Equal(t, audit.CloudEventType, events[0].Type)
+			assert.Equal(t, "node-resolver-test", events[0].Source)
+			assert.Equal(t, "testsrv-123", events[0].Subject)
+			assert.Equal(t, "idntusr-abc", events[0].Data.Subject)
+			assert.Equal(t, audit.OutcomeUnknownPrefix, events[1].Data.Outcome)
+			assert.NotEqual(t, events[0].ID, events[1].ID)
+		})
+	}
+}
+
+type memorySink struct {
+	mu      sync.Mutex
+	records []audit.Record
+}
+
+func (s *memorySink) Emit(_ context.Context, records []audit.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, records...)
+
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestAuditorFlushesOnClose(t *testing.T) {
+	sink := &memorySink{}
+	a := audit.New(zap.NewNop().Sugar(), sink)
+
+	a.Start(context.Background())
+
+	for i := 0; i < 10; i++ {
+		a.Record(audit.Record{Operation: "node", ID: "testsrv-123", Outcome: audit.OutcomeResolved})
+	}
+
+	require.NoError(t, a.Close())
+
+	assert.Len(t, sink.records, 10)
+	assert.False(t, sink.records[0].Time.IsZero())
+}
+
+func TestLogSink(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	a, err := audit.NewFromConfig(context.Background(), audit.Config{Log: true}, nil, zap.New(core).Sugar())
+	require.NoError(t, err)
+	require.NotNil(t, a)
+
+	a.Start(context.Background())
+
+	a.Record(audit.Record{Subject: "idntusr-abc", Operation: "node", ID: "testsrv-123", Prefix: "testsrv", Type: "Server", Outcome: audit.OutcomeResolved})
+	a.Record(audit.Record{Operation: "_entities", ID: "unknown-123", Prefix: "unknown", Outcome: audit.OutcomeUnknownPrefix})
+
+	require.NoError(t, a.Close())
+
+	entries := logs.FilterMessage("id resolution").All()
+	require.Len(t, entries, 2)
+
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "idntusr-abc", fields["subject"])
+	assert.Equal(t, "testsrv-123", fields["id"])
+	assert.Equal(t, "Server", fields["type"])
+	assert.Equal(t, "resolved", fields["outcome"])
+	assert.Equal(t, "log", entries[0].LoggerName)
+
+	fields = entries[1].ContextMap()
+	assert.NotContains(t, fields, "subject")
+	assert.NotContains(t, fields, "type")
+	assert.Equal(t, "unknown_prefix", fields["outcome"])
+}
@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// CloudEventType is the type of the CloudEvents emitted for audit records
+	CloudEventType = "com.infratographer.node-resolver.resolution.audit"
+
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+	cloudEventsBatchType   = "application/cloudevents-batch+json"
+)
+
+// ErrMissingCloudEventsTarget is returned when neither an http url or nats url is configured
+var ErrMissingCloudEventsTarget = errors.New("missing cloudevents target; you must pass an http url or nats url")
+
+// CloudEvent is a structured mode CloudEvent carrying an audit record
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            Record    `json:"data"`
+}
+
+// CloudEventsSink emits records as CloudEvents using the HTTP or NATS protocol binding
+type CloudEventsSink struct {
+	cfg  CloudEventsConfig
+	http *http.Client
+	nats *nats.Conn
+}
+
+// NewCloudEventsSink returns a sink for the configured binding
+func NewCloudEventsSink(cfg CloudEventsConfig) (*CloudEventsSink, error) {
+	s := &CloudEventsSink{cfg: cfg}
+
+	switch {
+	case cfg.HTTPURL != "":
+		s.http = &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport}
+	case cfg.NATSURL != "":
+		opts := []nats.Option{nats.Name(cfg.Source), nats.Timeout(cfg.Timeout)}
+
+		switch {
+		case cfg.NATSCredsFile != "":
+			opts = append(opts, nats.UserCredentials(cfg.NATSCredsFile))
+		case cfg.NATSToken != "":
+			opts = append(opts, nats.Token(cfg.NATSToken))
+		}
+
+		conn, err := nats.Connect(cfg.NATSURL, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		s.nats = conn
+	default:
+		return nil, ErrMissingCloudEventsTarget
+	}
+
+	return s, nil
+}
+
+func (s *CloudEventsSink) event(rec Record) (CloudEvent, error) {
+	id := make([]byte, 16) //nolint:gomnd
+
+	if _, err := rand.Read(id); err != nil {
+		return CloudEvent{}, err
+	}
+
+	return CloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              hex.EncodeToString(id),
+		Source:          s.cfg.Source,
+		Type:            CloudEventType,
+		Subject:         rec.ID,
+		Time:            rec.Time,
+		DataContentType: "application/json",
+		Data:            rec,
+	}, nil
+}
+
+// Emit sends the records as a batch over http, or as one message each over nats
+func (s *CloudEventsSink) Emit(ctx context.Context, records []Record) error {
+	events := make([]CloudEvent, len(records))
+
+	for i, rec := range records {
+		e, err := s.event(rec)
+		if err != nil {
+			return err
+		}
+
+		events[i] = e
+	}
+
+	if s.nats != nil {
+		return s.publish(events)
+	}
+
+	return s.post(ctx, events)
+}
+
+func (s *CloudEventsSink) publish(events []CloudEvent) error {
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		msg := nats.NewMsg(s.cfg.NATSSubject)
+		msg.Data = b
+		msg.Header.Set("content-type", cloudEventsContentType)
+
+		if err := s.nats.PublishMsg(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *CloudEventsSink) post(ctx context.Context, events []CloudEvent) error {
+	if s.cfg.HTTPBatch && len(events) > 1 {
+		return s.send(ctx, events, cloudEventsBatchType)
+	}
+
+	for _, e := range events {
+		if err := s.send(ctx, e, cloudEventsContentType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *CloudEventsSink) send(ctx context.Context, body interface{}, contentType string) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.HTTPURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response from cloudevents sink: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Close drains the nats connection
+func (s *CloudEventsSink) Close() error {
+	if s.nats != nil {
+		return s.nats.Drain()
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 5 * time.Second
+
+// Config stores the audit settings
+type Config struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	Log         bool              `mapstructure:"log"`
+	CloudEvents CloudEventsConfig `mapstructure:"cloudevents"`
+	CRDB        CRDBConfig        `mapstructure:"crdb"`
+}
+
+// Auditing returns true when auditing is enabled, either with --audit or by
+// writing records to the log
+func (c Config) Auditing() bool {
+	return c.Enabled || c.Log
+}
+
+// CRDBConfig stores the settings for persisting records to CockroachDB. The
+// connection uses the shared crdb config.
+type CRDBConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Table         string        `mapstructure:"table"`
+	BatchSize     int           `mapstructure:"batch-size"`
+	Retention     time.Duration `mapstructure:"retention"`
+	PruneInterval time.Duration `mapstructure:"prune-interval"`
+}
+
+// CloudEventsConfig stores the settings for emitting records as CloudEvents
+type CloudEventsConfig struct {
+	Source        string        `mapstructure:"source"`
+	HTTPURL       string        `mapstructure:"http-url"`
+	HTTPBatch     bool          `mapstructure:"http-batch"`
+	NATSURL       string        `mapstructure:"nats-url"`
+	NATSSubject   string        `mapstructure:"nats-subject"`
+	NATSToken     string        `mapstructure:"nats-token"`
+	NATSCredsFile string        `mapstructure:"nats-creds-file"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+
+	// Transport is used for requests to the http sink, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// Enabled returns true when a CloudEvents target is configured
+func (c CloudEventsConfig) Enabled() bool {
+	return c.HTTPURL != "" || c.NATSURL != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet, appName string) {
+	flags.Bool("audit", false, "emit an audit record for every resolved id")
+	viperx.MustBindFlag(v, "audit.enabled", flags.Lookup("audit"))
+
+	flags.Bool("audit-log", false, "emit an audit record for every resolved id to the log")
+	viperx.MustBindFlag(v, "audit.log", flags.Lookup("audit-log"))
+
+	flags.String("audit-cloudevents-http-url", "", "url to POST audit records to as CloudEvents")
+	viperx.MustBindFlag(v, "audit.cloudevents.http-url", flags.Lookup("audit-cloudevents-http-url"))
+
+	flags.String("audit-cloudevents-nats-url", "", "nats server to publish audit records to as CloudEvents")
+	viperx.MustBindFlag(v, "audit.cloudevents.nats-url", flags.Lookup("audit-cloudevents-nats-url"))
+
+	flags.Bool("audit-crdb", false, "persist audit records to cockroachdb using the crdb config")
+	viperx.MustBindFlag(v, "audit.crdb.enabled", flags.Lookup("audit-crdb"))
+
+	flags.Duration("audit-crdb-retention", defaultCRDBRetention, "how long audit records are kept in cockroachdb, 0 keeps them forever")
+	viperx.MustBindFlag(v, "audit.crdb.retention", flags.Lookup("audit-crdb-retention"))
+
+	v.MustBindEnv("audit.cloudevents.source")
+	v.MustBindEnv("audit.cloudevents.http-batch")
+	v.MustBindEnv("audit.cloudevents.nats-subject")
+	v.MustBindEnv("audit.cloudevents.nats-token")
+	v.MustBindEnv("audit.cloudevents.nats-creds-file")
+	v.MustBindEnv("audit.cloudevents.timeout")
+
+	v.MustBindEnv("audit.crdb.table")
+	v.MustBindEnv("audit.crdb.batch-size")
+	v.MustBindEnv("audit.crdb.prune-interval")
+
+	v.SetDefault("audit.cloudevents.source", appName)
+	v.SetDefault("audit.cloudevents.nats-subject", "com.infratographer.audit.node-resolver")
+	v.SetDefault("audit.cloudevents.timeout", defaultTimeout)
+	v.SetDefault("audit.crdb.table", defaultCRDBTable)
+	v.SetDefault("audit.crdb.batch-size", defaultCRDBBatchSize)
+	v.SetDefault("audit.crdb.prune-interval", defaultPruneInterval)
+}
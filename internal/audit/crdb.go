@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCRDBTable     = "node_resolver_audit"
+	defaultCRDBBatchSize = 500
+	defaultCRDBRetention = 30 * 24 * time.Hour
+	defaultPruneInterval = time.Hour
+	crdbPruneLimit       = 1000
+	crdbColumnsPerRecord = 7
+)
+
+var (
+	// ErrInvalidTable is returned when the configured table name isn't a valid identifier
+	ErrInvalidTable = errors.New("invalid audit table name")
+
+	tableNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+)
+
+// CRDBSink writes audit records to a CockroachDB table in batches and prunes
+// rows older than the configured retention
+type CRDBSink struct {
+	cfg    CRDBConfig
+	db     *sql.DB
+	logger *zap.SugaredLogger
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewCRDBSink creates the audit table if needed and starts pruning expired
+// rows in the background
+func NewCRDBSink(ctx context.Context, cfg CRDBConfig, db *sql.DB, logger *zap.SugaredLogger) (*CRDBSink, error) {
+	if cfg.Table == "" {
+		cfg.Table = defaultCRDBTable
+	}
+
+	if !tableNameRegexp.MatchString(cfg.Table) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTable, cfg.Table)
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultCRDBBatchSize
+	}
+
+	if cfg.PruneInterval <= 0 {
+		cfg.PruneInterval = defaultPruneInterval
+	}
+
+	s := &CRDBSink{cfg: cfg, db: db, logger: logger}
+
+	if _, err := db.ExecContext(ctx, s.createTableStatement()); err != nil {
+		return nil, fmt.Errorf("creating audit table: %w", err)
+	}
+
+	if cfg.Retention > 0 {
+		pruneCtx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+
+			s.pruneLoop(pruneCtx)
+		}()
+	}
+
+	return s, nil
+}
+
+// Emit inserts the records using multi-row inserts of up to BatchSize rows
+func (s *CRDBSink) Emit(ctx context.Context, records []Record) error {
+	for start := 0; start < len(records); start += s.cfg.BatchSize {
+		end := start + s.cfg.BatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+
+		query, args := s.insertStatement(records[start:end])
+
+		if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close stops pruning. The database is owned by the caller and left open.
+func (s *CRDBSink) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *CRDBSink) createTableStatement() string {
+	return `CREATE TABLE IF NOT EXISTS ` + s.cfg.Table + ` (
+	id UUID NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY,
+	recorded_at TIMESTAMPTZ NOT NULL,
+	subject STRING NOT NULL,
+	operation STRING NOT NULL,
+	node_id STRING NOT NULL,
+	prefix STRING NOT NULL,
+	type STRING NOT NULL,
+	outcome STRING NOT NULL,
+	INDEX (recorded_at)
+)`
+}
+
+func (s *CRDBSink) insertStatement(records []Record) (string, []interface{}) {
+	var sb strings.Builder
+
+	sb.WriteString("INSERT INTO " + s.cfg.Table + " (recorded_at, subject, operation, node_id, prefix, type, outcome) VALUES ")
+
+	args := make([]interface{}, 0, len(records)*crdbColumnsPerRecord)
+
+	for i, rec := range records {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		n := len(args)
+		fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7) //nolint:gomnd
+
+		args = append(args, rec.Time, rec.Subject, rec.Operation, rec.ID, rec.Prefix, rec.Type, string(rec.Outcome))
+	}
+
+	return sb.String(), args
+}
+
+func (s *CRDBSink) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.prune(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Errorw("failed to prune audit records", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune deletes expired rows in small batches to avoid large transactions
+func (s *CRDBSink) prune(ctx context.Context) error {
+	cutoff := time.Now().Add(-s.cfg.Retention)
+	query := fmt.Sprintf("DELETE FROM %s WHERE recorded_at < $1 LIMIT %d", s.cfg.Table, crdbPruneLimit)
+
+	for {
+		res, err := s.db.ExecContext(ctx, query, cutoff)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n < crdbPruneLimit {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCRDBInsertStatement(t *testing.T) {
+	s := &CRDBSink{cfg: CRDBConfig{Table: "audit"}}
+	now := time.Now()
+
+	query, args := s.insertStatement([]Record{
+		{Time: now, Subject: "idntusr-1", Operation: "node", ID: "testsrv-1", Prefix: "testsrv", Type: "Server", Outcome: OutcomeResolved},
+		{Time: now, Operation: "node", ID: "bad", Outcome: OutcomeInvalidID},
+	})
+
+	assert.Equal(t, "INSERT INTO audit (recorded_at, subject, operation, node_id, prefix, type, outcome) VALUES "+
+		"($1, $2, $3, $4, $5, $6, $7), ($8, $9, $10, $11, $12, $13, $14)", query)
+	assert.Equal(t, []interface{}{
+		now, "idntusr-1", "node", "testsrv-1", "testsrv", "Server", "resolved",
+		now, "", "node", "bad", "", "", "invalid_id",
+	}, args)
+}
+
+func TestCRDBInvalidTable(t *testing.T) {
+	_, err := NewCRDBSink(context.Background(), CRDBConfig{Table: "audit; DROP TABLE users"}, nil, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, ErrInvalidTable)
+}
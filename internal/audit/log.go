@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// LogSink writes audit records as structured log entries, one per record
+type LogSink struct {
+	logger *zap.SugaredLogger
+}
+
+// NewLogSink returns a sink writing records to logger
+func NewLogSink(logger *zap.SugaredLogger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+// Emit logs records
+func (s *LogSink) Emit(_ context.Context, records []Record) error {
+	for _, rec := range records {
+		fields := []interface{}{
+			"time", rec.Time,
+			"operation", rec.Operation,
+			"id", rec.ID,
+			"outcome", string(rec.Outcome),
+		}
+
+		if rec.Subject != "" {
+			fields = append(fields, "subject", rec.Subject)
+		}
+
+		if rec.Prefix != "" {
+			fields = append(fields, "prefix", rec.Prefix)
+		}
+
+		if rec.Type != "" {
+			fields = append(fields, "type", rec.Type)
+		}
+
+		s.logger.Infow("id resolution", fields...)
+	}
+
+	return nil
+}
+
+// Close flushes the logger
+func (s *LogSink) Close() error {
+	// syncing stdout and stderr fails on some platforms, which isn't an
+	// error worth failing shutdown over
+	_ = s.logger.Sync()
+
+	return nil
+}
@@ -0,0 +1,161 @@
+// Package authz provides pluggable authorization for node resolution
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.infratographer.com/x/echojwtx"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnauthorized is returned when the subject is not allowed to resolve an id
+	ErrUnauthorized = errors.New("not authorized to resolve id")
+
+	// ErrUnknownProvider is returned when the configured authorization provider isn't supported
+	ErrUnknownProvider = errors.New("unknown authorization provider")
+
+	// ErrDeleted is matched by a DeletedError
+	ErrDeleted = errors.New("id was deleted")
+)
+
+// DeletedError is returned by authorizers whose lookup found that id was
+// deleted or archived, once the subject is known to be allowed to resolve it,
+// so stale references can be told apart from invalid ids
+type DeletedError struct {
+	// DeletedAt is when the node was deleted, zero when unknown
+	DeletedAt time.Time
+}
+
+func (e *DeletedError) Error() string {
+	return ErrDeleted.Error()
+}
+
+// Is makes a DeletedError match ErrDeleted
+func (e *DeletedError) Is(target error) bool {
+	return target == ErrDeleted
+}
+
+// Provider is the name of an authorization backend
+type Provider string
+
+const (
+	// ProviderNone disables authorization checks
+	ProviderNone Provider = ""
+
+	// ProviderOpenFGA checks relationships using an OpenFGA store
+	ProviderOpenFGA Provider = "openfga"
+
+	// ProviderPermissionsAPI checks actions using permissions-api
+	ProviderPermissionsAPI Provider = "permissions-api"
+)
+
+// Authorizer decides if a subject is allowed to resolve the given id. A nil
+// error means resolution is allowed.
+type Authorizer interface {
+	CanResolve(ctx context.Context, subject string, id gidx.PrefixedID) error
+}
+
+// Subject returns the authenticated subject stored on the context by the auth
+// middleware, or an empty string for anonymous requests
+func Subject(ctx context.Context) string {
+	if subject, ok := ctx.Value(echojwtx.ActorCtxKey).(string); ok {
+		return subject
+	}
+
+	return ""
+}
+
+// NewAuthorizer returns the Authorizer for the configured provider. A nil
+// Authorizer is returned when authorization is disabled.
+func NewAuthorizer(cfg Config, logger *zap.SugaredLogger) (Authorizer, error) {
+	switch cfg.Provider {
+	case ProviderNone:
+		return nil, nil
+	case ProviderOpenFGA:
+		a, err := NewOpenFGA(cfg.OpenFGA, logger.Named("openfga"))
+		if err != nil {
+			return nil, err
+		}
+
+		return a, nil
+	case ProviderPermissionsAPI:
+		a, err := NewPermissionsAPI(cfg.PermissionsAPI, logger.Named("permissions-api"))
+		if err != nil {
+			return nil, err
+		}
+
+		return a, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, cfg.Provider)
+	}
+}
+
+type allAuthorizer []Authorizer
+
+// All returns an Authorizer that only allows resolution when every given
+// Authorizer allows it. Nil authorizers are ignored and nil is returned when
+// none remain.
+func All(authorizers ...Authorizer) Authorizer {
+	all := allAuthorizer{}
+
+	for _, a := range authorizers {
+		if a != nil {
+			all = append(all, a)
+		}
+	}
+
+	switch len(all) {
+	case 0:
+		return nil
+	case 1:
+		return all[0]
+	default:
+		return all
+	}
+}
+
+// CanResolve returns the first error from the authorizers
+func (all allAuthorizer) CanResolve(ctx context.Context, subject string, id gidx.PrefixedID) error {
+	for _, a := range all {
+		if err := a.CanResolve(ctx, subject, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type prefixAuthorizer struct {
+	authorizer Authorizer
+	prefixes   map[string]bool
+}
+
+// ForPrefixes returns an Authorizer only checking ids with the given prefixes
+// with a, allowing any other id. a is returned when prefixes is empty.
+func ForPrefixes(a Authorizer, prefixes []string) Authorizer {
+	if a == nil || len(prefixes) == 0 {
+		return a
+	}
+
+	p := prefixAuthorizer{authorizer: a, prefixes: map[string]bool{}}
+
+	for _, prefix := range prefixes {
+		p.prefixes[prefix] = true
+	}
+
+	return p
+}
+
+// CanResolve checks ids with the configured prefixes
+func (p prefixAuthorizer) CanResolve(ctx context.Context, subject string, id gidx.PrefixedID) error {
+	if !p.prefixes[id.Prefix()] {
+		return nil
+	}
+
+	return p.authorizer.CanResolve(ctx, subject, id)
+}
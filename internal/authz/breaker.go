@@ -0,0 +1,36 @@
+package authz
+
+import (
+	"context"
+	"errors"
+
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/breaker"
+)
+
+type breakerAuthorizer struct {
+	authorizer Authorizer
+	breaker    *breaker.Breaker
+}
+
+// WithBreaker returns an Authorizer checking with a through b, so checks fail
+// fast with breaker.ErrOpen while a is failing. Denials, deleted ids and
+// checks canceled by the caller aren't failures of a.
+func WithBreaker(a Authorizer, b *breaker.Breaker) Authorizer {
+	if a == nil || b == nil {
+		return a
+	}
+
+	return &breakerAuthorizer{authorizer: a, breaker: b}
+}
+
+// CanResolve checks with the wrapped Authorizer unless the breaker is open
+func (b *breakerAuthorizer) CanResolve(ctx context.Context, subject string, id gidx.PrefixedID) error {
+	return b.breaker.Do(
+		func() error { return b.authorizer.CanResolve(ctx, subject, id) },
+		func(err error) bool {
+			return !errors.Is(err, ErrUnauthorized) && !errors.Is(err, ErrDeleted) && !errors.Is(err, context.Canceled)
+		},
+	)
+}
@@ -0,0 +1,54 @@
+package authz
+
+import (
+	"context"
+
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+// cacheName is the name of the grant cache in cache lookup metrics
+const cacheName = "authorization"
+
+type cachedAuthorizer struct {
+	authorizer Authorizer
+	cache      *cache.Cache
+	metrics    metrics.Sink
+}
+
+// Cached returns an Authorizer that caches allowed results of a in c. Denials
+// are never cached so newly granted access takes effect immediately; cached
+// grants are removed when c is invalidated for the id. Lookups are recorded
+// to sink when it isn't nil.
+func Cached(a Authorizer, c *cache.Cache, sink metrics.Sink) Authorizer {
+	if a == nil || c == nil {
+		return a
+	}
+
+	return &cachedAuthorizer{authorizer: a, cache: c, metrics: sink}
+}
+
+// CanResolve returns a cached grant or checks with the wrapped Authorizer
+func (c *cachedAuthorizer) CanResolve(ctx context.Context, subject string, id gidx.PrefixedID) error {
+	key := "authz:" + subject + ":" + id.String()
+
+	_, ok := c.cache.Get(key)
+
+	if c.metrics != nil {
+		c.metrics.CacheLookup(cacheName, ok)
+	}
+
+	if ok {
+		return nil
+	}
+
+	if err := c.authorizer.CanResolve(ctx, subject, id); err != nil {
+		return err
+	}
+
+	c.cache.Set(key, id.String(), true)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package authz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+type lookupCounter struct {
+	metrics.Nop
+
+	hits, misses int
+}
+
+func (c *lookupCounter) CacheLookup(cache string, hit bool) {
+	if cache != "authorization" {
+		return
+	}
+
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+type countingAuthorizer struct {
+	checks int
+}
+
+var errDenied = errors.New("denied")
+
+func (a *countingAuthorizer) CanResolve(_ context.Context, subject string, _ gidx.PrefixedID) error {
+	a.checks++
+
+	if subject != "alice" {
+		return errDenied
+	}
+
+	return nil
+}
+
+func TestCached(t *testing.T) {
+	backend := &countingAuthorizer{}
+	counter := &lookupCounter{}
+
+	a := authz.Cached(backend, cache.New(10, time.Minute), counter)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, a.CanResolve(context.Background(), "alice", "testsrv-1"))
+		require.ErrorIs(t, a.CanResolve(context.Background(), "bob", "testsrv-1"), errDenied)
+	}
+
+	// grants are cached, denials are checked every time
+	assert.Equal(t, 4, backend.checks)
+	assert.Equal(t, 2, counter.hits)
+	assert.Equal(t, 4, counter.misses)
+
+	// without a cache the authorizer is used as it is
+	assert.Same(t, backend, authz.Cached(backend, nil, counter))
+}
@@ -0,0 +1,87 @@
+package authz
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 5 * time.Second
+
+// Config stores the authorization settings
+type Config struct {
+	Provider Provider `mapstructure:"provider"`
+	// Prefixes limits the checks of the provider to ids with these
+	// prefixes, any other id can be resolved by every caller. Every id is
+	// checked when it's empty.
+	Prefixes       []string             `mapstructure:"prefixes"`
+	OpenFGA        OpenFGAConfig        `mapstructure:"openfga"`
+	PermissionsAPI PermissionsAPIConfig `mapstructure:"permissions-api"`
+}
+
+// OpenFGAConfig stores the settings for the OpenFGA authorizer
+type OpenFGAConfig struct {
+	URL         string            `mapstructure:"url"`
+	StoreID     string            `mapstructure:"store-id"`
+	ModelID     string            `mapstructure:"model-id"`
+	Token       string            `mapstructure:"token"`
+	Relation    string            `mapstructure:"relation"`
+	UserType    string            `mapstructure:"user-type"`
+	ObjectType  string            `mapstructure:"object-type"`
+	ObjectTypes map[string]string `mapstructure:"object-types"`
+	Timeout     time.Duration     `mapstructure:"timeout"`
+
+	// Transport is used for requests to OpenFGA, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// PermissionsAPIConfig stores the settings for the permissions-api authorizer
+type PermissionsAPIConfig struct {
+	URL     string            `mapstructure:"url"`
+	Action  string            `mapstructure:"action"`
+	Actions map[string]string `mapstructure:"actions"`
+	Timeout time.Duration     `mapstructure:"timeout"`
+
+	// Transport is used for requests to permissions-api, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("authz-provider", "", `authorization provider to use options: "openfga", "permissions-api"`)
+	viperx.MustBindFlag(v, "authz.provider", flags.Lookup("authz-provider"))
+
+	flags.StringSlice("authz-prefixes", nil, "only check the ids with these prefixes with the authorization provider, every id is checked when empty")
+	viperx.MustBindFlag(v, "authz.prefixes", flags.Lookup("authz-prefixes"))
+
+	flags.String("authz-openfga-url", "", "url of the OpenFGA api")
+	viperx.MustBindFlag(v, "authz.openfga.url", flags.Lookup("authz-openfga-url"))
+
+	flags.String("authz-openfga-store-id", "", "OpenFGA store id to check relationships in")
+	viperx.MustBindFlag(v, "authz.openfga.store-id", flags.Lookup("authz-openfga-store-id"))
+
+	flags.String("authz-permissions-api-url", "", "url of permissions-api")
+	viperx.MustBindFlag(v, "authz.permissions-api.url", flags.Lookup("authz-permissions-api-url"))
+
+	v.MustBindEnv("authz.openfga.model-id")
+	v.MustBindEnv("authz.openfga.token")
+	v.MustBindEnv("authz.openfga.relation")
+	v.MustBindEnv("authz.openfga.user-type")
+	v.MustBindEnv("authz.openfga.object-type")
+	v.MustBindEnv("authz.openfga.object-types")
+	v.MustBindEnv("authz.openfga.timeout")
+
+	v.MustBindEnv("authz.permissions-api.action")
+	v.MustBindEnv("authz.permissions-api.actions")
+	v.MustBindEnv("authz.permissions-api.timeout")
+
+	v.SetDefault("authz.openfga.relation", "can_view")
+	v.SetDefault("authz.openfga.user-type", "user")
+	v.SetDefault("authz.openfga.object-type", "node")
+	v.SetDefault("authz.openfga.timeout", defaultTimeout)
+	v.SetDefault("authz.permissions-api.action", "node_resolve")
+	v.SetDefault("authz.permissions-api.timeout", defaultTimeout)
+}
@@ -0,0 +1,31 @@
+package authz
+
+import (
+	"context"
+
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/hedge"
+)
+
+type hedgedAuthorizer struct {
+	authorizer Authorizer
+	hedger     *hedge.Hedger
+}
+
+// Hedged returns an Authorizer checking with a through h, so checks slower
+// than most are sent to a again. Checks only read, so they're safe to repeat.
+func Hedged(a Authorizer, h *hedge.Hedger) Authorizer {
+	if a == nil || h == nil {
+		return a
+	}
+
+	return &hedgedAuthorizer{authorizer: a, hedger: h}
+}
+
+// CanResolve checks with the wrapped Authorizer, hedging slow checks
+func (h *hedgedAuthorizer) CanResolve(ctx context.Context, subject string, id gidx.PrefixedID) error {
+	return h.hedger.Do(ctx, func(ctx context.Context) error {
+		return h.authorizer.CanResolve(ctx, subject, id)
+	})
+}
@@ -0,0 +1,127 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+// ErrMissingOpenFGAConfig is returned when the OpenFGA url or store id is not configured
+var ErrMissingOpenFGAConfig = errors.New("missing OpenFGA config options; you must pass a url and store id")
+
+type openFGATupleKey struct {
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+type openFGACheckRequest struct {
+	TupleKey             openFGATupleKey `json:"tuple_key"`
+	AuthorizationModelID string          `json:"authorization_model_id,omitempty"`
+}
+
+type openFGACheckResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+// OpenFGA authorizes resolution by checking a relationship between the subject
+// and the node in an OpenFGA store
+type OpenFGA struct {
+	cfg      OpenFGAConfig
+	logger   *zap.SugaredLogger
+	http     *http.Client
+	checkURL string
+}
+
+// NewOpenFGA returns an Authorizer backed by the OpenFGA check api
+func NewOpenFGA(cfg OpenFGAConfig, logger *zap.SugaredLogger) (*OpenFGA, error) {
+	if cfg.URL == "" || cfg.StoreID == "" {
+		return nil, ErrMissingOpenFGAConfig
+	}
+
+	checkURL, err := url.JoinPath(cfg.URL, "stores", cfg.StoreID, "check")
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &OpenFGA{
+		cfg:      cfg,
+		logger:   logger,
+		http:     &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		checkURL: checkURL,
+	}, nil
+}
+
+// CanResolve checks that subject has the configured relation on the node
+func (o *OpenFGA) CanResolve(ctx context.Context, subject string, id gidx.PrefixedID) error {
+	if subject == "" {
+		return ErrUnauthorized
+	}
+
+	body, err := json.Marshal(openFGACheckRequest{
+		TupleKey: openFGATupleKey{
+			User:     o.cfg.UserType + ":" + subject,
+			Relation: o.cfg.Relation,
+			Object:   o.objectType(id) + ":" + id.String(),
+		},
+		AuthorizationModelID: o.cfg.ModelID,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.checkURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if o.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.cfg.Token)
+	}
+
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from OpenFGA: %s", resp.Status)
+	}
+
+	var check openFGACheckResponse
+	if err := json.NewDecoder(resp.Body).Decode(&check); err != nil {
+		return err
+	}
+
+	if !check.Allowed {
+		o.logger.Debugw("OpenFGA check denied", "subject", subject, "id", id)
+
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// objectType returns the OpenFGA type for the id, allowing prefixes to be
+// mapped to types in the authorization model
+func (o *OpenFGA) objectType(id gidx.PrefixedID) string {
+	if t, ok := o.cfg.ObjectTypes[id.Prefix()]; ok {
+		return t
+	}
+
+	return o.cfg.ObjectType
+}
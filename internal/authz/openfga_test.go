@@ -0,0 +1,94 @@
+package authz_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+)
+
+func TestOpenFGA(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/stores/teststore/check", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var body struct {
+			TupleKey struct {
+				User     string `json:"user"`
+				Relation string `json:"relation"`
+				Object   string `json:"object"`
+			} `json:"tuple_key"`
+		}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "can_view", body.TupleKey.Relation)
+
+		allowed := body.TupleKey.User == "user:idntusr-allowed" && body.TupleKey.Object == "loadbalancer:loadbal-123"
+
+		_ = json.NewEncoder(w).Encode(map[string]bool{"allowed": allowed})
+	}))
+	defer srv.Close()
+
+	a, err := authz.NewAuthorizer(authz.Config{
+		Provider: authz.ProviderOpenFGA,
+		OpenFGA: authz.OpenFGAConfig{
+			URL:         srv.URL,
+			StoreID:     "teststore",
+			Token:       "secret",
+			Relation:    "can_view",
+			UserType:    "user",
+			ObjectType:  "node",
+			ObjectTypes: map[string]string{"loadbal": "loadbalancer"},
+		},
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		TestName string
+		subject  string
+		id       gidx.PrefixedID
+		err      error
+	}{
+		{
+			TestName: "allowed relationship",
+			subject:  "idntusr-allowed",
+			id:       "loadbal-123",
+		},
+		{
+			TestName: "denied relationship",
+			subject:  "idntusr-denied",
+			id:       "loadbal-123",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName: "unmapped prefix uses default object type",
+			subject:  "idntusr-allowed",
+			id:       "testsrv-123",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName: "anonymous subject",
+			id:       "loadbal-123",
+			err:      authz.ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			err := a.CanResolve(context.Background(), tt.subject, tt.id)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
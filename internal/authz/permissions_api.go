@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+// ErrMissingPermissionsAPIConfig is returned when the permissions-api url is not configured
+var ErrMissingPermissionsAPIConfig = errors.New("missing permissions-api config options; you must pass a url")
+
+type permissionsAPIAction struct {
+	ResourceID string `json:"resource_id"`
+	Action     string `json:"action"`
+}
+
+type permissionsAPIRequest struct {
+	Actions []permissionsAPIAction `json:"actions"`
+}
+
+// PermissionsAPI authorizes resolution by asking permissions-api whether the
+// caller may perform an action on the node. permissions-api authenticates
+// the caller itself, so the caller's jwt is sent along with the check.
+type PermissionsAPI struct {
+	cfg      PermissionsAPIConfig
+	logger   *zap.SugaredLogger
+	http     *http.Client
+	allowURL string
+}
+
+// NewPermissionsAPI returns an Authorizer backed by the permissions-api allow api
+func NewPermissionsAPI(cfg PermissionsAPIConfig, logger *zap.SugaredLogger) (*PermissionsAPI, error) {
+	if cfg.URL == "" {
+		return nil, ErrMissingPermissionsAPIConfig
+	}
+
+	allowURL, err := url.JoinPath(cfg.URL, "api", "v1", "allow")
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &PermissionsAPI{
+		cfg:      cfg,
+		logger:   logger,
+		http:     &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		allowURL: allowURL,
+	}, nil
+}
+
+// CanResolve checks that the caller is allowed the configured action on the
+// node
+func (p *PermissionsAPI) CanResolve(ctx context.Context, subject string, id gidx.PrefixedID) error {
+	token := oidc.Token(ctx)
+	if subject == "" || token == "" {
+		return ErrUnauthorized
+	}
+
+	body, err := json.Marshal(permissionsAPIRequest{
+		Actions: []permissionsAPIAction{{ResourceID: id.String(), Action: p.action(id)}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.allowURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		p.logger.Debugw("permissions-api check denied", "subject", subject, "id", id, "status", resp.StatusCode)
+
+		return ErrUnauthorized
+	default:
+		return fmt.Errorf("unexpected response from permissions-api: %s", resp.Status)
+	}
+}
+
+// action returns the permissions-api action checked for the id, allowing
+// prefixes to be mapped to their own actions
+func (p *PermissionsAPI) action(id gidx.PrefixedID) string {
+	if a, ok := p.cfg.Actions[id.Prefix()]; ok {
+		return a
+	}
+
+	return p.cfg.Action
+}
@@ -0,0 +1,146 @@
+package authz_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+func newPermissionsAPIServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/allow", r.URL.Path)
+
+		var body struct {
+			Actions []struct {
+				ResourceID string `json:"resource_id"`
+				Action     string `json:"action"`
+			} `json:"actions"`
+		}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Actions, 1)
+
+		switch {
+		case r.Header.Get("Authorization") == "Bearer broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.Header.Get("Authorization") != "Bearer allowed-token":
+			w.WriteHeader(http.StatusForbidden)
+		case body.Actions[0].ResourceID == "idnttkn-123" && body.Actions[0].Action != "token_get":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestPermissionsAPI(t *testing.T) {
+	srv := newPermissionsAPIServer(t)
+
+	a, err := authz.NewAuthorizer(authz.Config{
+		Provider: authz.ProviderPermissionsAPI,
+		PermissionsAPI: authz.PermissionsAPIConfig{
+			URL:     srv.URL,
+			Action:  "node_resolve",
+			Actions: map[string]string{"idnttkn": "token_get"},
+		},
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		TestName  string
+		subject   string
+		token     string
+		id        gidx.PrefixedID
+		err       error
+		expectErr bool
+	}{
+		{
+			TestName: "allowed",
+			subject:  "idntusr-allowed",
+			token:    "allowed-token",
+			id:       "loadbal-123",
+		},
+		{
+			TestName: "mapped action",
+			subject:  "idntusr-allowed",
+			token:    "allowed-token",
+			id:       "idnttkn-123",
+		},
+		{
+			TestName: "denied",
+			subject:  "idntusr-denied",
+			token:    "denied-token",
+			id:       "loadbal-123",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName: "unauthenticated",
+			id:       "loadbal-123",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName:  "failed check",
+			subject:   "idntusr-allowed",
+			token:     "broken",
+			id:        "loadbal-123",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = oidc.WithToken(ctx, tt.token)
+			}
+
+			err := a.CanResolve(ctx, tt.subject, tt.id)
+
+			switch {
+			case tt.err != nil:
+				assert.ErrorIs(t, err, tt.err)
+			case tt.expectErr:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, authz.ErrUnauthorized)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPermissionsAPIMissingURL(t *testing.T) {
+	_, err := authz.NewAuthorizer(authz.Config{Provider: authz.ProviderPermissionsAPI}, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, authz.ErrMissingPermissionsAPIConfig)
+}
+
+func TestForPrefixes(t *testing.T) {
+	srv := newPermissionsAPIServer(t)
+
+	a, err := authz.NewPermissionsAPI(authz.PermissionsAPIConfig{URL: srv.URL, Action: "node_resolve"}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	restricted := authz.ForPrefixes(a, []string{"idnttkn"})
+
+	// only tokens are checked, so anyone resolves other ids
+	assert.NoError(t, restricted.CanResolve(context.Background(), "", "loadbal-123"))
+	assert.ErrorIs(t, restricted.CanResolve(context.Background(), "", "idnttkn-123"), authz.ErrUnauthorized)
+
+	assert.Equal(t, authz.Authorizer(a), authz.ForPrefixes(a, nil))
+	assert.Nil(t, authz.ForPrefixes(nil, []string{"idnttkn"}))
+}
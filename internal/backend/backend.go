@@ -0,0 +1,64 @@
+// Package backend checks that the nodes of ids exist before they're resolved
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotFound is returned when the node of an id doesn't exist
+	ErrNotFound = errors.New("node not found")
+
+	// ErrUnknownProvider is returned when the configured node backend isn't supported
+	ErrUnknownProvider = errors.New("unknown node backend")
+
+	// ErrMissingDB is returned when the crdb backend is configured without a database
+	ErrMissingDB = errors.New("the crdb node backend requires the crdb config")
+)
+
+// Provider is the name of a node backend
+type Provider string
+
+const (
+	// ProviderNone disables existence checks, every well formed id with a
+	// known prefix is resolved
+	ProviderNone Provider = ""
+
+	// ProviderHTTP looks nodes up with an http api
+	ProviderHTTP Provider = "http"
+
+	// ProviderCRDB looks nodes up in a CockroachDB table
+	ProviderCRDB Provider = "crdb"
+)
+
+// NodeBackend reports whether the node of an id exists. An error means the
+// lookup itself failed, not that the node is missing.
+type NodeBackend interface {
+	Exists(ctx context.Context, id gidx.PrefixedID) (bool, error)
+}
+
+// NewFromConfig returns the NodeBackend for the configured provider. A nil
+// NodeBackend is returned when existence checks are disabled. db is only used
+// by the crdb backend.
+func NewFromConfig(cfg Config, db *sql.DB, logger *zap.SugaredLogger) (NodeBackend, error) {
+	switch cfg.Provider {
+	case ProviderNone:
+		return nil, nil
+	case ProviderHTTP:
+		return NewHTTP(cfg.HTTP, logger.Named("http"))
+	case ProviderCRDB:
+		if db == nil {
+			return nil, ErrMissingDB
+		}
+
+		return NewCRDB(cfg.CRDB, db)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, cfg.Provider)
+	}
+}
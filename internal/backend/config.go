@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 5 * time.Second
+
+// Config stores the node backend settings
+type Config struct {
+	Provider Provider   `mapstructure:"provider"`
+	HTTP     HTTPConfig `mapstructure:"http"`
+	CRDB     CRDBConfig `mapstructure:"crdb"`
+	// KeyLookup looks up the ids of entities referenced by another @key
+	KeyLookup KeyLookupConfig `mapstructure:"key-lookup"`
+}
+
+// Enabled returns true when a node backend is configured
+func (c Config) Enabled() bool {
+	return c.Provider != ProviderNone
+}
+
+// HTTPConfig stores the settings for the http node backend
+type HTTPConfig struct {
+	// URL is requested with the id appended to its path
+	URL     string        `mapstructure:"url"`
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Transport is used for lookups, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// CRDBConfig stores the settings for the crdb node backend. The connection
+// uses the shared crdb config.
+type CRDBConfig struct {
+	// Table is the table ids are looked up in, and Tables overrides it for
+	// prefixes whose nodes are stored elsewhere
+	Table  string            `mapstructure:"table"`
+	Tables map[string]string `mapstructure:"tables"`
+	// Column is the column holding the ids
+	Column string `mapstructure:"column"`
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("node-backend", "", `backend checking that nodes exist before their ids are resolved options: "http", "crdb"`)
+	viperx.MustBindFlag(v, "backend.provider", flags.Lookup("node-backend"))
+
+	flags.String("node-backend-http-url", "", "url ids are appended to when checking nodes exist with the http backend")
+	viperx.MustBindFlag(v, "backend.http.url", flags.Lookup("node-backend-http-url"))
+
+	flags.String("node-backend-crdb-table", defaultCRDBTable, "table nodes are looked up in with the crdb backend")
+	viperx.MustBindFlag(v, "backend.crdb.table", flags.Lookup("node-backend-crdb-table"))
+
+	flags.String("key-lookup-url", "", "url the _entities representations keyed by fields other than id are posted to, to look up their id")
+	viperx.MustBindFlag(v, "backend.key-lookup.url", flags.Lookup("key-lookup-url"))
+
+	v.MustBindEnv("backend.http.timeout")
+	v.MustBindEnv("backend.key-lookup.timeout")
+	v.MustBindEnv("backend.crdb.tables")
+	v.MustBindEnv("backend.crdb.column")
+
+	v.SetDefault("backend.http.timeout", defaultTimeout)
+	v.SetDefault("backend.key-lookup.timeout", defaultTimeout)
+	v.SetDefault("backend.crdb.column", defaultCRDBColumn)
+}
@@ -0,0 +1,92 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"go.infratographer.com/x/gidx"
+)
+
+const (
+	defaultCRDBTable  = "nodes"
+	defaultCRDBColumn = "id"
+)
+
+var (
+	// ErrInvalidIdentifier is returned when a configured table or column
+	// name isn't a valid identifier
+	ErrInvalidIdentifier = errors.New("invalid node backend table or column name")
+
+	tableNameRegexp  = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+	columnNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// CRDB checks nodes exist by looking their ids up in CockroachDB tables
+type CRDB struct {
+	db *sql.DB
+	// queries are the lookup statements of the prefixes with their own
+	// table, any other prefix uses query
+	query   string
+	queries map[string]string
+}
+
+// NewCRDB returns a NodeBackend looking nodes up in the configured tables
+func NewCRDB(cfg CRDBConfig, db *sql.DB) (*CRDB, error) {
+	if cfg.Table == "" {
+		cfg.Table = defaultCRDBTable
+	}
+
+	if cfg.Column == "" {
+		cfg.Column = defaultCRDBColumn
+	}
+
+	if !columnNameRegexp.MatchString(cfg.Column) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdentifier, cfg.Column)
+	}
+
+	query, err := existsStatement(cfg.Table, cfg.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CRDB{db: db, query: query, queries: map[string]string{}}
+
+	for prefix, table := range cfg.Tables {
+		if c.queries[prefix], err = existsStatement(table, cfg.Column); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Exists looks id up in the table of its prefix
+func (c *CRDB) Exists(ctx context.Context, id gidx.PrefixedID) (bool, error) {
+	var exists bool
+
+	if err := c.db.QueryRowContext(ctx, c.statement(id), id.String()).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// statement returns the lookup statement for id
+func (c *CRDB) statement(id gidx.PrefixedID) string {
+	if query, ok := c.queries[id.Prefix()]; ok {
+		return query
+	}
+
+	return c.query
+}
+
+func existsStatement(table, column string) (string, error) {
+	if !tableNameRegexp.MatchString(table) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidIdentifier, table)
+	}
+
+	return fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1)", table, column), nil
+}
@@ -0,0 +1,27 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRDBStatements(t *testing.T) {
+	c, err := NewCRDB(CRDBConfig{Tables: map[string]string{"testusr": "identity.users"}}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "SELECT EXISTS (SELECT 1 FROM nodes WHERE id = $1)", c.statement("testsrv-123"))
+	assert.Equal(t, "SELECT EXISTS (SELECT 1 FROM identity.users WHERE id = $1)", c.statement("testusr-123"))
+}
+
+func TestCRDBInvalidIdentifiers(t *testing.T) {
+	_, err := NewCRDB(CRDBConfig{Table: "nodes; DROP TABLE users"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+
+	_, err = NewCRDB(CRDBConfig{Tables: map[string]string{"testusr": "users--"}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+
+	_, err = NewCRDB(CRDBConfig{Column: "id = id OR 1"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+}
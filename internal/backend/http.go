@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+// ErrMissingHTTPConfig is returned when the http backend url is not configured
+var ErrMissingHTTPConfig = errors.New("missing http node backend config options; you must pass a url")
+
+// HTTP checks nodes exist by requesting them from an http api. A 200 or 204
+// means the node exists and a 404 or 410 that it doesn't; the caller's jwt
+// is sent along so the api can authenticate the lookup.
+type HTTP struct {
+	logger *zap.SugaredLogger
+	http   *http.Client
+	url    string
+}
+
+// NewHTTP returns a NodeBackend looking nodes up at the configured url
+func NewHTTP(cfg HTTPConfig, logger *zap.SugaredLogger) (*HTTP, error) {
+	if cfg.URL == "" {
+		return nil, ErrMissingHTTPConfig
+	}
+
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, err
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &HTTP{
+		logger: logger,
+		http:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		url:    cfg.URL,
+	}, nil
+}
+
+// Exists requests the node of id
+func (h *HTTP) Exists(ctx context.Context, id gidx.PrefixedID) (bool, error) {
+	nodeURL, err := url.JoinPath(h.url, id.String())
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, nodeURL, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if token := oidc.Token(ctx); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := h.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound, http.StatusGone:
+		h.logger.Debugw("node not found", "id", id, "status", resp.StatusCode)
+
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected response from node backend: %s", resp.Status)
+	}
+}
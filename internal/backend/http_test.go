@@ -0,0 +1,79 @@
+package backend_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer caller-token", r.Header.Get("Authorization"))
+
+		switch r.URL.Path {
+		case "/nodes/testsrv-123":
+			w.WriteHeader(http.StatusOK)
+		case "/nodes/testsrv-456":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	b, err := backend.NewFromConfig(backend.Config{
+		Provider: backend.ProviderHTTP,
+		HTTP:     backend.HTTPConfig{URL: srv.URL + "/nodes"},
+	}, nil, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	ctx := oidc.WithToken(context.Background(), "caller-token")
+
+	testCases := []struct {
+		TestName      string
+		id            gidx.PrefixedID
+		expected      bool
+		expectedError bool
+	}{
+		{TestName: "exists", id: "testsrv-123", expected: true},
+		{TestName: "missing", id: "testsrv-456"},
+		{TestName: "backend failure", id: "testsrv-789", expectedError: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			exists, err := b.Exists(ctx, tt.id)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, exists)
+		})
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	b, err := backend.NewFromConfig(backend.Config{}, nil, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	assert.Nil(t, b)
+
+	_, err = backend.NewFromConfig(backend.Config{Provider: backend.ProviderHTTP}, nil, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, backend.ErrMissingHTTPConfig)
+
+	_, err = backend.NewFromConfig(backend.Config{Provider: backend.ProviderCRDB}, nil, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, backend.ErrMissingDB)
+
+	_, err = backend.NewFromConfig(backend.Config{Provider: "spanner"}, nil, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, backend.ErrUnknownProvider)
+}
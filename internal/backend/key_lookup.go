@@ -0,0 +1,135 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+// ErrMissingKeyLookupConfig is returned when the key lookup url is not configured
+var ErrMissingKeyLookupConfig = errors.New("missing key lookup config options; you must pass a url")
+
+// KeyLookup returns the id of the node of typeName whose @key fields have
+// the values of key, for entities referenced by a key other than their id.
+// ErrNotFound is returned when no node has the key; other errors mean the
+// lookup itself failed.
+type KeyLookup interface {
+	LookupKey(ctx context.Context, typeName string, key map[string]interface{}) (gidx.PrefixedID, error)
+}
+
+// KeyLookupConfig stores the settings of the http key lookup
+type KeyLookupConfig struct {
+	// URL is sent the keys to look up, empty disables key lookups
+	URL     string        `mapstructure:"url"`
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Transport is used for lookups, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// Enabled returns true when a key lookup url is configured
+func (c KeyLookupConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// HTTPKeyLookup looks keys up by posting them to an http api as the
+// representation of the entity, such as {"__typename": "Server", "serial":
+// "abc"}. A 200 with {"id": "..."} returns the id and a 404 or 410 that no
+// node has the key; the caller's jwt is sent along so the api can
+// authenticate the lookup.
+type HTTPKeyLookup struct {
+	logger *zap.SugaredLogger
+	http   *http.Client
+	url    string
+}
+
+// NewHTTPKeyLookup returns a KeyLookup posting keys to the configured url
+func NewHTTPKeyLookup(cfg KeyLookupConfig, logger *zap.SugaredLogger) (*HTTPKeyLookup, error) {
+	if cfg.URL == "" {
+		return nil, ErrMissingKeyLookupConfig
+	}
+
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, err
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &HTTPKeyLookup{
+		logger: logger,
+		http:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		url:    cfg.URL,
+	}, nil
+}
+
+// keyLookupResponse is the body of a successful key lookup
+type keyLookupResponse struct {
+	ID string `json:"id"`
+}
+
+// LookupKey posts the key of typeName
+func (h *HTTPKeyLookup) LookupKey(ctx context.Context, typeName string, key map[string]interface{}) (gidx.PrefixedID, error) {
+	rep := make(map[string]interface{}, len(key)+1)
+	for field, v := range key {
+		rep[field] = v
+	}
+
+	rep["__typename"] = typeName
+
+	body, err := json.Marshal(rep)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if token := oidc.Token(ctx); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := h.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		h.logger.Debugw("key not found", "graphql_type", typeName, "status", resp.StatusCode)
+
+		return "", ErrNotFound
+	default:
+		return "", fmt.Errorf("unexpected response from key lookup: %s", resp.Status)
+	}
+
+	var kr keyLookupResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&kr); err != nil {
+		return "", fmt.Errorf("decoding key lookup response: %w", err)
+	}
+
+	id, err := gidx.Parse(kr.ID)
+	if err != nil {
+		return "", fmt.Errorf("key lookup returned an invalid id: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,80 @@
+package backend_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+func TestHTTPKeyLookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer caller-token", r.Header.Get("Authorization"))
+
+		var rep map[string]interface{}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&rep)) {
+			return
+		}
+
+		assert.Equal(t, "Server", rep["__typename"])
+
+		switch rep["serial"] {
+		case "abc":
+			_, _ = w.Write([]byte(`{"id": "testsrv-123"}`))
+		case "def":
+			w.WriteHeader(http.StatusNotFound)
+		case "invalid":
+			_, _ = w.Write([]byte(`{"id": "notanid"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	l, err := backend.NewHTTPKeyLookup(backend.KeyLookupConfig{URL: srv.URL}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	ctx := oidc.WithToken(context.Background(), "caller-token")
+
+	testCases := []struct {
+		TestName      string
+		serial        string
+		expected      gidx.PrefixedID
+		expectedError error
+	}{
+		{TestName: "found", serial: "abc", expected: "testsrv-123"},
+		{TestName: "missing", serial: "def", expectedError: backend.ErrNotFound},
+		{TestName: "invalid id", serial: "invalid"},
+		{TestName: "lookup failure", serial: "ghi"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			id, err := l.LookupKey(ctx, "Server", map[string]interface{}{"serial": tt.serial})
+			if tt.expected == "" {
+				require.Error(t, err)
+
+				if tt.expectedError != nil {
+					assert.ErrorIs(t, err, tt.expectedError)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, id)
+		})
+	}
+
+	_, err = backend.NewHTTPKeyLookup(backend.KeyLookupConfig{}, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, backend.ErrMissingKeyLookupConfig)
+}
@@ -0,0 +1,218 @@
+// Package breaker provides circuit breakers for backend lookups, so a failing
+// backend is given time to recover and requests fail fast instead of each
+// waiting for it to time out
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+// ErrOpen is returned for lookups rejected while a breaker is open
+var ErrOpen = errors.New("backend unavailable, circuit breaker open")
+
+// State is the state of a breaker, used as the metrics label
+type State string
+
+// Breaker states
+const (
+	// StateClosed lets every lookup through, counting failures
+	StateClosed State = "closed"
+	// StateOpen rejects every lookup until the open duration has passed
+	StateOpen State = "open"
+	// StateHalfOpen lets a few probe lookups through, closing the breaker
+	// when they all succeed and opening it again when one fails
+	StateHalfOpen State = "half_open"
+)
+
+// Breaker tracks the failure rate of lookups to a backend in fixed windows
+// and opens when it reaches the configured rate. A Breaker is safe for
+// concurrent use.
+type Breaker struct {
+	name    string
+	cfg     Config
+	logger  *zap.SugaredLogger
+	metrics metrics.Sink
+
+	mu    sync.Mutex
+	state State
+	// generation changes with every state change, so lookups started in an
+	// earlier state aren't counted in the current one
+	generation  uint64
+	openedAt    time.Time
+	windowStart time.Time
+	requests    int
+	failures    int
+	probes      int
+	successes   int
+
+	// now returns the current time
+	now func() time.Time
+}
+
+// New returns a closed Breaker for the backend called name. Transitions and
+// rejected lookups are counted with sink, which may be nil.
+func New(name string, cfg Config, sink metrics.Sink, logger *zap.SugaredLogger) *Breaker {
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = defaultMinRequests
+	}
+
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = defaultOpenDuration
+	}
+
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = defaultHalfOpenProbes
+	}
+
+	return &Breaker{
+		name:    name,
+		cfg:     cfg,
+		logger:  logger,
+		metrics: sink,
+		state:   StateClosed,
+		now:     time.Now,
+	}
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Do calls lookup unless the breaker is open, in which case ErrOpen is
+// returned. isFailure reports whether an error returned by lookup is a
+// failure of the backend; other errors, such as a denial, are answers from a
+// healthy backend. Lookups slower than the slow call duration are failures
+// whatever they return.
+func (b *Breaker) Do(lookup func() error, isFailure func(error) bool) error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	start := b.now()
+	err = lookup()
+
+	failed := err != nil && isFailure(err)
+	if b.cfg.SlowCall > 0 && b.now().Sub(start) > b.cfg.SlowCall {
+		failed = true
+	}
+
+	b.record(generation, failed)
+
+	return err
+}
+
+// allow returns the generation a lookup starts in, or ErrOpen when it's
+// rejected
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < b.cfg.OpenDuration {
+			return 0, b.reject()
+		}
+
+		b.transition(StateHalfOpen)
+	}
+
+	if b.state == StateHalfOpen {
+		if b.probes >= b.cfg.HalfOpenProbes {
+			return 0, b.reject()
+		}
+
+		b.probes++
+	}
+
+	return b.generation, nil
+}
+
+func (b *Breaker) reject() error {
+	if b.metrics != nil {
+		b.metrics.BreakerRejection(b.name)
+	}
+
+	return ErrOpen
+}
+
+// record counts the result of a lookup started in generation
+func (b *Breaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateHalfOpen:
+		if failed {
+			b.transition(StateOpen)
+
+			return
+		}
+
+		b.successes++
+
+		if b.successes >= b.cfg.HalfOpenProbes {
+			b.transition(StateClosed)
+		}
+	case StateClosed:
+		now := b.now()
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+
+		b.requests++
+
+		if failed {
+			b.failures++
+		}
+
+		if b.requests >= b.cfg.MinRequests && float64(b.failures)/float64(b.requests) >= b.cfg.FailureRate {
+			b.transition(StateOpen)
+		}
+	}
+}
+
+// transition moves the breaker to state, resetting the counts of the
+// previous state. b.mu must be held.
+func (b *Breaker) transition(state State) {
+	b.state = state
+	b.generation++
+	b.probes = 0
+	b.successes = 0
+	b.requests = 0
+	b.failures = 0
+	b.windowStart = b.now()
+
+	switch state {
+	case StateOpen:
+		b.openedAt = b.now()
+		b.logger.Warnw("circuit breaker opened", "backend", b.name, "open_duration", b.cfg.OpenDuration)
+	case StateHalfOpen:
+		b.logger.Infow("circuit breaker half open, probing backend", "backend", b.name, "probes", b.cfg.HalfOpenProbes)
+	case StateClosed:
+		b.logger.Infow("circuit breaker closed", "backend", b.name)
+	}
+
+	if b.metrics != nil {
+		b.metrics.BreakerTransition(b.name, string(state))
+	}
+}
@@ -0,0 +1,142 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+type breakerCounter struct {
+	metrics.Nop
+
+	transitions []string
+	rejections  int
+}
+
+func (c *breakerCounter) BreakerTransition(backend, state string) {
+	c.transitions = append(c.transitions, backend+":"+state)
+}
+
+func (c *breakerCounter) BreakerRejection(_ string) { c.rejections++ }
+
+var (
+	errBackend = errors.New("backend failed")
+	errDenied  = errors.New("denied")
+)
+
+func isFailure(err error) bool {
+	return !errors.Is(err, errDenied)
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	counter := &breakerCounter{}
+
+	b := New("openfga", Config{
+		FailureRate:    0.5,
+		MinRequests:    4,
+		Window:         time.Minute,
+		OpenDuration:   10 * time.Second,
+		HalfOpenProbes: 2,
+	}, counter, zap.NewNop().Sugar())
+	b.now = func() time.Time { return now }
+
+	call := func(err error) error {
+		return b.Do(func() error { return err }, isFailure)
+	}
+
+	// denials aren't failures
+	for i := 0; i < 10; i++ {
+		assert.ErrorIs(t, call(errDenied), errDenied)
+	}
+
+	assert.Equal(t, StateClosed, b.State())
+
+	// failures below the minimum requests don't open the breaker
+	assert.ErrorIs(t, call(errBackend), errBackend)
+	assert.NoError(t, call(nil))
+	assert.Equal(t, StateClosed, b.State())
+
+	// the window ends, so the earlier failure isn't counted
+	now = now.Add(time.Minute)
+
+	assert.NoError(t, call(nil))
+	assert.NoError(t, call(nil))
+	assert.ErrorIs(t, call(errBackend), errBackend)
+	assert.Equal(t, StateClosed, b.State())
+	assert.ErrorIs(t, call(errBackend), errBackend)
+	assert.Equal(t, StateOpen, b.State())
+
+	// open breakers reject lookups without calling the backend
+	called := false
+	err := b.Do(func() error { called = true; return nil }, isFailure)
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.False(t, called)
+	assert.Equal(t, 1, counter.rejections)
+
+	// after the open duration a failed probe opens it again
+	now = now.Add(10 * time.Second)
+
+	assert.ErrorIs(t, call(errBackend), errBackend)
+	assert.Equal(t, StateOpen, b.State())
+	assert.ErrorIs(t, call(nil), ErrOpen)
+
+	// and successful probes close it
+	now = now.Add(10 * time.Second)
+
+	assert.NoError(t, call(nil))
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.NoError(t, call(nil))
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(t, []string{
+		"openfga:open",
+		"openfga:half_open",
+		"openfga:open",
+		"openfga:half_open",
+		"openfga:closed",
+	}, counter.transitions)
+}
+
+func TestBreakerHalfOpenProbes(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	b := New("tenant", Config{FailureRate: 1, MinRequests: 1, HalfOpenProbes: 1, OpenDuration: time.Second}, nil, zap.NewNop().Sugar())
+	b.now = func() time.Time { return now }
+
+	assert.ErrorIs(t, b.Do(func() error { return errBackend }, isFailure), errBackend)
+	assert.Equal(t, StateOpen, b.State())
+
+	now = now.Add(time.Second)
+
+	// only one probe is let through while it's in flight
+	err := b.Do(func() error {
+		assert.ErrorIs(t, b.Do(func() error { return nil }, isFailure), ErrOpen)
+
+		return nil
+	}, isFailure)
+	assert.NoError(t, err)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerSlowCalls(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	b := New("openfga", Config{FailureRate: 1, MinRequests: 2, SlowCall: time.Second}, nil, zap.NewNop().Sugar())
+	b.now = func() time.Time { return now }
+
+	slow := func() error {
+		now = now.Add(2 * time.Second)
+
+		return nil
+	}
+
+	assert.NoError(t, b.Do(slow, isFailure))
+	assert.NoError(t, b.Do(slow, isFailure))
+	assert.Equal(t, StateOpen, b.State())
+}
@@ -0,0 +1,61 @@
+package breaker
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var (
+	defaultMinRequests    = 20
+	defaultWindow         = 10 * time.Second
+	defaultOpenDuration   = 5 * time.Second
+	defaultHalfOpenProbes = 3
+)
+
+// Config stores the circuit breaker settings, shared by the breakers of
+// every backend
+type Config struct {
+	// FailureRate is the fraction of failed lookups in a window at which a
+	// breaker opens, 0 disables the breakers
+	FailureRate float64 `mapstructure:"failure-rate"`
+	// SlowCall is the duration above which a lookup counts as failed even
+	// when it succeeds, 0 only counts errors
+	SlowCall       time.Duration `mapstructure:"slow-call"`
+	MinRequests    int           `mapstructure:"min-requests"`
+	Window         time.Duration `mapstructure:"window"`
+	OpenDuration   time.Duration `mapstructure:"open-duration"`
+	HalfOpenProbes int           `mapstructure:"half-open-probes"`
+	// FallbackPrefixOnly resolves ids by their prefix alone, without
+	// authorization, while a breaker is open
+	FallbackPrefixOnly bool `mapstructure:"fallback-prefix-only"`
+}
+
+// Enabled returns true when a failure rate is configured
+func (c Config) Enabled() bool {
+	return c.FailureRate > 0
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Float64("breaker-failure-rate", 0, "fraction of failed backend lookups at which the backend's circuit breaker opens, 0 disables circuit breakers")
+	viperx.MustBindFlag(v, "breaker.failure-rate", flags.Lookup("breaker-failure-rate"))
+
+	flags.Duration("breaker-slow-call", 0, "duration above which a backend lookup counts as failed, 0 only counts errors")
+	viperx.MustBindFlag(v, "breaker.slow-call", flags.Lookup("breaker-slow-call"))
+
+	flags.Bool("breaker-fallback-prefix-only", false, "resolve ids by prefix alone, without authorization, while a backend's circuit breaker is open")
+	viperx.MustBindFlag(v, "breaker.fallback-prefix-only", flags.Lookup("breaker-fallback-prefix-only"))
+
+	v.MustBindEnv("breaker.min-requests")
+	v.MustBindEnv("breaker.window")
+	v.MustBindEnv("breaker.open-duration")
+	v.MustBindEnv("breaker.half-open-probes")
+
+	v.SetDefault("breaker.min-requests", defaultMinRequests)
+	v.SetDefault("breaker.window", defaultWindow)
+	v.SetDefault("breaker.open-duration", defaultOpenDuration)
+	v.SetDefault("breaker.half-open-probes", defaultHalfOpenProbes)
+}
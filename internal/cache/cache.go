@@ -0,0 +1,189 @@
+// Package cache provides a local cache for resolution results and shares
+// invalidations between replicas so stale results don't persist
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key     string
+	ids     []string
+	value   interface{}
+	expires time.Time
+}
+
+// Cache is a size bounded LRU cache with expiring entries. Every entry is
+// tagged with the node id it describes so all results for a node can be
+// invalidated together. It is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+	ids   map[string]map[string]struct{}
+	now   func() time.Time
+}
+
+// New returns a cache holding up to size entries for ttl
+func New(size int, ttl time.Duration) *Cache {
+	if size <= 0 {
+		size = defaultSize
+	}
+
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &Cache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: map[string]*list.Element{},
+		ids:   map[string]map[string]struct{}{},
+		now:   time.Now,
+	}
+}
+
+// NewFromConfig returns the cache described by cfg or nil when neither
+// results nor responses are cached
+func NewFromConfig(cfg Config) *Cache {
+	if !cfg.Enabled && !cfg.Responses {
+		return nil
+	}
+
+	return New(cfg.Size, cfg.TTL)
+}
+
+// Get returns the cached value for key. A nil Cache never has any values.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+
+	if c.now().After(e.expires) {
+		c.remove(el)
+
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+
+	return e.value, true
+}
+
+// Set caches value under key, tagged with the node id it describes
+func (c *Cache) Set(key, id string, value interface{}) {
+	c.SetIDs(key, []string{id}, value)
+}
+
+// SetIDs caches value under key, tagged with every node id it describes so
+// invalidating any of them removes it
+func (c *Cache) SetIDs(key string, ids []string, value interface{}) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	el := c.ll.PushFront(&entry{key: key, ids: ids, value: value, expires: c.now().Add(c.ttl)})
+	c.items[key] = el
+
+	for _, id := range ids {
+		if c.ids[id] == nil {
+			c.ids[id] = map[string]struct{}{}
+		}
+
+		c.ids[id][key] = struct{}{}
+	}
+
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+}
+
+// InvalidateID removes every entry tagged with the given node ids
+func (c *Cache) InvalidateID(ids ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		for key := range c.ids[id] {
+			c.remove(c.items[key])
+		}
+	}
+}
+
+// Purge removes every entry
+func (c *Cache) Purge() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+	c.ids = map[string]map[string]struct{}{}
+}
+
+// TTL returns how long entries are cached for
+func (c *Cache) TTL() time.Duration {
+	if c == nil {
+		return 0
+	}
+
+	return c.ttl
+}
+
+// Len returns the number of cached entries, including expired entries that
+// haven't been evicted yet
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*entry)
+
+	delete(c.items, e.key)
+
+	for _, id := range e.ids {
+		if keys, ok := c.ids[id]; ok {
+			delete(keys, e.key)
+
+			if len(keys) == 0 {
+				delete(c.ids, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+
+	c := New(3, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", "testsrv-1", 1)
+	c.Set("b", "testsrv-1", 2)
+	c.Set("c", "testsrv-2", 3)
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	// b is the least recently used entry and is evicted
+	c.Set("d", "testsrv-3", 4)
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 3, c.Len())
+
+	c.InvalidateID("testsrv-1", "testsrv-2")
+
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+
+	_, ok = c.Get("c")
+	assert.False(t, ok)
+
+	_, ok = c.Get("d")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+
+	_, ok = c.Get("d")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCacheSetIDs(t *testing.T) {
+	c := New(10, time.Minute)
+
+	c.SetIDs("a", []string{"testsrv-1", "testsrv-2"}, 1)
+	c.SetIDs("b", []string{"testsrv-2", "testsrv-3"}, 2)
+
+	c.InvalidateID("testsrv-1")
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+
+	c.InvalidateID("testsrv-3")
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	assert.Empty(t, c.ids)
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+
+	c.Set("a", "testsrv-1", 1)
+	c.InvalidateID("testsrv-1")
+	c.Purge()
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Nil(t, NewFromConfig(Config{}))
+
+	// responses can be cached without caching results
+	assert.NotNil(t, NewFromConfig(Config{Responses: true}))
+}
+
+func TestInvalidationHandlers(t *testing.T) {
+	c := New(10, time.Minute)
+	i := &Invalidator{cache: c, logger: zap.NewNop().Sugar(), origin: "self"}
+
+	c.Set("a", "testsrv-1", 1)
+	c.Set("b", "testsrv-2", 2)
+	c.Set("c", "testsrv-3", 3)
+
+	// invalidations published by this replica have already been applied locally
+	i.handleInvalidation(&nats.Msg{Data: []byte(`{"origin":"self","purge":true}`)})
+	assert.Equal(t, 3, c.Len())
+
+	i.handleInvalidation(&nats.Msg{Data: []byte(`{"origin":"other","ids":["testsrv-1"]}`)})
+	assert.Equal(t, 2, c.Len())
+
+	i.handleDeletion(&nats.Msg{Data: []byte(`{"subjectID":"testsrv-2","eventType":"delete"}`)})
+	assert.Equal(t, 1, c.Len())
+
+	i.handleInvalidation(&nats.Msg{Data: []byte(`{"origin":"other","purge":true}`)})
+	assert.Equal(t, 0, c.Len())
+}
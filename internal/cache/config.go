@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var (
+	defaultSize        = 10000
+	defaultTTL         = time.Minute
+	defaultNATSTimeout = 5 * time.Second
+)
+
+// Config stores the settings for caching resolution results
+type Config struct {
+	Enabled      bool               `mapstructure:"enabled"`
+	Size         int                `mapstructure:"size"`
+	TTL          time.Duration      `mapstructure:"ttl"`
+	Responses    bool               `mapstructure:"responses"`
+	Invalidation InvalidationConfig `mapstructure:"invalidation"`
+}
+
+// InvalidationConfig stores the settings for sharing cache invalidations
+// between replicas over NATS
+type InvalidationConfig struct {
+	NATSURL          string        `mapstructure:"nats-url"`
+	NATSToken        string        `mapstructure:"nats-token"`
+	NATSCredsFile    string        `mapstructure:"nats-creds-file"`
+	Subject          string        `mapstructure:"subject"`
+	DeletionSubjects []string      `mapstructure:"deletion-subjects"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+}
+
+// Enabled returns true when a NATS server has been configured
+func (c InvalidationConfig) Enabled() bool {
+	return c.NATSURL != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Bool("cache", false, "cache resolution results")
+	viperx.MustBindFlag(v, "cache.enabled", flags.Lookup("cache"))
+
+	flags.Int("cache-size", defaultSize, "maximum number of cached results")
+	viperx.MustBindFlag(v, "cache.size", flags.Lookup("cache-size"))
+
+	flags.Duration("cache-ttl", defaultTTL, "how long results are cached for")
+	viperx.MustBindFlag(v, "cache.ttl", flags.Lookup("cache-ttl"))
+
+	flags.Bool("cache-responses", false, "cache responses of the query and resolve api endpoints")
+	viperx.MustBindFlag(v, "cache.responses", flags.Lookup("cache-responses"))
+
+	flags.String("cache-invalidation-nats-url", "", "nats server used to share cache invalidations between replicas")
+	viperx.MustBindFlag(v, "cache.invalidation.nats-url", flags.Lookup("cache-invalidation-nats-url"))
+
+	v.MustBindEnv("cache.invalidation.nats-token")
+	v.MustBindEnv("cache.invalidation.nats-creds-file")
+	v.MustBindEnv("cache.invalidation.subject")
+	v.MustBindEnv("cache.invalidation.deletion-subjects")
+	v.MustBindEnv("cache.invalidation.timeout")
+
+	v.SetDefault("cache.invalidation.subject", "com.infratographer.node-resolver.cache.invalidate")
+	v.SetDefault("cache.invalidation.deletion-subjects", []string{"com.infratographer.changes.delete.>"})
+	v.SetDefault("cache.invalidation.timeout", defaultNATSTimeout)
+}
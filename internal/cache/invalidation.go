@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// Invalidation is the message shared between replicas when cached results
+// become stale. Purge invalidates every entry, for example when the prefix
+// map changes.
+type Invalidation struct {
+	Origin string   `json:"origin"`
+	IDs    []string `json:"ids,omitempty"`
+	Purge  bool     `json:"purge,omitempty"`
+}
+
+// changeMessage is the part of the infratographer change events needed to
+// invalidate deleted nodes
+type changeMessage struct {
+	SubjectID string `json:"subjectID"`
+}
+
+// Invalidator applies invalidations to the local cache and shares them with
+// the other replicas over NATS. It also invalidates nodes when the change
+// events published by the backends report they've been deleted.
+type Invalidator struct {
+	cfg    InvalidationConfig
+	cache  *Cache
+	logger *zap.SugaredLogger
+	conn   *nats.Conn
+	subs   []*nats.Subscription
+	origin string
+}
+
+// NewInvalidator connects to NATS and subscribes to invalidations from other
+// replicas and deletion events
+func NewInvalidator(cfg InvalidationConfig, c *Cache, appName string, logger *zap.SugaredLogger) (*Invalidator, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultNATSTimeout
+	}
+
+	opts := []nats.Option{nats.Name(appName), nats.Timeout(cfg.Timeout)}
+
+	switch {
+	case cfg.NATSCredsFile != "":
+		opts = append(opts, nats.UserCredentials(cfg.NATSCredsFile))
+	case cfg.NATSToken != "":
+		opts = append(opts, nats.Token(cfg.NATSToken))
+	}
+
+	conn, err := nats.Connect(cfg.NATSURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Invalidator{
+		cfg:    cfg,
+		cache:  c,
+		logger: logger,
+		conn:   conn,
+		origin: newOrigin(),
+	}
+
+	sub, err := conn.Subscribe(cfg.Subject, i.handleInvalidation)
+	if err != nil {
+		i.Close()
+
+		return nil, err
+	}
+
+	i.subs = append(i.subs, sub)
+
+	for _, subject := range cfg.DeletionSubjects {
+		sub, err := conn.Subscribe(subject, i.handleDeletion)
+		if err != nil {
+			i.Close()
+
+			return nil, err
+		}
+
+		i.subs = append(i.subs, sub)
+	}
+
+	return i, nil
+}
+
+// InvalidateID removes the ids from the local cache and the caches of the other replicas
+func (i *Invalidator) InvalidateID(ctx context.Context, ids ...string) error {
+	i.cache.InvalidateID(ids...)
+
+	return i.publish(ctx, Invalidation{Origin: i.origin, IDs: ids})
+}
+
+// Purge empties the local cache and the caches of the other replicas
+func (i *Invalidator) Purge(ctx context.Context) error {
+	i.cache.Purge()
+
+	return i.publish(ctx, Invalidation{Origin: i.origin, Purge: true})
+}
+
+// Close unsubscribes and closes the NATS connection
+func (i *Invalidator) Close() {
+	for _, sub := range i.subs {
+		_ = sub.Unsubscribe()
+	}
+
+	i.conn.Close()
+}
+
+func (i *Invalidator) publish(ctx context.Context, inv Invalidation) error {
+	ctx, cancel := context.WithTimeout(ctx, i.cfg.Timeout)
+	defer cancel()
+
+	b, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+
+	if err := i.conn.Publish(i.cfg.Subject, b); err != nil {
+		return err
+	}
+
+	return i.conn.FlushWithContext(ctx)
+}
+
+func (i *Invalidator) handleInvalidation(msg *nats.Msg) {
+	var inv Invalidation
+	if err := json.Unmarshal(msg.Data, &inv); err != nil {
+		i.logger.Warnw("ignoring invalid cache invalidation", "error", err)
+
+		return
+	}
+
+	// invalidations from this replica have already been applied
+	if inv.Origin == i.origin {
+		return
+	}
+
+	if inv.Purge {
+		i.logger.Debugw("purging cache", "origin", inv.Origin)
+		i.cache.Purge()
+
+		return
+	}
+
+	i.cache.InvalidateID(inv.IDs...)
+}
+
+func (i *Invalidator) handleDeletion(msg *nats.Msg) {
+	var change changeMessage
+	if err := json.Unmarshal(msg.Data, &change); err != nil {
+		i.logger.Warnw("ignoring invalid change message", "subject", msg.Subject, "error", err)
+
+		return
+	}
+
+	if change.SubjectID == "" {
+		return
+	}
+
+	i.logger.Debugw("invalidating deleted node", "id", change.SubjectID)
+	i.cache.InvalidateID(change.SubjectID)
+}
+
+func newOrigin() string {
+	b := make([]byte, 8) //nolint:gomnd
+
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,118 @@
+// Package chaos injects faults into requests, so gateway owners can test how
+// their retries and partial failure handling cope with a misbehaving
+// subgraph
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+var (
+	// ErrInvalidConfig is returned for rates outside 0 to 1 and error
+	// statuses that aren't errors
+	ErrInvalidConfig = errors.New("invalid chaos config")
+	// ErrInjected is the error of ids failed by fault injection. It's
+	// reported like a failing backend, so clients handle it as they would
+	// a real failure.
+	ErrInjected = errcode.New(errcode.Unavailable, errors.New("injected fault"))
+)
+
+// Injector injects the faults of its config. An Injector is safe for
+// concurrent use.
+type Injector struct {
+	cfg      Config
+	prefixes map[string]bool
+
+	// random returns a number in [0, 1) deciding whether to inject a fault
+	random func() float64
+}
+
+// New returns an Injector for cfg
+func New(cfg Config) (*Injector, error) {
+	for name, rate := range map[string]float64{
+		"latency rate":        cfg.LatencyRate,
+		"error rate":          cfg.ErrorRate,
+		"entity failure rate": cfg.EntityFailureRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%w: %s must be between 0 and 1", ErrInvalidConfig, name)
+		}
+	}
+
+	if cfg.ErrorRate != 0 && (cfg.ErrorStatus < http.StatusBadRequest || cfg.ErrorStatus > 599) {
+		return nil, fmt.Errorf("%w: error status %d isn't an error", ErrInvalidConfig, cfg.ErrorStatus)
+	}
+
+	prefixes := make(map[string]bool, len(cfg.Prefixes))
+	for _, prefix := range cfg.Prefixes {
+		prefixes[prefix] = true
+	}
+
+	return &Injector{
+		cfg:      cfg,
+		prefixes: prefixes,
+		random:   rand.Float64, //nolint:gosec // sampling doesn't need a secure source
+	}, nil
+}
+
+// Middleware delays requests and answers them with the error status, each
+// for its configured fraction of requests
+func (i *Injector) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if i.sample(i.cfg.LatencyRate) {
+				if err := sleep(c.Request().Context(), i.cfg.Latency); err != nil {
+					return err
+				}
+			}
+
+			if i.sample(i.cfg.ErrorRate) {
+				return echo.NewHTTPError(i.cfg.ErrorStatus, echo.Map{
+					"code":    errcode.Unavailable,
+					"message": "injected fault",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// FailID returns ErrInjected when an id with prefix should fail. Ids in
+// _entities batches fail for the configured fraction of ids, and ids with
+// a configured prefix always fail.
+func (i *Injector) FailID(prefix string, entity bool) error {
+	if i.prefixes[prefix] || (entity && i.sample(i.cfg.EntityFailureRate)) {
+		return ErrInjected
+	}
+
+	return nil
+}
+
+// sample reports whether a fault injected for a fraction rate of requests
+// is injected
+func (i *Injector) sample(rate float64) bool {
+	return rate > 0 && i.random() < rate
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
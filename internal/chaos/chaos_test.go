@@ -0,0 +1,94 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInvalid(t *testing.T) {
+	for _, cfg := range []Config{
+		{LatencyRate: 1.5},
+		{ErrorRate: -0.1},
+		{EntityFailureRate: 2},
+		{ErrorRate: 0.5, ErrorStatus: http.StatusOK},
+	} {
+		_, err := New(cfg)
+		assert.ErrorIs(t, err, ErrInvalidConfig, "%+v", cfg)
+	}
+
+	// the error status isn't used without an error rate
+	_, err := New(Config{ErrorStatus: http.StatusOK})
+	assert.NoError(t, err)
+}
+
+func TestMiddleware(t *testing.T) {
+	i, err := New(Config{
+		Latency:     20 * time.Millisecond,
+		LatencyRate: 0.5,
+		ErrorStatus: http.StatusBadGateway,
+		ErrorRate:   0.25,
+	})
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(i.Middleware())
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+
+	serve := func(sample float64) (int, time.Duration) {
+		i.random = func() float64 { return sample }
+
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		return rec.Code, time.Since(start)
+	}
+
+	code, took := serve(0.1)
+	assert.Equal(t, http.StatusBadGateway, code)
+	assert.GreaterOrEqual(t, took, 20*time.Millisecond)
+
+	code, took = serve(0.4)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.GreaterOrEqual(t, took, 20*time.Millisecond)
+
+	code, took = serve(0.9)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Less(t, took, 20*time.Millisecond)
+}
+
+func TestMiddlewareCanceled(t *testing.T) {
+	i, err := New(Config{Latency: time.Hour, LatencyRate: 1})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx), httptest.NewRecorder())
+
+	err = i.Middleware()(func(c echo.Context) error { return nil })(c)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFailID(t *testing.T) {
+	i, err := New(Config{EntityFailureRate: 0.5, Prefixes: []string{"testbad"}})
+	require.NoError(t, err)
+
+	i.random = func() float64 { return 0.9 }
+
+	assert.ErrorIs(t, i.FailID("testbad", false), ErrInjected)
+	assert.ErrorIs(t, i.FailID("testbad", true), ErrInjected)
+	assert.NoError(t, i.FailID("testsrv", true))
+
+	i.random = func() float64 { return 0.1 }
+
+	assert.ErrorIs(t, i.FailID("testsrv", true), ErrInjected)
+	assert.NoError(t, i.FailID("testsrv", false), "the failure rate only applies to entities")
+}
@@ -0,0 +1,55 @@
+package chaos
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+// Config stores the faults injected into requests. Faults are only injected
+// when Inject is set, which is only done by the --chaos-enabled flag of the
+// serve command.
+type Config struct {
+	Inject bool `mapstructure:"-"`
+	// Latency is added to a fraction LatencyRate of requests
+	Latency     time.Duration `mapstructure:"latency"`
+	LatencyRate float64       `mapstructure:"latency-rate"`
+	// ErrorStatus is returned for a fraction ErrorRate of requests
+	// instead of serving them
+	ErrorStatus int     `mapstructure:"error-status"`
+	ErrorRate   float64 `mapstructure:"error-rate"`
+	// EntityFailureRate is the fraction of ids in _entities batches that
+	// fail, so the batch partially fails
+	EntityFailureRate float64 `mapstructure:"entity-failure-rate"`
+	// Prefixes are prefixes whose ids always fail
+	Prefixes []string `mapstructure:"prefixes"`
+}
+
+// Enabled returns true when faults are injected
+func (c Config) Enabled() bool {
+	return c.Inject
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Duration("chaos-latency", 0, "latency added to a fraction of requests")
+	viperx.MustBindFlag(v, "chaos.latency", flags.Lookup("chaos-latency"))
+
+	flags.Float64("chaos-latency-rate", 0, "fraction of requests delayed by the chaos latency")
+	viperx.MustBindFlag(v, "chaos.latency-rate", flags.Lookup("chaos-latency-rate"))
+
+	flags.Int("chaos-error-status", http.StatusServiceUnavailable, "http status returned for a fraction of requests")
+	viperx.MustBindFlag(v, "chaos.error-status", flags.Lookup("chaos-error-status"))
+
+	flags.Float64("chaos-error-rate", 0, "fraction of requests answered with the chaos error status")
+	viperx.MustBindFlag(v, "chaos.error-rate", flags.Lookup("chaos-error-rate"))
+
+	flags.Float64("chaos-entity-failure-rate", 0, "fraction of ids in _entities batches that fail")
+	viperx.MustBindFlag(v, "chaos.entity-failure-rate", flags.Lookup("chaos-entity-failure-rate"))
+
+	flags.StringSlice("chaos-prefixes", nil, "prefixes whose ids always fail")
+	viperx.MustBindFlag(v, "chaos.prefixes", flags.Lookup("chaos-prefixes"))
+}
@@ -5,14 +5,65 @@ import (
 	"go.infratographer.com/x/crdbx"
 	"go.infratographer.com/x/echox"
 	"go.infratographer.com/x/loggingx"
-	"go.infratographer.com/x/otelx"
+
+	"go.infratographer.com/node-resolver/internal/admin"
+	"go.infratographer.com/node-resolver/internal/announce"
+	"go.infratographer.com/node-resolver/internal/audit"
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/breaker"
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/chaos"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/featureflags"
+	"go.infratographer.com/node-resolver/internal/grpcapi"
+	"go.infratographer.com/node-resolver/internal/hedge"
+	"go.infratographer.com/node-resolver/internal/metrics"
+	"go.infratographer.com/node-resolver/internal/oidc"
+	"go.infratographer.com/node-resolver/internal/policy"
+	"go.infratographer.com/node-resolver/internal/ratelimit"
+	"go.infratographer.com/node-resolver/internal/registry"
+	"go.infratographer.com/node-resolver/internal/schemasync"
+	"go.infratographer.com/node-resolver/internal/schemaurl"
+	"go.infratographer.com/node-resolver/internal/schemawatch"
+	"go.infratographer.com/node-resolver/internal/shed"
+	"go.infratographer.com/node-resolver/internal/spiffex"
+	"go.infratographer.com/node-resolver/internal/supergraph"
+	"go.infratographer.com/node-resolver/internal/tenant"
+	"go.infratographer.com/node-resolver/internal/tracing"
+	"go.infratographer.com/node-resolver/internal/vault"
 )
 
 // AppConfig stores all the config values for our application
 var AppConfig struct {
-	CRDB       crdbx.Config
-	Logging    loggingx.Config
-	Server     echox.Config
-	Tracing    otelx.Config
-	SchemaFile *string
+	Admin        admin.Config
+	Announce     announce.Config
+	Audit        audit.Config
+	Authz        authz.Config
+	Backend      backend.Config
+	Breaker      breaker.Config
+	Cache        cache.Config
+	Chaos        chaos.Config
+	CRDB         crdbx.Config
+	Errors       errcode.Config
+	FeatureFlags featureflags.Config
+	GRPC         grpcapi.Config
+	Hedge        hedge.Config
+	Logging      loggingx.Config
+	Metrics      metrics.Config
+	OIDC         oidc.Config
+	Policy       policy.Config
+	RateLimit    ratelimit.Config
+	Registry     registry.Config
+	Server       echox.Config
+	Tracing      tracing.Config
+	SchemaFile   *string
+	SchemaURL    schemaurl.Config
+	SchemaWatch  schemawatch.Config
+	Shed         shed.Config
+	SPIFFE       spiffex.Config
+	Supergraph   supergraph.Config
+	Sync         schemasync.Config
+	Tenant       tenant.Config
+	Vault        vault.Config
 }
@@ -0,0 +1,181 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is the language of the built-in messages
+const DefaultLanguage = "en"
+
+var (
+	// ErrUnknownCode is returned when a message is configured for a code
+	// that doesn't exist
+	ErrUnknownCode = errors.New("unknown error code")
+	// ErrInvalidLanguage is returned when messages are configured for a
+	// language that isn't a valid BCP 47 tag
+	ErrInvalidLanguage = errors.New("invalid message language")
+)
+
+// defaultMessages are the built-in English messages
+var defaultMessages = map[Code]string{
+	InvalidRequest: "The request is invalid: {{.Message}}",
+	InvalidID:      "The id isn't valid.",
+	UnknownPrefix:  "The id doesn't belong to a known resource type.",
+	Unauthorized:   "You aren't allowed to access this resource.",
+	Denied:         "The request was denied: {{.Message}}",
+	Overloaded:     "The service is busy. Try again shortly.",
+	Internal:       "Something went wrong. Try again later.",
+	Timeout:        "The request took too long. Try again later.",
+	Unavailable:    "A service needed for this request is unavailable. Try again later.",
+	Deleted:        "This resource was deleted.",
+	// clients match the message of the persisted query protocol, so it
+	// mustn't be overridden
+	PersistedQueryNotFound: "PersistedQueryNotFound",
+	RateLimited:            "Too many requests. Try again shortly.",
+	NotFound:               "This resource doesn't exist.",
+	QueryTooComplex:        "The query is too complex: {{.Message}}",
+	UnknownInterface:       "The type of the representation isn't known.",
+	TypeInterfaceMismatch:  "The resource isn't of the type of the representation.",
+}
+
+// MessageData is passed to message templates
+type MessageData struct {
+	// Code is the code of the error
+	Code Code
+	// Message is the original message of the error
+	Message string
+}
+
+// Catalog renders the messages of coded errors from templates keyed by code,
+// in the language a client prefers, so portals showing the errors can
+// present them in the user's language or house style. A Catalog is safe for
+// concurrent use.
+type Catalog struct {
+	// languages are the tags of the catalog, the default language first
+	languages []string
+	matcher   language.Matcher
+	templates map[string]map[Code]*template.Template
+}
+
+// NewCatalog returns a catalog of the built-in messages along with the
+// messages of cfg, which take precedence
+func NewCatalog(cfg Config) (*Catalog, error) {
+	defaultLang := cfg.DefaultLanguage
+	if defaultLang == "" {
+		defaultLang = DefaultLanguage
+	}
+
+	sources := map[string]map[string]string{DefaultLanguage: {}}
+	for code, msg := range defaultMessages {
+		sources[DefaultLanguage][string(code)] = msg
+	}
+
+	for lang, msgs := range cfg.Messages {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLanguage, lang)
+		}
+
+		if sources[tag.String()] == nil {
+			sources[tag.String()] = map[string]string{}
+		}
+
+		for code, msg := range msgs {
+			sources[tag.String()][code] = msg
+		}
+	}
+
+	defaultTag, err := language.Parse(defaultLang)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLanguage, defaultLang)
+	}
+
+	c := &Catalog{templates: map[string]map[Code]*template.Template{}}
+
+	for lang, msgs := range sources {
+		c.templates[lang] = map[Code]*template.Template{}
+
+		for code, msg := range msgs {
+			if !known(Code(code)) {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownCode, code)
+			}
+
+			t, err := template.New(lang + "/" + code).Option("missingkey=error").Parse(msg)
+			if err != nil {
+				return nil, err
+			}
+
+			c.templates[lang][Code(code)] = t
+		}
+
+		if lang != defaultTag.String() {
+			c.languages = append(c.languages, lang)
+		}
+	}
+
+	// the matcher falls back to its first tag
+	sort.Strings(c.languages)
+	c.languages = append([]string{defaultTag.String()}, c.languages...)
+
+	tags := make([]language.Tag, len(c.languages))
+	for i, lang := range c.languages {
+		tags[i] = language.Make(lang)
+	}
+
+	c.matcher = language.NewMatcher(tags)
+
+	return c, nil
+}
+
+// Language returns the language of the catalog best matching an
+// Accept-Language header, or the default language
+func (c *Catalog) Language(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return c.languages[0]
+	}
+
+	_, i, _ := c.matcher.Match(tags...)
+
+	return c.languages[i]
+}
+
+// Message returns the message of an error with code in lang, rendered with
+// its original message. The message of the default language is used when
+// lang has none for code, and the original message when neither has one or
+// the template fails.
+func (c *Catalog) Message(lang string, code Code, message string) string {
+	t, ok := c.templates[lang][code]
+	if !ok {
+		t, ok = c.templates[c.languages[0]][code]
+	}
+
+	if !ok {
+		return message
+	}
+
+	var sb strings.Builder
+
+	if err := t.Execute(&sb, MessageData{Code: code, Message: message}); err != nil {
+		return message
+	}
+
+	return sb.String()
+}
+
+// known reports whether code is one of Codes
+func known(code Code) bool {
+	for _, c := range Codes() {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,120 @@
+package errcode_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+func TestCatalog(t *testing.T) {
+	c, err := errcode.NewCatalog(errcode.Config{
+		Messages: map[string]map[string]string{
+			"de": {
+				"unauthorized": "Sie dürfen diese Ressource nicht abrufen.",
+				"denied":       "Die Anfrage wurde abgelehnt: {{.Message}}",
+			},
+			"en": {
+				"internal": "Our house style: {{.Code}}",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		TestName       string
+		acceptLanguage string
+		code           errcode.Code
+		message        string
+		language       string
+		expected       string
+	}{
+		{
+			TestName: "default language",
+			code:     errcode.Unauthorized,
+			message:  "not authorized to resolve id",
+			language: "en",
+			expected: "You aren't allowed to access this resource.",
+		},
+		{
+			TestName:       "preferred language",
+			acceptLanguage: "fr;q=0.9, de-CH, en;q=0.5",
+			code:           errcode.Unauthorized,
+			language:       "de",
+			expected:       "Sie dürfen diese Ressource nicht abrufen.",
+		},
+		{
+			TestName:       "original message",
+			acceptLanguage: "de",
+			code:           errcode.Denied,
+			message:        "prefix testtkn is restricted",
+			language:       "de",
+			expected:       "Die Anfrage wurde abgelehnt: prefix testtkn is restricted",
+		},
+		{
+			TestName:       "missing translation",
+			acceptLanguage: "de",
+			code:           errcode.Timeout,
+			language:       "de",
+			expected:       "The request took too long. Try again later.",
+		},
+		{
+			TestName:       "overridden built-in message",
+			acceptLanguage: "en-US",
+			code:           errcode.Internal,
+			language:       "en",
+			expected:       "Our house style: internal",
+		},
+		{
+			TestName:       "unsupported language",
+			acceptLanguage: "ja",
+			code:           errcode.Deleted,
+			language:       "en",
+			expected:       "This resource was deleted.",
+		},
+		{
+			TestName:       "invalid header",
+			acceptLanguage: ";;;",
+			code:           errcode.Deleted,
+			language:       "en",
+			expected:       "This resource was deleted.",
+		},
+		{
+			TestName: "unknown code",
+			code:     errcode.Code("other"),
+			message:  "original",
+			language: "en",
+			expected: "original",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			lang := c.Language(tt.acceptLanguage)
+			assert.Equal(t, tt.language, lang)
+			assert.Equal(t, tt.expected, c.Message(lang, tt.code, tt.message))
+		})
+	}
+}
+
+func TestCatalogMessagesForEveryCode(t *testing.T) {
+	c, err := errcode.NewCatalog(errcode.Config{})
+	require.NoError(t, err)
+
+	for _, code := range errcode.Codes() {
+		assert.NotEqual(t, "original", c.Message(errcode.DefaultLanguage, code, "original"), "%s has no built-in message", code)
+	}
+}
+
+func TestCatalogInvalidConfig(t *testing.T) {
+	_, err := errcode.NewCatalog(errcode.Config{Messages: map[string]map[string]string{"en": {"missing": "x"}}})
+	assert.ErrorIs(t, err, errcode.ErrUnknownCode)
+
+	_, err = errcode.NewCatalog(errcode.Config{Messages: map[string]map[string]string{"not a language": {"internal": "x"}}})
+	assert.ErrorIs(t, err, errcode.ErrInvalidLanguage)
+
+	_, err = errcode.NewCatalog(errcode.Config{Messages: map[string]map[string]string{"en": {"internal": "{{"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package errcode
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+// Config stores the settings for localized error messages
+type Config struct {
+	// Localize replaces error messages with those of the catalog
+	Localize bool `mapstructure:"localize"`
+	// DefaultLanguage is used for clients that don't accept a language of
+	// the catalog
+	DefaultLanguage string `mapstructure:"default-language"`
+	// Messages are message templates by language and code, added to the
+	// built-in English messages or replacing them
+	Messages map[string]map[string]string `mapstructure:"messages"`
+	// Mask replaces error messages with those of the catalog in the default
+	// language, hiding internal details such as id parse errors and schema
+	// type names, and reports errors without a code as Internal
+	Mask bool `mapstructure:"mask"`
+	// Verbose keeps the original message of masked or localized errors in
+	// their details, for development
+	Verbose bool `mapstructure:"verbose"`
+}
+
+// Enabled returns true when error messages are localized or masked
+func (c Config) Enabled() bool {
+	return c.Localize || c.Mask
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Bool("localize-errors", false, "replace error messages with the error catalog, in the language the client accepts")
+	viperx.MustBindFlag(v, "errors.localize", flags.Lookup("localize-errors"))
+
+	flags.String("errors-default-language", DefaultLanguage, "language of error messages for clients that don't accept one in the catalog")
+	viperx.MustBindFlag(v, "errors.default-language", flags.Lookup("errors-default-language"))
+
+	flags.Bool("mask-errors", false, "replace error messages with the stable messages of their codes, hiding internal details")
+	viperx.MustBindFlag(v, "errors.mask", flags.Lookup("mask-errors"))
+
+	flags.Bool("verbose-errors", false, "keep the original message of masked or localized errors in their details, for development")
+	viperx.MustBindFlag(v, "errors.verbose", flags.Lookup("verbose-errors"))
+}
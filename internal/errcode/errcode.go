@@ -0,0 +1,99 @@
+// Package errcode defines the error codes node-resolver reports in graphql
+// error extensions, resolve api errors and metrics labels.
+//
+// Clients and automation key off these codes, so they're stable: a released
+// code never changes value and is never reused with a different meaning.
+// Codes may be added in any release, so clients should handle codes they
+// don't know like Internal. Removing a code is a breaking change.
+package errcode
+
+import "errors"
+
+// Code identifies the kind of an error
+type Code string
+
+const (
+	// InvalidRequest is reported for requests that are malformed, such as
+	// graphql documents that don't parse or validate
+	InvalidRequest Code = "invalid_request"
+	// InvalidID is reported for ids that aren't valid prefixed ids
+	InvalidID Code = "invalid_id"
+	// UnknownPrefix is reported for ids whose prefix isn't in the schema
+	UnknownPrefix Code = "unknown_prefix"
+	// Unauthorized is reported when the subject may not resolve an id
+	Unauthorized Code = "unauthorized"
+	// Denied is reported for requests denied by policy
+	Denied Code = "denied"
+	// Overloaded is reported for requests shed under memory pressure
+	Overloaded Code = "overloaded"
+	// Internal is reported for any other error
+	Internal Code = "internal"
+	// Timeout is reported when a lookup or the request runs out of time
+	Timeout Code = "timeout"
+	// Unavailable is reported when a backend needed to resolve an id is
+	// failing and isn't being called
+	Unavailable Code = "unavailable"
+	// Deleted is reported for ids of nodes that were deleted or archived,
+	// as opposed to ids that never existed
+	Deleted Code = "deleted"
+	// PersistedQueryNotFound is reported for persisted queries sent by hash
+	// that aren't known, so the client sends the query again in full
+	PersistedQueryNotFound Code = "persisted_query_not_found"
+	// RateLimited is reported for requests of clients over their rate limit
+	RateLimited Code = "rate_limited"
+	// NotFound is reported for ids whose node doesn't exist, as found by the
+	// node backend
+	NotFound Code = "not_found"
+	// QueryTooComplex is reported for graphql operations over the query
+	// depth, alias or complexity limits
+	QueryTooComplex Code = "query_too_complex"
+	// UnknownInterface is reported for _entities representations whose
+	// __typename isn't an interface of the schema
+	UnknownInterface Code = "unknown_interface"
+	// TypeInterfaceMismatch is reported for _entities representations whose
+	// id resolves to a type that doesn't implement their __typename
+	TypeInterfaceMismatch Code = "type_interface_mismatch"
+)
+
+// ExtensionKey is the key of the code in the extensions of graphql errors
+const ExtensionKey = "code"
+
+// Codes returns every code, in the order they were added
+func Codes() []Code {
+	return []Code{InvalidRequest, InvalidID, UnknownPrefix, Unauthorized, Denied, Overloaded, Internal, Timeout, Unavailable, Deleted, PersistedQueryNotFound, RateLimited, NotFound, QueryTooComplex, UnknownInterface, TypeInterfaceMismatch}
+}
+
+// Error is an error with a code. It implements graphql-go's ExtendedError,
+// so the code is reported in the extensions of graphql errors.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+// New returns err with the given code
+func New(code Code, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Extensions returns the graphql error extensions reporting the code
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{ExtensionKey: string(e.Code)}
+}
+
+// Of returns the code of err, or Internal when it doesn't have one
+func Of(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return Internal
+}
@@ -0,0 +1,61 @@
+package errcode_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+// TestCodesAreStable fails when a code changes value or is removed. Codes are
+// relied on by clients, so this test must only change to add codes.
+func TestCodesAreStable(t *testing.T) {
+	expected := []struct {
+		code  errcode.Code
+		value string
+	}{
+		{errcode.InvalidRequest, "invalid_request"},
+		{errcode.InvalidID, "invalid_id"},
+		{errcode.UnknownPrefix, "unknown_prefix"},
+		{errcode.Unauthorized, "unauthorized"},
+		{errcode.Denied, "denied"},
+		{errcode.Overloaded, "overloaded"},
+		{errcode.Internal, "internal"},
+		{errcode.Timeout, "timeout"},
+		{errcode.Unavailable, "unavailable"},
+		{errcode.Deleted, "deleted"},
+		{errcode.PersistedQueryNotFound, "persisted_query_not_found"},
+		{errcode.RateLimited, "rate_limited"},
+		{errcode.NotFound, "not_found"},
+		{errcode.QueryTooComplex, "query_too_complex"},
+		{errcode.UnknownInterface, "unknown_interface"},
+		{errcode.TypeInterfaceMismatch, "type_interface_mismatch"},
+	}
+
+	codes := errcode.Codes()
+
+	assert.Len(t, codes, len(expected), "every code must be listed here")
+
+	for i, tt := range expected {
+		assert.Equal(t, tt.value, string(tt.code))
+
+		if i < len(codes) {
+			assert.Equal(t, tt.code, codes[i], "codes must be listed in the order they were added")
+		}
+	}
+
+	assert.Equal(t, "code", errcode.ExtensionKey)
+}
+
+func TestError(t *testing.T) {
+	base := errors.New("unknown prefix")
+	err := fmt.Errorf("resolving: %w", errcode.New(errcode.UnknownPrefix, base))
+
+	assert.ErrorIs(t, err, base)
+	assert.Equal(t, errcode.UnknownPrefix, errcode.Of(err))
+	assert.Equal(t, errcode.Internal, errcode.Of(base))
+	assert.Equal(t, map[string]interface{}{"code": "unknown_prefix"}, errcode.New(errcode.UnknownPrefix, base).Extensions())
+}
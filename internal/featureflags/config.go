@@ -0,0 +1,80 @@
+package featureflags
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var (
+	defaultCacheTTL  = 30 * time.Second
+	defaultCacheSize = 10000
+	defaultTimeout   = time.Second
+)
+
+// Provider names
+const (
+	ProviderStatic = "static"
+	ProviderOFREP  = "ofrep"
+)
+
+// Config stores the settings for gating prefixes with feature flags
+type Config struct {
+	// Provider evaluates the flags, static or ofrep
+	Provider string `mapstructure:"provider"`
+	// URL is the base url of the OFREP api
+	URL string `mapstructure:"url"`
+	// Token is sent as a bearer token to the OFREP api
+	Token string `mapstructure:"token"`
+	// Environment is sent in the evaluation context, so flags can be
+	// toggled per environment
+	Environment string `mapstructure:"environment"`
+	// Prefixes are the prefixes gated by a flag. Other prefixes are always
+	// resolved without evaluating a flag.
+	Prefixes []string `mapstructure:"prefixes"`
+	// KeyPrefix is prepended to a prefix to name its flag
+	KeyPrefix string        `mapstructure:"key-prefix"`
+	CacheTTL  time.Duration `mapstructure:"cache-ttl"`
+	CacheSize int           `mapstructure:"cache-size"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+	// Static are the flags of the static provider by key
+	Static map[string]StaticFlag `mapstructure:"static"`
+
+	// Transport is used for requests to the OFREP api, defaulting to
+	// http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// Enabled returns true when prefixes are gated by feature flags
+func (c Config) Enabled() bool {
+	return len(c.Prefixes) != 0
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("feature-flags-provider", ProviderStatic, "feature flag provider gating prefixes: static or ofrep")
+	viperx.MustBindFlag(v, "featureflags.provider", flags.Lookup("feature-flags-provider"))
+
+	flags.String("feature-flags-url", "", "base url of the OpenFeature remote evaluation (OFREP) api")
+	viperx.MustBindFlag(v, "featureflags.url", flags.Lookup("feature-flags-url"))
+
+	flags.String("feature-flags-environment", "", "environment sent to the feature flag provider")
+	viperx.MustBindFlag(v, "featureflags.environment", flags.Lookup("feature-flags-environment"))
+
+	flags.StringSlice("feature-flags-prefixes", nil, "prefixes only resolved while their feature flag is enabled")
+	viperx.MustBindFlag(v, "featureflags.prefixes", flags.Lookup("feature-flags-prefixes"))
+
+	v.MustBindEnv("featureflags.token")
+	v.MustBindEnv("featureflags.key-prefix")
+	v.MustBindEnv("featureflags.cache-ttl")
+	v.MustBindEnv("featureflags.cache-size")
+	v.MustBindEnv("featureflags.timeout")
+
+	v.SetDefault("featureflags.key-prefix", "prefix.")
+	v.SetDefault("featureflags.cache-ttl", defaultCacheTTL)
+	v.SetDefault("featureflags.cache-size", defaultCacheSize)
+	v.SetDefault("featureflags.timeout", defaultTimeout)
+}
@@ -0,0 +1,132 @@
+// Package featureflags gates the resolution of prefixes with feature flags,
+// so a new resource type can be dark-launched and enabled per environment or
+// caller before general availability. Flags are evaluated by a Provider
+// mirroring the OpenFeature provider interface.
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/cache"
+)
+
+// ErrUnknownProvider is returned when a configured provider isn't supported
+var ErrUnknownProvider = errors.New("unknown feature flag provider")
+
+// Evaluation context attributes
+const (
+	AttributePrefix      = "prefix"
+	AttributeEnvironment = "environment"
+)
+
+// EvaluationContext is the context flags are evaluated in, as in
+// OpenFeature. The targeting key is the subject of the request.
+type EvaluationContext struct {
+	TargetingKey string                 `json:"targetingKey,omitempty"`
+	Attributes   map[string]interface{} `json:"-"`
+}
+
+// Provider evaluates boolean flags like an OpenFeature provider, returning
+// defaultValue along with an error when a flag can't be evaluated. An
+// OpenFeature client can be adapted to it to use any OpenFeature provider.
+type Provider interface {
+	BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx EvaluationContext) (bool, error)
+}
+
+// NewProvider returns the provider configured in cfg
+func NewProvider(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderStatic, "":
+		return NewStatic(cfg.Static), nil
+	case ProviderOFREP:
+		o, err := NewOFREP(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return o, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, cfg.Provider)
+	}
+}
+
+// Gate reports whether prefixes gated by a flag are enabled for a caller.
+// Evaluations are cached per prefix and subject for the cache ttl. A Gate is
+// safe for concurrent use.
+type Gate struct {
+	provider    Provider
+	logger      *zap.SugaredLogger
+	prefixes    map[string]bool
+	keyPrefix   string
+	environment string
+	evaluations *cache.Cache
+}
+
+// NewGate returns a Gate evaluating the flags of the configured prefixes
+// with provider
+func NewGate(cfg Config, provider Provider, logger *zap.SugaredLogger) *Gate {
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = defaultCacheSize
+	}
+
+	prefixes := make(map[string]bool, len(cfg.Prefixes))
+	for _, prefix := range cfg.Prefixes {
+		prefixes[prefix] = true
+	}
+
+	g := &Gate{
+		provider:    provider,
+		logger:      logger,
+		prefixes:    prefixes,
+		keyPrefix:   cfg.KeyPrefix,
+		environment: cfg.Environment,
+	}
+
+	if cfg.CacheTTL > 0 {
+		g.evaluations = cache.New(cfg.CacheSize, cfg.CacheTTL)
+	}
+
+	return g
+}
+
+// Flag returns the key of the flag gating prefix
+func (g *Gate) Flag(prefix string) string {
+	return g.keyPrefix + prefix
+}
+
+// Enabled reports whether ids with prefix are resolved for subject. Prefixes
+// without a flag are always enabled. Flags that can't be evaluated are
+// disabled, so a dark-launched prefix stays dark while the provider is
+// unavailable.
+func (g *Gate) Enabled(ctx context.Context, prefix, subject string) bool {
+	if !g.prefixes[prefix] {
+		return true
+	}
+
+	key := prefix + "\x00" + subject
+
+	if v, ok := g.evaluations.Get(key); ok {
+		return v.(bool)
+	}
+
+	enabled, err := g.provider.BooleanEvaluation(ctx, g.Flag(prefix), false, EvaluationContext{
+		TargetingKey: subject,
+		Attributes: map[string]interface{}{
+			AttributePrefix:      prefix,
+			AttributeEnvironment: g.environment,
+		},
+	})
+	if err != nil {
+		g.logger.Warnw("failed to evaluate prefix feature flag", "flag", g.Flag(prefix), "error", err)
+
+		return false
+	}
+
+	g.evaluations.SetIDs(key, nil, enabled)
+
+	return enabled
+}
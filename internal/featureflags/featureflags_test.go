@@ -0,0 +1,116 @@
+package featureflags_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/featureflags"
+)
+
+type countingProvider struct {
+	featureflags.Provider
+	calls int
+	err   error
+}
+
+func (p *countingProvider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx featureflags.EvaluationContext) (bool, error) {
+	p.calls++
+
+	if p.err != nil {
+		return defaultValue, p.err
+	}
+
+	return p.Provider.BooleanEvaluation(ctx, flag, defaultValue, evalCtx)
+}
+
+func TestStatic(t *testing.T) {
+	static := featureflags.NewStatic(map[string]featureflags.StaticFlag{
+		"prefix.newtype": {Targets: []string{"idntusr-beta"}},
+		"prefix.stgtype": {Enabled: true, Environments: []string{"staging"}},
+		"prefix.gatype1": {Enabled: true},
+	})
+
+	testCases := []struct {
+		TestName    string
+		flag        string
+		subject     string
+		environment string
+		enabled     bool
+	}{
+		{TestName: "unknown flag", flag: "prefix.unknown", enabled: true},
+		{TestName: "targeted subject", flag: "prefix.newtype", subject: "idntusr-beta", enabled: true},
+		{TestName: "other subject", flag: "prefix.newtype", subject: "idntusr-other"},
+		{TestName: "enabled environment", flag: "prefix.stgtype", environment: "staging", enabled: true},
+		{TestName: "other environment", flag: "prefix.stgtype", environment: "production"},
+		{TestName: "enabled everywhere", flag: "prefix.gatype1", environment: "production", enabled: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			enabled, err := static.BooleanEvaluation(context.Background(), tt.flag, true, featureflags.EvaluationContext{
+				TargetingKey: tt.subject,
+				Attributes:   map[string]interface{}{featureflags.AttributeEnvironment: tt.environment},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.enabled, enabled)
+		})
+	}
+}
+
+func TestGate(t *testing.T) {
+	provider := &countingProvider{Provider: featureflags.NewStatic(map[string]featureflags.StaticFlag{
+		"prefix.newtype": {Targets: []string{"idntusr-beta"}},
+	})}
+
+	gate := featureflags.NewGate(featureflags.Config{
+		Prefixes:  []string{"newtype"},
+		KeyPrefix: "prefix.",
+		CacheTTL:  time.Minute,
+	}, provider, zap.NewNop().Sugar())
+
+	ctx := context.Background()
+
+	assert.Equal(t, "prefix.newtype", gate.Flag("newtype"))
+
+	assert.True(t, gate.Enabled(ctx, "testusr", "idntusr-other"), "ungated prefixes are enabled")
+	assert.Zero(t, provider.calls, "ungated prefixes aren't evaluated")
+
+	assert.True(t, gate.Enabled(ctx, "newtype", "idntusr-beta"))
+	assert.False(t, gate.Enabled(ctx, "newtype", "idntusr-other"))
+	assert.Equal(t, 2, provider.calls)
+
+	assert.True(t, gate.Enabled(ctx, "newtype", "idntusr-beta"))
+	assert.Equal(t, 2, provider.calls, "evaluations are cached per subject")
+}
+
+func TestGateProviderError(t *testing.T) {
+	provider := &countingProvider{
+		Provider: featureflags.NewStatic(nil),
+		err:      errors.New("provider unavailable"),
+	}
+
+	gate := featureflags.NewGate(featureflags.Config{Prefixes: []string{"newtype"}, CacheTTL: time.Minute}, provider, zap.NewNop().Sugar())
+
+	ctx := context.Background()
+
+	assert.False(t, gate.Enabled(ctx, "newtype", "idntusr-beta"), "prefixes are disabled when the flag can't be evaluated")
+	assert.False(t, gate.Enabled(ctx, "newtype", "idntusr-beta"))
+	assert.Equal(t, 2, provider.calls, "failed evaluations aren't cached")
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := featureflags.NewProvider(featureflags.Config{Provider: "launchdarkly"})
+	assert.ErrorIs(t, err, featureflags.ErrUnknownProvider)
+
+	_, err = featureflags.NewProvider(featureflags.Config{Provider: featureflags.ProviderOFREP})
+	assert.ErrorIs(t, err, featureflags.ErrMissingOFREPConfig)
+
+	p, err := featureflags.NewProvider(featureflags.Config{})
+	assert.NoError(t, err)
+	assert.IsType(t, &featureflags.Static{}, p)
+}
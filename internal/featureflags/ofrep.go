@@ -0,0 +1,115 @@
+package featureflags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ErrMissingOFREPConfig is returned when the OFREP url is not configured
+var ErrMissingOFREPConfig = errors.New("missing feature flag config options; you must pass an OFREP url")
+
+// ErrFlagEvaluation is returned when the OFREP api fails to evaluate a flag
+var ErrFlagEvaluation = errors.New("feature flag evaluation failed")
+
+// errFlagNotBoolean is returned when a flag evaluates to a value other than
+// a boolean
+var errFlagNotBoolean = errors.New("feature flag value isn't a boolean")
+
+// OFREP evaluates flags with the OpenFeature remote evaluation protocol,
+// supported by flag services such as flagd, so any of them can gate prefixes
+// without an sdk
+type OFREP struct {
+	cfg     Config
+	http    *http.Client
+	baseURL string
+}
+
+type ofrepRequest struct {
+	Context map[string]interface{} `json:"context"`
+}
+
+type ofrepResponse struct {
+	Value     json.RawMessage `json:"value"`
+	ErrorCode string          `json:"errorCode"`
+}
+
+// NewOFREP returns a provider evaluating flags with the OFREP api at the
+// configured url
+func NewOFREP(cfg Config) (*OFREP, error) {
+	if cfg.URL == "" {
+		return nil, ErrMissingOFREPConfig
+	}
+
+	baseURL, err := url.JoinPath(cfg.URL, "ofrep", "v1", "evaluate", "flags")
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &OFREP{
+		cfg:     cfg,
+		http:    &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		baseURL: baseURL,
+	}, nil
+}
+
+// BooleanEvaluation evaluates flag with the OFREP api
+func (o *OFREP) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx EvaluationContext) (bool, error) {
+	evaluation := make(map[string]interface{}, len(evalCtx.Attributes)+1)
+	for k, v := range evalCtx.Attributes {
+		evaluation[k] = v
+	}
+
+	if evalCtx.TargetingKey != "" {
+		evaluation["targetingKey"] = evalCtx.TargetingKey
+	}
+
+	body, err := json.Marshal(ofrepRequest{Context: evaluation})
+	if err != nil {
+		return defaultValue, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/"+url.PathEscape(flag), bytes.NewReader(body))
+	if err != nil {
+		return defaultValue, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if o.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.cfg.Token)
+	}
+
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return defaultValue, err
+	}
+
+	defer resp.Body.Close()
+
+	var evaluated ofrepResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&evaluated); err != nil {
+		return defaultValue, fmt.Errorf("decoding flag evaluation: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return defaultValue, fmt.Errorf("%w: %s: %d %s", ErrFlagEvaluation, flag, resp.StatusCode, evaluated.ErrorCode)
+	}
+
+	var value bool
+
+	if err := json.Unmarshal(evaluated.Value, &value); err != nil {
+		return defaultValue, fmt.Errorf("%w: %s", errFlagNotBoolean, flag)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,87 @@
+package featureflags_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/featureflags"
+)
+
+func TestOFREP(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		status   int
+		response string
+		enabled  bool
+		errorMsg string
+	}{
+		{
+			TestName: "enabled",
+			status:   http.StatusOK,
+			response: `{"key": "prefix.newtype", "value": true, "reason": "TARGETING_MATCH"}`,
+			enabled:  true,
+		},
+		{
+			TestName: "disabled",
+			status:   http.StatusOK,
+			response: `{"key": "prefix.newtype", "value": false, "reason": "DEFAULT"}`,
+		},
+		{
+			TestName: "not a boolean",
+			status:   http.StatusOK,
+			response: `{"key": "prefix.newtype", "value": "on"}`,
+			errorMsg: "feature flag value isn't a boolean",
+		},
+		{
+			TestName: "flag not found",
+			status:   http.StatusNotFound,
+			response: `{"key": "prefix.newtype", "errorCode": "FLAG_NOT_FOUND"}`,
+			errorMsg: "feature flag evaluation failed: prefix.newtype: 404 FLAG_NOT_FOUND",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/ofrep/v1/evaluate/flags/prefix.newtype", r.URL.Path)
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+				var body struct {
+					Context map[string]interface{} `json:"context"`
+				}
+
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, "idntusr-beta", body.Context["targetingKey"])
+				assert.Equal(t, "staging", body.Context[featureflags.AttributeEnvironment])
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			o, err := featureflags.NewOFREP(featureflags.Config{URL: srv.URL, Token: "secret"})
+			require.NoError(t, err)
+
+			enabled, err := o.BooleanEvaluation(context.Background(), "prefix.newtype", false, featureflags.EvaluationContext{
+				TargetingKey: "idntusr-beta",
+				Attributes:   map[string]interface{}{featureflags.AttributeEnvironment: "staging"},
+			})
+
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+				assert.False(t, enabled, "the default value is returned on errors")
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.enabled, enabled)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package featureflags
+
+import (
+	"context"
+)
+
+// StaticFlag is a flag of the static provider. A flag is enabled for the
+// subjects in Targets, and for everyone else when Enabled is set and the
+// environment is in Environments, or Environments is empty.
+type StaticFlag struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Environments []string `mapstructure:"environments"`
+	Targets      []string `mapstructure:"targets"`
+}
+
+// Static evaluates flags from the config, for deployments without a flag
+// service
+type Static struct {
+	flags map[string]StaticFlag
+}
+
+// NewStatic returns a provider evaluating flags by key
+func NewStatic(flags map[string]StaticFlag) *Static {
+	return &Static{flags: flags}
+}
+
+// BooleanEvaluation evaluates flag, returning defaultValue for unknown flags
+func (s *Static) BooleanEvaluation(_ context.Context, flag string, defaultValue bool, evalCtx EvaluationContext) (bool, error) {
+	f, ok := s.flags[flag]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	for _, target := range f.Targets {
+		if target == evalCtx.TargetingKey {
+			return true, nil
+		}
+	}
+
+	if !f.Enabled || len(f.Environments) == 0 {
+		return f.Enabled, nil
+	}
+
+	env, _ := evalCtx.Attributes[AttributeEnvironment].(string)
+
+	for _, e := range f.Environments {
+		if e == env {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
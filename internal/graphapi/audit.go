@@ -0,0 +1,77 @@
+package graphapi
+
+import (
+	"context"
+
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/audit"
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+const (
+	auditOperationNode     = "node"
+	auditOperationNodes    = "nodes"
+	auditOperationEntities = "_entities"
+	auditOperationResolve  = "resolve"
+)
+
+// recordResolution records the outcome of resolving id in the metrics, the
+// unknown prefix log and, when auditing is enabled, the audit log
+func (r *Resolver) recordResolution(ctx context.Context, operation string, id string, typeName string, err error) {
+	if err == nil && r.deprecatedPrefixes != nil {
+		r.recordDeprecatedPrefix(operation, prefixOf(gidx.PrefixedID(id)))
+	}
+
+	if r.metrics == nil && r.auditor == nil && r.unknownPrefixes == nil {
+		return
+	}
+
+	// ids rejected before parsing aren't recorded, they may be huge or forge
+	// log lines
+	if err != nil && r.checkID(id) != nil {
+		id = ""
+	}
+
+	prefix := prefixOf(gidx.PrefixedID(id))
+
+	// ids resolved to the type of unknown prefixes still have one
+	if err == nil && r.isUnknownPrefixType(typeName) {
+		r.recordUnknownPrefix(operation, prefix, ErrUnknownPrefix)
+	} else {
+		r.recordUnknownPrefix(operation, prefix, err)
+	}
+
+	outcome := auditOutcome(err)
+
+	if r.metrics != nil {
+		r.metrics.Resolution(operation, prefix, string(outcome))
+	}
+
+	if r.auditor == nil {
+		return
+	}
+
+	r.auditor.Record(audit.Record{
+		Subject:   authz.Subject(ctx),
+		Operation: operation,
+		ID:        id,
+		Prefix:    prefix,
+		Type:      typeName,
+		Outcome:   outcome,
+	})
+}
+
+func auditOutcome(err error) audit.Outcome {
+	if err == nil {
+		return audit.OutcomeResolved
+	}
+
+	switch code := errorCode(err); code {
+	case errcode.InvalidID, errcode.UnknownPrefix, errcode.Unauthorized, errcode.Deleted, errcode.NotFound:
+		return audit.Outcome(code)
+	default:
+		return audit.OutcomeFailed
+	}
+}
@@ -0,0 +1,50 @@
+package graphapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Authenticate runs the middleware of the resolver, such as authentication
+// and tenant checks, for a request with header sent from remoteAddr outside
+// of http, returning the context it populated. Requests the middleware
+// rejects return an *echo.HTTPError with the status they would have been
+// rejected with.
+func (r *Resolver) Authenticate(ctx context.Context, header http.Header, remoteAddr string) (context.Context, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/api/v1/resolve", http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = header.Clone()
+	req.RemoteAddr = remoteAddr
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	var authenticated context.Context
+
+	next := echo.HandlerFunc(func(c echo.Context) error {
+		authenticated = c.Request().Context()
+
+		return nil
+	})
+
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		next = r.middleware[i](next)
+	}
+
+	if err := next(c); err != nil {
+		return nil, err
+	}
+
+	// middleware writing their own response rather than returning an error
+	if authenticated == nil {
+		return nil, echo.NewHTTPError(rec.Code, http.StatusText(rec.Code))
+	}
+
+	return authenticated, nil
+}
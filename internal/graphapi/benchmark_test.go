@@ -0,0 +1,69 @@
+package graphapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func benchmarkGraphHandler(b *testing.B, body string) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(b, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d", rec.Code)
+		}
+	}
+}
+
+func BenchmarkGraphHandlerNode(b *testing.B) {
+	benchmarkGraphHandler(b, `{"query":"{ node(id: \"testsrv-abc\") { __typename id } }"}`)
+}
+
+func BenchmarkGraphHandlerNodeVariable(b *testing.B) {
+	benchmarkGraphHandler(b, `{"query":"query($id: ID!) { node(id: $id) { __typename id } }","variables":{"id":"testsrv-abc"}}`)
+}
+
+// BenchmarkGraphHandlerNodeExecuted selects the node through a fragment, so
+// unlike BenchmarkGraphHandlerNode the query is executed by graphql-go
+func BenchmarkGraphHandlerNodeExecuted(b *testing.B) {
+	benchmarkGraphHandler(b, `{"query":"{ node(id: \"testsrv-abc\") { __typename ... on Server { id } } }"}`)
+}
+
+func BenchmarkGraphHandlerEntities(b *testing.B) {
+	reps := make([]map[string]string, 100)
+
+	for i := range reps {
+		reps[i] = map[string]string{"__typename": "Node", "id": fmt.Sprintf("testsrv-%04d", i)}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     "query($representations:[_Any!]!){_entities(representations:$representations){...on Server{id}}}",
+		"variables": map[string]interface{}{"representations": reps},
+	})
+	require.NoError(b, err)
+
+	benchmarkGraphHandler(b, string(body))
+}
@@ -0,0 +1,179 @@
+package graphapi
+
+import (
+	"hash/fnv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+)
+
+// Schema versions served during a canary rollout, used as the metrics label
+const (
+	VersionStable = "stable"
+	VersionCanary = "canary"
+)
+
+// Outcomes of requests served during a canary rollout, used as the metrics
+// label
+const (
+	VersionSuccess = "success"
+	VersionError   = "error"
+)
+
+// schemaVersionKey is the echo context key of the schema version serving a
+// request during a canary rollout
+const schemaVersionKey = "graphapi.schemaVersion"
+
+// canary is a resolver served to a percentage of clients instead of the
+// current resolver
+type canary struct {
+	resolver *Resolver
+	percent  int
+}
+
+// CanaryStatus describes the canary rollout of a Handler
+type CanaryStatus struct {
+	Percent        int    `json:"percent"`
+	StableChecksum string `json:"stableChecksum"`
+	CanaryChecksum string `json:"canaryChecksum"`
+}
+
+// StartCanary serves r to percent of clients in place of the current
+// resolver, replacing any canary being served. Clients are assigned to a
+// version by their subject, or their address when anonymous, so each client
+// keeps seeing the same schema while the percentage doesn't change.
+func (h *Handler) StartCanary(r *Resolver, percent int) {
+	h.canaryMu.Lock()
+	defer h.canaryMu.Unlock()
+
+	h.canary.Store(&canary{resolver: r, percent: clampPercent(percent)})
+}
+
+// SetCanaryPercent changes the percentage of clients served the canary,
+// returning false when no canary is being served
+func (h *Handler) SetCanaryPercent(percent int) bool {
+	h.canaryMu.Lock()
+	defer h.canaryMu.Unlock()
+
+	c := h.canary.Load()
+	if c == nil {
+		return false
+	}
+
+	h.canary.Store(&canary{resolver: c.resolver, percent: clampPercent(percent)})
+
+	return true
+}
+
+// PromoteCanary serves the canary to every client, returning false when no
+// canary is being served
+func (h *Handler) PromoteCanary() bool {
+	h.canaryMu.Lock()
+	defer h.canaryMu.Unlock()
+
+	c := h.canary.Load()
+	if c == nil {
+		return false
+	}
+
+	h.Swap(c.resolver)
+	h.canary.Store(nil)
+
+	return true
+}
+
+// AbortCanary stops serving the canary, returning false when none is being
+// served
+func (h *Handler) AbortCanary() bool {
+	h.canaryMu.Lock()
+	defer h.canaryMu.Unlock()
+
+	if h.canary.Load() == nil {
+		return false
+	}
+
+	h.canary.Store(nil)
+
+	return true
+}
+
+// Canary returns the status of the canary rollout, if any
+func (h *Handler) Canary() (CanaryStatus, bool) {
+	c := h.canary.Load()
+	if c == nil {
+		return CanaryStatus{}, false
+	}
+
+	return CanaryStatus{
+		Percent:        c.percent,
+		StableChecksum: h.Resolver().SDLChecksum(),
+		CanaryChecksum: c.resolver.SDLChecksum(),
+	}, true
+}
+
+// serving returns the resolver serving the request. During a canary rollout
+// the version serving the request is stored on the echo context so its
+// outcome is counted per version.
+func (h *Handler) serving(c echo.Context) *Resolver {
+	rollout := h.canary.Load()
+	if rollout == nil {
+		return h.Resolver()
+	}
+
+	if canaryBucket(clientKey(c)) < rollout.percent {
+		c.Set(schemaVersionKey, VersionCanary)
+
+		return rollout.resolver
+	}
+
+	c.Set(schemaVersionKey, VersionStable)
+
+	return h.Resolver()
+}
+
+// clientKey identifies the client of a request for sticky canary
+// assignment: its subject, or its address when anonymous
+func clientKey(c echo.Context) string {
+	if subject := authz.Subject(c.Request().Context()); subject != "" {
+		return subject
+	}
+
+	return c.RealIP()
+}
+
+// canaryBucket returns the percentile, 0 to 99, of the client with key
+func canaryBucket(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum32() % 100)
+}
+
+func clampPercent(percent int) int {
+	switch {
+	case percent < 0:
+		return 0
+	case percent > 100:
+		return 100
+	default:
+		return percent
+	}
+}
+
+// recordSchemaVersion counts the outcome of a request served during a canary
+// rollout by the schema version that served it
+func (r *Resolver) recordSchemaVersion(c echo.Context, result *graphql.Result) {
+	version, ok := c.Get(schemaVersionKey).(string)
+	if !ok || r.metrics == nil {
+		return
+	}
+
+	outcome := VersionSuccess
+	if result.HasErrors() {
+		outcome = VersionError
+	}
+
+	r.metrics.SchemaVersionRequest(version, outcome)
+}
@@ -0,0 +1,132 @@
+package graphapi_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// versionCounter counts requests by schema version and outcome
+type versionCounter struct {
+	panicCounter
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (c *versionCounter) SchemaVersionRequest(version, outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests[version+"/"+outcome]++
+}
+
+func TestCanary(t *testing.T) {
+	counter := &versionCounter{panicCounter: panicCounter{}, requests: map[string]int{}}
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithMetrics(counter))
+	require.NoError(t, err)
+
+	// the canary renames the type of testsrv ids
+	candidate, err := r.WithSchema(strings.Replace(validTestSchema, "type Server", "type Host", 1))
+	require.NoError(t, err)
+
+	h := graphapi.NewHandler(r)
+
+	e := echo.New()
+	h.Routes(e.Group(""))
+
+	fromClient := func(ip string) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set(echo.HeaderXRealIP, ip)
+		}
+	}
+
+	servedCanary := func(ip string) bool {
+		code, body := resolveType(e, "testsrv-1", fromClient(ip))
+		require.Equal(t, http.StatusOK, code)
+
+		return strings.Contains(body, `"Host"`)
+	}
+
+	_, ok := h.Canary()
+	assert.False(t, ok)
+	assert.False(t, h.PromoteCanary(), "there's no canary to promote")
+
+	h.StartCanary(candidate, 50)
+
+	status, ok := h.Canary()
+	require.True(t, ok)
+	assert.Equal(t, graphapi.CanaryStatus{
+		Percent:        50,
+		StableChecksum: r.SDLChecksum(),
+		CanaryChecksum: candidate.SDLChecksum(),
+	}, status)
+
+	canaryClients := map[string]bool{}
+
+	for i := 0; i < 100; i++ {
+		ip := fmt.Sprintf("10.0.0.%d", i)
+		canaryClients[ip] = servedCanary(ip)
+	}
+
+	served := 0
+
+	for ip, canary := range canaryClients {
+		assert.Equal(t, canary, servedCanary(ip), "clients keep their version")
+
+		if canary {
+			served++
+		}
+	}
+
+	assert.InDelta(t, 50, served, 20)
+	assert.Equal(t, 2*served, counter.requests[graphapi.VersionCanary+"/"+graphapi.VersionSuccess])
+	assert.Equal(t, 2*(100-served), counter.requests[graphapi.VersionStable+"/"+graphapi.VersionSuccess])
+
+	assert.True(t, h.SetCanaryPercent(0))
+	assert.False(t, servedCanary("10.0.0.1"))
+
+	assert.True(t, h.SetCanaryPercent(100))
+	assert.True(t, servedCanary("10.0.0.1"))
+
+	assert.True(t, h.PromoteCanary())
+	assert.Same(t, candidate, h.Resolver())
+
+	_, ok = h.Canary()
+	assert.False(t, ok)
+
+	// requests outside a rollout aren't counted by version
+	counted := map[string]int{}
+	for k, v := range counter.requests {
+		counted[k] = v
+	}
+
+	assert.True(t, servedCanary("10.0.0.1"))
+	assert.Equal(t, counted, counter.requests)
+}
+
+func TestCanaryAbort(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	candidate, err := r.WithSchema(strings.Replace(validTestSchema, "type Server", "type Host", 1))
+	require.NoError(t, err)
+
+	h := graphapi.NewHandler(r)
+	h.StartCanary(candidate, 100)
+
+	assert.True(t, h.AbortCanary())
+	assert.False(t, h.AbortCanary())
+	assert.False(t, h.SetCanaryPercent(10))
+	assert.Same(t, r, h.Resolver())
+}
@@ -0,0 +1,110 @@
+package graphapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/labstack/echo/v4"
+)
+
+// WithCanonicalResponses writes responses in a canonical form, so equal
+// responses are always the same bytes and can be compared directly, such
+// as when shadow testing a new version. Object keys are always sorted and
+// responses are never indented; canonical responses also order graphql
+// errors by path, location and message rather than the order they happened
+// in, and ignore echo's pretty query parameter.
+func WithCanonicalResponses() Option {
+	return func(r *Resolver) {
+		r.canonical = true
+	}
+}
+
+// writeJSON writes v as the json response. Echo indents responses to
+// requests with a pretty query parameter, which canonical responses ignore.
+func (r *Resolver) writeJSON(c echo.Context, code int, v interface{}) error {
+	if !r.canonical {
+		return c.JSON(code, v)
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.JSONBlob(code, body)
+}
+
+// canonicalErrors returns errs ordered by path, location and message. errs
+// may be shared with the document cache, so they're never sorted in place.
+func canonicalErrors(errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	if len(errs) < 2 {
+		return errs
+	}
+
+	sorted := append([]gqlerrors.FormattedError(nil), errs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return compareErrors(sorted[i], sorted[j]) < 0 })
+
+	return sorted
+}
+
+func compareErrors(a, b gqlerrors.FormattedError) int {
+	if c := comparePaths(a.Path, b.Path); c != 0 {
+		return c
+	}
+
+	for i := 0; i < len(a.Locations) && i < len(b.Locations); i++ {
+		if c := compareInts(a.Locations[i].Line, b.Locations[i].Line); c != 0 {
+			return c
+		}
+
+		if c := compareInts(a.Locations[i].Column, b.Locations[i].Column); c != 0 {
+			return c
+		}
+	}
+
+	if c := compareInts(len(a.Locations), len(b.Locations)); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.Message, b.Message)
+}
+
+// comparePaths compares error paths element by element. List indexes sort
+// before field names, and shorter paths before longer ones they start.
+func comparePaths(a, b []interface{}) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ai, aIndex := a[i].(int)
+		bi, bIndex := b[i].(int)
+
+		switch {
+		case aIndex && bIndex:
+			if c := compareInts(ai, bi); c != 0 {
+				return c
+			}
+		case aIndex:
+			return -1
+		case bIndex:
+			return 1
+		default:
+			if c := strings.Compare(fmt.Sprint(a[i]), fmt.Sprint(b[i])); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return compareInts(len(a), len(b))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,55 @@
+package graphapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestCanonicalResponses(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema,
+		graphapi.WithCanonicalResponses(),
+		graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testtkn"}),
+	)
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	// the fields are executed in map order, so errors happen in a random order
+	query := `{"query": "{ z: node(id: \"testtkn-1\") { id } b: node(id: \"unknown-1\") { id } a: node(id: \"bad\") { id } s: node(id: \"testsrv-1\") { id } }"}`
+
+	expected := `{"data":{"a":null,"b":null,"s":{"id":"testsrv-1"},"z":null},"errors":[` +
+		`{"message":"invalid id: expected id format is prefix-id, but received bad","locations":[{"line":1,"column":67}],"path":["a"],"extensions":{"code":"invalid_id"}},` +
+		`{"message":"invalid id; unknown prefix","locations":[{"line":1,"column":35}],"path":["b"],"extensions":{"code":"unknown_prefix"}},` +
+		`{"message":"not authorized to resolve id","locations":[{"line":1,"column":3}],"path":["z"],"extensions":{"code":"unauthorized"}}]}` + "\n"
+
+	for i := 0; i < 20; i++ {
+		rec := serve(http.MethodPost, "/query", query)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, rec.Body.String())
+	}
+
+	// echo's pretty parameter is ignored
+	rec := serve(http.MethodPost, "/api/v1/resolve?pretty", `{"ids": []}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, `{"apiVersion":"v1","error":{"code":"invalid_request","message":"at least one id is required"}}`, rec.Body.String())
+}
@@ -0,0 +1,40 @@
+package graphapi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/chaos"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestFaultInjection(t *testing.T) {
+	injector, err := chaos.New(chaos.Config{Prefixes: []string{"testsrv"}})
+	require.NoError(t, err)
+
+	resp, err := testQuery(validTestSchema, `{"query":"{ a: node(id: \"testusr-abc\") { id } b: node(id: \"testsrv-abc\") { id } }"}`,
+		graphapi.WithFaultInjection(injector))
+	require.NoError(t, err)
+
+	// only ids with the faulted prefix fail, like a failing backend
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, string(errcode.Unavailable), resp.Errors[0].Extensions[errcode.ExtensionKey])
+	assert.Contains(t, string(resp.RawData), `"a":{"id":"testusr-abc"}`)
+}
+
+func TestFaultInjectionEntities(t *testing.T) {
+	injector, err := chaos.New(chaos.Config{EntityFailureRate: 1})
+	require.NoError(t, err)
+
+	resp, err := testQuery(validTestSchema, entitiesQuery(t, 10), graphapi.WithFaultInjection(injector))
+	require.NoError(t, err)
+
+	require.Len(t, resp.Errors, 10)
+
+	for _, e := range resp.Errors {
+		assert.Equal(t, string(errcode.Unavailable), e.Extensions[errcode.ExtensionKey])
+	}
+}
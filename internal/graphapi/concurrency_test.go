@@ -0,0 +1,179 @@
+package graphapi_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/echojwtx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// TestConcurrentRequests serves thousands of requests concurrently while the
+// resolver is replaced, run with -race to detect unsynchronized state
+func TestConcurrentRequests(t *testing.T) {
+	const workers = 16
+
+	requests := 4000
+	if testing.Short() {
+		requests = 400
+	}
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema,
+		graphapi.WithAuthorizer(&countingAuthorizer{}),
+		graphapi.WithResponseCache(cache.New(100, time.Minute)),
+		graphapi.WithEntityConcurrency(4),
+		graphapi.WithDocumentCacheSize(10),
+	)
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+
+	e := echo.New()
+	handler.Routes(e.Group(""))
+
+	entities := entitiesQuery(t, 250)
+
+	serve := func(i int) error {
+		var req *http.Request
+
+		switch i % 4 {
+		case 0:
+			// distinct ids so the document and response caches keep evicting
+			req = httptest.NewRequest(http.MethodPost, "/query",
+				strings.NewReader(fmt.Sprintf(`{"query":"{ node(id: \"testsrv-%d\") { __typename id } }"}`, i%50)))
+		case 1:
+			req = httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(entities))
+		case 2:
+			req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/resolve?id=testusr-%d", i%50), nil)
+		default:
+			req = httptest.NewRequest(http.MethodPost, "/query",
+				strings.NewReader(`{"query":"{ __type(name: \"_Entities\") { possibleTypes { name } } }"}`))
+		}
+
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req = req.WithContext(context.WithValue(req.Context(), echojwtx.ActorCtxKey, fmt.Sprintf("user-%d", i%3)))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			return fmt.Errorf("request %d: unexpected status %d: %s", i, rec.Code, rec.Body.String())
+		}
+
+		if strings.Contains(rec.Body.String(), `"errors"`) {
+			return fmt.Errorf("request %d: unexpected errors: %s", i, rec.Body.String())
+		}
+
+		return nil
+	}
+
+	var (
+		wg   sync.WaitGroup
+		next = make(chan int)
+		errs = make(chan error, workers)
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range next {
+				if err := serve(i); err != nil {
+					errs <- err
+
+					return
+				}
+			}
+		}()
+	}
+
+	// replace the resolver and read its types while requests are served
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 20; i++ {
+			current := handler.Resolver()
+
+			assert.Len(t, current.GraphTypes(), 5)
+			assert.NotEmpty(t, current.SDLChecksum())
+
+			replacement, err := current.WithSchema(validTestSchema)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			handler.Swap(replacement)
+		}
+	}()
+
+feed:
+	for i := 0; i < requests; i++ {
+		select {
+		case next <- i:
+		case err := <-errs:
+			t.Error(err)
+
+			break feed
+		}
+	}
+
+	close(next)
+	wg.Wait()
+	<-done
+
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestConcurrentReloads reloads the handler concurrently while it's read,
+// checking the schema served is the latest in its history
+func TestConcurrentReloads(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			schema := validTestSchema + fmt.Sprintf("\ntype Reload%d implements Node @prefixedID(prefix: \"reload%d\") {\n  id: ID!\n}\n", i, i)
+			assert.NoError(t, handler.Reload(schema))
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			assert.NotEmpty(t, handler.Resolver().GraphTypes())
+		}()
+	}
+
+	wg.Wait()
+
+	history := handler.History()
+	require.Len(t, history, 9)
+	assert.Equal(t, history[0].Checksum, handler.Resolver().SDLChecksum())
+}
@@ -0,0 +1,109 @@
+package graphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+var testDeletedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// deletedAuthorizer reports testsrv-deleted as deleted at testDeletedAt and
+// testsrv-archived as deleted at an unknown time
+type deletedAuthorizer struct{}
+
+func (deletedAuthorizer) CanResolve(_ context.Context, _ string, id gidx.PrefixedID) error {
+	switch id {
+	case "testsrv-deleted":
+		return &authz.DeletedError{DeletedAt: testDeletedAt}
+	case "testsrv-archived":
+		return &authz.DeletedError{}
+	default:
+		return nil
+	}
+}
+
+func TestDeleted(t *testing.T) {
+	testCases := []struct {
+		TestName   string
+		query      string
+		response   string
+		extensions map[string]interface{}
+	}{
+		{
+			TestName:   "deleted node",
+			query:      `{"query": "{ node(id: \"testsrv-deleted\") { id } }"}`,
+			response:   `{"node":null}`,
+			extensions: map[string]interface{}{"code": "deleted", "deletedAt": "2024-05-01T12:00:00Z"},
+		},
+		{
+			TestName:   "deleted node at an unknown time",
+			query:      `{"query": "{ node(id: \"testsrv-archived\") { id } }"}`,
+			response:   `{"node":null}`,
+			extensions: map[string]interface{}{"code": "deleted"},
+		},
+		{
+			TestName: "deleted entity",
+			query: `{
+				"query": "query($representations:[_Any!]!){_entities(representations:$representations){...on Node{id}}}",
+				"variables": {"representations": [{ "__typename": "Node", "id": "testsrv-123" }, { "__typename": "Node", "id": "testsrv-deleted" }]}
+			}`,
+			response:   `{"_entities":[{"id":"testsrv-123"},null]}`,
+			extensions: map[string]interface{}{"code": "deleted", "deletedAt": "2024-05-01T12:00:00Z"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			resp, err := testQuery(validTestSchema, tt.query, graphapi.WithAuthorizer(deletedAuthorizer{}))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tt.response, resp.Data)
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, "id was deleted", resp.Errors[0].Message)
+			assert.Equal(t, tt.extensions, resp.Errors[0].Extensions)
+		})
+	}
+}
+
+func TestDeletedResolveAPI(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithAuthorizer(deletedAuthorizer{}))
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/resolve?id=testsrv-deleted&id=testsrv-archived", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp graphapi.ResolveResponse
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 2)
+
+	deleted := resp.Results[0]
+	assert.False(t, deleted.Resolved)
+	assert.Equal(t, "Server", deleted.Type, "deleted ids report their type")
+	require.NotNil(t, deleted.Error)
+	assert.Equal(t, "deleted", string(deleted.Error.Code))
+	require.NotNil(t, deleted.Error.DeletedAt)
+	assert.True(t, testDeletedAt.Equal(*deleted.Error.DeletedAt))
+
+	archived := resp.Results[1]
+	require.NotNil(t, archived.Error)
+	assert.Equal(t, "deleted", string(archived.Error.Code))
+	assert.Nil(t, archived.Error.DeletedAt)
+}
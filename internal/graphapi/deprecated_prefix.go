@@ -0,0 +1,143 @@
+package graphapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// deprecatedPrefix is a prefix a type used to have, declared by the
+// deprecatedPrefixes argument of its @prefixedID directive. Ids with the
+// prefix still resolve to the type, so stored references keep working while
+// they're migrated to the prefix replacing it.
+type deprecatedPrefix struct {
+	replacement string
+	obj         *graphql.Object
+	// warned is set once the first id with the prefix is logged
+	warned sync.Once
+}
+
+// pendingDeprecation is a deprecated prefix read from the schema, added once
+// every prefix of the schema is known
+type pendingDeprecation struct {
+	prefix      string
+	replacement string
+	typeName    string
+}
+
+// deprecatedPrefixesOf returns the deprecated prefixes declared by the
+// @prefixedID directive pd
+func deprecatedPrefixesOf(pd *ast.Directive) []string {
+	arg := pd.Arguments.ForName("deprecatedPrefixes")
+	if arg == nil || arg.Value == nil {
+		return nil
+	}
+
+	// a single prefix may be given without a list
+	if arg.Value.Kind != ast.ListValue {
+		return []string{arg.Value.Raw}
+	}
+
+	prefixes := make([]string, 0, len(arg.Value.Children))
+	for _, c := range arg.Value.Children {
+		prefixes = append(prefixes, c.Value.Raw)
+	}
+
+	return prefixes
+}
+
+// addDeprecatedPrefixes resolves the ids of the deprecated prefixes to the
+// types replacing them. Prefixes that ids can't have, or that a type still
+// declares as its prefix, are logged and left out. A prefix deprecated by
+// several types resolves to the last of them, unless prefixes are strict.
+func (r *Resolver) addDeprecatedPrefixes(pending []pendingDeprecation) error {
+	for _, d := range pending {
+		obj := r.prefixMap[d.replacement]
+
+		// the type was shadowed by another type declaring its prefix
+		if obj == nil || obj.Name() != d.typeName {
+			continue
+		}
+
+		if problem := prefixProblem(d.prefix, false); problem != "" {
+			r.logger.Warnw("invalid deprecated prefix on @prefixedID directive, it isn't resolved", "graphql_type", d.typeName, "prefix", d.prefix, "problem", problem)
+			continue
+		}
+
+		if current, ok := r.prefixMap[d.prefix]; ok {
+			r.logger.Warnw("deprecated prefix on @prefixedID directive is the prefix of a type, it resolves to that type", "graphql_type", d.typeName, "prefix", d.prefix, "prefix_type", current.Name())
+			continue
+		}
+
+		if prev, ok := r.deprecatedPrefixes[d.prefix]; ok && prev.obj.Name() != d.typeName {
+			if r.strictPrefixes {
+				return fmt.Errorf("%w: deprecated prefix %s is declared by %s and %s", ErrDuplicatePrefix, d.prefix, prev.obj.Name(), d.typeName)
+			}
+
+			r.logger.Warnw("duplicate deprecated prefix on @prefixedID directive, the last type declaring it is used", "prefix", d.prefix, "graphql_type", d.typeName, "shadowed_type", prev.obj.Name())
+		}
+
+		if r.deprecatedPrefixes == nil {
+			r.deprecatedPrefixes = map[string]*deprecatedPrefix{}
+		}
+
+		r.deprecatedPrefixes[d.prefix] = &deprecatedPrefix{replacement: d.replacement, obj: obj}
+	}
+
+	return nil
+}
+
+// recordDeprecatedPrefix counts an id resolved by a deprecated prefix,
+// logging the first id of each prefix so migrations can be followed without
+// logging every id
+func (r *Resolver) recordDeprecatedPrefix(operation, prefix string) {
+	d, ok := r.deprecatedPrefixes[prefix]
+	if !ok {
+		return
+	}
+
+	if r.metrics != nil {
+		r.metrics.DeprecatedPrefix(prefix, d.replacement)
+	}
+
+	d.warned.Do(func() {
+		r.logger.Warnw("id resolved by a deprecated prefix", "operation", operation, "prefix", prefix, "replacement", d.replacement, "graphql_type", d.obj.Name())
+	})
+}
+
+// deprecatedPrefixesFor returns the deprecated prefixes resolving to the
+// type called typeName, sorted
+func (r *Resolver) deprecatedPrefixesFor(typeName string) []string {
+	prefixes := []string{}
+
+	for prefix, d := range r.deprecatedPrefixes {
+		if d.obj.Name() == typeName {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	sort.Strings(prefixes)
+
+	return prefixes
+}
+
+// deprecatedPrefixesSDL returns the deprecatedPrefixes argument of the
+// @prefixedID directive of typeName in the SDL, or an empty string when it
+// has none
+func (r *Resolver) deprecatedPrefixesSDL(typeName string) string {
+	prefixes := r.deprecatedPrefixesFor(typeName)
+	if len(prefixes) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		quoted[i] = fmt.Sprintf("%q", prefix)
+	}
+
+	return ", deprecatedPrefixes: [" + strings.Join(quoted, ", ") + "]"
+}
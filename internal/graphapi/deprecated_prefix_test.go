@@ -0,0 +1,130 @@
+package graphapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+const deprecatedPrefixTestSchema = `directive @prefixedID(prefix: String!, deprecatedPrefixes: [String!]) on OBJECT
+
+type LoadBalancer implements Node @key(fields: "id") @prefixedID(prefix: "loadbal", deprecatedPrefixes: ["lodbaln", "oldlbal"]) {
+	id: ID!
+}
+type Server implements Node @key(fields: "id") @prefixedID(prefix: "testsrv", deprecatedPrefixes: "oldserv") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+// deprecatedPrefixCounter counts the ids resolved by deprecated prefixes
+type deprecatedPrefixCounter struct {
+	metrics.Nop
+
+	prefixes map[string]int
+}
+
+func (c *deprecatedPrefixCounter) DeprecatedPrefix(prefix, replacement string) {
+	c.prefixes[prefix+">"+replacement]++
+}
+
+func TestDeprecatedPrefixes(t *testing.T) {
+	counter := &deprecatedPrefixCounter{prefixes: map[string]int{}}
+
+	r, err := NewResolver(zap.NewNop().Sugar(), deprecatedPrefixTestSchema, WithMetrics(counter))
+	require.NoError(t, err)
+
+	// ids with a deprecated prefix resolve to the type replacing it
+	node, err := r.GetNode(context.Background(), "lodbaln-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "LoadBalancer", node.GraphType.Name())
+	assert.Equal(t, "lodbaln-abc", node.ID.String())
+
+	result := r.execute(context.Background(), &postData{Query: `{ node(id: "oldserv-abc") { __typename id } }`})
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"__typename": "Server", "id": "oldserv-abc"}}, result.Data)
+
+	query := &postData{
+		Query:     `query($representations:[_Any!]!){_entities(representations:$representations){__typename}}`,
+		Variables: map[string]interface{}{"representations": []interface{}{map[string]interface{}{"__typename": "Node", "id": "oldlbal-abc"}}},
+	}
+
+	result = r.execute(context.Background(), query)
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"_entities": []interface{}{map[string]interface{}{"__typename": "LoadBalancer"}}}, result.Data)
+
+	// current prefixes aren't counted
+	_, err = r.GetNode(context.Background(), "loadbal-abc")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"lodbaln>loadbal": 1, "oldserv>testsrv": 1, "oldlbal>loadbal": 1}, counter.prefixes)
+
+	// deprecated prefixes are listed, but never given for new ids
+	assert.Contains(t, r.Prefixes(), PrefixType{Prefix: "lodbaln", Type: "LoadBalancer", Deprecated: true})
+	assert.Contains(t, r.Prefixes(), PrefixType{Prefix: "loadbal", Type: "LoadBalancer"})
+
+	result = r.execute(context.Background(), &postData{Query: `{ typePrefix(typename: "LoadBalancer") }`})
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"typePrefix": "loadbal"}, result.Data)
+
+	// the SDL declares them, so reloading it keeps them
+	assert.Contains(t, r.SDL(), `@prefixedID(prefix: "loadbal", deprecatedPrefixes: ["lodbaln", "oldlbal"])`)
+
+	reloaded, err := NewResolver(zap.NewNop().Sugar(), r.SDL())
+	require.NoError(t, err)
+	assert.Equal(t, r.Prefixes(), reloaded.Prefixes())
+}
+
+func TestDeprecatedPrefixesIgnored(t *testing.T) {
+	schema := `type LoadBalancer implements Node @key(fields: "id") @prefixedID(prefix: "loadbal", deprecatedPrefixes: ["lb", "testsrv"]) {
+	id: ID!
+}
+type Server implements Node @key(fields: "id") @prefixedID(prefix: "testsrv") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}`
+
+	r, err := NewResolver(zap.NewNop().Sugar(), schema)
+	require.NoError(t, err)
+
+	// invalid prefixes can't be resolved, and current prefixes resolve to
+	// their own type
+	assert.Equal(t, []PrefixType{{Prefix: "loadbal", Type: "LoadBalancer"}, {Prefix: "testsrv", Type: "Server"}}, r.Prefixes())
+
+	node, err := r.GetNode(context.Background(), "testsrv-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "Server", node.GraphType.Name())
+}
+
+func TestDeprecatedPrefixesDuplicate(t *testing.T) {
+	schema := `type LoadBalancer implements Node @key(fields: "id") @prefixedID(prefix: "loadbal", deprecatedPrefixes: ["oldprfx"]) {
+	id: ID!
+}
+type Server implements Node @key(fields: "id") @prefixedID(prefix: "testsrv", deprecatedPrefixes: ["oldprfx"]) {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}`
+
+	r, err := NewResolver(zap.NewNop().Sugar(), schema)
+	require.NoError(t, err)
+
+	node, err := r.GetNode(context.Background(), "oldprfx-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "Server", node.GraphType.Name())
+
+	_, err = NewResolver(zap.NewNop().Sugar(), schema, WithStrictPrefixes())
+	assert.ErrorIs(t, err, ErrDuplicatePrefix)
+}
@@ -0,0 +1,234 @@
+package graphapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+const (
+	// defaultDocumentCacheSize is the default number of parsed queries and
+	// validation results cached
+	defaultDocumentCacheSize = 1000
+	// documentCacheTTL is how long parsed queries and validation results are
+	// cached. Entries never become stale, they only expire to eventually free
+	// queries that stopped being sent and schemas that were replaced.
+	documentCacheTTL = time.Hour
+)
+
+// Cache names used for cache lookup metrics
+const (
+	cacheNameDocument   = "document"
+	cacheNameValidation = "validation"
+	cacheNameResponse   = "response"
+)
+
+// validationResult is a cached validation result, nil errors meaning the
+// document is valid
+type validationResult struct {
+	errors []gqlerrors.FormattedError
+}
+
+// WithDocumentCacheSize sets how many parsed queries and validation results
+// are cached. Gateways send the same few queries over and over, so cache
+// hits skip parsing and validation entirely, including the parsing done for
+// the policy input and the response cache key. A size of 0 disables the
+// cache.
+//
+// The cache is created with the option and shared by every Resolver built
+// with it, including those created by WithSchema. Parsed queries don't
+// depend on the schema and validation results are keyed by the schema
+// checksum, so replacing the schema never serves stale results.
+func WithDocumentCacheSize(size int) Option {
+	documents := newDocumentCache(size)
+
+	return func(r *Resolver) {
+		r.documents = documents
+		r.documentsConfigured = true
+	}
+}
+
+// execute runs a graphql request like graphql.Do, using the document cache
+// to skip parsing and validating queries that have been seen before
+func (r *Resolver) execute(ctx context.Context, p *postData) *graphql.Result {
+	doc, errs := r.document(p.Query)
+	if len(errs) != 0 {
+		r.recordRequest(OperationInvalid, false)
+
+		return &graphql.Result{Errors: errs}
+	}
+
+	operation, err := r.selectOperation(operationNames(doc), p.Operation)
+	if err != nil {
+		r.recordRequest(OperationInvalid, false)
+
+		return &graphql.Result{Errors: withCode(errcode.InvalidRequest, gqlerrors.FormatErrors(err))}
+	}
+
+	if err := r.checkQueryLimits(doc, operation); err != nil {
+		r.recordRequest(OperationInvalid, false)
+
+		return &graphql.Result{Errors: withCode(errcode.QueryTooComplex, gqlerrors.FormatErrors(err))}
+	}
+
+	if !introspectionAllowed(ctx) && selectsIntrospection(doc, operation) {
+		r.recordRequest(OperationIntrospection, false)
+
+		return introspectionDisabledResult()
+	}
+
+	if q, ok := nodeQueryOf(doc, operation, p.Variables, r.idArgType()); ok {
+		result := r.executeNodeQuery(ctx, q)
+
+		if r.metrics != nil {
+			r.recordRequest(operationType(doc, operation), !result.HasErrors())
+		}
+
+		return result
+	}
+
+	if len(r.sources) != 0 {
+		ctx = withSourceLoader(ctx)
+	}
+
+	ctx, itemErrs := withItemErrors(ctx)
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        r.handlerSchema,
+		AST:           doc,
+		OperationName: operation,
+		Args:          p.Variables,
+		Context:       ctx,
+	})
+
+	itemErrs.addTo(result)
+
+	if r.metrics != nil {
+		r.recordRequest(operationType(doc, operation), !result.HasErrors())
+	}
+
+	return result
+}
+
+// document returns the parsed query once it's been validated against the
+// schema. Documents are never modified during execution so they're shared
+// between requests.
+func (r *Resolver) document(query string) (*ast.Document, []gqlerrors.FormattedError) {
+	if r.documents == nil {
+		return r.parseAndValidate(query)
+	}
+
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+
+	documentKey := cacheNameDocument + ":" + hash
+	validationKey := cacheNameValidation + ":" + r.SDLChecksum() + ":" + hash
+
+	v, ok := r.documents.Get(documentKey)
+	r.recordCacheLookup(cacheNameDocument, ok)
+
+	if !ok {
+		doc, errs := r.parseAndValidate(query)
+		if doc != nil {
+			r.documents.Set(documentKey, "", doc)
+		}
+
+		r.documents.Set(validationKey, "", &validationResult{errors: errs})
+
+		return doc, errs
+	}
+
+	doc := v.(*ast.Document)
+
+	v, ok = r.documents.Get(validationKey)
+	r.recordCacheLookup(cacheNameValidation, ok)
+
+	if !ok {
+		v = &validationResult{errors: r.validate(doc)}
+
+		r.documents.Set(validationKey, "", v)
+	}
+
+	if errs := v.(*validationResult).errors; len(errs) != 0 {
+		return nil, errs
+	}
+
+	return doc, nil
+}
+
+// parseAndValidate parses query and validates it against the schema,
+// returning the document even when it's invalid so it can be cached
+func (r *Resolver) parseAndValidate(query string) (*ast.Document, []gqlerrors.FormattedError) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: []byte(query),
+			Name: "GraphQL request",
+		}),
+	})
+	if err != nil {
+		return nil, withCode(errcode.InvalidRequest, gqlerrors.FormatErrors(err))
+	}
+
+	if errs := r.validate(doc); len(errs) != 0 {
+		return doc, errs
+	}
+
+	return doc, nil
+}
+
+// errInvalidQuery is returned for documents that panic during validation
+var errInvalidQuery = errors.New("invalid query")
+
+// validate validates doc against the schema. graphql-go panics validating
+// some malformed documents it parses, such as variables without a type, so
+// a panic is reported as a validation error.
+func (r *Resolver) validate(doc *ast.Document) (errs []gqlerrors.FormattedError) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			errs = withCode(errcode.InvalidRequest, gqlerrors.FormatErrors(errInvalidQuery))
+		}
+	}()
+
+	return withCode(errcode.InvalidRequest, graphql.ValidateDocument(r.schema(), doc, nil).Errors)
+}
+
+// recordRequest records an executed request to the metrics sink
+func (r *Resolver) recordRequest(operation string, ok bool) {
+	if r.metrics == nil {
+		return
+	}
+
+	outcome := RequestSuccess
+	if !ok {
+		outcome = RequestError
+	}
+
+	r.metrics.Request(operation, outcome)
+}
+
+// recordCacheLookup records a cache lookup to the metrics sink
+func (r *Resolver) recordCacheLookup(name string, hit bool) {
+	if r.metrics != nil {
+		r.metrics.CacheLookup(name, hit)
+	}
+}
+
+// newDocumentCache returns the document cache for size, or nil when disabled
+func newDocumentCache(size int) *cache.Cache {
+	if size <= 0 {
+		return nil
+	}
+
+	return cache.New(size, documentCacheTTL)
+}
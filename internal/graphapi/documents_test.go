@@ -0,0 +1,106 @@
+package graphapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+func TestDocumentCache(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema)
+	require.NoError(t, err)
+
+	uncached, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema, WithDocumentCacheSize(0))
+	require.NoError(t, err)
+	assert.Nil(t, uncached.documents)
+
+	queries := []*postData{
+		{Query: `{ node(id: "testsrv-abc") { __typename id } }`},
+		{Query: `query($id: ID!) { node(id: $id) { id } }`, Variables: map[string]interface{}{"id": "testsrv-def"}},
+		{Query: `{ node(id: "testsrv-abc") { missing } }`},
+		{Query: `{ node(id: `},
+		{Query: `query A { a: node(id: "testsrv-a") { id } } query B { b: node(id: "testsrv-b") { id } }`, Operation: "B"},
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, p := range queries {
+			expected := graphql.Do(graphql.Params{
+				Context:        context.Background(),
+				Schema:         r.handlerSchema,
+				RequestString:  p.Query,
+				VariableValues: p.Variables,
+				OperationName:  p.Operation,
+			})
+
+			// documents that fail to parse or validate aren't executed
+			if expected.Data == nil {
+				withCode(errcode.InvalidRequest, expected.Errors)
+			}
+
+			assert.Equal(t, expected, r.execute(context.Background(), p), p.Query)
+			assert.Equal(t, expected, uncached.execute(context.Background(), p), p.Query)
+		}
+	}
+
+	// parsed documents and validation results are cached separately, a
+	// query that fails to parse only caches its errors
+	assert.Equal(t, 9, r.documents.Len())
+}
+
+func TestDocumentCacheSchemaChange(t *testing.T) {
+	opt := WithDocumentCacheSize(10)
+
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema, opt)
+	require.NoError(t, err)
+
+	query := &postData{Query: `{ node(id: "testusr-abc") { ... on User { id } } }`}
+
+	result := r.execute(context.Background(), query)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, `Unknown type "User".`, result.Errors[0].Message)
+
+	updated, err := r.WithSchema(prefixTestSchema + `
+type User implements Node @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}`)
+	require.NoError(t, err)
+
+	assert.Same(t, r.documents, updated.documents)
+	assert.NotEqual(t, r.schemaChecksum, updated.schemaChecksum)
+
+	result = updated.execute(context.Background(), query)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"id": "testusr-abc"}}, result.Data)
+
+	// the parsed document is shared, validation is cached per schema
+	assert.Equal(t, 3, r.documents.Len())
+}
+
+func TestQueryDocumentCache(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema)
+	require.NoError(t, err)
+
+	uncached, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema, WithDocumentCacheSize(0))
+	require.NoError(t, err)
+
+	query := "{\n  node(id: \"testsrv-abc\") {   id }\n}"
+
+	first := r.queryDocument(query)
+	require.NoError(t, first.err)
+	assert.Equal(t, uncached.queryDocument(query).normalized, first.normalized)
+
+	// the parsed document is shared by later requests, errors included
+	assert.Same(t, first, r.queryDocument(query))
+
+	invalid := r.queryDocument(`{ node(id: `)
+	assert.Error(t, invalid.err)
+	assert.Same(t, invalid, r.queryDocument(`{ node(id: `))
+
+	assert.Equal(t, 2, r.documents.Len())
+}
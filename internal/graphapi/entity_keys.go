@@ -0,0 +1,256 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+// ErrKeyLookup is returned for entities whose key was looked up as the id of
+// another type
+var ErrKeyLookup = errors.New("key lookup failed")
+
+// WithKeyLookup serves the @key directives of types declaring fields other
+// than id as their key, such as @key(fields: "serial"). _entities
+// representations without an id, keyed by one of those keys instead, are
+// resolved to the id returned by l for them.
+func WithKeyLookup(l backend.KeyLookup) Option {
+	return func(r *Resolver) {
+		r.keyLookup = l
+	}
+}
+
+// typeKeys are the keys of a type other than its id, and the fields they
+// select in the order they're declared by the type
+type typeKeys struct {
+	keys   [][]string
+	fields []keyField
+}
+
+// keyField is a field selected by a key, with its type in the SDL
+type keyField struct {
+	name    string
+	sdlType string
+}
+
+// keysOf returns the keys of def other than id, as the fields each of them
+// selects. Keys selecting nested fields aren't supported and are returned in
+// unsupported.
+func keysOf(def *ast.Definition) (keys [][]string, unsupported []string) {
+	for _, d := range def.Directives.ForNames("key") {
+		arg := d.Arguments.ForName("fields")
+		if arg == nil || arg.Value == nil {
+			continue
+		}
+
+		fields := strings.Fields(arg.Value.Raw)
+		if len(fields) == 1 && fields[0] == "id" {
+			continue
+		}
+
+		if strings.ContainsAny(arg.Value.Raw, "{}") {
+			unsupported = append(unsupported, arg.Value.Raw)
+
+			continue
+		}
+
+		keys = append(keys, fields)
+	}
+
+	return keys, unsupported
+}
+
+// addEntityKeys adds the fields selected by the keys of def other than id
+// to obj, served from the representations the entities were resolved from.
+// Keys whose fields can't be served are logged and left out.
+func (r *Resolver) addEntityKeys(obj *graphql.Object, def *ast.Definition) {
+	keys, unsupported := keysOf(def)
+
+	for _, key := range unsupported {
+		r.logger.Warnw("@key selecting nested fields isn't supported, it isn't served", "graphql_type", def.Name, "key", key)
+	}
+
+	tk := &typeKeys{}
+	added := map[string]bool{}
+
+	for _, key := range keys {
+		fields := make([]keyField, 0, len(key))
+
+		for _, name := range key {
+			field := def.Fields.ForName(name)
+			if field == nil {
+				break
+			}
+
+			out, ok := r.sourcedOutputType(field.Type)
+			if !ok || len(field.Arguments) != 0 {
+				break
+			}
+
+			if !added[name] {
+				obj.AddFieldConfig(name, &graphql.Field{
+					Type:        out,
+					Description: field.Description,
+					Resolve:     keyFieldResolver(name),
+				})
+			}
+
+			fields = append(fields, keyField{name: name, sdlType: strings.TrimSuffix(field.Type.String(), "!")})
+		}
+
+		if len(fields) != len(key) {
+			r.logger.Warnw("@key field isn't a scalar field without arguments of the type, the key isn't served", "graphql_type", def.Name, "key", strings.Join(key, " "))
+
+			continue
+		}
+
+		for _, f := range fields {
+			if !added[f.name] {
+				added[f.name] = true
+				tk.fields = append(tk.fields, f)
+			}
+		}
+
+		tk.keys = append(tk.keys, key)
+	}
+
+	if len(tk.keys) == 0 {
+		return
+	}
+
+	if r.entityKeys == nil {
+		r.entityKeys = map[string]*typeKeys{}
+	}
+
+	r.entityKeys[def.Name] = tk
+}
+
+// keyFieldResolver resolves a key field from the representation of an
+// entity, the field is null for nodes resolved by id
+func keyFieldResolver(name string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if e, ok := p.Source.(*Entity); ok {
+			return e.key[name], nil
+		}
+
+		return nil, nil
+	}
+}
+
+// representationKey returns the values of the first key of typename whose
+// fields are all set by rep
+func (r *Resolver) representationKey(typename string, rep map[string]interface{}) (map[string]interface{}, bool) {
+	tk, ok := r.entityKeys[typename]
+	if !ok {
+		return nil, false
+	}
+
+	for _, key := range tk.keys {
+		values := make(map[string]interface{}, len(key))
+
+		for _, field := range key {
+			if v, ok := rep[field]; ok && v != nil {
+				values[field] = v
+			}
+		}
+
+		if len(values) == len(key) {
+			return values, true
+		}
+	}
+
+	return nil, false
+}
+
+// lookupEntityKeys looks up the ids of the entities represented by a key,
+// failing those whose key can't be looked up
+func (r *Resolver) lookupEntityKeys(ctx context.Context, entities []*Entity) {
+	for _, entity := range entities {
+		if entity.key == nil || entity.err != nil {
+			continue
+		}
+
+		entity.err = r.lookupEntityKey(ctx, entity)
+	}
+}
+
+func (r *Resolver) lookupEntityKey(ctx context.Context, entity *Entity) error {
+	lctx, cancel, err := r.lookupContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer cancel()
+
+	id, err := r.keyLookup.LookupKey(lctx, entity.typeName, entity.key)
+	if err != nil {
+		if ctx.Err() == nil && lctx.Err() != nil {
+			return ErrLookupTimeout
+		}
+
+		if !errors.Is(err, backend.ErrNotFound) {
+			r.logger.Errorw("key lookup failed", "graphql_type", entity.typeName, "error", err)
+		}
+
+		return err
+	}
+
+	if obj, _ := r.objectForPrefix(prefixOf(id)); obj == nil || obj.Name() != entity.typeName {
+		r.logger.Errorw("key lookup returned the id of another type", "graphql_type", entity.typeName, "id", id)
+
+		return errcode.New(errcode.Internal, fmt.Errorf("%w: %s", ErrKeyLookup, entity.typeName))
+	}
+
+	entity.ID = id
+
+	return nil
+}
+
+// keyedEntityType returns the object type of an entity represented by a
+// key, or the error reported for it like entityType
+func (r *Resolver) keyedEntityType(ctx context.Context, entity *Entity) (*graphql.Object, error) {
+	typeName := ""
+	if entity.ID != "" {
+		typeName = entity.typeName
+	}
+
+	if entity.err != nil {
+		r.recordResolution(ctx, auditOperationEntities, entity.ID.String(), typeName, entity.err)
+		return nil, codedError(entity.err)
+	}
+
+	objType := r.objectForRequest(ctx, prefixOf(entity.ID))
+	if objType == nil || objType.Name() != entity.typeName {
+		r.recordResolution(ctx, auditOperationEntities, entity.ID.String(), "", ErrUnknownPrefix)
+		return nil, errcode.New(errcode.UnknownPrefix, errors.New(safeString(prefixOf(entity.ID))+" is an unknown id prefix"))
+	}
+
+	r.recordResolution(ctx, auditOperationEntities, entity.ID.String(), objType.Name(), nil)
+
+	return objType, nil
+}
+
+// hasKeyedRepresentations reports whether a variable of p lists _entities
+// representations keyed by something other than an id. Their responses
+// can't be invalidated along with the ids they resolve to, so they aren't
+// cached.
+func hasKeyedRepresentations(p postData) bool {
+	for _, v := range p.Variables {
+		reps, _ := v.([]interface{})
+
+		for _, rep := range reps {
+			if m, ok := rep.(map[string]interface{}); ok && m["__typename"] != nil && m["id"] == nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/backend"
+)
+
+const keyedTestSchema = `directive @prefixedID(prefix: String!) on OBJECT
+
+type Server implements Node @key(fields: "id") @key(fields: "serial") @key(fields: "rack slot") @prefixedID(prefix: "testsrv") {
+	id: ID!
+	serial: String!
+	rack: String!
+	slot: Int!
+}
+type LoadBalancer implements Node @key(fields: "id") @prefixedID(prefix: "loadbal") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+// fakeKeyLookup looks keys up in a map of their first value
+type fakeKeyLookup map[interface{}]gidx.PrefixedID
+
+func (f fakeKeyLookup) LookupKey(_ context.Context, _ string, key map[string]interface{}) (gidx.PrefixedID, error) {
+	v := key["serial"]
+	if v == nil {
+		v = key["rack"]
+	}
+
+	if v == "broken" {
+		return "", errors.New("lookup failed")
+	}
+
+	id, ok := f[v]
+	if !ok {
+		return "", backend.ErrNotFound
+	}
+
+	return id, nil
+}
+
+func TestEntityKeys(t *testing.T) {
+	lookup := fakeKeyLookup{"abc": "testsrv-abc", "r1": "testsrv-r1", "lb": "loadbal-abc"}
+
+	r, err := NewResolver(zap.NewNop().Sugar(), keyedTestSchema, WithKeyLookup(lookup))
+	require.NoError(t, err)
+
+	query := &postData{
+		Query: `query($representations:[_Any!]!){_entities(representations:$representations){__typename ... on Server { id serial rack }}}`,
+		Variables: map[string]interface{}{"representations": []interface{}{
+			map[string]interface{}{"__typename": "Server", "serial": "abc"},
+			map[string]interface{}{"__typename": "Server", "rack": "r1", "slot": 4},
+			map[string]interface{}{"__typename": "Server", "serial": "def"},
+			map[string]interface{}{"__typename": "Server", "serial": "lb"},
+			map[string]interface{}{"__typename": "Server", "serial": "broken"},
+			map[string]interface{}{"__typename": "Server", "rack": "r1"},
+			map[string]interface{}{"__typename": "Node", "id": "testsrv-xyz"},
+		}},
+	}
+
+	result := r.execute(context.Background(), query)
+
+	entities := result.Data.(map[string]interface{})["_entities"].([]interface{})
+	require.Len(t, entities, 7)
+
+	assert.Equal(t, map[string]interface{}{"__typename": "Server", "id": "testsrv-abc", "serial": "abc", "rack": nil}, entities[0])
+	assert.Equal(t, map[string]interface{}{"__typename": "Server", "id": "testsrv-r1", "serial": nil, "rack": "r1"}, entities[1])
+	assert.Equal(t, map[string]interface{}{"__typename": "Server", "id": "testsrv-xyz", "serial": nil, "rack": nil}, entities[6])
+
+	for _, i := range []int{2, 3, 4, 5} {
+		assert.Nil(t, entities[i])
+	}
+
+	// the key wasn't found, was the id of another type, the lookup failed
+	// and the representation is missing a field of the key
+	require.Len(t, result.Errors, 4)
+	assert.Equal(t, "not_found", result.Errors[0].Extensions["code"])
+	assert.Equal(t, "internal", result.Errors[1].Extensions["code"])
+	assert.Equal(t, "internal", result.Errors[2].Extensions["code"])
+	assert.Contains(t, result.Errors[3].Message, "id is missing")
+
+	sdl := r.SDL()
+	assert.Contains(t, sdl, `type Server implements Node @key(fields: "id") @key(fields: "serial") @key(fields: "rack slot") @prefixedID(prefix: "testsrv") {
+  id: ID!
+  serial: String
+  rack: String
+  slot: Int
+}`)
+	assert.Contains(t, sdl, `type LoadBalancer implements Node @key(fields: "id") @prefixedID(prefix: "loadbal") {
+  id: ID!
+}`)
+}
+
+func TestEntityKeysDisabled(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), keyedTestSchema)
+	require.NoError(t, err)
+
+	query := &postData{
+		Query:     `query($representations:[_Any!]!){_entities(representations:$representations){__typename}}`,
+		Variables: map[string]interface{}{"representations": []interface{}{map[string]interface{}{"__typename": "Server", "serial": "abc"}}},
+	}
+
+	// without a key lookup only ids are keys
+	result := r.execute(context.Background(), query)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, "id is missing")
+	assert.NotContains(t, r.SDL(), "serial")
+}
@@ -0,0 +1,168 @@
+package graphapi
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// poolTuneEvery is how many chunks are authorized between resizing an
+	// adaptive entity pool
+	poolTuneEvery = 16
+	// poolSlowdown is how much slower than the baseline chunks must get for
+	// an adaptive entity pool to shrink
+	poolSlowdown = 2
+	// poolBaselineDrift is the fraction of the difference to the latest
+	// latency the baseline moves by each time the pool is tuned, so a lasting
+	// change in the authorizer's latency becomes the new baseline
+	poolBaselineDrift = 8
+)
+
+// entityPool limits how many _entities chunks are authorized concurrently
+// across every request, queueing the rest in arrival order. An adaptive pool
+// resizes itself between min and max: it grows while chunks queue for a
+// worker and the authorizer keeps up, and shrinks when chunks get slower than
+// the baseline, since more concurrency would only add to the authorizer's
+// load. An entityPool is safe for concurrent use.
+type entityPool struct {
+	min int
+	max int
+
+	mu      sync.Mutex
+	limit   int
+	active  int
+	waiters list.List
+
+	// the chunks authorized since the pool was last tuned, their total
+	// latency and whether any of them had to queue
+	chunks  int
+	latency time.Duration
+	queued  bool
+	// baseline is the average chunk latency the pool compares against
+	baseline time.Duration
+}
+
+// newEntityPool returns a pool of min workers which may grow up to max
+func newEntityPool(min, max int) *entityPool {
+	if max < min {
+		max = min
+	}
+
+	return &entityPool{min: min, max: max, limit: min}
+}
+
+// acquire waits for a worker, returning how long it waited. The wait ends
+// with the error of ctx when it's done first.
+func (p *entityPool) acquire(ctx context.Context) (time.Duration, error) {
+	p.mu.Lock()
+
+	if p.active < p.limit && p.waiters.Len() == 0 {
+		p.active++
+		p.mu.Unlock()
+
+		return 0, nil
+	}
+
+	start := time.Now()
+	ready := make(chan struct{})
+	waiter := p.waiters.PushBack(ready)
+	p.queued = true
+
+	p.mu.Unlock()
+
+	select {
+	case <-ready:
+		return time.Since(start), nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-ready:
+		// the worker was handed over as ctx was done, so pass it on
+		p.active--
+		p.dispatch()
+	default:
+		p.waiters.Remove(waiter)
+	}
+
+	return time.Since(start), ctx.Err()
+}
+
+// release returns a worker to the pool after authorizing a chunk in latency
+func (p *entityPool) release(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.active--
+	p.chunks++
+	p.latency += latency
+
+	if p.chunks >= poolTuneEvery {
+		p.tune()
+	}
+
+	p.dispatch()
+}
+
+// stats returns the current number of workers, how many of them are busy
+// and how many chunks wait for one
+func (p *entityPool) stats() EntityPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return EntityPoolStats{
+		MinWorkers: p.min,
+		MaxWorkers: p.max,
+		Workers:    p.limit,
+		Active:     p.active,
+		Queued:     p.waiters.Len(),
+	}
+}
+
+// tune resizes the pool from the chunks authorized since it was last tuned.
+// p.mu must be held.
+func (p *entityPool) tune() {
+	avg := p.latency / time.Duration(p.chunks)
+
+	switch {
+	case p.baseline == 0 || avg < p.baseline:
+		p.baseline = avg
+	default:
+		p.baseline += (avg - p.baseline) / poolBaselineDrift
+	}
+
+	switch {
+	case avg > poolSlowdown*p.baseline:
+		p.limit -= (p.limit + 3) / 4 //nolint:gomnd
+	case p.queued:
+		p.limit++
+	}
+
+	if p.limit < p.min {
+		p.limit = p.min
+	}
+
+	if p.limit > p.max {
+		p.limit = p.max
+	}
+
+	p.chunks = 0
+	p.latency = 0
+	p.queued = false
+}
+
+// dispatch hands free workers to the longest waiting chunks. p.mu must be
+// held.
+func (p *entityPool) dispatch() {
+	for p.active < p.limit && p.waiters.Len() > 0 {
+		ready := p.waiters.Remove(p.waiters.Front()).(chan struct{})
+		p.active++
+
+		close(ready)
+	}
+}
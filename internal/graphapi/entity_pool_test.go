@@ -0,0 +1,100 @@
+package graphapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntityPool(t *testing.T) {
+	p := newEntityPool(2, 2)
+
+	for i := 0; i < 2; i++ {
+		wait, err := p.acquire(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	// chunks beyond the workers wait in arrival order
+	acquired := make(chan int, 2)
+
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			_, err := p.acquire(context.Background())
+			assert.NoError(t, err)
+
+			acquired <- i
+		}(i)
+
+		require.Eventually(t, func() bool {
+			return p.stats().Queued == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	p.release(time.Millisecond)
+	assert.Equal(t, 0, <-acquired)
+
+	p.release(time.Millisecond)
+	assert.Equal(t, 1, <-acquired)
+
+	assert.Equal(t, EntityPoolStats{MinWorkers: 2, MaxWorkers: 2, Workers: 2, Active: 2}, p.stats())
+}
+
+func TestEntityPoolCanceled(t *testing.T) {
+	p := newEntityPool(2, 2)
+
+	for i := 0; i < 2; i++ {
+		_, err := p.acquire(context.Background())
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	wait, err := p.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, wait, 10*time.Millisecond)
+
+	// the canceled chunk left the queue, so released workers are free
+	assert.Equal(t, 0, p.stats().Queued)
+
+	p.release(time.Millisecond)
+
+	_, err = p.acquire(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestEntityPoolAdapts(t *testing.T) {
+	p := newEntityPool(4, 6)
+
+	// chunks queueing while the authorizer keeps up add workers
+	for round := 0; round < 4; round++ {
+		p.mu.Lock()
+		p.queued = true
+		p.mu.Unlock()
+
+		for i := 0; i < poolTuneEvery; i++ {
+			_, err := p.acquire(context.Background())
+			require.NoError(t, err)
+
+			p.release(10 * time.Millisecond)
+		}
+	}
+
+	assert.Equal(t, 6, p.stats().Workers, "workers are added up to the max")
+
+	// chunks getting slower remove workers, down to the min
+	for round := 0; round < 2; round++ {
+		for i := 0; i < poolTuneEvery; i++ {
+			_, err := p.acquire(context.Background())
+			require.NoError(t, err)
+
+			p.release(100 * time.Millisecond)
+		}
+
+		assert.Equal(t, 4, p.stats().Workers)
+	}
+}
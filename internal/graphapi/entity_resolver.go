@@ -1,15 +1,42 @@
 package graphapi
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/graphql-go/graphql"
-	"github.com/graphql-go/graphql/gqlerrors"
 	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+const (
+	// defaultEntityWorkers is the default number of entity chunks processed
+	// concurrently across all requests
+	defaultEntityWorkers = 8
+	// entityChunkSize is the number of representations processed together.
+	// Batches up to this size are processed on the request goroutine.
+	entityChunkSize = 100
 )
 
+// ErrInvalidRepresentation is returned for the entities of representations
+// without a string __typename and id
+var ErrInvalidRepresentation = errors.New("invalid representation")
+
+// errEntityChunkFailed is set on every entity of a chunk that failed
+// unexpectedly, so only that chunk fails rather than the whole batch
+var errEntityChunkFailed = errors.New("failed to resolve entity")
+
 // Entity represents an entity interface object when an _entities query is made
 type Entity struct {
 	typeName string //__typename that is provided in representations
 	ID       gidx.PrefixedID
+	key      map[string]interface{} // set when the representation has a key other than the id
+	err      error                  // set when the entity can't be resolved, e.g. it isn't authorized
+	objType  *graphql.Object        // the object type the entity resolved to
 }
 
 func (r *Resolver) entitiesResolver(p graphql.ResolveParams) (interface{}, error) {
@@ -17,53 +44,283 @@ func (r *Resolver) entitiesResolver(p graphql.ResolveParams) (interface{}, error
 	entities := make([]*Entity, len(reps))
 
 	for repLoc, rep := range reps {
-		re := rep.(map[string]interface{})
-		id := gidx.PrefixedID(re["id"].(string))
-		typename := re["__typename"].(string)
+		typename, id, key, err := r.parseRepresentation(repLoc, rep)
+		if err != nil {
+			entities[repLoc] = &Entity{typeName: typename, err: err}
+
+			continue
+		}
+
+		if key != nil {
+			entities[repLoc] = &Entity{typeName: typename, key: key}
+
+			continue
+		}
+
+		id, err = r.normalizeID(id)
+		if err != nil {
+			entities[repLoc] = &Entity{typeName: typename, err: err}
+
+			continue
+		}
+
+		entities[repLoc] = &Entity{typeName: typename, ID: gidx.PrefixedID(id)}
+	}
+
+	ctx, span := startEntitiesSpan(p.Context, entities)
+	defer endEntitiesSpan(span, entities)
+
+	if r.keyLookup != nil {
+		r.lookupEntityKeys(ctx, entities)
+	}
+
+	r.authorizeEntities(ctx, entities)
+
+	if len(r.sources) != 0 {
+		for _, entity := range entities {
+			if entity.err != nil {
+				continue
+			}
+
+			if obj := r.objectForRequest(ctx, prefixOf(entity.ID)); obj != nil {
+				r.wantSource(ctx, obj, entity.ID)
+			}
+		}
+	}
+
+	// entities that fail are null in the list, with their errors reported
+	// for their index once the request is executed
+	items := make([]interface{}, len(entities))
+	errs := make([]error, len(entities))
+
+	for i, entity := range entities {
+		objType, err := r.entityType(ctx, entity)
+		if err != nil {
+			errs[i] = err
 
-		entities[repLoc] = &Entity{typeName: typename, ID: id}
+			continue
+		}
+
+		entity.objType = objType
+		items[i] = entity
 	}
 
-	return entities, nil
+	itemErrorsFrom(ctx).add(p, errs)
+
+	return items, nil
 }
 
-// entityTypeResolver gets called after we convert the representations to an []*Entities. If for some reason one of those
-// entities is not valid the only way to make it null and give an error is to panic with the error. This seems strange, but
-// the graphql library catches the panic and returns the proper error to the user making the request.
-func (r *Resolver) entityTypeResolver(p graphql.ResolveTypeParams) *graphql.Object {
-	entity := p.Value.(*Entity)
+// parseRepresentation returns the __typename and id of the representation
+// at index i, or an error saying what's wrong with it, so a malformed
+// representation only fails its own entity. Representations of keyed types
+// without an id return the values of their key instead.
+func (r *Resolver) parseRepresentation(i int, rep interface{}) (typename, id string, key map[string]interface{}, err error) {
+	re, ok := rep.(map[string]interface{})
+	if !ok {
+		return "", "", nil, invalidRepresentation(i, "it isn't an object")
+	}
 
-	graphType, ok := r.interfaceMap[entity.typeName]
+	typename, ok = re["__typename"].(string)
 	if !ok {
-		panic(gqlerrors.NewFormattedError(entity.typeName + " is an unknown interface type"))
+		if _, present := re["__typename"]; present {
+			return "", "", nil, invalidRepresentation(i, "__typename isn't a string")
+		}
+
+		return "", "", nil, invalidRepresentation(i, "__typename is missing")
 	}
 
-	objType, ok := r.prefixMap[entity.ID.Prefix()]
+	id, ok = re["id"].(string)
 	if !ok {
-		panic(gqlerrors.NewFormattedError(entity.ID.Prefix() + " is an unknown id prefix"))
+		if _, present := re["id"]; present {
+			return typename, "", nil, invalidRepresentation(i, "id isn't a string")
+		}
+
+		if key, ok := r.representationKey(typename, re); ok {
+			return typename, "", key, nil
+		}
+
+		return typename, "", nil, invalidRepresentation(i, "id is missing")
+	}
+
+	return typename, id, nil, nil
+}
+
+// invalidRepresentation returns the error of the malformed representation
+// at index i
+func invalidRepresentation(i int, problem string) error {
+	return errcode.New(errcode.InvalidRequest, fmt.Errorf("%w %d: %s", ErrInvalidRepresentation, i, problem))
+}
+
+// authorizeEntities authorizes the entities in chunks, processing chunks
+// concurrently with the workers of the entity pool so large batches aren't
+// limited by the latency of the authorizer
+func (r *Resolver) authorizeEntities(ctx context.Context, entities []*Entity) {
+	if r.authorizer == nil && r.chaos == nil && r.nodeBackend == nil {
+		return
+	}
+
+	if r.entityPool == nil || len(entities) <= entityChunkSize {
+		r.authorizeEntityChunk(ctx, entities)
+
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for start := 0; start < len(entities); start += entityChunkSize {
+		end := start + entityChunkSize
+		if end > len(entities) {
+			end = len(entities)
+		}
+
+		// once the request is done the remaining chunks fail without
+		// waiting for a worker
+		wait, err := r.entityPool.acquire(ctx)
+		r.recordEntityWait(wait)
+
+		if err != nil {
+			for _, entity := range entities[start:] {
+				if entity.err == nil {
+					entity.err = err
+				}
+			}
+
+			wg.Wait()
+
+			return
+		}
+
+		wg.Add(1)
+
+		go func(chunk []*Entity) {
+			start := time.Now()
+
+			defer func() {
+				r.entityPool.release(time.Since(start))
+				r.recordEntityPool()
+				wg.Done()
+			}()
+
+			r.authorizeEntityChunk(ctx, chunk)
+		}(entities[start:end])
+	}
+
+	wg.Wait()
+}
+
+// recordEntityWait records how long a chunk waited for a worker of the entity
+// pool to the metrics sink
+func (r *Resolver) recordEntityWait(wait time.Duration) {
+	if r.metrics != nil {
+		r.metrics.EntityWait(wait)
 	}
-	if r.handlerSchema.IsPossibleType(graphType, objType) {
-		return objType
-	} else {
-		panic(gqlerrors.NewFormattedError(objType.Name() + " doesn't implement interface " + graphType.Name()))
+
+	r.recordEntityPool()
+}
+
+// recordEntityPool records the queue length and size of the entity pool to
+// the metrics sink
+func (r *Resolver) recordEntityPool() {
+	if r.metrics != nil {
+		stats := r.entityPool.stats()
+		r.metrics.EntityPool(stats.Queued, stats.Workers)
+	}
+}
+
+// authorizeEntityChunk authorizes each entity of chunk with a known prefix.
+// A panic fails every entity in the chunk instead of the request.
+func (r *Resolver) authorizeEntityChunk(ctx context.Context, chunk []*Entity) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.logger.Errorw("failed to authorize entities", "error", rec, "entities", len(chunk))
+
+			for _, entity := range chunk {
+				entity.err = errEntityChunkFailed
+			}
+		}
+	}()
+
+	for _, entity := range chunk {
+		if entity.err != nil {
+			continue
+		}
+
+		if obj := r.objectForRequest(ctx, prefixOf(entity.ID)); obj == nil {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			entity.err = err
+
+			continue
+		}
+
+		entity.err = r.authorizeID(ctx, entity.ID, true)
 	}
 }
 
-func (r *Resolver) entitiesUnion() *graphql.Union {
-	if r.entities != nil {
-		return r.entities
+// entityType returns the object type of entity, or the error reported for
+// it when it can't be resolved, recording the outcome
+func (r *Resolver) entityType(ctx context.Context, entity *Entity) (*graphql.Object, error) {
+	// malformed representations have no type or id to look up
+	if errors.Is(entity.err, ErrInvalidRepresentation) {
+		r.recordResolution(ctx, auditOperationEntities, "", "", entity.err)
+		return nil, entity.err
+	}
+
+	// keyed representations name the object type they were looked up as
+	if entity.key != nil {
+		return r.keyedEntityType(ctx, entity)
+	}
+
+	graphType, ok := r.interfaceMap[entity.typeName]
+	if !ok {
+		err := errcode.New(errcode.UnknownInterface, errors.New(safeString(entity.typeName)+" is an unknown interface type"))
+		r.recordResolution(ctx, auditOperationEntities, entity.ID.String(), "", err)
+
+		return nil, err
+	}
+
+	// ids rejected before parsing are dropped, so there's no prefix to look up
+	if entity.ID == "" && entity.err != nil {
+		r.recordResolution(ctx, auditOperationEntities, "", "", entity.err)
+		return nil, codedError(entity.err)
+	}
+
+	objType := r.objectForRequest(ctx, prefixOf(entity.ID))
+	if objType == nil {
+		r.recordResolution(ctx, auditOperationEntities, entity.ID.String(), "", ErrUnknownPrefix)
+		return nil, errcode.New(errcode.UnknownPrefix, errors.New(safeString(prefixOf(entity.ID))+" is an unknown id prefix"))
+	}
+
+	if entity.err != nil {
+		r.recordResolution(ctx, auditOperationEntities, entity.ID.String(), objType.Name(), entity.err)
+		return nil, codedError(entity.err)
 	}
 
-	entTypes := []*graphql.Object{}
-	for _, obj := range r.prefixMap {
-		entTypes = append(entTypes, obj)
+	if !r.schema().IsPossibleType(graphType, objType) {
+		err := errcode.New(errcode.TypeInterfaceMismatch, errors.New(objType.Name()+" doesn't implement interface "+graphType.Name()))
+		r.recordResolution(ctx, auditOperationEntities, entity.ID.String(), objType.Name(), err)
+
+		return nil, err
 	}
 
-	r.entities = graphql.NewUnion(graphql.UnionConfig{
+	r.recordResolution(ctx, auditOperationEntities, entity.ID.String(), objType.Name(), nil)
+
+	return objType, nil
+}
+
+// entityTypeResolver returns the object type an entity resolved to. Entities
+// that failed are never resolved, they're null in the _entities list.
+func (r *Resolver) entityTypeResolver(p graphql.ResolveTypeParams) *graphql.Object {
+	return p.Value.(*Entity).objType
+}
+
+// newEntitiesUnion returns the _Entities union of every object type
+func (r *Resolver) newEntitiesUnion() *graphql.Union {
+	return graphql.NewUnion(graphql.UnionConfig{
 		Name:        "_Entities",
-		Types:       entTypes,
-		ResolveType: r.entityTypeResolver,
+		Types:       r.objects,
+		ResolveType: r.recoverResolveType(r.entityTypeResolver),
 	})
-
-	return r.entities
 }
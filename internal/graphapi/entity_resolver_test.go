@@ -0,0 +1,180 @@
+package graphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+	"go.infratographer.com/node-resolver/internal/shed"
+)
+
+// slowAuthorizer tracks how many checks run concurrently and panics for a
+// single id
+type slowAuthorizer struct {
+	running     atomic.Int32
+	maxRunning  atomic.Int32
+	panicForID  gidx.PrefixedID
+	checkLength time.Duration
+}
+
+func (a *slowAuthorizer) CanResolve(_ context.Context, _ string, id gidx.PrefixedID) error {
+	n := a.running.Add(1)
+	defer a.running.Add(-1)
+
+	for {
+		max := a.maxRunning.Load()
+		if n <= max || a.maxRunning.CompareAndSwap(max, n) {
+			break
+		}
+	}
+
+	if id == a.panicForID {
+		panic("authorizer failure")
+	}
+
+	time.Sleep(a.checkLength)
+
+	return nil
+}
+
+func entitiesQuery(t *testing.T, n int) string {
+	reps := make([]map[string]string, n)
+
+	for i := range reps {
+		reps[i] = map[string]string{"__typename": "Node", "id": fmt.Sprintf("testsrv-%04d", i)}
+	}
+
+	b, err := json.Marshal(map[string]interface{}{
+		"query":     "query($representations:[_Any!]!){_entities(representations:$representations){...on Server{id}}}",
+		"variables": map[string]interface{}{"representations": reps},
+	})
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+func TestEntitiesConcurrency(t *testing.T) {
+	authorizer := &slowAuthorizer{panicForID: "testsrv-0150", checkLength: 100 * time.Microsecond}
+
+	resp, err := testQuery(validTestSchema, entitiesQuery(t, 1000),
+		graphapi.WithAuthorizer(authorizer),
+		graphapi.WithEntityConcurrency(4),
+	)
+	require.NoError(t, err)
+
+	var data struct {
+		Entities []*struct {
+			ID string `json:"id"`
+		} `json:"_entities"`
+	}
+
+	require.NoError(t, json.Unmarshal(resp.RawData, &data))
+	require.Len(t, data.Entities, 1000)
+
+	// the panic only fails the chunk containing the id
+	for i, entity := range data.Entities {
+		if i >= 100 && i < 200 {
+			assert.Nil(t, entity, i)
+
+			continue
+		}
+
+		require.NotNil(t, entity, i)
+		assert.Equal(t, fmt.Sprintf("testsrv-%04d", i), entity.ID)
+	}
+
+	assert.Len(t, resp.Errors, 100)
+	assert.Greater(t, authorizer.maxRunning.Load(), int32(1))
+	assert.LessOrEqual(t, authorizer.maxRunning.Load(), int32(4))
+}
+
+func TestEntitiesSerial(t *testing.T) {
+	authorizer := &slowAuthorizer{}
+
+	resp, err := testQuery(validTestSchema, entitiesQuery(t, 300),
+		graphapi.WithAuthorizer(authorizer),
+		graphapi.WithEntityConcurrency(1),
+	)
+	require.NoError(t, err)
+	assert.Len(t, resp.Errors, 0)
+	assert.Equal(t, int32(1), authorizer.maxRunning.Load())
+}
+
+func TestEntitiesLoadShedding(t *testing.T) {
+	shedder := shed.New(shed.Config{MemoryThreshold: 1, MaxBodySize: 1 << 20, MaxRepresentations: 100}, nil, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	shedder.Start(ctx)
+	require.True(t, shedder.UnderPressure())
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithLoadShedder(shedder))
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	for n, expected := range map[int]int{100: http.StatusOK, 101: http.StatusServiceUnavailable} {
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(entitiesQuery(t, n)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, expected, rec.Code, n)
+	}
+}
+
+func TestEntitiesErrors(t *testing.T) {
+	body := `{"query": "query($r: [_Any!]!) { nodes(ids: [\"testtkn-abc\", \"testsrv-abc\", \"notanid\"]) { id } _entities(representations: $r) { ... on Node { id } } }", "variables": {"r": [
+		{"__typename": "Missing", "id": "testsrv-abc"},
+		{"__typename": "Node", "id": "testsrv-abc"},
+		{"__typename": "Node"},
+		{"__typename": "Node", "id": "testtkn-abc"},
+		{"__typename": "Node", "id": "unknown-abc"}
+	]}}`
+
+	resp, err := testQuery(validTestSchema, body, graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testtkn"}))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"nodes": [null, {"id": "testsrv-abc"}, null],
+		"_entities": [null, {"id": "testsrv-abc"}, null, null, null]
+	}`, string(resp.RawData))
+
+	// failed items are reported ordered by path, each with the path of its
+	// item and its own code
+	expected := []struct {
+		path []interface{}
+		code string
+	}{
+		{[]interface{}{"_entities", float64(0)}, "unknown_interface"},
+		{[]interface{}{"_entities", float64(2)}, "invalid_request"},
+		{[]interface{}{"_entities", float64(3)}, "unauthorized"},
+		{[]interface{}{"_entities", float64(4)}, "unknown_prefix"},
+		{[]interface{}{"nodes", float64(0)}, "unauthorized"},
+		{[]interface{}{"nodes", float64(2)}, "invalid_id"},
+	}
+
+	require.Len(t, resp.Errors, len(expected))
+
+	for i, e := range expected {
+		assert.Equal(t, e.path, resp.Errors[i].Path, i)
+		assert.Equal(t, e.code, resp.Errors[i].Extensions["code"], i)
+		assert.NotEmpty(t, resp.Errors[i].Locations, i)
+	}
+}
@@ -0,0 +1,118 @@
+package graphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestErrorCatalog(t *testing.T) {
+	catalog, err := errcode.NewCatalog(errcode.Config{
+		Messages: map[string]map[string]string{
+			"de": {"unauthorized": "Kein Zugriff ({{.Message}})"},
+		},
+	})
+	require.NoError(t, err)
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema,
+		graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testtkn"}),
+		graphapi.WithErrorCatalog(catalog),
+	)
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	serve := func(req *http.Request, acceptLanguage string) *httptest.ResponseRecorder {
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	query := func(acceptLanguage string) (string, string) {
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query":"{ node(id: \"testtkn-123\") { id } }"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := serve(req, acceptLanguage)
+
+		var resp queryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Len(t, resp.Errors, 1)
+
+		return resp.Errors[0].Message, rec.Header().Get("Content-Language")
+	}
+
+	msg, lang := query("de-DE, en;q=0.8")
+	assert.Equal(t, "Kein Zugriff (not authorized to resolve id)", msg)
+	assert.Equal(t, "de", lang)
+
+	msg, lang = query("")
+	assert.Equal(t, "You aren't allowed to access this resource.", msg)
+	assert.Equal(t, "en", lang)
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/api/v1/resolve?id=testtkn-123&id=testsrv-123", nil), "de")
+
+	var resp graphapi.ResolveResponse
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 2)
+	require.NotNil(t, resp.Results[0].Error)
+	assert.Equal(t, "Kein Zugriff (not authorized to resolve id)", resp.Results[0].Error.Message)
+	assert.Nil(t, resp.Results[1].Error)
+
+	rec = serve(httptest.NewRequest(http.MethodGet, "/api/v1/resolve", nil), "")
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, "The request is invalid: at least one id is required", resp.Error.Message)
+}
+
+func TestErrorMasking(t *testing.T) {
+	catalog, err := errcode.NewCatalog(errcode.Config{Mask: true})
+	require.NoError(t, err)
+
+	body := `{"query": "query($r: [_Any!]!) { node(id: \"notanid\") { id } _entities(representations: $r) { __typename } }", "variables": {"r": [{"__typename": "Missing", "id": "testsrv-abc"}]}}`
+
+	resp, err := testQuery(validTestSchema, body, graphapi.WithErrorMasking(catalog))
+	require.NoError(t, err)
+	require.Len(t, resp.Errors, 2)
+
+	// internal details, such as the gidx parse error and the name of the
+	// type, are masked by the messages of their codes
+	assert.Equal(t, "The id isn't valid.", resp.Errors[0].Message)
+	assert.Equal(t, map[string]interface{}{"code": "invalid_id"}, resp.Errors[0].Extensions)
+	assert.Equal(t, "The type of the representation isn't known.", resp.Errors[1].Message)
+	assert.Equal(t, map[string]interface{}{"code": "unknown_interface"}, resp.Errors[1].Extensions)
+
+	resp, err = testQuery(validTestSchema, body, graphapi.WithErrorMasking(catalog), graphapi.WithVerboseErrors())
+	require.NoError(t, err)
+	require.Len(t, resp.Errors, 2)
+
+	assert.Equal(t, "The type of the representation isn't known.", resp.Errors[1].Message)
+	assert.Equal(t, map[string]interface{}{"code": "unknown_interface", "detail": "Missing is an unknown interface type"}, resp.Errors[1].Extensions)
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithErrorMasking(catalog))
+	require.NoError(t, err)
+
+	results, err := r.ResolveIDs(context.Background(), []string{"notanid", "unknown-abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "The id isn't valid.", results[0].Error.Message)
+	assert.Equal(t, "The id doesn't belong to a known resource type.", results[1].Error.Message)
+	assert.Empty(t, results[1].Error.Detail)
+}
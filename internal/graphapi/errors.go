@@ -0,0 +1,218 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/labstack/echo/v4"
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/breaker"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/policy"
+)
+
+// errorCode returns the code reported for err
+func errorCode(err error) errcode.Code {
+	var invalidID *gidx.ErrInvalidID
+
+	switch {
+	case errors.As(err, &invalidID), errors.Is(err, ErrIDTooLong), errors.Is(err, ErrIDInvalidCharacters), errors.Is(err, ErrEmptyID):
+		return errcode.InvalidID
+	case errors.Is(err, ErrUnknownPrefix):
+		return errcode.UnknownPrefix
+	case errors.Is(err, authz.ErrUnauthorized):
+		return errcode.Unauthorized
+	case errors.Is(err, policy.ErrDenied):
+		return errcode.Denied
+	case errors.Is(err, ErrLookupTimeout), errors.Is(err, context.DeadlineExceeded):
+		return errcode.Timeout
+	case errors.Is(err, breaker.ErrOpen):
+		return errcode.Unavailable
+	case errors.Is(err, authz.ErrDeleted):
+		return errcode.Deleted
+	case errors.Is(err, backend.ErrNotFound):
+		return errcode.NotFound
+	default:
+		return errcode.Of(err)
+	}
+}
+
+// DeletedAtExtensionKey is the key of the deletion time of a deleted id in
+// the extensions of graphql errors
+const DeletedAtExtensionKey = "deletedAt"
+
+// codedError returns err with its code, which graphql-go adds to the
+// extensions of the error
+func codedError(err error) error {
+	var deleted *authz.DeletedError
+	if errors.As(err, &deleted) && !deleted.DeletedAt.IsZero() {
+		return &deletedError{coded: errcode.New(errcode.Deleted, err), deletedAt: deleted.DeletedAt}
+	}
+
+	return errcode.New(errorCode(err), err)
+}
+
+// deletedError is the error of a deleted id, reporting when it was deleted
+// in its extensions along with its code
+type deletedError struct {
+	coded     *errcode.Error
+	deletedAt time.Time
+}
+
+func (e *deletedError) Error() string {
+	return e.coded.Error()
+}
+
+func (e *deletedError) Unwrap() error {
+	return e.coded
+}
+
+// Extensions returns the code and deletion time of the error
+func (e *deletedError) Extensions() map[string]interface{} {
+	ext := e.coded.Extensions()
+	ext[DeletedAtExtensionKey] = e.deletedAt.UTC().Format(time.RFC3339)
+
+	return ext
+}
+
+// DetailExtensionKey is the key of the original message of masked or
+// localized errors in the extensions of graphql errors, with verbose errors
+const DetailExtensionKey = "detail"
+
+// localizeErrors returns errs with the messages of coded errors from the
+// error catalog, in the language accepted by the request, or masked with
+// the messages of their codes. errs may be shared with the document cache,
+// so they're never changed in place.
+func (r *Resolver) localizeErrors(c echo.Context, errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	if (r.errorCatalog == nil && r.errorMask == nil) || len(errs) == 0 {
+		return errs
+	}
+
+	catalog, lang := r.errorMessages(c)
+	localized := append([]gqlerrors.FormattedError(nil), errs...)
+
+	for i := range localized {
+		code, ok := localized[i].Extensions[errcode.ExtensionKey].(string)
+		if !ok {
+			if r.errorMask == nil {
+				continue
+			}
+
+			// errors without a code are masked as internal errors
+			code = string(errcode.Internal)
+		}
+
+		message := localized[i].Message
+		localized[i].Message = catalog.Message(lang, errcode.Code(code), message)
+
+		if !ok || (r.verboseErrors && localized[i].Message != message) {
+			ext := make(map[string]interface{}, len(localized[i].Extensions)+2)
+			for k, v := range localized[i].Extensions {
+				ext[k] = v
+			}
+
+			ext[errcode.ExtensionKey] = code
+
+			if r.verboseErrors {
+				ext[DetailExtensionKey] = message
+			}
+
+			localized[i].Extensions = ext
+		}
+	}
+
+	return localized
+}
+
+// localizeResolveResponse replaces the messages of the errors of resp with
+// those of the error catalog, in the language accepted by the request, or
+// masks them
+func (r *Resolver) localizeResolveResponse(c echo.Context, resp *ResolveResponse) {
+	if r.errorCatalog == nil && r.errorMask == nil {
+		return
+	}
+
+	errs := []*ResolveError{resp.Error}
+	for i := range resp.Results {
+		errs = append(errs, resp.Results[i].Error)
+	}
+
+	var (
+		catalog *errcode.Catalog
+		lang    string
+	)
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		if catalog == nil {
+			catalog, lang = r.errorMessages(c)
+		}
+
+		r.replaceMessage(catalog, lang, err)
+	}
+}
+
+// maskResolveResults masks the messages of the errors of results, for
+// callers without a request to pick the language from
+func (r *Resolver) maskResolveResults(results []ResolveResult) {
+	if r.errorMask == nil {
+		return
+	}
+
+	lang := r.errorMask.Language("")
+
+	for i := range results {
+		if results[i].Error != nil {
+			r.replaceMessage(r.errorMask, lang, results[i].Error)
+		}
+	}
+}
+
+// replaceMessage replaces the message of err with that of catalog in lang,
+// keeping the original message as its detail with verbose errors
+func (r *Resolver) replaceMessage(catalog *errcode.Catalog, lang string, err *ResolveError) {
+	message := err.Message
+	err.Message = catalog.Message(lang, err.Code, message)
+
+	if r.verboseErrors && err.Message != message {
+		err.Detail = message
+	}
+}
+
+// errorMessages returns the catalog and language the error messages of the
+// request are replaced from: the language accepted by the request when
+// errors are localized, otherwise the default language of the masking
+// catalog
+func (r *Resolver) errorMessages(c echo.Context) (*errcode.Catalog, string) {
+	if r.errorCatalog != nil {
+		return r.errorCatalog, r.errorLanguage(c)
+	}
+
+	return r.errorMask, r.errorMask.Language("")
+}
+
+// errorLanguage returns the language of error messages for the request,
+// reporting it in the Content-Language header
+func (r *Resolver) errorLanguage(c echo.Context) string {
+	lang := r.errorCatalog.Language(c.Request().Header.Get("Accept-Language"))
+	c.Response().Header().Set("Content-Language", lang)
+
+	return lang
+}
+
+// withCode adds code to the extensions of errs
+func withCode(code errcode.Code, errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	for i := range errs {
+		errs[i].Extensions = map[string]interface{}{errcode.ExtensionKey: string(code)}
+	}
+
+	return errs
+}
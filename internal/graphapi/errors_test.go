@@ -0,0 +1,106 @@
+package graphapi_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestErrorCodes(t *testing.T) {
+	entities := func(typeName, id string) string {
+		return `{
+			"query": "query($representations:[_Any!]!){_entities(representations:$representations){__typename}}",
+			"variables": {"representations": [{"__typename": "` + typeName + `", "id": "` + id + `"}]}
+		}`
+	}
+
+	testCases := []struct {
+		TestName string
+		query    string
+		code     errcode.Code
+	}{
+		{
+			TestName: "syntax error",
+			query:    `{"query": "{ node(id: "}`,
+			code:     errcode.InvalidRequest,
+		},
+		{
+			TestName: "validation error",
+			query:    `{"query": "{ node(id: \"testsrv-123\") { missing } }"}`,
+			code:     errcode.InvalidRequest,
+		},
+		{
+			TestName: "invalid id",
+			query:    `{"query": "{ node(id: \"bad\") { id } }"}`,
+			code:     errcode.InvalidID,
+		},
+		{
+			TestName: "unknown prefix",
+			query:    `{"query": "{ node(id: \"unknown-123\") { id } }"}`,
+			code:     errcode.UnknownPrefix,
+		},
+		{
+			TestName: "unauthorized",
+			query:    `{"query": "{ node(id: \"testtkn-123\") { id } }"}`,
+			code:     errcode.Unauthorized,
+		},
+		{
+			TestName: "denied",
+			query:    `{"query": "{ node(id: \"testdny-123\") { id } }"}`,
+			code:     errcode.Denied,
+		},
+		{
+			TestName: "unknown entity prefix",
+			query:    entities("Node", "unknown-123"),
+			code:     errcode.UnknownPrefix,
+		},
+		{
+			TestName: "unauthorized entity",
+			query:    entities("Node", "testtkn-123"),
+			code:     errcode.Unauthorized,
+		},
+		{
+			TestName: "unknown entity interface",
+			query:    entities("Missing", "testsrv-123"),
+			code:     errcode.UnknownInterface,
+		},
+		{
+			TestName: "entity not implementing interface",
+			query:    entities("Actor", "testsrv-123"),
+			code:     errcode.TypeInterfaceMismatch,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			resp, err := testQuery(validTestSchema, tt.query,
+				graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testtkn"}),
+				graphapi.WithPolicy(&prefixPolicy{prefix: "testdny"}),
+			)
+			require.NoError(t, err)
+			require.Len(t, resp.Errors, 1)
+
+			assert.Equal(t, map[string]interface{}{"code": string(tt.code)}, resp.Errors[0].Extensions, resp.Errors[0].Message)
+		})
+	}
+}
+
+func TestGetNodeErrorCodes(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema,
+		graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testtkn"}),
+	)
+	require.NoError(t, err)
+
+	_, err = r.GetNode(context.Background(), "unknown-123")
+	assert.ErrorIs(t, err, graphapi.ErrUnknownPrefix)
+	assert.Equal(t, errcode.UnknownPrefix, errcode.Of(err))
+
+	_, err = r.GetNode(context.Background(), "testtkn-123")
+	assert.Equal(t, errcode.Unauthorized, errcode.Of(err))
+}
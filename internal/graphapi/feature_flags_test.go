@@ -0,0 +1,83 @@
+package graphapi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/featureflags"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	flags := map[string]featureflags.StaticFlag{
+		"prefix.testsrv": {Enabled: true, Environments: []string{"staging"}},
+	}
+
+	testCases := []struct {
+		TestName    string
+		environment string
+		query       string
+		response    string
+		errorMsg    string
+	}{
+		{
+			TestName:    "enabled node",
+			environment: "staging",
+			query:       `{"query": "{ node(id: \"testsrv-123\") { id } }"}`,
+			response:    `{"node":{"id":"testsrv-123"}}`,
+		},
+		{
+			TestName:    "disabled node",
+			environment: "production",
+			query:       `{"query": "{ node(id: \"testsrv-123\") { id } }"}`,
+			response:    `{"node":null}`,
+			errorMsg:    "invalid id; unknown prefix",
+		},
+		{
+			TestName:    "ungated node",
+			environment: "production",
+			query:       `{"query": "{ node(id: \"testusr-123\") { id } }"}`,
+			response:    `{"node":{"id":"testusr-123"}}`,
+		},
+		{
+			TestName:    "disabled entity",
+			environment: "production",
+			query: `{
+				"query": "query($representations:[_Any!]!){_entities(representations:$representations){...on Node{id}}}",
+				"variables": {"representations": [{ "__typename": "Node", "id": "testusr-123" }, { "__typename": "Node", "id": "testsrv-123" }]}
+			}`,
+			response: `{"_entities":[{"id":"testusr-123"},null]}`,
+			errorMsg: "testsrv is an unknown id prefix",
+		},
+		{
+			TestName:    "disabled prefix mapping",
+			environment: "production",
+			query:       `{"query": "{ prefixes { prefix } typePrefix(typename: \"Server\") }"}`,
+			response:    `{"prefixes":[{"prefix":"testtkn"},{"prefix":"testusr"}],"typePrefix":null}`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			cfg := featureflags.Config{Environment: tt.environment, Prefixes: []string{"testsrv"}, KeyPrefix: "prefix."}
+			gate := featureflags.NewGate(cfg, featureflags.NewStatic(flags), zap.NewNop().Sugar())
+
+			resp, err := testQuery(validTestSchema, tt.query, graphapi.WithFeatureFlags(gate))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tt.response, resp.Data)
+
+			if tt.errorMsg == "" {
+				assert.Empty(t, resp.Errors)
+
+				return
+			}
+
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tt.errorMsg, resp.Errors[0].Message)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package graphapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+// The fuzz targets run their seeds as part of go test. Inputs found while
+// fuzzing with go test -fuzz that fail are written to testdata/fuzz and
+// should be committed so they're run as regression tests from then on.
+
+func FuzzDecodePostData(f *testing.F) {
+	f.Add([]byte(`{"query":"{ node(id: \"testsrv-abc\") { id } }"}`))
+	f.Add([]byte(`{"query":"query($id: ID!) { node(id: $id) { id } }","operation":"","variables":{"id":"testsrv-abc"}}`))
+	f.Add([]byte(`{"query":1}`))
+	f.Add([]byte(`{"variables":[]}`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`{`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, strict := range []bool{false, true} {
+			p, err := decodePostData(bytes.NewReader(data), strict)
+			if err != nil {
+				continue
+			}
+
+			putPostData(p)
+		}
+	})
+}
+
+func FuzzGraphHandler(f *testing.F) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	f.Add(`{"query":"{ node(id: \"testsrv-abc\") { __typename id } }"}`)
+	f.Add(`{"query":"query($id: ID!) { node(id: $id) { id } }","variables":{"id":"testsrv-"}}`)
+	f.Add(`{"query":"query($r:[_Any!]!){_entities(representations:$r){...on Server{id}}}","variables":{"r":[{"__typename":"Node","id":"testsrv-abc"}]}}`)
+	f.Add(`{"query":"query($r:[_Any!]!){_entities(representations:$r){__typename}}","variables":{"r":[{"__typename":1},{"id":"unknown-abc"},null,"testsrv-abc"]}}`)
+	f.Add(`{"query":"{ _entities(representations: [{__typename: \"Server\", id: 1}]) { __typename } }"}`)
+	f.Add(`{"query":"{ node(id: \"-\") { id } }"}`)
+
+	f.Fuzz(func(t *testing.T, body string) {
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK && rec.Code != http.StatusInternalServerError {
+			t.Fatalf("unexpected status %d for %q", rec.Code, body)
+		}
+	})
+}
+
+func FuzzParseID(f *testing.F) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, seed := range []string{"testsrv-abc", "testsrv-", "testsrv--", "-abc", "unknown-abc", "TESTSRV-abc", "testsrv-\x00", ""} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		id, err := r.parseID(raw)
+
+		// the fast path must only accept ids gidx accepts
+		if _, gidxErr := gidx.Parse(raw); err == nil && gidxErr != nil {
+			t.Fatalf("parseID accepted %q rejected by gidx: %v", raw, gidxErr)
+		}
+
+		if err != nil {
+			return
+		}
+
+		node, err := r.GetNode(context.Background(), id)
+		if err == nil && node.GraphType.Name() != "Server" {
+			t.Fatalf("%q resolved to %s", raw, node.GraphType.Name())
+		}
+	})
+}
+
+func FuzzNewResolver(f *testing.F) {
+	f.Add(prefixTestSchema)
+	f.Add(wildcardTestSchema)
+	f.Add(`type A implements Node @prefixedID(prefix: "") { id: ID! } interface Node { id: ID! }`)
+	f.Add(`type A implements Node @prefixedID { id: ID! } interface Node { id: ID! }`)
+	f.Add(`type A implements B @prefixedID(prefix: "testabc") { id: ID! } interface B { id: ID! }`)
+	f.Add(`type A implements Node & Node @prefixedID(prefix: "testabc") { id: ID! }`)
+	f.Add(`type A {`)
+
+	f.Fuzz(func(t *testing.T, schema string) {
+		r, err := NewResolver(zap.NewNop().Sugar(), schema)
+		if err != nil {
+			return
+		}
+
+		if len(r.prefixMap) == 0 {
+			t.Fatal("resolver created without prefixes")
+		}
+
+		if r.SDLChecksum() == "" {
+			t.Fatal("empty sdl checksum")
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package graphapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEGraphQLResponse is the media type of graphql responses defined by the
+// GraphQL-over-HTTP spec. Clients accepting it get status codes that reflect
+// whether the request could be executed.
+const MIMEGraphQLResponse = "application/graphql-response+json"
+
+// decodeQueryParams reads a graphql request from the query, variables,
+// operationName and extensions query parameters of a GET request. The
+// returned postData must be released with putPostData.
+func decodeQueryParams(params url.Values) (*postData, error) {
+	p := postDataPool.Get().(*postData)
+
+	p.Query = params.Get("query")
+	p.OperationName = params.Get("operationName")
+
+	if raw := params.Get("variables"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &p.Variables); err != nil {
+			putPostData(p)
+
+			return nil, fmt.Errorf("%w: invalid variables: %s", ErrMalformedRequest, safeString(err.Error()))
+		}
+	}
+
+	if raw := params.Get("extensions"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &p.Extensions); err != nil {
+			putPostData(p)
+
+			return nil, fmt.Errorf("%w: invalid extensions: %s", ErrMalformedRequest, safeString(err.Error()))
+		}
+	}
+
+	return p, nil
+}
+
+// normalize checks the request has a query and merges the standard
+// operationName field into the operation field
+func (p *postData) normalize() error {
+	if p.OperationName != "" {
+		if p.Operation != "" && p.Operation != p.OperationName {
+			return fmt.Errorf("%w: operation and operationName differ", ErrMalformedRequest)
+		}
+
+		p.Operation, p.OperationName = p.OperationName, ""
+	}
+
+	if p.Query == "" {
+		return fmt.Errorf("%w: missing query", ErrMalformedRequest)
+	}
+
+	return nil
+}
+
+// responseMediaType returns the media type of the response to a request
+// accepting the media types in accept, or false when none of them can be
+// sent. Responses are sent as application/json unless the graphql response
+// media type is preferred.
+func responseMediaType(accept string) (string, bool) {
+	if accept == "" {
+		return echo.MIMEApplicationJSON, true
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		if q, ok := params["q"]; ok {
+			if weight, err := strconv.ParseFloat(q, 64); err != nil || weight == 0 {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case MIMEGraphQLResponse:
+			return MIMEGraphQLResponse, true
+		case echo.MIMEApplicationJSON, "application/*", "*/*":
+			return echo.MIMEApplicationJSON, true
+		}
+	}
+
+	return "", false
+}
+
+// resultStatus returns the status code of a response with result. With the
+// graphql response media type, requests that couldn't be executed, so have
+// no data, are sent with a 400 and denied requests with a 403; otherwise
+// responses are always sent with a 200.
+func resultStatus(mediaType string, result *graphql.Result, denied bool) int {
+	switch {
+	case mediaType != MIMEGraphQLResponse || result.Data != nil:
+		return http.StatusOK
+	case denied:
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,140 @@
+package graphapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestGraphQLOverHTTP(t *testing.T) {
+	get := func(params url.Values) *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/query?"+params.Encode(), nil)
+	}
+
+	post := func(body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+	}
+
+	testCases := []struct {
+		TestName            string
+		req                 *http.Request
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			TestName: "get",
+			req: get(url.Values{
+				"query":         {`query Lookup($id: ID!) { node(id: $id) { id } } query Other { node(id: "testusr-abc") { id } }`},
+				"operationName": {"Lookup"},
+				"variables":     {`{"id": "testsrv-abc"}`},
+			}),
+			expectedStatus:      http.StatusOK,
+			expectedContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			expectedBody:        `{"data":{"node":{"id":"testsrv-abc"}}}`,
+		},
+		{
+			TestName:            "post with operationName",
+			req:                 post(`{"query": "query Lookup { node(id: \"testsrv-abc\") { id } } query Other { node(id: \"testusr-abc\") { id } }", "operationName": "Other"}`),
+			expectedStatus:      http.StatusOK,
+			expectedContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			expectedBody:        `{"data":{"node":{"id":"testusr-abc"}}}`,
+		},
+		{
+			TestName:       "operation and operationName differ",
+			req:            post(`{"query": "{ node(id: \"testsrv-abc\") { id } }", "operation": "A", "operationName": "B"}`),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"data":null,"errors":[{"message":"malformed request: operation and operationName differ","locations":[],"extensions":{"code":"invalid_request"}}]}`,
+		},
+		{
+			TestName:       "malformed json",
+			req:            post(`{"query": `),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"data":null,"errors":[{"message":"malformed request: invalid json: unexpected end of JSON input","locations":[],"extensions":{"code":"invalid_request"}}]}`,
+		},
+		{
+			TestName:       "malformed variables",
+			req:            get(url.Values{"query": {`{ node(id: "testsrv-abc") { id } }`}, "variables": {`{`}}),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"data":null,"errors":[{"message":"malformed request: invalid variables: unexpected end of JSON input","locations":[],"extensions":{"code":"invalid_request"}}]}`,
+		},
+		{
+			TestName:       "missing query",
+			req:            get(url.Values{}),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"data":null,"errors":[{"message":"malformed request: missing query","locations":[],"extensions":{"code":"invalid_request"}}]}`,
+		},
+		{
+			TestName:            "graphql response",
+			req:                 post(`{"query": "{ node(id: \"testsrv-abc\") { id } }"}`),
+			accept:              "application/graphql-response+json, application/json;q=0.9",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: graphapi.MIMEGraphQLResponse + "; charset=UTF-8",
+			expectedBody:        `{"data":{"node":{"id":"testsrv-abc"}}}`,
+		},
+		{
+			TestName:            "graphql response with field errors",
+			req:                 post(`{"query": "{ node(id: \"unknown-abc\") { id } }"}`),
+			accept:              graphapi.MIMEGraphQLResponse,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: graphapi.MIMEGraphQLResponse + "; charset=UTF-8",
+		},
+		{
+			TestName:            "graphql response with request errors",
+			req:                 post(`{"query": "{ nodeee(id: \"testsrv-abc\") { id } }"}`),
+			accept:              graphapi.MIMEGraphQLResponse,
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: graphapi.MIMEGraphQLResponse + "; charset=UTF-8",
+		},
+		{
+			TestName:            "request errors",
+			req:                 post(`{"query": "{ nodeee(id: \"testsrv-abc\") { id } }"}`),
+			accept:              "application/json",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: echo.MIMEApplicationJSONCharsetUTF8,
+		},
+		{
+			TestName:       "not acceptable",
+			req:            post(`{"query": "{ node(id: \"testsrv-abc\") { id } }"}`),
+			accept:         "text/html, application/json;q=0",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+			require.NoError(t, err)
+
+			e := echo.New()
+			r.Routes(e.Group(""))
+
+			if tt.accept != "" {
+				tt.req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, tt.req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if tt.expectedContentType != "" {
+				assert.Equal(t, tt.expectedContentType, rec.Header().Get(echo.HeaderContentType))
+			}
+
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
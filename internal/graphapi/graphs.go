@@ -0,0 +1,138 @@
+package graphapi
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultGraphHeader is the default header selecting the graph of a request
+const DefaultGraphHeader = "X-Graph-Tenant"
+
+// ErrUnknownGraph is returned when a graph isn't served
+var ErrUnknownGraph = errors.New("unknown graph")
+
+// GraphSelector configures how the graph serving a request is selected
+type GraphSelector struct {
+	// Header is the request header naming the graph
+	Header string
+	// Claim is the jwt claim naming the graph. When set the header is
+	// ignored, so callers can only use the graph their token is issued for.
+	Claim string
+}
+
+// Graphs serves several isolated graphs from one process, each with its own
+// schema and prefixes, selecting the graph of each request by a header or jwt
+// claim. Requests that don't select a graph are served by the default graph.
+// Each graph is served by its own Handler, so graphs are replaced
+// independently. Graphs is safe for concurrent use.
+type Graphs struct {
+	selector     GraphSelector
+	defaultGraph string
+
+	mu     sync.RWMutex
+	graphs map[string]*Handler
+}
+
+// NewGraphs returns Graphs serving h as the graph named defaultGraph
+func NewGraphs(selector GraphSelector, defaultGraph string, h *Handler) *Graphs {
+	return &Graphs{
+		selector:     selector,
+		defaultGraph: defaultGraph,
+		graphs:       map[string]*Handler{defaultGraph: h},
+	}
+}
+
+// Set serves h as the graph called name, replacing any graph of that name
+func (g *Graphs) Set(name string, h *Handler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.graphs[name] = h
+}
+
+// Graph returns the handler of the graph called name
+func (g *Graphs) Graph(name string) (*Handler, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	h, ok := g.graphs[name]
+
+	return h, ok
+}
+
+// Names returns the names of the graphs served, sorted
+func (g *Graphs) Names() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	names := make([]string, 0, len(g.graphs))
+	for name := range g.graphs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Reload replaces the schema of the graph called name, keeping its options.
+// Invalid schemas are rejected and the graph keeps being served unchanged.
+func (g *Graphs) Reload(name, rawSchema string) error {
+	h, ok := g.Graph(name)
+	if !ok {
+		return ErrUnknownGraph
+	}
+
+	return h.Reload(rawSchema)
+}
+
+// Routes adds the resolver routes to e, serving each request with the graph
+// it selects. The middleware of the default graph is used for every graph,
+// so the jwt claim selecting a graph is validated before it's used.
+func (g *Graphs) Routes(e *echo.Group) {
+	h, _ := g.Graph(g.defaultGraph)
+
+	h.Resolver().routes(e, g.resolver)
+}
+
+// resolver returns the resolver of the graph selected by the request
+func (g *Graphs) resolver(c echo.Context) (*Resolver, error) {
+	name := g.selectedGraph(c)
+	if name == "" {
+		name = g.defaultGraph
+	}
+
+	h, ok := g.Graph(name)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusNotFound, ErrUnknownGraph.Error())
+	}
+
+	return h.serving(c), nil
+}
+
+// selectedGraph returns the name of the graph the request selects, or an
+// empty string when it doesn't select one
+func (g *Graphs) selectedGraph(c echo.Context) string {
+	if g.selector.Claim == "" {
+		return c.Request().Header.Get(g.selector.Header)
+	}
+
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+
+	name, _ := claims[g.selector.Claim].(string)
+
+	return name
+}
@@ -0,0 +1,141 @@
+package graphapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+const widgetGraphSchema = `directive @prefixedID(prefix: String!) on OBJECT
+
+type Widget implements Node @key(fields: "id") @prefixedID(prefix: "testwdg") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+// newTestGraphs returns graphs serving validTestSchema as the default graph
+// and widgetGraphSchema as the widgets graph
+func newTestGraphs(t *testing.T, selector graphapi.GraphSelector, opts ...graphapi.Option) *graphapi.Graphs {
+	t.Helper()
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, opts...)
+	require.NoError(t, err)
+
+	widgets, err := graphapi.NewResolver(zap.NewNop().Sugar(), widgetGraphSchema, opts...)
+	require.NoError(t, err)
+
+	graphs := graphapi.NewGraphs(selector, "default", graphapi.NewHandler(r))
+	graphs.Set("widgets", graphapi.NewHandler(widgets))
+
+	return graphs
+}
+
+// resolveType returns the status and body of resolving the type of id
+func resolveType(e *echo.Echo, id string, modify func(*http.Request)) (int, string) {
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query":"{ node(id: \"`+id+`\") { __typename } }"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	modify(req)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec.Code, rec.Body.String()
+}
+
+func TestGraphsHeader(t *testing.T) {
+	graphs := newTestGraphs(t, graphapi.GraphSelector{Header: graphapi.DefaultGraphHeader})
+
+	e := echo.New()
+	graphs.Routes(e.Group(""))
+
+	selectGraph := func(name string) func(*http.Request) {
+		return func(req *http.Request) {
+			if name != "" {
+				req.Header.Set(graphapi.DefaultGraphHeader, name)
+			}
+		}
+	}
+
+	code, body := resolveType(e, "testusr-1", selectGraph(""))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"User"`)
+
+	code, body = resolveType(e, "testwdg-1", selectGraph("widgets"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"Widget"`)
+
+	// graphs are isolated, so their prefixes aren't known to each other
+	_, body = resolveType(e, "testusr-1", selectGraph("widgets"))
+	assert.Contains(t, body, "unknown_prefix")
+
+	code, _ = resolveType(e, "testusr-1", selectGraph("gadgets"))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	assert.Equal(t, []string{"default", "widgets"}, graphs.Names())
+}
+
+func TestGraphsClaim(t *testing.T) {
+	claims := jwt.MapClaims{}
+
+	graphs := newTestGraphs(t, graphapi.GraphSelector{Header: graphapi.DefaultGraphHeader, Claim: "graph"},
+		graphapi.WithMiddleware(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.Set("user", &jwt.Token{Claims: claims})
+
+				return next(c)
+			}
+		}),
+	)
+
+	e := echo.New()
+	graphs.Routes(e.Group(""))
+
+	// the header is ignored when graphs are selected by a claim
+	_, body := resolveType(e, "testusr-1", func(req *http.Request) {
+		req.Header.Set(graphapi.DefaultGraphHeader, "widgets")
+	})
+	assert.Contains(t, body, `"User"`)
+
+	claims["graph"] = "widgets"
+
+	_, body = resolveType(e, "testwdg-1", func(*http.Request) {})
+	assert.Contains(t, body, `"Widget"`)
+}
+
+func TestGraphsReload(t *testing.T) {
+	graphs := newTestGraphs(t, graphapi.GraphSelector{Header: graphapi.DefaultGraphHeader})
+
+	e := echo.New()
+	graphs.Routes(e.Group(""))
+
+	selectWidgets := func(req *http.Request) { req.Header.Set(graphapi.DefaultGraphHeader, "widgets") }
+
+	require.NoError(t, graphs.Reload("widgets", strings.Replace(widgetGraphSchema, "testwdg", "testgdg", 1)))
+
+	_, body := resolveType(e, "testgdg-1", selectWidgets)
+	assert.Contains(t, body, `"Widget"`)
+
+	// other graphs are unchanged
+	_, body = resolveType(e, "testusr-1", func(*http.Request) {})
+	assert.Contains(t, body, `"User"`)
+
+	assert.Error(t, graphs.Reload("widgets", "type Query {"))
+	assert.ErrorIs(t, graphs.Reload("gadgets", widgetGraphSchema), graphapi.ErrUnknownGraph)
+
+	_, body = resolveType(e, "testgdg-1", selectWidgets)
+	assert.Contains(t, body, `"Widget"`)
+}
@@ -0,0 +1,142 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ErrNoSchema is reported by the readiness check until a resolver is served
+var ErrNoSchema = errors.New("no schema is being served")
+
+// Handler serves the current Resolver and allows it to be replaced while
+// running, so the schema can change without restarting the server. Requests
+// are always served entirely by the resolver that was current when they
+// started.
+type Handler struct {
+	// current is the resolver being served. It's only read and replaced
+	// under mu, and replacements are serialized by swapMu so a reload always
+	// builds on the resolver it replaces, without blocking requests while
+	// the new resolver is built.
+	mu      sync.RWMutex
+	swapMu  sync.Mutex
+	current *Resolver
+
+	// canary is served to a percentage of clients during a rollout.
+	// Changes to it are serialized by canaryMu.
+	canary   atomic.Pointer[canary]
+	canaryMu sync.Mutex
+
+	// history is the schemas served, oldest first
+	history   []SchemaVersion
+	historyMu sync.Mutex
+}
+
+// NewHandler returns a Handler serving r
+func NewHandler(r *Resolver) *Handler {
+	h := &Handler{current: r}
+	h.recordVersion(r)
+
+	return h
+}
+
+// Resolver returns the resolver currently being served
+func (h *Handler) Resolver() *Resolver {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.current
+}
+
+// ReadinessCheck fails until the handler serves a resolver, whose schema has
+// been parsed and built
+func (h *Handler) ReadinessCheck(_ context.Context) error {
+	if h.Resolver() == nil {
+		return ErrNoSchema
+	}
+
+	return nil
+}
+
+// Swap replaces the resolver being served
+func (h *Handler) Swap(r *Resolver) {
+	h.swapMu.Lock()
+	prev := h.swap(r)
+	h.swapMu.Unlock()
+
+	invalidate(prev, r)
+}
+
+// swap replaces the resolver being served, returning the resolver it
+// replaced. swapMu must be held.
+func (h *Handler) swap(r *Resolver) *Resolver {
+	h.mu.Lock()
+	prev := h.current
+	h.current = r
+	h.mu.Unlock()
+
+	h.recordVersion(r)
+
+	return prev
+}
+
+// invalidate purges cached results with the cache invalidator of next when
+// it serves a different schema than prev. Failures are logged, the results
+// of the other replicas then expire with their ttl.
+func invalidate(prev, next *Resolver) {
+	if next.invalidator == nil || (prev != nil && prev.SDLChecksum() == next.SDLChecksum()) {
+		return
+	}
+
+	if err := next.invalidator.Purge(context.Background()); err != nil {
+		next.logger.Warnw("failed to purge cached results", "error", err)
+	}
+}
+
+// Reload replaces the schema being served with rawSchema, keeping the options
+// of the current resolver. Invalid schemas are rejected and the current
+// schema keeps being served. Requests already being served finish with the
+// resolver they started with. Concurrent reloads are applied one after the
+// other.
+func (h *Handler) Reload(rawSchema string) error {
+	h.swapMu.Lock()
+
+	r, err := h.Resolver().WithSchema(rawSchema)
+	if err != nil {
+		h.swapMu.Unlock()
+
+		return err
+	}
+
+	prev := h.swap(r)
+	h.swapMu.Unlock()
+
+	invalidate(prev, r)
+
+	return nil
+}
+
+// Routes adds the resolver routes to e. The middleware of the resolver
+// being served when the routes are added is used for every resolver.
+func (h *Handler) Routes(e *echo.Group) {
+	h.Resolver().routes(e, func(c echo.Context) (*Resolver, error) { return h.serving(c), nil })
+}
+
+// resolverFunc returns the resolver serving a request
+type resolverFunc func(c echo.Context) (*Resolver, error)
+
+// serve returns a handler serving requests with handler of the resolver
+// returned by current
+func (current resolverFunc) serve(handler func(*Resolver, echo.Context) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r, err := current(c)
+		if err != nil {
+			return err
+		}
+
+		return handler(r, c)
+	}
+}
@@ -0,0 +1,29 @@
+package graphapi_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestHandlerReadiness(t *testing.T) {
+	var empty graphapi.Handler
+
+	assert.ErrorIs(t, empty.ReadinessCheck(context.Background()), graphapi.ErrNoSchema)
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	h := graphapi.NewHandler(r)
+	assert.NoError(t, h.ReadinessCheck(context.Background()))
+
+	// rejected reloads keep serving the previous schema
+	assert.Error(t, h.Reload("type Query {"))
+	assert.NoError(t, h.ReadinessCheck(context.Background()))
+	assert.Equal(t, r, h.Resolver())
+}
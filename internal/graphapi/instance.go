@@ -0,0 +1,54 @@
+package graphapi
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/labstack/echo/v4"
+)
+
+// Response headers identifying the replica and schema serving a request
+const (
+	HeaderInstanceID     = "X-Node-Resolver-Instance"
+	HeaderSchemaChecksum = "X-Node-Resolver-Schema-Checksum"
+)
+
+// instanceExtension is the graphql response extension holding the instance
+// id and schema checksum
+const instanceExtension = "nodeResolver"
+
+// WithInstanceID reports id and the schema checksum in the headers of every
+// response and in the extensions of graphql responses. With many replicas
+// behind a load balancer, sampling responses shows which replicas serve a
+// different schema version.
+func WithInstanceID(id string) Option {
+	return func(r *Resolver) {
+		r.instanceID = id
+	}
+}
+
+// setInstanceHeaders sets the instance headers on the response when
+// instance reporting is enabled
+func (r *Resolver) setInstanceHeaders(c echo.Context) {
+	if r.instanceID == "" {
+		return
+	}
+
+	c.Response().Header().Set(HeaderInstanceID, r.instanceID)
+	c.Response().Header().Set(HeaderSchemaChecksum, r.SDLChecksum())
+}
+
+// addInstanceExtension adds the instance extension to result when instance
+// reporting is enabled
+func (r *Resolver) addInstanceExtension(result *graphql.Result) {
+	if r.instanceID == "" {
+		return
+	}
+
+	if result.Extensions == nil {
+		result.Extensions = map[string]interface{}{}
+	}
+
+	result.Extensions[instanceExtension] = map[string]interface{}{
+		"instanceID":     r.instanceID,
+		"schemaChecksum": r.SDLChecksum(),
+	}
+}
@@ -0,0 +1,64 @@
+package graphapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestInstanceID(t *testing.T) {
+	serve := func(r *graphapi.Resolver, method, target, body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		r.Routes(e.Group(""))
+
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	query := `{"query":"{ node(id: \"testsrv-abc\") { id } }"}`
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithInstanceID("replica-1"))
+	require.NoError(t, err)
+
+	rec := serve(r, http.MethodPost, "/query", query)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "replica-1", rec.Header().Get(graphapi.HeaderInstanceID))
+	assert.Equal(t, r.SDLChecksum(), rec.Header().Get(graphapi.HeaderSchemaChecksum))
+
+	var resp struct {
+		Extensions map[string]map[string]string `json:"extensions"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]string{"instanceID": "replica-1", "schemaChecksum": r.SDLChecksum()}, resp.Extensions["nodeResolver"])
+
+	for _, rec := range []*httptest.ResponseRecorder{
+		serve(r, http.MethodGet, "/api/v1/resolve?id=testsrv-abc", ""),
+		serve(r, http.MethodPost, "/api/v1/resolve", "invalid"),
+	} {
+		assert.Equal(t, "replica-1", rec.Header().Get(graphapi.HeaderInstanceID))
+		assert.Equal(t, r.SDLChecksum(), rec.Header().Get(graphapi.HeaderSchemaChecksum))
+	}
+
+	// nothing is reported by default
+	r, err = graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	rec = serve(r, http.MethodPost, "/query", query)
+	assert.Empty(t, rec.Header().Get(graphapi.HeaderInstanceID))
+	assert.NotContains(t, rec.Body.String(), "extensions")
+}
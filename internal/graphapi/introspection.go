@@ -0,0 +1,171 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+var (
+	// ErrIntrospectionDisabled is reported for requests selecting __schema
+	// or __type when introspection is disabled for the client
+	ErrIntrospectionDisabled = errors.New("introspection is disabled")
+	// ErrInvalidCIDR is returned when parsing an invalid allowed network
+	ErrInvalidCIDR = errors.New("invalid cidr")
+)
+
+// WithIntrospectionDisabled rejects graphql requests selecting __schema or
+// __type, except from clients whose address, as reported by echo's RealIP,
+// is in one of allowed. __typename is always allowed.
+func WithIntrospectionDisabled(allowed []*net.IPNet) Option {
+	return func(r *Resolver) {
+		r.introspectionDisabled = true
+		r.introspectionAllowed = allowed
+	}
+}
+
+// ParseCIDRs parses networks in CIDR notation, such as 10.0.0.0/8
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCIDR, cidr)
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+type introspectionKey struct{}
+
+// withIntrospection returns ctx recording whether the client at ip may
+// introspect the schema, when introspection is disabled
+func (r *Resolver) withIntrospection(ctx context.Context, ip string) context.Context {
+	if !r.introspectionDisabled {
+		return ctx
+	}
+
+	allowed := false
+
+	if addr := net.ParseIP(ip); addr != nil {
+		for _, n := range r.introspectionAllowed {
+			if n.Contains(addr) {
+				allowed = true
+				break
+			}
+		}
+	}
+
+	return context.WithValue(ctx, introspectionKey{}, allowed)
+}
+
+// introspectionAllowed reports whether the request of ctx may introspect the
+// schema
+func introspectionAllowed(ctx context.Context) bool {
+	allowed, ok := ctx.Value(introspectionKey{}).(bool)
+
+	return !ok || allowed
+}
+
+// selectsIntrospection reports whether the operation name of doc selects
+// __schema or __type
+func selectsIntrospection(doc *ast.Document, name string) bool {
+	fields, _ := rootFields(doc, name)
+
+	for field := range fields {
+		if field != "__typename" && strings.HasPrefix(field, "__") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// introspectionDisabledResult is the result of requests rejected since
+// introspection is disabled
+func introspectionDisabledResult() *graphql.Result {
+	return &graphql.Result{Errors: withCode(errcode.Denied, gqlerrors.FormatErrors(ErrIntrospectionDisabled))}
+}
+
+// graphql-go builds the introspection lists of schema types, interface fields
+// and interface implementations from maps, so their order changes between
+// processes and schema diffing tools see spurious changes. Its introspection
+// types are package level, so their resolvers are wrapped once to sort the
+// lists by name.
+func init() {
+	sortIntrospectionField(graphql.SchemaType, "types")
+	sortIntrospectionField(graphql.TypeType, "fields")
+	sortIntrospectionField(graphql.TypeType, "possibleTypes")
+}
+
+// sortIntrospectionField replaces the resolver of the named field of obj with
+// one returning the same list sorted by name
+func sortIntrospectionField(obj *graphql.Object, name string) {
+	def := obj.Fields()[name]
+
+	args := graphql.FieldConfigArgument{}
+	for _, arg := range def.Args {
+		args[arg.Name()] = &graphql.ArgumentConfig{
+			Type:         arg.Type,
+			DefaultValue: arg.DefaultValue,
+			Description:  arg.Description(),
+		}
+	}
+
+	resolve := def.Resolve
+
+	obj.AddFieldConfig(name, &graphql.Field{
+		Type:              def.Type,
+		Args:              args,
+		Description:       def.Description,
+		DeprecationReason: def.DeprecationReason,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			v, err := resolve(p)
+			if err != nil {
+				return v, err
+			}
+
+			return sortedByName(v), nil
+		},
+	})
+
+	// define the fields again now, rather than lazily while serving requests
+	obj.Fields()
+}
+
+// sortedByName returns a copy of the list of types or fields v sorted by
+// name. The lists may belong to the schema, so they're never sorted in place.
+func sortedByName(v interface{}) interface{} {
+	switch list := v.(type) {
+	case []graphql.Type:
+		sorted := append([]graphql.Type(nil), list...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+
+		return sorted
+	case []*graphql.Object:
+		sorted := append([]*graphql.Object(nil), list...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+
+		return sorted
+	case []*graphql.FieldDefinition:
+		sorted := append([]*graphql.FieldDefinition(nil), list...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+		return sorted
+	default:
+		return v
+	}
+}
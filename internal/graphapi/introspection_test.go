@@ -0,0 +1,113 @@
+package graphapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql/testutil"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+const introspectionQuery = `{"query":"{ __schema { types { name fields { name } possibleTypes { name } } } }"}`
+
+func TestDeterministicIntrospection(t *testing.T) {
+	first, err := testQuery(validTestSchema, introspectionQuery)
+	require.NoError(t, err)
+	require.Empty(t, first.Errors)
+
+	// map iteration order is random, so a few resolvers are enough to see
+	// differences if anything isn't sorted
+	for i := 0; i < 20; i++ {
+		resp, err := testQuery(validTestSchema, introspectionQuery)
+		require.NoError(t, err)
+
+		assert.Equal(t, first.Data, resp.Data)
+	}
+
+	resp, err := testQuery(validTestSchema, `{"query":"{ __type(name: \"Node\") { possibleTypes { name } } }"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"__type":{"possibleTypes":[{"name":"Server"},{"name":"Token"},{"name":"User"}]}}`, resp.Data)
+
+	resp, err = testQuery(validTestSchema, `{"query":"{ __schema { types { name } } }"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"__schema":{"types":[
+		{"name":"Actor"},{"name":"Boolean"},{"name":"ID"},{"name":"Node"},{"name":"PrefixMapping"},{"name":"Query"},{"name":"Server"},
+		{"name":"String"},{"name":"Token"},{"name":"User"},{"name":"_Any"},{"name":"_Entities"},{"name":"_Service"},
+		{"name":"__Directive"},{"name":"__DirectiveLocation"},{"name":"__EnumValue"},{"name":"__Field"},
+		{"name":"__InputValue"},{"name":"__Schema"},{"name":"__Type"},{"name":"__TypeKind"}
+	]}}`, resp.Data)
+}
+
+func TestFullIntrospection(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	body, err := json.Marshal(map[string]string{"query": testutil.IntrospectionQuery})
+	require.NoError(t, err)
+
+	for _, schema := range []string{validTestSchema, fmt.Sprintf(sourcedTestSchema, srv.URL)} {
+		resp, err := testQuery(schema, string(body), graphapi.WithSourceLookups(nil))
+		require.NoError(t, err)
+		require.Empty(t, resp.Errors)
+		assert.Contains(t, resp.Data, `"queryType":{"name":"Query"}`)
+	}
+}
+
+func TestIntrospectionDisabled(t *testing.T) {
+	allowed, err := graphapi.ParseCIDRs([]string{"10.0.0.0/8", " fd00::/8"})
+	require.NoError(t, err)
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema,
+		graphapi.WithIntrospectionDisabled(allowed), graphapi.WithResponseCache(cache.New(100, time.Minute)))
+	require.NoError(t, err)
+
+	query := func(remote, body string) *queryResponse {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		req.RemoteAddr = remote
+
+		rec := httptest.NewRecorder()
+		require.NoError(t, r.GraphHandler(echo.New().NewContext(req, rec)))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp queryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+		resp.Data = string(resp.RawData)
+
+		return &resp
+	}
+
+	// allowed clients introspect first, so a cached response would be
+	// served to the others
+	for _, remote := range []string{"10.1.2.3:1234", "[fd00::1]:1234"} {
+		resp := query(remote, introspectionQuery)
+		assert.Empty(t, resp.Errors, remote)
+	}
+
+	for _, body := range []string{introspectionQuery, `{"query":"{ u: node(id: \"testusr-abc\") { id } t: __type(name: \"Node\") { name } }"}`} {
+		resp := query("192.0.2.1:1234", body)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, graphapi.ErrIntrospectionDisabled.Error(), resp.Errors[0].Message)
+		assert.Equal(t, string(errcode.Denied), resp.Errors[0].Extensions[errcode.ExtensionKey])
+		assert.Equal(t, "null", resp.Data)
+	}
+
+	resp := query("192.0.2.1:1234", `{"query":"{ node(id: \"testusr-abc\") { __typename id } }"}`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"node":{"__typename":"User","id":"testusr-abc"}}`, resp.Data)
+
+	_, err = graphapi.ParseCIDRs([]string{"10.0.0.0"})
+	assert.ErrorIs(t, err, graphapi.ErrInvalidCIDR)
+}
@@ -0,0 +1,76 @@
+package graphapi
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// itemErrors collects the errors of the items of list fields that failed to
+// resolve, such as _entities representations and nodes ids. graphql-go can
+// only fail a single item of a list by panicking while resolving its type,
+// so failed items are returned as null instead and their errors are added
+// to the result once the request is executed.
+type itemErrors struct {
+	mu   sync.Mutex
+	errs []gqlerrors.FormattedError
+}
+
+type itemErrorsKey struct{}
+
+// withItemErrors returns ctx with a collector for the item errors of a
+// request
+func withItemErrors(ctx context.Context) (context.Context, *itemErrors) {
+	errs := &itemErrors{}
+
+	return context.WithValue(ctx, itemErrorsKey{}, errs), errs
+}
+
+func itemErrorsFrom(ctx context.Context) *itemErrors {
+	errs, _ := ctx.Value(itemErrorsKey{}).(*itemErrors)
+
+	return errs
+}
+
+// add records errs, the errors of the items of the list field resolved by
+// p, with nil for the items that resolved. The errors are added in the order
+// of their items and reported with the path of their item and the location
+// of the field, like graphql-go reports the errors of fields.
+func (c *itemErrors) add(p graphql.ResolveParams, errs []error) {
+	if c == nil {
+		return
+	}
+
+	path := p.Info.Path.AsArray()
+	nodes := gqlerrors.FieldASTsToNodeASTs(p.Info.FieldASTs)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		located := gqlerrors.NewLocatedError(err, nodes)
+		located.Path = append(append([]interface{}(nil), path...), i)
+
+		c.errs = append(c.errs, gqlerrors.FormatError(located))
+	}
+}
+
+// addTo appends the collected errors to the errors of result, ordered by
+// path. graphql-go resolves the fields of a query in no particular order, so
+// the errors of different fields are ordered by their paths rather than
+// the order they were collected in.
+func (c *itemErrors) addTo(result *graphql.Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sort.SliceStable(c.errs, func(i, j int) bool { return comparePaths(c.errs[i].Path, c.errs[j].Path) < 0 })
+
+	result.Errors = append(result.Errors, c.errs...)
+}
@@ -0,0 +1,99 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultLookupTimeoutFloor is the least time left before the request
+// deadline for a lookup to be started
+const DefaultLookupTimeoutFloor = 10 * time.Millisecond
+
+// ErrLookupTimeout is returned when a lookup, such as authorizing an id,
+// doesn't finish within its timeout
+var ErrLookupTimeout = errors.New("lookup timed out")
+
+type lookupDeadlineKey struct{}
+
+// WithRequestTimeout sets how long the lookups made by a graphql request may
+// take in total. Once the request deadline has passed the remaining lookups
+// fail with a timeout and the response is returned with the ids resolved so
+// far, rather than the request context being canceled, which would discard
+// them. A timeout of 0 only uses the deadline of the request context, if any.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(r *Resolver) {
+		r.requestTimeout = d
+	}
+}
+
+// WithLookupTimeouts bounds each lookup made while resolving ids, such as
+// authorizing an id, so one slow backend can't stall a whole batch. Each
+// lookup gets the time left until the request deadline, at most ceiling;
+// lookups aren't started with less than floor left and fail immediately.
+// Requests without a deadline give each lookup ceiling. A ceiling of 0
+// disables lookup timeouts.
+func WithLookupTimeouts(floor, ceiling time.Duration) Option {
+	return func(r *Resolver) {
+		r.lookupFloor = floor
+		r.lookupCeiling = ceiling
+	}
+}
+
+// lookupDeadline returns ctx carrying the deadline of the lookups of a
+// request starting now, when the request timeout is set
+func (r *Resolver) lookupDeadline(ctx context.Context) context.Context {
+	if r.requestTimeout <= 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, lookupDeadlineKey{}, time.Now().Add(r.requestTimeout))
+}
+
+// requestDeadline returns the earlier of the deadline of ctx and the deadline
+// of the lookups of the request
+func requestDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Deadline()
+
+	if lookup, set := ctx.Value(lookupDeadlineKey{}).(time.Time); set && (!ok || lookup.Before(deadline)) {
+		return lookup, true
+	}
+
+	return deadline, ok
+}
+
+// lookupContext returns the context for a single lookup made while serving a
+// request with ctx, which must be canceled when the lookup is done
+func (r *Resolver) lookupContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if r.lookupCeiling <= 0 {
+		return ctx, func() {}, nil
+	}
+
+	timeout := r.lookupCeiling
+
+	if deadline, ok := requestDeadline(ctx); ok {
+		left := time.Until(deadline)
+		if left < r.lookupFloor || left <= 0 {
+			return nil, nil, ErrLookupTimeout
+		}
+
+		if left < timeout {
+			timeout = left
+		}
+	}
+
+	lctx, cancel := context.WithTimeout(ctx, timeout)
+
+	return lctx, cancel, nil
+}
+
+// WithBreakerFallback resolves ids by their prefix alone while a circuit
+// breaker around the authorizer is open, rather than failing them with the
+// unavailable code. Ids are resolved without being authorized while the
+// fallback is used, so it should only be enabled when the type of an id
+// isn't sensitive.
+func WithBreakerFallback() Option {
+	return func(r *Resolver) {
+		r.breakerFallback = true
+	}
+}
@@ -0,0 +1,183 @@
+package graphapi_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/breaker"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// hangingAuthorizer never answers for ids with its prefix until the lookup is
+// canceled, and counts the lookups it's asked for
+type hangingAuthorizer struct {
+	prefix  string
+	lookups atomic.Int32
+}
+
+func (a *hangingAuthorizer) CanResolve(ctx context.Context, _ string, id gidx.PrefixedID) error {
+	a.lookups.Add(1)
+
+	if id.Prefix() != a.prefix {
+		return nil
+	}
+
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func TestLookupTimeouts(t *testing.T) {
+	entities := `{
+		"query": "query($representations:[_Any!]!){_entities(representations:$representations){... on Node { id }}}",
+		"variables": {"representations": [
+			{"__typename": "Node", "id": "testsrv-1"},
+			{"__typename": "Node", "id": "testtkn-1"},
+			{"__typename": "Node", "id": "testsrv-2"}
+		]}
+	}`
+
+	testCases := []struct {
+		TestName        string
+		query           string
+		opts            []graphapi.Option
+		expectedData    string
+		expectedErrors  int
+		expectedLookups int32
+	}{
+		{
+			TestName:        "node lookup timeout",
+			query:           `{"query": "{ node(id: \"testtkn-1\") { id } }"}`,
+			opts:            []graphapi.Option{graphapi.WithLookupTimeouts(0, 20*time.Millisecond)},
+			expectedData:    `{"node":null}`,
+			expectedErrors:  1,
+			expectedLookups: 1,
+		},
+		{
+			TestName:        "only slow entities time out",
+			query:           entities,
+			opts:            []graphapi.Option{graphapi.WithLookupTimeouts(0, 20*time.Millisecond)},
+			expectedData:    `{"_entities":[{"id":"testsrv-1"},null,{"id":"testsrv-2"}]}`,
+			expectedErrors:  1,
+			expectedLookups: 3,
+		},
+		{
+			TestName: "lookups are bounded by the request deadline",
+			query:    entities,
+			opts: []graphapi.Option{
+				graphapi.WithRequestTimeout(20 * time.Millisecond),
+				graphapi.WithLookupTimeouts(0, time.Minute),
+			},
+			expectedData:    `{"_entities":[{"id":"testsrv-1"},null,null]}`,
+			expectedErrors:  2,
+			expectedLookups: 2,
+		},
+		{
+			TestName: "lookups aren't started below the floor",
+			query:    `{"query": "{ node(id: \"testsrv-1\") { id } }"}`,
+			opts: []graphapi.Option{
+				graphapi.WithRequestTimeout(time.Second),
+				graphapi.WithLookupTimeouts(time.Minute, time.Minute),
+			},
+			expectedData:    `{"node":null}`,
+			expectedErrors:  1,
+			expectedLookups: 0,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			authorizer := &hangingAuthorizer{prefix: "testtkn"}
+
+			start := time.Now()
+
+			resp, err := testQuery(validTestSchema, tt.query, append(tt.opts, graphapi.WithAuthorizer(authorizer))...)
+			require.NoError(t, err)
+
+			assert.Less(t, time.Since(start), 5*time.Second)
+			assert.JSONEq(t, tt.expectedData, resp.Data)
+			assert.Equal(t, tt.expectedLookups, authorizer.lookups.Load())
+			require.Len(t, resp.Errors, tt.expectedErrors)
+
+			for _, e := range resp.Errors {
+				assert.Equal(t, "lookup timed out", e.Message)
+				assert.Equal(t, map[string]interface{}{"code": "timeout"}, e.Extensions)
+			}
+		})
+	}
+}
+
+// failingAuthorizer fails every check, like a backend that's down
+type failingAuthorizer struct {
+	lookups atomic.Int32
+}
+
+func (a *failingAuthorizer) CanResolve(_ context.Context, _ string, _ gidx.PrefixedID) error {
+	a.lookups.Add(1)
+
+	return errors.New("backend down")
+}
+
+func TestBreakerFallback(t *testing.T) {
+	query := `{"query": "{ node(id: \"testsrv-1\") { id } }"}`
+
+	testCases := []struct {
+		TestName     string
+		opts         []graphapi.Option
+		expectedData string
+		expectedCode string
+	}{
+		{
+			TestName:     "unavailable",
+			expectedData: `{"node":null}`,
+			expectedCode: "unavailable",
+		},
+		{
+			TestName:     "prefix-only fallback",
+			opts:         []graphapi.Option{graphapi.WithBreakerFallback()},
+			expectedData: `{"node":{"id":"testsrv-1"}}`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			backend := &failingAuthorizer{}
+			b := breaker.New("openfga", breaker.Config{FailureRate: 0.5, MinRequests: 2}, nil, zap.NewNop().Sugar())
+			opts := append(tt.opts, graphapi.WithAuthorizer(authz.WithBreaker(backend, b)))
+
+			// failing checks are denials until the breaker opens
+			for i := 0; i < 2; i++ {
+				resp, err := testQuery(validTestSchema, query, opts...)
+				require.NoError(t, err)
+				require.Len(t, resp.Errors, 1)
+				assert.Equal(t, "unauthorized", resp.Errors[0].Extensions["code"])
+			}
+
+			require.Equal(t, breaker.StateOpen, b.State())
+
+			resp, err := testQuery(validTestSchema, query, opts...)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tt.expectedData, resp.Data)
+			assert.Equal(t, int32(2), backend.lookups.Load())
+
+			if tt.expectedCode == "" {
+				assert.Empty(t, resp.Errors)
+
+				return
+			}
+
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tt.expectedCode, resp.Errors[0].Extensions["code"])
+		})
+	}
+}
@@ -1,10 +1,18 @@
 package graphapi
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
 	"github.com/graphql-go/graphql"
 	"go.infratographer.com/x/gidx"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/breaker"
+	"go.infratographer.com/node-resolver/internal/errcode"
 )
 
 var ErrUnknownPrefix = errors.New("invalid id; unknown prefix")
@@ -14,13 +22,199 @@ type Node struct {
 	GraphType *graphql.Object
 }
 
-func (r *Resolver) GetNode(id gidx.PrefixedID) (*Node, error) {
-	if resType, ok := r.prefixMap[id.Prefix()]; ok {
+// ResolveID resolves raw the way the node query does, validating and parsing
+// it first, for callers resolving ids in-process rather than over http
+func (r *Resolver) ResolveID(ctx context.Context, raw string) (*Node, error) {
+	id, err := r.parseID(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetNode(ctx, id)
+}
+
+// GetNode resolves id the way the node query does, failing with an error
+// carrying the code reported for it
+func (r *Resolver) GetNode(ctx context.Context, id gidx.PrefixedID) (*Node, error) {
+	node, err := r.resolveNode(ctx, auditOperationNode, id)
+	if err != nil {
+		return nil, codedError(err)
+	}
+
+	return node, nil
+}
+
+// nodesResolver resolves the nodes query. The nodes are returned in the
+// order of the ids, with ids that fail to resolve null in the list and
+// reported as errors for their index once the request is executed.
+func (r *Resolver) nodesResolver(p graphql.ResolveParams) (interface{}, error) {
+	ids := p.Args["ids"].([]interface{})
+	if len(ids) > MaxResolveIDs {
+		return nil, errcode.New(errcode.InvalidRequest, fmt.Errorf("at most %d ids can be resolved at once", MaxResolveIDs))
+	}
+
+	nodes := make([]interface{}, len(ids))
+	errs := make([]error, len(ids))
+
+	for i, raw := range ids {
+		id, err := r.parseID(raw.(string))
+		if err != nil {
+			r.recordResolution(p.Context, auditOperationNodes, raw.(string), "", err)
+
+			errs[i] = codedError(err)
+
+			continue
+		}
+
+		node, err := r.resolveNode(p.Context, auditOperationNodes, id)
+		if err != nil {
+			errs[i] = codedError(err)
+
+			continue
+		}
+
+		nodes[i] = node
+	}
+
+	itemErrorsFrom(p.Context).add(p, errs)
+
+	return nodes, nil
+}
+
+// resolveNode looks up the type of id and checks it's authorized, recording
+// the outcome as the given operation
+func (r *Resolver) resolveNode(ctx context.Context, operation string, id gidx.PrefixedID) (node *Node, err error) {
+	ctx, span := tracer().Start(ctx, "resolve node", trace.WithAttributes(attrOperation.String(operation), attrPrefix.String(prefixOf(id))))
+	defer func() { endResolutionSpan(span, err) }()
+
+	if resType := r.objectForRequest(ctx, prefixOf(id)); resType != nil {
+		span.SetAttributes(attrType.String(resType.Name()))
+
+		if err := r.authorizeID(ctx, id, false); err != nil {
+			r.recordResolution(ctx, operation, id.String(), resType.Name(), err)
+
+			return nil, err
+		}
+
+		r.recordResolution(ctx, operation, id.String(), resType.Name(), nil)
+		r.wantSource(ctx, resType, id)
+
 		return &Node{
 			ID:        id,
 			GraphType: resType,
 		}, nil
 	}
 
+	r.recordResolution(ctx, operation, id.String(), "", ErrUnknownPrefix)
+
 	return nil, ErrUnknownPrefix
 }
+
+// authorizeID fails ids failed by fault injection, otherwise it checks the
+// id is authorized and its node exists. entity is set for ids in _entities
+// batches.
+func (r *Resolver) authorizeID(ctx context.Context, id gidx.PrefixedID, entity bool) error {
+	if r.chaos != nil {
+		if err := r.chaos.FailID(prefixOf(id), entity); err != nil {
+			return err
+		}
+	}
+
+	if err := r.authorize(ctx, id); err != nil {
+		return err
+	}
+
+	return r.checkExists(ctx, id)
+}
+
+// checkExists checks the node of the id exists with the configured
+// NodeBackend, if any. It's only asked about authorized ids, so callers can't
+// learn which nodes exist from ids they may not resolve. Failures of the
+// backend itself fail the id rather than resolving it unchecked.
+func (r *Resolver) checkExists(ctx context.Context, id gidx.PrefixedID) error {
+	if r.nodeBackend == nil {
+		return nil
+	}
+
+	lctx, cancel, err := r.lookupContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer cancel()
+
+	exists, err := r.nodeBackend.Exists(lctx, id)
+
+	switch {
+	case err == nil && !exists:
+		return backend.ErrNotFound
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case lctx.Err() != nil:
+		r.logger.Warnw("node lookup timed out", "id", safeString(id.String()))
+
+		return ErrLookupTimeout
+	default:
+		r.logger.Errorw("node lookup failed", "id", safeString(id.String()), "error", err)
+
+		return err
+	}
+}
+
+// authorize checks the id with the configured Authorizer, if any. Failures of
+// the authorizer itself are logged and treated as a denial, except lookups
+// that time out or are canceled with the request, and lookups rejected by an
+// open circuit breaker, which are allowed when falling back to prefix-only
+// answers.
+func (r *Resolver) authorize(ctx context.Context, id gidx.PrefixedID) error {
+	if r.authorizer == nil {
+		return nil
+	}
+
+	subject := authz.Subject(ctx)
+
+	lctx, cancel, err := r.lookupContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer cancel()
+
+	if err := r.authorizer.CanResolve(lctx, subject, id); err != nil {
+		if errors.Is(err, authz.ErrUnauthorized) {
+			return authz.ErrUnauthorized
+		}
+
+		// the deletion is reported with the time the authorizer found
+		if errors.Is(err, authz.ErrDeleted) {
+			return err
+		}
+
+		// a lookup that was cut short isn't a denial
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if errors.Is(err, breaker.ErrOpen) {
+			if r.breakerFallback {
+				return nil
+			}
+
+			return err
+		}
+
+		if lctx.Err() != nil {
+			r.logger.Warnw("authorization check timed out", "subject", subject, "id", safeString(id.String()))
+
+			return ErrLookupTimeout
+		}
+
+		r.logger.Errorw("authorization check failed", "subject", subject, "id", safeString(id.String()), "error", err)
+
+		return authz.ErrUnauthorized
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package graphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// testNodeBackend knows testsrv-123, fails lookups of testsrv-broken and
+// records every id it's asked about
+type testNodeBackend struct {
+	asked []gidx.PrefixedID
+}
+
+func (b *testNodeBackend) Exists(_ context.Context, id gidx.PrefixedID) (bool, error) {
+	b.asked = append(b.asked, id)
+
+	switch id {
+	case "testsrv-123":
+		return true, nil
+	case "testsrv-broken":
+		return false, errors.New("backend unavailable")
+	default:
+		return false, nil
+	}
+}
+
+// denyAuthorizer denies testsrv-secret
+type denyAuthorizer struct{}
+
+func (denyAuthorizer) CanResolve(_ context.Context, _ string, id gidx.PrefixedID) error {
+	if id == "testsrv-secret" {
+		return authz.ErrUnauthorized
+	}
+
+	return nil
+}
+
+func TestNodeBackend(t *testing.T) {
+	testCases := []struct {
+		TestName   string
+		query      string
+		response   string
+		message    string
+		extensions map[string]interface{}
+	}{
+		{
+			TestName: "existing node",
+			query:    `{"query": "{ node(id: \"testsrv-123\") { id } }"}`,
+			response: `{"node":{"id":"testsrv-123"}}`,
+		},
+		{
+			TestName:   "missing node",
+			query:      `{"query": "{ node(id: \"testsrv-456\") { id } }"}`,
+			response:   `{"node":null}`,
+			message:    "node not found",
+			extensions: map[string]interface{}{"code": "not_found"},
+		},
+		{
+			TestName: "missing entity",
+			query: `{
+				"query": "query($representations:[_Any!]!){_entities(representations:$representations){...on Node{id}}}",
+				"variables": {"representations": [{ "__typename": "Node", "id": "testsrv-123" }, { "__typename": "Node", "id": "testsrv-456" }]}
+			}`,
+			response:   `{"_entities":[{"id":"testsrv-123"},null]}`,
+			message:    "node not found",
+			extensions: map[string]interface{}{"code": "not_found"},
+		},
+		{
+			TestName:   "backend failure",
+			query:      `{"query": "{ node(id: \"testsrv-broken\") { id } }"}`,
+			response:   `{"node":null}`,
+			message:    "backend unavailable",
+			extensions: map[string]interface{}{"code": "internal"},
+		},
+		{
+			TestName:   "unauthorized ids aren't looked up",
+			query:      `{"query": "{ node(id: \"testsrv-secret\") { id } }"}`,
+			response:   `{"node":null}`,
+			message:    authz.ErrUnauthorized.Error(),
+			extensions: map[string]interface{}{"code": "unauthorized"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			b := &testNodeBackend{}
+
+			resp, err := testQuery(validTestSchema, tt.query, graphapi.WithNodeBackend(b), graphapi.WithAuthorizer(denyAuthorizer{}))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tt.response, resp.Data)
+			assert.NotContains(t, b.asked, gidx.PrefixedID("testsrv-secret"))
+
+			if tt.message == "" {
+				assert.Empty(t, resp.Errors)
+				return
+			}
+
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tt.message, resp.Errors[0].Message)
+			assert.Equal(t, tt.extensions, resp.Errors[0].Extensions)
+		})
+	}
+}
+
+func TestNodeBackendWithoutAuthorizer(t *testing.T) {
+	query := `{
+		"query": "query($representations:[_Any!]!){_entities(representations:$representations){...on Node{id}}}",
+		"variables": {"representations": [{ "__typename": "Node", "id": "testsrv-123" }, { "__typename": "Node", "id": "testsrv-456" }]}
+	}`
+
+	resp, err := testQuery(validTestSchema, query, graphapi.WithNodeBackend(&testNodeBackend{}))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"_entities":[{"id":"testsrv-123"},null]}`, resp.Data)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, map[string]interface{}{"code": "not_found"}, resp.Errors[0].Extensions)
+}
+
+func TestNodeBackendResolveAPI(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithNodeBackend(&testNodeBackend{}))
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/resolve?id=testsrv-123&id=testsrv-456", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp graphapi.ResolveResponse
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 2)
+
+	assert.True(t, resp.Results[0].Resolved)
+
+	missing := resp.Results[1]
+	assert.False(t, missing.Resolved)
+	require.NotNil(t, missing.Error)
+	assert.Equal(t, "not_found", string(missing.Error.Code))
+}
@@ -0,0 +1,158 @@
+package graphapi
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// nodeQuery is a query of the shape gateways send for every id they resolve
+//
+//	{ node(id: "...") { __typename id } }
+//
+// with the id given inline or as a variable. Resolving the id is cheap
+// compared to executing the query with graphql-go, so queries of this shape
+// are answered directly instead, with the same response.
+type nodeQuery struct {
+	field *ast.Field
+	// key is the response key of the node field, and typenameKey and idKey
+	// those of the fields selected on the node, empty when not selected
+	key         string
+	typenameKey string
+	idKey       string
+	id          string
+}
+
+// nodeQueryOf returns the node query of the operation called name in doc,
+// or false when the operation has any other shape. Only plain fields are
+// accepted: fragments, directives and arguments other than the id of the
+// node take the full execution, and so do id variables idType can't coerce,
+// which graphql-go fails as a whole rather than as an error of the field.
+func nodeQueryOf(doc *ast.Document, name string, variables map[string]interface{}, idType *graphql.Scalar) (*nodeQuery, bool) {
+	op, _ := findOperation(doc, name)
+	if op == nil || op.Operation != ast.OperationTypeQuery || len(op.Directives) != 0 || op.SelectionSet == nil || len(op.SelectionSet.Selections) != 1 {
+		return nil, false
+	}
+
+	field, ok := op.SelectionSet.Selections[0].(*ast.Field)
+	if !ok || field.Name.Value != "node" || len(field.Directives) != 0 || len(field.Arguments) != 1 || field.SelectionSet == nil {
+		return nil, false
+	}
+
+	q := &nodeQuery{field: field, key: responseKey(field)}
+
+	if q.id, ok = nodeQueryID(field.Arguments[0], variables, idType); !ok {
+		return nil, false
+	}
+
+	for _, sel := range field.SelectionSet.Selections {
+		f, ok := sel.(*ast.Field)
+		if !ok || len(f.Directives) != 0 || len(f.Arguments) != 0 || f.SelectionSet != nil {
+			return nil, false
+		}
+
+		var key *string
+
+		switch f.Name.Value {
+		case "__typename":
+			key = &q.typenameKey
+		case "id":
+			key = &q.idKey
+		default:
+			return nil, false
+		}
+
+		// a field selected twice is merged by graphql-go
+		if *key != "" {
+			return nil, false
+		}
+
+		*key = responseKey(f)
+	}
+
+	return q, true
+}
+
+// nodeQueryID returns the id given by arg, inline or as a string variable
+// idType coerces
+func nodeQueryID(arg *ast.Argument, variables map[string]interface{}, idType *graphql.Scalar) (string, bool) {
+	if arg.Name.Value != "id" {
+		return "", false
+	}
+
+	switch v := arg.Value.(type) {
+	case *ast.StringValue:
+		return v.Value, true
+	case *ast.Variable:
+		if _, ok := variables[v.Name.Value].(string); !ok {
+			return "", false
+		}
+
+		id, ok := idType.ParseValue(variables[v.Name.Value]).(string)
+
+		return id, ok
+	default:
+		return "", false
+	}
+}
+
+// responseKey returns the key of field in the response, its alias if any
+func responseKey(field *ast.Field) string {
+	if field.Alias != nil {
+		return field.Alias.Value
+	}
+
+	return field.Name.Value
+}
+
+// executeNodeQuery answers q the way executing it with graphql-go does,
+// resolving its id like the node field
+func (r *Resolver) executeNodeQuery(ctx context.Context, q *nodeQuery) (result *graphql.Result) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			result = r.nodeQueryResult(q, nil, r.recovered(ctx, "query", rec))
+		}
+	}()
+
+	id, err := r.parseID(q.id)
+	if err != nil {
+		r.recordResolution(ctx, auditOperationNode, q.id, "", err)
+
+		return r.nodeQueryResult(q, nil, codedError(err))
+	}
+
+	node, err := r.GetNode(ctx, id)
+	if err != nil {
+		return r.nodeQueryResult(q, nil, err)
+	}
+
+	return r.nodeQueryResult(q, node, nil)
+}
+
+// nodeQueryResult returns the result of q for node, or for err when the
+// node failed to resolve
+func (r *Resolver) nodeQueryResult(q *nodeQuery, node *Node, err error) *graphql.Result {
+	if err != nil {
+		located := gqlerrors.NewLocatedError(err, []ast.Node{q.field})
+		located.Path = []interface{}{q.key}
+
+		return &graphql.Result{
+			Data:   map[string]interface{}{q.key: nil},
+			Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(located)},
+		}
+	}
+
+	fields := make(map[string]interface{}, 2)
+
+	if q.typenameKey != "" {
+		fields[q.typenameKey] = node.GraphType.Name()
+	}
+
+	if q.idKey != "" {
+		fields[q.idKey] = node.ID.String()
+	}
+
+	return &graphql.Result{Data: map[string]interface{}{q.key: fields}}
+}
@@ -0,0 +1,160 @@
+package graphapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+)
+
+type denyUsersAuthorizer struct{}
+
+func (denyUsersAuthorizer) CanResolve(_ context.Context, _ string, id gidx.PrefixedID) error {
+	if id.Prefix() == "testusr" {
+		return authz.ErrUnauthorized
+	}
+
+	return nil
+}
+
+func TestNodeQueryFastPath(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), servicesTestSchema, WithAuthorizer(denyUsersAuthorizer{}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+	}{
+		{name: "resolved", query: `{ node(id: "testsrv-abc") { __typename id } }`},
+		{name: "aliases", query: `query Lookup { n: node(id: "loadbal-abc") { t: __typename i: id } }`},
+		{name: "id only", query: `{ node(id: "testsrv-abc") { id } }`},
+		{name: "variable", query: `query($id: ID!) { node(id: $id) { __typename id } }`, variables: map[string]interface{}{"id": "testsrv-abc"}},
+		{name: "unauthorized", query: `{ node(id: "testusr-abc") { __typename id } }`},
+		{name: "invalid id", query: `{ node(id: "notanid") { __typename id } }`},
+		{name: "unknown prefix", query: `{ node(id: "unknown-abc") {
+			__typename
+			id
+		} }`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, errs := r.document(tt.query)
+			require.Empty(t, errs)
+
+			_, ok := nodeQueryOf(doc, "", tt.variables, graphql.ID)
+			require.True(t, ok)
+
+			// the fast path answers exactly like executing the query
+			expected := graphql.Execute(graphql.ExecuteParams{
+				Schema:  r.handlerSchema,
+				AST:     doc,
+				Args:    tt.variables,
+				Context: context.Background(),
+			})
+
+			result := r.execute(context.Background(), &postData{Query: tt.query, Variables: tt.variables})
+
+			expectedJSON, err := json.Marshal(expected)
+			require.NoError(t, err)
+
+			resultJSON, err := json.Marshal(result)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, string(expectedJSON), string(resultJSON))
+		})
+	}
+}
+
+func TestNodeQueryShapes(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), servicesTestSchema)
+	require.NoError(t, err)
+
+	// queries of any other shape are executed by graphql-go
+	for _, query := range []string{
+		`{ node(id: "testsrv-abc") { __typename id service { name } } }`,
+		`{ node(id: "testsrv-abc") { ... on Server { id } } }`,
+		`{ node(id: "testsrv-abc") { id @include(if: true) } }`,
+		`{ node(id: "testsrv-abc") { id id } }`,
+		`{ a: node(id: "testsrv-abc") { id } b: node(id: "testsrv-def") { id } }`,
+		`query($id: ID!) { node(id: $id) { id } }`,
+	} {
+		doc, errs := r.document(query)
+		require.Empty(t, errs, query)
+
+		_, ok := nodeQueryOf(doc, "", map[string]interface{}{"id": 1}, graphql.ID)
+		assert.False(t, ok, query)
+	}
+}
+
+func TestNodeQueryFastPathInvalidVariables(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), servicesTestSchema, WithPrefixedIDScalar())
+	require.NoError(t, err)
+
+	query := `query($id: PrefixedID!) { node(id: $id) { __typename id } }`
+
+	for _, variables := range []map[string]interface{}{
+		{"id": "notanid"},
+		{"id": ""},
+		{"id": " \t"},
+		{"id": 5},
+		{"id": nil},
+		{},
+	} {
+		doc, errs := r.document(query)
+		require.Empty(t, errs)
+
+		// invalid ids are answered exactly like executing the query, as
+		// errors of the field or of the whole request
+		expected := graphql.Execute(graphql.ExecuteParams{
+			Schema:  r.handlerSchema,
+			AST:     doc,
+			Args:    variables,
+			Context: context.Background(),
+		})
+
+		result := r.execute(context.Background(), &postData{Query: query, Variables: variables})
+
+		expectedJSON, err := json.Marshal(expected)
+		require.NoError(t, err)
+
+		resultJSON, err := json.Marshal(result)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, string(expectedJSON), string(resultJSON), variables)
+	}
+
+	// variables the id type can't coerce take the full execution
+	strict := graphql.NewScalar(graphql.ScalarConfig{
+		Name:      "StrictID",
+		Serialize: func(v interface{}) interface{} { return v },
+		ParseValue: func(v interface{}) interface{} {
+			if s, ok := v.(string); ok && strings.Contains(s, "-") {
+				return s
+			}
+
+			return nil
+		},
+		ParseLiteral: func(_ ast.Value) interface{} { return nil },
+	})
+
+	doc, errs := r.document(query)
+	require.Empty(t, errs)
+
+	_, ok := nodeQueryOf(doc, "", map[string]interface{}{"id": "notanid"}, strict)
+	assert.False(t, ok)
+
+	q, ok := nodeQueryOf(doc, "", map[string]interface{}{"id": "testsrv-abc"}, strict)
+	require.True(t, ok)
+	assert.Equal(t, "testsrv-abc", q.id)
+}
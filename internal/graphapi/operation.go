@@ -0,0 +1,211 @@
+package graphapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// OperationSelection controls which operation is executed when a document
+// has several operations and the request doesn't name one
+type OperationSelection string
+
+// Operation selection policies
+const (
+	// OperationSelectionError rejects the request, as the graphql spec requires
+	OperationSelectionError OperationSelection = "error"
+	// OperationSelectionFirst executes the first operation of the document,
+	// for legacy clients that send documents with several operations
+	OperationSelectionFirst OperationSelection = "first"
+)
+
+var (
+	// ErrInvalidOperationSelection is returned when parsing an unknown operation selection policy
+	ErrInvalidOperationSelection = errors.New("invalid operation selection")
+	// ErrOperationRequired is returned for documents with several operations
+	// when the request doesn't name the one to execute
+	ErrOperationRequired = errors.New("Must provide operation name if query contains multiple operations.") //nolint:stylecheck // the message graphql-go returns
+)
+
+// ParseOperationSelection returns the OperationSelection named s
+func ParseOperationSelection(s string) (OperationSelection, error) {
+	switch o := OperationSelection(s); o {
+	case OperationSelectionError, OperationSelectionFirst:
+		return o, nil
+	default:
+		return "", fmt.Errorf("%w: %q, expected error or first", ErrInvalidOperationSelection, s)
+	}
+}
+
+// WithOperationSelection sets which operation is executed when a document
+// has several operations and the request doesn't name one, defaulting to
+// OperationSelectionError
+func WithOperationSelection(o OperationSelection) Option {
+	return func(r *Resolver) {
+		r.operationSelection = o
+	}
+}
+
+// selectOperation returns the name of the operation to execute from the
+// names of the operations in a document, or an error when the requested
+// operation isn't in the document or none was requested from several. An
+// empty name selects the only operation.
+func (r *Resolver) selectOperation(names []string, requested string) (string, error) {
+	if requested != "" {
+		for _, name := range names {
+			if name == requested {
+				return requested, nil
+			}
+		}
+
+		return "", fmt.Errorf(`Unknown operation named "%s".`, safeString(requested))
+	}
+
+	if len(names) < 2 {
+		return "", nil
+	}
+
+	if r.operationSelection == OperationSelectionFirst {
+		return names[0], nil
+	}
+
+	return "", ErrOperationRequired
+}
+
+// operationNames returns the names of the operations in doc, in order
+func operationNames(doc *ast.Document) []string {
+	names := []string{}
+
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			name := ""
+			if op.Name != nil {
+				name = op.Name.Value
+			}
+
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Operation types of executed requests, used as the metrics label along
+// with the node, nodes, _entities, _service, typePrefix, prefixes and resolve
+// operations
+const (
+	// OperationIntrospection is an operation only selecting introspection
+	// fields
+	OperationIntrospection = "introspection"
+	// OperationMixed is an operation selecting several of the query fields
+	OperationMixed = "mixed"
+	// OperationInvalid is a request that failed validation or didn't
+	// select an operation
+	OperationInvalid = "invalid"
+)
+
+// Outcomes of executed requests, used as the metrics label
+const (
+	RequestSuccess = "success"
+	RequestError   = "error"
+)
+
+// operationType returns the operation type of the operation name in doc,
+// from the query fields it selects
+func operationType(doc *ast.Document, name string) string {
+	fields, ok := rootFields(doc, name)
+	if !ok {
+		return OperationInvalid
+	}
+
+	opType := ""
+
+	for field := range fields {
+		switch {
+		case field == "__typename":
+			// selected alongside any field, so it doesn't change the type
+			continue
+		case strings.HasPrefix(field, "__"):
+			field = OperationIntrospection
+		}
+
+		switch {
+		case opType == "":
+			opType = field
+		case opType != field:
+			return OperationMixed
+		}
+	}
+
+	if opType == "" {
+		// only __typename was selected
+		return OperationIntrospection
+	}
+
+	return opType
+}
+
+// rootFields returns the names of the query fields selected by the operation
+// name in doc, or false when doc has no such operation
+func rootFields(doc *ast.Document, name string) (map[string]bool, bool) {
+	op, fragments := findOperation(doc, name)
+	if op == nil {
+		return nil, false
+	}
+
+	fields := map[string]bool{}
+	collectRootFields(op.SelectionSet, fragments, map[string]bool{}, fields)
+
+	return fields, true
+}
+
+// findOperation returns the operation name of doc, or nil when doc has no
+// such operation, and the fragments of doc by name
+func findOperation(doc *ast.Document, name string) (*ast.OperationDefinition, map[string]*ast.FragmentDefinition) {
+	fragments := map[string]*ast.FragmentDefinition{}
+
+	var op *ast.OperationDefinition
+
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			defName := ""
+			if def.Name != nil {
+				defName = def.Name.Value
+			}
+
+			if op == nil && (name == "" || defName == name) {
+				op = def
+			}
+		}
+	}
+
+	return op, fragments
+}
+
+// collectRootFields adds the names of the fields selected by set to fields,
+// following fragments
+func collectRootFields(set *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, visited map[string]bool, fields map[string]bool) {
+	if set == nil {
+		return
+	}
+
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			fields[sel.Name.Value] = true
+		case *ast.InlineFragment:
+			collectRootFields(sel.SelectionSet, fragments, visited, fields)
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			if frag, ok := fragments[name]; ok && !visited[name] {
+				visited[name] = true
+				collectRootFields(frag.SelectionSet, fragments, visited, fields)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package graphapi_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+	"go.infratographer.com/node-resolver/internal/policy"
+)
+
+func TestParseOperationSelection(t *testing.T) {
+	for _, s := range []string{"error", "first"} {
+		o, err := graphapi.ParseOperationSelection(s)
+		require.NoError(t, err)
+		assert.Equal(t, graphapi.OperationSelection(s), o)
+	}
+
+	_, err := graphapi.ParseOperationSelection("last")
+	assert.ErrorIs(t, err, graphapi.ErrInvalidOperationSelection)
+}
+
+// operationPolicy records the operation of the last input it evaluated
+type operationPolicy struct {
+	operation string
+	fields    []string
+}
+
+func (p *operationPolicy) Evaluate(_ context.Context, input policy.Input) (policy.Decision, error) {
+	p.operation = input.Operation
+	p.fields = input.Fields
+
+	return policy.Decision{Allow: true}, nil
+}
+
+func TestOperationSelection(t *testing.T) {
+	document := `query A { node(id: \"testsrv-a\") { id } } query B { _entities(representations: []) { __typename } }`
+
+	testCases := []struct {
+		TestName          string
+		opts              []graphapi.Option
+		operation         string
+		expectedData      string
+		expectedError     string
+		expectedOperation string
+		expectedFields    []string
+	}{
+		{
+			TestName:       "multiple operations",
+			expectedData:   `null`,
+			expectedError:  "Must provide operation name if query contains multiple operations.",
+			expectedFields: []string{},
+		},
+		{
+			TestName:          "named operation",
+			operation:         "B",
+			expectedData:      `{"_entities":[]}`,
+			expectedOperation: "B",
+			expectedFields:    []string{"_entities"},
+		},
+		{
+			TestName:          "unknown operation",
+			operation:         "C",
+			expectedData:      `null`,
+			expectedError:     `Unknown operation named "C".`,
+			expectedOperation: "C",
+			expectedFields:    []string{},
+		},
+		{
+			TestName:          "first operation",
+			opts:              []graphapi.Option{graphapi.WithOperationSelection(graphapi.OperationSelectionFirst)},
+			expectedData:      `{"node":{"id":"testsrv-a"}}`,
+			expectedOperation: "A",
+			expectedFields:    []string{"node"},
+		},
+		{
+			TestName:          "named operation with first selection",
+			opts:              []graphapi.Option{graphapi.WithOperationSelection(graphapi.OperationSelectionFirst)},
+			operation:         "B",
+			expectedData:      `{"_entities":[]}`,
+			expectedOperation: "B",
+			expectedFields:    []string{"_entities"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			p := &operationPolicy{}
+
+			resp, err := testQuery(validTestSchema,
+				`{"query": "`+document+`", "operation": "`+tt.operation+`"}`,
+				append(tt.opts, graphapi.WithPolicy(p))...,
+			)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tt.expectedData, resp.Data)
+			assert.Equal(t, tt.expectedOperation, p.operation)
+			assert.Equal(t, tt.expectedFields, p.fields)
+
+			if tt.expectedError == "" {
+				assert.Empty(t, resp.Errors)
+
+				return
+			}
+
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tt.expectedError, resp.Errors[0].Message)
+			assert.Equal(t, map[string]interface{}{"code": "invalid_request"}, resp.Errors[0].Extensions)
+		})
+	}
+
+	// a single operation doesn't need to be named
+	resp, err := testQuery(validTestSchema, `{"query": "query A { node(id: \"testsrv-a\") { id } }"}`)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"node":{"id":"testsrv-a"}}`, resp.Data)
+}
+
+// requestCounter counts executed requests by operation type and outcome
+type requestCounter struct {
+	panicCounter
+
+	requests map[string]int
+}
+
+func (c *requestCounter) Request(operation, outcome string) {
+	c.requests[operation+"/"+outcome]++
+}
+
+func TestRequestMetrics(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		query    string
+		expected string
+	}{
+		{
+			TestName: "node",
+			query:    `{"query":"{ node(id: \"testsrv-abc\") { id } }"}`,
+			expected: "node/success",
+		},
+		{
+			TestName: "unknown prefix",
+			query:    `{"query":"{ node(id: \"unknown-abc\") { id } }"}`,
+			expected: "node/error",
+		},
+		{
+			TestName: "nodes in a fragment",
+			query:    `{"query":"query { ...n } fragment n on Query { __typename nodes(ids: [\"testsrv-abc\"]) { id } }"}`,
+			expected: "nodes/success",
+		},
+		{
+			TestName: "entities",
+			query:    `{"query":"query($r: [_Any!]!) { _entities(representations: $r) { __typename } }","variables":{"r":[{"__typename":"Node","id":"testsrv-abc"}]}}`,
+			expected: "_entities/success",
+		},
+		{
+			TestName: "introspection",
+			query:    `{"query":"{ __schema { queryType { name } } }"}`,
+			expected: graphapi.OperationIntrospection + "/success",
+		},
+		{
+			TestName: "mixed",
+			query:    `{"query":"{ _service { sdl } node(id: \"testsrv-abc\") { id } }"}`,
+			expected: graphapi.OperationMixed + "/success",
+		},
+		{
+			TestName: "selected operation",
+			query:    `{"query":"query a { _service { sdl } } query b { node(id: \"testsrv-abc\") { id } }","operationName":"b"}`,
+			expected: "node/success",
+		},
+		{
+			TestName: "invalid",
+			query:    `{"query":"{ missing }"}`,
+			expected: graphapi.OperationInvalid + "/error",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			counter := &requestCounter{panicCounter: panicCounter{}, requests: map[string]int{}}
+
+			_, err := testQuery(validTestSchema, tt.query, graphapi.WithMetrics(counter))
+			require.NoError(t, err)
+
+			assert.Equal(t, map[string]int{tt.expected: 1}, counter.requests)
+		})
+	}
+}
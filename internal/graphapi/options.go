@@ -0,0 +1,167 @@
+package graphapi
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/audit"
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/chaos"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/featureflags"
+	"go.infratographer.com/node-resolver/internal/metrics"
+	"go.infratographer.com/node-resolver/internal/policy"
+	"go.infratographer.com/node-resolver/internal/shed"
+)
+
+// Option configures optional behavior of the Resolver
+type Option func(*Resolver)
+
+// WithAuthorizer checks every node and entity with the given Authorizer before
+// it's resolved
+func WithAuthorizer(a authz.Authorizer) Option {
+	return func(r *Resolver) {
+		r.authorizer = a
+	}
+}
+
+// WithNodeBackend checks with the given NodeBackend that the node of every
+// authorized id exists before it's resolved, failing ids of missing nodes
+// with backend.ErrNotFound
+func WithNodeBackend(b backend.NodeBackend) Option {
+	return func(r *Resolver) {
+		r.nodeBackend = b
+	}
+}
+
+// WithAuditor records every node and entity resolution with the given Auditor
+func WithAuditor(a *audit.Auditor) Option {
+	return func(r *Resolver) {
+		r.auditor = a
+	}
+}
+
+// CacheInvalidator purges cached results, such as those of every replica
+type CacheInvalidator interface {
+	Purge(ctx context.Context) error
+}
+
+// WithCacheInvalidator purges cached results with i whenever a Handler
+// starts serving a different schema, so replicas don't keep serving results
+// of the previous prefix map
+func WithCacheInvalidator(i CacheInvalidator) Option {
+	return func(r *Resolver) {
+		r.invalidator = i
+	}
+}
+
+// WithPolicy evaluates every request with the given policy Evaluator before
+// it's executed. Denied requests return an error without being executed and
+// annotations from allowed requests are added to the response extensions.
+func WithPolicy(e policy.Evaluator) Option {
+	return func(r *Resolver) {
+		r.policy = e
+	}
+}
+
+// WithMiddleware adds middleware to the graphql routes, such as the
+// middleware populating the request context for authorization
+func WithMiddleware(mw ...echo.MiddlewareFunc) Option {
+	return func(r *Resolver) {
+		r.middleware = append(r.middleware, mw...)
+	}
+}
+
+// WithMetrics records resolutions and request durations to the given Sink
+func WithMetrics(s metrics.Sink) Option {
+	return func(r *Resolver) {
+		r.metrics = s
+	}
+}
+
+// WithEntityConcurrency sets how many chunks of large _entities batches are
+// authorized concurrently, across every request. Values below 2 process
+// batches serially.
+//
+// The workers are created with the option and shared by every Resolver built
+// with it, including those created by WithSchema, so replacing the schema
+// doesn't let in-flight and new requests use twice as many.
+func WithEntityConcurrency(workers int) Option {
+	return WithAdaptiveEntityConcurrency(workers, workers)
+}
+
+// WithAdaptiveEntityConcurrency authorizes chunks of large _entities batches
+// with between min and max concurrent workers, shared like those of
+// WithEntityConcurrency. Workers are added while chunks queue for one and
+// removed when chunks get slower, which means the authorizer is saturated.
+// A min below 2 processes batches serially.
+func WithAdaptiveEntityConcurrency(min, max int) Option {
+	var pool *entityPool
+	if min > 1 {
+		pool = newEntityPool(min, max)
+	}
+
+	return func(r *Resolver) {
+		r.entityPool = pool
+		r.entityPoolConfigured = true
+	}
+}
+
+// WithLoadShedder rejects requests with large bodies and large _entities
+// batches while s reports memory pressure
+func WithLoadShedder(s *shed.Shedder) Option {
+	return func(r *Resolver) {
+		r.shedder = s
+		r.middleware = append(r.middleware, s.Middleware())
+	}
+}
+
+// WithFeatureFlags only resolves ids with a prefix gated by g while its flag
+// is enabled for the subject of the request. Ids with a disabled prefix are
+// rejected as having an unknown prefix, so a dark-launched type can't be
+// discovered.
+func WithFeatureFlags(g *featureflags.Gate) Option {
+	return func(r *Resolver) {
+		r.featureFlags = g
+	}
+}
+
+// WithErrorCatalog replaces the messages of coded errors in graphql and
+// resolve api responses with those of c, in the language accepted by the
+// client
+func WithErrorCatalog(c *errcode.Catalog) Option {
+	return func(r *Resolver) {
+		r.errorCatalog = c
+	}
+}
+
+// WithErrorMasking replaces the messages of errors in graphql, resolve api
+// and grpc responses with the messages of their codes in c's default
+// language, so internal details such as id parse errors and schema type names
+// aren't exposed. Errors without a code are reported as internal errors.
+// Errors localized by WithErrorCatalog use the client's language instead.
+func WithErrorMasking(c *errcode.Catalog) Option {
+	return func(r *Resolver) {
+		r.errorMask = c
+	}
+}
+
+// WithVerboseErrors keeps the original message of masked or localized errors
+// in their detail, for development
+func WithVerboseErrors() Option {
+	return func(r *Resolver) {
+		r.verboseErrors = true
+	}
+}
+
+// WithFaultInjection injects the faults of i into requests: latency and
+// error responses in the middleware, and failures of ids as if their lookup
+// failed
+func WithFaultInjection(i *chaos.Injector) Option {
+	return func(r *Resolver) {
+		r.chaos = i
+		r.middleware = append(r.middleware, i.Middleware())
+	}
+}
@@ -0,0 +1,130 @@
+package graphapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+const (
+	// persistedQueryVersion is the version of the automatic persisted query
+	// protocol that's supported
+	persistedQueryVersion = 1
+	// persistedQueryTTL is how long persisted queries are kept after they
+	// were registered. Clients register queries again when they're missing.
+	persistedQueryTTL = 24 * time.Hour
+	// cacheNamePersistedQuery is the cache name used for lookup metrics
+	cacheNamePersistedQuery = "persisted_query"
+)
+
+// Messages of the persisted query protocol, matched by clients. Not found
+// asks the client to send the query in full, and not supported tells it to
+// stop sending hashes alone.
+const (
+	persistedQueryNotFound     = "PersistedQueryNotFound"
+	persistedQueryNotSupported = "PersistedQueryNotSupported"
+)
+
+// requestExtensions are the extensions of a graphql request
+type requestExtensions struct {
+	PersistedQuery *persistedQuery `json:"persistedQuery"`
+}
+
+// UnmarshalJSON decodes the extensions ignoring unknown ones, even when the
+// request is decoded strictly, since clients send extensions of their own
+func (e *requestExtensions) UnmarshalJSON(data []byte) error {
+	type extensions requestExtensions
+
+	return json.Unmarshal(data, (*extensions)(e))
+}
+
+// persistedQuery identifies the query of a request by its hash, as sent by
+// clients using automatic persisted queries
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// WithPersistedQueries enables automatic persisted queries, keeping up to
+// size queries. Clients send the sha256 hash of a query instead of the
+// query, and send the query along with its hash when it isn't known yet. A
+// size of 0 disables persisted queries.
+//
+// The queries are kept by every Resolver built with the option, including
+// those created by WithSchema.
+func WithPersistedQueries(size int) Option {
+	var queries *cache.Cache
+	if size > 0 {
+		queries = cache.New(size, persistedQueryTTL)
+	}
+
+	return func(r *Resolver) {
+		r.persistedQueries = queries
+	}
+}
+
+// resolvePersistedQuery sets the query of a request sent with a persisted
+// query hash, registering the query when it's sent along with its hash. It
+// returns a graphql error for the client when the query can't be found or
+// persisted queries aren't supported, and ErrMalformedRequest for invalid
+// hashes.
+func (r *Resolver) resolvePersistedQuery(p *postData) (*graphql.Result, error) {
+	pq := p.Extensions.PersistedQuery
+	if pq == nil {
+		return nil, nil
+	}
+
+	if r.persistedQueries == nil {
+		if p.Query == "" {
+			return persistedQueryResult(errcode.InvalidRequest, persistedQueryNotSupported), nil
+		}
+
+		return nil, nil
+	}
+
+	if pq.Version != persistedQueryVersion {
+		return nil, fmt.Errorf("%w: unsupported persisted query version %d", ErrMalformedRequest, pq.Version)
+	}
+
+	hash := strings.ToLower(pq.SHA256Hash)
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("%w: invalid persisted query hash", ErrMalformedRequest)
+	}
+
+	if p.Query == "" {
+		query, ok := r.persistedQueries.Get(hash)
+		r.recordCacheLookup(cacheNamePersistedQuery, ok)
+
+		if !ok {
+			return persistedQueryResult(errcode.PersistedQueryNotFound, persistedQueryNotFound), nil
+		}
+
+		p.Query = query.(string)
+
+		return nil, nil
+	}
+
+	sum := sha256.Sum256([]byte(p.Query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("%w: provided sha does not match query", ErrMalformedRequest)
+	}
+
+	r.persistedQueries.Set(hash, "", p.Query)
+
+	return nil, nil
+}
+
+func persistedQueryResult(code errcode.Code, msg string) *graphql.Result {
+	return &graphql.Result{
+		Errors: withCode(code, []gqlerrors.FormattedError{gqlerrors.NewFormattedError(msg)}),
+	}
+}
@@ -0,0 +1,98 @@
+package graphapi_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestPersistedQueries(t *testing.T) {
+	query := `{ node(id: \"testsrv-abc\") { id } }`
+
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(query, `\"`, `"`)))
+	hash := hex.EncodeToString(sum[:])
+	extensions := `"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + hash + `"}, "clientLibrary": {"name": "gateway"}}`
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithPersistedQueries(10), graphapi.WithStrictRequests())
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		return serve(httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body)))
+	}
+
+	// the hash alone isn't known yet, so the client is asked for the query
+	rec := post(`{` + extensions + `}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":null,"errors":[{"message":"PersistedQueryNotFound","locations":[],"extensions":{"code":"persisted_query_not_found"}}]}`, rec.Body.String())
+
+	rec = post(`{"query": "` + query + `", ` + extensions + `}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"node":{"id":"testsrv-abc"}}}`, rec.Body.String())
+
+	rec = post(`{` + extensions + `}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"node":{"id":"testsrv-abc"}}}`, rec.Body.String())
+
+	params := url.Values{"extensions": {`{"persistedQuery": {"version": 1, "sha256Hash": "` + hash + `"}}`}}
+	rec = serve(httptest.NewRequest(http.MethodGet, "/query?"+params.Encode(), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"node":{"id":"testsrv-abc"}}}`, rec.Body.String())
+
+	// queries are kept by resolvers with a new schema
+	next, err := r.WithSchema(validTestSchema)
+	require.NoError(t, err)
+
+	e = echo.New()
+	next.Routes(e.Group(""))
+
+	rec = post(`{` + extensions + `}`)
+	assert.JSONEq(t, `{"data":{"node":{"id":"testsrv-abc"}}}`, rec.Body.String())
+
+	rec = post(`{"query": "{ node(id: \"testusr-abc\") { id } }", ` + extensions + `}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "provided sha does not match query")
+
+	rec = post(`{"extensions": {"persistedQuery": {"version": 2, "sha256Hash": "` + hash + `"}}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "unsupported persisted query version 2")
+
+	rec = post(`{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "abc"}}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid persisted query hash")
+}
+
+func TestPersistedQueriesDisabled(t *testing.T) {
+	extensions := `"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "0000000000000000000000000000000000000000000000000000000000000000"}}`
+
+	resp, err := testQuery(validTestSchema, `{`+extensions+`}`, graphapi.WithPersistedQueries(0))
+	require.NoError(t, err)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "PersistedQueryNotSupported", resp.Errors[0].Message)
+
+	// the query is executed without checking the hash
+	resp, err = testQuery(validTestSchema, `{"query": "{ node(id: \"testsrv-abc\") { id } }", `+extensions+`}`)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"node":{"id":"testsrv-abc"}}`, resp.Data)
+}
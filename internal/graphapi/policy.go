@@ -0,0 +1,214 @@
+package graphapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/policy"
+)
+
+// evaluatePolicy checks the request with the configured policy Evaluator, if
+// any. An error wrapping policy.ErrDenied is returned when the request is
+// denied, otherwise the annotations from the decision are returned to be
+// added to the response.
+func (r *Resolver) evaluatePolicy(ctx context.Context, input policy.Input) (map[string]interface{}, error) {
+	if r.policy == nil {
+		return nil, nil
+	}
+
+	input.Subject = authz.Subject(ctx)
+
+	d, err := r.policy.Evaluate(ctx, input)
+	if err != nil {
+		r.logger.Errorw("policy evaluation failed", "subject", input.Subject, "error", err)
+
+		return nil, policy.ErrDenied
+	}
+
+	if !d.Allow {
+		r.logger.Debugw("request denied by policy", "subject", input.Subject, "reason", d.Reason)
+
+		if d.Reason != "" {
+			return nil, fmt.Errorf("%w: %s", policy.ErrDenied, d.Reason)
+		}
+
+		return nil, policy.ErrDenied
+	}
+
+	return d.Annotations, nil
+}
+
+func deniedResult(msg string) *graphql.Result {
+	return &graphql.Result{
+		Errors: withCode(errcode.Denied, []gqlerrors.FormattedError{gqlerrors.NewFormattedError(msg)}),
+	}
+}
+
+// policyInput collects the root fields and ids referenced by the operation
+// that will be executed. Queries that can't be parsed produce an input
+// without fields or ids; they will fail validation when executed.
+func (r *Resolver) policyInput(p postData) policy.Input {
+	input := policy.Input{
+		Operation: p.Operation,
+		Fields:    []string{},
+		IDs:       []string{},
+		Prefixes:  []string{},
+	}
+
+	qd := r.queryDocument(p.Query)
+	if qd.err != nil {
+		return input
+	}
+
+	doc := qd.doc
+
+	names := make([]string, len(doc.Operations))
+	for i, op := range doc.Operations {
+		names[i] = op.Name
+	}
+
+	// the operation is selected the same way it will be when executed
+	name, err := r.selectOperation(names, p.Operation)
+	if err != nil {
+		return input
+	}
+
+	op := doc.Operations.ForName(name)
+	if op == nil {
+		return input
+	}
+
+	input.Operation = name
+
+	c := &idCollector{
+		doc:         doc,
+		variables:   p.Variables,
+		fields:      map[string]bool{},
+		ids:         map[string]bool{},
+		prefixes:    map[string]bool{},
+		visited:     map[string]bool{},
+		maxIDLength: r.maxIDLength,
+	}
+
+	c.collect(op.SelectionSet)
+
+	input.Fields = sortedKeys(c.fields)
+	input.IDs = sortedKeys(c.ids)
+	input.Prefixes = sortedKeys(c.prefixes)
+
+	return input
+}
+
+type idCollector struct {
+	doc       *ast.QueryDocument
+	variables map[string]interface{}
+	fields    map[string]bool
+	ids       map[string]bool
+	prefixes  map[string]bool
+	visited   map[string]bool
+	// maxIDLength is the maximum id length, longer ids are left out since
+	// they'll be rejected
+	maxIDLength int
+}
+
+func (c *idCollector) collect(set ast.SelectionSet) {
+	for _, sel := range set {
+		switch s := sel.(type) {
+		case *ast.Field:
+			c.fields[s.Name] = true
+
+			switch s.Name {
+			case "node":
+				if arg := s.Arguments.ForName("id"); arg != nil {
+					c.add(c.value(arg.Value))
+				}
+			case "nodes":
+				if arg := s.Arguments.ForName("ids"); arg != nil {
+					c.addList(c.value(arg.Value))
+				}
+			case "_entities":
+				if arg := s.Arguments.ForName("representations"); arg != nil {
+					c.addRepresentations(c.value(arg.Value))
+				}
+			}
+		case *ast.InlineFragment:
+			c.collect(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if c.visited[s.Name] {
+				continue
+			}
+
+			c.visited[s.Name] = true
+
+			if f := c.doc.Fragments.ForName(s.Name); f != nil {
+				c.collect(f.SelectionSet)
+			}
+		}
+	}
+}
+
+// value returns the go value of an argument, resolving variables
+func (c *idCollector) value(v *ast.Value) interface{} {
+	val, err := v.Value(c.variables)
+	if err != nil {
+		return nil
+	}
+
+	return val
+}
+
+func (c *idCollector) addList(v interface{}) {
+	ids, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, id := range ids {
+		c.add(id)
+	}
+}
+
+func (c *idCollector) addRepresentations(v interface{}) {
+	reps, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, rep := range reps {
+		if m, ok := rep.(map[string]interface{}); ok {
+			c.add(m["id"])
+		}
+	}
+}
+
+func (c *idCollector) add(v interface{}) {
+	id, ok := v.(string)
+	if !ok || id == "" || checkID(id, c.maxIDLength) != nil {
+		return
+	}
+
+	c.ids[id] = true
+
+	if pid, err := gidx.Parse(id); err == nil {
+		c.prefixes[pid.Prefix()] = true
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,118 @@
+package graphapi_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+	"go.infratographer.com/node-resolver/internal/policy"
+)
+
+// prefixPolicy denies requests referencing the given prefix and annotates the
+// rest with the ids they reference
+type prefixPolicy struct {
+	prefix string
+	inputs []policy.Input
+}
+
+func (p *prefixPolicy) Evaluate(_ context.Context, input policy.Input) (policy.Decision, error) {
+	p.inputs = append(p.inputs, input)
+
+	for _, prefix := range input.Prefixes {
+		if prefix == p.prefix {
+			return policy.Decision{Reason: "prefix " + prefix + " is restricted"}, nil
+		}
+	}
+
+	return policy.Decision{
+		Allow:       true,
+		Annotations: map[string]interface{}{"ids": strings.Join(input.IDs, ",")},
+	}, nil
+}
+
+func TestPolicy(t *testing.T) {
+	testCases := []struct {
+		TestName   string
+		query      string
+		response   string
+		errorMsgs  []string
+		input      policy.Input
+		extensions map[string]interface{}
+	}{
+		{
+			TestName: "allowed node",
+			query:    `{"query": "query Lookup { node(id: \"testsrv-123\") { id } }", "operation": "Lookup" }`,
+			response: `{"node":{"id":"testsrv-123"}}`,
+			input: policy.Input{
+				Operation: "Lookup",
+				Fields:    []string{"node"},
+				IDs:       []string{"testsrv-123"},
+				Prefixes:  []string{"testsrv"},
+			},
+			extensions: map[string]interface{}{"policy": map[string]interface{}{"ids": "testsrv-123"}},
+		},
+		{
+			TestName: "allowed entities in fragment",
+			query: `{
+				"query": "query($representations:[_Any!]!){ ...entities } fragment entities on Query { _entities(representations:$representations){...on Actor{id}} }",
+				"variables": {"representations": [{ "__typename": "Actor", "id": "testusr-456" }, { "__typename": "Actor", "id": "testusr-123" }]}
+			}`,
+			response: `{"_entities":[{"id":"testusr-456"},{"id":"testusr-123"}]}`,
+			input: policy.Input{
+				Fields:   []string{"_entities"},
+				IDs:      []string{"testusr-123", "testusr-456"},
+				Prefixes: []string{"testusr"},
+			},
+			extensions: map[string]interface{}{"policy": map[string]interface{}{"ids": "testusr-123,testusr-456"}},
+		},
+		{
+			TestName: "allowed nodes in variables",
+			query: `{
+				"query": "query($ids:[ID!]!){ nodes(ids:$ids){ id } }",
+				"variables": {"ids": ["testsrv-123", "testusr-456"]}
+			}`,
+			response: `{"nodes":[{"id":"testsrv-123"},{"id":"testusr-456"}]}`,
+			input: policy.Input{
+				Fields:   []string{"nodes"},
+				IDs:      []string{"testsrv-123", "testusr-456"},
+				Prefixes: []string{"testsrv", "testusr"},
+			},
+			extensions: map[string]interface{}{"policy": map[string]interface{}{"ids": "testsrv-123,testusr-456"}},
+		},
+		{
+			TestName:  "denied",
+			query:     `{"query": "{ a: node(id: \"testsrv-123\") { id } b: node(id: \"testtkn-123\") { id } }" }`,
+			response:  `null`,
+			errorMsgs: []string{"request denied by policy: prefix testtkn is restricted"},
+			input: policy.Input{
+				Fields:   []string{"node"},
+				IDs:      []string{"testsrv-123", "testtkn-123"},
+				Prefixes: []string{"testsrv", "testtkn"},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			p := &prefixPolicy{prefix: "testtkn"}
+
+			resp, err := testQuery(validTestSchema, tt.query, graphapi.WithPolicy(p))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.response, resp.Data)
+			assert.Equal(t, tt.extensions, resp.Extensions)
+			require.Equal(t, len(tt.errorMsgs), len(resp.Errors))
+
+			for i, msg := range tt.errorMsgs {
+				assert.Contains(t, resp.Errors[i].Message, msg)
+			}
+
+			require.Len(t, p.inputs, 1)
+			assert.Equal(t, tt.input, p.inputs[0])
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package graphapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize is the largest buffer returned to the pool, so a
+// single huge request doesn't keep its memory alive
+const maxPooledBufferSize = 1 << 20
+
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+
+	postDataPool = sync.Pool{
+		New: func() interface{} { return new(postData) },
+	}
+)
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// decodePostData reads a graphql request from body using pooled buffers,
+// rejecting unknown and duplicate fields when strict. Bodies that aren't a
+// valid request are reported as ErrMalformedRequest. The returned postData
+// must be released with putPostData.
+func decodePostData(body io.Reader, strict bool) (*postData, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if _, err := buf.ReadFrom(body); err != nil {
+		return nil, err
+	}
+
+	p := postDataPool.Get().(*postData)
+
+	var err error
+	if strict {
+		err = decodeStrict(buf.Bytes(), p)
+	} else {
+		err = json.Unmarshal(buf.Bytes(), p)
+	}
+
+	if err != nil {
+		putPostData(p)
+
+		if !errors.Is(err, ErrMalformedRequest) {
+			err = fmt.Errorf("%w: invalid json: %s", ErrMalformedRequest, safeString(err.Error()))
+		}
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func putPostData(p *postData) {
+	*p = postData{}
+	postDataPool.Put(p)
+}
@@ -0,0 +1,141 @@
+package graphapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.infratographer.com/x/gidx"
+)
+
+// DefaultMaxIDLength is the default maximum length of an id in bytes
+const DefaultMaxIDLength = 128
+
+var (
+	// ErrIDTooLong is returned for ids longer than the maximum id length
+	ErrIDTooLong = errors.New("invalid id; too long")
+	// ErrIDInvalidCharacters is returned for ids containing control
+	// characters or invalid utf-8
+	ErrIDInvalidCharacters = errors.New("invalid id; contains control characters")
+	// ErrEmptyID is returned for ids that are empty or only whitespace
+	ErrEmptyID = errors.New("invalid id; empty")
+	// ErrInvalidPrefix is returned for prefixes gidx ids can't have
+	ErrInvalidPrefix = errors.New("invalid prefix")
+)
+
+// WithMaxIDLength sets the maximum length of ids in bytes. Longer ids are
+// rejected before they're parsed, logged or audited. Zero disables the limit.
+func WithMaxIDLength(n int) Option {
+	return func(r *Resolver) {
+		r.maxIDLength = n
+	}
+}
+
+// prefixProblem returns why prefix, declared by a @prefixedID directive, can't
+// be the prefix of a gidx id, or an empty string when it can. Wildcard
+// prefixes are checked by the characters before the wildcard.
+func prefixProblem(prefix string, wildcard bool) string {
+	if wildcard && isWildcardPrefix(prefix) {
+		for _, c := range strings.TrimSuffix(prefix, prefixWildcard) {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+				return fmt.Sprintf("wildcard prefix %q, only lowercase letters and digits may come before the wildcard", prefix)
+			}
+		}
+
+		return ""
+	}
+
+	if len(prefix) != gidx.PrefixPartLength {
+		return fmt.Sprintf("prefix %q of %d characters, gidx prefixes are %d", prefix, len(prefix), gidx.PrefixPartLength)
+	}
+
+	if !gidx.PrefixRegexp.MatchString(prefix) {
+		return fmt.Sprintf("prefix %q not matching %s", prefix, gidx.PrefixRegexp)
+	}
+
+	return ""
+}
+
+// ValidatePrefix returns an ErrInvalidPrefix describing the problem when ids
+// can't have prefix under the gidx rules
+func ValidatePrefix(prefix string) error {
+	if problem := prefixProblem(prefix, false); problem != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidPrefix, problem)
+	}
+
+	return nil
+}
+
+// prefixOf returns the prefix of id, the same as gidx.PrefixedID.Prefix but
+// without allocating
+func prefixOf(id gidx.PrefixedID) string {
+	i := strings.IndexByte(string(id), '-')
+	if i < 0 {
+		return ""
+	}
+
+	return string(id[:i])
+}
+
+// parseID returns raw as a PrefixedID. Ids with an exact prefix from the
+// schema are accepted after only splitting at the first dash; the full
+// gidx.Parse is used for any other id, so ids that would fail to resolve
+// still get its validation errors and ids matched by a wildcard prefix are
+// still validated. Surrounding whitespace is trimmed first, so ids are
+// parsed the same wherever they come from.
+func (r *Resolver) parseID(raw string) (gidx.PrefixedID, error) {
+	raw, err := r.normalizeID(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if i := strings.IndexByte(raw, '-'); i == gidx.PrefixPartLength && i < len(raw)-1 {
+		if _, exact := r.objectForPrefix(raw[:i]); exact {
+			return gidx.PrefixedID(raw), nil
+		}
+	}
+
+	id, err := gidx.Parse(raw)
+	if err != nil {
+		return "", sanitizeIDError(err, raw)
+	}
+
+	return id, nil
+}
+
+// normalizeID returns raw without surrounding whitespace, rejecting ids that
+// are empty or fail checkID. The length is checked first so oversized ids
+// aren't scanned.
+func (r *Resolver) normalizeID(raw string) (string, error) {
+	if r.maxIDLength > 0 && len(raw) > r.maxIDLength {
+		return "", ErrIDTooLong
+	}
+
+	id := strings.TrimSpace(raw)
+	if id == "" {
+		return "", ErrEmptyID
+	}
+
+	return id, r.checkID(id)
+}
+
+// checkID rejects ids that are too long or contain control characters, such
+// as newlines that could forge log lines, before any other work is done with
+// them
+func (r *Resolver) checkID(raw string) error {
+	return checkID(raw, r.maxIDLength)
+}
+
+func checkID(raw string, maxLength int) error {
+	if maxLength > 0 && len(raw) > maxLength {
+		return ErrIDTooLong
+	}
+
+	for _, c := range raw {
+		if unsafeRune(c) {
+			return ErrIDInvalidCharacters
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+)
+
+const (
+	// maxSchemaHistory is the number of schemas a Handler remembers serving
+	maxSchemaHistory = 20
+	// DefaultUnknownPrefixLogSize is the default number of requests for ids
+	// with unknown prefixes kept
+	DefaultUnknownPrefixLogSize = 100
+)
+
+// ErrDuplicatePrefix is returned by NewResolver with strict prefixes when
+// several types declare the same prefix
+var ErrDuplicatePrefix = errors.New("duplicate prefix")
+
+// WithStrictPrefixes rejects schemas in which several types declare the same
+// @prefixedID prefix. Without it the last type declaring a prefix resolves
+// its ids and the others are only logged, so a type can silently shadow
+// another one.
+func WithStrictPrefixes() Option {
+	return func(r *Resolver) {
+		r.strictPrefixes = true
+	}
+}
+
+// WithStrictSchema rejects schemas with types implementing interfaces but
+// missing the @prefixedID directive or its prefix, or declaring a prefix gidx
+// ids can't have. Without it those types are logged, and dropped when they
+// have no prefix, so their ids never resolve.
+func WithStrictSchema() Option {
+	return func(r *Resolver) {
+		r.strictSchema = true
+	}
+}
+
+// PrefixType is a prefix served by a resolver and the type its ids resolve
+// to. Deprecated prefixes are prefixes the type used to have.
+type PrefixType struct {
+	Prefix     string `json:"prefix"`
+	Type       string `json:"type"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+}
+
+// Prefixes returns the prefixes the resolver serves sorted by prefix,
+// including wildcard and deprecated prefixes
+func (r *Resolver) Prefixes() []PrefixType {
+	prefixes := make([]PrefixType, 0, len(r.prefixMap)+len(r.deprecatedPrefixes))
+
+	for prefix, obj := range r.prefixMap {
+		prefixes = append(prefixes, PrefixType{Prefix: prefix, Type: obj.Name()})
+	}
+
+	for prefix, d := range r.deprecatedPrefixes {
+		prefixes = append(prefixes, PrefixType{Prefix: prefix, Type: d.obj.Name(), Deprecated: true})
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Prefix < prefixes[j].Prefix })
+
+	return prefixes
+}
+
+// Interfaces returns the interfaces implemented by each type with a prefix,
+// by type name, sorted by name
+func (r *Resolver) Interfaces() map[string][]string {
+	interfaces := make(map[string][]string, len(r.prefixMap))
+
+	for _, obj := range r.prefixMap {
+		names := make([]string, len(obj.Interfaces()))
+		for i, iface := range obj.Interfaces() {
+			names[i] = iface.Name()
+		}
+
+		sort.Strings(names)
+
+		interfaces[obj.Name()] = names
+	}
+
+	return interfaces
+}
+
+// LoadedAt returns when the schema of the resolver was loaded. Resolvers
+// created by WithSchema with an unchanged schema keep the time of the first.
+func (r *Resolver) LoadedAt() time.Time {
+	return r.loadedAt
+}
+
+// prefixesFor returns the prefixes served to the caller of ctx, leaving out
+// those whose feature flag is disabled for them
+func (r *Resolver) prefixesFor(ctx context.Context) []PrefixType {
+	prefixes := r.Prefixes()
+	if r.featureFlags == nil {
+		return prefixes
+	}
+
+	subject := authz.Subject(ctx)
+	served := prefixes[:0]
+
+	for _, p := range prefixes {
+		if r.featureFlags.Enabled(ctx, p.Prefix, subject) {
+			served = append(served, p)
+		}
+	}
+
+	return served
+}
+
+// prefixMappingType is the PrefixMapping type returned by the prefixes query
+var prefixMappingType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "PrefixMapping",
+	Description: "A prefix and the type its ids resolve to.",
+	Fields: graphql.Fields{
+		"prefix": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "The id prefix, ending in * for wildcard prefixes.",
+		},
+		"type": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "The name of the type ids with the prefix resolve to.",
+		},
+		"deprecated": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Whether the prefix is one the type used to have, still resolved for existing ids.",
+		},
+	},
+})
+
+// prefixesResolver resolves the prefixes query
+func (r *Resolver) prefixesResolver(p graphql.ResolveParams) (interface{}, error) {
+	return r.prefixesFor(p.Context), nil
+}
+
+// typePrefixResolver resolves the typePrefix query to the first prefix of the
+// type, or null when the type has no prefix. Deprecated prefixes are never
+// returned, so new ids get the current prefix.
+func (r *Resolver) typePrefixResolver(p graphql.ResolveParams) (interface{}, error) {
+	typename := p.Args["typename"].(string)
+
+	for _, prefix := range r.prefixesFor(p.Context) {
+		if prefix.Type == typename && !prefix.Deprecated {
+			return prefix.Prefix, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// UnknownPrefixEvent is a request for an id whose prefix wasn't served
+type UnknownPrefixEvent struct {
+	Prefix    string    `json:"prefix"`
+	Operation string    `json:"operation"`
+	At        time.Time `json:"at"`
+}
+
+// UnknownPrefixLog keeps the most recent requests for ids with unknown
+// prefixes, so clients using stale or mistyped ids can be spotted. An
+// UnknownPrefixLog is safe for concurrent use.
+type UnknownPrefixLog struct {
+	mu     sync.Mutex
+	events []UnknownPrefixEvent
+	next   int
+	full   bool
+}
+
+// NewUnknownPrefixLog returns a log keeping the latest size events
+func NewUnknownPrefixLog(size int) *UnknownPrefixLog {
+	if size < 1 {
+		size = 1
+	}
+
+	return &UnknownPrefixLog{events: make([]UnknownPrefixEvent, size)}
+}
+
+// WithUnknownPrefixLog records requests for ids with unknown prefixes in l.
+// The log is shared by resolvers built from r with WithSchema, so events
+// survive schema changes.
+func WithUnknownPrefixLog(l *UnknownPrefixLog) Option {
+	return func(r *Resolver) {
+		r.unknownPrefixes = l
+	}
+}
+
+// Recent returns the events in the log, most recent first
+func (l *UnknownPrefixLog) Recent() []UnknownPrefixEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := l.next
+	if l.full {
+		n = len(l.events)
+	}
+
+	events := make([]UnknownPrefixEvent, n)
+
+	for i := range events {
+		events[i] = l.events[(l.next-1-i+len(l.events))%len(l.events)]
+	}
+
+	return events
+}
+
+func (l *UnknownPrefixLog) record(e UnknownPrefixEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events[l.next] = e
+	l.next = (l.next + 1) % len(l.events)
+
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// UnknownPrefixes returns the recent requests for ids with unknown prefixes,
+// most recent first, or nil when they aren't recorded
+func (r *Resolver) UnknownPrefixes() []UnknownPrefixEvent {
+	if r.unknownPrefixes == nil {
+		return nil
+	}
+
+	return r.unknownPrefixes.Recent()
+}
+
+// recordUnknownPrefix logs the request for an id with the given prefix when
+// err is an unknown prefix
+func (r *Resolver) recordUnknownPrefix(operation, prefix string, err error) {
+	if r.unknownPrefixes == nil || !errors.Is(err, ErrUnknownPrefix) {
+		return
+	}
+
+	r.unknownPrefixes.record(UnknownPrefixEvent{
+		Prefix:    safeString(prefix),
+		Operation: operation,
+		At:        time.Now(),
+	})
+}
+
+// SchemaVersion is a schema served by a Handler
+type SchemaVersion struct {
+	Checksum string    `json:"checksum"`
+	LoadedAt time.Time `json:"loaded_at"`
+	// Prefixes is the number of prefixes the schema serves
+	Prefixes int `json:"prefixes"`
+}
+
+// History returns the schemas the handler served, most recent first, up to
+// the latest maxSchemaHistory. Swapping in an unchanged schema isn't a new
+// version.
+func (h *Handler) History() []SchemaVersion {
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	history := make([]SchemaVersion, len(h.history))
+	for i, v := range h.history {
+		history[len(history)-1-i] = v
+	}
+
+	return history
+}
+
+// recordVersion adds the schema of r to the history, unless it's the schema
+// served last
+func (h *Handler) recordVersion(r *Resolver) {
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	checksum := r.SDLChecksum()
+
+	if n := len(h.history); n != 0 && h.history[n-1].Checksum == checksum {
+		return
+	}
+
+	h.history = append(h.history, SchemaVersion{
+		Checksum: checksum,
+		LoadedAt: r.loadedAt,
+		Prefixes: len(r.prefixMap),
+	})
+
+	if len(h.history) > maxSchemaHistory {
+		h.history = h.history[len(h.history)-maxSchemaHistory:]
+	}
+}
@@ -0,0 +1,146 @@
+package graphapi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestPrefixes(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	assert.Equal(t, []graphapi.PrefixType{
+		{Prefix: "testsrv", Type: "Server"},
+		{Prefix: "testtkn", Type: "Token"},
+		{Prefix: "testusr", Type: "User"},
+	}, r.Prefixes())
+}
+
+func TestPrefixQueries(t *testing.T) {
+	resp, err := testQuery(validTestSchema, `{"query":"{ prefixes { prefix type } user: typePrefix(typename: \"User\") missing: typePrefix(typename: \"Actor\") }"}`)
+	require.NoError(t, err)
+	require.Empty(t, resp.Errors)
+
+	assert.JSONEq(t, `{
+		"prefixes": [
+			{"prefix":"testsrv","type":"Server"},
+			{"prefix":"testtkn","type":"Token"},
+			{"prefix":"testusr","type":"User"}
+		],
+		"user": "testusr",
+		"missing": null
+	}`, resp.Data)
+
+	// the queries are part of the subgraph composed by gateways
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+	assert.Contains(t, r.SDL(), "  typePrefix(typename: String!): String\n  prefixes: [PrefixMapping!]!\n")
+}
+
+func TestUnknownPrefixLog(t *testing.T) {
+	log := graphapi.NewUnknownPrefixLog(2)
+	opts := []graphapi.Option{graphapi.WithUnknownPrefixLog(log)}
+
+	for _, id := range []string{"testusr-abc", "unknwn1-abc", "unknwn2-abc", "unknwn3-abc"} {
+		_, err := testQuery(validTestSchema, `{"query":"{ node(id: \"`+id+`\") { id } }"}`, opts...)
+		require.NoError(t, err)
+	}
+
+	// resolved ids aren't logged, and only the latest events are kept
+	events := log.Recent()
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "unknwn3", events[0].Prefix)
+	assert.Equal(t, "node", events[0].Operation)
+	assert.Equal(t, "unknwn2", events[1].Prefix)
+	assert.False(t, events[0].At.Before(events[1].At))
+
+	// the log is kept by resolvers with a new schema
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, opts...)
+	require.NoError(t, err)
+
+	next, err := r.WithSchema(widgetGraphSchema)
+	require.NoError(t, err)
+	assert.Equal(t, events, next.UnknownPrefixes())
+}
+
+func TestHandlerHistory(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	h := graphapi.NewHandler(r)
+
+	widgets, err := r.WithSchema(widgetGraphSchema)
+	require.NoError(t, err)
+
+	h.Swap(widgets)
+
+	// swapping in the same schema isn't a new version
+	same, err := widgets.WithSchema(widgetGraphSchema)
+	require.NoError(t, err)
+
+	h.Swap(same)
+
+	history := h.History()
+	require.Len(t, history, 2)
+
+	assert.Equal(t, widgets.SDLChecksum(), history[0].Checksum)
+	assert.Equal(t, 1, history[0].Prefixes)
+	assert.Equal(t, r.SDLChecksum(), history[1].Checksum)
+	assert.Equal(t, 3, history[1].Prefixes)
+}
+
+func TestDuplicatePrefixes(t *testing.T) {
+	schema := validTestSchema + `
+type Account implements Node @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+`
+
+	// the last type declaring a prefix is used
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), schema)
+	require.NoError(t, err)
+	assert.Contains(t, r.Prefixes(), graphapi.PrefixType{Prefix: "testusr", Type: "Account"})
+
+	_, err = graphapi.NewResolver(zap.NewNop().Sugar(), schema, graphapi.WithStrictPrefixes())
+	require.ErrorIs(t, err, graphapi.ErrDuplicatePrefix)
+	assert.Contains(t, err.Error(), "User")
+	assert.Contains(t, err.Error(), "Account")
+
+	// a schema without duplicates is fine
+	_, err = graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithStrictPrefixes())
+	assert.NoError(t, err)
+}
+
+func TestStrictSchema(t *testing.T) {
+	schema := validTestSchema + `
+type Account implements Node @key(fields: "id") {
+	id: ID!
+}
+type Group implements Node @key(fields: "id") @prefixedID {
+	id: ID!
+}
+type Host implements Node @key(fields: "id") @prefixedID(prefix: "host") {
+	id: ID!
+}
+`
+
+	// types without a prefix are dropped, invalid prefixes are kept
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), schema)
+	require.NoError(t, err)
+	assert.Len(t, r.Prefixes(), 4)
+
+	_, err = graphapi.NewResolver(zap.NewNop().Sugar(), schema, graphapi.WithStrictSchema())
+	require.ErrorAs(t, err, &graphapi.ErrInvalidSchema{})
+	assert.Contains(t, err.Error(), "Account has no @prefixedID directive")
+	assert.Contains(t, err.Error(), "Group has no prefix")
+	assert.Contains(t, err.Error(), `Host has an invalid prefix "host" of 4 characters`)
+
+	_, err = graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithStrictSchema())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,208 @@
+package graphapi
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+)
+
+// prefixWildcard ends a wildcard prefix, matching every prefix starting with
+// the characters before it
+const prefixWildcard = "*"
+
+// WithWildcardPrefixes allows @prefixedID prefixes ending in a wildcard, such
+// as "loadb*", which resolve every id whose prefix starts with "loadb" and
+// has no exact match to the type. When more than one wildcard matches the
+// longest one is used.
+func WithWildcardPrefixes() Option {
+	return func(r *Resolver) {
+		r.wildcardPrefixes = true
+	}
+}
+
+// isWildcardPrefix reports whether prefix is a valid wildcard prefix: shorter
+// than a full prefix and ending with the wildcard
+func isWildcardPrefix(prefix string) bool {
+	return strings.HasSuffix(prefix, prefixWildcard) && len(prefix) <= gidx.PrefixPartLength
+}
+
+// prefixMatcher matches id prefixes to object types using a trie of exact
+// and wildcard prefixes and prefix patterns. It's built once from the schema
+// and only read afterwards.
+type prefixMatcher struct {
+	root prefixNode
+	// patterns is set when the trie has prefix patterns, which are only
+	// searched for then
+	patterns bool
+}
+
+// prefixNode is a node of the prefix trie. Children are kept in a slice
+// sorted by their byte, since each node only has a handful of them. The
+// characters of patterns matching any character are children keyed by
+// prefixPatternAny.
+type prefixNode struct {
+	keys     []byte
+	children []*prefixNode
+	exact    *graphql.Object
+	wildcard *graphql.Object
+	pattern  *graphql.Object
+}
+
+// newPrefixMatcher returns a matcher for the prefixes of prefixes, with keys
+// ending in prefixWildcard matched as wildcards and keys containing
+// prefixPatternAny as patterns
+func newPrefixMatcher(prefixes map[string]*graphql.Object) *prefixMatcher {
+	m := &prefixMatcher{}
+
+	for prefix, obj := range prefixes {
+		switch {
+		case isWildcardPrefix(prefix):
+			m.root.insert(strings.TrimSuffix(prefix, prefixWildcard)).wildcard = obj
+		case isPrefixPattern(prefix):
+			m.root.insert(prefix).pattern = obj
+			m.patterns = true
+		default:
+			m.root.insert(prefix).exact = obj
+		}
+	}
+
+	return m
+}
+
+// insert returns the node for key, adding the nodes that are missing
+func (n *prefixNode) insert(key string) *prefixNode {
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+
+		j := sort.Search(len(n.keys), func(j int) bool { return n.keys[j] >= c })
+		if j < len(n.keys) && n.keys[j] == c {
+			n = n.children[j]
+
+			continue
+		}
+
+		child := &prefixNode{}
+
+		n.keys = append(n.keys, 0)
+		copy(n.keys[j+1:], n.keys[j:])
+		n.keys[j] = c
+
+		n.children = append(n.children, nil)
+		copy(n.children[j+1:], n.children[j:])
+		n.children[j] = child
+
+		n = child
+	}
+
+	return n
+}
+
+// child returns the child of n for c, or nil
+func (n *prefixNode) child(c byte) *prefixNode {
+	for i, k := range n.keys {
+		if k == c {
+			return n.children[i]
+		}
+	}
+
+	return nil
+}
+
+// match returns the object type for prefix, preferring an exact match over
+// a matching pattern, and a pattern over the longest matching wildcard.
+// exact reports whether prefix itself is in the schema.
+func (m *prefixMatcher) match(prefix string) (obj *graphql.Object, exact bool) {
+	n := &m.root
+
+	for i := 0; i < len(prefix) && n != nil; i++ {
+		if n.wildcard != nil {
+			obj = n.wildcard
+		}
+
+		n = n.child(prefix[i])
+	}
+
+	if n != nil && n.exact != nil {
+		return n.exact, true
+	}
+
+	if m.patterns {
+		if pattern := m.root.matchPattern(prefix); pattern != nil {
+			return pattern, false
+		}
+	}
+
+	if n != nil && n.wildcard != nil {
+		obj = n.wildcard
+	}
+
+	return obj, false
+}
+
+// matchPattern returns the object type of the pattern below n matching
+// prefix. Where several patterns match, the one with a literal character
+// at the first position they differ wins.
+func (n *prefixNode) matchPattern(prefix string) *graphql.Object {
+	if prefix == "" {
+		return n.pattern
+	}
+
+	if c := n.child(prefix[0]); c != nil {
+		if obj := c.matchPattern(prefix[1:]); obj != nil {
+			return obj
+		}
+	}
+
+	if c := n.child(prefixPatternAny); c != nil {
+		return c.matchPattern(prefix[1:])
+	}
+
+	return nil
+}
+
+// objectForPrefix returns the object type of ids with the given prefix.
+// exact is false when the type was matched by a wildcard prefix, or is the
+// type of unknown prefixes. Deprecated prefixes resolve exactly to the type
+// replacing them.
+func (r *Resolver) objectForPrefix(prefix string) (obj *graphql.Object, exact bool) {
+	if r.prefixes != nil {
+		obj, exact = r.prefixes.match(prefix)
+	} else {
+		obj, exact = r.prefixMap[prefix]
+	}
+
+	// deprecated prefixes are exact prefixes of the types replacing them
+	if !exact {
+		if d, ok := r.deprecatedPrefixes[prefix]; ok {
+			return d.obj, true
+		}
+	}
+
+	if obj == nil && r.unknownPrefixType != nil {
+		return r.unknownPrefixType, false
+	}
+
+	return obj, exact
+}
+
+// objectForRequest returns the object type of ids with the given prefix for
+// the caller of ctx, or nil when the prefix is unknown or its feature flag is
+// disabled for the caller. Prefixes disabled for the caller are unknown to
+// them, resolving to the type of unknown prefixes when there is one.
+func (r *Resolver) objectForRequest(ctx context.Context, prefix string) *graphql.Object {
+	obj, _ := r.objectForPrefix(prefix)
+	if obj == nil || r.featureFlags == nil {
+		return obj
+	}
+
+	if !r.featureFlags.Enabled(ctx, prefix, authz.Subject(ctx)) {
+		return r.unknownPrefixType
+	}
+
+	return obj
+}
@@ -0,0 +1,135 @@
+package graphapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const wildcardTestSchema = `directive @prefixedID(prefix: String!) on OBJECT
+
+type LoadBalancer implements Node @key(fields: "id") @prefixedID(prefix: "loadbal") {
+	id: ID!
+}
+type LoadBalancerResource implements Node @key(fields: "id") @prefixedID(prefix: "loadb*") {
+	id: ID!
+}
+type LoadBalancerPool implements Node @key(fields: "id") @prefixedID(prefix: "loadbp*") {
+	id: ID!
+}
+type Invalid implements Node @key(fields: "id") @prefixedID(prefix: "load*bal") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+func TestPrefixMatcher(t *testing.T) {
+	objs := map[string]*graphql.Object{}
+	prefixes := map[string]*graphql.Object{}
+
+	for _, prefix := range []string{"loadbal", "loadb*", "loadbp*", "testsrv", "*"} {
+		objs[prefix] = graphql.NewObject(graphql.ObjectConfig{Name: fmt.Sprintf("Object%d", len(objs)), Fields: graphql.Fields{}})
+		prefixes[prefix] = objs[prefix]
+	}
+
+	m := newPrefixMatcher(prefixes)
+
+	tests := []struct {
+		prefix   string
+		expected string
+		exact    bool
+	}{
+		{prefix: "loadbal", expected: "loadbal", exact: true},
+		{prefix: "loadbpl", expected: "loadbp*"},
+		{prefix: "loadbp", expected: "loadbp*"},
+		{prefix: "loadbxy", expected: "loadb*"},
+		{prefix: "loadb", expected: "loadb*"},
+		{prefix: "testsrv", expected: "testsrv", exact: true},
+		{prefix: "testsr", expected: "*"},
+		{prefix: "testsrvx", expected: "*"},
+		{prefix: "", expected: "*"},
+	}
+
+	for _, tt := range tests {
+		obj, exact := m.match(tt.prefix)
+
+		assert.Same(t, objs[tt.expected], obj, tt.prefix)
+		assert.Equal(t, tt.exact, exact, tt.prefix)
+	}
+
+	delete(prefixes, "*")
+
+	obj, exact := newPrefixMatcher(prefixes).match("testusr")
+	assert.Nil(t, obj)
+	assert.False(t, exact)
+}
+
+func TestWildcardPrefixes(t *testing.T) {
+	exact, err := NewResolver(zap.NewNop().Sugar(), wildcardTestSchema)
+	require.NoError(t, err)
+
+	_, err = exact.GetNode(context.Background(), "loadbpl-abc")
+	assert.ErrorIs(t, err, ErrUnknownPrefix, "wildcards are only matched when enabled")
+
+	r, err := NewResolver(zap.NewNop().Sugar(), wildcardTestSchema, WithWildcardPrefixes())
+	require.NoError(t, err)
+
+	assert.NotContains(t, r.prefixMap, "load*bal")
+
+	for id, expected := range map[string]string{
+		"loadbal-abc": "LoadBalancer",
+		"loadbpl-abc": "LoadBalancerPool",
+		"loadbxy-abc": "LoadBalancerResource",
+	} {
+		query := &postData{Query: fmt.Sprintf(`{ node(id: %q) { __typename id } }`, id)}
+
+		result := r.execute(context.Background(), query)
+		require.Empty(t, result.Errors, id)
+		assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"__typename": expected, "id": id}}, result.Data, id)
+
+		query = &postData{
+			Query:     `query($representations:[_Any!]!){_entities(representations:$representations){__typename}}`,
+			Variables: map[string]interface{}{"representations": []interface{}{map[string]interface{}{"__typename": "Node", "id": id}}},
+		}
+
+		result = r.execute(context.Background(), query)
+		require.Empty(t, result.Errors, id)
+		assert.Equal(t, map[string]interface{}{"_entities": []interface{}{map[string]interface{}{"__typename": expected}}}, result.Data, id)
+	}
+
+	// ids matched by a wildcard are still validated
+	_, err = r.parseID("loadbXY-abc")
+	assert.Error(t, err)
+
+	_, err = r.GetNode(context.Background(), "testsrv-abc")
+	assert.ErrorIs(t, err, ErrUnknownPrefix)
+}
+
+func BenchmarkPrefixMatcher(b *testing.B) {
+	prefixes := map[string]*graphql.Object{}
+
+	for i := 0; i < 500; i++ {
+		prefixes[fmt.Sprintf("pre%04d", i)] = graphql.NewObject(graphql.ObjectConfig{Name: fmt.Sprintf("Object%d", i), Fields: graphql.Fields{}})
+	}
+
+	prefixes["pre1*"] = graphql.NewObject(graphql.ObjectConfig{Name: "Wildcard", Fields: graphql.Fields{}})
+
+	m := newPrefixMatcher(prefixes)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if obj, _ := m.match("pre0250"); obj == nil {
+			b.Fatal("prefix not matched")
+		}
+	}
+}
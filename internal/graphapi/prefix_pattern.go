@@ -0,0 +1,66 @@
+package graphapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.infratographer.com/x/gidx"
+)
+
+// prefixPatternAny matches any character of a prefix in a @prefixedID
+// prefixPattern
+const prefixPatternAny = '.'
+
+// isPrefixPattern reports whether prefix is a prefix pattern, such as
+// "load...", rather than a prefix
+func isPrefixPattern(prefix string) bool {
+	return strings.IndexByte(prefix, prefixPatternAny) >= 0
+}
+
+// directivePrefix returns the prefix of a @prefixedID directive, or its
+// prefixPattern when it has no prefix. Patterns resolve every prefix whose
+// characters match theirs, with a '.' matching any character, so a service
+// minting many prefixes starting with "load" can declare them all with
+// @prefixedID(prefixPattern: "load...").
+func directivePrefix(pd *ast.Directive) (prefix string, pattern bool, ok bool) {
+	if pa := pd.Arguments.ForName("prefix"); pa != nil && pa.Value != nil {
+		return pa.Value.Raw, false, true
+	}
+
+	if pa := pd.Arguments.ForName("prefixPattern"); pa != nil && pa.Value != nil {
+		return pa.Value.Raw, true, true
+	}
+
+	return "", false, false
+}
+
+// prefixPatternProblem returns why pattern can't match the prefix of a gidx
+// id, or an empty string when it can
+func prefixPatternProblem(pattern string) string {
+	if len(pattern) != gidx.PrefixPartLength {
+		return fmt.Sprintf("prefix pattern %q of %d characters, gidx prefixes are %d", pattern, len(pattern), gidx.PrefixPartLength)
+	}
+
+	if !isPrefixPattern(pattern) {
+		return fmt.Sprintf("prefix pattern %q without a %q, use a prefix instead", pattern, prefixPatternAny)
+	}
+
+	for _, c := range pattern {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != prefixPatternAny {
+			return fmt.Sprintf("prefix pattern %q, only lowercase letters, digits and %q are allowed", pattern, prefixPatternAny)
+		}
+	}
+
+	return ""
+}
+
+// declaredPrefixProblem returns why a @prefixedID prefix, or prefixPattern
+// when pattern is set, can't match the prefix of a gidx id
+func (r *Resolver) declaredPrefixProblem(prefix string, pattern bool) string {
+	if pattern {
+		return prefixPatternProblem(prefix)
+	}
+
+	return prefixProblem(prefix, r.wildcardPrefixes)
+}
@@ -0,0 +1,111 @@
+package graphapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+const patternTestSchema = `directive @prefixedID(prefix: String, prefixPattern: String) on OBJECT
+
+type LoadBalancer implements Node @key(fields: "id") @prefixedID(prefix: "loadbal") {
+	id: ID!
+}
+type LoadBalancerResource implements Node @key(fields: "id") @prefixedID(prefixPattern: "load...") {
+	id: ID!
+}
+type LoadBalancerPool implements Node @key(fields: "id") @prefixedID(prefixPattern: "load.pl") {
+	id: ID!
+}
+type Short implements Node @key(fields: "id") @prefixedID(prefixPattern: "sho..") {
+	id: ID!
+}
+type Dotted implements Node @key(fields: "id") @prefixedID(prefix: "dot....") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+func TestPrefixMatcherPatterns(t *testing.T) {
+	objs := map[string]*graphql.Object{}
+	prefixes := map[string]*graphql.Object{}
+
+	for _, prefix := range []string{"loadbal", "load...", "load.pl", "loadb.l", "lo*"} {
+		objs[prefix] = graphql.NewObject(graphql.ObjectConfig{Name: fmt.Sprintf("Object%d", len(objs)), Fields: graphql.Fields{}})
+		prefixes[prefix] = objs[prefix]
+	}
+
+	m := newPrefixMatcher(prefixes)
+
+	tests := []struct {
+		prefix   string
+		expected string
+		exact    bool
+	}{
+		{prefix: "loadbal", expected: "loadbal", exact: true},
+		{prefix: "loadbxl", expected: "loadb.l"},
+		{prefix: "loadxpl", expected: "load.pl"},
+		{prefix: "loadbpl", expected: "loadb.l"},
+		{prefix: "loadxyz", expected: "load..."},
+		{prefix: "loadxy", expected: "lo*"},
+		{prefix: "lotsabc", expected: "lo*"},
+	}
+
+	for _, tt := range tests {
+		obj, exact := m.match(tt.prefix)
+
+		assert.Same(t, objs[tt.expected], obj, tt.prefix)
+		assert.Equal(t, tt.exact, exact, tt.prefix)
+	}
+}
+
+func TestPrefixPatterns(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), patternTestSchema)
+	require.NoError(t, err)
+
+	// invalid patterns and prefixes that look like patterns are dropped
+	assert.NotContains(t, r.prefixMap, "sho..")
+	assert.NotContains(t, r.prefixMap, "dot....")
+
+	for id, expected := range map[gidx.PrefixedID]string{
+		"loadbal-abc": "LoadBalancer",
+		"loadxpl-abc": "LoadBalancerPool",
+		"loadxyz-abc": "LoadBalancerResource",
+	} {
+		node, err := r.GetNode(context.Background(), id)
+		require.NoError(t, err, id)
+		assert.Equal(t, expected, node.GraphType.Name(), id)
+	}
+
+	_, err = r.GetNode(context.Background(), "testsrv-abc")
+	assert.ErrorIs(t, err, ErrUnknownPrefix)
+
+	// ids matched by a pattern are still validated
+	_, err = r.parseID("loadXYZ-abc")
+	assert.Error(t, err)
+
+	sdl := r.SDL()
+	assert.Contains(t, sdl, "directive @prefixedID(prefix: String, prefixPattern: String) on OBJECT\n")
+	assert.Contains(t, sdl, `@prefixedID(prefixPattern: "load...")`)
+	assert.Contains(t, sdl, `@prefixedID(prefix: "loadbal")`)
+
+	// the sdl declares the same prefixes
+	again, err := NewResolver(zap.NewNop().Sugar(), sdl)
+	require.NoError(t, err)
+	assert.Equal(t, r.Prefixes(), again.Prefixes())
+
+	_, problems := CheckSchema([]SchemaSource{{Name: "schema.graphql", SDL: patternTestSchema}})
+	require.Len(t, problems, 2)
+	assert.Contains(t, problems[0].Message, `prefix pattern "sho.."`)
+	assert.Contains(t, problems[1].Message, `prefix "dot...."`)
+}
@@ -0,0 +1,176 @@
+package graphapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/audit"
+)
+
+const prefixTestSchema = `directive @prefixedID(prefix: String!) on OBJECT
+
+type Server implements Node @key(fields: "id") @prefixedID(prefix: "testsrv") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+func TestPrefixOf(t *testing.T) {
+	for _, id := range []gidx.PrefixedID{"testsrv-abc", "testsrv-abc-def", "testsrv-", "-abc", "testsrv", ""} {
+		assert.Equal(t, id.Prefix(), prefixOf(id), id)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema)
+	require.NoError(t, err)
+
+	for _, raw := range []string{"testsrv-abc", "testsrv-", "unknown-abc", "toolongprefix-abc", "BADPRFX-abc", "noid"} {
+		expected, expectedErr := gidx.Parse(raw)
+		id, err := r.parseID(raw)
+
+		assert.Equal(t, expected, id, raw)
+		assert.Equal(t, expectedErr, err, raw)
+	}
+}
+
+func TestParseIDNormalizes(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema, WithMaxIDLength(32))
+	require.NoError(t, err)
+
+	tests := []struct {
+		raw      string
+		expected gidx.PrefixedID
+		err      error
+	}{
+		{raw: "  testsrv-abc ", expected: "testsrv-abc"},
+		{raw: "\ttestsrv-abc\n", expected: "testsrv-abc"},
+		{raw: "", err: ErrEmptyID},
+		{raw: " \t ", err: ErrEmptyID},
+		{raw: " testsrv-abc\nforged", err: ErrIDInvalidCharacters},
+		{raw: strings.Repeat(" ", 33), err: ErrIDTooLong},
+	}
+
+	for _, tt := range tests {
+		id, err := r.parseID(tt.raw)
+
+		assert.Equal(t, tt.expected, id, tt.raw)
+		assert.Equal(t, tt.err, err, tt.raw)
+	}
+}
+
+func TestCheckID(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected error
+	}{
+		{raw: "testsrv-rXirlFQULBHDw9urtOjya"},
+		{raw: ""},
+		{raw: "testsrv-" + strings.Repeat("a", 24)},
+		{raw: "testsrv-" + strings.Repeat("a", 25), expected: ErrIDTooLong},
+		{raw: "testsrv-abc\nlevel=error", expected: ErrIDInvalidCharacters},
+		{raw: "testsrv-abc\x00", expected: ErrIDInvalidCharacters},
+		{raw: "testsrv-abc\x7f", expected: ErrIDInvalidCharacters},
+		{raw: "testsrv-\u0085", expected: ErrIDInvalidCharacters},
+		{raw: "testsrv-\u2028", expected: ErrIDInvalidCharacters},
+		{raw: "testsrv-\xff", expected: ErrIDInvalidCharacters},
+		{raw: "testsrv-ünïcode"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, checkID(tt.raw, 32), tt.raw)
+	}
+
+	assert.NoError(t, checkID(strings.Repeat("a", 1000), 0), "zero disables the length limit")
+}
+
+func TestRejectedIDs(t *testing.T) {
+	sink := &auditRecorder{}
+	auditor := audit.New(zap.NewNop().Sugar(), sink)
+	auditor.Start(context.Background())
+
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema, WithAuditor(auditor), WithMaxIDLength(32))
+	require.NoError(t, err)
+
+	long := "testsrv-" + strings.Repeat("a", 100)
+
+	_, err = r.parseID(long)
+	assert.ErrorIs(t, err, ErrIDTooLong)
+
+	result := r.execute(context.Background(), &postData{
+		Query:     `query($id: ID!) { node(id: $id) { id } }`,
+		Variables: map[string]interface{}{"id": "testsrv-abc\nforged"},
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, ErrIDInvalidCharacters.Error(), result.Errors[0].Message)
+	assert.Equal(t, map[string]interface{}{"code": "invalid_id"}, result.Errors[0].Extensions)
+
+	result = r.execute(context.Background(), &postData{
+		Query:     `query($r: [_Any!]!) { _entities(representations: $r) { __typename } }`,
+		Variables: map[string]interface{}{"r": []interface{}{map[string]interface{}{"__typename": "Node", "id": long}}},
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, ErrIDTooLong.Error(), result.Errors[0].Message)
+	assert.Equal(t, map[string]interface{}{"code": "invalid_id"}, result.Errors[0].Extensions)
+
+	input := r.resolveAPIPolicyInput([]string{long, "testsrv-abc"})
+	assert.Equal(t, []string{"testsrv-abc"}, input.IDs, "rejected ids aren't passed to the policy")
+
+	require.NoError(t, auditor.Close())
+
+	require.Len(t, sink.records, 2)
+
+	for _, record := range sink.records {
+		assert.Empty(t, record.ID, "rejected ids aren't audited")
+		assert.Equal(t, audit.OutcomeInvalidID, record.Outcome)
+	}
+}
+
+type auditRecorder struct {
+	records []audit.Record
+}
+
+func (s *auditRecorder) Emit(_ context.Context, records []audit.Record) error {
+	s.records = append(s.records, records...)
+
+	return nil
+}
+
+func (s *auditRecorder) Close() error {
+	return nil
+}
+
+func BenchmarkParseID(b *testing.B) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema)
+	require.NoError(b, err)
+
+	const raw = "testsrv-rXirlFQULBHDw9urtOjya"
+
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			id, _ := r.parseID(raw)
+			_ = r.prefixMap[prefixOf(id)]
+		}
+	})
+
+	b.Run("gidx", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			id, _ := gidx.Parse(raw)
+			_ = r.prefixMap[id.Prefix()]
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package graphapi
+
+import (
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"go.infratographer.com/x/gidx"
+)
+
+// prefixedIDScalarName is the name of the scalar of the id arguments of the
+// node queries with WithPrefixedIDScalar
+const prefixedIDScalarName = "PrefixedID"
+
+// prefixedIDScalar is the scalar of the id arguments of the node queries
+// with WithPrefixedIDScalar. Ids are trimmed when they're coerced, whether
+// they're given inline or as variables, and then validated by the resolvers
+// like every other id, so empty and malformed ids get the same coded errors
+// either way.
+var prefixedIDScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        prefixedIDScalarName,
+	Description: "The id of a node, a prefix of seven lowercase letters or digits followed by a dash and the rest of the id.",
+	Serialize: func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case gidx.PrefixedID:
+			return v.String()
+		case string:
+			return v
+		default:
+			return nil
+		}
+	},
+	ParseValue: func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return strings.TrimSpace(s)
+		}
+
+		return nil
+	},
+	ParseLiteral: func(v ast.Value) interface{} {
+		if s, ok := v.(*ast.StringValue); ok {
+			return strings.TrimSpace(s.Value)
+		}
+
+		return nil
+	},
+})
+
+// WithPrefixedIDScalar types the id arguments of the node and nodes queries
+// as the PrefixedID scalar rather than ID, documenting the ids they take in
+// the schema. Clients declaring their id variables as ID! must declare them
+// as PrefixedID! instead, so only enable it once they do.
+func WithPrefixedIDScalar() Option {
+	return func(r *Resolver) {
+		r.prefixedIDArgs = true
+	}
+}
+
+// idArgType returns the type of the id arguments of the node queries
+func (r *Resolver) idArgType() *graphql.Scalar {
+	if r.prefixedIDArgs {
+		return prefixedIDScalar
+	}
+
+	return graphql.ID
+}
@@ -0,0 +1,101 @@
+package graphapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPrefixedIDScalar(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema, WithPrefixedIDScalar())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		data      map[string]interface{}
+		message   string
+	}{
+		{
+			name:  "inline",
+			query: `{ node(id: " testsrv-abc ") { id } }`,
+			data:  map[string]interface{}{"node": map[string]interface{}{"id": "testsrv-abc"}},
+		},
+		{
+			name:      "variable",
+			query:     `query($id: PrefixedID!) { node(id: $id) { id } }`,
+			variables: map[string]interface{}{"id": " testsrv-abc "},
+			data:      map[string]interface{}{"node": map[string]interface{}{"id": "testsrv-abc"}},
+		},
+		{
+			name:      "list variable",
+			query:     `query($ids: [PrefixedID!]!) { nodes(ids: $ids) { id } }`,
+			variables: map[string]interface{}{"ids": []interface{}{"testsrv-abc\t"}},
+			data:      map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"id": "testsrv-abc"}}},
+		},
+		{
+			name:    "empty inline",
+			query:   `{ node(id: "  ") { id } }`,
+			data:    map[string]interface{}{"node": nil},
+			message: ErrEmptyID.Error(),
+		},
+		{
+			name:      "empty variable",
+			query:     `query($id: PrefixedID!) { node(id: $id) { id } }`,
+			variables: map[string]interface{}{"id": ""},
+			data:      map[string]interface{}{"node": nil},
+			message:   ErrEmptyID.Error(),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result := r.execute(context.Background(), &postData{Query: tt.query, Variables: tt.variables})
+			assert.Equal(t, tt.data, result.Data)
+
+			if tt.message == "" {
+				assert.Empty(t, result.Errors)
+
+				return
+			}
+
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, tt.message, result.Errors[0].Message)
+			assert.Equal(t, map[string]interface{}{"code": "invalid_id"}, result.Errors[0].Extensions)
+		})
+	}
+
+	// id variables must be declared with the scalar
+	result := r.execute(context.Background(), &postData{
+		Query:     `query($id: ID!) { node(id: $id) { id } }`,
+		Variables: map[string]interface{}{"id": "testsrv-abc"},
+	})
+	require.Len(t, result.Errors, 1)
+
+	sdl := r.SDL()
+	assert.Contains(t, sdl, "scalar PrefixedID\ntype PrefixMapping {\n  prefix: String!\n  type: String!\n  deprecated: Boolean!\n}\ntype Query {\n  node(id: PrefixedID!): Node\n  nodes(ids: [PrefixedID!]!): [Node]!\n  typePrefix(typename: String!): String\n  prefixes: [PrefixMapping!]!\n}\n")
+
+	again, err := NewResolver(zap.NewNop().Sugar(), sdl, WithPrefixedIDScalar())
+	require.NoError(t, err)
+	assert.Equal(t, sdl, again.SDL())
+}
+
+func TestPrefixedIDScalarDisabled(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema)
+	require.NoError(t, err)
+
+	assert.Contains(t, r.SDL(), "type Query {\n  node(id: ID!): Node\n  nodes(ids: [ID!]!): [Node]!\n  typePrefix(typename: String!): String\n  prefixes: [PrefixMapping!]!\n}\n")
+	assert.NotContains(t, r.SDL(), "PrefixedID!")
+
+	// ids are trimmed without the scalar as well
+	result := r.execute(context.Background(), &postData{
+		Query:     `query($id: ID!) { node(id: $id) { id } }`,
+		Variables: map[string]interface{}{"id": " testsrv-abc "},
+	})
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"id": "testsrv-abc"}}, result.Data)
+}
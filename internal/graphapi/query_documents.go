@@ -0,0 +1,60 @@
+package graphapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// cacheNameQuery is the cache of queries parsed before they're executed, for
+// the policy input and the response cache key
+const cacheNameQuery = "query"
+
+// queryDocument is a query parsed with gqlparser, along with its normalized
+// form. Queries that fail to parse are cached with their error so they're
+// not parsed again either.
+type queryDocument struct {
+	doc        *ast.QueryDocument
+	normalized string
+	err        error
+}
+
+// queryDocument returns query parsed by gqlparser, from the document cache
+// when it's been seen before. The document is shared between requests, so
+// it must not be modified.
+func (r *Resolver) queryDocument(query string) *queryDocument {
+	var key string
+
+	if r.documents != nil {
+		sum := sha256.Sum256([]byte(query))
+		key = cacheNameQuery + ":" + hex.EncodeToString(sum[:])
+
+		v, ok := r.documents.Get(key)
+		r.recordCacheLookup(cacheNameQuery, ok)
+
+		if ok {
+			return v.(*queryDocument)
+		}
+	}
+
+	qd := &queryDocument{}
+
+	qd.doc, qd.err = parser.ParseQuery(&ast.Source{Input: query})
+	if qd.err == nil {
+		var normalized strings.Builder
+
+		formatter.NewFormatter(&normalized).FormatQueryDocument(qd.doc)
+
+		qd.normalized = normalized.String()
+	}
+
+	if r.documents != nil {
+		r.documents.Set(key, "", qd)
+	}
+
+	return qd
+}
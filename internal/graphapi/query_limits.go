@@ -0,0 +1,114 @@
+package graphapi
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// ErrQueryTooComplex is returned for operations over one of the query limits
+var ErrQueryTooComplex = errors.New("query too complex")
+
+// QueryLimits bounds the operations executed, so a single request can't make
+// the resolver do an unbounded amount of work. A zero limit is disabled.
+type QueryLimits struct {
+	// MaxDepth is the most fields may be nested, root fields having a
+	// depth of 1
+	MaxDepth int
+	// MaxAliases is the most aliased fields an operation may select, such
+	// as many node fields each with its own alias
+	MaxAliases int
+	// MaxComplexity is the most fields an operation may select, counting
+	// the fields of a fragment every time it's spread
+	MaxComplexity int
+}
+
+// enabled reports whether any limit is set
+func (l QueryLimits) enabled() bool {
+	return l.MaxDepth > 0 || l.MaxAliases > 0 || l.MaxComplexity > 0
+}
+
+// WithQueryLimits rejects operations over limits with the query_too_complex
+// code before they're executed
+func WithQueryLimits(limits QueryLimits) Option {
+	return func(r *Resolver) {
+		r.queryLimits = limits
+	}
+}
+
+// checkQueryLimits returns an error when the operation name of doc is over
+// the query limits
+func (r *Resolver) checkQueryLimits(doc *ast.Document, name string) error {
+	if !r.queryLimits.enabled() {
+		return nil
+	}
+
+	op, fragments := findOperation(doc, name)
+	if op == nil {
+		return nil
+	}
+
+	c := &queryCost{limits: r.queryLimits, fragments: fragments}
+
+	return c.walk(op.SelectionSet, 1)
+}
+
+// queryCost counts the fields and aliases of an operation, stopping at the
+// first limit exceeded so documents spreading fragments many times over
+// aren't walked in full
+type queryCost struct {
+	limits    QueryLimits
+	fragments map[string]*ast.FragmentDefinition
+	fields    int
+	aliases   int
+}
+
+// walk counts the fields selected by set, at depth
+func (c *queryCost) walk(set *ast.SelectionSet, depth int) error {
+	if set == nil {
+		return nil
+	}
+
+	for _, sel := range set.Selections {
+		var err error
+
+		switch sel := sel.(type) {
+		case *ast.Field:
+			err = c.field(sel, depth)
+		case *ast.InlineFragment:
+			err = c.walk(sel.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			// validation rejects fragment cycles, so spreads always end
+			if frag, ok := c.fragments[sel.Name.Value]; ok {
+				err = c.walk(frag.SelectionSet, depth)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *queryCost) field(f *ast.Field, depth int) error {
+	if c.limits.MaxDepth > 0 && depth > c.limits.MaxDepth {
+		return fmt.Errorf("%w: fields are nested more than %d deep", ErrQueryTooComplex, c.limits.MaxDepth)
+	}
+
+	c.fields++
+	if c.limits.MaxComplexity > 0 && c.fields > c.limits.MaxComplexity {
+		return fmt.Errorf("%w: more than %d fields are selected", ErrQueryTooComplex, c.limits.MaxComplexity)
+	}
+
+	if f.Alias != nil && f.Alias.Value != f.Name.Value {
+		c.aliases++
+		if c.limits.MaxAliases > 0 && c.aliases > c.limits.MaxAliases {
+			return fmt.Errorf("%w: more than %d aliases are used", ErrQueryTooComplex, c.limits.MaxAliases)
+		}
+	}
+
+	return c.walk(f.SelectionSet, depth+1)
+}
@@ -0,0 +1,94 @@
+package graphapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestQueryLimits(t *testing.T) {
+	aliased := func(n int) string {
+		fields := make([]string, n)
+		for i := range fields {
+			fields[i] = fmt.Sprintf(`n%d: node(id: \"testusr-%d\") { id }`, i, i)
+		}
+
+		return `{"query":"{ ` + strings.Join(fields, " ") + ` }"}`
+	}
+
+	introspection, err := json.Marshal(map[string]string{"query": testutil.IntrospectionQuery})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		limits graphapi.QueryLimits
+		query  string
+		errMsg string
+	}{
+		{
+			name:   "aliases within limit",
+			limits: graphapi.QueryLimits{MaxAliases: 3},
+			query:  aliased(3),
+		},
+		{
+			name:   "too many aliases",
+			limits: graphapi.QueryLimits{MaxAliases: 3},
+			query:  aliased(4),
+			errMsg: "more than 3 aliases",
+		},
+		{
+			name:   "too many fields",
+			limits: graphapi.QueryLimits{MaxComplexity: 7},
+			query:  aliased(4),
+			errMsg: "more than 7 fields",
+		},
+		{
+			name:   "fragments counted every spread",
+			limits: graphapi.QueryLimits{MaxComplexity: 5},
+			query:  `{"query":"{ a: node(id: \"testusr-a\") { ...f } b: node(id: \"testusr-b\") { ...f } } fragment f on Node { id __typename }"}`,
+			errMsg: "more than 5 fields",
+		},
+		{
+			name:   "too deep",
+			limits: graphapi.QueryLimits{MaxDepth: 2},
+			query:  `{"query":"{ node(id: \"testusr-a\") { ... on User { id } __typename } __schema { types { fields { name } } } }"}`,
+			errMsg: "more than 2 deep",
+		},
+		{
+			name:   "full introspection within depth",
+			limits: graphapi.QueryLimits{MaxDepth: 13},
+			query:  string(introspection),
+		},
+		{
+			name:   "disabled",
+			limits: graphapi.QueryLimits{},
+			query:  aliased(100),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := testQuery(validTestSchema, tt.query, graphapi.WithQueryLimits(tt.limits))
+			require.NoError(t, err)
+
+			if tt.errMsg == "" {
+				assert.Empty(t, resp.Errors)
+
+				return
+			}
+
+			require.Len(t, resp.Errors, 1)
+			assert.Contains(t, resp.Errors[0].Message, tt.errMsg)
+			assert.Equal(t, string(errcode.QueryTooComplex), resp.Errors[0].Extensions[errcode.ExtensionKey])
+			assert.Equal(t, "null", resp.Data)
+		})
+	}
+}
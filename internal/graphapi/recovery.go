@@ -0,0 +1,164 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+// Extension keys of the ids reported with internal errors
+const (
+	requestIDExtension = "request_id"
+	traceIDExtension   = "trace_id"
+)
+
+type requestIDKey struct{}
+
+// withRequestID returns ctx carrying the id of the request, so errors
+// reported by resolvers can include it
+func withRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestID returns the id of the request set by echo's request id
+// middleware, falling back to the one sent by the client
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+// internalError is reported in place of a recovered panic. The panic value
+// may reveal internals, so it's only logged, and the error reports the
+// request and trace ids instead so it can be matched with the log entry.
+type internalError struct {
+	requestID string
+	traceID   string
+}
+
+func (e *internalError) Error() string {
+	return "internal error"
+}
+
+// Extensions returns the graphql error extensions reporting the code and ids
+func (e *internalError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{errcode.ExtensionKey: string(errcode.Internal)}
+
+	if e.requestID != "" {
+		ext[requestIDExtension] = e.requestID
+	}
+
+	if e.traceID != "" {
+		ext[traceIDExtension] = e.traceID
+	}
+
+	return ext
+}
+
+// recovered logs the panic rec with its stack trace, counts it and returns
+// the error reported for it
+func (r *Resolver) recovered(ctx context.Context, handler string, rec interface{}) *internalError {
+	err := &internalError{}
+	err.requestID, _ = ctx.Value(requestIDKey{}).(string)
+
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		err.traceID = sc.TraceID().String()
+	}
+
+	r.logger.Errorw("recovered from panic",
+		"handler", handler,
+		"panic", rec,
+		"request_id", err.requestID,
+		"trace_id", err.traceID,
+		"stack", string(debug.Stack()),
+	)
+
+	if r.metrics != nil {
+		r.metrics.Panic(handler)
+	}
+
+	return err
+}
+
+// recoverField converts unexpected panics in a field resolver into internal
+// errors. graphql-go executes queries on its own goroutine, where a panic it
+// can't report would crash the process, so resolvers can't rely on the
+// handler recovering. Panics already recovered by a nested resolver are
+// passed on unchanged for graphql-go to report.
+func (r *Resolver) recoverField(ctx context.Context) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+
+	if err, ok := rec.(error); ok {
+		var internal *internalError
+
+		if errors.As(err, &internal) {
+			panic(rec)
+		}
+	}
+
+	panic(r.recovered(ctx, "query", rec))
+}
+
+// recoverResolve returns resolve recovering from unexpected panics
+func (r *Resolver) recoverResolve(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		defer r.recoverField(p.Context)
+
+		return resolve(p)
+	}
+}
+
+// recoverResolveType returns resolve recovering from unexpected panics
+func (r *Resolver) recoverResolveType(resolve graphql.ResolveTypeFn) graphql.ResolveTypeFn {
+	return func(p graphql.ResolveTypeParams) *graphql.Object {
+		defer r.recoverField(p.Context)
+
+		return resolve(p)
+	}
+}
+
+// recoverGraphHandler recovers from panics in the graph handler, responding
+// with a 500 and an internal error. It must be deferred by the handler, and
+// err is set to the result of writing the response.
+func (r *Resolver) recoverGraphHandler(c echo.Context, err *error) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+
+	ctx := withRequestID(c.Request().Context(), requestID(c))
+	ierr := r.recovered(ctx, "query", rec)
+
+	// the response may have been partly written, in which case it can't be
+	// replaced and the client sees it cut short
+	if c.Response().Committed {
+		*err = nil
+
+		return
+	}
+
+	formatted := gqlerrors.NewFormattedError(ierr.Error())
+	formatted.Extensions = ierr.Extensions()
+
+	result := &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+	r.addInstanceExtension(result)
+
+	*err = r.writeJSON(c, http.StatusInternalServerError, result)
+}
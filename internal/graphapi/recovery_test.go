@@ -0,0 +1,122 @@
+package graphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+	"go.infratographer.com/node-resolver/internal/metrics"
+	"go.infratographer.com/node-resolver/internal/policy"
+)
+
+type panicCounter struct {
+	metrics.Nop
+
+	handlers map[string]int
+}
+
+func (c *panicCounter) Panic(handler string) { c.handlers[handler]++ }
+
+type panicPolicy struct{}
+
+func (panicPolicy) Evaluate(_ context.Context, _ policy.Input) (policy.Decision, error) {
+	panic("policy exploded")
+}
+
+type panicAuthorizer struct{}
+
+func (panicAuthorizer) CanResolve(_ context.Context, _ string, _ gidx.PrefixedID) error {
+	panic("authorizer exploded")
+}
+
+func TestPanicRecovery(t *testing.T) {
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{1}})
+
+	testCases := []struct {
+		TestName       string
+		query          string
+		opts           []graphapi.Option
+		expectedStatus int
+		expectedData   string
+		expectedErrors []queryError
+		expectedPanics int
+	}{
+		{
+			TestName:       "resolver panic",
+			query:          `{"query": "{ node(id: \"testsrv-abc\") { id } }"}`,
+			opts:           []graphapi.Option{graphapi.WithAuthorizer(panicAuthorizer{})},
+			expectedStatus: http.StatusOK,
+			expectedData:   `{"node":null}`,
+			expectedErrors: []queryError{{
+				Message:    "internal error",
+				Extensions: map[string]interface{}{"code": "internal", "request_id": "req-1", "trace_id": traceID.String()},
+			}},
+			expectedPanics: 1,
+		},
+		{
+			TestName:       "failed entities aren't reported as panics",
+			query:          `{"query": "query($r: [_Any!]!) { _entities(representations: $r) { __typename } }", "variables": {"r": [{"__typename": "Missing", "id": "testsrv-abc"}]}}`,
+			expectedStatus: http.StatusOK,
+			expectedData:   `{"_entities":[null]}`,
+			expectedErrors: []queryError{{
+				Message:    "Missing is an unknown interface type",
+				Extensions: map[string]interface{}{"code": "unknown_interface"},
+			}},
+		},
+		{
+			TestName:       "handler panic",
+			query:          `{"query": "{ node(id: \"testsrv-abc\") { id } }"}`,
+			opts:           []graphapi.Option{graphapi.WithPolicy(panicPolicy{})},
+			expectedStatus: http.StatusInternalServerError,
+			expectedData:   `null`,
+			expectedErrors: []queryError{{
+				Message:    "internal error",
+				Extensions: map[string]interface{}{"code": "internal", "request_id": "req-1", "trace_id": traceID.String()},
+			}},
+			expectedPanics: 1,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			counter := &panicCounter{handlers: map[string]int{}}
+
+			r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, append(tt.opts, graphapi.WithMetrics(counter))...)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(tt.query))
+			req = req.WithContext(trace.ContextWithSpanContext(req.Context(), spanCtx))
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.Response().Header().Set(echo.HeaderXRequestID, "req-1")
+
+			require.NoError(t, r.GraphHandler(c))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			var resp queryResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+			assert.JSONEq(t, tt.expectedData, string(resp.RawData))
+			require.Len(t, resp.Errors, len(tt.expectedErrors))
+
+			for i, expected := range tt.expectedErrors {
+				assert.Equal(t, expected.Message, resp.Errors[i].Message)
+				assert.Equal(t, expected.Extensions, resp.Errors[i].Extensions)
+			}
+
+			assert.Equal(t, tt.expectedPanics, counter.handlers["query"])
+		})
+	}
+}
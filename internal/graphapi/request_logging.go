@@ -0,0 +1,90 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+// RequestLogging controls how much of each graphql request is logged
+type RequestLogging string
+
+// Request logging modes
+const (
+	// RequestLoggingNone doesn't log requests
+	RequestLoggingNone RequestLogging = "none"
+	// RequestLoggingSummary logs the operation name, the size and a hash of
+	// the query and the variable names, but never their values
+	RequestLoggingSummary RequestLogging = "summary"
+	// RequestLoggingFull logs the complete query and variables. Variables
+	// may contain sensitive values and large batches make for huge log
+	// lines, so this is only meant for debugging.
+	RequestLoggingFull RequestLogging = "full"
+)
+
+// ErrInvalidRequestLogging is returned when parsing an unknown request logging mode
+var ErrInvalidRequestLogging = errors.New("invalid request logging mode")
+
+// ParseRequestLogging returns the RequestLogging named s
+func ParseRequestLogging(s string) (RequestLogging, error) {
+	switch l := RequestLogging(s); l {
+	case RequestLoggingNone, RequestLoggingSummary, RequestLoggingFull:
+		return l, nil
+	default:
+		return "", fmt.Errorf("%w: %q, expected none, summary or full", ErrInvalidRequestLogging, s)
+	}
+}
+
+// WithRequestLogging sets how much of each graphql request is logged,
+// defaulting to RequestLoggingSummary
+func WithRequestLogging(l RequestLogging) Option {
+	return func(r *Resolver) {
+		r.requestLogging = l
+	}
+}
+
+// logRequest logs p according to the configured request logging mode, along
+// with the subject and issuer of the request's jwt when it was authenticated
+func (r *Resolver) logRequest(ctx context.Context, p *postData) {
+	var fields []interface{}
+
+	switch r.requestLogging {
+	case RequestLoggingFull:
+		fields = []interface{}{"postData.Query", p.Query, "postData.Operation", p.Operation, "postdata.Variables", p.Variables}
+	case RequestLoggingSummary:
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(p.Query))
+
+		variables := make([]string, 0, len(p.Variables))
+		for name := range p.Variables {
+			variables = append(variables, name)
+		}
+
+		sort.Strings(variables)
+
+		fields = []interface{}{
+			"operation", p.Operation,
+			"query_hash", strconv.FormatUint(h.Sum64(), 16),
+			"query_bytes", len(p.Query),
+			"variables", variables,
+		}
+	default:
+		return
+	}
+
+	if subject := authz.Subject(ctx); subject != "" {
+		fields = append(fields, "subject", subject)
+	}
+
+	if issuer := oidc.Issuer(ctx); issuer != "" {
+		fields = append(fields, "issuer", issuer)
+	}
+
+	r.logger.Infow("request info", fields...)
+}
@@ -0,0 +1,110 @@
+package graphapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/echojwtx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+func TestParseRequestLogging(t *testing.T) {
+	for _, s := range []string{"none", "summary", "full"} {
+		l, err := ParseRequestLogging(s)
+		require.NoError(t, err)
+		assert.Equal(t, RequestLogging(s), l)
+	}
+
+	_, err := ParseRequestLogging("debug")
+	assert.ErrorIs(t, err, ErrInvalidRequestLogging)
+}
+
+func TestLogRequest(t *testing.T) {
+	p := &postData{
+		Query:     `query Node($id: ID!, $b: String) { node(id: $id) { id } }`,
+		Operation: "Node",
+		Variables: map[string]interface{}{"id": "testsrv-secret", "b": "value"},
+	}
+
+	authenticated := oidc.WithIssuer(context.WithValue(context.Background(), echojwtx.ActorCtxKey, "idntusr-abc"), "https://issuer.example.com/")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		opts     []Option
+		expected map[string]interface{}
+	}{
+		{
+			name: "default",
+			expected: map[string]interface{}{
+				"operation":   "Node",
+				"query_hash":  "",
+				"query_bytes": int64(len(p.Query)),
+				"variables":   []interface{}{"b", "id"},
+			},
+		},
+		{
+			name: "authenticated",
+			ctx:  authenticated,
+			expected: map[string]interface{}{
+				"operation":   "Node",
+				"query_hash":  "",
+				"query_bytes": int64(len(p.Query)),
+				"variables":   []interface{}{"b", "id"},
+				"subject":     "idntusr-abc",
+				"issuer":      "https://issuer.example.com/",
+			},
+		},
+		{
+			name: "full",
+			opts: []Option{WithRequestLogging(RequestLoggingFull)},
+			expected: map[string]interface{}{
+				"postData.Query":     p.Query,
+				"postData.Operation": "Node",
+				"postdata.Variables": p.Variables,
+			},
+		},
+		{
+			name: "none",
+			ctx:  authenticated,
+			opts: []Option{WithRequestLogging(RequestLoggingNone)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.InfoLevel)
+
+			r, err := NewResolver(zap.New(core).Sugar(), prefixTestSchema, tt.opts...)
+			require.NoError(t, err)
+
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			r.logRequest(ctx, p)
+
+			if tt.expected == nil {
+				assert.Zero(t, logs.Len())
+				return
+			}
+
+			require.Equal(t, 1, logs.Len())
+
+			fields := logs.All()[0].ContextMap()
+			if _, ok := tt.expected["query_hash"]; ok {
+				assert.NotEmpty(t, fields["query_hash"])
+				fields["query_hash"] = ""
+			}
+
+			assert.Equal(t, tt.expected, fields)
+		})
+	}
+}
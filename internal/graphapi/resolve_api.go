@@ -0,0 +1,290 @@
+package graphapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/policy"
+)
+
+// ResolveAPIVersion is the version of the /api/v1/resolve contract
+const ResolveAPIVersion = "v1"
+
+// MaxResolveIDs is the maximum number of ids accepted in a single resolve request
+const MaxResolveIDs = 100
+
+// ResolveAPISchema is the JSON schema of the /api/v1/resolve request and
+// response bodies
+//
+//go:embed api/v1/resolve.schema.json
+var ResolveAPISchema []byte
+
+// ResolveRequest is the body of POST /api/v1/resolve
+type ResolveRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// ResolveError describes why a request or id couldn't be resolved
+type ResolveError struct {
+	Code    errcode.Code `json:"code"`
+	Message string       `json:"message"`
+	// DeletedAt is when a deleted id was deleted, when known
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Detail is the original message of masked or localized errors, with
+	// verbose errors
+	Detail string `json:"detail,omitempty"`
+}
+
+// ResolveResult is the outcome of resolving a single id
+type ResolveResult struct {
+	ID         string        `json:"id"`
+	Resolved   bool          `json:"resolved"`
+	Prefix     string        `json:"prefix,omitempty"`
+	Type       string        `json:"type,omitempty"`
+	Interfaces []string      `json:"interfaces,omitempty"`
+	Service    *Service      `json:"service,omitempty"`
+	Error      *ResolveError `json:"error,omitempty"`
+}
+
+// ResolveResponse is the body returned by /api/v1/resolve. Results are in
+// the same order as the requested ids.
+type ResolveResponse struct {
+	APIVersion  string                 `json:"apiVersion"`
+	Results     []ResolveResult        `json:"results,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+	Error       *ResolveError          `json:"error,omitempty"`
+}
+
+func (r *Resolver) resolveAPIRoutes(e *echo.Group, current resolverFunc) {
+	e.GET("/api/v1/resolve", current.serve((*Resolver).resolveAPIGetHandler), r.middleware...)
+	e.POST("/api/v1/resolve", current.serve((*Resolver).resolveAPIPostHandler), r.middleware...)
+	e.GET("/api/v1/schema.json", func(c echo.Context) error {
+		return writeCacheable(c, schemaCacheControl, "application/schema+json", time.Time{}, ResolveAPISchema)
+	})
+}
+
+// resolveAPIGetHandler resolves the ids given as repeated id query parameters
+func (r *Resolver) resolveAPIGetHandler(c echo.Context) error {
+	r.setInstanceHeaders(c)
+
+	return r.resolveAPI(c, c.QueryParams()["id"])
+}
+
+func (r *Resolver) resolveAPIPostHandler(c echo.Context) error {
+	r.setInstanceHeaders(c)
+
+	var req ResolveRequest
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return r.resolveAPIError(c, "invalid request body")
+	}
+
+	return r.resolveAPI(c, req.IDs)
+}
+
+func (r *Resolver) resolveAPI(c echo.Context, ids []string) error {
+	defer r.observeRequest(auditOperationResolve, time.Now())
+
+	switch {
+	case len(ids) == 0:
+		return r.resolveAPIError(c, "at least one id is required")
+	case len(ids) > MaxResolveIDs:
+		return r.resolveAPIError(c, fmt.Sprintf("at most %d ids can be resolved at once", MaxResolveIDs))
+	}
+
+	annotations, err := r.evaluatePolicy(c.Request().Context(), r.resolveAPIPolicyInput(ids))
+	if err != nil {
+		resp := ResolveResponse{
+			APIVersion: ResolveAPIVersion,
+			Error:      &ResolveError{Code: errcode.Denied, Message: err.Error()},
+		}
+		r.localizeResolveResponse(c, &resp)
+
+		return r.writeJSON(c, http.StatusForbidden, resp)
+	}
+
+	key, cacheable := r.responseCacheKey(c.Request().Context(), auditOperationResolve, ids)
+	if cacheable {
+		if body, ok := r.cachedResponse(key); ok {
+			return r.writeResolveAPIResponse(c, r.cacheControl(), body)
+		}
+	}
+
+	resp := ResolveResponse{
+		APIVersion:  ResolveAPIVersion,
+		Results:     make([]ResolveResult, len(ids)),
+		Annotations: annotations,
+	}
+
+	if !r.resolveResults(c.Request().Context(), ids, resp.Results) {
+		cacheable = false
+	}
+
+	r.localizeResolveResponse(c, &resp)
+
+	body, err := encodeJSON(resp)
+	if err != nil {
+		return err
+	}
+
+	if !cacheable {
+		return r.writeResolveAPIResponse(c, "no-cache", body)
+	}
+
+	r.cacheResponse(key, ids, body)
+
+	return r.writeResolveAPIResponse(c, r.cacheControl(), body)
+}
+
+// writeResolveAPIResponse writes a successful response, with caching
+// headers for GET requests
+func (r *Resolver) writeResolveAPIResponse(c echo.Context, cacheControl string, body []byte) error {
+	if c.Request().Method != http.MethodGet {
+		return c.JSONBlob(http.StatusOK, body)
+	}
+
+	return writeCacheable(c, cacheControl, echo.MIMEApplicationJSONCharsetUTF8, r.lastModified(), body)
+}
+
+// ResolveIDs resolves ids like the resolve api, for callers serving it over
+// another transport. ctx carries the caller's identity, as populated by
+// Authenticate. Errors of the request as a whole, such as too many ids or a
+// policy denial, are returned with their code; the errors of single ids are
+// in their result.
+func (r *Resolver) ResolveIDs(ctx context.Context, ids []string) ([]ResolveResult, error) {
+	defer r.observeRequest(auditOperationResolve, time.Now())
+
+	switch {
+	case len(ids) == 0:
+		return nil, errcode.New(errcode.InvalidRequest, errors.New("at least one id is required"))
+	case len(ids) > MaxResolveIDs:
+		return nil, errcode.New(errcode.InvalidRequest, fmt.Errorf("at most %d ids can be resolved at once", MaxResolveIDs))
+	}
+
+	if _, err := r.evaluatePolicy(ctx, r.resolveAPIPolicyInput(ids)); err != nil {
+		return nil, errcode.New(errcode.Denied, err)
+	}
+
+	results := make([]ResolveResult, len(ids))
+	r.resolveResults(ctx, ids, results)
+	r.maskResolveResults(results)
+
+	return results, nil
+}
+
+// resolveResults resolves ids into results, reporting whether all of them
+// resolved
+func (r *Resolver) resolveResults(ctx context.Context, ids []string, results []ResolveResult) bool {
+	failed := false
+
+	for i, id := range ids {
+		results[i] = r.resolveAPIResult(ctx, id)
+
+		if results[i].Error != nil {
+			failed = true
+		}
+	}
+
+	r.recordRequest(auditOperationResolve, !failed)
+
+	return !failed
+}
+
+func (r *Resolver) resolveAPIResult(ctx context.Context, rawID string) ResolveResult {
+	result := ResolveResult{ID: rawID}
+
+	id, err := r.parseID(rawID)
+	if err != nil {
+		r.recordResolution(ctx, auditOperationResolve, rawID, "", err)
+
+		result.Error = resolveErrorFor(err)
+
+		return result
+	}
+
+	result.Prefix = prefixOf(id)
+
+	node, err := r.resolveNode(ctx, auditOperationResolve, id)
+	if err != nil {
+		result.Error = resolveErrorFor(err)
+
+		// deleted ids still report their type, so stale references can be
+		// attributed
+		if errors.Is(err, authz.ErrDeleted) {
+			if obj := r.objectForRequest(ctx, result.Prefix); obj != nil {
+				result.Type = obj.Name()
+			}
+		}
+
+		return result
+	}
+
+	result.Resolved = true
+	result.Type = node.GraphType.Name()
+	result.Interfaces = make([]string, len(node.GraphType.Interfaces()))
+
+	for i, iface := range node.GraphType.Interfaces() {
+		result.Interfaces[i] = iface.Name()
+	}
+
+	sort.Strings(result.Interfaces)
+
+	result.Service = r.services[result.Type]
+
+	return result
+}
+
+func (r *Resolver) resolveAPIPolicyInput(ids []string) policy.Input {
+	c := &idCollector{ids: map[string]bool{}, prefixes: map[string]bool{}, maxIDLength: r.maxIDLength}
+
+	for _, id := range ids {
+		c.add(id)
+	}
+
+	return policy.Input{
+		Operation: auditOperationResolve,
+		Fields:    []string{auditOperationResolve},
+		IDs:       sortedKeys(c.ids),
+		Prefixes:  sortedKeys(c.prefixes),
+	}
+}
+
+func resolveErrorFor(err error) *ResolveError {
+	var deleted *authz.DeletedError
+
+	switch code := errorCode(err); code {
+	case errcode.InvalidID, errcode.UnknownPrefix, errcode.Unauthorized, errcode.NotFound:
+		return &ResolveError{Code: code, Message: err.Error()}
+	case errcode.Deleted:
+		resolveErr := &ResolveError{Code: code, Message: err.Error()}
+
+		if errors.As(err, &deleted) && !deleted.DeletedAt.IsZero() {
+			deletedAt := deleted.DeletedAt.UTC()
+			resolveErr.DeletedAt = &deletedAt
+		}
+
+		return resolveErr
+	default:
+		return &ResolveError{Code: errcode.Internal, Message: "internal error"}
+	}
+}
+
+func (r *Resolver) resolveAPIError(c echo.Context, msg string) error {
+	resp := ResolveResponse{
+		APIVersion: ResolveAPIVersion,
+		Error:      &ResolveError{Code: errcode.InvalidRequest, Message: msg},
+	}
+	r.localizeResolveResponse(c, &resp)
+
+	return r.writeJSON(c, http.StatusBadRequest, resp)
+}
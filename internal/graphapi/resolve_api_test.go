@@ -0,0 +1,67 @@
+package graphapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+type resolveAPIContract struct {
+	Method   string          `json:"method"`
+	Target   string          `json:"target"`
+	Body     json.RawMessage `json:"body"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// TestResolveAPIContract ensures the responses of the stable resolve api
+// don't change. The contracts in testdata must only ever gain fields.
+func TestResolveAPIContract(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema,
+		graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testtkn"}),
+	)
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	files, err := filepath.Glob("testdata/api/v1/*.json")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			b, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			var contract resolveAPIContract
+			require.NoError(t, json.Unmarshal(b, &contract))
+
+			req := httptest.NewRequest(contract.Method, contract.Target, bytes.NewReader(contract.Body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, contract.Status, rec.Code)
+			assert.JSONEq(t, string(contract.Response), rec.Body.String())
+		})
+	}
+}
+
+func TestResolveAPISchema(t *testing.T) {
+	var schema map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(graphapi.ResolveAPISchema, &schema))
+	assert.Contains(t, schema, "$defs")
+}
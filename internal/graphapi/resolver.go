@@ -1,18 +1,32 @@
 package graphapi
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"github.com/labstack/echo/v4"
 	"github.com/vektah/gqlparser/v2/ast"
-	"github.com/vektah/gqlparser/v2/parser"
 	"go.infratographer.com/x/gidx"
-
 	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/audit"
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/backend"
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/chaos"
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/featureflags"
+	"go.infratographer.com/node-resolver/internal/metrics"
+	"go.infratographer.com/node-resolver/internal/policy"
+	"go.infratographer.com/node-resolver/internal/shed"
 )
 
 type ErrInvalidSchema struct {
@@ -27,23 +41,137 @@ func newInvalidSchemaError(s string) error {
 	return ErrInvalidSchema{message: s}
 }
 
-// Resolver provides a graph response resolver
+// Resolver provides a graph response resolver.
+//
+// A Resolver is safe for concurrent use once NewResolver returns. The schema
+// and every type in it are built before NewResolver returns and, like the
+// options, never modified after, state shared between requests such as the
+// caches is synchronized, and the SDL, built on first use, is guarded so
+// it's only built once. Use a Handler to replace the resolver while serving
+// rather than modifying it.
 type Resolver struct {
-	logger        *zap.SugaredLogger
-	schemaDoc     *ast.SchemaDocument
-	prefixMap     map[string]*graphql.Object
-	interfaceMap  map[string]*graphql.Interface
-	scalars       map[string]*graphql.Scalar
-	handlerSchema graphql.Schema
-	entities      *graphql.Union
+	logger         *zap.SugaredLogger
+	schemaDoc      *ast.SchemaDocument
+	prefixMap      map[string]*graphql.Object
+	objects        []*graphql.Object
+	interfaceMap   map[string]*graphql.Interface
+	scalars        map[string]*graphql.Scalar
+	handlerSchema  graphql.Schema
+	entities       *graphql.Union
+	graphTypes     []graphql.Type
+	authorizer     authz.Authorizer
+	nodeBackend    backend.NodeBackend
+	auditor        *audit.Auditor
+	metrics        metrics.Sink
+	policy         policy.Evaluator
+	shedder        *shed.Shedder
+	responses      *cache.Cache
+	invalidator    CacheInvalidator
+	documents      *cache.Cache
+	entityPool     *entityPool
+	maxIDLength    int
+	requestLogging RequestLogging
+	instanceID     string
+	prefixes       *prefixMatcher
+	middleware     []echo.MiddlewareFunc
+	opts           []Option
+
+	// sdl and schemaChecksum are built on first use, guarded by sdlOnce.
+	// The checksum identifies the schema in cache keys.
+	sdlOnce        sync.Once
+	sdl            string
+	schemaChecksum string
+	// loadedAt is when the schema was loaded, used as the Last-Modified time
+	// of responses that only depend on the schema
+	loadedAt time.Time
+	// wildcardPrefixes is set when prefixes ending in a wildcard are matched
+	// by prefixes rather than only exactly
+	wildcardPrefixes bool
+	// strictPrefixes is set when schemas declaring a prefix on several types
+	// are rejected
+	strictPrefixes bool
+	// strictSchema is set when schemas with types missing a @prefixedID
+	// prefix or declaring an invalid one are rejected
+	strictSchema bool
+	// documentsConfigured is set when the document cache was configured by
+	// an option, otherwise the resolver uses its own default cache
+	documentsConfigured bool
+	// persistedQueries are the queries registered by automatic persisted
+	// queries, keyed by hash
+	persistedQueries *cache.Cache
+	// entityPoolConfigured is set when the entity pool was configured by an
+	// option, otherwise the resolver uses its own default pool
+	entityPoolConfigured bool
+	// canonical is set when responses are written in canonical form
+	canonical bool
+	// strictRequests is set when requests with unknown or duplicate fields
+	// are rejected
+	strictRequests bool
+	// operationSelection selects the operation of documents with several
+	// operations when the request doesn't name one
+	operationSelection OperationSelection
+	// requestTimeout is how long lookups made by a graphql request may take
+	// in total, and lookupFloor and lookupCeiling bound the timeouts of each
+	// lookup derived from it
+	requestTimeout time.Duration
+	lookupFloor    time.Duration
+	lookupCeiling  time.Duration
+	// breakerFallback is set when ids are resolved by prefix alone while
+	// the authorizer's circuit breaker is open
+	breakerFallback bool
+	// shadow compares results with those of a candidate schema
+	shadow *Shadow
+	// featureFlags gates the resolution of prefixes
+	featureFlags *featureflags.Gate
+	// errorCatalog localizes error messages, errorMask masks them and
+	// verboseErrors keeps the original messages in their details
+	errorCatalog  *errcode.Catalog
+	errorMask     *errcode.Catalog
+	verboseErrors bool
+	// unknownPrefixes logs recent requests for ids with unknown prefixes
+	unknownPrefixes *UnknownPrefixLog
+	// unknownPrefixType is the type ids with unknown prefixes resolve to,
+	// called unknownPrefixName, when configured
+	unknownPrefixName string
+	unknownPrefixType *graphql.Object
+	// chaos injects faults for testing gateways
+	chaos *chaos.Injector
+	// sourceClient looks up the fields of types with a @source directive,
+	// and sources are the subgraphs of those types by type name
+	sourceClient *http.Client
+	sources      map[string]*typeSource
+	// introspectionDisabled is set when only clients in the
+	// introspectionAllowed networks may introspect the schema
+	introspectionDisabled bool
+	introspectionAllowed  []*net.IPNet
+	queryLimits           QueryLimits
+	// prefixPatterns is set when a type declares a @prefixedID
+	// prefixPattern, which are matched along with wildcard prefixes
+	prefixPatterns bool
+	// keyLookup looks up the ids of entities represented by a key other
+	// than their id, and entityKeys are those keys by type name
+	keyLookup  backend.KeyLookup
+	entityKeys map[string]*typeKeys
+	// services are the services owning the nodes of types declaring a
+	// @service directive, by type name
+	services map[string]*Service
+	// prefixedIDArgs is set when the id arguments of the node queries are
+	// typed as the PrefixedID scalar
+	prefixedIDArgs bool
+	// deprecatedPrefixes are the prefixes types used to have, by prefix
+	deprecatedPrefixes map[string]*deprecatedPrefix
 }
 
 // NewResolver returns a resolver configured with the given logger
-func NewResolver(logger *zap.SugaredLogger, rawSchema string) (*Resolver, error) {
+func NewResolver(logger *zap.SugaredLogger, rawSchema string, opts ...Option) (*Resolver, error) {
 	r := &Resolver{
-		logger:       logger,
-		prefixMap:    map[string]*graphql.Object{},
-		interfaceMap: map[string]*graphql.Interface{},
+		logger:             logger,
+		prefixMap:          map[string]*graphql.Object{},
+		interfaceMap:       map[string]*graphql.Interface{},
+		maxIDLength:        DefaultMaxIDLength,
+		requestLogging:     RequestLoggingSummary,
+		operationSelection: OperationSelectionError,
+		lookupFloor:        DefaultLookupTimeoutFloor,
 		scalars: map[string]*graphql.Scalar{
 			"_Any": {
 				PrivateName: "_Any",
@@ -51,14 +179,46 @@ func NewResolver(logger *zap.SugaredLogger, rawSchema string) (*Resolver, error)
 		},
 	}
 
-	schema, err := parser.ParseSchemas(&ast.Source{
-		Input: rawSchema,
-	})
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.opts = opts
+
+	// the scalar is declared by the SDL, so it's never added as a custom
+	// scalar of the schema
+	if r.prefixedIDArgs {
+		r.scalars[prefixedIDScalarName] = prefixedIDScalar
+	}
+
+	if !r.documentsConfigured {
+		r.documents = newDocumentCache(defaultDocumentCacheSize)
+	}
+
+	if !r.entityPoolConfigured {
+		r.entityPool = newEntityPool(defaultEntityWorkers, defaultEntityWorkers)
+	}
+
+	schema, err := parseSchema(rawSchema)
 	if err != nil {
 		return nil, err
 	}
 
 	r.schemaDoc = schema
+	r.readServices()
+
+	if r.sourceClient != nil {
+		r.addSourceScalars()
+	}
+
+	// unprefixed lists the types without a valid prefix, reported together
+	// with a strict schema
+	unprefixed := []string{}
+
+	// deprecated are the deprecated prefixes of the types, added once every
+	// prefix is known
+	deprecated := []pendingDeprecation{}
+
 	for _, obj := range r.schemaDoc.Definitions {
 		if len(obj.Interfaces) == 0 {
 			// this definition isn't a object that has interfaces, skip it
@@ -80,26 +240,113 @@ func NewResolver(logger *zap.SugaredLogger, rawSchema string) (*Resolver, error)
 		pd := obj.Directives.ForName("prefixedID")
 		if pd == nil {
 			logger.Warnw("missing @prefixedID directive", "graphql_type", obj.Name)
+			unprefixed = append(unprefixed, obj.Name+" has no @prefixedID directive")
+
 			continue
 		}
 
-		pa := pd.Arguments.ForName("prefix")
-		if pa == nil {
+		prefix, pattern, ok := directivePrefix(pd)
+		if !ok {
 			logger.Warnw("missing prefix on @prefixedID directive", "graphql_type", obj.Name)
+			unprefixed = append(unprefixed, obj.Name+" has no prefix on its @prefixedID directive")
+
 			continue
 		}
 
-		prefix := pa.Value.String()
-		// This value has the quotes in it, so we need to strip those
-		prefix = strings.Trim(prefix, `"`)
+		if r.wildcardPrefixes && strings.Contains(prefix, prefixWildcard) && !isWildcardPrefix(prefix) {
+			logger.Warnw("invalid wildcard prefix on @prefixedID directive", "graphql_type", obj.Name, "prefix", prefix)
+			continue
+		}
+
+		// a prefix can't be mistaken for a pattern
+		if !pattern && isPrefixPattern(prefix) {
+			logger.Warnw("invalid prefix on @prefixedID directive, patterns are declared with prefixPattern", "graphql_type", obj.Name, "prefix", prefix)
+			unprefixed = append(unprefixed, obj.Name+" has an invalid "+prefixProblem(prefix, r.wildcardPrefixes))
+
+			continue
+		}
 
-		r.prefixMap[prefix] = r.graphTypeFor(obj.Name, prefix, ifaces)
+		// ids with an invalid prefix never parse, the type is kept so the
+		// schema still loads but the mistake is reported now rather than
+		// when its ids fail to resolve
+		if problem := r.declaredPrefixProblem(prefix, pattern); problem != "" {
+			logger.Warnw("invalid prefix on @prefixedID directive", "graphql_type", obj.Name, "prefix", prefix, "problem", problem)
+			unprefixed = append(unprefixed, obj.Name+" has an invalid "+problem)
+
+			// invalid patterns are dropped, since they might match
+			// anything from nothing to a wildcard
+			if pattern {
+				continue
+			}
+		}
+
+		if pattern {
+			r.prefixPatterns = true
+		}
+
+		if prev, ok := r.prefixMap[prefix]; ok && prev.Name() != obj.Name {
+			if r.strictPrefixes {
+				return nil, fmt.Errorf("%w: %s is declared by %s and %s", ErrDuplicatePrefix, prefix, prev.Name(), obj.Name)
+			}
+
+			logger.Warnw("duplicate prefix on @prefixedID directive, the last type declaring it is used", "prefix", prefix, "graphql_type", obj.Name, "shadowed_type", prev.Name())
+		}
+
+		gt := r.graphTypeFor(obj.Name, ifaces)
+
+		if r.keyLookup != nil {
+			r.addEntityKeys(gt, obj)
+		}
+
+		if r.sourceClient != nil {
+			r.addSourcedFields(gt, obj)
+		}
+
+		r.prefixMap[prefix] = gt
+
+		if !pattern {
+			for _, old := range deprecatedPrefixesOf(pd) {
+				deprecated = append(deprecated, pendingDeprecation{prefix: old, replacement: prefix, typeName: obj.Name})
+			}
+		}
+	}
+
+	if err := r.addDeprecatedPrefixes(deprecated); err != nil {
+		return nil, err
+	}
+
+	if r.strictSchema && len(unprefixed) != 0 {
+		return nil, newInvalidSchemaError("types without a valid prefix: " + strings.Join(unprefixed, ", "))
 	}
 
 	if len(r.prefixMap) == 0 {
 		return nil, newInvalidSchemaError("schema has no valid objet types")
 	}
 
+	if r.wildcardPrefixes || r.prefixPatterns {
+		r.prefixes = newPrefixMatcher(r.prefixMap)
+	}
+
+	if err := r.addUnknownPrefixType(); err != nil {
+		return nil, err
+	}
+
+	r.objects = make([]*graphql.Object, 0, len(r.prefixMap)+1)
+	for _, obj := range r.prefixMap {
+		r.objects = append(r.objects, obj)
+	}
+
+	if r.unknownPrefixType != nil {
+		r.objects = append(r.objects, r.unknownPrefixType)
+	}
+
+	sort.Slice(r.objects, func(i, j int) bool { return r.objects[i].Name() < r.objects[j].Name() })
+
+	// every type is built before the schema, so nothing is built lazily
+	// once the resolver is shared between requests
+	r.entities = r.newEntitiesUnion()
+	r.graphTypes = r.buildGraphTypes()
+
 	q, err := r.Query()
 	if err != nil {
 		return nil, err
@@ -113,35 +360,78 @@ func NewResolver(logger *zap.SugaredLogger, rawSchema string) (*Resolver, error)
 		return nil, err
 	}
 
+	r.initPossibleTypes()
+
+	r.loadedAt = time.Now()
+
+	if r.responses != nil {
+		r.disableUncacheableResponses()
+	}
+
 	return r, nil
 }
 
-func (r *Resolver) graphTypeFor(name string, prefix string, interfaces []*graphql.Interface) *graphql.Object {
+// isTypeOf reports whether id resolves to the object type called name
+func (r *Resolver) isTypeOf(id gidx.PrefixedID, name string) bool {
+	obj, _ := r.objectForPrefix(prefixOf(id))
+
+	return obj != nil && obj.Name() == name
+}
+
+// initPossibleTypes fills the possible types of every abstract type in the
+// schema. graphql-go builds them lazily in IsPossibleType, writing to the
+// schema, so they're built here before the schema is shared between requests.
+func (r *Resolver) initPossibleTypes() {
+	obj := r.objects[0]
+
+	for _, iface := range r.interfaceMap {
+		r.handlerSchema.IsPossibleType(iface, obj)
+	}
+
+	r.handlerSchema.IsPossibleType(r.entities, obj)
+}
+
+// schema returns a copy of the schema for graphql-go functions which take a
+// pointer and may write to it, so concurrent requests never share writes
+func (r *Resolver) schema() *graphql.Schema {
+	s := r.handlerSchema
+
+	return &s
+}
+
+// nodeIDField is the id field of every object type. graphql-go only reads
+// field configs, so a single field is shared rather than built per type.
+var nodeIDField = &graphql.Field{
+	Type:        graphql.NewNonNull(graphql.ID),
+	Description: "The id of the node.",
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		switch o := p.Source.(type) {
+		case *Node:
+			return o.ID, nil
+		case *Entity:
+			return o.ID, nil
+		default:
+			return nil, errors.New("invalid node type")
+		}
+	},
+}
+
+func (r *Resolver) graphTypeFor(name string, interfaces []*graphql.Interface) *graphql.Object {
+	fields := graphql.Fields{"id": nodeIDField}
+	if len(r.services) != 0 {
+		fields["service"] = r.serviceField()
+	}
+
 	return graphql.NewObject(graphql.ObjectConfig{
-		Name: name,
-		Fields: graphql.Fields{
-			"id": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.ID),
-				Description: "The id of the node.",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					switch o := p.Source.(type) {
-					case *Node:
-						return o.ID, nil
-					case *Entity:
-						return o.ID, nil
-					default:
-						return nil, errors.New("invalid node type")
-					}
-				},
-			},
-		},
+		Name:   name,
+		Fields: fields,
 		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
 			// TODO: This should be able to check account the name of the type instead :thinking-face:
 			switch o := p.Value.(type) {
 			case *Node:
-				return o.ID.Prefix() == prefix
+				return r.isTypeOf(o.ID, name)
 			case *Entity:
-				return o.ID.Prefix() == prefix
+				return r.isTypeOf(o.ID, name)
 			default:
 				return false
 			}
@@ -151,24 +441,30 @@ func (r *Resolver) graphTypeFor(name string, prefix string, interfaces []*graphq
 }
 
 func (r *Resolver) graphInterfaceFor(name string) *graphql.Interface {
-	return graphql.NewInterface(graphql.InterfaceConfig{
-		Name: name,
-		Fields: graphql.Fields{
-			"id": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.ID),
-				Description: "The id of the node.",
-			},
+	fields := graphql.Fields{
+		"id": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "The id of the node.",
 		},
-		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+	}
+
+	if len(r.services) != 0 {
+		fields["service"] = r.serviceField()
+	}
+
+	return graphql.NewInterface(graphql.InterfaceConfig{
+		Name:   name,
+		Fields: fields,
+		ResolveType: r.recoverResolveType(func(p graphql.ResolveTypeParams) *graphql.Object {
 			switch o := p.Value.(type) {
 			case *Node:
 				return o.GraphType
 			case *Entity:
-				return r.entityTypeResolver(graphql.ResolveTypeParams{Value: o})
+				return o.objType
 			default:
 				return nil
 			}
-		},
+		}),
 	})
 }
 
@@ -186,69 +482,301 @@ func (r *Resolver) Query() (*graphql.Object, error) {
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
 						Description: "ID of the node",
-						Type:        graphql.NewNonNull(graphql.ID),
+						Type:        graphql.NewNonNull(r.idArgType()),
 					},
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					id, err := gidx.Parse(p.Args["id"].(string))
+				Resolve: r.recoverResolve(func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := r.parseID(p.Args["id"].(string))
 					if err != nil {
-						return nil, err
+						r.recordResolution(p.Context, auditOperationNode, p.Args["id"].(string), "", err)
+
+						return nil, codedError(err)
 					}
-					return r.GetNode(id)
+
+					node, err := r.GetNode(p.Context, id)
+					if err != nil {
+						return nil, codedError(err)
+					}
+
+					return node, nil
+				}),
+			},
+			"nodes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(nodeInt)),
+				Args: graphql.FieldConfigArgument{
+					"ids": &graphql.ArgumentConfig{
+						Description: "IDs of the nodes",
+						Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(r.idArgType()))),
+					},
 				},
+				Resolve: r.recoverResolve(r.nodesResolver),
 			},
 			"_entities": &graphql.Field{
-				Type: graphql.NewNonNull(graphql.NewList(r.entitiesUnion())),
+				Type: graphql.NewNonNull(graphql.NewList(r.entities)),
 				Args: graphql.FieldConfigArgument{
 					"representations": &graphql.ArgumentConfig{
 						Description: "ID of the node",
 						Type:        graphql.NewNonNull(graphql.NewList(r.scalars["_Any"])),
 					},
 				},
-				Resolve: r.entitiesResolver,
+				Resolve: r.recoverResolve(r.entitiesResolver),
+			},
+			"typePrefix": &graphql.Field{
+				Type:        graphql.String,
+				Description: "The prefix of the ids of a type, or null when the type has none.",
+				Args: graphql.FieldConfigArgument{
+					"typename": &graphql.ArgumentConfig{
+						Description: "Name of the type",
+						Type:        graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.recoverResolve(r.typePrefixResolver),
+			},
+			"prefixes": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(prefixMappingType))),
+				Description: "The prefixes served and the types their ids resolve to, sorted by prefix.",
+				Resolve:     r.recoverResolve(r.prefixesResolver),
+			},
+			"_service": &graphql.Field{
+				Type: graphql.NewNonNull(serviceType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return map[string]interface{}{"sdl": r.SDL()}, nil
+				},
 			},
 		},
 	}), nil
 }
 
+// serviceType is the federation _Service type, letting gateways compose the
+// supergraph from the resolver's subgraph sdl
+var serviceType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "_Service",
+	Fields: graphql.Fields{
+		"sdl": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+	},
+})
+
+// GraphTypes returns the types added to the schema, ordered by name so the
+// schema is built the same way every time
 func (r *Resolver) GraphTypes() []graphql.Type {
+	return r.graphTypes
+}
+
+// buildGraphTypes returns the object types, the _Entities union and the
+// scalars of the schema, ordered by name
+func (r *Resolver) buildGraphTypes() []graphql.Type {
 	objs := []graphql.Type{}
-	for _, obj := range r.prefixMap {
+	for _, obj := range r.objects {
 		objs = append(objs, obj)
 	}
 
-	objs = append(objs, r.entitiesUnion())
+	objs = append(objs, r.entities)
 
-	for _, obj := range r.scalars {
-		objs = append(objs, obj)
+	scalars := make([]string, 0, len(r.scalars))
+	for name := range r.scalars {
+		scalars = append(scalars, name)
+	}
+
+	sort.Strings(scalars)
+
+	for _, name := range scalars {
+		objs = append(objs, r.scalars[name])
 	}
 
 	return objs
 }
 
 type postData struct {
-	Query     string                 `json:"query"`
-	Operation string                 `json:"operation"`
-	Variables map[string]interface{} `json:"variables"`
+	Query     string `json:"query"`
+	Operation string `json:"operation"`
+	// OperationName is the operation field of the GraphQL-over-HTTP spec,
+	// merged into Operation once the request is decoded
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    requestExtensions      `json:"extensions"`
+}
+
+// representationCount returns the number of _entities representations
+// passed in the variables of p
+func representationCount(p *postData) int {
+	reps, _ := p.Variables["representations"].([]interface{})
+
+	return len(reps)
 }
 
 func (r *Resolver) Routes(e *echo.Group) {
-	e.POST("/query", r.GraphHandler)
+	r.routes(e, func(echo.Context) (*Resolver, error) { return r, nil })
 }
 
-func (r *Resolver) GraphHandler(ctx echo.Context) error {
-	var p postData
-	if err := json.NewDecoder(ctx.Request().Body).Decode(&p); err != nil {
+// routes registers the handlers of the resolver returned by current, which
+// is called for every request so the resolver can be replaced while serving
+func (r *Resolver) routes(e *echo.Group, current resolverFunc) {
+	e.GET("/query", current.serve((*Resolver).GraphHandler), r.middleware...)
+	e.POST("/query", current.serve((*Resolver).GraphHandler), r.middleware...)
+
+	r.resolveAPIRoutes(e, current)
+}
+
+// GraphHandler executes a graphql request, sent as a json body or, for GET
+// requests, as query parameters as described by the GraphQL-over-HTTP spec
+func (r *Resolver) GraphHandler(ctx echo.Context) (err error) {
+	defer r.observeRequest("query", time.Now())
+	defer r.recoverGraphHandler(ctx, &err)
+
+	r.setInstanceHeaders(ctx)
+
+	mediaType, ok := responseMediaType(ctx.Request().Header.Get(echo.HeaderAccept))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotAcceptable, "responses can only be sent as "+echo.MIMEApplicationJSON+" or "+MIMEGraphQLResponse)
+	}
+
+	// the content type is set first so every response is sent with it
+	ctx.Response().Header().Set(echo.HeaderContentType, mediaType+"; charset=UTF-8")
+
+	var p *postData
+
+	if ctx.Request().Method == http.MethodGet {
+		p, err = decodeQueryParams(ctx.QueryParams())
+	} else {
+		p, err = decodePostData(ctx.Request().Body, r.strictRequests)
+	}
+
+	if errors.Is(err, ErrMalformedRequest) {
+		return r.malformedRequest(ctx, err)
+	}
+
+	if err != nil {
 		return err
 	}
-	r.logger.Infow("request info", "postData.Query", p.Query, "postData.Operation", p.Operation, "postdata.Variables", p.Variables)
-	result := graphql.Do(graphql.Params{
-		Context:        ctx.Request().Context(),
-		Schema:         r.handlerSchema,
-		RequestString:  p.Query,
-		VariableValues: p.Variables,
-		OperationName:  p.Operation,
-	})
+	defer putPostData(p)
+
+	pqResult, err := r.resolvePersistedQuery(p)
+	if err != nil {
+		return r.malformedRequest(ctx, err)
+	}
+
+	if pqResult != nil {
+		pqResult.Errors = r.localizeErrors(ctx, pqResult.Errors)
+		r.addInstanceExtension(pqResult)
+
+		return r.writeJSON(ctx, resultStatus(mediaType, pqResult, false), pqResult)
+	}
+
+	if err := p.normalize(); err != nil {
+		return r.malformedRequest(ctx, err)
+	}
+
+	if r.shedder != nil && !r.shedder.AllowRepresentations(representationCount(p)) {
+		return r.shedder.Reject(ctx, shed.ReasonRepresentations)
+	}
+
+	r.logRequest(ctx.Request().Context(), p)
+
+	span := startOperationSpan(ctx, p)
+	defer span.End()
+
+	if r.introspectionDisabled {
+		ctx.SetRequest(ctx.Request().WithContext(r.withIntrospection(ctx.Request().Context(), ctx.RealIP())))
+	}
+
+	key, cacheable := r.graphResponseCacheKey(ctx.Request().Context(), *p)
+	if cacheable && r.keyLookup != nil && hasKeyedRepresentations(*p) {
+		cacheable = false
+	}
+
+	// parsing the query for the policy input is only needed by the policy
+	// and to tag cached responses
+	var input policy.Input
+	if r.policy != nil || cacheable {
+		input = r.policyInput(*p)
+	}
+
+	// the policy is evaluated before the response cache is looked up, so
+	// cached responses never outlive a denial
+	annotations, err := r.evaluatePolicy(ctx.Request().Context(), input)
+	if err != nil {
+		denied := deniedResult(err.Error())
+		setOperationStatus(span, denied)
+
+		denied.Errors = r.localizeErrors(ctx, denied.Errors)
+		r.addInstanceExtension(denied)
+
+		return r.writeJSON(ctx, resultStatus(mediaType, denied, true), denied)
+	}
+
+	if cacheable {
+		if body, ok := r.cachedResponse(key); ok {
+			return ctx.JSONBlob(http.StatusOK, body)
+		}
+	}
+
+	execCtx := r.lookupDeadline(withRequestID(ctx.Request().Context(), requestID(ctx)))
+	result := r.execute(execCtx, p)
+	setOperationStatus(span, result)
+
+	r.shadowRequest(execCtx, p, result)
+	r.recordSchemaVersion(ctx, result)
+
+	if r.canonical {
+		result.Errors = canonicalErrors(result.Errors)
+	}
+
+	result.Errors = r.localizeErrors(ctx, result.Errors)
+
+	if len(annotations) != 0 {
+		if result.Extensions == nil {
+			result.Extensions = map[string]interface{}{}
+		}
+
+		result.Extensions["policy"] = annotations
+	}
+
+	r.addInstanceExtension(result)
+
+	if cacheable && !result.HasErrors() {
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		if err := encodeResult(buf, result); err != nil {
+			return err
+		}
+
+		r.cacheResponse(key, input.IDs, bytes.Clone(buf.Bytes()))
+
+		return ctx.JSONBlob(http.StatusOK, buf.Bytes())
+	}
+
+	ctx.Response().WriteHeader(resultStatus(mediaType, result, false))
+
+	if err := encodeResult(ctx.Response(), result); err != nil {
+		// the status has already been sent so the error can only be logged
+		r.logger.Errorw("failed to write response", "error", err)
+	}
+
+	return nil
+}
+
+// observeRequest records the duration of a request started at start
+func (r *Resolver) observeRequest(handler string, start time.Time) {
+	if r.metrics != nil {
+		r.metrics.RequestDuration(handler, time.Since(start))
+	}
+}
+
+// WithSchema returns a new resolver for rawSchema configured with the same
+// options as r. When the schema is unchanged the new resolver keeps the load
+// time of r, so clients revalidating responses aren't sent them again.
+func (r *Resolver) WithSchema(rawSchema string) (*Resolver, error) {
+	next, err := NewResolver(r.logger, rawSchema, r.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if next.SDLChecksum() == r.SDLChecksum() {
+		next.loadedAt = r.loadedAt
+	}
 
-	return ctx.JSON(http.StatusOK, result)
+	return next, nil
 }
@@ -1,18 +1,23 @@
 package graphapi_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
 	"go.uber.org/zap"
 
+	"go.infratographer.com/node-resolver/internal/audit"
+	"go.infratographer.com/node-resolver/internal/authz"
 	"go.infratographer.com/node-resolver/internal/graphapi"
 )
 
@@ -153,6 +158,20 @@ func TestNodeResolving(t *testing.T) {
 			response:  `{"_entities":[null,{"__typename":"Token","id":"testtkn-NU0CbUfS_0yGG1hzvIfDH"}]}`,
 			errorMsgs: []string{"Hardware is an unknown interface type"},
 		},
+		{
+			TestName: "Entities request returns errors for malformed representations and resolves the rest",
+			query: `{
+				"query": "query($representations:[_Any!]!){_entities(representations:$representations){...on Actor{__typename id}}}",
+				"variables": {"representations": [{ "id": "testusr-rXirlFQULBHDw9urtOjya" },{ "__typename": "Actor", "id": "testtkn-NU0CbUfS_0yGG1hzvIfDH" },{ "__typename": "Actor" },{ "__typename": "Actor", "id": 12 },"testusr-rXirlFQULBHDw9urtOjya"]}
+				}`,
+			response: `{"_entities":[null,{"__typename":"Token","id":"testtkn-NU0CbUfS_0yGG1hzvIfDH"},null,null,null]}`,
+			errorMsgs: []string{
+				"invalid representation 0: __typename is missing",
+				"invalid representation 2: id is missing",
+				"invalid representation 3: id isn't a string",
+				"invalid representation 4: it isn't an object",
+			},
+		},
 	}
 
 	for _, tt := range testCases {
@@ -176,15 +195,232 @@ func TestNodeResolving(t *testing.T) {
 	}
 }
 
+type denyPrefixAuthorizer struct {
+	prefix string
+}
+
+func (a denyPrefixAuthorizer) CanResolve(_ context.Context, _ string, id gidx.PrefixedID) error {
+	if id.Prefix() == a.prefix {
+		return authz.ErrUnauthorized
+	}
+
+	return nil
+}
+
+func TestAuthorization(t *testing.T) {
+	testCases := []struct {
+		TestName  string
+		query     string
+		response  string
+		errorMsgs []string
+	}{
+		{
+			TestName: "allowed node",
+			query:    `{"query": "{ node(id: \"testsrv-123\") { __typename id } }" }`,
+			response: `{"node":{"__typename":"Server","id":"testsrv-123"}}`,
+		},
+		{
+			TestName:  "denied node",
+			query:     `{"query": "{ node(id: \"testtkn-123\") { __typename id } }" }`,
+			response:  `{"node":null}`,
+			errorMsgs: []string{"not authorized to resolve id"},
+		},
+		{
+			TestName: "denied entity",
+			query: `{
+				"query": "query($representations:[_Any!]!){_entities(representations:$representations){...on Actor{__typename id}}}",
+				"variables": {"representations": [{ "__typename": "Actor", "id": "testtkn-rXirlFQULBHDw9urtOjya" },{ "__typename": "Actor", "id": "testusr-DPCwfa6KxhXp_ociFWV8C" }]}
+				}`,
+			response:  `{"_entities":[null,{"__typename":"User","id":"testusr-DPCwfa6KxhXp_ociFWV8C"}]}`,
+			errorMsgs: []string{"not authorized to resolve id"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			resp, err := testQuery(validTestSchema, tt.query, graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testtkn"}))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.response, resp.Data)
+			require.Equal(t, len(tt.errorMsgs), len(resp.Errors))
+
+			for i, msg := range tt.errorMsgs {
+				assert.Contains(t, resp.Errors[i].Message, msg)
+			}
+		})
+	}
+}
+
+func TestDeterministicTypes(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+		require.NoError(t, err)
+
+		names := []string{}
+		for _, typ := range r.GraphTypes() {
+			names = append(names, typ.Name())
+		}
+
+		assert.Equal(t, []string{"Server", "Token", "User", "_Entities", "_Any"}, names)
+		assert.Same(t, &r.GraphTypes()[0], &r.GraphTypes()[0], "types are only computed once")
+
+		resp, err := testQuery(validTestSchema, `{"query":"{ __type(name: \"_Entities\") { possibleTypes { name } } }"}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"__type":{"possibleTypes":[{"name":"Server"},{"name":"Token"},{"name":"User"}]}}`, resp.Data)
+	}
+}
+
+func TestSDL(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	expected := `extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key"])
+directive @prefixedID(prefix: String!) on OBJECT
+type Server implements Node @key(fields: "id") @prefixedID(prefix: "testsrv") {
+  id: ID!
+}
+type Token implements Node & Actor @key(fields: "id") @prefixedID(prefix: "testtkn") {
+  id: ID!
+}
+type User implements Node & Actor @key(fields: "id") @prefixedID(prefix: "testusr") {
+  id: ID!
+}
+interface Actor @key(fields: "id") {
+  id: ID!
+}
+interface Node @key(fields: "id") {
+  id: ID!
+}
+type PrefixMapping {
+  prefix: String!
+  type: String!
+  deprecated: Boolean!
+}
+type Query {
+  node(id: ID!): Node
+  nodes(ids: [ID!]!): [Node]!
+  typePrefix(typename: String!): String
+  prefixes: [PrefixMapping!]!
+}
+`
+
+	assert.Equal(t, expected, r.SDL())
+	assert.Equal(t, r.SDLChecksum(), r.SDLChecksum())
+
+	// the generated sdl can be served by another resolver
+	r2, err := graphapi.NewResolver(zap.NewNop().Sugar(), r.SDL())
+	require.NoError(t, err)
+	assert.Equal(t, r.SDL(), r2.SDL())
+}
+
+func TestNodes(t *testing.T) {
+	resp, err := testQuery(validTestSchema, `{"query":"{ nodes(ids: [\"testusr-abc\", \"unknown-abc\", \"bad\", \"testsrv-def\"]) { id __typename } }"}`,
+		graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testsrv"}))
+	require.NoError(t, err)
+
+	// failed ids are null in the list and reported as errors
+	assert.JSONEq(t, `{"nodes":[{"id":"testusr-abc","__typename":"User"},null,null,null]}`, string(resp.RawData))
+	require.Len(t, resp.Errors, 3)
+
+	codes := []interface{}{}
+	for _, e := range resp.Errors {
+		codes = append(codes, e.Extensions["code"])
+	}
+
+	assert.ElementsMatch(t, []interface{}{"unknown_prefix", "invalid_id", "unauthorized"}, codes)
+}
+
+func TestNodesLimit(t *testing.T) {
+	ids := make([]string, graphapi.MaxResolveIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf(`\"testusr-%d\"`, i)
+	}
+
+	resp, err := testQuery(validTestSchema, `{"query":"{ nodes(ids: [`+strings.Join(ids, ",")+`]) { id } }"}`)
+	require.NoError(t, err)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "at most 100 ids")
+	assert.Equal(t, "invalid_request", resp.Errors[0].Extensions["code"])
+}
+
+func TestServiceSDL(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	resp, err := testQuery(validTestSchema, `{"query":"{ _service { sdl } }"}`)
+	require.NoError(t, err)
+	require.Empty(t, resp.Errors)
+
+	var data struct {
+		Service struct {
+			SDL string `json:"sdl"`
+		} `json:"_service"`
+	}
+
+	require.NoError(t, json.Unmarshal(resp.RawData, &data))
+	assert.Equal(t, r.SDL(), data.Service.SDL)
+}
+
+type memoryAuditSink struct {
+	records []audit.Record
+}
+
+func (s *memoryAuditSink) Emit(_ context.Context, records []audit.Record) error {
+	s.records = append(s.records, records...)
+
+	return nil
+}
+
+func (s *memoryAuditSink) Close() error {
+	return nil
+}
+
+func TestAuditing(t *testing.T) {
+	sink := &memoryAuditSink{}
+	auditor := audit.New(zap.NewNop().Sugar(), sink)
+	auditor.Start(context.Background())
+
+	query := `{
+		"query": "query($representations:[_Any!]!){ a: node(id: \"testsrv-123\") { id } b: node(id: \"unknown-123\") { id } c: node(id: \"bad\") { id } _entities(representations:$representations){...on Actor{id}}}",
+		"variables": {"representations": [{ "__typename": "Actor", "id": "testusr-456" }, { "__typename": "Actor", "id": "testtkn-789" }]}
+	}`
+
+	_, err := testQuery(validTestSchema, query,
+		graphapi.WithAuditor(auditor),
+		graphapi.WithAuthorizer(denyPrefixAuthorizer{prefix: "testtkn"}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, auditor.Close())
+
+	expected := []audit.Record{
+		{Operation: "node", ID: "testsrv-123", Prefix: "testsrv", Type: "Server", Outcome: audit.OutcomeResolved},
+		{Operation: "node", ID: "unknown-123", Prefix: "unknown", Outcome: audit.OutcomeUnknownPrefix},
+		{Operation: "node", ID: "bad", Outcome: audit.OutcomeInvalidID},
+		{Operation: "_entities", ID: "testusr-456", Prefix: "testusr", Type: "User", Outcome: audit.OutcomeResolved},
+		{Operation: "_entities", ID: "testtkn-789", Prefix: "testtkn", Type: "Token", Outcome: audit.OutcomeUnauthorized},
+	}
+
+	// root fields aren't resolved in a fixed order
+	for i := range sink.records {
+		sink.records[i].Time = time.Time{}
+	}
+
+	assert.ElementsMatch(t, expected, sink.records)
+}
+
 type queryResponse struct {
-	Data    string
-	RawData json.RawMessage `json:"data"`
-	Errors  []queryError    `json:"errors"`
+	Data       string
+	RawData    json.RawMessage        `json:"data"`
+	Errors     []queryError           `json:"errors"`
+	Extensions map[string]interface{} `json:"extensions"`
 }
 
 type queryError struct {
-	Message   string               `json:"message"`
-	Locations []queryErrorLocation `json:"locations"`
+	Message    string                 `json:"message"`
+	Locations  []queryErrorLocation   `json:"locations"`
+	Path       []interface{}          `json:"path"`
+	Extensions map[string]interface{} `json:"extensions"`
 }
 
 type queryErrorLocation struct {
@@ -192,14 +428,14 @@ type queryErrorLocation struct {
 	Column int `json:"column"`
 }
 
-func testQuery(schema string, query string) (*queryResponse, error) {
-	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), schema)
+func testQuery(schema string, query string, opts ...graphapi.Option) (*queryResponse, error) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), schema, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	rec := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodGet, "/graphql", strings.NewReader(query))
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query))
 	e := echo.New()
 	c := e.NewContext(req, rec)
 
@@ -0,0 +1,218 @@
+package graphapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/cache"
+)
+
+// schemaCacheControl is used for the resolve api json schema, which only
+// changes with new releases
+const schemaCacheControl = "public, max-age=3600"
+
+// cachedResponse is an encoded response stored in the response cache
+type cachedResponse struct {
+	body []byte
+}
+
+// WithResponseCache caches encoded responses in c. Responses are keyed by
+// the schema checksum, the subject and the normalized request so they're
+// never shared between schema versions or subjects. Only responses without
+// errors are cached, so denials always reach the authorizer. Response
+// caching is disabled along with auditing, a policy, a node backend,
+// feature flags, fault injection or source lookups, since cached responses
+// would skip them.
+func WithResponseCache(c *cache.Cache) Option {
+	return func(r *Resolver) {
+		r.responses = c
+	}
+}
+
+// disableUncacheableResponses turns off the response cache when responses
+// depend on more than the schema, the subject and the request: a cache hit
+// would skip auditing, a policy revoking access, a node that has since been
+// deleted from the node backend, a flag that has since been turned off, the
+// faults injected into the request or fields that have since changed in the
+// subgraph they're looked up from.
+func (r *Resolver) disableUncacheableResponses() {
+	var reason string
+
+	switch {
+	case r.auditor != nil:
+		reason = "auditing"
+	case r.policy != nil:
+		reason = "the policy"
+	case r.nodeBackend != nil:
+		reason = "the node backend"
+	case r.featureFlags != nil:
+		reason = "feature flags"
+	case r.chaos != nil:
+		reason = "fault injection"
+	case r.sourceClient != nil && len(r.sources) != 0:
+		reason = "source lookups"
+	default:
+		return
+	}
+
+	r.logger.Warn("response caching is disabled since cached responses would skip " + reason)
+
+	r.responses = nil
+}
+
+// responseCacheKey returns the key of a request in the response cache.
+// Requests that can't be normalized aren't cached.
+func (r *Resolver) responseCacheKey(ctx context.Context, kind string, request interface{}) (string, bool) {
+	if r.responses == nil {
+		return "", false
+	}
+
+	// clients that may not introspect the schema never share responses
+	// with those that may
+	b, err := json.Marshal([]interface{}{r.SDLChecksum(), authz.Subject(ctx), introspectionAllowed(ctx), kind, request})
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(b)
+
+	return "response:" + hex.EncodeToString(sum[:]), true
+}
+
+// graphResponseCacheKey returns the response cache key of a graphql request.
+// The query is normalized so formatting and comments don't affect the key;
+// variables are encoded with sorted keys.
+func (r *Resolver) graphResponseCacheKey(ctx context.Context, p postData) (string, bool) {
+	if r.responses == nil {
+		return "", false
+	}
+
+	qd := r.queryDocument(p.Query)
+	if qd.err != nil {
+		return "", false
+	}
+
+	return r.responseCacheKey(ctx, "query", postData{
+		Query:     qd.normalized,
+		Operation: p.Operation,
+		Variables: p.Variables,
+	})
+}
+
+func (r *Resolver) cachedResponse(key string) ([]byte, bool) {
+	v, ok := r.responses.Get(key)
+	r.recordCacheLookup(cacheNameResponse, ok)
+
+	if !ok {
+		return nil, false
+	}
+
+	return v.(*cachedResponse).body, true
+}
+
+// cacheResponse stores body under key, tagged with the ids it describes so
+// it's invalidated along with them
+func (r *Resolver) cacheResponse(key string, ids []string, body []byte) {
+	r.responses.SetIDs(key, ids, &cachedResponse{body: body})
+}
+
+// cacheControl returns the Cache-Control header for cacheable responses.
+// Responses depending on the subject are private.
+func (r *Resolver) cacheControl() string {
+	if r.responses == nil {
+		return "no-cache"
+	}
+
+	scope := "public"
+	if r.authorizer != nil || r.policy != nil {
+		scope = "private"
+	}
+
+	return scope + ", max-age=" + strconv.Itoa(int(r.responses.TTL().Seconds()))
+}
+
+// lastModified returns the Last-Modified time of resolve api responses. The
+// responses only depend on the schema unless an authorizer or policy is
+// configured, in which case no time is returned.
+func (r *Resolver) lastModified() time.Time {
+	if r.authorizer != nil || r.policy != nil {
+		return time.Time{}
+	}
+
+	return r.loadedAt
+}
+
+// writeCacheable writes a response to a GET request with an ETag and, unless
+// lastModified is zero, a Last-Modified header, replying with 304 Not
+// Modified when the client already has it
+func writeCacheable(c echo.Context, cacheControl, contentType string, lastModified time.Time, body []byte) error {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Response().Header().Set(echo.HeaderCacheControl, cacheControl)
+	c.Response().Header().Set("ETag", etag)
+
+	if !lastModified.IsZero() {
+		c.Response().Header().Set(echo.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request(), etag, lastModified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.Blob(http.StatusOK, contentType, body)
+}
+
+// notModified reports whether the client already has the response, using
+// If-Modified-Since only when the request has no If-None-Match header
+func notModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if header := req.Header.Get("If-None-Match"); header != "" {
+		return etagMatches(header, etag)
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get(echo.HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison required for If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encodeJSON encodes v the same way echo does for JSON responses
+func encodeJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,251 @@
+package graphapi_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/echojwtx"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/cache"
+	"go.infratographer.com/node-resolver/internal/featureflags"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+type countingAuthorizer struct {
+	calls atomic.Int32
+}
+
+func (a *countingAuthorizer) CanResolve(_ context.Context, _ string, _ gidx.PrefixedID) error {
+	a.calls.Add(1)
+
+	return nil
+}
+
+func TestResponseCache(t *testing.T) {
+	authorizer := &countingAuthorizer{}
+	responses := cache.New(10, time.Minute)
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema,
+		graphapi.WithAuthorizer(authorizer),
+		graphapi.WithResponseCache(responses),
+	)
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	serve := func(method, target, subject, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		req = req.WithContext(context.WithValue(req.Context(), echojwtx.ActorCtxKey, subject))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	query := `{"query":"{ node(id: \"testsrv-abc\") { id } }"}`
+	reformatted := `{"query":"query {\n  node(id: \"testsrv-abc\") {\n    # the id\n    id\n  }\n}"}`
+
+	first := serve(http.MethodPost, "/query", "user-1", query)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.EqualValues(t, 1, authorizer.calls.Load())
+
+	second := serve(http.MethodPost, "/query", "user-1", reformatted)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.EqualValues(t, 1, authorizer.calls.Load(), "normalized queries share cached responses")
+
+	serve(http.MethodPost, "/query", "user-2", query)
+	assert.EqualValues(t, 2, authorizer.calls.Load(), "responses aren't shared between subjects")
+
+	// errors aren't cached
+	serve(http.MethodPost, "/query", "user-1", `{"query":"{ node(id: \"unknown-abc\") { id } }"}`)
+	serve(http.MethodPost, "/query", "user-1", `{"query":"{ node(id: \"unknown-abc\") { id } }"}`)
+	assert.Equal(t, 2, responses.Len())
+
+	responses.InvalidateID("testsrv-abc")
+	assert.Equal(t, 0, responses.Len())
+
+	rec := serve(http.MethodGet, "/api/v1/resolve?id=testsrv-abc", "user-1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "private, max-age=60", rec.Header().Get(echo.HeaderCacheControl))
+
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	rec = serve(http.MethodGet, "/api/v1/resolve?id=testsrv-abc", "user-1", "", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = serve(http.MethodGet, "/api/v1/resolve?id=unknown-abc", "user-1", "")
+	assert.Equal(t, "no-cache", rec.Header().Get(echo.HeaderCacheControl))
+}
+
+func TestSchemaJSONETag(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/schema.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/schema.json", nil)
+	req.Header.Set("If-None-Match", `W/`+rec.Header().Get("ETag"))
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestLastModified(t *testing.T) {
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	handler := graphapi.NewHandler(r)
+
+	e := echo.New()
+	handler.Routes(e.Group(""))
+
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/resolve?id=testsrv-abc", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := serve("", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	lastModified := rec.Header().Get(echo.HeaderLastModified)
+	require.NotEmpty(t, lastModified)
+
+	assert.Equal(t, http.StatusNotModified, serve(echo.HeaderIfModifiedSince, lastModified).Code)
+	assert.Equal(t, http.StatusOK, serve(echo.HeaderIfModifiedSince, "Mon, 02 Jan 2006 15:04:05 GMT").Code)
+	assert.Equal(t, http.StatusOK, serve(echo.HeaderIfModifiedSince, "invalid").Code)
+
+	// the same schema keeps its load time
+	same, err := r.WithSchema(validTestSchema)
+	require.NoError(t, err)
+
+	handler.Swap(same)
+	assert.Equal(t, lastModified, serve("", "").Header().Get(echo.HeaderLastModified))
+
+	// responses depending on the subject have no Last-Modified time
+	private, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithAuthorizer(&countingAuthorizer{}))
+	require.NoError(t, err)
+
+	handler.Swap(private)
+
+	rec = serve(echo.HeaderIfModifiedSince, lastModified)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderLastModified))
+}
+
+func TestResponseCacheAfterPolicy(t *testing.T) {
+	responses := cache.New(10, time.Minute)
+	pol := &prefixPolicy{}
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema,
+		graphapi.WithPolicy(pol),
+		graphapi.WithResponseCache(responses),
+	)
+	require.NoError(t, err)
+
+	e := echo.New()
+	r.Routes(e.Group(""))
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	query := `{"query":"{ node(id: \"testsrv-abc\") { id } }"}`
+
+	rec := serve(http.MethodPost, "/query", query)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"testsrv-abc"`)
+
+	rec = serve(http.MethodGet, "/api/v1/resolve?id=testsrv-abc", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-cache", rec.Header().Get(echo.HeaderCacheControl))
+
+	// a denial following an allowed request is never answered from the cache
+	pol.prefix = "testsrv"
+
+	rec = serve(http.MethodPost, "/query", query)
+	assert.Contains(t, rec.Body.String(), "prefix testsrv is restricted")
+	assert.NotContains(t, rec.Body.String(), `"testsrv-abc"`)
+
+	rec = serve(http.MethodGet, "/api/v1/resolve?id=testsrv-abc", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	assert.Zero(t, responses.Len())
+}
+
+func TestResponseCacheDisabled(t *testing.T) {
+	srv, _ := newSourceSubgraph(t)
+
+	testCases := []struct {
+		TestName string
+		schema   string
+		opt      graphapi.Option
+	}{
+		{TestName: "policy", opt: graphapi.WithPolicy(&prefixPolicy{})},
+		{TestName: "node backend", opt: graphapi.WithNodeBackend(&testNodeBackend{})},
+		{TestName: "feature flags", opt: graphapi.WithFeatureFlags(featureflags.NewGate(featureflags.Config{}, featureflags.NewStatic(nil), zap.NewNop().Sugar()))},
+		{TestName: "source lookups", schema: fmt.Sprintf(sourcedTestSchema, srv.URL), opt: graphapi.WithSourceLookups(nil)},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			schema := tt.schema
+			if schema == "" {
+				schema = validTestSchema
+			}
+
+			responses := cache.New(10, time.Minute)
+
+			r, err := graphapi.NewResolver(zap.NewNop().Sugar(), schema, tt.opt, graphapi.WithResponseCache(responses))
+			require.NoError(t, err)
+
+			e := echo.New()
+			r.Routes(e.Group(""))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/resolve?id=testsrv-123", nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			assert.Zero(t, responses.Len())
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package graphapi
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.infratographer.com/x/gidx"
+)
+
+// maxUntrustedLength is the longest untrusted string included in error
+// messages and logs, in bytes. Longer strings are truncated.
+const maxUntrustedLength = 64
+
+// truncatedMarker is appended to truncated untrusted strings
+const truncatedMarker = "...(truncated)"
+
+// safeString returns the untrusted string s, such as an id or type name from
+// a request, made safe to include in error messages and logs. Control
+// characters, line separators and invalid utf-8 are replaced with U+FFFD so
+// they can't forge log lines, and strings longer than maxUntrustedLength
+// are truncated and marked as such.
+func safeString(s string) string {
+	if isSafe(s) {
+		return s
+	}
+
+	var sb strings.Builder
+
+	for i, c := range s {
+		if i >= maxUntrustedLength {
+			sb.WriteString(truncatedMarker)
+
+			break
+		}
+
+		if unsafeRune(c) {
+			c = utf8.RuneError
+		}
+
+		sb.WriteRune(c)
+	}
+
+	return sb.String()
+}
+
+// isSafe reports whether s can be included in messages as is
+func isSafe(s string) bool {
+	if len(s) > maxUntrustedLength {
+		return false
+	}
+
+	for _, c := range s {
+		if unsafeRune(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unsafeRune reports whether c is a control character, a line or paragraph
+// separator, or decoded from invalid utf-8
+func unsafeRune(c rune) bool {
+	return c == utf8.RuneError || unicode.IsControl(c) || c == '\u2028' || c == '\u2029'
+}
+
+// sanitizedError is an error whose message has been made safe, keeping the
+// original error for errors.Is and errors.As
+type sanitizedError struct {
+	msg string
+	err error
+}
+
+func (e *sanitizedError) Error() string {
+	return e.msg
+}
+
+func (e *sanitizedError) Unwrap() error {
+	return e.err
+}
+
+// sanitizeIDError returns err, from parsing the id raw, with the id or its
+// prefix in the message made safe
+func sanitizeIDError(err error, raw string) error {
+	msg := err.Error()
+
+	for _, s := range []string{raw, prefixOf(gidx.PrefixedID(raw))} {
+		if s == "" || !strings.Contains(msg, s) {
+			continue
+		}
+
+		if safe := safeString(s); safe != s {
+			return &sanitizedError{msg: strings.ReplaceAll(msg, s, safe), err: err}
+		}
+
+		return err
+	}
+
+	return err
+}
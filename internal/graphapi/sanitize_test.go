@@ -0,0 +1,68 @@
+package graphapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+func TestSafeString(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{s: "testsrv-abc", expected: "testsrv-abc"},
+		{s: "", expected: ""},
+		{s: "ünïcode \"quoted\"", expected: "ünïcode \"quoted\""},
+		{s: "Node\nlevel=error", expected: "Node�level=error"},
+		{s: "a\r\x00\x7f\u0085  b", expected: "a������b"},
+		{s: "bad\xffutf8", expected: "bad�utf8"},
+		{s: strings.Repeat("a", maxUntrustedLength), expected: strings.Repeat("a", maxUntrustedLength)},
+		{s: strings.Repeat("a", maxUntrustedLength+1), expected: strings.Repeat("a", maxUntrustedLength) + truncatedMarker},
+		{s: strings.Repeat("é", maxUntrustedLength), expected: strings.Repeat("é", maxUntrustedLength/2) + truncatedMarker},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, safeString(tt.s), tt.s)
+	}
+}
+
+func TestSanitizedErrors(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), prefixTestSchema, WithMaxIDLength(0))
+	require.NoError(t, err)
+
+	long := strings.Repeat("x", 1000)
+
+	for _, raw := range []string{long, long + "-abc", "bad"} {
+		_, err := r.parseID(raw)
+
+		var invalidID *gidx.ErrInvalidID
+
+		assert.ErrorAs(t, err, &invalidID, "sanitized errors keep the gidx error")
+		assert.NotContains(t, err.Error(), long)
+		assert.Less(t, len(err.Error()), 200, err.Error())
+	}
+
+	_, err = r.parseID("bad")
+	assert.EqualError(t, err, "invalid id: expected id format is prefix-id, but received bad")
+
+	result := r.execute(context.Background(), &postData{
+		Query: `query($r: [_Any!]!) { _entities(representations: $r) { __typename } }`,
+		Variables: map[string]interface{}{"r": []interface{}{
+			map[string]interface{}{"__typename": "Node\n{\"level\":\"error\"}", "id": "testsrv-abc"},
+			map[string]interface{}{"__typename": "Node", "id": long + "-abc"},
+		}},
+	})
+	require.Len(t, result.Errors, 2)
+
+	messages := []string{result.Errors[0].Message, result.Errors[1].Message}
+	assert.ElementsMatch(t, []string{
+		"Node�{\"level\":\"error\"} is an unknown interface type",
+		strings.Repeat("x", maxUntrustedLength) + truncatedMarker + " is an unknown id prefix",
+	}, messages)
+}
@@ -0,0 +1,242 @@
+package graphapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+	"go.uber.org/zap"
+)
+
+// SchemaProblem is a problem found in a schema by CheckSchema
+type SchemaProblem struct {
+	// Source and Line locate the problem, they're empty for problems with
+	// the schema as a whole
+	Source string `json:"source,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	// Type is the type the problem is with, if any
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p SchemaProblem) String() string {
+	var sb strings.Builder
+
+	if p.Source != "" {
+		sb.WriteString(p.Source)
+		sb.WriteString(":")
+	}
+
+	if p.Line != 0 {
+		fmt.Fprintf(&sb, "%d:", p.Line)
+	}
+
+	if sb.Len() != 0 {
+		sb.WriteString(" ")
+	}
+
+	if p.Type != "" {
+		sb.WriteString(p.Type)
+		sb.WriteString(": ")
+	}
+
+	sb.WriteString(p.Message)
+
+	return sb.String()
+}
+
+// CheckSchema checks the schema made of sources the way NewResolver reads
+// it, returning the prefixes it would serve and every problem found rather
+// than only the first. Types implementing interfaces without a prefix, which
+// NewResolver skips with a warning, prefixes gidx ids can't have and
+// prefixes claimed by several types, of which NewResolver keeps the last, are
+// reported as problems too. The schema is valid when no problems are
+// returned.
+func CheckSchema(sources []SchemaSource, opts ...Option) ([]PrefixType, []SchemaProblem) {
+	if len(sources) == 0 {
+		return nil, []SchemaProblem{{Message: "no schema given"}}
+	}
+
+	astSources := make([]*ast.Source, len(sources))
+	for i, src := range sources {
+		astSources[i] = &ast.Source{Name: src.Name, Input: src.SDL}
+	}
+
+	doc, err := parser.ParseSchemas(astSources...)
+	if err != nil {
+		return nil, []SchemaProblem{parseProblem(err)}
+	}
+
+	// cfg is the options, for the checks depending on them
+	cfg := &Resolver{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	problems := []SchemaProblem{}
+	implementsNode := false
+
+	// owners are the first type claiming each prefix, and deprecatedOwners
+	// the first type deprecating each prefix
+	owners := map[string]*ast.Definition{}
+	deprecatedOwners := map[string]*ast.Definition{}
+
+	for _, def := range doc.Definitions {
+		if len(def.Interfaces) == 0 {
+			continue
+		}
+
+		for _, iface := range def.Interfaces {
+			if iface == "Node" {
+				implementsNode = true
+			}
+		}
+
+		pd := def.Directives.ForName("prefixedID")
+		if pd == nil {
+			problems = append(problems, definitionProblem(def, "missing @prefixedID directive"))
+			continue
+		}
+
+		prefix, pattern, ok := directivePrefix(pd)
+		if !ok || prefix == "" {
+			problems = append(problems, definitionProblem(def, "missing prefix on @prefixedID directive"))
+			continue
+		}
+
+		if !pattern && isPrefixPattern(prefix) {
+			problems = append(problems, definitionProblem(def, "invalid "+prefixProblem(prefix, cfg.wildcardPrefixes)+", patterns are declared with prefixPattern"))
+			continue
+		}
+
+		if problem := cfg.declaredPrefixProblem(prefix, pattern); problem != "" {
+			problems = append(problems, definitionProblem(def, "invalid "+problem))
+			continue
+		}
+
+		prev, ok := owners[prefix]
+		switch {
+		case !ok:
+			owners[prefix] = def
+		case prev.Name != def.Name:
+			problems = append(problems, definitionProblem(def, fmt.Sprintf("prefix %s is already used by %s at %s", prefix, prev.Name, positionString(prev.Position))))
+		}
+
+		if pattern {
+			continue
+		}
+
+		for _, deprecated := range deprecatedPrefixesOf(pd) {
+			if problem := prefixProblem(deprecated, false); problem != "" {
+				problems = append(problems, definitionProblem(def, "invalid deprecated "+problem))
+				continue
+			}
+
+			prev, ok := deprecatedOwners[deprecated]
+			switch {
+			case !ok:
+				deprecatedOwners[deprecated] = def
+			case prev.Name != def.Name:
+				problems = append(problems, definitionProblem(def, fmt.Sprintf("deprecated prefix %s is already deprecated by %s at %s", deprecated, prev.Name, positionString(prev.Position))))
+			}
+		}
+	}
+
+	// deprecated prefixes still declared as the prefix of a type resolve to
+	// that type, so they're known once every prefix is
+	for deprecated, def := range deprecatedOwners {
+		if owner, ok := owners[deprecated]; ok {
+			problems = append(problems, definitionProblem(def, fmt.Sprintf("deprecated prefix %s is the prefix of %s at %s", deprecated, owner.Name, positionString(owner.Position))))
+		}
+	}
+
+	if !implementsNode {
+		problems = append(problems, SchemaProblem{Message: "no type implements the Node interface"})
+	}
+
+	if len(problems) != 0 {
+		sortProblems(problems)
+
+		return nil, problems
+	}
+
+	// anything else NewResolver rejects, such as types graphql-go can't
+	// build, is reported as is
+	merged, err := MergeSchemas(sources)
+	if err != nil {
+		return nil, []SchemaProblem{{Message: err.Error()}}
+	}
+
+	r, err := NewResolver(zap.NewNop().Sugar(), merged, opts...)
+	if err != nil {
+		return nil, []SchemaProblem{{Message: err.Error()}}
+	}
+
+	return r.Prefixes(), nil
+}
+
+func definitionProblem(def *ast.Definition, message string) SchemaProblem {
+	p := SchemaProblem{Type: def.Name, Message: message}
+
+	if def.Position != nil {
+		p.Line = def.Position.Line
+
+		if def.Position.Src != nil {
+			p.Source = def.Position.Src.Name
+		}
+	}
+
+	return p
+}
+
+func parseProblem(err error) SchemaProblem {
+	gqlErr, ok := err.(*gqlerror.Error)
+	if !ok {
+		return SchemaProblem{Message: err.Error()}
+	}
+
+	p := SchemaProblem{Message: gqlErr.Message}
+
+	if src, ok := gqlErr.Extensions["file"].(string); ok {
+		p.Source = src
+	}
+
+	if len(gqlErr.Locations) != 0 {
+		p.Line = gqlErr.Locations[0].Line
+	}
+
+	return p
+}
+
+func positionString(pos *ast.Position) string {
+	if pos == nil {
+		return "an unknown position"
+	}
+
+	if pos.Src == nil || pos.Src.Name == "" {
+		return fmt.Sprintf("line %d", pos.Line)
+	}
+
+	return fmt.Sprintf("%s:%d", pos.Src.Name, pos.Line)
+}
+
+// sortProblems sorts problems by where they are, with problems of the whole
+// schema last
+func sortProblems(problems []SchemaProblem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+
+		if (a.Source == "" && a.Line == 0) != (b.Source == "" && b.Line == 0) {
+			return b.Source == "" && b.Line == 0
+		}
+
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+
+		return a.Line < b.Line
+	})
+}
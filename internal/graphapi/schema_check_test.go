@@ -0,0 +1,132 @@
+package graphapi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestCheckSchema(t *testing.T) {
+	testCases := []struct {
+		TestName         string
+		sources          []graphapi.SchemaSource
+		expectedPrefixes []graphapi.PrefixType
+		expectedProblems []string
+	}{
+		{
+			TestName: "valid",
+			sources: []graphapi.SchemaSource{
+				{Name: "users.graphql", SDL: usersSubgraph},
+				{Name: "servers.graphql", SDL: serversSubgraph},
+			},
+			expectedPrefixes: []graphapi.PrefixType{{Prefix: "testsrv", Type: "Server"}, {Prefix: "testusr", Type: "User"}},
+		},
+		{
+			TestName: "syntax error",
+			sources:  []graphapi.SchemaSource{{Name: "broken.graphql", SDL: "type User implements Node {\n\tid: ID!\n"}},
+			expectedProblems: []string{
+				"broken.graphql:3: Expected Name, found <EOF>",
+			},
+		},
+		{
+			TestName: "every problem is reported",
+			sources: []graphapi.SchemaSource{
+				{Name: "users.graphql", SDL: usersSubgraph},
+				{Name: "more.graphql", SDL: `type Token implements Node {
+	id: ID!
+}
+
+type Host implements Node @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+
+type Key implements Node @prefixedID {
+	id: ID!
+}`},
+			},
+			expectedProblems: []string{
+				"more.graphql:1: Token: missing @prefixedID directive",
+				"more.graphql:5: Host: prefix testusr is already used by User at users.graphql:6",
+				"more.graphql:9: Key: missing prefix on @prefixedID directive",
+			},
+		},
+		{
+			TestName: "invalid prefixes",
+			sources: []graphapi.SchemaSource{
+				{Name: "users.graphql", SDL: usersSubgraph},
+				{Name: "more.graphql", SDL: `type Host implements Node @prefixedID(prefix: "host") {
+	id: ID!
+}
+
+type Key implements Node @prefixedID(prefix: "Testkey") {
+	id: ID!
+}
+
+type Pool implements Node @prefixedID(prefix: "pool*") {
+	id: ID!
+}`},
+			},
+			expectedProblems: []string{
+				`more.graphql:1: Host: invalid prefix "host" of 4 characters, gidx prefixes are 7`,
+				`more.graphql:5: Key: invalid prefix "Testkey" not matching ^[a-z0-9]{7}$`,
+				`more.graphql:9: Pool: invalid prefix "pool*" of 5 characters, gidx prefixes are 7`,
+			},
+		},
+		{
+			TestName: "deprecated prefixes",
+			sources: []graphapi.SchemaSource{
+				{Name: "users.graphql", SDL: usersSubgraph},
+				{Name: "more.graphql", SDL: `type Host implements Node @prefixedID(prefix: "testhst", deprecatedPrefixes: ["host", "testusr"]) {
+	id: ID!
+}
+
+type Key implements Node @prefixedID(prefix: "testkey", deprecatedPrefixes: "oldhost") {
+	id: ID!
+}
+
+type Pool implements Node @prefixedID(prefix: "testpol", deprecatedPrefixes: ["oldhost"]) {
+	id: ID!
+}`},
+			},
+			expectedProblems: []string{
+				`more.graphql:1: Host: invalid deprecated prefix "host" of 4 characters, gidx prefixes are 7`,
+				"more.graphql:1: Host: deprecated prefix testusr is the prefix of User at users.graphql:6",
+				"more.graphql:9: Pool: deprecated prefix oldhost is already deprecated by Key at more.graphql:5",
+			},
+		},
+		{
+			TestName: "missing node interface",
+			sources: []graphapi.SchemaSource{{Name: "schema.graphql", SDL: `type User @prefixedID(prefix: "testusr") {
+	id: ID!
+}`}},
+			expectedProblems: []string{
+				"no type implements the Node interface",
+			},
+		},
+		{
+			TestName:         "no schema",
+			expectedProblems: []string{"no schema given"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			prefixes, problems := graphapi.CheckSchema(tt.sources)
+
+			messages := []string{}
+			for _, p := range problems {
+				messages = append(messages, p.String())
+			}
+
+			if tt.expectedProblems == nil {
+				assert.Empty(t, messages)
+			} else {
+				assert.Equal(t, tt.expectedProblems, messages)
+			}
+
+			assert.Equal(t, tt.expectedPrefixes, prefixes)
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+// schemaSeed replays a single generated schema, as reported by a failing run
+var schemaSeed = flag.Int64("schema-seed", 0, "seed of the generated schema to test, random schemas are tested when zero")
+
+// prefixLetters are the letters of generated prefixes, kept few so prefixes
+// often collide and share wildcard stems
+const prefixLetters = "abcd"
+
+// genObject is a generated object type and what NewResolver is expected to
+// read from it
+type genObject struct {
+	name       string
+	interfaces []string
+	prefix     string
+	// hasPrefix is false when the @prefixedID directive or its prefix is
+	// missing
+	hasPrefix bool
+}
+
+// genSchema is a generated schema
+type genSchema struct {
+	sdl       string
+	objects   []genObject
+	wildcards bool
+}
+
+// schemaGenerator generates random schemas. Object types get a random mix of
+// interfaces and prefixes, valid or not, and the schema is padded with other
+// definitions, descriptions and comments containing braces and quotes.
+type schemaGenerator struct {
+	rand *rand.Rand
+}
+
+func newSchemaGenerator(seed int64) *schemaGenerator {
+	return &schemaGenerator{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (g *schemaGenerator) chance(p float64) bool {
+	return g.rand.Float64() < p
+}
+
+func (g *schemaGenerator) letters(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = prefixLetters[g.rand.Intn(len(prefixLetters))]
+	}
+
+	return string(b)
+}
+
+// prefix returns a prefix for a @prefixedID directive
+func (g *schemaGenerator) prefix() string {
+	switch n := g.rand.Intn(20); {
+	case n < 12:
+		return g.letters(gidx.PrefixPartLength)
+	case n < 15:
+		return g.letters(1+g.rand.Intn(gidx.PrefixPartLength-1)) + prefixWildcard
+	case n < 16:
+		// wildcard in the wrong place or too long
+		return g.letters(2) + prefixWildcard + g.letters(2)
+	case n < 17:
+		return g.letters(gidx.PrefixPartLength) + prefixWildcard
+	case n < 18:
+		return ""
+	default:
+		return strings.ToUpper(g.letters(3)) + fmt.Sprint(g.rand.Intn(1000))
+	}
+}
+
+// description returns a description, comment or nothing to write before a
+// definition or field
+func (g *schemaGenerator) description() string {
+	switch g.rand.Intn(6) {
+	case 0:
+		return "\"a } ( [ \\\" \\\\ # description\"\n"
+	case 1:
+		return "\"\"\"\nblock } description with \\\"\"\" and \" quotes {\n\"\"\"\n"
+	case 2:
+		return "# comment with a } and \"\n"
+	default:
+		return ""
+	}
+}
+
+// fields returns the fields of an object or interface
+func (g *schemaGenerator) fields() string {
+	var sb strings.Builder
+
+	sb.WriteString("{\n  id: ID!\n")
+
+	for i := g.rand.Intn(3); i > 0; i-- {
+		sb.WriteString(g.description())
+		fmt.Fprintf(&sb, "  field%d(filter: Filter = {name: \"}\"}, ids: [ID!] = [\"a\"]): String\n", i)
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// generate returns a random valid schema
+func (g *schemaGenerator) generate() *genSchema {
+	s := &genSchema{wildcards: g.chance(0.5)}
+
+	interfaces := []string{"Node"}
+	if g.chance(0.1) {
+		interfaces = nil
+	}
+
+	for i := g.rand.Intn(4); i > 0; i-- {
+		interfaces = append(interfaces, fmt.Sprintf("Owner%d", i))
+	}
+
+	defs := []string{
+		"scalar Filter\n",
+		"type Query {\n  node(id: ID!): Node!\n}\n",
+		"enum Kind {\n  A\n  B\n}\n",
+		"input Options {\n  kind: Kind = A\n}\n",
+	}
+
+	for _, name := range interfaces {
+		defs = append(defs, fmt.Sprintf("%sinterface %s @key(fields: \"id\") %s", g.description(), name, g.fields()))
+	}
+
+	objects := map[string]genObject{}
+
+	for i := g.rand.Intn(12); i > 0; i-- {
+		obj := genObject{name: fmt.Sprintf("Type%d", i)}
+
+		for _, name := range interfaces {
+			if g.chance(0.6) {
+				obj.interfaces = append(obj.interfaces, name)
+			}
+		}
+
+		var sb strings.Builder
+
+		sb.WriteString(g.description())
+		fmt.Fprintf(&sb, "type %s", obj.name)
+
+		if len(obj.interfaces) > 0 {
+			fmt.Fprintf(&sb, " implements %s", strings.Join(obj.interfaces, " & "))
+		}
+
+		sb.WriteString(` @key(fields: "id")`)
+
+		switch n := g.rand.Intn(10); {
+		case n == 0:
+		case n == 1:
+			sb.WriteString(" @prefixedID")
+		case n == 2:
+			sb.WriteString(` @prefixedID(other: "testsrv")`)
+		default:
+			obj.prefix = g.prefix()
+			obj.hasPrefix = true
+
+			fmt.Fprintf(&sb, " @prefixedID(prefix: %q)", obj.prefix)
+		}
+
+		sb.WriteString(" ")
+		sb.WriteString(g.fields())
+
+		defs = append(defs, sb.String())
+		objects[sb.String()] = obj
+	}
+
+	if g.chance(0.8) {
+		defs = append(defs, "directive @prefixedID(prefix: String!) on OBJECT\n")
+	}
+
+	g.rand.Shuffle(len(defs), func(i, j int) { defs[i], defs[j] = defs[j], defs[i] })
+
+	for _, def := range defs {
+		if obj, ok := objects[def]; ok {
+			s.objects = append(s.objects, obj)
+		}
+	}
+
+	s.sdl = strings.Join(defs, "")
+
+	return s
+}
+
+// corrupt returns sdl with a syntax error added
+func (g *schemaGenerator) corrupt(sdl string) string {
+	broken := []string{
+		"type Broken {\n  id: ID!\n",
+		"\"unterminated description\ntype Broken { id: ID! }\n",
+		"}\n",
+		"interface\n",
+		"type Broken implements Node @prefixedID(prefix: \"broken\" { id: ID! }\n",
+		"= Node\n",
+	}
+
+	return sdl + broken[g.rand.Intn(len(broken))]
+}
+
+// expectedPrefixes returns the type name expected for each prefix. Objects
+// without interfaces or a prefix are skipped, as are invalid wildcard
+// prefixes when wildcards are enabled, and later objects replace earlier
+// ones with the same prefix.
+func (s *genSchema) expectedPrefixes() map[string]string {
+	prefixes := map[string]string{}
+
+	for _, obj := range s.objects {
+		if len(obj.interfaces) == 0 || !obj.hasPrefix {
+			continue
+		}
+
+		if s.wildcards && strings.Contains(obj.prefix, prefixWildcard) && !isWildcardPrefix(obj.prefix) {
+			continue
+		}
+
+		prefixes[obj.prefix] = obj.name
+	}
+
+	return prefixes
+}
+
+// expectedInterfaces returns the interfaces implemented by any object
+func (s *genSchema) expectedInterfaces() []string {
+	interfaces := map[string]bool{}
+
+	for _, obj := range s.objects {
+		for _, name := range obj.interfaces {
+			interfaces[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(interfaces))
+	for name := range interfaces {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// implemented reports whether any object implements the interface
+func (s *genSchema) implemented(name string) bool {
+	for _, obj := range s.objects {
+		for _, iface := range obj.interfaces {
+			if iface == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// expectedType returns the type name an id prefix should resolve to, found
+// by checking every prefix rather than with the prefix matcher
+func (s *genSchema) expectedType(prefix string) string {
+	prefixes := s.expectedPrefixes()
+
+	if name, ok := prefixes[prefix]; ok || !s.wildcards {
+		return name
+	}
+
+	var stem, name string
+
+	for p, n := range prefixes {
+		if !isWildcardPrefix(p) {
+			continue
+		}
+
+		if p = strings.TrimSuffix(p, prefixWildcard); strings.HasPrefix(prefix, p) && len(p) >= len(stem) {
+			stem, name = p, n
+		}
+	}
+
+	return name
+}
+
+// TestGeneratedSchemas checks NewResolver against random schemas. A failing
+// schema can be replayed with go test -run TestGeneratedSchemas -schema-seed
+// and the seed from the failure.
+func TestGeneratedSchemas(t *testing.T) {
+	seeds := []int64{*schemaSeed}
+
+	if *schemaSeed == 0 {
+		cases := 1000
+		if testing.Short() {
+			cases = 100
+		}
+
+		seeds = make([]int64, cases)
+
+		base := time.Now().UnixNano()
+		for i := range seeds {
+			seeds[i] = base + int64(i)
+		}
+	}
+
+	for _, seed := range seeds {
+		g := newSchemaGenerator(seed)
+		s := g.generate()
+
+		ok := t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			testGeneratedSchema(t, g, s)
+		})
+		if !ok {
+			t.Logf("schema with seed %d:\n%s", seed, s.sdl)
+
+			return
+		}
+	}
+}
+
+func testGeneratedSchema(t *testing.T, g *schemaGenerator, s *genSchema) {
+	var opts []Option
+	if s.wildcards {
+		opts = append(opts, WithWildcardPrefixes())
+	}
+
+	// every split of the schema parses to the same definitions
+	expectedDoc, err := parser.ParseSchema(&ast.Source{Input: s.sdl})
+	require.NoError(t, err)
+
+	for n := 2; n <= 8; n++ {
+		doc := &ast.SchemaDocument{}
+
+		for _, part := range splitSchema(s.sdl, n) {
+			partDoc, err := parser.ParseSchema(&ast.Source{Input: part})
+			require.NoError(t, err, "split into %d parts", n)
+
+			doc.Merge(partDoc)
+		}
+
+		require.Equal(t, definitionNames(expectedDoc), definitionNames(doc), "split into %d parts", n)
+	}
+
+	// syntax errors are reported as parse errors, not invalid schemas
+	_, err = NewResolver(zap.NewNop().Sugar(), g.corrupt(s.sdl), opts...)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &ErrInvalidSchema{}), "syntax errors aren't invalid schema errors: %v", err)
+
+	r, err := NewResolver(zap.NewNop().Sugar(), s.sdl, opts...)
+
+	expected := s.expectedPrefixes()
+	interfaces := s.expectedInterfaces()
+
+	switch {
+	case len(expected) == 0:
+		assert.ErrorAs(t, err, &ErrInvalidSchema{}, "schemas without valid object types are invalid")
+
+		return
+	case !s.implemented("Node"):
+		assert.ErrorAs(t, err, &ErrInvalidSchema{}, "schemas without a Node interface are invalid")
+
+		return
+	}
+
+	require.NoError(t, err)
+
+	actual := map[string]string{}
+	for prefix, obj := range r.prefixMap {
+		actual[prefix] = obj.Name()
+	}
+
+	assert.Equal(t, expected, actual)
+
+	actualInterfaces := make([]string, 0, len(r.interfaceMap))
+	for name := range r.interfaceMap {
+		actualInterfaces = append(actualInterfaces, name)
+	}
+
+	sort.Strings(actualInterfaces)
+	assert.Equal(t, interfaces, actualInterfaces)
+
+	assert.Len(t, r.objects, len(expected))
+	assert.NotEmpty(t, r.SDLChecksum())
+
+	// ids resolve to the expected types, for the schema's prefixes and some
+	// random ones
+	var prefixes []string
+
+	for prefix := range expected {
+		if len(prefix) == gidx.PrefixPartLength && !strings.Contains(prefix, prefixWildcard) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		prefixes = append(prefixes, g.letters(gidx.PrefixPartLength))
+	}
+
+	for _, prefix := range prefixes {
+		id := gidx.PrefixedID(prefix + "-abc")
+
+		node, err := r.GetNode(context.Background(), id)
+
+		if name := s.expectedType(prefix); name != "" {
+			if assert.NoError(t, err, id) {
+				assert.Equal(t, name, node.GraphType.Name(), id)
+			}
+		} else {
+			assert.ErrorIs(t, err, ErrUnknownPrefix, id)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package graphapi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// SchemaSource is a schema document, such as the sdl of one subgraph, named
+// so errors point at the document they're in
+type SchemaSource struct {
+	Name string
+	SDL  string
+}
+
+// schemaFileExtensions are the extensions of the schema files read from a
+// directory
+var schemaFileExtensions = map[string]bool{".graphql": true, ".graphqls": true, ".gql": true}
+
+// ReadSchemaFiles reads the schema files in paths. Directories are read as
+// every .graphql, .graphqls and .gql file directly in them, sorted by name.
+func ReadSchemaFiles(paths []string) ([]SchemaSource, error) {
+	sources := []SchemaSource{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files := []string{path}
+
+		if info.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+
+			files = files[:0]
+
+			for _, entry := range entries {
+				if !entry.IsDir() && schemaFileExtensions[filepath.Ext(entry.Name())] {
+					files = append(files, filepath.Join(path, entry.Name()))
+				}
+			}
+
+			sort.Strings(files)
+		}
+
+		for _, file := range files {
+			sdl, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			sources = append(sources, SchemaSource{Name: file, SDL: string(sdl)})
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, newInvalidSchemaError("no schema files found in " + strings.Join(paths, ", "))
+	}
+
+	return sources, nil
+}
+
+// LoadSchemaFiles reads the schema files in paths and merges them
+func LoadSchemaFiles(paths []string) (string, error) {
+	sources, err := ReadSchemaFiles(paths)
+	if err != nil {
+		return "", err
+	}
+
+	return MergeSchemas(sources)
+}
+
+// MergeSchemas returns a schema combining the documents of sources, to be
+// passed to NewResolver. The prefixes of every document end up in the same
+// prefix map, so definitions shared by the documents, such as the Node
+// interface and the @prefixedID directive, may be repeated. A prefix given
+// to different types in different documents is rejected.
+func MergeSchemas(sources []SchemaSource) (string, error) {
+	if len(sources) == 1 {
+		return sources[0].SDL, nil
+	}
+
+	// owners maps prefixes to the type and source claiming them
+	type owner struct {
+		typeName string
+		source   string
+	}
+
+	owners := map[string]owner{}
+	parts := make([]string, 0, len(sources))
+
+	for _, src := range sources {
+		doc, err := parser.ParseSchema(&ast.Source{Name: src.Name, Input: src.SDL})
+		if err != nil {
+			return "", err
+		}
+
+		for _, def := range doc.Definitions {
+			prefix, ok := prefixOfDefinition(def)
+			if !ok {
+				continue
+			}
+
+			if prev, ok := owners[prefix]; ok && prev.typeName != def.Name {
+				return "", newInvalidSchemaError(fmt.Sprintf("prefix %s is used by %s in %s and %s in %s", prefix, prev.typeName, prev.source, def.Name, src.Name))
+			}
+
+			owners[prefix] = owner{typeName: def.Name, source: src.Name}
+		}
+
+		parts = append(parts, src.SDL)
+	}
+
+	return strings.Join(parts, "\n"), nil
+}
+
+// prefixOfDefinition returns the @prefixedID prefix or prefix pattern of def
+func prefixOfDefinition(def *ast.Definition) (string, bool) {
+	pd := def.Directives.ForName("prefixedID")
+	if pd == nil {
+		return "", false
+	}
+
+	prefix, _, ok := directivePrefix(pd)
+
+	return prefix, ok
+}
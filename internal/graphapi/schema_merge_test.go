@@ -0,0 +1,97 @@
+package graphapi_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+const (
+	usersSubgraph = `directive @prefixedID(prefix: String!) on OBJECT
+
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type User implements Node @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}`
+
+	serversSubgraph = `directive @prefixedID(prefix: String!) on OBJECT
+
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Server implements Node @key(fields: "id") @prefixedID(prefix: "testsrv") {
+	id: ID!
+}`
+)
+
+func TestLoadSchemaFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, sdl := range map[string]string{
+		"users.graphql":   usersSubgraph,
+		"servers.graphql": serversSubgraph,
+		"README.md":       "not a schema",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(sdl), 0o600))
+	}
+
+	sources, err := graphapi.ReadSchemaFiles([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	assert.Equal(t, filepath.Join(dir, "servers.graphql"), sources[0].Name)
+	assert.Equal(t, filepath.Join(dir, "users.graphql"), sources[1].Name)
+
+	schema, err := graphapi.LoadSchemaFiles([]string{dir})
+	require.NoError(t, err)
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), schema)
+	require.NoError(t, err)
+
+	assert.Equal(t, []graphapi.PrefixType{
+		{Prefix: "testsrv", Type: "Server"},
+		{Prefix: "testusr", Type: "User"},
+	}, r.Prefixes())
+
+	// files and directories can be mixed
+	single := filepath.Join(t.TempDir(), "schema.graphql")
+	require.NoError(t, os.WriteFile(single, []byte(usersSubgraph), 0o600))
+
+	sources, err = graphapi.ReadSchemaFiles([]string{single, dir})
+	require.NoError(t, err)
+	assert.Len(t, sources, 3)
+
+	_, err = graphapi.ReadSchemaFiles([]string{t.TempDir()})
+	assert.ErrorAs(t, err, &graphapi.ErrInvalidSchema{})
+}
+
+func TestMergeSchemasConflict(t *testing.T) {
+	_, err := graphapi.MergeSchemas([]graphapi.SchemaSource{
+		{Name: "users.graphql", SDL: usersSubgraph},
+		{Name: "accounts.graphql", SDL: `type Account implements Node @prefixedID(prefix: "testusr") { id: ID! }`},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prefix testusr is used by User in users.graphql and Account in accounts.graphql")
+
+	// the same type may be repeated
+	_, err = graphapi.MergeSchemas([]graphapi.SchemaSource{
+		{Name: "users.graphql", SDL: usersSubgraph},
+		{Name: "more-users.graphql", SDL: usersSubgraph},
+	})
+	assert.NoError(t, err)
+
+	// syntax errors name the file
+	_, err = graphapi.MergeSchemas([]graphapi.SchemaSource{
+		{Name: "users.graphql", SDL: usersSubgraph},
+		{Name: "broken.graphql", SDL: "type Broken {"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken.graphql")
+}
@@ -0,0 +1,130 @@
+package graphapi
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// parallelParseMinSize is the smallest schema parsed in parallel. Smaller
+// schemas parse quickly enough that splitting them isn't worth it.
+const parallelParseMinSize = 256 << 10
+
+// parseSchema parses rawSchema, splitting large schemas into chunks of whole
+// definitions that are parsed concurrently. Any error is reported by parsing
+// the schema again in one piece, so error positions match the input.
+func parseSchema(rawSchema string) (*ast.SchemaDocument, error) {
+	chunks := 1
+	if len(rawSchema) >= parallelParseMinSize {
+		chunks = runtime.GOMAXPROCS(0)
+	}
+
+	parts := splitSchema(rawSchema, chunks)
+	if len(parts) < 2 {
+		return parser.ParseSchemas(&ast.Source{Input: rawSchema})
+	}
+
+	var (
+		wg   sync.WaitGroup
+		docs = make([]*ast.SchemaDocument, len(parts))
+		errs = make([]error, len(parts))
+	)
+
+	for i, part := range parts {
+		wg.Add(1)
+
+		go func(i int, part string) {
+			defer wg.Done()
+
+			docs[i], errs[i] = parser.ParseSchema(&ast.Source{Input: part})
+		}(i, part)
+	}
+
+	wg.Wait()
+
+	doc := &ast.SchemaDocument{}
+
+	for i := range docs {
+		if errs[i] != nil {
+			return parser.ParseSchemas(&ast.Source{Input: rawSchema})
+		}
+
+		doc.Merge(docs[i])
+	}
+
+	return doc, nil
+}
+
+// splitSchema splits s into up to n parts of roughly equal size. Parts only
+// end after a closing brace outside of any braces, parentheses, strings or
+// comments, which always completes a definition.
+func splitSchema(s string, n int) []string {
+	if n < 2 {
+		return []string{s}
+	}
+
+	var (
+		parts  []string
+		start  int
+		depth  int
+		target = len(s) / n
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case '"':
+			if strings.HasPrefix(s[i:], `"""`) {
+				i = skipBlockString(s, i+3)
+			} else {
+				i = skipString(s, i+1)
+			}
+		case '{', '(', '[':
+			depth++
+		case '}', ')', ']':
+			depth--
+
+			if s[i] == '}' && depth == 0 && i+1-start >= target && len(parts) < n-1 {
+				parts = append(parts, s[start:i+1])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// skipString returns the index of the quote closing the string starting at i
+func skipString(s string, i int) int {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"', '\n':
+			return i
+		}
+	}
+
+	return i
+}
+
+// skipBlockString returns the index of the last quote closing the block
+// string starting at i
+func skipBlockString(s string, i int) int {
+	for ; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], `\"""`):
+			i += 3
+		case strings.HasPrefix(s[i:], `"""`):
+			return i + 2
+		}
+	}
+
+	return i
+}
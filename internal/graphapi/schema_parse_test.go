@@ -0,0 +1,106 @@
+package graphapi
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"go.uber.org/zap"
+)
+
+// largeSchema returns a schema with n object types spread across a few
+// interfaces, similar to a supergraph sized schema
+func largeSchema(n int) string {
+	var sb strings.Builder
+
+	sb.WriteString("directive @prefixedID(prefix: String!) on OBJECT\n")
+	sb.WriteString("interface Node @key(fields: \"id\") {\n  id: ID!\n}\n")
+
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&sb, "\"\"\"\nOwner %d, with a } in its description\n\"\"\"\ninterface Owner%d @key(fields: \"id\") {\n  id: ID!\n}\n", i, i)
+	}
+
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "# type %d }\ntype Type%d implements Node & Owner%d @key(fields: \"id\") @prefixedID(prefix: \"t%06d\") {\n  id: ID!\n  \"the name {\"\n  name(filter: Filter = {name: \"x\"}): String!\n}\n", i, i, i%20, i)
+	}
+
+	sb.WriteString("scalar Filter\nunion Owners = Owner0 | Owner1\ntype Query {\n  node(id: ID!): Node!\n}\n")
+
+	return sb.String()
+}
+
+func definitionNames(doc *ast.SchemaDocument) []string {
+	names := make([]string, 0, len(doc.Definitions))
+	for _, def := range doc.Definitions {
+		names = append(names, def.Name)
+	}
+
+	return names
+}
+
+func TestSplitSchema(t *testing.T) {
+	schema := largeSchema(100)
+
+	expected, err := parser.ParseSchema(&ast.Source{Input: schema})
+	require.NoError(t, err)
+
+	for _, n := range []int{1, 2, 3, 8, 1000} {
+		parts := splitSchema(schema, n)
+		assert.LessOrEqual(t, len(parts), n)
+		assert.Equal(t, schema, strings.Join(parts, ""))
+
+		doc := &ast.SchemaDocument{}
+
+		for _, part := range parts {
+			partDoc, err := parser.ParseSchema(&ast.Source{Input: part})
+			require.NoError(t, err, n)
+
+			doc.Merge(partDoc)
+		}
+
+		assert.Equal(t, definitionNames(expected), definitionNames(doc), n)
+		assert.Len(t, doc.Directives, 1)
+	}
+}
+
+func TestParseSchemaParallel(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	schema := largeSchema(5000)
+	require.GreaterOrEqual(t, len(schema), parallelParseMinSize)
+
+	expected, err := parser.ParseSchema(&ast.Source{Input: schema})
+	require.NoError(t, err)
+
+	doc, err := parseSchema(schema)
+	require.NoError(t, err)
+	assert.Equal(t, definitionNames(expected), definitionNames(doc))
+
+	broken := schema + "type Broken {\n"
+
+	_, expectedErr := parser.ParseSchema(&ast.Source{Input: broken})
+	require.Error(t, expectedErr)
+
+	_, err = parseSchema(broken)
+	assert.Equal(t, expectedErr, err, "errors are reported for the whole schema")
+}
+
+func BenchmarkNewResolver(b *testing.B) {
+	for _, n := range []int{100, 1000, 5000} {
+		schema := largeSchema(n)
+
+		b.Run(fmt.Sprintf("types=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				_, err := NewResolver(zap.NewNop().Sugar(), schema)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package graphapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const federationLink = `extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key"])`
+
+// SDL returns the subgraph schema served by the resolver: every type with a
+// known prefix, the interfaces they implement, the node queries and the
+// prefix lookups. Types and
+// interfaces are sorted so the same schema always produces the same SDL.
+// The SDL is built on first use.
+func (r *Resolver) SDL() string {
+	r.initSDL()
+
+	return r.sdl
+}
+
+// SDLChecksum returns the sha256 checksum of the resolver's SDL
+func (r *Resolver) SDLChecksum() string {
+	r.initSDL()
+
+	return r.schemaChecksum
+}
+
+// initSDL builds the SDL and its checksum once
+func (r *Resolver) initSDL() {
+	r.sdlOnce.Do(func() {
+		r.sdl = r.buildSDL()
+
+		sum := sha256.Sum256([]byte(r.sdl))
+		r.schemaChecksum = "sha256:" + hex.EncodeToString(sum[:])
+	})
+}
+
+func (r *Resolver) buildSDL() string {
+	prefixes := make([]string, 0, len(r.prefixMap))
+	for prefix := range r.prefixMap {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool {
+		return r.prefixMap[prefixes[i]].Name() < r.prefixMap[prefixes[j]].Name()
+	})
+
+	ifaces := map[string]bool{}
+
+	var sb strings.Builder
+
+	sb.WriteString(federationLink + "\n")
+
+	directiveArgs := "prefix: String!"
+	if r.prefixPatterns {
+		directiveArgs = "prefix: String, prefixPattern: String"
+	}
+
+	if len(r.deprecatedPrefixes) != 0 {
+		directiveArgs += ", deprecatedPrefixes: [String!]"
+	}
+
+	fmt.Fprintf(&sb, "directive @prefixedID(%s) on OBJECT\n", directiveArgs)
+
+	sb.WriteString(r.serviceSDL())
+
+	// nodeFields are the fields of every type and interface
+	nodeFields := "  id: ID!\n"
+	if len(r.services) != 0 {
+		nodeFields += "  service: " + serviceTypeName + "\n"
+	}
+
+	for _, prefix := range prefixes {
+		obj := r.prefixMap[prefix]
+
+		names := make([]string, len(obj.Interfaces()))
+		for i, iface := range obj.Interfaces() {
+			names[i] = iface.Name()
+			ifaces[iface.Name()] = true
+		}
+
+		keys, fields := "", ""
+
+		if tk, ok := r.entityKeys[obj.Name()]; ok {
+			for _, key := range tk.keys {
+				keys += fmt.Sprintf(" @key(fields: %q)", strings.Join(key, " "))
+			}
+
+			for _, f := range tk.fields {
+				fields += fmt.Sprintf("  %s: %s\n", f.name, f.sdlType)
+			}
+		}
+
+		arg := "prefix"
+		if isPrefixPattern(prefix) {
+			arg = "prefixPattern"
+		}
+
+		fmt.Fprintf(&sb, "type %s implements %s @key(fields: \"id\")%s @prefixedID(%s: %q%s)%s {\n%s%s}\n",
+			obj.Name(), strings.Join(names, " & "), keys, arg, prefix, r.deprecatedPrefixesSDL(obj.Name()), r.serviceDirectiveSDL(obj.Name()), nodeFields, fields)
+	}
+
+	if r.unknownPrefixType != nil {
+		ifaces["Node"] = true
+
+		fmt.Fprintf(&sb, "type %s implements Node @key(fields: \"id\") {\n%s}\n", r.unknownPrefixType.Name(), nodeFields)
+	}
+
+	for _, name := range sortedKeys(ifaces) {
+		fmt.Fprintf(&sb, "interface %s @key(fields: \"id\") {\n%s}\n", name, nodeFields)
+	}
+
+	if r.prefixedIDArgs {
+		sb.WriteString("scalar " + prefixedIDScalarName + "\n")
+	}
+
+	// the prefix lookups are part of the sdl so gateways can route them
+	sb.WriteString("type PrefixMapping {\n  prefix: String!\n  type: String!\n  deprecated: Boolean!\n}\n")
+
+	fmt.Fprintf(&sb, "type Query {\n  node(id: %[1]s!): Node\n  nodes(ids: [%[1]s!]!): [Node]!\n  typePrefix(typename: String!): String\n  prefixes: [PrefixMapping!]!\n}\n", r.idArgType().Name())
+
+	return sb.String()
+}
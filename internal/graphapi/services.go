@@ -0,0 +1,133 @@
+package graphapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// serviceTypeName is the name of the type of the service field
+const serviceTypeName = "NodeService"
+
+// Service is the service owning the nodes of a type, declared by the
+// @service(name: "...", url: "...") directive of the type
+type Service struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// nodeServiceType is the type of the service field of nodes
+var nodeServiceType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        serviceTypeName,
+	Description: "The service owning a node.",
+	Fields: graphql.Fields{
+		"name": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"url": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if s := p.Source.(*Service); s.URL != "" {
+					return s.URL, nil
+				}
+
+				return nil, nil
+			},
+		},
+	},
+})
+
+// serviceOf returns the service declared by the @service directive of def
+func serviceOf(def *ast.Definition) (*Service, bool) {
+	d := def.Directives.ForName("service")
+	if d == nil {
+		return nil, false
+	}
+
+	name := d.Arguments.ForName("name")
+	if name == nil || name.Value == nil || name.Value.Raw == "" {
+		return nil, false
+	}
+
+	s := &Service{Name: name.Value.Raw}
+
+	if url := d.Arguments.ForName("url"); url != nil && url.Value != nil {
+		s.URL = url.Value.Raw
+	}
+
+	return s, true
+}
+
+// readServices reads the services declared by the types of the schema.
+// Services are only served when a type declares one, so schemas without
+// them are served as before.
+func (r *Resolver) readServices() {
+	for _, def := range r.schemaDoc.Definitions {
+		if def.Kind != ast.Object {
+			continue
+		}
+
+		if def.Directives.ForName("service") != nil {
+			s, ok := serviceOf(def)
+			if !ok {
+				r.logger.Warnw("@service directive without a name, it isn't served", "graphql_type", def.Name)
+
+				continue
+			}
+
+			if r.services == nil {
+				r.services = map[string]*Service{}
+			}
+
+			r.services[def.Name] = s
+		}
+	}
+}
+
+// serviceField returns the service field added to every node type and
+// interface when the schema declares services
+func (r *Resolver) serviceField() *graphql.Field {
+	return &graphql.Field{
+		Type:        nodeServiceType,
+		Description: "The service owning the node, or null when its type declares none.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if s, ok := r.services[p.Info.ParentType.Name()]; ok {
+				return s, nil
+			}
+
+			return nil, nil
+		},
+	}
+}
+
+// serviceDirectiveSDL returns the @service directive of typeName in the
+// SDL, or an empty string when it has none
+func (r *Resolver) serviceDirectiveSDL(typeName string) string {
+	s, ok := r.services[typeName]
+	if !ok {
+		return ""
+	}
+
+	if s.URL == "" {
+		return fmt.Sprintf(" @service(name: %q)", s.Name)
+	}
+
+	return fmt.Sprintf(" @service(name: %q, url: %q)", s.Name, s.URL)
+}
+
+// serviceSDL returns the declarations of the @service directive and the
+// NodeService type for the SDL, when the schema declares services
+func (r *Resolver) serviceSDL() string {
+	if len(r.services) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("directive @service(name: String!, url: String) on OBJECT\n")
+	fmt.Fprintf(&sb, "type %s {\n  name: String!\n  url: String\n}\n", serviceTypeName)
+
+	return sb.String()
+}
@@ -0,0 +1,83 @@
+package graphapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const servicesTestSchema = `directive @prefixedID(prefix: String!) on OBJECT
+directive @service(name: String!, url: String) on OBJECT
+
+type LoadBalancer implements Node @key(fields: "id") @prefixedID(prefix: "loadbal") @service(name: "load-balancer-api", url: "https://lb.example.com/query") {
+	id: ID!
+}
+type Server implements Node @key(fields: "id") @prefixedID(prefix: "testsrv") @service(name: "server-api") {
+	id: ID!
+}
+type User implements Node @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+func TestServices(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), servicesTestSchema)
+	require.NoError(t, err)
+
+	query := &postData{Query: `{
+		lb: node(id: "loadbal-abc") { service { name url } }
+		srv: node(id: "testsrv-abc") { service { name url } }
+		usr: node(id: "testusr-abc") { service { name } }
+	}`}
+
+	result := r.execute(context.Background(), query)
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{
+		"lb":  map[string]interface{}{"service": map[string]interface{}{"name": "load-balancer-api", "url": "https://lb.example.com/query"}},
+		"srv": map[string]interface{}{"service": map[string]interface{}{"name": "server-api", "url": nil}},
+		"usr": map[string]interface{}{"service": nil},
+	}, result.Data)
+
+	query = &postData{
+		Query:     `query($representations:[_Any!]!){_entities(representations:$representations){... on Node { service { name } }}}`,
+		Variables: map[string]interface{}{"representations": []interface{}{map[string]interface{}{"__typename": "Node", "id": "loadbal-abc"}}},
+	}
+
+	result = r.execute(context.Background(), query)
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"_entities": []interface{}{map[string]interface{}{"service": map[string]interface{}{"name": "load-balancer-api"}}}}, result.Data)
+
+	res := r.resolveAPIResult(context.Background(), "loadbal-abc")
+	assert.Equal(t, &Service{Name: "load-balancer-api", URL: "https://lb.example.com/query"}, res.Service)
+
+	sdl := r.SDL()
+	assert.Contains(t, sdl, "directive @service(name: String!, url: String) on OBJECT\n")
+	assert.Contains(t, sdl, `@prefixedID(prefix: "testsrv") @service(name: "server-api") {
+  id: ID!
+  service: NodeService
+}`)
+	assert.Contains(t, sdl, "interface Node @key(fields: \"id\") {\n  id: ID!\n  service: NodeService\n}\n")
+
+	// the sdl declares the same services
+	again, err := NewResolver(zap.NewNop().Sugar(), sdl)
+	require.NoError(t, err)
+	assert.Equal(t, r.services, again.services)
+}
+
+func TestServicesUndeclared(t *testing.T) {
+	r, err := NewResolver(zap.NewNop().Sugar(), wildcardTestSchema)
+	require.NoError(t, err)
+
+	// schemas without services serve no service field
+	result := r.execute(context.Background(), &postData{Query: `{ node(id: "loadbal-abc") { service { name } } }`})
+	require.Len(t, result.Errors, 1)
+	assert.NotContains(t, r.SDL(), "service")
+}
@@ -0,0 +1,165 @@
+package graphapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// shadowTimeout is the longest a request is resolved against the candidate
+// schema
+const shadowTimeout = 10 * time.Second
+
+// Shadow comparison outcomes, used as the metrics label
+const (
+	// ShadowMatch is a candidate result equal to the served result
+	ShadowMatch = "match"
+	// ShadowMismatch is a candidate result with different data or errors
+	ShadowMismatch = "mismatch"
+	// ShadowDropped is a request that wasn't compared because the maximum
+	// number of comparisons were running
+	ShadowDropped = "dropped"
+	// ShadowTimeout is a request the candidate didn't resolve in time
+	ShadowTimeout = "timeout"
+)
+
+// Shadow resolves requests against a candidate schema in the background,
+// after they're resolved against the served schema, and compares the
+// results, so a schema change can be validated against live traffic before
+// it's served. A Shadow is safe for concurrent use.
+type Shadow struct {
+	candidate  *Resolver
+	sampleRate float64
+	slots      chan struct{}
+}
+
+// NewShadow returns a Shadow comparing the given fraction of requests with
+// their results from candidate, with at most concurrency comparisons running
+// at once; further requests aren't compared. candidate should be built
+// without an auditor or metrics so shadowed resolutions aren't recorded
+// twice.
+func NewShadow(candidate *Resolver, sampleRate float64, concurrency int) *Shadow {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &Shadow{
+		candidate:  candidate,
+		sampleRate: sampleRate,
+		slots:      make(chan struct{}, concurrency),
+	}
+}
+
+// WithShadow compares the results of graphql requests with their results
+// from the candidate schema of s. Only executed requests are compared, not
+// those denied by the policy or served from the response cache.
+func WithShadow(s *Shadow) Option {
+	return func(r *Resolver) {
+		r.shadow = s
+	}
+}
+
+// shadowRequest compares result, the result of p served by r, with the
+// result of p from the candidate schema in the background
+func (r *Resolver) shadowRequest(ctx context.Context, p *postData, result *graphql.Result) {
+	if r.shadow == nil || rand.Float64() >= r.shadow.sampleRate { //nolint:gosec // sampling doesn't need a secure source
+		return
+	}
+
+	select {
+	case r.shadow.slots <- struct{}{}:
+	default:
+		r.recordShadow(ShadowDropped)
+
+		return
+	}
+
+	// p is released when the request is done and result may be changed
+	// while it's written
+	req := *p
+	data, errs := result.Data, result.Errors
+
+	go func() {
+		defer func() { <-r.shadow.slots }()
+
+		defer func() {
+			if rec := recover(); rec != nil {
+				r.logger.Errorw("shadow request panicked", "error", rec)
+			}
+		}()
+
+		r.compareShadow(ctx, &req, data, errs)
+	}()
+}
+
+// compareShadow resolves p against the candidate schema and compares the
+// result with the served data and errors
+func (r *Resolver) compareShadow(ctx context.Context, p *postData, data interface{}, errs []gqlerrors.FormattedError) {
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, shadowTimeout)
+	defer cancel()
+
+	candidate := r.shadow.candidate.execute(ctx, p)
+
+	if ctx.Err() != nil {
+		r.recordShadow(ShadowTimeout)
+		r.logger.Warnw("shadow request timed out", "operation", p.Operation)
+
+		return
+	}
+
+	dataDiffers := !jsonEqual(data, candidate.Data)
+	errorsDiffer := !jsonEqual(canonicalErrors(errs), canonicalErrors(candidate.Errors))
+
+	if !dataDiffers && !errorsDiffer {
+		r.recordShadow(ShadowMatch)
+
+		return
+	}
+
+	r.recordShadow(ShadowMismatch)
+	r.logger.Warnw("shadow result differs from served result",
+		"operation", p.Operation,
+		"data_differs", dataDiffers,
+		"errors_differ", errorsDiffer,
+		"served_errors", len(errs),
+		"candidate_errors", len(candidate.Errors),
+		"candidate_schema_checksum", r.shadow.candidate.SDLChecksum(),
+	)
+}
+
+func (r *Resolver) recordShadow(outcome string) {
+	if r.metrics != nil {
+		r.metrics.ShadowComparison(outcome)
+	}
+}
+
+// jsonEqual reports whether a and b encode to the same json. Maps are
+// encoded with sorted keys, so equal results always encode the same.
+func jsonEqual(a, b interface{}) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(ja, jb)
+}
+
+// detachedContext keeps the values of a request context, such as the
+// authenticated subject, without being canceled when the request is done
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
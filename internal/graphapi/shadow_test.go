@@ -0,0 +1,82 @@
+package graphapi_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// shadowCounter counts shadow comparisons by outcome
+type shadowCounter struct {
+	panicCounter
+
+	mu       sync.Mutex
+	outcomes map[string]int
+}
+
+func (c *shadowCounter) ShadowComparison(outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.outcomes[outcome]++
+}
+
+func (c *shadowCounter) count(outcome string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.outcomes[outcome]
+}
+
+func TestShadow(t *testing.T) {
+	// the candidate renames the type of testsrv ids
+	candidate, err := graphapi.NewResolver(zap.NewNop().Sugar(), strings.Replace(validTestSchema, "type Server", "type Host", 1))
+	require.NoError(t, err)
+
+	counter := &shadowCounter{panicCounter: panicCounter{}, outcomes: map[string]int{}}
+
+	opts := []graphapi.Option{
+		graphapi.WithMetrics(counter),
+		graphapi.WithShadow(graphapi.NewShadow(candidate, 1, 4)),
+	}
+
+	resp, err := testQuery(validTestSchema, `{"query":"{ node(id: \"testusr-abc\") { __typename } }"}`, opts...)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"node":{"__typename":"User"}}`, string(resp.RawData))
+
+	require.Eventually(t, func() bool { return counter.count(graphapi.ShadowMatch) == 1 }, time.Second, time.Millisecond)
+
+	// the served result isn't affected by the candidate
+	resp, err = testQuery(validTestSchema, `{"query":"{ node(id: \"testsrv-abc\") { __typename } }"}`, opts...)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"node":{"__typename":"Server"}}`, string(resp.RawData))
+
+	require.Eventually(t, func() bool { return counter.count(graphapi.ShadowMismatch) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, counter.count(graphapi.ShadowMatch))
+}
+
+func TestShadowSampling(t *testing.T) {
+	candidate, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	counter := &shadowCounter{panicCounter: panicCounter{}, outcomes: map[string]int{}}
+
+	for i := 0; i < 10; i++ {
+		_, err := testQuery(validTestSchema, `{"query":"{ node(id: \"testusr-abc\") { __typename } }"}`,
+			graphapi.WithMetrics(counter),
+			graphapi.WithShadow(graphapi.NewShadow(candidate, 0, 4)),
+		)
+		require.NoError(t, err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Zero(t, counter.count(graphapi.ShadowMatch))
+}
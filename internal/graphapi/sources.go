@@ -0,0 +1,381 @@
+package graphapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.infratographer.com/x/gidx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.infratographer.com/node-resolver/internal/oidc"
+)
+
+// DefaultSourceTimeout bounds the requests made to the subgraphs of @source
+// types when no client is given
+const DefaultSourceTimeout = 10 * time.Second
+
+// ErrSourceLookup is returned for the fields of nodes whose subgraph couldn't
+// be queried
+var ErrSourceLookup = errors.New("source lookup failed")
+
+// attrSourceURL is the url of the subgraph queried by a source lookup span
+const attrSourceURL = attribute.Key("node_resolver.source.url")
+
+// sourceEntitiesQuery is the query sent to subgraphs, %s is replaced with the
+// type and its fields
+const sourceEntitiesQuery = "query($representations:[_Any!]!){_entities(representations:$representations){...on %s{%s}}}"
+
+// builtinScalars are the graphql scalars sourced fields can have, besides
+// the custom scalars declared by the schema
+var builtinScalars = map[string]*graphql.Scalar{
+	"String":  graphql.String,
+	"Int":     graphql.Int,
+	"Float":   graphql.Float,
+	"Boolean": graphql.Boolean,
+	"ID":      graphql.ID,
+}
+
+// WithSourceLookups serves the fields of types with a @source(url: "...")
+// directive, rather than only their ids, by querying _entities of the
+// subgraph at url for them. Only fields without arguments whose type is a
+// scalar, or a list of scalars, are served; they're always nullable since a
+// lookup may fail. client is used for the lookups, or a client with the
+// DefaultSourceTimeout when it's nil.
+func WithSourceLookups(client *http.Client) Option {
+	return func(r *Resolver) {
+		if client == nil {
+			client = &http.Client{Timeout: DefaultSourceTimeout}
+		}
+
+		r.sourceClient = client
+	}
+}
+
+// typeSource is the subgraph serving the fields of a type
+type typeSource struct {
+	url    string
+	fields []string
+}
+
+// sourceOf returns the url of the @source directive of def
+func sourceOf(def *ast.Definition) (string, bool) {
+	d := def.Directives.ForName("source")
+	if d == nil {
+		return "", false
+	}
+
+	arg := d.Arguments.ForName("url")
+	if arg == nil || arg.Value == nil || arg.Value.Raw == "" {
+		return "", false
+	}
+
+	return arg.Value.Raw, true
+}
+
+// addSourceScalars adds the custom scalars declared by the schema, which
+// sourced fields may have. Their values are passed through from the
+// subgraph as they are.
+func (r *Resolver) addSourceScalars() {
+	for _, def := range r.schemaDoc.Definitions {
+		if def.Kind != ast.Scalar || r.scalars[def.Name] != nil || builtinScalars[def.Name] != nil {
+			continue
+		}
+
+		r.scalars[def.Name] = graphql.NewScalar(graphql.ScalarConfig{
+			Name:      def.Name,
+			Serialize: func(v interface{}) interface{} { return v },
+		})
+	}
+}
+
+// addSourcedFields adds the fields of def served by its @source subgraph to
+// obj. Fields that can't be served are logged and left out.
+func (r *Resolver) addSourcedFields(obj *graphql.Object, def *ast.Definition) {
+	url, ok := sourceOf(def)
+	if !ok {
+		return
+	}
+
+	src := &typeSource{url: url}
+
+	for _, field := range def.Fields {
+		if field.Name == "id" || strings.HasPrefix(field.Name, "__") {
+			continue
+		}
+
+		out, ok := r.sourcedOutputType(field.Type)
+		if !ok || len(field.Arguments) != 0 {
+			r.logger.Warnw("field of @source type isn't a scalar without arguments, it isn't served", "graphql_type", def.Name, "field", field.Name)
+
+			continue
+		}
+
+		obj.AddFieldConfig(field.Name, &graphql.Field{
+			Type:        out,
+			Description: field.Description,
+			Resolve:     r.recoverResolve(r.sourcedFieldResolver(def.Name, field.Name)),
+		})
+
+		src.fields = append(src.fields, field.Name)
+	}
+
+	if len(src.fields) == 0 {
+		return
+	}
+
+	sort.Strings(src.fields)
+
+	if r.sources == nil {
+		r.sources = map[string]*typeSource{}
+	}
+
+	r.sources[def.Name] = src
+}
+
+// sourcedOutputType returns the graphql type of a sourced field of type t,
+// without its non-null wrappers
+func (r *Resolver) sourcedOutputType(t *ast.Type) (graphql.Output, bool) {
+	if t.Elem != nil {
+		elem, ok := r.sourcedOutputType(t.Elem)
+		if !ok {
+			return nil, false
+		}
+
+		return graphql.NewList(elem), true
+	}
+
+	if s, ok := builtinScalars[t.NamedType]; ok {
+		return s, true
+	}
+
+	if s, ok := r.scalars[t.NamedType]; ok && t.NamedType != "_Any" {
+		return s, true
+	}
+
+	return nil, false
+}
+
+// sourcedFieldResolver resolves a field of typeName from its subgraph
+func (r *Resolver) sourcedFieldResolver(typeName, field string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		var id gidx.PrefixedID
+
+		switch o := p.Source.(type) {
+		case *Node:
+			id = o.ID
+		case *Entity:
+			id = o.ID
+		default:
+			return nil, errors.New("invalid node type")
+		}
+
+		loader := sourceLoaderFrom(p.Context)
+		if loader == nil {
+			return nil, nil
+		}
+
+		fields, err := loader.load(p.Context, r, typeName, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return fields[field], nil
+	}
+}
+
+// wantSource registers id, resolved to obj, with the source loader of the
+// request, so its fields are looked up together with the other ids of the
+// request when the first of them is needed
+func (r *Resolver) wantSource(ctx context.Context, obj *graphql.Object, id gidx.PrefixedID) {
+	if _, ok := r.sources[obj.Name()]; !ok {
+		return
+	}
+
+	if loader := sourceLoaderFrom(ctx); loader != nil {
+		loader.want(obj.Name(), id)
+	}
+}
+
+type sourceLoaderKey struct{}
+
+// sourceLoader batches the source lookups of a request. The ids resolved by
+// the request are collected as they're resolved and looked up per type, in
+// a single request to the subgraph, once the fields of one are needed.
+type sourceLoader struct {
+	mu sync.Mutex
+	// pending are the ids of each type not looked up yet
+	pending map[string][]gidx.PrefixedID
+	// results are the fields of the ids looked up, nil for ids the subgraph
+	// didn't return, and errs the errors of the ids that failed
+	results map[gidx.PrefixedID]map[string]interface{}
+	errs    map[gidx.PrefixedID]error
+}
+
+// withSourceLoader returns ctx with a loader for the source lookups of a
+// request
+func withSourceLoader(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sourceLoaderKey{}, &sourceLoader{
+		pending: map[string][]gidx.PrefixedID{},
+		results: map[gidx.PrefixedID]map[string]interface{}{},
+		errs:    map[gidx.PrefixedID]error{},
+	})
+}
+
+func sourceLoaderFrom(ctx context.Context) *sourceLoader {
+	l, _ := ctx.Value(sourceLoaderKey{}).(*sourceLoader)
+
+	return l
+}
+
+func (l *sourceLoader) want(typeName string, id gidx.PrefixedID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pending[typeName] = append(l.pending[typeName], id)
+}
+
+// load returns the fields of id, looking up the pending ids of its type
+// first when it hasn't been looked up yet
+func (l *sourceLoader) load(ctx context.Context, r *Resolver, typeName string, id gidx.PrefixedID) (map[string]interface{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err, ok := l.errs[id]; ok {
+		return nil, err
+	}
+
+	if fields, ok := l.results[id]; ok {
+		return fields, nil
+	}
+
+	ids := uniqueIDs(append(l.pending[typeName], id))
+	delete(l.pending, typeName)
+
+	results, err := r.lookupSource(ctx, typeName, ids)
+
+	for i, lookedUp := range ids {
+		if err != nil {
+			l.errs[lookedUp] = err
+
+			continue
+		}
+
+		l.results[lookedUp] = results[i]
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return l.results[id], nil
+}
+
+func uniqueIDs(ids []gidx.PrefixedID) []gidx.PrefixedID {
+	seen := make(map[gidx.PrefixedID]bool, len(ids))
+	unique := ids[:0]
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+// sourceResponse is the response of a subgraph to a source lookup
+type sourceResponse struct {
+	Data struct {
+		Entities []map[string]interface{} `json:"_entities"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// lookupSource queries the subgraph of typeName for the fields of ids,
+// returning them in the order of ids. The caller's jwt is sent along so the
+// subgraph can authorize the lookup.
+func (r *Resolver) lookupSource(ctx context.Context, typeName string, ids []gidx.PrefixedID) (results []map[string]interface{}, err error) {
+	src := r.sources[typeName]
+
+	ctx, span := tracer().Start(ctx, "source lookup", trace.WithAttributes(attrType.String(typeName), attrSourceURL.String(src.url), attrEntityCount.Int(len(ids))))
+	defer func() { endResolutionSpan(span, err) }()
+
+	reps := make([]map[string]string, len(ids))
+	for i, id := range ids {
+		reps[i] = map[string]string{"__typename": typeName, "id": id.String()}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     fmt.Sprintf(sourceEntitiesQuery, typeName, strings.Join(src.fields, " ")),
+		"variables": map[string]interface{}{"representations": reps},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lctx, cancel, err := r.lookupContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(lctx, http.MethodPost, src.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if token := oidc.Token(ctx); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := r.sourceClient.Do(req)
+	if err != nil {
+		if ctx.Err() == nil && lctx.Err() != nil {
+			return nil, ErrLookupTimeout
+		}
+
+		r.logger.Errorw("source lookup failed", "graphql_type", typeName, "url", src.url, "error", err)
+
+		return nil, fmt.Errorf("%w: %s", ErrSourceLookup, typeName)
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	var sr sourceResponse
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected response: %s", resp.Status)
+	} else if err = json.NewDecoder(resp.Body).Decode(&sr); err == nil && len(sr.Data.Entities) != len(ids) {
+		err = fmt.Errorf("expected %d entities, got %d", len(ids), len(sr.Data.Entities))
+	}
+
+	if err != nil {
+		r.logger.Errorw("source lookup failed", "graphql_type", typeName, "url", src.url, "error", err)
+
+		return nil, fmt.Errorf("%w: %s", ErrSourceLookup, typeName)
+	}
+
+	// entities the subgraph failed are null, their errors are only logged
+	// since they may describe the subgraph's internals
+	for _, e := range sr.Errors {
+		r.logger.Warnw("source lookup error", "graphql_type", typeName, "url", src.url, "error", e.Message)
+	}
+
+	return sr.Data.Entities, nil
+}
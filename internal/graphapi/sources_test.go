@@ -0,0 +1,160 @@
+package graphapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+const sourcedTestSchema = `directive @prefixedID(prefix: String!) on OBJECT
+directive @source(url: String!) on OBJECT
+
+scalar Time
+
+type User implements Node @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+type Server implements Node @key(fields: "id") @prefixedID(prefix: "testsrv") @source(url: "%s") {
+	id: ID!
+	name: String!
+	tags: [String!]
+	createdAt: Time
+	owner: User
+	count(filter: String): Int
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+// newSourceSubgraph returns a subgraph serving the name, tags and createdAt
+// of servers, failing testsrv-broken, and the number of requests it got
+func newSourceSubgraph(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		var body struct {
+			Query     string `json:"query"`
+			Variables struct {
+				Representations []map[string]string `json:"representations"`
+			} `json:"variables"`
+		}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Contains(t, body.Query, "...on Server{createdAt name tags}")
+
+		entities := []interface{}{}
+
+		for _, rep := range body.Variables.Representations {
+			assert.Equal(t, "Server", rep["__typename"])
+
+			if rep["id"] == "testsrv-broken" {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+
+			entities = append(entities, map[string]interface{}{
+				"name":      "server " + strings.TrimPrefix(rep["id"], "testsrv-"),
+				"tags":      []string{"a", "b"},
+				"createdAt": "2024-05-01T12:00:00Z",
+			})
+		}
+
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"_entities": entities}}))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func TestSourceLookups(t *testing.T) {
+	srv, requests := newSourceSubgraph(t)
+	schema := fmt.Sprintf(sourcedTestSchema, srv.URL)
+
+	testCases := []struct {
+		TestName         string
+		query            string
+		response         string
+		errors           int
+		expectedRequests int32
+	}{
+		{
+			TestName:         "node",
+			query:            `{"query": "{ node(id: \"testsrv-1\") { id ... on Server { name tags createdAt } } }"}`,
+			response:         `{"node":{"id":"testsrv-1","name":"server 1","tags":["a","b"],"createdAt":"2024-05-01T12:00:00Z"}}`,
+			expectedRequests: 1,
+		},
+		{
+			TestName:         "ids aren't looked up",
+			query:            `{"query": "{ node(id: \"testsrv-1\") { id ... on Server { id } } }"}`,
+			response:         `{"node":{"id":"testsrv-1"}}`,
+			expectedRequests: 0,
+		},
+		{
+			TestName: "entities are looked up together",
+			query: `{
+				"query": "query($representations:[_Any!]!){_entities(representations:$representations){...on Server{id name}...on User{id}}}",
+				"variables": {"representations": [
+					{ "__typename": "Node", "id": "testsrv-1" },
+					{ "__typename": "Node", "id": "testusr-1" },
+					{ "__typename": "Node", "id": "testsrv-2" },
+					{ "__typename": "Node", "id": "testsrv-1" }
+				]}
+			}`,
+			response:         `{"_entities":[{"id":"testsrv-1","name":"server 1"},{"id":"testusr-1"},{"id":"testsrv-2","name":"server 2"},{"id":"testsrv-1","name":"server 1"}]}`,
+			expectedRequests: 1,
+		},
+		{
+			TestName:         "failed lookup",
+			query:            `{"query": "{ node(id: \"testsrv-broken\") { id ... on Server { name } } }"}`,
+			response:         `{"node":{"id":"testsrv-broken","name":null}}`,
+			errors:           1,
+			expectedRequests: 1,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			atomic.StoreInt32(requests, 0)
+
+			resp, err := testQuery(schema, tt.query, graphapi.WithSourceLookups(nil))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tt.response, resp.Data)
+			assert.Len(t, resp.Errors, tt.errors)
+			assert.Equal(t, tt.expectedRequests, atomic.LoadInt32(requests))
+		})
+	}
+}
+
+func TestSourceLookupsUnservedFields(t *testing.T) {
+	srv, _ := newSourceSubgraph(t)
+	schema := fmt.Sprintf(sourcedTestSchema, srv.URL)
+
+	// object fields and fields with arguments aren't served
+	for _, field := range []string{"owner { id }", "count"} {
+		resp, err := testQuery(schema, `{"query": "{ node(id: \"testsrv-1\") { ... on Server { `+field+` } } }"}`, graphapi.WithSourceLookups(nil))
+		require.NoError(t, err)
+		require.Len(t, resp.Errors, 1, field)
+	}
+
+	// without source lookups only ids are served
+	resp, err := testQuery(schema, `{"query": "{ node(id: \"testsrv-1\") { ... on Server { name } } }"}`)
+	require.NoError(t, err)
+	require.Len(t, resp.Errors, 1)
+}
@@ -0,0 +1,41 @@
+package graphapi
+
+// Stats describes the state a Resolver shares between requests, for
+// inspecting its capacity while it's running
+type Stats struct {
+	// DocumentCacheEntries and ResponseCacheEntries are the number of
+	// entries in the document and response caches
+	DocumentCacheEntries int `json:"document_cache_entries"`
+	ResponseCacheEntries int `json:"response_cache_entries"`
+	// EntityPool is nil when _entities batches are authorized serially
+	EntityPool *EntityPoolStats `json:"entity_pool,omitempty"`
+}
+
+// EntityPoolStats describes the utilization of the workers authorizing
+// chunks of large _entities batches
+type EntityPoolStats struct {
+	MinWorkers int `json:"min_workers"`
+	MaxWorkers int `json:"max_workers"`
+	// Workers is the current number of workers, which adaptive pools change
+	// between MinWorkers and MaxWorkers
+	Workers int `json:"workers"`
+	// Active is the number of workers authorizing a chunk
+	Active int `json:"active"`
+	// Queued is the number of chunks waiting for a worker
+	Queued int `json:"queued"`
+}
+
+// Stats returns the current state of the resolver's caches and workers
+func (r *Resolver) Stats() Stats {
+	stats := Stats{
+		DocumentCacheEntries: r.documents.Len(),
+		ResponseCacheEntries: r.responses.Len(),
+	}
+
+	if r.entityPool != nil {
+		pool := r.entityPool.stats()
+		stats.EntityPool = &pool
+	}
+
+	return stats
+}
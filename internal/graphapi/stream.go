@@ -0,0 +1,250 @@
+package graphapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/graphql-go/graphql"
+)
+
+// streamBufferSize is the size of the buffer between the streaming encoder
+// and the response writer
+const streamBufferSize = 32 << 10
+
+var streamWriterPool = sync.Pool{
+	New: func() interface{} { return bufio.NewWriterSize(nil, streamBufferSize) },
+}
+
+// encodeResult writes result to w as json, producing the same output as
+// json.Encoder. Objects and lists are written an element at a time so only
+// a single leaf value is ever encoded in memory, rather than the whole
+// response as json.Encoder does, which keeps peak memory low for huge
+// _entities batches.
+func encodeResult(w io.Writer, result *graphql.Result) error {
+	bw := streamWriterPool.Get().(*bufio.Writer)
+	bw.Reset(w)
+
+	defer func() {
+		bw.Reset(nil)
+		streamWriterPool.Put(bw)
+	}()
+
+	s := &streamEncoder{w: bw}
+	s.enc = json.NewEncoder(leafWriter{bw})
+
+	s.writeString(`{"data":`)
+	s.value(result.Data)
+
+	if len(result.Errors) != 0 {
+		s.writeString(`,"errors":[`)
+
+		for i, e := range result.Errors {
+			if i > 0 {
+				s.writeString(",")
+			}
+
+			s.leaf(e)
+		}
+
+		s.writeString("]")
+	}
+
+	if len(result.Extensions) != 0 {
+		s.writeString(`,"extensions":`)
+		s.value(result.Extensions)
+	}
+
+	s.writeString("}\n")
+
+	if s.err != nil {
+		return s.err
+	}
+
+	return bw.Flush()
+}
+
+// streamEncoder writes json values element by element, keeping the first
+// error so callers can check once at the end
+type streamEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+	err error
+
+	// scratch is reused to quote strings
+	scratch []byte
+	keys    []string
+}
+
+// leafWriter drops the newline json.Encoder writes after every value, so a
+// single encoder with its pooled buffers can encode every leaf value
+type leafWriter struct {
+	w *bufio.Writer
+}
+
+func (l leafWriter) Write(p []byte) (int, error) {
+	if _, err := l.w.Write(bytes.TrimSuffix(p, []byte("\n"))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (s *streamEncoder) value(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			s.writeString("null")
+
+			return
+		}
+
+		// keys of nested objects are appended after the keys of their parents
+		// so a single slice is reused for the whole response
+		base := len(s.keys)
+		for k := range v {
+			s.keys = append(s.keys, k)
+		}
+
+		keys := s.keys[base:]
+		sort.Strings(keys)
+
+		defer func() { s.keys = s.keys[:base] }()
+
+		s.writeString("{")
+
+		for i, k := range keys {
+			if i > 0 {
+				s.writeString(",")
+			}
+
+			s.str(k)
+			s.writeString(":")
+			s.value(v[k])
+		}
+
+		s.writeString("}")
+	case []interface{}:
+		if v == nil {
+			s.writeString("null")
+
+			return
+		}
+
+		s.writeString("[")
+
+		for i, e := range v {
+			if i > 0 {
+				s.writeString(",")
+			}
+
+			s.value(e)
+		}
+
+		s.writeString("]")
+	default:
+		s.leaf(v)
+	}
+}
+
+func (s *streamEncoder) leaf(v interface{}) {
+	if s.err != nil {
+		return
+	}
+
+	// strings are most of a response, so they're quoted directly rather
+	// than going through reflection
+	if str, ok := v.(string); ok {
+		s.str(str)
+
+		return
+	}
+
+	s.err = s.enc.Encode(v)
+}
+
+func (s *streamEncoder) str(str string) {
+	if s.err != nil {
+		return
+	}
+
+	s.scratch = appendQuoted(s.scratch[:0], str)
+	_, s.err = s.w.Write(s.scratch)
+}
+
+func (s *streamEncoder) writeString(str string) {
+	if s.err != nil {
+		return
+	}
+
+	_, s.err = s.w.WriteString(str)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendQuoted appends str to dst as a json string, escaped the same way as
+// encoding/json including its escaping of html characters
+func appendQuoted(dst []byte, str string) []byte {
+	dst = append(dst, '"')
+	start := 0
+
+	for i := 0; i < len(str); {
+		if b := str[i]; b < utf8.RuneSelf {
+			if b >= ' ' && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+
+				continue
+			}
+
+			dst = append(dst, str[start:i]...)
+
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+
+			i++
+			start = i
+
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(str[i:])
+
+		switch {
+		case c == utf8.RuneError && size == 1:
+			dst = append(dst, str[start:i]...)
+			dst = append(dst, `\ufffd`...)
+		case c == '\u2028' || c == '\u2029':
+			dst = append(dst, str[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[c&0xF])
+		default:
+			i += size
+
+			continue
+		}
+
+		i += size
+		start = i
+	}
+
+	dst = append(dst, str[start:]...)
+
+	return append(dst, '"')
+}
@@ -0,0 +1,73 @@
+package graphapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeResult(t *testing.T) {
+	results := []*graphql.Result{
+		{},
+		{Data: map[string]interface{}{}},
+		{Data: map[string]interface{}{"node": nil}},
+		{
+			Data: map[string]interface{}{
+				"_entities": []interface{}{
+					map[string]interface{}{"id": "testsrv-1", "__typename": "Server"},
+					nil,
+					map[string]interface{}{"id": "<b>& ", "tags": []interface{}{}, "none": []interface{}(nil)},
+				},
+				"count": 3,
+			},
+			Errors: []gqlerrors.FormattedError{
+				gqlerrors.NewFormattedError("testtkn is an unknown id prefix"),
+			},
+			Extensions: map[string]interface{}{"policy": map[string]interface{}{"reason": "ok"}},
+		},
+	}
+
+	for _, result := range results {
+		var expected, actual bytes.Buffer
+
+		require.NoError(t, json.NewEncoder(&expected).Encode(result))
+		require.NoError(t, encodeResult(&actual, result))
+
+		assert.Equal(t, expected.String(), actual.String())
+	}
+}
+
+func BenchmarkEncodeResult(b *testing.B) {
+	entities := make([]interface{}, 10000)
+	for i := range entities {
+		entities[i] = map[string]interface{}{"__typename": "Server", "id": "testsrv-rXirlFQULBHDw9urtOjya"}
+	}
+
+	result := &graphql.Result{Data: map[string]interface{}{"_entities": entities}}
+
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if err := encodeResult(io.Discard, result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("encoder", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if err := json.NewEncoder(io.Discard).Encode(result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
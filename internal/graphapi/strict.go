@@ -0,0 +1,110 @@
+package graphapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/labstack/echo/v4"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+// ErrMalformedRequest is returned decoding a request that isn't valid json
+// or has no query, or, with strict decoding enabled, that has unknown or
+// duplicate fields or data after the request
+var ErrMalformedRequest = errors.New("malformed request")
+
+// WithStrictRequests rejects graphql requests with unknown or duplicate
+// fields, such as a misspelled variables field, instead of ignoring them.
+// encoding/json matches field names case insensitively, so fields differing
+// only in case are duplicates.
+func WithStrictRequests() Option {
+	return func(r *Resolver) {
+		r.strictRequests = true
+	}
+}
+
+// decodeStrict decodes the request in data into p, rejecting unknown and
+// duplicate fields. Only the fields of the request are checked, not the
+// fields of its variables.
+func decodeStrict(data []byte, p *postData) error {
+	if err := checkDuplicateFields(data); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(p); err != nil {
+		// encoding/json doesn't export an error type for unknown fields
+		if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			name, _ = strconv.Unquote(name)
+
+			return fmt.Errorf("%w: unknown field %q", ErrMalformedRequest, safeString(name))
+		}
+
+		return err
+	}
+
+	if dec.More() {
+		return fmt.Errorf("%w: data after the request", ErrMalformedRequest)
+	}
+
+	return nil
+}
+
+// checkDuplicateFields returns an error when the json object in data has a
+// field more than once. Data that isn't an object is left for decoding to
+// reject.
+func checkDuplicateFields(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+
+	seen := []string{}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		name, _ := tok.(string)
+
+		for _, s := range seen {
+			if strings.EqualFold(s, name) {
+				return fmt.Errorf("%w: duplicate field %q", ErrMalformedRequest, safeString(name))
+			}
+		}
+
+		seen = append(seen, name)
+
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// malformedRequest responds to a malformed request with a 400 and an
+// invalid_request error
+func (r *Resolver) malformedRequest(c echo.Context, err error) error {
+	result := &graphql.Result{
+		Errors: withCode(errcode.InvalidRequest, []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}),
+	}
+	result.Errors = r.localizeErrors(c, result.Errors)
+	r.addInstanceExtension(result)
+
+	return r.writeJSON(c, http.StatusBadRequest, result)
+}
@@ -0,0 +1,93 @@
+package graphapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestStrictRequests(t *testing.T) {
+	query := `"query": "query($id: ID!) { node(id: $id) { id } }"`
+
+	testCases := []struct {
+		TestName        string
+		body            string
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			TestName:       "valid",
+			body:           `{` + query + `, "operation": "", "variables": {"id": "testsrv-abc", "extra": 1}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			TestName:        "unknown field",
+			body:            `{` + query + `, "variabels": {"id": "testsrv-abc"}}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: `malformed request: unknown field "variabels"`,
+		},
+		{
+			TestName:        "duplicate field",
+			body:            `{` + query + `, "variables": {"id": "testsrv-abc"}, "variables": {}}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: `malformed request: duplicate field "variables"`,
+		},
+		{
+			TestName:        "duplicate field in another case",
+			body:            `{` + query + `, "variables": {"id": "testsrv-abc"}, "Variables": {}}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: `malformed request: duplicate field "Variables"`,
+		},
+		{
+			TestName:        "data after the request",
+			body:            `{` + query + `, "variables": {"id": "testsrv-abc"}} {}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: `malformed request: data after the request`,
+		},
+		{
+			TestName:        "sanitized field name",
+			body:            `{` + query + `, "bad\nfield": 1}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: `malformed request: unknown field "bad�field"`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema, graphapi.WithStrictRequests())
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			require.NoError(t, r.GraphHandler(echo.New().NewContext(req, rec)))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if tt.expectedMessage == "" {
+				assert.JSONEq(t, `{"data":{"node":{"id":"testsrv-abc"}}}`, rec.Body.String())
+
+				return
+			}
+
+			message, err := json.Marshal(tt.expectedMessage)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, `{"data":null,"errors":[{"message":`+string(message)+`,"locations":[],"extensions":{"code":"invalid_request"}}]}`, rec.Body.String())
+		})
+	}
+
+	// without strict requests the misspelled field is ignored
+	resp, err := testQuery(validTestSchema, `{`+query+`, "variabels": {"id": "testsrv-abc"}}`)
+	require.NoError(t, err)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, `Variable "$id" of required type "ID!" was not provided.`, resp.Errors[0].Message)
+}
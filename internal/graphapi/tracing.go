@@ -0,0 +1,118 @@
+package graphapi
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans created by the
+// resolver
+const tracerName = "go.infratographer.com/node-resolver/internal/graphapi"
+
+// Attributes of the resolution spans
+const (
+	attrOperation       = attribute.Key("node_resolver.operation")
+	attrPrefix          = attribute.Key("node_resolver.prefix")
+	attrType            = attribute.Key("node_resolver.type")
+	attrOutcome         = attribute.Key("node_resolver.outcome")
+	attrEntityCount     = attribute.Key("node_resolver.entities.count")
+	attrEntityFailures  = attribute.Key("node_resolver.entities.failures")
+	attrEntityPrefixes  = attribute.Key("node_resolver.entities.prefixes")
+	attrEntityTypenames = attribute.Key("node_resolver.entities.typenames")
+)
+
+// tracer returns the tracer of the global tracer provider, looked up each
+// time so a provider set after the resolver was built is used
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// startOperationSpan starts the span of the graphql operation requested by
+// p, replacing the context of the request with it. The trace is continued
+// from the request's headers unless the server's middleware already did.
+func startOperationSpan(c echo.Context, p *postData) trace.Span {
+	ctx := c.Request().Context()
+
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(c.Request().Header))
+	}
+
+	name := "query"
+	attrs := []attribute.KeyValue{semconv.GraphqlOperationTypeQuery}
+
+	if p.Operation != "" {
+		name += " " + p.Operation
+		attrs = append(attrs, semconv.GraphqlOperationName(p.Operation))
+	}
+
+	ctx, span := tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+
+	c.SetRequest(c.Request().WithContext(ctx))
+
+	return span
+}
+
+// setOperationStatus sets the status of the span of a graphql operation
+// from its result
+func setOperationStatus(span trace.Span, result *graphql.Result) {
+	if result.HasErrors() {
+		span.SetStatus(codes.Error, result.Errors[0].Message)
+	}
+}
+
+// endResolutionSpan ends a resolution span with the outcome of err
+func endResolutionSpan(span trace.Span, err error) {
+	span.SetAttributes(attrOutcome.String(string(auditOutcome(err))))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// startEntitiesSpan starts the span resolving entities, describing the
+// prefixes and typenames requested
+func startEntitiesSpan(ctx context.Context, entities []*Entity) (context.Context, trace.Span) {
+	prefixes := map[string]bool{}
+	typenames := map[string]bool{}
+
+	for _, entity := range entities {
+		if entity.ID != "" {
+			prefixes[prefixOf(entity.ID)] = true
+		}
+
+		typenames[safeString(entity.typeName)] = true
+	}
+
+	return tracer().Start(ctx, "resolve entities", trace.WithAttributes(
+		attrOperation.String(auditOperationEntities),
+		attrEntityCount.Int(len(entities)),
+		attrEntityPrefixes.StringSlice(sortedKeys(prefixes)),
+		attrEntityTypenames.StringSlice(sortedKeys(typenames)),
+	))
+}
+
+// endEntitiesSpan ends the span resolving entities, counting the entities
+// that failed
+func endEntitiesSpan(span trace.Span, entities []*Entity) {
+	failures := 0
+
+	for _, entity := range entities {
+		if entity.err != nil {
+			failures++
+		}
+	}
+
+	span.SetAttributes(attrEntityFailures.Int(failures))
+	span.End()
+}
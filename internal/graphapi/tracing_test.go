@@ -0,0 +1,112 @@
+package graphapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), validTestSchema)
+	require.NoError(t, err)
+
+	query := `{
+		"query": "query Lookup($representations:[_Any!]!) { node(id: \"testsrv-abc\") { id } _entities(representations:$representations){...on Actor{id}} }",
+		"operationName": "Lookup",
+		"variables": {"representations": [{ "__typename": "Actor", "id": "testusr-456" }, { "__typename": "Actor", "id": "testtkn-789" }]}
+	}`
+
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(query))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, r.GraphHandler(echo.New().NewContext(req, rec)))
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	require.Contains(t, spans, "query Lookup")
+	require.Contains(t, spans, "resolve node")
+	require.Contains(t, spans, "resolve entities")
+
+	// the operation continues the trace of the request
+	operation := spans["query Lookup"]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", operation.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", operation.Parent().SpanID().String())
+	assert.Contains(t, operation.Attributes(), attribute.String("graphql.operation.name", "Lookup"))
+	assert.Equal(t, codes.Unset, operation.Status().Code)
+
+	node := spans["resolve node"]
+	assert.Equal(t, operation.SpanContext().SpanID(), node.Parent().SpanID())
+	assert.Subset(t, node.Attributes(), []attribute.KeyValue{
+		attribute.String("node_resolver.prefix", "testsrv"),
+		attribute.String("node_resolver.type", "Server"),
+		attribute.String("node_resolver.outcome", "resolved"),
+	})
+
+	entities := spans["resolve entities"]
+	assert.Equal(t, operation.SpanContext().SpanID(), entities.Parent().SpanID())
+	assert.Subset(t, entities.Attributes(), []attribute.KeyValue{
+		attribute.Int("node_resolver.entities.count", 2),
+		attribute.StringSlice("node_resolver.entities.prefixes", []string{"testtkn", "testusr"}),
+		attribute.StringSlice("node_resolver.entities.typenames", []string{"Actor"}),
+		attribute.Int("node_resolver.entities.failures", 0),
+	})
+}
+
+func TestTracingFailedResolution(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	_, err := testQuery(validTestSchema, `{"query":"{ node(id: \"unknown-abc\") { id } }"}`)
+	require.NoError(t, err)
+
+	var node, operation sdktrace.ReadOnlySpan
+
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "resolve node":
+			node = span
+		case "query":
+			operation = span
+		}
+	}
+
+	require.NotNil(t, node)
+	require.NotNil(t, operation)
+
+	assert.Contains(t, node.Attributes(), attribute.String("node_resolver.outcome", "unknown_prefix"))
+	assert.Equal(t, codes.Error, node.Status().Code)
+	assert.Equal(t, codes.Error, operation.Status().Code)
+}
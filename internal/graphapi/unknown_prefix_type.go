@@ -0,0 +1,45 @@
+package graphapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// WithUnknownPrefixType resolves well-formed ids whose prefix no type
+// declares to an object type called name implementing only Node, such as
+// UnknownNode, instead of failing them with the unknown_prefix code. The type
+// is added to the SDL without a prefix, so gateways can compose it. Ids are
+// still authorized, and are still recorded in the unknown prefix log.
+func WithUnknownPrefixType(name string) Option {
+	return func(r *Resolver) {
+		r.unknownPrefixName = name
+	}
+}
+
+// addUnknownPrefixType adds the type unknown prefixes resolve to, when one
+// is configured
+func (r *Resolver) addUnknownPrefixType() error {
+	if r.unknownPrefixName == "" {
+		return nil
+	}
+
+	node, ok := r.interfaceMap["Node"]
+	if !ok {
+		return nil
+	}
+
+	if r.schemaDoc.Definitions.ForName(r.unknownPrefixName) != nil {
+		return newInvalidSchemaError(fmt.Sprintf("unknown prefix type %s is already defined by the schema", r.unknownPrefixName))
+	}
+
+	r.unknownPrefixType = r.graphTypeFor(r.unknownPrefixName, []*graphql.Interface{node})
+
+	return nil
+}
+
+// isUnknownPrefixType reports whether typeName is the type unknown prefixes
+// resolve to
+func (r *Resolver) isUnknownPrefixType(typeName string) bool {
+	return r.unknownPrefixType != nil && typeName == r.unknownPrefixType.Name()
+}
@@ -0,0 +1,71 @@
+package graphapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestUnknownPrefixType(t *testing.T) {
+	log := NewUnknownPrefixLog(10)
+
+	r, err := NewResolver(zap.NewNop().Sugar(), wildcardTestSchema, WithUnknownPrefixType("UnknownNode"), WithUnknownPrefixLog(log))
+	require.NoError(t, err)
+
+	query := &postData{Query: `{ node(id: "unknown-abc") { __typename id ... on LoadBalancer { id } } }`}
+
+	result := r.execute(context.Background(), query)
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"__typename": "UnknownNode", "id": "unknown-abc"}}, result.Data)
+
+	query = &postData{
+		Query:     `query($representations:[_Any!]!){_entities(representations:$representations){__typename}}`,
+		Variables: map[string]interface{}{"representations": []interface{}{map[string]interface{}{"__typename": "Node", "id": "unknown-abc"}}},
+	}
+
+	result = r.execute(context.Background(), query)
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"_entities": []interface{}{map[string]interface{}{"__typename": "UnknownNode"}}}, result.Data)
+
+	// known prefixes resolve to their type, malformed ids still fail
+	node, err := r.GetNode(context.Background(), "loadbal-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "LoadBalancer", node.GraphType.Name())
+
+	_, err = r.ResolveID(context.Background(), "notanid")
+	assert.Error(t, err)
+
+	// the ids are still recorded as unknown prefixes, and the type has no
+	// prefix of its own
+	events := log.Recent()
+	require.Len(t, events, 2)
+	assert.Equal(t, "unknown", events[0].Prefix)
+
+	assert.NotContains(t, r.Prefixes(), PrefixType{Type: "UnknownNode"})
+	assert.Contains(t, r.SDL(), "type UnknownNode implements Node @key(fields: \"id\") {\n  id: ID!\n}\n")
+
+	_, err = NewResolver(zap.NewNop().Sugar(), wildcardTestSchema, WithUnknownPrefixType("LoadBalancer"))
+	assert.ErrorAs(t, err, &ErrInvalidSchema{})
+}
+
+func TestUnknownPrefixTypeWildcard(t *testing.T) {
+	// wildcard prefixes, including a catch-all, are matched first
+	schema := wildcardTestSchema + `
+type Resource implements Node @key(fields: "id") @prefixedID(prefix: "*") {
+	id: ID!
+}`
+
+	r, err := NewResolver(zap.NewNop().Sugar(), schema, WithWildcardPrefixes(), WithUnknownPrefixType("UnknownNode"))
+	require.NoError(t, err)
+
+	node, err := r.GetNode(context.Background(), "loadbpl-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "LoadBalancerPool", node.GraphType.Name())
+
+	node, err = r.GetNode(context.Background(), "unknown-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "Resource", node.GraphType.Name())
+}
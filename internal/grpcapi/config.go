@@ -0,0 +1,35 @@
+package grpcapi
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultShutdownGracePeriod = 5 * time.Second
+
+// Config stores the grpc server settings
+type Config struct {
+	// Listen is the address the grpc server listens on, empty disables it
+	Listen string `mapstructure:"listen"`
+	// ShutdownGracePeriod is how long calls being served may take to finish
+	// when shutting down
+	ShutdownGracePeriod time.Duration `mapstructure:"shutdown-grace-period"`
+}
+
+// Enabled returns true when a listen address is configured
+func (c Config) Enabled() bool {
+	return c.Listen != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("grpc-listen", "", "address to serve the grpc resolution service on, such as :7905; empty disables it")
+	viperx.MustBindFlag(v, "grpc.listen", flags.Lookup("grpc-listen"))
+
+	v.MustBindEnv("grpc.shutdown-grace-period")
+
+	v.SetDefault("grpc.shutdown-grace-period", defaultShutdownGracePeriod)
+}
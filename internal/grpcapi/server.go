@@ -0,0 +1,254 @@
+// Package grpcapi serves the resolution of prefixed ids over grpc, alongside
+// the http apis, for services that would rather not make graphql requests.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+	"go.infratographer.com/node-resolver/internal/graphapi"
+	noderesolverv1 "go.infratographer.com/node-resolver/proto/noderesolver/v1"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail of failed calls
+const ErrorDomain = "node-resolver.infratographer.com"
+
+// Server serves the NodeResolver grpc service with the resolver currently
+// served by a handler, so it follows schema reloads like the http apis
+type Server struct {
+	noderesolverv1.UnimplementedNodeResolverServer
+
+	handler *graphapi.Handler
+	logger  *zap.SugaredLogger
+	server  *grpc.Server
+}
+
+// NewServer returns a Server resolving ids with the resolver of handler,
+// registering server reflection so tools like grpcurl can call it. opts
+// configure the grpc server, such as its transport credentials.
+func NewServer(handler *graphapi.Handler, logger *zap.SugaredLogger, opts ...grpc.ServerOption) *Server {
+	s := &Server{
+		handler: handler,
+		logger:  logger,
+		server:  grpc.NewServer(opts...),
+	}
+
+	noderesolverv1.RegisterNodeResolverServer(s.server, s)
+	reflection.Register(s.server)
+
+	return s
+}
+
+// Serve serves calls on listener until ctx is done, then stops accepting
+// calls and waits up to gracePeriod for the calls being served to finish
+func (s *Server) Serve(ctx context.Context, listener net.Listener, gracePeriod time.Duration) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		stopped := make(chan struct{})
+
+		go func() {
+			s.server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(gracePeriod):
+			s.server.Stop()
+		}
+	}()
+
+	s.logger.Infow("serving grpc", "address", listener.Addr().String())
+
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+
+	return nil
+}
+
+// ResolveNode resolves a single id, failing with the status of its error
+// when it doesn't resolve
+func (s *Server) ResolveNode(ctx context.Context, req *noderesolverv1.ResolveNodeRequest) (*noderesolverv1.ResolveNodeResponse, error) {
+	results, err := s.resolve(ctx, []string{req.GetId()})
+	if err != nil {
+		return nil, err
+	}
+
+	result := results[0]
+	if result.Error != nil {
+		return nil, statusError(result.Error.Code, result.Error.Message)
+	}
+
+	return &noderesolverv1.ResolveNodeResponse{
+		Typename:   result.Type,
+		Prefix:     result.Prefix,
+		Interfaces: result.Interfaces,
+	}, nil
+}
+
+// ResolveBatch resolves ids, reporting the ids that don't resolve in their
+// result
+func (s *Server) ResolveBatch(ctx context.Context, req *noderesolverv1.ResolveBatchRequest) (*noderesolverv1.ResolveBatchResponse, error) {
+	results, err := s.resolve(ctx, req.GetIds())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &noderesolverv1.ResolveBatchResponse{
+		Results: make([]*noderesolverv1.ResolveResult, len(results)),
+	}
+
+	for i, result := range results {
+		resp.Results[i] = &noderesolverv1.ResolveResult{
+			Id:         result.ID,
+			Resolved:   result.Resolved,
+			Typename:   result.Type,
+			Prefix:     result.Prefix,
+			Interfaces: result.Interfaces,
+		}
+
+		if result.Error != nil {
+			resp.Results[i].Error = &noderesolverv1.ResolveError{
+				Code:    string(result.Error.Code),
+				Message: result.Error.Message,
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// resolve authenticates the call with the middleware of the resolver being
+// served and resolves ids with it
+func (s *Server) resolve(ctx context.Context, ids []string) ([]graphapi.ResolveResult, error) {
+	r := s.handler.Resolver()
+
+	ctx, err := r.Authenticate(ctx, headerOf(ctx), remoteAddrOf(ctx))
+	if err != nil {
+		return nil, authError(err)
+	}
+
+	results, err := r.ResolveIDs(ctx, ids)
+	if err != nil {
+		return nil, statusError(errcode.Of(err), err.Error())
+	}
+
+	return results, nil
+}
+
+// headerOf returns the metadata of the call as http headers, so the
+// middleware of the resolver sees the call's authorization and tenant
+// headers like those of http requests
+func headerOf(ctx context.Context) http.Header {
+	header := http.Header{}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+
+		for _, v := range values {
+			header.Add(key, v)
+		}
+	}
+
+	return header
+}
+
+func remoteAddrOf(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+// grpcCodes maps error codes to the grpc codes of the calls failing with them
+var grpcCodes = map[errcode.Code]codes.Code{
+	errcode.InvalidRequest: codes.InvalidArgument,
+	errcode.InvalidID:      codes.InvalidArgument,
+	errcode.UnknownPrefix:  codes.NotFound,
+	errcode.NotFound:       codes.NotFound,
+	errcode.Deleted:        codes.NotFound,
+	errcode.Unauthorized:   codes.PermissionDenied,
+	errcode.Denied:         codes.PermissionDenied,
+	errcode.Timeout:        codes.DeadlineExceeded,
+	errcode.Unavailable:    codes.Unavailable,
+	errcode.Overloaded:     codes.ResourceExhausted,
+	errcode.RateLimited:    codes.ResourceExhausted,
+}
+
+// statusError returns the status of a call failing with code, carrying an
+// ErrorInfo whose reason is the code
+func statusError(code errcode.Code, msg string) error {
+	c, ok := grpcCodes[code]
+	if !ok {
+		code, c, msg = errcode.Internal, codes.Internal, "internal error"
+	}
+
+	st := status.New(c, msg)
+
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(code), Domain: ErrorDomain}); err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
+
+// authError returns the status of a call rejected by the middleware of the
+// resolver. Middleware rejecting requests with a coded body, such as the
+// rate limiter, fail the call with their code.
+func authError(err error) error {
+	var he *echo.HTTPError
+	if !errors.As(err, &he) {
+		return statusError(errcode.Internal, "")
+	}
+
+	msg := fmt.Sprint(he.Message)
+
+	if body, ok := he.Message.(echo.Map); ok {
+		msg = fmt.Sprint(body["message"])
+
+		if code, ok := body["code"].(errcode.Code); ok {
+			return statusError(code, msg)
+		}
+	}
+
+	switch he.Code {
+	case http.StatusUnauthorized:
+		return status.Error(codes.Unauthenticated, msg)
+	case http.StatusForbidden:
+		return statusError(errcode.Unauthorized, msg)
+	case http.StatusBadRequest:
+		return statusError(errcode.InvalidRequest, msg)
+	default:
+		return statusError(errcode.Internal, msg)
+	}
+}
@@ -0,0 +1,186 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+	"go.infratographer.com/node-resolver/internal/grpcapi"
+	"go.infratographer.com/node-resolver/internal/ratelimit"
+	noderesolverv1 "go.infratographer.com/node-resolver/proto/noderesolver/v1"
+)
+
+const testSchema = `
+directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+directive @prefixedID(prefix: String!) on OBJECT
+
+interface Node @key(fields: "id") {
+	id: ID!
+}
+
+interface Actor @key(fields: "id") {
+	id: ID!
+}
+
+type User implements Node & Actor @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+`
+
+// newClient serves a resolver of testSchema with opts over an in-memory
+// connection, returning a client calling it
+func newClient(t *testing.T, opts ...graphapi.Option) noderesolverv1.NodeResolverClient {
+	t.Helper()
+
+	r, err := graphapi.NewResolver(zap.NewNop().Sugar(), testSchema, opts...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listener := bufconn.Listen(1 << 20)
+	s := grpcapi.NewServer(graphapi.NewHandler(r), zap.NewNop().Sugar())
+
+	done := make(chan error)
+
+	go func() { done <- s.Serve(ctx, listener, time.Second) }()
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close() //nolint:errcheck // closing a test connection
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	return noderesolverv1.NewNodeResolverClient(conn)
+}
+
+// errorReason returns the grpc code of err and the reason of its ErrorInfo
+func errorReason(t *testing.T, err error) (codes.Code, string) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			assert.Equal(t, grpcapi.ErrorDomain, info.Domain)
+
+			return st.Code(), info.Reason
+		}
+	}
+
+	return st.Code(), ""
+}
+
+func TestResolveNode(t *testing.T) {
+	client := newClient(t)
+
+	resp, err := client.ResolveNode(context.Background(), &noderesolverv1.ResolveNodeRequest{Id: "testusr-abc"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "User", resp.Typename)
+	assert.Equal(t, "testusr", resp.Prefix)
+	assert.Equal(t, []string{"Actor", "Node"}, resp.Interfaces)
+
+	tests := []struct {
+		id     string
+		code   codes.Code
+		reason string
+	}{
+		{id: "unknown-abc", code: codes.NotFound, reason: "unknown_prefix"},
+		{id: "notanid", code: codes.InvalidArgument, reason: "invalid_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			_, err := client.ResolveNode(context.Background(), &noderesolverv1.ResolveNodeRequest{Id: tt.id})
+			require.Error(t, err)
+
+			code, reason := errorReason(t, err)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestResolveBatch(t *testing.T) {
+	client := newClient(t)
+
+	resp, err := client.ResolveBatch(context.Background(), &noderesolverv1.ResolveBatchRequest{Ids: []string{"testusr-abc", "unknown-abc"}})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 2)
+
+	assert.Equal(t, "testusr-abc", resp.Results[0].Id)
+	assert.True(t, resp.Results[0].Resolved)
+	assert.Equal(t, "User", resp.Results[0].Typename)
+	assert.Nil(t, resp.Results[0].Error)
+
+	assert.Equal(t, "unknown-abc", resp.Results[1].Id)
+	assert.False(t, resp.Results[1].Resolved)
+	require.NotNil(t, resp.Results[1].Error)
+	assert.Equal(t, "unknown_prefix", resp.Results[1].Error.Code)
+
+	// the batch is rejected as a whole when it has too many ids
+	ids := strings.Split(strings.Repeat("testusr-abc,", graphapi.MaxResolveIDs+1), ",")
+
+	_, err = client.ResolveBatch(context.Background(), &noderesolverv1.ResolveBatchRequest{Ids: ids[:graphapi.MaxResolveIDs+1]})
+	code, reason := errorReason(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	assert.Equal(t, "invalid_request", reason)
+}
+
+func TestMiddleware(t *testing.T) {
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Header.Get("Authorization") != "Bearer valid" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+			}
+
+			return next(c)
+		}
+	}
+
+	client := newClient(t, graphapi.WithMiddleware(auth))
+
+	_, err := client.ResolveNode(context.Background(), &noderesolverv1.ResolveNodeRequest{Id: "testusr-abc"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// the metadata of calls is seen by the middleware as headers
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer valid")
+
+	resp, err := client.ResolveNode(ctx, &noderesolverv1.ResolveNodeRequest{Id: "testusr-abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "User", resp.Typename)
+
+	// middleware rejecting requests with a code fail calls with it
+	limited := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error { return ratelimit.ErrRateLimited }
+	}
+
+	client = newClient(t, graphapi.WithMiddleware(limited))
+
+	_, err = client.ResolveNode(context.Background(), &noderesolverv1.ResolveNodeRequest{Id: "testusr-abc"})
+	code, reason := errorReason(t, err)
+	assert.Equal(t, codes.ResourceExhausted, code)
+	assert.Equal(t, "rate_limited", reason)
+}
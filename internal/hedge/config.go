@@ -0,0 +1,44 @@
+package hedge
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var (
+	defaultBudget   = 0.1
+	defaultMinDelay = 5 * time.Millisecond
+)
+
+// Config stores the request hedging settings
+type Config struct {
+	// Percentile of recent lookup latencies after which a lookup is sent
+	// again, such as 0.95, 0 disables hedging
+	Percentile float64 `mapstructure:"percentile"`
+	// Budget is the fraction of lookups, across every backend, that may be
+	// hedged
+	Budget float64 `mapstructure:"budget"`
+	// MinDelay is the least time waited before hedging a lookup
+	MinDelay time.Duration `mapstructure:"min-delay"`
+}
+
+// Enabled returns true when a percentile is configured
+func (c Config) Enabled() bool {
+	return c.Percentile > 0
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Float64("hedge-percentile", 0, "percentile of recent backend lookup latencies after which a lookup is sent again, such as 0.95, 0 disables hedging")
+	viperx.MustBindFlag(v, "hedge.percentile", flags.Lookup("hedge-percentile"))
+
+	flags.Float64("hedge-budget", defaultBudget, "fraction of backend lookups that may be hedged")
+	viperx.MustBindFlag(v, "hedge.budget", flags.Lookup("hedge-budget"))
+
+	v.MustBindEnv("hedge.min-delay")
+
+	v.SetDefault("hedge.min-delay", defaultMinDelay)
+}
@@ -0,0 +1,188 @@
+// Package hedge sends a second attempt of idempotent backend lookups that
+// take longer than most, so an occasional slow backend replica doesn't set
+// the tail latency of resolution
+package hedge
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+const (
+	// sampleSize is the number of recent latencies the delay is computed from
+	sampleSize = 128
+	// minSamples is the number of latencies needed before lookups are hedged
+	minSamples = 20
+	// recomputeEvery is how many latencies are observed between computing
+	// the delay again
+	recomputeEvery = 16
+	// maxBudgetTokens is the most hedges the budget saves up for a burst
+	maxBudgetTokens = 10
+)
+
+// Budget limits the fraction of lookups that are hedged, so hedging can't
+// double the load on backends that are slow because they're overloaded. A
+// Budget is shared by the Hedgers of every backend and is safe for
+// concurrent use.
+type Budget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+// NewBudget returns a Budget allowing ratio hedges per lookup
+func NewBudget(ratio float64) *Budget {
+	return &Budget{ratio: ratio}
+}
+
+// deposit adds the hedges a lookup earns
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > maxBudgetTokens {
+		b.tokens = maxBudgetTokens
+	}
+}
+
+// withdraw reports whether a lookup may be hedged, spending a hedge if so
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// Hedger hedges the lookups to a single backend after the configured
+// percentile of its recent latencies. A Hedger is safe for concurrent use.
+type Hedger struct {
+	name    string
+	cfg     Config
+	budget  *Budget
+	metrics metrics.Sink
+
+	mu       sync.Mutex
+	samples  [sampleSize]time.Duration
+	count    int
+	next     int
+	observed int
+	delay    time.Duration
+}
+
+// New returns a Hedger for the backend called name, spending hedges from
+// budget. Hedged lookups are counted with sink, which may be nil.
+func New(name string, cfg Config, budget *Budget, sink metrics.Sink) *Hedger {
+	return &Hedger{
+		name:    name,
+		cfg:     cfg,
+		budget:  budget,
+		metrics: sink,
+	}
+}
+
+// Do calls lookup and, when it hasn't returned after the hedge delay and the
+// budget allows, calls it again, returning the result of whichever call
+// returns first. lookup must be idempotent. The call that loses is canceled
+// through its context.
+func (h *Hedger) Do(ctx context.Context, lookup func(context.Context) error) error {
+	h.budget.deposit()
+
+	start := time.Now()
+
+	delay, ok := h.hedgeDelay()
+	if !ok {
+		err := lookup(ctx)
+		h.observe(time.Since(start))
+
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered so the losing call never blocks
+	results := make(chan error, 2)
+
+	go func() { results <- lookup(ctx) }()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case err := <-results:
+		h.observe(time.Since(start))
+
+		return err
+	case <-timer.C:
+	}
+
+	if h.budget.withdraw() {
+		if h.metrics != nil {
+			h.metrics.Hedge(h.name)
+		}
+
+		go func() { results <- lookup(ctx) }()
+	}
+
+	err := <-results
+	h.observe(time.Since(start))
+
+	return err
+}
+
+// hedgeDelay returns how long to wait before hedging a lookup, or false when
+// there aren't enough latencies observed yet
+func (h *Hedger) hedgeDelay() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count < minSamples {
+		return 0, false
+	}
+
+	if h.observed >= recomputeEvery || h.delay == 0 {
+		h.observed = 0
+
+		sorted := make([]time.Duration, h.count)
+		copy(sorted, h.samples[:h.count])
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		i := int(h.cfg.Percentile * float64(len(sorted)))
+		if i >= len(sorted) {
+			i = len(sorted) - 1
+		}
+
+		h.delay = sorted[i]
+	}
+
+	if h.delay < h.cfg.MinDelay {
+		return h.cfg.MinDelay, true
+	}
+
+	return h.delay, true
+}
+
+// observe records the latency of a lookup
+func (h *Hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % sampleSize
+	h.observed++
+
+	if h.count < sampleSize {
+		h.count++
+	}
+}
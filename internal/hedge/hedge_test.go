@@ -0,0 +1,124 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errSecond = errors.New("second attempt")
+
+// warm observes enough fast lookups for h to start hedging
+func warm(t *testing.T, h *Hedger) {
+	t.Helper()
+
+	for i := 0; i < minSamples; i++ {
+		require.NoError(t, h.Do(context.Background(), func(context.Context) error { return nil }))
+	}
+}
+
+// slowFirst returns a lookup whose first attempt waits until it's canceled
+// and whose later attempts fail with errSecond immediately, counting attempts
+func slowFirst(attempts *atomic.Int32, canceled chan<- struct{}) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if attempts.Add(1) > 1 {
+			return errSecond
+		}
+
+		select {
+		case <-ctx.Done():
+			close(canceled)
+
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+}
+
+func TestHedger(t *testing.T) {
+	h := New("openfga", Config{Percentile: 0.9, MinDelay: 5 * time.Millisecond}, NewBudget(1), nil)
+
+	// lookups aren't hedged until enough latencies are observed
+	var attempts atomic.Int32
+
+	err := h.Do(context.Background(), func(context.Context) error {
+		attempts.Add(1)
+		time.Sleep(10 * time.Millisecond)
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), attempts.Load())
+
+	warm(t, h)
+
+	attempts.Store(0)
+	canceled := make(chan struct{})
+	start := time.Now()
+
+	err = h.Do(context.Background(), slowFirst(&attempts, canceled))
+	assert.ErrorIs(t, err, errSecond)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, int32(2), attempts.Load())
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("the losing attempt wasn't canceled")
+	}
+}
+
+func TestHedgerBudget(t *testing.T) {
+	budget := NewBudget(0.05)
+	h := New("openfga", Config{Percentile: 0.9, MinDelay: time.Millisecond}, budget, nil)
+
+	warm(t, h)
+
+	// 21 lookups earned a single hedge
+	var attempts atomic.Int32
+
+	err := h.Do(context.Background(), slowFirst(&attempts, make(chan struct{})))
+	assert.ErrorIs(t, err, errSecond)
+	assert.Equal(t, int32(2), attempts.Load())
+
+	// which is spent, so the next slow lookup is waited for
+	attempts.Store(0)
+
+	err = h.Do(context.Background(), func(context.Context) error {
+		attempts.Add(1)
+		time.Sleep(20 * time.Millisecond)
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(0.5)
+	assert.False(t, b.withdraw())
+
+	b.deposit()
+	assert.False(t, b.withdraw())
+
+	b.deposit()
+	assert.True(t, b.withdraw())
+	assert.False(t, b.withdraw())
+
+	// hedges are only saved up to a limit
+	for i := 0; i < 100; i++ {
+		b.deposit()
+	}
+
+	for i := 0; i < maxBudgetTokens; i++ {
+		assert.True(t, b.withdraw())
+	}
+
+	assert.False(t, b.withdraw())
+}
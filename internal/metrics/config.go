@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+// Config stores the metrics settings
+type Config struct {
+	Sinks  []string     `mapstructure:"sinks"`
+	StatsD StatsDConfig `mapstructure:"statsd"`
+}
+
+// StatsDConfig stores the settings for the statsd sink
+type StatsDConfig struct {
+	Address   string   `mapstructure:"address"`
+	Prefix    string   `mapstructure:"prefix"`
+	Tags      []string `mapstructure:"tags"`
+	DogStatsD bool     `mapstructure:"dogstatsd"`
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.StringSlice("metrics-sinks", []string{SinkPrometheus}, `metrics sinks to record to options: "prometheus", "statsd"`)
+	viperx.MustBindFlag(v, "metrics.sinks", flags.Lookup("metrics-sinks"))
+
+	flags.String("metrics-statsd-address", "", "address of the statsd agent, e.g. localhost:8125")
+	viperx.MustBindFlag(v, "metrics.statsd.address", flags.Lookup("metrics-statsd-address"))
+
+	flags.Bool("metrics-statsd-dogstatsd", false, "send tags using the DogStatsD extension")
+	viperx.MustBindFlag(v, "metrics.statsd.dogstatsd", flags.Lookup("metrics-statsd-dogstatsd"))
+
+	v.MustBindEnv("metrics.statsd.prefix")
+	v.MustBindEnv("metrics.statsd.tags")
+
+	v.SetDefault("metrics.statsd.prefix", "node_resolver.")
+}
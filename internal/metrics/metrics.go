@@ -0,0 +1,218 @@
+// Package metrics records resolver metrics to one or more sinks, such as
+// Prometheus or a statsd agent
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrUnknownSink is returned when a configured metrics sink isn't supported
+var ErrUnknownSink = errors.New("unknown metrics sink")
+
+// Sink names
+const (
+	SinkPrometheus = "prometheus"
+	SinkStatsD     = "statsd"
+)
+
+// Sink records the resolver's metrics. Every sink emits the same metrics:
+//
+//   - resolutions: a counter of ids resolved, by operation, prefix and outcome.
+//     Failed resolutions are labelled with their errcode code, or failed.
+//   - requests: a counter of executed graphql and resolve api requests, by
+//     operation type and outcome
+//   - request duration: a histogram of request durations, by handler
+//   - cache lookups: a counter of cache lookups, by cache and result
+//   - shed requests: a counter of requests rejected under memory pressure, by reason
+//   - panics: a counter of panics recovered while serving requests, by handler
+//   - breaker transitions: a counter of circuit breaker state changes, by backend and state
+//   - breaker rejections: a counter of lookups rejected by open circuit breakers, by backend
+//   - hedges: a counter of lookups sent again because they were slow, by backend
+//   - entity queue: gauges of the _entities chunks waiting for a worker and of
+//     the workers authorizing them
+//   - entity wait: a histogram of how long _entities chunks waited for a worker
+//   - namespace conflicts: a counter of decisions on prefix namespaces claimed
+//     by several schemas, by namespace and outcome
+//   - shadow comparisons: a counter of requests compared with a candidate
+//     schema, by outcome
+//   - schema version requests: a counter of graphql requests served during a
+//     canary rollout, by schema version and outcome
+//   - deprecated prefixes: a counter of ids resolved by a deprecated prefix,
+//     by prefix and the prefix replacing it
+type Sink interface {
+	Resolution(operation, prefix, outcome string)
+	Request(operation, outcome string)
+	RequestDuration(handler string, d time.Duration)
+	CacheLookup(cache string, hit bool)
+	Shed(reason string)
+	Panic(handler string)
+	BreakerTransition(backend, state string)
+	BreakerRejection(backend string)
+	Hedge(backend string)
+	EntityPool(queued, workers int)
+	EntityWait(d time.Duration)
+	NamespaceConflict(namespace, outcome string)
+	ShadowComparison(outcome string)
+	SchemaVersionRequest(version, outcome string)
+	DeprecatedPrefix(prefix, replacement string)
+}
+
+// Nop is a Sink recording nothing. Sinks recording only some of the metrics,
+// such as the fakes of tests, can embed it and implement only those.
+type Nop struct{}
+
+var _ Sink = Nop{}
+
+func (Nop) Resolution(_, _, _ string)                 {}
+func (Nop) Request(_, _ string)                       {}
+func (Nop) RequestDuration(_ string, _ time.Duration) {}
+func (Nop) CacheLookup(_ string, _ bool)              {}
+func (Nop) Shed(_ string)                             {}
+func (Nop) Panic(_ string)                            {}
+func (Nop) BreakerTransition(_, _ string)             {}
+func (Nop) BreakerRejection(_ string)                 {}
+func (Nop) Hedge(_ string)                            {}
+func (Nop) EntityPool(_, _ int)                       {}
+func (Nop) EntityWait(_ time.Duration)                {}
+func (Nop) NamespaceConflict(_, _ string)             {}
+func (Nop) ShadowComparison(_ string)                 {}
+func (Nop) SchemaVersionRequest(_, _ string)          {}
+func (Nop) DeprecatedPrefix(_, _ string)              {}
+
+// Cache lookup results
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// cacheResult returns the result label of a cache lookup
+func cacheResult(hit bool) string {
+	if hit {
+		return CacheHit
+	}
+
+	return CacheMiss
+}
+
+// New returns a Sink recording to every configured sink
+func New(cfg Config, logger *zap.SugaredLogger) (Sink, error) {
+	sinks := multiSink{}
+
+	for _, name := range cfg.Sinks {
+		switch name {
+		case SinkPrometheus:
+			sinks = append(sinks, NewPrometheus())
+		case SinkStatsD:
+			s, err := NewStatsD(cfg.StatsD, logger.Named("statsd"))
+			if err != nil {
+				return nil, err
+			}
+
+			sinks = append(sinks, s)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSink, name)
+		}
+	}
+
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+
+	return sinks, nil
+}
+
+type multiSink []Sink
+
+func (m multiSink) Resolution(operation, prefix, outcome string) {
+	for _, s := range m {
+		s.Resolution(operation, prefix, outcome)
+	}
+}
+
+func (m multiSink) Request(operation, outcome string) {
+	for _, s := range m {
+		s.Request(operation, outcome)
+	}
+}
+
+func (m multiSink) RequestDuration(handler string, d time.Duration) {
+	for _, s := range m {
+		s.RequestDuration(handler, d)
+	}
+}
+
+func (m multiSink) CacheLookup(cache string, hit bool) {
+	for _, s := range m {
+		s.CacheLookup(cache, hit)
+	}
+}
+
+func (m multiSink) Shed(reason string) {
+	for _, s := range m {
+		s.Shed(reason)
+	}
+}
+
+func (m multiSink) Panic(handler string) {
+	for _, s := range m {
+		s.Panic(handler)
+	}
+}
+
+func (m multiSink) BreakerTransition(backend, state string) {
+	for _, s := range m {
+		s.BreakerTransition(backend, state)
+	}
+}
+
+func (m multiSink) BreakerRejection(backend string) {
+	for _, s := range m {
+		s.BreakerRejection(backend)
+	}
+}
+
+func (m multiSink) Hedge(backend string) {
+	for _, s := range m {
+		s.Hedge(backend)
+	}
+}
+
+func (m multiSink) EntityPool(queued, workers int) {
+	for _, s := range m {
+		s.EntityPool(queued, workers)
+	}
+}
+
+func (m multiSink) EntityWait(d time.Duration) {
+	for _, s := range m {
+		s.EntityWait(d)
+	}
+}
+
+func (m multiSink) NamespaceConflict(namespace, outcome string) {
+	for _, s := range m {
+		s.NamespaceConflict(namespace, outcome)
+	}
+}
+
+func (m multiSink) ShadowComparison(outcome string) {
+	for _, s := range m {
+		s.ShadowComparison(outcome)
+	}
+}
+
+func (m multiSink) SchemaVersionRequest(version, outcome string) {
+	for _, s := range m {
+		s.SchemaVersionRequest(version, outcome)
+	}
+}
+
+func (m multiSink) DeprecatedPrefix(prefix, replacement string) {
+	for _, s := range m {
+		s.DeprecatedPrefix(prefix, replacement)
+	}
+}
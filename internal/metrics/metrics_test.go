@@ -0,0 +1,135 @@
+package metrics_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+func TestStatsD(t *testing.T) {
+	testCases := []struct {
+		TestName  string
+		dogstatsd bool
+		expected  []string
+	}{
+		{
+			TestName: "statsd",
+			expected: []string{
+				"node_resolver.resolutions.node.loadbal.resolved:1|c",
+				"node_resolver.requests.node.success:1|c",
+				"node_resolver.request_duration.query:1.5|ms",
+				"node_resolver.cache_lookups.document.hit:1|c",
+				"node_resolver.shed_requests.body_size:1|c",
+				"node_resolver.panics.query:1|c",
+				"node_resolver.breaker_transitions.openfga.open:1|c",
+				"node_resolver.breaker_rejections.openfga:1|c",
+				"node_resolver.hedges.openfga:1|c",
+				"node_resolver.entity_queue_length:3|g",
+				"node_resolver.entity_workers:8|g",
+				"node_resolver.entity_wait:2.5|ms",
+				"node_resolver.namespace_conflicts.load.rejected:1|c",
+				"node_resolver.shadow_comparisons.mismatch:1|c",
+				"node_resolver.schema_version_requests.canary.error:1|c",
+				"node_resolver.deprecated_prefixes.lodbaln.loadbal:1|c",
+			},
+		},
+		{
+			TestName:  "dogstatsd",
+			dogstatsd: true,
+			expected: []string{
+				"node_resolver.resolutions:1|c|#operation:node,prefix:loadbal,outcome:resolved,env:test",
+				"node_resolver.requests:1|c|#operation:node,outcome:success,env:test",
+				"node_resolver.request_duration:1.5|ms|#handler:query,env:test",
+				"node_resolver.cache_lookups:1|c|#cache:document,result:hit,env:test",
+				"node_resolver.shed_requests:1|c|#reason:body_size,env:test",
+				"node_resolver.panics:1|c|#handler:query,env:test",
+				"node_resolver.breaker_transitions:1|c|#backend:openfga,state:open,env:test",
+				"node_resolver.breaker_rejections:1|c|#backend:openfga,env:test",
+				"node_resolver.hedges:1|c|#backend:openfga,env:test",
+				"node_resolver.entity_queue_length:3|g|#env:test",
+				"node_resolver.entity_workers:8|g|#env:test",
+				"node_resolver.entity_wait:2.5|ms|#env:test",
+				"node_resolver.namespace_conflicts:1|c|#namespace:load,outcome:rejected,env:test",
+				"node_resolver.shadow_comparisons:1|c|#outcome:mismatch,env:test",
+				"node_resolver.schema_version_requests:1|c|#version:canary,outcome:error,env:test",
+				"node_resolver.deprecated_prefixes:1|c|#prefix:lodbaln,replacement:loadbal,env:test",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			defer conn.Close()
+
+			sink, err := metrics.New(metrics.Config{
+				Sinks: []string{metrics.SinkStatsD},
+				StatsD: metrics.StatsDConfig{
+					Address:   conn.LocalAddr().String(),
+					Prefix:    "node_resolver.",
+					Tags:      []string{"env:test"},
+					DogStatsD: tt.dogstatsd,
+				},
+			}, zap.NewNop().Sugar())
+			require.NoError(t, err)
+
+			sink.Resolution("node", "loadbal", "resolved")
+			sink.Request("node", "success")
+			sink.RequestDuration("query", 1500*time.Microsecond)
+			sink.CacheLookup("document", true)
+			sink.Shed("body_size")
+			sink.Panic("query")
+			sink.BreakerTransition("openfga", "open")
+			sink.BreakerRejection("openfga")
+			sink.Hedge("openfga")
+			sink.EntityPool(3, 8)
+			sink.EntityWait(2500 * time.Microsecond)
+			sink.NamespaceConflict("load", "rejected")
+			sink.ShadowComparison("mismatch")
+			sink.SchemaVersionRequest("canary", "error")
+			sink.DeprecatedPrefix("lodbaln", "loadbal")
+
+			buf := make([]byte, 1024)
+
+			for _, expected := range tt.expected {
+				require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+				n, _, err := conn.ReadFrom(buf)
+				require.NoError(t, err)
+
+				assert.Equal(t, expected, string(buf[:n]))
+			}
+		})
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	sink, err := metrics.New(metrics.Config{Sinks: []string{metrics.SinkPrometheus}}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	sink.Resolution("node", "loadbal", "resolved")
+
+	expected := `
+# HELP node_resolver_resolutions_total Number of ids resolved by operation, prefix and outcome.
+# TYPE node_resolver_resolutions_total counter
+node_resolver_resolutions_total{operation="node",outcome="resolved",prefix="loadbal"} 1
+`
+
+	assert.NoError(t, testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "node_resolver_resolutions_total"))
+}
+
+func TestUnknownSink(t *testing.T) {
+	_, err := metrics.New(metrics.Config{Sinks: []string{"graphite"}}, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, metrics.ErrUnknownSink)
+}
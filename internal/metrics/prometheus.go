@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "node_resolver"
+
+var (
+	registerOnce sync.Once
+
+	resolutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resolutions_total",
+		Help:      "Number of ids resolved by operation, prefix and outcome.",
+	}, []string{"operation", "prefix", "outcome"})
+
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Number of executed requests by operation type and outcome.",
+	}, []string{"operation", "outcome"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of resolution requests by handler.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Number of cache lookups by cache and result.",
+	}, []string{"cache", "result"})
+
+	shedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shed_requests_total",
+		Help:      "Number of requests rejected under memory pressure by reason.",
+	}, []string{"reason"})
+
+	panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "panics_total",
+		Help:      "Number of panics recovered while serving requests by handler.",
+	}, []string{"handler"})
+
+	breakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "breaker_transitions_total",
+		Help:      "Number of circuit breaker state changes by backend and state.",
+	}, []string{"backend", "state"})
+
+	breakerRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "breaker_rejections_total",
+		Help:      "Number of lookups rejected by open circuit breakers by backend.",
+	}, []string{"backend"})
+
+	hedges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "hedges_total",
+		Help:      "Number of slow lookups sent again by backend.",
+	}, []string{"backend"})
+
+	entityQueue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "entity_queue_length",
+		Help:      "Number of _entities chunks waiting for a worker.",
+	})
+
+	entityWorkers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "entity_workers",
+		Help:      "Number of workers authorizing _entities chunks.",
+	})
+
+	entityWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "entity_wait_seconds",
+		Help:      "Time _entities chunks waited for a worker.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 8), //nolint:gomnd
+	})
+
+	namespaceConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "namespace_conflicts_total",
+		Help:      "Number of decisions on prefix namespaces claimed by several schemas by namespace and outcome.",
+	}, []string{"namespace", "outcome"})
+
+	shadowComparisons = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_comparisons_total",
+		Help:      "Number of requests compared with a candidate schema by outcome.",
+	}, []string{"outcome"})
+
+	schemaVersionRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "schema_version_requests_total",
+		Help:      "Number of graphql requests served during a canary rollout by schema version and outcome.",
+	}, []string{"version", "outcome"})
+
+	deprecatedPrefixes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deprecated_prefixes_total",
+		Help:      "Number of ids resolved by a deprecated prefix by prefix and the prefix replacing it.",
+	}, []string{"prefix", "replacement"})
+)
+
+// Prometheus records metrics to the default prometheus registry, which is
+// served on /metrics
+type Prometheus struct{}
+
+// NewPrometheus returns a Sink recording to the default prometheus registry
+func NewPrometheus() *Prometheus {
+	registerOnce.Do(func() {
+		prometheus.MustRegister(resolutions, requests, requestDuration, cacheLookups, shedRequests, panics, breakerTransitions, breakerRejections, hedges,
+			entityQueue, entityWorkers, entityWait, namespaceConflicts, shadowComparisons, schemaVersionRequests, deprecatedPrefixes)
+	})
+
+	return &Prometheus{}
+}
+
+// Resolution counts a resolved id
+func (p *Prometheus) Resolution(operation, prefix, outcome string) {
+	resolutions.WithLabelValues(operation, prefix, outcome).Inc()
+}
+
+// Request counts an executed request
+func (p *Prometheus) Request(operation, outcome string) {
+	requests.WithLabelValues(operation, outcome).Inc()
+}
+
+// RequestDuration observes the duration of a request
+func (p *Prometheus) RequestDuration(handler string, d time.Duration) {
+	requestDuration.WithLabelValues(handler).Observe(d.Seconds())
+}
+
+// CacheLookup counts a cache lookup
+func (p *Prometheus) CacheLookup(cache string, hit bool) {
+	cacheLookups.WithLabelValues(cache, cacheResult(hit)).Inc()
+}
+
+// Shed counts a request rejected under memory pressure
+func (p *Prometheus) Shed(reason string) {
+	shedRequests.WithLabelValues(reason).Inc()
+}
+
+// Panic counts a panic recovered while serving a request
+func (p *Prometheus) Panic(handler string) {
+	panics.WithLabelValues(handler).Inc()
+}
+
+// BreakerTransition counts a circuit breaker state change
+func (p *Prometheus) BreakerTransition(backend, state string) {
+	breakerTransitions.WithLabelValues(backend, state).Inc()
+}
+
+// BreakerRejection counts a lookup rejected by an open circuit breaker
+func (p *Prometheus) BreakerRejection(backend string) {
+	breakerRejections.WithLabelValues(backend).Inc()
+}
+
+// Hedge counts a slow lookup sent again
+func (p *Prometheus) Hedge(backend string) {
+	hedges.WithLabelValues(backend).Inc()
+}
+
+// EntityPool sets the number of _entities chunks waiting for a worker and
+// the number of workers
+func (p *Prometheus) EntityPool(queued, workers int) {
+	entityQueue.Set(float64(queued))
+	entityWorkers.Set(float64(workers))
+}
+
+// EntityWait observes how long an _entities chunk waited for a worker
+func (p *Prometheus) EntityWait(d time.Duration) {
+	entityWait.Observe(d.Seconds())
+}
+
+// NamespaceConflict counts a decision on a prefix namespace claimed by
+// several schemas
+func (p *Prometheus) NamespaceConflict(namespace, outcome string) {
+	namespaceConflicts.WithLabelValues(namespace, outcome).Inc()
+}
+
+// ShadowComparison counts a request compared with a candidate schema
+func (p *Prometheus) ShadowComparison(outcome string) {
+	shadowComparisons.WithLabelValues(outcome).Inc()
+}
+
+// SchemaVersionRequest counts a graphql request served during a canary
+// rollout
+func (p *Prometheus) SchemaVersionRequest(version, outcome string) {
+	schemaVersionRequests.WithLabelValues(version, outcome).Inc()
+}
+
+// DeprecatedPrefix counts an id resolved by a deprecated prefix
+func (p *Prometheus) DeprecatedPrefix(prefix, replacement string) {
+	deprecatedPrefixes.WithLabelValues(prefix, replacement).Inc()
+}
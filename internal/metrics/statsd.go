@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrMissingStatsDAddress is returned when the statsd sink is selected without an address
+var ErrMissingStatsDAddress = errors.New("missing statsd address")
+
+// StatsD sends metrics to a statsd agent over udp. With DogStatsD enabled
+// tags are sent using the DogStatsD tag extension, otherwise they're
+// appended to the metric name since plain statsd has no tags.
+type StatsD struct {
+	cfg    StatsDConfig
+	logger *zap.SugaredLogger
+	conn   net.Conn
+	tags   string
+}
+
+// NewStatsD returns a Sink sending metrics to the configured statsd agent
+func NewStatsD(cfg StatsDConfig, logger *zap.SugaredLogger) (*StatsD, error) {
+	if cfg.Address == "" {
+		return nil, ErrMissingStatsDAddress
+	}
+
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := append([]string{}, cfg.Tags...)
+	sort.Strings(tags)
+
+	return &StatsD{
+		cfg:    cfg,
+		logger: logger,
+		conn:   conn,
+		tags:   strings.Join(tags, ","),
+	}, nil
+}
+
+// Resolution counts a resolved id
+func (s *StatsD) Resolution(operation, prefix, outcome string) {
+	s.send("resolutions", "1|c", "operation", operation, "prefix", prefix, "outcome", outcome)
+}
+
+// Request counts an executed request
+func (s *StatsD) Request(operation, outcome string) {
+	s.send("requests", "1|c", "operation", operation, "outcome", outcome)
+}
+
+// RequestDuration sends the duration of a request as a timing in milliseconds
+func (s *StatsD) RequestDuration(handler string, d time.Duration) {
+	ms := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+
+	s.send("request_duration", ms+"|ms", "handler", handler)
+}
+
+// CacheLookup counts a cache lookup
+func (s *StatsD) CacheLookup(cache string, hit bool) {
+	s.send("cache_lookups", "1|c", "cache", cache, "result", cacheResult(hit))
+}
+
+// Shed counts a request rejected under memory pressure
+func (s *StatsD) Shed(reason string) {
+	s.send("shed_requests", "1|c", "reason", reason)
+}
+
+// Panic counts a panic recovered while serving a request
+func (s *StatsD) Panic(handler string) {
+	s.send("panics", "1|c", "handler", handler)
+}
+
+// BreakerTransition counts a circuit breaker state change
+func (s *StatsD) BreakerTransition(backend, state string) {
+	s.send("breaker_transitions", "1|c", "backend", backend, "state", state)
+}
+
+// BreakerRejection counts a lookup rejected by an open circuit breaker
+func (s *StatsD) BreakerRejection(backend string) {
+	s.send("breaker_rejections", "1|c", "backend", backend)
+}
+
+// Hedge counts a slow lookup sent again
+func (s *StatsD) Hedge(backend string) {
+	s.send("hedges", "1|c", "backend", backend)
+}
+
+// EntityPool sends the number of _entities chunks waiting for a worker and
+// the number of workers as gauges
+func (s *StatsD) EntityPool(queued, workers int) {
+	s.send("entity_queue_length", strconv.Itoa(queued)+"|g")
+	s.send("entity_workers", strconv.Itoa(workers)+"|g")
+}
+
+// EntityWait sends how long an _entities chunk waited for a worker as a
+// timing in milliseconds
+func (s *StatsD) EntityWait(d time.Duration) {
+	ms := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+
+	s.send("entity_wait", ms+"|ms")
+}
+
+// NamespaceConflict counts a decision on a prefix namespace claimed by
+// several schemas
+func (s *StatsD) NamespaceConflict(namespace, outcome string) {
+	s.send("namespace_conflicts", "1|c", "namespace", namespace, "outcome", outcome)
+}
+
+// ShadowComparison counts a request compared with a candidate schema
+func (s *StatsD) ShadowComparison(outcome string) {
+	s.send("shadow_comparisons", "1|c", "outcome", outcome)
+}
+
+// SchemaVersionRequest counts a graphql request served during a canary
+// rollout
+func (s *StatsD) SchemaVersionRequest(version, outcome string) {
+	s.send("schema_version_requests", "1|c", "version", version, "outcome", outcome)
+}
+
+// DeprecatedPrefix counts an id resolved by a deprecated prefix
+func (s *StatsD) DeprecatedPrefix(prefix, replacement string) {
+	s.send("deprecated_prefixes", "1|c", "prefix", prefix, "replacement", replacement)
+}
+
+// Close closes the connection to the agent
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// send writes a metric with the given tag key value pairs. Failures are only
+// logged since metrics must never fail a request.
+func (s *StatsD) send(name, value string, tagKVs ...string) {
+	var sb strings.Builder
+
+	sb.WriteString(s.cfg.Prefix)
+	sb.WriteString(name)
+
+	if !s.cfg.DogStatsD {
+		for i := 1; i < len(tagKVs); i += 2 {
+			sb.WriteString(".")
+			sb.WriteString(sanitize(tagKVs[i]))
+		}
+	}
+
+	sb.WriteString(":")
+	sb.WriteString(value)
+
+	if s.cfg.DogStatsD {
+		tags := make([]string, 0, len(tagKVs)/2+1) //nolint:gomnd
+
+		for i := 0; i+1 < len(tagKVs); i += 2 {
+			tags = append(tags, tagKVs[i]+":"+sanitize(tagKVs[i+1]))
+		}
+
+		if s.tags != "" {
+			tags = append(tags, s.tags)
+		}
+
+		sb.WriteString("|#")
+		sb.WriteString(strings.Join(tags, ","))
+	}
+
+	if _, err := s.conn.Write([]byte(sb.String())); err != nil {
+		s.logger.Debugw("failed to send metric", "metric", name, "error", err)
+	}
+}
+
+// sanitize replaces the characters with special meaning in the statsd
+// protocol
+func sanitize(s string) string {
+	if s == "" {
+		return "none"
+	}
+
+	return strings.NewReplacer(":", "_", "|", "_", ",", "_", "#", "_", "@", "_", ".", "_").Replace(s)
+}
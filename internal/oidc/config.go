@@ -0,0 +1,36 @@
+package oidc
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/echojwtx"
+	"go.infratographer.com/x/viperx"
+)
+
+// Config stores the settings for authenticating requests with oidc jwts
+type Config struct {
+	Issuer   string `mapstructure:"issuer"`
+	Audience string `mapstructure:"audience"`
+	// JWKSRemoteTimeout limits fetching the signing keys of the issuer
+	JWKSRemoteTimeout time.Duration `mapstructure:"jwks-remote-timeout"`
+}
+
+// Enabled returns true when an issuer is configured
+func (c Config) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("oidc-issuer", "", "issuer of the oidc jwts required to resolve ids, requests aren't authenticated when unset")
+	viperx.MustBindFlag(v, "oidc.issuer", flags.Lookup("oidc-issuer"))
+
+	flags.String("oidc-audience", "", "audience the oidc jwts must be issued for")
+	viperx.MustBindFlag(v, "oidc.audience", flags.Lookup("oidc-audience"))
+
+	v.MustBindEnv("oidc.jwks-remote-timeout")
+
+	v.SetDefault("oidc.jwks-remote-timeout", echojwtx.DefaultOIDCJWKSRemoteTimeout)
+}
@@ -0,0 +1,114 @@
+// Package oidc authenticates requests with jwts issued by an oidc provider
+package oidc
+
+import (
+	"context"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"go.infratographer.com/x/echojwtx"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// attrIssuer is the span attribute of the issuer of the request's jwt
+const attrIssuer = attribute.Key("node_resolver.issuer")
+
+type (
+	issuerCtxKey struct{}
+	tokenCtxKey  struct{}
+)
+
+// WithIssuer returns a copy of ctx carrying the issuer of the request's jwt
+func WithIssuer(ctx context.Context, iss string) context.Context {
+	return context.WithValue(ctx, issuerCtxKey{}, iss)
+}
+
+// Issuer returns the issuer of the jwt the request was authenticated with,
+// or an empty string when it wasn't authenticated
+func Issuer(ctx context.Context) string {
+	if iss, ok := ctx.Value(issuerCtxKey{}).(string); ok {
+		return iss
+	}
+
+	return ""
+}
+
+// WithToken returns a copy of ctx carrying the request's raw jwt
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenCtxKey{}, token)
+}
+
+// Token returns the raw jwt the request was authenticated with, so it can be
+// passed on to services authorizing the caller, or an empty string when the
+// request wasn't authenticated
+func Token(ctx context.Context) string {
+	if token, ok := ctx.Value(tokenCtxKey{}).(string); ok {
+		return token
+	}
+
+	return ""
+}
+
+// NewMiddleware returns middleware rejecting requests without a valid jwt
+// from the configured issuer. The signing keys are fetched from the jwks_uri
+// of the issuer's openid configuration.
+func NewMiddleware(ctx context.Context, cfg Config, logger *zap.SugaredLogger) (echo.MiddlewareFunc, error) {
+	return newMiddleware(ctx, echojwtx.AuthConfig{
+		Logger:   logger.Desugar(),
+		Issuer:   cfg.Issuer,
+		Audience: cfg.Audience,
+		KeyFuncOptions: keyfunc.Options{
+			RefreshTimeout: cfg.JWKSRemoteTimeout,
+		},
+	})
+}
+
+func newMiddleware(ctx context.Context, cfg echojwtx.AuthConfig) (echo.MiddlewareFunc, error) {
+	auth, err := echojwtx.NewAuth(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	validate := auth.Middleware()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return validate(annotate(next))
+	}, nil
+}
+
+// annotate adds the validated jwt and its issuer to the request context, and
+// its subject and issuer to the request's span
+func annotate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := c.Get("user").(*jwt.Token)
+		if !ok {
+			return next(c)
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return next(c)
+		}
+
+		ctx := WithToken(c.Request().Context(), token.Raw)
+		span := trace.SpanFromContext(ctx)
+
+		if sub, err := claims.GetSubject(); err == nil && sub != "" {
+			span.SetAttributes(semconv.EnduserID(sub))
+		}
+
+		if iss, err := claims.GetIssuer(); err == nil && iss != "" {
+			span.SetAttributes(attrIssuer.String(iss))
+
+			ctx = WithIssuer(ctx, iss)
+		}
+
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		return next(c)
+	}
+}
@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 2 * time.Second
+
+// Config stores the settings for evaluating requests against an OPA policy
+type Config struct {
+	URL      string        `mapstructure:"url"`
+	Path     string        `mapstructure:"path"`
+	Token    string        `mapstructure:"token"`
+	FailOpen bool          `mapstructure:"fail-open"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+
+	// Transport is used for requests to OPA, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// Enabled returns true when an OPA url has been configured
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("policy-opa-url", "", "url of the OPA api to evaluate requests with")
+	viperx.MustBindFlag(v, "policy.url", flags.Lookup("policy-opa-url"))
+
+	flags.String("policy-opa-path", "noderesolver", "path of the OPA decision document, relative to /v1/data")
+	viperx.MustBindFlag(v, "policy.path", flags.Lookup("policy-opa-path"))
+
+	flags.Bool("policy-fail-open", false, "allow requests when the policy can't be evaluated")
+	viperx.MustBindFlag(v, "policy.fail-open", flags.Lookup("policy-fail-open"))
+
+	v.MustBindEnv("policy.token")
+	v.MustBindEnv("policy.timeout")
+
+	v.SetDefault("policy.timeout", defaultTimeout)
+}
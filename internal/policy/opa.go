@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ErrMissingOPAConfig is returned when the OPA url is not configured
+var ErrMissingOPAConfig = errors.New("missing OPA config options; you must pass a url")
+
+type opaRequest struct {
+	Input Input `json:"input"`
+}
+
+type opaResponse struct {
+	Result json.RawMessage `json:"result"`
+}
+
+// OPA evaluates requests using the data api of an Open Policy Agent, usually
+// running as a sidecar. The decision document may either be a boolean or an
+// object in the form of Decision.
+type OPA struct {
+	cfg     Config
+	logger  *zap.SugaredLogger
+	http    *http.Client
+	dataURL string
+}
+
+// NewOPA returns an Evaluator backed by the OPA data api
+func NewOPA(cfg Config, logger *zap.SugaredLogger) (*OPA, error) {
+	if cfg.URL == "" {
+		return nil, ErrMissingOPAConfig
+	}
+
+	dataURL, err := url.JoinPath(cfg.URL, "v1", "data", strings.Trim(cfg.Path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &OPA{
+		cfg:     cfg,
+		logger:  logger,
+		http:    &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		dataURL: dataURL,
+	}, nil
+}
+
+// Evaluate queries the decision document with the given input. Failures to
+// reach OPA deny the request unless FailOpen is set.
+func (o *OPA) Evaluate(ctx context.Context, input Input) (Decision, error) {
+	d, err := o.evaluate(ctx, input)
+	if err != nil {
+		if o.cfg.FailOpen {
+			o.logger.Warnw("policy evaluation failed, allowing request", "error", err)
+
+			return Decision{Allow: true}, nil
+		}
+
+		return Decision{}, err
+	}
+
+	return d, nil
+}
+
+func (o *OPA) evaluate(ctx context.Context, input Input) (Decision, error) {
+	body, err := json.Marshal(opaRequest{Input: input})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.dataURL, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if o.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.cfg.Token)
+	}
+
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	if resp.StatusCode != http.StatusOK {
+		return Decision{}, fmt.Errorf("unexpected response from OPA: %s", resp.Status)
+	}
+
+	var or opaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&or); err != nil {
+		return Decision{}, err
+	}
+
+	// an undefined decision has no result and denies the request
+	if len(or.Result) == 0 {
+		return Decision{Reason: "policy decision is undefined"}, nil
+	}
+
+	var allow bool
+	if err := json.Unmarshal(or.Result, &allow); err == nil {
+		return Decision{Allow: allow}, nil
+	}
+
+	var d Decision
+	if err := json.Unmarshal(or.Result, &d); err != nil {
+		return Decision{}, fmt.Errorf("invalid policy decision: %w", err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,101 @@
+package policy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/policy"
+)
+
+func TestOPA(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		status   int
+		result   string
+		failOpen bool
+		decision policy.Decision
+		errorMsg string
+	}{
+		{
+			TestName: "boolean allow",
+			status:   http.StatusOK,
+			result:   `{"result": true}`,
+			decision: policy.Decision{Allow: true},
+		},
+		{
+			TestName: "decision object",
+			status:   http.StatusOK,
+			result:   `{"result": {"allow": false, "reason": "no", "annotations": {"team": "security"}}}`,
+			decision: policy.Decision{Reason: "no", Annotations: map[string]interface{}{"team": "security"}},
+		},
+		{
+			TestName: "undefined decision",
+			status:   http.StatusOK,
+			result:   `{}`,
+			decision: policy.Decision{Reason: "policy decision is undefined"},
+		},
+		{
+			TestName: "unavailable",
+			status:   http.StatusInternalServerError,
+			errorMsg: "unexpected response from OPA",
+		},
+		{
+			TestName: "unavailable fail open",
+			status:   http.StatusInternalServerError,
+			failOpen: true,
+			decision: policy.Decision{Allow: true},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/data/noderesolver/decision", r.URL.Path)
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+				var body struct {
+					Input policy.Input `json:"input"`
+				}
+
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, "idntusr-123", body.Input.Subject)
+				assert.Equal(t, []string{"testsrv-123"}, body.Input.IDs)
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.result))
+			}))
+			defer srv.Close()
+
+			opa, err := policy.NewOPA(policy.Config{
+				URL:      srv.URL,
+				Path:     "/noderesolver/decision",
+				Token:    "secret",
+				FailOpen: tt.failOpen,
+			}, zap.NewNop().Sugar())
+			require.NoError(t, err)
+
+			d, err := opa.Evaluate(context.Background(), policy.Input{
+				Subject:  "idntusr-123",
+				Fields:   []string{"node"},
+				IDs:      []string{"testsrv-123"},
+				Prefixes: []string{"testsrv"},
+			})
+
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.decision, d)
+		})
+	}
+}
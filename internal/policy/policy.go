@@ -0,0 +1,32 @@
+// Package policy evaluates incoming requests against an external policy
+// engine, allowing requests to be denied or annotated without code changes
+package policy
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDenied is returned when the policy doesn't allow a request
+var ErrDenied = errors.New("request denied by policy")
+
+// Input describes the request being evaluated
+type Input struct {
+	Subject   string   `json:"subject"`
+	Operation string   `json:"operation"`
+	Fields    []string `json:"fields"`
+	IDs       []string `json:"ids"`
+	Prefixes  []string `json:"prefixes"`
+}
+
+// Decision is the result of evaluating a request
+type Decision struct {
+	Allow       bool                   `json:"allow"`
+	Reason      string                 `json:"reason,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// Evaluator decides if a request is allowed and how it should be annotated
+type Evaluator interface {
+	Evaluate(ctx context.Context, input Input) (Decision, error)
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultExpiresIn = 3 * time.Minute
+
+// Config stores the rate limiting settings
+type Config struct {
+	// Rate is the number of requests per second each client is allowed, 0
+	// disables rate limiting
+	Rate float64 `mapstructure:"rate"`
+	// Burst is the number of requests a client may send at once, defaulting
+	// to the rate
+	Burst int `mapstructure:"burst"`
+	Key   Key `mapstructure:"key"`
+	// ExpiresIn is how long the limit of a client that stopped sending
+	// requests is kept
+	ExpiresIn time.Duration `mapstructure:"expires-in"`
+}
+
+// Enabled returns true when a rate is configured
+func (c Config) Enabled() bool {
+	return c.Rate > 0
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Float64("rate-limit", 0, "requests per second allowed for each client, 0 disables rate limiting")
+	viperx.MustBindFlag(v, "ratelimit.rate", flags.Lookup("rate-limit"))
+
+	flags.Int("rate-limit-burst", 0, "requests each client may send at once (default the rate limit)")
+	viperx.MustBindFlag(v, "ratelimit.burst", flags.Lookup("rate-limit-burst"))
+
+	flags.String("rate-limit-key", string(KeyIP), `what identifies a client to rate limit options: "ip", "subject"`)
+	viperx.MustBindFlag(v, "ratelimit.key", flags.Lookup("rate-limit-key"))
+
+	v.MustBindEnv("ratelimit.expires-in")
+
+	v.SetDefault("ratelimit.expires-in", defaultExpiresIn)
+}
@@ -0,0 +1,83 @@
+// Package ratelimit throttles the requests of each client with a token bucket
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/errcode"
+)
+
+// retryAfter is the Retry-After header sent with rejected requests
+const retryAfter = "1"
+
+// Key identifies the client a request is counted against
+type Key string
+
+const (
+	// KeyIP counts requests by the client's ip
+	KeyIP Key = "ip"
+	// KeySubject counts requests by the authenticated subject, and the
+	// requests of anonymous clients by their ip
+	KeySubject Key = "subject"
+)
+
+var (
+	// ErrRateLimited is returned for requests of clients over their rate limit
+	ErrRateLimited = echo.NewHTTPError(http.StatusTooManyRequests, echo.Map{
+		"code":    errcode.RateLimited,
+		"message": "too many requests",
+	})
+
+	// ErrInvalidKey is returned when the configured key isn't supported
+	ErrInvalidKey = errors.New("invalid rate limit key")
+)
+
+// Middleware returns middleware rejecting the requests of clients sending
+// more than the configured rate with ErrRateLimited
+func Middleware(cfg Config) (echo.MiddlewareFunc, error) {
+	var extractor middleware.Extractor
+
+	switch cfg.Key {
+	case KeyIP, "":
+		extractor = ipIdentifier
+	case KeySubject:
+		extractor = subjectIdentifier
+	default:
+		return nil, fmt.Errorf("%w: %q, expected ip or subject", ErrInvalidKey, cfg.Key)
+	}
+
+	store := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(cfg.Rate),
+		Burst:     cfg.Burst,
+		ExpiresIn: cfg.ExpiresIn,
+	})
+
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Store:               store,
+		IdentifierExtractor: extractor,
+		DenyHandler: func(c echo.Context, _ string, _ error) error {
+			c.Response().Header().Set(echo.HeaderRetryAfter, retryAfter)
+
+			return ErrRateLimited
+		},
+	}), nil
+}
+
+func ipIdentifier(c echo.Context) (string, error) {
+	return "ip:" + c.RealIP(), nil
+}
+
+func subjectIdentifier(c echo.Context) (string, error) {
+	if subject := authz.Subject(c.Request().Context()); subject != "" {
+		return "subject:" + subject, nil
+	}
+
+	return ipIdentifier(c)
+}
@@ -0,0 +1,89 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/echojwtx"
+
+	"go.infratographer.com/node-resolver/internal/ratelimit"
+)
+
+func TestMiddleware(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		key      ratelimit.Key
+		// expected is the status of a request from each client after
+		// the first client used its burst
+		expected map[string]int
+	}{
+		{
+			TestName: "ip",
+			key:      ratelimit.KeyIP,
+			expected: map[string]int{
+				"10.0.0.1/idntusr-a": http.StatusTooManyRequests,
+				"10.0.0.1/idntusr-b": http.StatusTooManyRequests,
+				"10.0.0.2/":          http.StatusOK,
+			},
+		},
+		{
+			TestName: "subject",
+			key:      ratelimit.KeySubject,
+			expected: map[string]int{
+				"10.0.0.1/idntusr-a": http.StatusTooManyRequests,
+				"10.0.0.1/idntusr-b": http.StatusOK,
+				"10.0.0.1/":          http.StatusOK,
+				"10.0.0.2/idntusr-a": http.StatusTooManyRequests,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			mw, err := ratelimit.Middleware(ratelimit.Config{Rate: 0.001, Burst: 2, Key: tt.key})
+			require.NoError(t, err)
+
+			e := echo.New()
+			e.GET("/query", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, mw)
+
+			send := func(ip, subject string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, "/query", nil)
+				req.RemoteAddr = ip + ":1234"
+
+				if subject != "" {
+					req = req.WithContext(context.WithValue(req.Context(), echojwtx.ActorCtxKey, subject))
+				}
+
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+
+				return rec
+			}
+
+			for i := 0; i < 2; i++ {
+				assert.Equal(t, http.StatusOK, send("10.0.0.1", "idntusr-a").Code)
+			}
+
+			rec := send("10.0.0.1", "idntusr-a")
+			assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+			assert.Equal(t, "1", rec.Header().Get(echo.HeaderRetryAfter))
+			assert.JSONEq(t, `{"code":"rate_limited","message":"too many requests"}`, rec.Body.String())
+
+			for client, status := range tt.expected {
+				ip, subject, _ := strings.Cut(client, "/")
+				assert.Equal(t, status, send(ip, subject).Code, client)
+			}
+		})
+	}
+}
+
+func TestInvalidKey(t *testing.T) {
+	_, err := ratelimit.Middleware(ratelimit.Config{Rate: 1, Key: "header"})
+	assert.ErrorIs(t, err, ratelimit.ErrInvalidKey)
+}
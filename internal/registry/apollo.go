@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultApolloURL = "https://api.apollographql.com/api/graphql"
+
+	publishSubgraphMutation = `mutation PublishSubgraph($graphID: ID!, $variant: String!, $name: String!, $url: String, $revision: String!, $schema: PartialSchemaInput!) {
+  graph(id: $graphID) {
+    publishSubgraph(graphVariant: $variant, name: $name, url: $url, revision: $revision, activePartialSchema: $schema) {
+      wasCreated
+      launchUrl
+      errors { message }
+    }
+  }
+}`
+)
+
+type apolloResponse struct {
+	Data struct {
+		Graph *struct {
+			PublishSubgraph struct {
+				WasCreated bool   `json:"wasCreated"`
+				LaunchURL  string `json:"launchUrl"`
+				Errors     []struct {
+					Message string `json:"message"`
+				} `json:"errors"`
+			} `json:"publishSubgraph"`
+		} `json:"graph"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Apollo publishes subgraphs to Apollo GraphOS, triggering composition of the
+// supergraph for the configured variant
+type Apollo struct {
+	cfg     Config
+	logger  *zap.SugaredLogger
+	http    *http.Client
+	graphID string
+	variant string
+}
+
+// NewApollo returns a Publisher for Apollo GraphOS. The graph ref defaults to
+// the current variant when no variant is given.
+func NewApollo(cfg Config, logger *zap.SugaredLogger) (*Apollo, error) {
+	if cfg.GraphRef == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("%w: apollo requires a graph ref and token", ErrMissingConfig)
+	}
+
+	if cfg.URL == "" {
+		cfg.URL = defaultApolloURL
+	}
+
+	graphID, variant, ok := strings.Cut(cfg.GraphRef, "@")
+	if !ok {
+		variant = "current"
+	}
+
+	return &Apollo{
+		cfg:     cfg,
+		logger:  logger,
+		http:    &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		graphID: graphID,
+		variant: variant,
+	}, nil
+}
+
+// Publish publishes the subgraph to the graph variant, using the checksum as
+// the revision
+func (a *Apollo) Publish(ctx context.Context, subgraph Subgraph) error {
+	body := map[string]interface{}{
+		"query": publishSubgraphMutation,
+		"variables": map[string]interface{}{
+			"graphID":  a.graphID,
+			"variant":  a.variant,
+			"name":     subgraph.Name,
+			"url":      subgraph.RoutingURL,
+			"revision": subgraph.Checksum,
+			"schema":   map[string]string{"sdl": subgraph.SDL},
+		},
+	}
+
+	headers := map[string]string{
+		"X-API-Key":                    a.cfg.Token,
+		"apollographql-client-name":    subgraph.Name,
+		"apollographql-client-version": subgraph.Checksum,
+	}
+
+	var resp apolloResponse
+	if err := postJSON(ctx, a.http, a.cfg.URL, headers, body, &resp); err != nil {
+		return err
+	}
+
+	if len(resp.Errors) != 0 {
+		return fmt.Errorf("publishing subgraph: %s", resp.Errors[0].Message)
+	}
+
+	if resp.Data.Graph == nil {
+		return fmt.Errorf("publishing subgraph: graph %s not found", a.graphID)
+	}
+
+	result := resp.Data.Graph.PublishSubgraph
+
+	if len(result.Errors) != 0 {
+		return fmt.Errorf("publishing subgraph: %s", result.Errors[0].Message)
+	}
+
+	a.logger.Infow("published subgraph",
+		"graph_ref", a.graphID+"@"+a.variant,
+		"name", subgraph.Name,
+		"checksum", subgraph.Checksum,
+		"created", result.WasCreated,
+		"launch_url", result.LaunchURL,
+	)
+
+	return nil
+}
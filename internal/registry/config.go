@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 10 * time.Second
+
+// Config stores the settings for publishing the resolver's SDL to a schema registry
+type Config struct {
+	Provider     Provider      `mapstructure:"provider"`
+	URL          string        `mapstructure:"url"`
+	RoutingURL   string        `mapstructure:"routing-url"`
+	SubgraphName string        `mapstructure:"subgraph-name"`
+	GraphRef     string        `mapstructure:"graph-ref"`
+	Token        string        `mapstructure:"token"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+
+	// SPIFFE presents the workload's SVID to the registry, for registries
+	// running in the mesh. Hosted registries such as Apollo GraphOS don't
+	// accept SVIDs.
+	SPIFFE bool `mapstructure:"spiffe"`
+
+	// Transport is used for requests to the registry, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// Enabled returns true when a registry provider has been configured
+func (c Config) Enabled() bool {
+	return c.Provider != ProviderNone
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet, appName string) {
+	flags.String("registry-provider", "", `schema registry to publish the sdl to on startup options: "apollo", "http"`)
+	viperx.MustBindFlag(v, "registry.provider", flags.Lookup("registry-provider"))
+
+	flags.String("registry-url", "", "url of the schema registry api")
+	viperx.MustBindFlag(v, "registry.url", flags.Lookup("registry-url"))
+
+	flags.String("registry-routing-url", "", "url the gateway should route requests for this subgraph to")
+	viperx.MustBindFlag(v, "registry.routing-url", flags.Lookup("registry-routing-url"))
+
+	flags.String("registry-graph-ref", "", "apollo graph ref to publish to in the form graph@variant")
+	viperx.MustBindFlag(v, "registry.graph-ref", flags.Lookup("registry-graph-ref"))
+
+	flags.Bool("registry-spiffe", false, "present the SPIFFE workload identity to the registry, when it runs in the mesh")
+	viperx.MustBindFlag(v, "registry.spiffe", flags.Lookup("registry-spiffe"))
+
+	v.MustBindEnv("registry.subgraph-name")
+	v.MustBindEnv("registry.token")
+	v.MustBindEnv("registry.timeout")
+
+	v.SetDefault("registry.subgraph-name", appName)
+	v.SetDefault("registry.timeout", defaultTimeout)
+}
@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// HTTP publishes subgraphs by posting them as json to a registry endpoint
+type HTTP struct {
+	cfg    Config
+	logger *zap.SugaredLogger
+	http   *http.Client
+}
+
+// NewHTTP returns a Publisher for a generic registry endpoint
+func NewHTTP(cfg Config, logger *zap.SugaredLogger) (*HTTP, error) {
+	if cfg.URL == "" {
+		return nil, ErrMissingConfig
+	}
+
+	return &HTTP{
+		cfg:    cfg,
+		logger: logger,
+		http:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+	}, nil
+}
+
+// Publish posts the subgraph to the registry url
+func (h *HTTP) Publish(ctx context.Context, subgraph Subgraph) error {
+	headers := map[string]string{}
+
+	if h.cfg.Token != "" {
+		headers["Authorization"] = "Bearer " + h.cfg.Token
+	}
+
+	if err := postJSON(ctx, h.http, h.cfg.URL, headers, subgraph, nil); err != nil {
+		return err
+	}
+
+	h.logger.Infow("published subgraph", "name", subgraph.Name, "checksum", subgraph.Checksum)
+
+	return nil
+}
@@ -0,0 +1,110 @@
+// Package registry publishes the resolver's subgraph SDL to a schema registry
+// so the supergraph can be composed without manual steps
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnknownProvider is returned when the configured registry provider isn't supported
+	ErrUnknownProvider = errors.New("unknown schema registry provider")
+
+	// ErrMissingConfig is returned when a required registry setting is missing
+	ErrMissingConfig = errors.New("missing schema registry config options")
+)
+
+// Provider is the name of a schema registry
+type Provider string
+
+const (
+	// ProviderNone disables publishing
+	ProviderNone Provider = ""
+
+	// ProviderApollo publishes subgraphs to Apollo GraphOS
+	ProviderApollo Provider = "apollo"
+
+	// ProviderHTTP posts the subgraph to a generic registry endpoint
+	ProviderHTTP Provider = "http"
+)
+
+// Subgraph is the schema published to a registry
+type Subgraph struct {
+	Name       string `json:"name"`
+	RoutingURL string `json:"routing_url"`
+	SDL        string `json:"sdl"`
+	Checksum   string `json:"checksum"`
+}
+
+// Publisher publishes a subgraph to a schema registry
+type Publisher interface {
+	Publish(ctx context.Context, subgraph Subgraph) error
+}
+
+// NewPublisher returns the Publisher for the configured provider
+func NewPublisher(cfg Config, logger *zap.SugaredLogger) (Publisher, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	switch cfg.Provider {
+	case ProviderApollo:
+		p, err := NewApollo(cfg, logger.Named("apollo"))
+		if err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	case ProviderHTTP:
+		p, err := NewHTTP(cfg, logger.Named("http"))
+		if err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, cfg.Provider)
+	}
+}
+
+// postJSON sends body to url and decodes the response into out, if given
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response from schema registry: %s", resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
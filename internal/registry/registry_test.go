@@ -0,0 +1,107 @@
+package registry_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/registry"
+)
+
+var testSubgraph = registry.Subgraph{
+	Name:       "node-resolver",
+	RoutingURL: "http://node-resolver:7904/query",
+	SDL:        "type Query { node(id: ID!): Node }",
+	Checksum:   "sha256:abc",
+}
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var body registry.Subgraph
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, testSubgraph, body)
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	p, err := registry.NewPublisher(registry.Config{
+		Provider: registry.ProviderHTTP,
+		URL:      srv.URL,
+		Token:    "secret",
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	require.NoError(t, p.Publish(context.Background(), testSubgraph))
+}
+
+func TestApollo(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		response string
+		errorMsg string
+	}{
+		{
+			TestName: "published",
+			response: `{"data":{"graph":{"publishSubgraph":{"wasCreated":true,"launchUrl":"https://studio.apollographql.com/launch","errors":[]}}}}`,
+		},
+		{
+			TestName: "composition errors",
+			response: `{"data":{"graph":{"publishSubgraph":{"errors":[{"message":"type Node is invalid"}]}}}}`,
+			errorMsg: "type Node is invalid",
+		},
+		{
+			TestName: "unknown graph",
+			response: `{"data":{"graph":null}}`,
+			errorMsg: "graph testgraph not found",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+
+				var body struct {
+					Variables map[string]interface{} `json:"variables"`
+				}
+
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, "testgraph", body.Variables["graphID"])
+				assert.Equal(t, "prod", body.Variables["variant"])
+				assert.Equal(t, testSubgraph.RoutingURL, body.Variables["url"])
+				assert.Equal(t, testSubgraph.Checksum, body.Variables["revision"])
+				assert.Equal(t, map[string]interface{}{"sdl": testSubgraph.SDL}, body.Variables["schema"])
+
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			p, err := registry.NewPublisher(registry.Config{
+				Provider: registry.ProviderApollo,
+				URL:      srv.URL,
+				GraphRef: "testgraph@prod",
+				Token:    "secret",
+			}, zap.NewNop().Sugar())
+			require.NoError(t, err)
+
+			err = p.Publish(context.Background(), testSubgraph)
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
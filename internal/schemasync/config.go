@@ -0,0 +1,67 @@
+package schemasync
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var (
+	defaultInterval   = 30 * time.Second
+	defaultResync     = 10 * time.Minute
+	defaultTimeout    = 10 * time.Second
+	defaultMaxRetries = 5
+	defaultBackoff    = time.Second
+)
+
+// Config stores the settings for syncing a subgraph schema into a central
+// node-resolver
+type Config struct {
+	SourceURL  string        `mapstructure:"source-url"`
+	SourceFile string        `mapstructure:"source-file"`
+	TargetURL  string        `mapstructure:"target-url"`
+	Name       string        `mapstructure:"name"`
+	Token      string        `mapstructure:"token"`
+	Interval   time.Duration `mapstructure:"interval"`
+	Resync     time.Duration `mapstructure:"resync"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	MaxRetries int           `mapstructure:"max-retries"`
+	Backoff    time.Duration `mapstructure:"backoff"`
+
+	// Transport is used for requests to the subgraph and the central
+	// resolver, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("sync-source-url", "", "graphql endpoint of the subgraph to read the sdl from using _service { sdl }")
+	viperx.MustBindFlag(v, "sync.source-url", flags.Lookup("sync-source-url"))
+
+	flags.String("sync-source-file", "", "path to a schema file to read the sdl from instead of a subgraph")
+	viperx.MustBindFlag(v, "sync.source-file", flags.Lookup("sync-source-file"))
+
+	flags.String("sync-target-url", "", "base url of the central node-resolver")
+	viperx.MustBindFlag(v, "sync.target-url", flags.Lookup("sync-target-url"))
+
+	flags.String("sync-name", "", "name the schema is stored under in the central node-resolver")
+	viperx.MustBindFlag(v, "sync.name", flags.Lookup("sync-name"))
+
+	flags.Duration("sync-interval", defaultInterval, "how often to check the subgraph sdl for changes")
+	viperx.MustBindFlag(v, "sync.interval", flags.Lookup("sync-interval"))
+
+	flags.Duration("sync-resync", defaultResync, "how often to push the sdl even when it hasn't changed, 0 to disable")
+	viperx.MustBindFlag(v, "sync.resync", flags.Lookup("sync-resync"))
+
+	v.MustBindEnv("sync.token")
+	v.MustBindEnv("sync.timeout")
+	v.MustBindEnv("sync.max-retries")
+	v.MustBindEnv("sync.backoff")
+
+	v.SetDefault("sync.timeout", defaultTimeout)
+	v.SetDefault("sync.max-retries", defaultMaxRetries)
+	v.SetDefault("sync.backoff", defaultBackoff)
+}
@@ -0,0 +1,277 @@
+// Package schemasync pushes a subgraph's schema into a central node-resolver
+// so subgraphs can roll out new node types without redeploying the resolver
+package schemasync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const serviceQuery = `{ _service { sdl } }`
+
+var (
+	// ErrMissingConfig is returned when the syncer is missing required config options
+	ErrMissingConfig = errors.New("missing schema sync config options")
+
+	// ErrEmptySDL is returned when the source returns an empty sdl
+	ErrEmptySDL = errors.New("source returned an empty sdl")
+)
+
+// StatusError is returned when a server responds with an unexpected status
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response from %s: %d %s", e.URL, e.StatusCode, e.Body)
+}
+
+// retryable returns true for responses that may succeed when sent again.
+// Rejected schemas won't, so they're left until the sdl changes or the next
+// resync.
+func (e *StatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// Syncer watches a subgraph sdl and pushes it to a central node-resolver's
+// admin schema api whenever it changes
+type Syncer struct {
+	cfg      Config
+	logger   *zap.SugaredLogger
+	http     *http.Client
+	checksum string
+	pushedAt time.Time
+}
+
+// NewSyncer returns a Syncer for the given config
+func NewSyncer(cfg Config, logger *zap.SugaredLogger) (*Syncer, error) {
+	if (cfg.SourceURL == "") == (cfg.SourceFile == "") {
+		return nil, fmt.Errorf("%w: exactly one of source url or source file is required", ErrMissingConfig)
+	}
+
+	if cfg.TargetURL == "" || cfg.Name == "" {
+		return nil, fmt.Errorf("%w: target url and name are required", ErrMissingConfig)
+	}
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+
+	return &Syncer{
+		cfg:    cfg,
+		logger: logger,
+		http:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+	}, nil
+}
+
+// Run syncs the sdl every interval until ctx is done
+func (s *Syncer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Errorw("failed to sync schema", "name", s.cfg.Name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync reads the sdl and pushes it when it has changed since the last
+// successful push or the resync period has passed
+func (s *Syncer) Sync(ctx context.Context) error {
+	sdl, err := s.readSDL(ctx)
+	if err != nil {
+		return err
+	}
+
+	checksum := checksum(sdl)
+	resync := s.cfg.Resync > 0 && time.Since(s.pushedAt) >= s.cfg.Resync
+
+	if checksum == s.checksum && !resync {
+		return nil
+	}
+
+	if err := s.pushWithRetries(ctx, sdl); err != nil {
+		return err
+	}
+
+	s.logger.Infow("pushed schema", "name", s.cfg.Name, "checksum", checksum, "changed", checksum != s.checksum)
+
+	s.checksum = checksum
+	s.pushedAt = time.Now()
+
+	return nil
+}
+
+func (s *Syncer) readSDL(ctx context.Context) (string, error) {
+	var (
+		sdl string
+		err error
+	)
+
+	if s.cfg.SourceFile != "" {
+		var b []byte
+
+		b, err = os.ReadFile(s.cfg.SourceFile)
+		sdl = string(b)
+	} else {
+		sdl, err = s.fetchServiceSDL(ctx)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(sdl) == "" {
+		return "", ErrEmptySDL
+	}
+
+	return sdl, nil
+}
+
+// fetchServiceSDL reads the sdl using the federation _service query
+func (s *Syncer) fetchServiceSDL(ctx context.Context) (string, error) {
+	body, err := json.Marshal(map[string]string{"query": serviceQuery})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.SourceURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	respBody, err := s.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Data struct {
+			Service struct {
+				SDL string `json:"sdl"`
+			} `json:"_service"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Errors) > 0 {
+		return "", fmt.Errorf("querying subgraph sdl: %s", resp.Errors[0].Message) //nolint:goerr113
+	}
+
+	return resp.Data.Service.SDL, nil
+}
+
+// pushWithRetries pushes the sdl, retrying transient failures with
+// exponential backoff
+func (s *Syncer) pushWithRetries(ctx context.Context, sdl string) error {
+	backoff := s.cfg.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err := s.push(ctx, sdl)
+		if err == nil {
+			return nil
+		}
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return err
+		}
+
+		if attempt >= s.cfg.MaxRetries {
+			return fmt.Errorf("pushing schema after %d attempts: %w", attempt+1, err)
+		}
+
+		s.logger.Warnw("failed to push schema, retrying", "name", s.cfg.Name, "backoff", backoff, "error", err)
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+func (s *Syncer) push(ctx context.Context, sdl string) error {
+	target := strings.TrimSuffix(s.cfg.TargetURL, "/") + "/admin/schemas/" + url.PathEscape(s.cfg.Name)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, strings.NewReader(sdl))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/graphql")
+
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	}
+
+	_, err = s.do(req)
+
+	return err
+}
+
+func (s *Syncer) do(req *http.Request) ([]byte, error) {
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, &StatusError{URL: req.URL.String(), StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+
+	return body, nil
+}
+
+func checksum(sdl string) string {
+	sum := sha256.Sum256([]byte(sdl))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
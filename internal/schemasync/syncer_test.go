@@ -0,0 +1,125 @@
+package schemasync_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/schemasync"
+)
+
+type target struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	pushes   []string
+	auth     string
+	path     string
+}
+
+func (t *target) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.failures > 0 {
+		t.failures--
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+
+		return
+	}
+
+	if t.status != 0 {
+		w.WriteHeader(t.status)
+
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+
+	t.pushes = append(t.pushes, string(body))
+	t.auth = r.Header.Get("Authorization")
+	t.path = r.URL.Path
+}
+
+func TestSync(t *testing.T) {
+	sdl := "type Widget { id: ID! }"
+
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"_service":{"sdl":` + quote(sdl) + `}}}`))
+	}))
+	defer source.Close()
+
+	tgt := &target{failures: 2}
+
+	central := httptest.NewServer(tgt)
+	defer central.Close()
+
+	s, err := schemasync.NewSyncer(schemasync.Config{
+		SourceURL:  source.URL,
+		TargetURL:  central.URL,
+		Name:       "widgets",
+		Token:      "secret",
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// transient failures are retried
+	require.NoError(t, s.Sync(ctx))
+	assert.Equal(t, []string{sdl}, tgt.pushes)
+	assert.Equal(t, "Bearer secret", tgt.auth)
+	assert.Equal(t, "/admin/schemas/widgets", tgt.path)
+
+	// unchanged schemas aren't pushed again
+	require.NoError(t, s.Sync(ctx))
+	assert.Len(t, tgt.pushes, 1)
+
+	sdl = "type Widget { id: ID! name: String }"
+
+	require.NoError(t, s.Sync(ctx))
+	assert.Equal(t, []string{"type Widget { id: ID! }", sdl}, tgt.pushes)
+
+	// rejected schemas aren't retried
+	sdl = "type Widget {"
+	tgt.status = http.StatusUnprocessableEntity
+
+	err = s.Sync(ctx)
+
+	var statusErr *schemasync.StatusError
+
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, statusErr.StatusCode)
+
+	// too many failures gives up
+	tgt.status = 0
+	tgt.failures = 3
+
+	assert.Error(t, s.Sync(ctx))
+}
+
+func TestNewSyncerConfig(t *testing.T) {
+	_, err := schemasync.NewSyncer(schemasync.Config{TargetURL: "http://localhost", Name: "widgets"}, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, schemasync.ErrMissingConfig)
+
+	_, err = schemasync.NewSyncer(schemasync.Config{SourceFile: "schema.graphql", Name: "widgets"}, zap.NewNop().Sugar())
+	assert.ErrorIs(t, err, schemasync.ErrMissingConfig)
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+
+	return string(b)
+}
@@ -0,0 +1,47 @@
+package schemaurl
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 30 * time.Second
+
+// Config stores the settings used to fetch the schema over http
+type Config struct {
+	URL string `mapstructure:"url"`
+	// Token is sent as a bearer token when set
+	Token   string        `mapstructure:"token"`
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// SPIFFE presents the workload's SVID to the schema url, for hosts
+	// running in the mesh
+	SPIFFE bool `mapstructure:"spiffe"`
+
+	// Transport is used for requests for the schema, defaulting to
+	// http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// Enabled returns true when a schema url has been configured
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("schema-url", "", "http(s) url to fetch the graphql schema from at startup, instead of a schema file")
+	viperx.MustBindFlag(v, "schemaurl.url", flags.Lookup("schema-url"))
+
+	flags.Duration("schema-url-timeout", defaultTimeout, "timeout for fetching the schema from the schema url")
+	viperx.MustBindFlag(v, "schemaurl.timeout", flags.Lookup("schema-url-timeout"))
+
+	flags.Bool("schema-url-spiffe", false, "present the SPIFFE workload identity to the schema url, when it's served in the mesh")
+	viperx.MustBindFlag(v, "schemaurl.spiffe", flags.Lookup("schema-url-spiffe"))
+
+	v.MustBindEnv("schemaurl.token")
+}
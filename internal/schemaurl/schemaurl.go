@@ -0,0 +1,69 @@
+// Package schemaurl fetches the schema over http, so it can be pulled from
+// wherever the deployment pipeline publishes it
+package schemaurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxSchemaSize is the largest schema fetched, in bytes
+const maxSchemaSize = 32 << 20
+
+var (
+	// ErrUnexpectedStatus is returned when the server doesn't respond with
+	// the schema
+	ErrUnexpectedStatus = errors.New("unexpected response fetching schema")
+	// ErrSchemaTooLarge is returned for schemas larger than 32MiB
+	ErrSchemaTooLarge = errors.New("schema too large")
+	// ErrEmptySchema is returned when the server responds with an empty body
+	ErrEmptySchema = errors.New("schema url returned an empty schema")
+)
+
+// Fetch returns the schema served at the configured url
+func Fetch(ctx context.Context, cfg Config) (string, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+
+	client := &http.Client{Transport: cfg.Transport}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSchemaSize+1))
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(body) > maxSchemaSize:
+		return "", ErrSchemaTooLarge
+	case len(body) == 0:
+		return "", ErrEmptySchema
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,42 @@
+package schemaurl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `type Query { node(id: ID!): Node! }`
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/empty":
+		case r.URL.Path == "/large":
+			_, _ = w.Write([]byte(strings.Repeat("#", maxSchemaSize+1)))
+		default:
+			_, _ = w.Write([]byte(testSchema))
+		}
+	}))
+	defer srv.Close()
+
+	schema, err := Fetch(context.Background(), Config{URL: srv.URL + "/schema.graphql", Token: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, testSchema, schema)
+
+	_, err = Fetch(context.Background(), Config{URL: srv.URL + "/schema.graphql"})
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+
+	_, err = Fetch(context.Background(), Config{URL: srv.URL + "/empty", Token: "secret"})
+	assert.ErrorIs(t, err, ErrEmptySchema)
+
+	_, err = Fetch(context.Background(), Config{URL: srv.URL + "/large", Token: "secret"})
+	assert.ErrorIs(t, err, ErrSchemaTooLarge)
+}
@@ -0,0 +1,27 @@
+package schemawatch
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+// Config stores the settings for reloading the schema file when it changes
+type Config struct {
+	// Interval is how often the schema file is checked for changes, 0
+	// disables reloading
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// Enabled returns true when the schema file is watched
+func (c Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Duration("schema-watch-interval", 0, "how often the --schema file is checked for changes and reloaded without a restart, 0 disables reloading")
+	viperx.MustBindFlag(v, "schemawatch.interval", flags.Lookup("schema-watch-interval"))
+}
@@ -0,0 +1,218 @@
+// Package schemawatch reloads the schema when its files change, so a new
+// schema is served without restarting node-resolver
+package schemawatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrReloading is reported by the readiness check while a changed
+	// schema is being loaded
+	ErrReloading = errors.New("schema is being reloaded")
+	// ErrReloadFailed is reported by the readiness check after the changed
+	// schema files were rejected, until they hold a valid schema again
+	ErrReloadFailed = errors.New("schema reload failed")
+)
+
+// Reloader serves a new schema, rejecting invalid schemas without changing
+// what's served
+type Reloader interface {
+	Reload(rawSchema string) error
+}
+
+// ReloaderFunc is a function serving a new schema
+type ReloaderFunc func(rawSchema string) error
+
+// Reload calls f
+func (f ReloaderFunc) Reload(rawSchema string) error {
+	return f(rawSchema)
+}
+
+// Loader reads the schema from the watched files
+type Loader func() (string, error)
+
+// Watcher checks schema files for changes every interval and reloads the
+// schema when they changed. Files are polled rather than watched for events,
+// so they're reloaded when they're replaced through a symlink, as
+// Kubernetes updates mounted ConfigMaps.
+type Watcher struct {
+	cfg      Config
+	paths    []string
+	load     Loader
+	reloader Reloader
+	logger   *zap.SugaredLogger
+
+	// state describes the files when they were last read, and schema is
+	// the schema they contained
+	state  string
+	schema string
+
+	// reloading is set while a changed schema is loaded, and failure is
+	// the error of the last reload when it failed
+	reloading atomic.Bool
+	mu        sync.Mutex
+	failure   error
+}
+
+// New returns a Watcher reloading the schema loaded by load with reloader
+// when the files or directories in paths change. schema is the schema being
+// served, loaded from paths at startup.
+func New(cfg Config, paths []string, load Loader, schema string, reloader Reloader, logger *zap.SugaredLogger) *Watcher {
+	w := &Watcher{
+		cfg:      cfg,
+		paths:    paths,
+		load:     load,
+		reloader: reloader,
+		logger:   logger,
+		schema:   schema,
+	}
+
+	w.state, _ = w.stat()
+
+	return w
+}
+
+// Start checks the files every interval until ctx is done
+func (w *Watcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+}
+
+// check reloads the schema when the files changed. Files that can't be read
+// and invalid schemas are logged and the current schema keeps being served;
+// they're retried once the files change again.
+func (w *Watcher) check() {
+	state, err := w.stat()
+	if err != nil {
+		w.logger.Warnw("failed to check schema files", "paths", w.paths, "error", err)
+
+		return
+	}
+
+	if state == w.state {
+		return
+	}
+
+	w.state = state
+
+	w.reloading.Store(true)
+	defer w.reloading.Store(false)
+
+	schema, err := w.load()
+	if err != nil {
+		w.setFailure(err)
+		w.logger.Warnw("failed to read schema files", "paths", w.paths, "error", err)
+
+		return
+	}
+
+	// touching a file doesn't change the schema, and reverting it to the
+	// schema being served recovers from a failed reload
+	if schema == w.schema {
+		w.setFailure(nil)
+
+		return
+	}
+
+	err = w.reloader.Reload(schema)
+	w.setFailure(err)
+
+	if err != nil {
+		w.logger.Errorw("rejected changed schema files, serving the previous schema", "paths", w.paths, "error", err)
+
+		return
+	}
+
+	w.schema = schema
+
+	w.logger.Infow("schema files reloaded", "paths", w.paths)
+}
+
+// ReadinessCheck fails while a changed schema is being loaded and after the
+// changed files were rejected, until they hold a valid schema again. The
+// previous schema is served meanwhile, so requests already sent to the
+// replica are still answered.
+func (w *Watcher) ReadinessCheck(_ context.Context) error {
+	if w.reloading.Load() {
+		return ErrReloading
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.failure != nil {
+		return fmt.Errorf("%w: %s", ErrReloadFailed, w.failure.Error())
+	}
+
+	return nil
+}
+
+func (w *Watcher) setFailure(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.failure = err
+}
+
+// stat returns the names, sizes and modification times of the files in
+// paths, including the files in directories, which change whenever a file
+// is changed, added or removed
+func (w *Watcher) stat() (string, error) {
+	var sb strings.Builder
+
+	for _, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		writeInfo(&sb, path, info)
+
+		if !info.IsDir() {
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", err
+		}
+
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return "", err
+			}
+
+			writeInfo(&sb, filepath.Join(path, entry.Name()), info)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func writeInfo(sb *strings.Builder, path string, info fs.FileInfo) {
+	fmt.Fprintf(sb, "%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+}
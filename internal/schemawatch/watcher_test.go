@@ -0,0 +1,165 @@
+package schemawatch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var errInvalidSchema = errors.New("invalid schema")
+
+type fakeReloader struct {
+	reloaded []string
+}
+
+func (r *fakeReloader) Reload(rawSchema string) error {
+	if rawSchema == "invalid" {
+		return errInvalidSchema
+	}
+
+	r.reloaded = append(r.reloaded, rawSchema)
+
+	return nil
+}
+
+// write writes schema to file with a modification time after the previous
+// write, so changes are seen regardless of the filesystem's time resolution
+func write(t *testing.T, file, schema string, at time.Time) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(file, []byte(schema), 0o600))
+	require.NoError(t, os.Chtimes(file, at, at))
+}
+
+// readFile returns a loader reading file
+func readFile(file string) Loader {
+	return func() (string, error) {
+		b, err := os.ReadFile(file)
+
+		return string(b), err
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "schema.graphql")
+	now := time.Now()
+
+	write(t, file, "v1", now)
+
+	reloader := &fakeReloader{}
+	w := New(Config{Interval: time.Second}, []string{file}, readFile(file), "v1", reloader, zap.NewNop().Sugar())
+
+	w.check()
+	assert.Empty(t, reloader.reloaded, "unchanged files aren't reloaded")
+
+	write(t, file, "v2", now.Add(time.Second))
+	w.check()
+	assert.Equal(t, []string{"v2"}, reloader.reloaded)
+
+	// touching the file doesn't reload it
+	write(t, file, "v2", now.Add(2*time.Second))
+	w.check()
+	assert.Equal(t, []string{"v2"}, reloader.reloaded)
+
+	// invalid schemas are rejected, and retried once they change
+	write(t, file, "invalid", now.Add(3*time.Second))
+	w.check()
+	assert.Equal(t, []string{"v2"}, reloader.reloaded)
+
+	write(t, file, "v3", now.Add(4*time.Second))
+	w.check()
+	assert.Equal(t, []string{"v2", "v3"}, reloader.reloaded)
+
+	// a missing file keeps the schema being served
+	require.NoError(t, os.Remove(file))
+	w.check()
+	assert.Equal(t, []string{"v2", "v3"}, reloader.reloaded)
+}
+
+func TestWatcherDirectory(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	write(t, filepath.Join(dir, "a.graphql"), "a", now)
+
+	load := func() (string, error) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+
+		names := ""
+		for _, entry := range entries {
+			names += entry.Name() + " "
+		}
+
+		return names, nil
+	}
+
+	schema, err := load()
+	require.NoError(t, err)
+
+	reloader := &fakeReloader{}
+	w := New(Config{Interval: time.Second}, []string{dir}, load, schema, reloader, zap.NewNop().Sugar())
+
+	w.check()
+	assert.Empty(t, reloader.reloaded)
+
+	// files added to the directory are seen
+	write(t, filepath.Join(dir, "b.graphql"), "b", now)
+	w.check()
+	assert.Equal(t, []string{"a.graphql b.graphql "}, reloader.reloaded)
+}
+
+func TestWatcherReadiness(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "schema.graphql")
+	now := time.Now()
+
+	write(t, file, "v1", now)
+
+	reloaded := make(chan struct{})
+	release := make(chan struct{})
+
+	reloader := ReloaderFunc(func(rawSchema string) error {
+		close(reloaded)
+		<-release
+
+		if rawSchema == "invalid" {
+			return errInvalidSchema
+		}
+
+		return nil
+	})
+
+	w := New(Config{Interval: time.Second}, []string{file}, readFile(file), "v1", reloader, zap.NewNop().Sugar())
+	assert.NoError(t, w.ReadinessCheck(context.Background()))
+
+	write(t, file, "invalid", now.Add(time.Second))
+
+	done := make(chan struct{})
+
+	go func() {
+		w.check()
+		close(done)
+	}()
+
+	<-reloaded
+	assert.ErrorIs(t, w.ReadinessCheck(context.Background()), ErrReloading)
+
+	close(release)
+	<-done
+
+	assert.ErrorIs(t, w.ReadinessCheck(context.Background()), ErrReloadFailed)
+
+	// reverting to the schema being served makes the replica ready again
+	write(t, file, "v1", now.Add(2*time.Second))
+	w.check()
+	assert.NoError(t, w.ReadinessCheck(context.Background()))
+}
@@ -0,0 +1,46 @@
+package shed
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var (
+	defaultInterval           = time.Second
+	defaultMaxBodySize        = int64(64 << 10)
+	defaultMaxRepresentations = 500
+)
+
+// Config stores the load shedding settings
+type Config struct {
+	// MemoryThreshold is the memory in bytes used by the go runtime above
+	// which large requests are shed, 0 disables shedding
+	MemoryThreshold    uint64        `mapstructure:"memory-threshold"`
+	Interval           time.Duration `mapstructure:"interval"`
+	MaxBodySize        int64         `mapstructure:"max-body-size"`
+	MaxRepresentations int           `mapstructure:"max-representations"`
+}
+
+// Enabled returns true when a memory threshold is configured
+func (c Config) Enabled() bool {
+	return c.MemoryThreshold != 0
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Uint64("shed-memory-threshold", 0, "memory in bytes used by the runtime above which large requests are rejected, 0 disables shedding")
+	viperx.MustBindFlag(v, "shed.memory-threshold", flags.Lookup("shed-memory-threshold"))
+
+	flags.Int64("shed-max-body-size", defaultMaxBodySize, "largest request body in bytes served under memory pressure")
+	viperx.MustBindFlag(v, "shed.max-body-size", flags.Lookup("shed-max-body-size"))
+
+	flags.Int("shed-max-representations", defaultMaxRepresentations, "largest _entities batch served under memory pressure")
+	viperx.MustBindFlag(v, "shed.max-representations", flags.Lookup("shed-max-representations"))
+
+	v.MustBindEnv("shed.interval")
+
+	v.SetDefault("shed.interval", defaultInterval)
+}
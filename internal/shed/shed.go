@@ -0,0 +1,152 @@
+// Package shed rejects the largest requests while the process is under
+// memory pressure, so a burst of huge batches fails fast instead of getting
+// the pod OOM killed
+package shed
+
+import (
+	"context"
+	"net/http"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/errcode"
+	nrmetrics "go.infratographer.com/node-resolver/internal/metrics"
+)
+
+// Reasons requests are shed, used as the metrics label
+const (
+	ReasonBodySize        = "body_size"
+	ReasonRepresentations = "representations"
+)
+
+// retryAfter is the Retry-After header sent with shed requests, in seconds
+const retryAfter = "1"
+
+// ErrOverloaded is returned for requests shed under memory pressure
+var ErrOverloaded = echo.NewHTTPError(http.StatusServiceUnavailable, echo.Map{
+	"code":    errcode.Overloaded,
+	"message": "server is under memory pressure",
+})
+
+// runtime/metrics samples used to compute the memory used by the runtime:
+// all memory mapped by the runtime, less heap memory returned to the OS
+const (
+	sampleTotal    = "/memory/classes/total:bytes"
+	sampleReleased = "/memory/classes/heap/released:bytes"
+)
+
+// Shedder tracks the memory used by the go runtime and sheds large requests
+// while it's above the configured threshold
+type Shedder struct {
+	cfg      Config
+	logger   *zap.SugaredLogger
+	metrics  nrmetrics.Sink
+	pressure atomic.Bool
+
+	// readMemory returns the memory used by the runtime
+	readMemory func() uint64
+}
+
+// New returns a Shedder for cfg. Shed requests are counted with sink, which
+// may be nil.
+func New(cfg Config, sink nrmetrics.Sink, logger *zap.SugaredLogger) *Shedder {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+
+	return &Shedder{
+		cfg:        cfg,
+		logger:     logger,
+		metrics:    sink,
+		readMemory: readRuntimeMemory,
+	}
+}
+
+// Start samples memory usage every interval until ctx is done
+func (s *Shedder) Start(ctx context.Context) {
+	s.sample()
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+}
+
+// UnderPressure reports whether memory usage was above the threshold when
+// it was last sampled
+func (s *Shedder) UnderPressure() bool {
+	return s.pressure.Load()
+}
+
+// sample reads the memory used by the runtime and updates the pressure
+// state, logging when it changes
+func (s *Shedder) sample() {
+	used := s.readMemory()
+	pressure := used > s.cfg.MemoryThreshold
+
+	if s.pressure.Swap(pressure) == pressure {
+		return
+	}
+
+	if pressure {
+		s.logger.Warnw("memory pressure, shedding large requests", "used_bytes", used, "threshold_bytes", s.cfg.MemoryThreshold)
+	} else {
+		s.logger.Infow("memory pressure relieved", "used_bytes", used, "threshold_bytes", s.cfg.MemoryThreshold)
+	}
+}
+
+// Middleware sheds requests with a body larger than the max body size while
+// under memory pressure. Requests of unknown length are let through.
+func (s *Shedder) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if s.UnderPressure() && c.Request().ContentLength > s.cfg.MaxBodySize {
+				return s.Reject(c, ReasonBodySize)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// AllowRepresentations reports whether an _entities batch of n
+// representations may be served
+func (s *Shedder) AllowRepresentations(n int) bool {
+	return n <= s.cfg.MaxRepresentations || !s.UnderPressure()
+}
+
+// Reject records a request shed for reason and returns ErrOverloaded, asking
+// the client to retry shortly, ideally against another replica
+func (s *Shedder) Reject(c echo.Context, reason string) error {
+	if s.metrics != nil {
+		s.metrics.Shed(reason)
+	}
+
+	c.Response().Header().Set(echo.HeaderRetryAfter, retryAfter)
+
+	return ErrOverloaded
+}
+
+// readRuntimeMemory returns the memory mapped by the runtime that hasn't
+// been returned to the OS, which approximates the process' resident memory
+// without stopping the world like runtime.ReadMemStats
+func readRuntimeMemory() uint64 {
+	samples := []metrics.Sample{{Name: sampleTotal}, {Name: sampleReleased}}
+
+	metrics.Read(samples)
+
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
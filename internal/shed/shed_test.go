@@ -0,0 +1,71 @@
+package shed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/metrics"
+)
+
+type shedCounter struct {
+	metrics.Nop
+
+	reasons map[string]int
+}
+
+func (c *shedCounter) Shed(reason string) { c.reasons[reason]++ }
+
+func TestShedder(t *testing.T) {
+	var used uint64
+
+	counter := &shedCounter{reasons: map[string]int{}}
+
+	s := New(Config{MemoryThreshold: 100, MaxBodySize: 10, MaxRepresentations: 5}, counter, zap.NewNop().Sugar())
+	s.readMemory = func() uint64 { return used }
+
+	e := echo.New()
+	e.Use(s.Middleware())
+	e.POST("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+		return rec
+	}
+
+	s.sample()
+	assert.False(t, s.UnderPressure())
+	assert.Equal(t, http.StatusOK, serve(strings.Repeat("a", 20)).Code)
+	assert.True(t, s.AllowRepresentations(10))
+
+	used = 101
+	s.sample()
+	assert.True(t, s.UnderPressure())
+
+	rec := serve(strings.Repeat("a", 20))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get(echo.HeaderRetryAfter))
+	assert.JSONEq(t, `{"code":"overloaded","message":"server is under memory pressure"}`, rec.Body.String())
+	assert.Equal(t, http.StatusOK, serve("small").Code)
+
+	assert.True(t, s.AllowRepresentations(5))
+	assert.False(t, s.AllowRepresentations(6))
+
+	used = 100
+	s.sample()
+	assert.False(t, s.UnderPressure())
+	assert.Equal(t, http.StatusOK, serve(strings.Repeat("a", 20)).Code)
+
+	assert.Equal(t, map[string]int{ReasonBodySize: 1}, counter.reasons)
+}
+
+func TestReadRuntimeMemory(t *testing.T) {
+	assert.NotZero(t, readRuntimeMemory())
+}
@@ -0,0 +1,51 @@
+package spiffex
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+// Config stores the settings for obtaining workload identity from a SPIFFE
+// Workload API
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// SocketPath is the Workload API address, e.g. unix:///run/spire/sockets/agent.sock.
+	// When empty the SPIFFE_ENDPOINT_SOCKET environment variable is used.
+	SocketPath string `mapstructure:"socket-path"`
+
+	// MTLS requires clients of the server to present an authorized SVID.
+	MTLS bool `mapstructure:"mtls"`
+
+	// TrustDomain limits authorized peers to members of the trust domain.
+	// When empty peers must be members of the workload's own trust domain.
+	TrustDomain string `mapstructure:"trust-domain"`
+
+	// AuthorizedIDs limits authorized clients of the server to the given SPIFFE IDs.
+	AuthorizedIDs []string `mapstructure:"authorized-ids"`
+
+	// BackendIDs limits authorized backends to the given SPIFFE IDs.
+	BackendIDs []string `mapstructure:"backend-ids"`
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Bool("spiffe", false, "obtain tls certificates from a SPIFFE Workload API")
+	viperx.MustBindFlag(v, "spiffe.enabled", flags.Lookup("spiffe"))
+
+	flags.String("spiffe-socket-path", "", "SPIFFE Workload API address (default $SPIFFE_ENDPOINT_SOCKET)")
+	viperx.MustBindFlag(v, "spiffe.socket-path", flags.Lookup("spiffe-socket-path"))
+
+	flags.Bool("spiffe-mtls", true, "require clients to present an authorized SVID")
+	viperx.MustBindFlag(v, "spiffe.mtls", flags.Lookup("spiffe-mtls"))
+
+	flags.String("spiffe-trust-domain", "", "trust domain peers must be members of (default is the trust domain of the workload)")
+	viperx.MustBindFlag(v, "spiffe.trust-domain", flags.Lookup("spiffe-trust-domain"))
+
+	flags.StringSlice("spiffe-authorized-ids", nil, "SPIFFE IDs allowed to connect to the server")
+	viperx.MustBindFlag(v, "spiffe.authorized-ids", flags.Lookup("spiffe-authorized-ids"))
+
+	flags.StringSlice("spiffe-backend-ids", nil, "SPIFFE IDs backends are allowed to present")
+	viperx.MustBindFlag(v, "spiffe.backend-ids", flags.Lookup("spiffe-backend-ids"))
+}
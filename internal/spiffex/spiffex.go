@@ -0,0 +1,160 @@
+// Package spiffex provides server and client tls configuration using
+// certificates obtained and rotated through a SPIFFE Workload API
+package spiffex
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"go.uber.org/zap"
+)
+
+// x509Source provides the SVID of the workload and the bundles its peers'
+// SVIDs are verified with, as a workloadapi.X509Source does
+type x509Source interface {
+	x509svid.Source
+	x509bundle.Source
+}
+
+// Source provides tls configurations backed by the X509-SVIDs of the workload.
+// Certificates are rotated by the underlying source as the Workload API pushes
+// updates, so the configs it returns never need to be rebuilt.
+type Source struct {
+	cfg    Config
+	logger *zap.SugaredLogger
+	x509   x509Source
+	closer io.Closer
+
+	serverAuthorizer tlsconfig.Authorizer
+	peerAuthorizer   tlsconfig.Authorizer
+}
+
+// NewSource connects to the Workload API and waits for the initial SVID
+func NewSource(ctx context.Context, cfg Config, logger *zap.SugaredLogger) (*Source, error) {
+	opts := []workloadapi.X509SourceOption{}
+	if cfg.SocketPath != "" {
+		opts = append(opts, workloadapi.WithClientOptions(workloadapi.WithAddr(cfg.SocketPath)))
+	}
+
+	x509, err := workloadapi.NewX509Source(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSource(cfg, logger, x509, x509)
+	if err != nil {
+		_ = x509.Close()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// newSource returns a Source backed by x509, closing closer when it's closed
+func newSource(cfg Config, logger *zap.SugaredLogger, x509 x509Source, closer io.Closer) (*Source, error) {
+	svid, err := x509.GetX509SVID()
+	if err != nil {
+		return nil, err
+	}
+
+	peer, server, err := authorizers(cfg, svid.ID.TrustDomain())
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infow("obtained workload identity", "spiffe_id", svid.ID.String())
+
+	return &Source{
+		cfg:              cfg,
+		logger:           logger,
+		x509:             x509,
+		closer:           closer,
+		serverAuthorizer: server,
+		peerAuthorizer:   peer,
+	}, nil
+}
+
+// authorizers returns the authorizers of backends and of clients of the
+// server. Peers must be members of the configured trust domain, or of the
+// workload's own trust domain when none is configured, and may be further
+// limited to lists of SPIFFE IDs.
+func authorizers(cfg Config, own spiffeid.TrustDomain) (peer tlsconfig.Authorizer, server tlsconfig.Authorizer, err error) {
+	td := own
+
+	if cfg.TrustDomain != "" {
+		td, err = spiffeid.TrustDomainFromString(cfg.TrustDomain)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	peer, err = authorizer(td, cfg.BackendIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	server, err = authorizer(td, cfg.AuthorizedIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return peer, server, nil
+}
+
+// authorizer authorizes the given SPIFFE IDs, or any member of td when
+// there are none
+func authorizer(td spiffeid.TrustDomain, ids []string) (tlsconfig.Authorizer, error) {
+	if len(ids) == 0 {
+		return tlsconfig.AuthorizeMemberOf(td), nil
+	}
+
+	parsed := make([]spiffeid.ID, len(ids))
+
+	for i, s := range ids {
+		id, err := spiffeid.FromString(s)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed[i] = id
+	}
+
+	return tlsconfig.AuthorizeOneOf(parsed...), nil
+}
+
+// ServerTLSConfig returns the tls config for the http server. When mTLS is
+// enabled clients must present an SVID accepted by the configured authorizer.
+func (s *Source) ServerTLSConfig() *tls.Config {
+	if !s.cfg.MTLS {
+		return tlsconfig.TLSServerConfig(s.x509)
+	}
+
+	return tlsconfig.MTLSServerConfig(s.x509, s.x509, s.serverAuthorizer)
+}
+
+// ClientTLSConfig returns the tls config for callouts to backend services
+// which are authenticated with their own SVIDs
+func (s *Source) ClientTLSConfig() *tls.Config {
+	return tlsconfig.MTLSClientConfig(s.x509, s.x509, s.peerAuthorizer)
+}
+
+// Transport returns an http transport presenting the workload's SVID to backends
+func (s *Source) Transport() http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = s.ClientTLSConfig()
+
+	return t
+}
+
+// Close stops watching the Workload API for updates
+func (s *Source) Close() error {
+	return s.closer.Close()
+}
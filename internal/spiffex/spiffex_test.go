@@ -0,0 +1,236 @@
+package spiffex
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testCA issues SVIDs of a trust domain
+type testCA struct {
+	td   spiffeid.TrustDomain
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T, td string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{td: spiffeid.RequireTrustDomainFromString(td), cert: cert, key: key}
+}
+
+func (ca *testCA) svid(t *testing.T, path string) *x509svid.SVID {
+	id := spiffeid.RequireFromPath(ca.td, path)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	uri, err := url.Parse(id.String())
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{uri},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &x509svid.SVID{ID: id, Certificates: []*x509.Certificate{cert}, PrivateKey: key}
+}
+
+// memorySource is an in-memory X509Source trusting the bundles of every CA
+type memorySource struct {
+	*x509svid.SVID
+	*x509bundle.Set
+}
+
+func (memorySource) Close() error { return nil }
+
+func newMemorySource(svid *x509svid.SVID, cas ...*testCA) memorySource {
+	set := x509bundle.NewSet()
+	for _, ca := range cas {
+		set.Add(x509bundle.FromX509Authorities(ca.td, []*x509.Certificate{ca.cert}))
+	}
+
+	return memorySource{SVID: svid, Set: set}
+}
+
+func newTestSource(t *testing.T, cfg Config, src memorySource) *Source {
+	s, err := newSource(cfg, zap.NewNop().Sugar(), src, src)
+	require.NoError(t, err)
+
+	return s
+}
+
+// handshake connects a client with clientConfig to a server with
+// serverConfig, returning the errors of both ends
+func handshake(t *testing.T, clientConfig, serverConfig *tls.Config) (clientErr, serverErr error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	done := make(chan error, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+
+		server := tls.Server(conn, serverConfig)
+		done <- server.Handshake()
+
+		_ = server.Close()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+
+	client := tls.Client(conn, clientConfig)
+	clientErr = client.Handshake()
+
+	// with TLS 1.3 the client finishes before the server verifies it, so
+	// a rejected client only finds out on its next read
+	if clientErr == nil {
+		_ = client.SetReadDeadline(time.Now().Add(time.Second))
+		_, _ = client.Read(make([]byte, 1))
+	}
+
+	_ = client.Close()
+
+	return clientErr, <-done
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	ca := newTestCA(t, "example.org")
+	other := newTestCA(t, "other.org")
+
+	server := ca.svid(t, "/node-resolver")
+	gateway := ca.svid(t, "/gateway")
+	batch := ca.svid(t, "/batch")
+	outsider := other.svid(t, "/gateway")
+
+	testCases := []struct {
+		name     string
+		cfg      Config
+		client   *x509svid.SVID
+		rejected bool
+	}{
+		{name: "own trust domain by default", cfg: Config{MTLS: true}, client: gateway},
+		{name: "other trust domain rejected by default", cfg: Config{MTLS: true}, client: outsider, rejected: true},
+		{name: "configured trust domain", cfg: Config{MTLS: true, TrustDomain: "other.org"}, client: outsider},
+		{name: "configured trust domain rejects own", cfg: Config{MTLS: true, TrustDomain: "other.org"}, client: gateway, rejected: true},
+		{name: "authorized id", cfg: Config{MTLS: true, AuthorizedIDs: []string{"spiffe://example.org/gateway"}}, client: gateway},
+		{name: "unauthorized id", cfg: Config{MTLS: true, AuthorizedIDs: []string{"spiffe://example.org/gateway"}}, client: batch, rejected: true},
+		{name: "missing client certificate", cfg: Config{MTLS: true}, rejected: true},
+		{name: "server only tls", cfg: Config{}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSource(t, tt.cfg, newMemorySource(server, ca, other))
+
+			// clients without an SVID only verify the server
+			clientConfig := &tls.Config{InsecureSkipVerify: true} //nolint:gosec // the server isn't under test
+			if tt.client != nil {
+				client := newTestSource(t, Config{TrustDomain: "example.org"}, newMemorySource(tt.client, ca, other))
+				clientConfig = client.ClientTLSConfig()
+			}
+
+			_, serverErr := handshake(t, clientConfig, s.ServerTLSConfig())
+			if tt.rejected {
+				assert.Error(t, serverErr)
+			} else {
+				assert.NoError(t, serverErr)
+			}
+		})
+	}
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	ca := newTestCA(t, "example.org")
+	other := newTestCA(t, "other.org")
+
+	client := ca.svid(t, "/node-resolver")
+
+	testCases := []struct {
+		name     string
+		cfg      Config
+		backend  *x509svid.SVID
+		rejected bool
+	}{
+		{name: "own trust domain by default", backend: ca.svid(t, "/openfga")},
+		{name: "other trust domain rejected by default", backend: other.svid(t, "/openfga"), rejected: true},
+		{name: "backend id", cfg: Config{BackendIDs: []string{"spiffe://example.org/openfga"}}, backend: ca.svid(t, "/openfga")},
+		{name: "unauthorized backend id", cfg: Config{BackendIDs: []string{"spiffe://example.org/openfga"}}, backend: ca.svid(t, "/gateway"), rejected: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSource(t, tt.cfg, newMemorySource(client, ca, other))
+			backend := newTestSource(t, Config{}, newMemorySource(tt.backend, ca, other))
+
+			clientErr, _ := handshake(t, s.ClientTLSConfig(), backend.ServerTLSConfig())
+			if tt.rejected {
+				assert.Error(t, clientErr)
+			} else {
+				assert.NoError(t, clientErr)
+			}
+		})
+	}
+}
+
+func TestNewSourceInvalidConfig(t *testing.T) {
+	ca := newTestCA(t, "example.org")
+	src := newMemorySource(ca.svid(t, "/node-resolver"), ca)
+
+	_, err := newSource(Config{TrustDomain: "not a trust domain"}, zap.NewNop().Sugar(), src, src)
+	assert.Error(t, err)
+
+	_, err = newSource(Config{AuthorizedIDs: []string{"gateway"}}, zap.NewNop().Sugar(), src, src)
+	assert.Error(t, err)
+
+	_, err = newSource(Config{BackendIDs: []string{"gateway"}}, zap.NewNop().Sugar(), src, src)
+	assert.Error(t, err)
+}
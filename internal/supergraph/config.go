@@ -0,0 +1,43 @@
+package supergraph
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 10 * time.Second
+
+// Config stores the settings used to build a schema from a running gateway
+type Config struct {
+	URL      string            `mapstructure:"url"`
+	Headers  []string          `mapstructure:"headers"`
+	Timeout  time.Duration     `mapstructure:"timeout"`
+	Prefixes map[string]string `mapstructure:"prefixes"`
+
+	// Transport is used for requests to the gateway, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// Enabled returns true when a gateway url has been configured
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.String("supergraph-url", "", "gateway url to build the schema from using introspection")
+	viperx.MustBindFlag(v, "supergraph.url", flags.Lookup("supergraph-url"))
+
+	flags.StringSlice("supergraph-header", nil, "headers to send with introspection requests in the form 'Name: value'")
+	viperx.MustBindFlag(v, "supergraph.headers", flags.Lookup("supergraph-header"))
+
+	flags.Duration("supergraph-timeout", defaultTimeout, "timeout for introspection requests to the gateway")
+	viperx.MustBindFlag(v, "supergraph.timeout", flags.Lookup("supergraph-timeout"))
+
+	flags.StringToString("supergraph-prefix", nil, "prefixes by convention in the form 'Type=prefix', taking precedence over the gateway sdl; may be repeated")
+	viperx.MustBindFlag(v, "supergraph.prefixes", flags.Lookup("supergraph-prefix"))
+}
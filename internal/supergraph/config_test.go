@@ -0,0 +1,50 @@
+package supergraph_test
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/node-resolver/internal/supergraph"
+)
+
+func TestMustViperFlags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected map[string]string
+	}{
+		{name: "no prefixes", expected: map[string]string{}},
+		{
+			name:     "prefixes",
+			args:     []string{"--supergraph-prefix", "LoadBalancer=loadbal", "--supergraph-prefix", "Server=testsrv"},
+			expected: map[string]string{"LoadBalancer": "loadbal", "Server": "testsrv"},
+		},
+		{
+			name:     "comma separated prefixes",
+			args:     []string{"--supergraph-prefix", "LoadBalancer=loadbal,Server=testsrv"},
+			expected: map[string]string{"LoadBalancer": "loadbal", "Server": "testsrv"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+
+			supergraph.MustViperFlags(v, flags)
+			require.NoError(t, flags.Parse(append([]string{"--supergraph-url", "http://gateway"}, tt.args...)))
+
+			var cfg struct {
+				Supergraph supergraph.Config `mapstructure:"supergraph"`
+			}
+
+			require.NoError(t, v.Unmarshal(&cfg))
+			assert.Equal(t, "http://gateway", cfg.Supergraph.URL)
+			assert.Equal(t, tt.expected, cfg.Supergraph.Prefixes)
+		})
+	}
+}
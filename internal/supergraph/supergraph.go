@@ -0,0 +1,252 @@
+// Package supergraph builds a node-resolver schema from the introspection
+// result of a running supergraph or gateway
+package supergraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"go.uber.org/zap"
+)
+
+const (
+	introspectionQuery = `query { __schema { types { kind name interfaces { name } } } }`
+	serviceQuery       = `query { _service { sdl } }`
+)
+
+var (
+	// ErrNoTypes is returned when the gateway doesn't serve any object types implementing interfaces
+	ErrNoTypes = errors.New("gateway introspection returned no object types with interfaces")
+
+	// ErrInvalidHeader is returned when a configured header isn't in the form 'Name: value'
+	ErrInvalidHeader = errors.New("invalid header; expected 'Name: value'")
+)
+
+type graphError struct {
+	Message string `json:"message"`
+}
+
+type introspectionType struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Interfaces []struct {
+		Name string `json:"name"`
+	} `json:"interfaces"`
+}
+
+type introspectionResponse struct {
+	Data struct {
+		Schema struct {
+			Types []introspectionType `json:"types"`
+		} `json:"__schema"`
+	} `json:"data"`
+	Errors []graphError `json:"errors"`
+}
+
+type serviceResponse struct {
+	Data struct {
+		Service struct {
+			SDL string `json:"sdl"`
+		} `json:"_service"`
+	} `json:"data"`
+	Errors []graphError `json:"errors"`
+}
+
+// Client fetches the types served by a gateway and converts them to a schema
+// that can be passed to graphapi.NewResolver
+type Client struct {
+	cfg    Config
+	logger *zap.SugaredLogger
+	http   *http.Client
+}
+
+// NewClient returns a client for the gateway described by the given config
+func NewClient(cfg Config, logger *zap.SugaredLogger) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &Client{
+		cfg:    cfg,
+		logger: logger,
+		http:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+	}
+}
+
+// Schema introspects the gateway and returns an SDL document containing every
+// object type that implements an interface. Prefixes are taken from the
+// @prefixedID directives found in the gateway's _service sdl when it's
+// available, with the configured prefix convention map taking precedence.
+func (c *Client) Schema(ctx context.Context) (string, error) {
+	var ir introspectionResponse
+	if err := c.query(ctx, introspectionQuery, &ir); err != nil {
+		return "", fmt.Errorf("introspecting gateway: %w", err)
+	}
+
+	if len(ir.Errors) != 0 {
+		return "", fmt.Errorf("introspecting gateway: %s", ir.Errors[0].Message)
+	}
+
+	prefixes := c.directivePrefixes(ctx)
+	for name, prefix := range c.cfg.Prefixes {
+		prefixes[name] = prefix
+	}
+
+	return buildSDL(ir.Data.Schema.Types, prefixes)
+}
+
+// directivePrefixes attempts to read the prefixes from the @prefixedID
+// directives in the sdl the gateway serves. Gateways that don't expose the
+// _service field are expected, so failures are only logged.
+func (c *Client) directivePrefixes(ctx context.Context) map[string]string {
+	prefixes := map[string]string{}
+
+	var sr serviceResponse
+	if err := c.query(ctx, serviceQuery, &sr); err != nil || len(sr.Errors) != 0 || sr.Data.Service.SDL == "" {
+		c.logger.Debugw("gateway sdl unavailable, relying on configured prefixes", "error", err)
+
+		return prefixes
+	}
+
+	doc, err := parser.ParseSchemas(&ast.Source{Input: sr.Data.Service.SDL})
+	if err != nil {
+		c.logger.Warnw("failed to parse gateway sdl, relying on configured prefixes", "error", err)
+
+		return prefixes
+	}
+
+	for _, def := range append(doc.Definitions, doc.Extensions...) {
+		pd := def.Directives.ForName("prefixedID")
+		if pd == nil {
+			continue
+		}
+
+		if pa := pd.Arguments.ForName("prefix"); pa != nil {
+			prefixes[def.Name] = strings.Trim(pa.Value.String(), `"`)
+		} else if pa := pd.Arguments.ForName("prefixPattern"); pa != nil {
+			prefixes[def.Name] = strings.Trim(pa.Value.String(), `"`)
+		}
+	}
+
+	return prefixes
+}
+
+func (c *Client) query(ctx context.Context, query string, out interface{}) error {
+	body, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for _, h := range c.cfg.Headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrInvalidHeader, name)
+		}
+
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from gateway: %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// buildSDL converts the introspected types into the minimal SDL the resolver
+// needs: the interfaces and the object types implementing them with their ids.
+func buildSDL(types []introspectionType, prefixes map[string]string) (string, error) {
+	objects := []introspectionType{}
+	interfaces := map[string]bool{}
+
+	for _, t := range types {
+		if t.Kind != string(ast.Object) || len(t.Interfaces) == 0 || strings.HasPrefix(t.Name, "__") {
+			continue
+		}
+
+		objects = append(objects, t)
+
+		for _, i := range t.Interfaces {
+			interfaces[i.Name] = true
+		}
+	}
+
+	if len(objects) == 0 {
+		return "", ErrNoTypes
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+
+	ifaceNames := make([]string, 0, len(interfaces))
+	for name := range interfaces {
+		ifaceNames = append(ifaceNames, name)
+	}
+
+	sort.Strings(ifaceNames)
+
+	var sb strings.Builder
+
+	patterns := false
+
+	for _, prefix := range prefixes {
+		if isPrefixPattern(prefix) {
+			patterns = true
+		}
+	}
+
+	if patterns {
+		sb.WriteString("directive @prefixedID(prefix: String, prefixPattern: String) on OBJECT\n")
+	} else {
+		sb.WriteString("directive @prefixedID(prefix: String!) on OBJECT\n")
+	}
+
+	for _, obj := range objects {
+		names := make([]string, len(obj.Interfaces))
+		for i, iface := range obj.Interfaces {
+			names[i] = iface.Name
+		}
+
+		fmt.Fprintf(&sb, "type %s implements %s", obj.Name, strings.Join(names, " & "))
+
+		if prefix, ok := prefixes[obj.Name]; ok && isPrefixPattern(prefix) {
+			fmt.Fprintf(&sb, " @prefixedID(prefixPattern: %q)", prefix)
+		} else if ok {
+			fmt.Fprintf(&sb, " @prefixedID(prefix: %q)", prefix)
+		}
+
+		sb.WriteString(" {\n  id: ID!\n}\n")
+	}
+
+	for _, name := range ifaceNames {
+		fmt.Fprintf(&sb, "interface %s {\n  id: ID!\n}\n", name)
+	}
+
+	return sb.String(), nil
+}
+
+// isPrefixPattern reports whether prefix is a prefix pattern such as
+// "load...", declared with prefixPattern rather than prefix
+func isPrefixPattern(prefix string) bool {
+	return strings.Contains(prefix, ".")
+}
@@ -0,0 +1,117 @@
+package supergraph_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+	"go.infratographer.com/node-resolver/internal/supergraph"
+)
+
+const testIntrospection = `{"data":{"__schema":{"types":[
+	{"kind":"OBJECT","name":"Query","interfaces":[]},
+	{"kind":"OBJECT","name":"Server","interfaces":[{"name":"Node"}]},
+	{"kind":"OBJECT","name":"User","interfaces":[{"name":"Node"},{"name":"Actor"}]},
+	{"kind":"OBJECT","name":"__Type","interfaces":[]},
+	{"kind":"INTERFACE","name":"Node","interfaces":[]},
+	{"kind":"INTERFACE","name":"Actor","interfaces":[]}
+]}}}`
+
+const testServiceSDL = `directive @prefixedID(prefix: String!) on OBJECT
+type Server implements Node @prefixedID(prefix: "testsrv") { id: ID! }
+interface Node { id: ID! }`
+
+func newTestGateway(t *testing.T, serveSDL bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var body struct {
+			Query string `json:"query"`
+		}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch {
+		case strings.Contains(body.Query, "__schema"):
+			_, _ = w.Write([]byte(testIntrospection))
+		case serveSDL:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"_service": map[string]string{"sdl": testServiceSDL}},
+			})
+		default:
+			_, _ = w.Write([]byte(`{"errors":[{"message":"Cannot query field \"_service\" on type \"Query\"."}]}`))
+		}
+	}))
+}
+
+func TestSchema(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		serveSDL bool
+		prefixes map[string]string
+		contains []string
+		excludes []string
+	}{
+		{
+			TestName: "prefixes from gateway sdl and convention",
+			serveSDL: true,
+			prefixes: map[string]string{"User": "testusr"},
+			contains: []string{
+				`type Server implements Node @prefixedID(prefix: "testsrv")`,
+				`type User implements Node & Actor @prefixedID(prefix: "testusr")`,
+				"interface Actor",
+			},
+			excludes: []string{"Query", "__Type"},
+		},
+		{
+			TestName: "convention overrides gateway sdl",
+			serveSDL: true,
+			prefixes: map[string]string{"Server": "othrsrv", "User": "testusr"},
+			contains: []string{`type Server implements Node @prefixedID(prefix: "othrsrv")`},
+		},
+		{
+			TestName: "gateway without _service",
+			prefixes: map[string]string{"User": "testusr"},
+			contains: []string{
+				"type Server implements Node {",
+				`type User implements Node & Actor @prefixedID(prefix: "testusr")`,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			srv := newTestGateway(t, tt.serveSDL)
+			defer srv.Close()
+
+			client := supergraph.NewClient(supergraph.Config{
+				URL:      srv.URL,
+				Headers:  []string{"Authorization: Bearer secret"},
+				Prefixes: tt.prefixes,
+			}, zap.NewNop().Sugar())
+
+			sdl, err := client.Schema(context.Background())
+			require.NoError(t, err)
+
+			for _, c := range tt.contains {
+				assert.Contains(t, sdl, c)
+			}
+
+			for _, e := range tt.excludes {
+				assert.NotContains(t, sdl, e)
+			}
+
+			r, err := graphapi.NewResolver(zap.NewNop().Sugar(), sdl)
+			require.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
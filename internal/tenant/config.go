@@ -0,0 +1,69 @@
+package tenant
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var (
+	defaultTimeout  = 5 * time.Second
+	defaultMaxDepth = 10
+)
+
+// Config stores the settings for tenant scoped resolution
+type Config struct {
+	Enabled      bool              `mapstructure:"enabled"`
+	URL          string            `mapstructure:"url"`
+	GatewayURL   string            `mapstructure:"gateway-url"`
+	Token        string            `mapstructure:"token"`
+	Claim        string            `mapstructure:"claim"`
+	Prefix       string            `mapstructure:"prefix"`
+	OwnerQuery   string            `mapstructure:"owner-query"`
+	OwnerQueries map[string]string `mapstructure:"owner-queries"`
+	// OwnerPath is the dotted path of the owned object in the response of
+	// owner queries, such as loadBalancer, used when a query returns more
+	// than one object with an owner. OwnerPaths overrides it by prefix.
+	OwnerPath  string            `mapstructure:"owner-path"`
+	OwnerPaths map[string]string `mapstructure:"owner-paths"`
+	// DeletedField is the field of the node returned by owner queries that's
+	// set once it's deleted or archived, such as deletedAt. Callers allowed
+	// to resolve a deleted node get authz.DeletedError.
+	DeletedField string        `mapstructure:"deleted-field"`
+	MaxDepth     int           `mapstructure:"max-depth"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+
+	// Transport is used for requests to tenant-api and the gateway, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Bool("tenant-scoped", false, "only resolve ids owned by the caller's tenant or its descendants")
+	viperx.MustBindFlag(v, "tenant.enabled", flags.Lookup("tenant-scoped"))
+
+	flags.String("tenant-api-url", "", "graphql url of tenant-api")
+	viperx.MustBindFlag(v, "tenant.url", flags.Lookup("tenant-api-url"))
+
+	flags.String("tenant-gateway-url", "", "graphql url used to look up the owner of a node")
+	viperx.MustBindFlag(v, "tenant.gateway-url", flags.Lookup("tenant-gateway-url"))
+
+	flags.String("tenant-claim", "tenant_id", "jwt claim containing the caller's tenant id")
+	viperx.MustBindFlag(v, "tenant.claim", flags.Lookup("tenant-claim"))
+
+	v.MustBindEnv("tenant.token")
+	v.MustBindEnv("tenant.prefix")
+	v.MustBindEnv("tenant.owner-query")
+	v.MustBindEnv("tenant.owner-queries")
+	v.MustBindEnv("tenant.owner-path")
+	v.MustBindEnv("tenant.deleted-field")
+	v.MustBindEnv("tenant.max-depth")
+	v.MustBindEnv("tenant.timeout")
+
+	v.SetDefault("tenant.prefix", "tnntten")
+	v.SetDefault("tenant.max-depth", defaultMaxDepth)
+	v.SetDefault("tenant.timeout", defaultTimeout)
+}
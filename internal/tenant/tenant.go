@@ -0,0 +1,341 @@
+// Package tenant restricts resolution to nodes owned by the caller's tenant,
+// using infratographer tenant-api to walk the tenant hierarchy
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+)
+
+const tenantParentQuery = `query TenantParent($id: ID!) { tenant(id: $id) { id parent { id } } }`
+
+// ErrMissingConfig is returned when tenant scoping is enabled without a tenant-api url
+var ErrMissingConfig = errors.New("missing tenant config options; you must pass a tenant-api url")
+
+type tenantCtxKey struct{}
+
+// WithTenant returns a context with the caller's tenant id
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, id)
+}
+
+// FromContext returns the caller's tenant id or an empty string when the
+// caller has no tenant
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantCtxKey{}).(string); ok {
+		return id
+	}
+
+	return ""
+}
+
+// Middleware copies the tenant claim of the validated jwt into the request
+// context. It must run after the jwt middleware.
+func Middleware(claim string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, ok := c.Get("user").(*jwt.Token)
+			if !ok {
+				return next(c)
+			}
+
+			claims, ok := token.Claims.(jwt.MapClaims)
+			if !ok {
+				return next(c)
+			}
+
+			if id, ok := claims[claim].(string); ok && id != "" {
+				c.SetRequest(c.Request().WithContext(WithTenant(c.Request().Context(), id)))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+type graphResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type tenantParentResponse struct {
+	Tenant *struct {
+		ID     string `json:"id"`
+		Parent *struct {
+			ID string `json:"id"`
+		} `json:"parent"`
+	} `json:"tenant"`
+}
+
+// Checker is an authz.Authorizer that allows resolving an id only when it's
+// owned by the caller's tenant or one of its descendants
+type Checker struct {
+	cfg    Config
+	logger *zap.SugaredLogger
+	http   *http.Client
+}
+
+var _ authz.Authorizer = (*Checker)(nil)
+
+// NewChecker returns a Checker using the tenant-api described by cfg
+func NewChecker(cfg Config, logger *zap.SugaredLogger) (*Checker, error) {
+	if cfg.URL == "" {
+		return nil, ErrMissingConfig
+	}
+
+	if cfg.GatewayURL == "" {
+		cfg.GatewayURL = cfg.URL
+	}
+
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = defaultMaxDepth
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &Checker{
+		cfg:    cfg,
+		logger: logger,
+		http:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+	}, nil
+}
+
+// CanResolve looks up the tenant owning id and walks its ancestors looking
+// for the caller's tenant. Callers without a tenant are never allowed.
+func (c *Checker) CanResolve(ctx context.Context, _ string, id gidx.PrefixedID) error {
+	callerTenant := FromContext(ctx)
+	if callerTenant == "" {
+		return authz.ErrUnauthorized
+	}
+
+	owner, deleted, err := c.owner(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for depth := 0; owner != "" && depth <= c.cfg.MaxDepth; depth++ {
+		if owner == callerTenant {
+			if deleted != nil {
+				return deleted
+			}
+
+			return nil
+		}
+
+		owner, err = c.parent(ctx, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.logger.Debugw("id not owned by caller's tenant", "id", id, "tenant", callerTenant)
+
+	return authz.ErrUnauthorized
+}
+
+// owner returns the tenant owning id, and whether it was deleted. Tenants
+// are their own owners, other nodes are looked up with the owner query
+// configured for their prefix.
+func (c *Checker) owner(ctx context.Context, id gidx.PrefixedID) (string, *authz.DeletedError, error) {
+	if id.Prefix() == c.cfg.Prefix {
+		return id.String(), nil, nil
+	}
+
+	query, ok := c.cfg.OwnerQueries[id.Prefix()]
+	if !ok {
+		query = c.cfg.OwnerQuery
+	}
+
+	if query == "" {
+		c.logger.Debugw("no owner query configured for prefix", "prefix", id.Prefix())
+
+		return "", nil, authz.ErrUnauthorized
+	}
+
+	var data interface{}
+	if err := c.query(ctx, c.cfg.GatewayURL, query, id.String(), &data); err != nil {
+		return "", nil, fmt.Errorf("looking up owner: %w", err)
+	}
+
+	path, ok := c.cfg.OwnerPaths[id.Prefix()]
+	if !ok {
+		path = c.cfg.OwnerPath
+	}
+
+	var node map[string]interface{}
+	if path != "" {
+		node = ownedAt(data, path)
+	} else {
+		node = findOwned(data)
+	}
+
+	if node == nil {
+		return "", nil, nil
+	}
+
+	owner, _ := node["owner"].(map[string]interface{})
+	ownerID, _ := owner["id"].(string)
+
+	return ownerID, c.deleted(node), nil
+}
+
+// deleted returns the deletion of node when its deleted field is set
+func (c *Checker) deleted(node map[string]interface{}) *authz.DeletedError {
+	if c.cfg.DeletedField == "" {
+		return nil
+	}
+
+	switch v := node[c.cfg.DeletedField].(type) {
+	case nil:
+		return nil
+	case bool:
+		if !v {
+			return nil
+		}
+	case string:
+		if deletedAt, err := time.Parse(time.RFC3339, v); err == nil {
+			return &authz.DeletedError{DeletedAt: deletedAt}
+		}
+	}
+
+	return &authz.DeletedError{}
+}
+
+// parent returns the parent of the given tenant, or an empty string for root tenants
+func (c *Checker) parent(ctx context.Context, id string) (string, error) {
+	var resp tenantParentResponse
+	if err := c.query(ctx, c.cfg.URL, tenantParentQuery, id, &resp); err != nil {
+		return "", fmt.Errorf("looking up tenant parent: %w", err)
+	}
+
+	if resp.Tenant == nil || resp.Tenant.Parent == nil {
+		return "", nil
+	}
+
+	return resp.Tenant.Parent.ID, nil
+}
+
+func (c *Checker) query(ctx context.Context, url, query, id string, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": map[string]string{"id": id},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	var gr graphResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gr); err != nil {
+		return err
+	}
+
+	if len(gr.Errors) != 0 {
+		return fmt.Errorf("%s", gr.Errors[0].Message)
+	}
+
+	return json.Unmarshal(gr.Data, out)
+}
+
+// hasOwner returns true when o has an owner id
+func hasOwner(o map[string]interface{}) bool {
+	owner, ok := o["owner"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	_, ok = owner["id"].(string)
+
+	return ok
+}
+
+// ownedAt returns the object with an owner id at the dotted path of the
+// response data, or nil when there's none
+func ownedAt(v interface{}, path string) map[string]interface{} {
+	for _, field := range strings.Split(path, ".") {
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		v = o[field]
+	}
+
+	if o, ok := v.(map[string]interface{}); ok && hasOwner(o) {
+		return o
+	}
+
+	return nil
+}
+
+// findOwned returns the first object with an owner id found in the response
+// data, so owner queries can be shaped however the gateway requires. Objects
+// are checked before their fields, and fields are visited in sorted order so
+// the same response always returns the same object.
+func findOwned(v interface{}) map[string]interface{} {
+	switch o := v.(type) {
+	case map[string]interface{}:
+		if hasOwner(o) {
+			return o
+		}
+
+		keys := make([]string, 0, len(o))
+		for k := range o {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if node := findOwned(o[k]); node != nil {
+				return node
+			}
+		}
+	case []interface{}:
+		for _, child := range o {
+			if node := findOwned(child); node != nil {
+				return node
+			}
+		}
+	}
+
+	return nil
+}
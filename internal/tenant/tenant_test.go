@@ -0,0 +1,274 @@
+package tenant_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/authz"
+	"go.infratographer.com/node-resolver/internal/tenant"
+)
+
+// testParents is the tenant hierarchy: root <- child <- grandchild
+var testParents = map[string]string{
+	"tnntten-child":      "tnntten-root",
+	"tnntten-grandchild": "tnntten-child",
+}
+
+var testOwners = map[string]string{
+	"loadbal-123": "tnntten-grandchild",
+	"loadbal-456": "tnntten-other",
+	"loadbal-789": "tnntten-grandchild",
+}
+
+// testDeleted is when load balancers were deleted
+var testDeleted = map[string]string{
+	"loadbal-789": "2024-05-01T12:00:00Z",
+}
+
+func newTestAPI(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		id := body.Variables["id"]
+
+		var data interface{}
+
+		switch {
+		case strings.Contains(body.Query, "tenant("):
+			tnt := map[string]interface{}{"id": id, "parent": nil}
+			if parent, ok := testParents[id]; ok {
+				tnt["parent"] = map[string]string{"id": parent}
+			}
+
+			data = map[string]interface{}{"tenant": tnt}
+		case strings.Contains(body.Query, "loadBalancer("):
+			lb := map[string]interface{}{"owner": map[string]string{"id": testOwners[id]}, "deletedAt": nil}
+			if deletedAt, ok := testDeleted[id]; ok {
+				lb["deletedAt"] = deletedAt
+			}
+
+			data = map[string]interface{}{"loadBalancer": lb}
+		default:
+			_, _ = w.Write([]byte(`{"errors":[{"message":"unexpected query"}]}`))
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+}
+
+func TestChecker(t *testing.T) {
+	srv := newTestAPI(t)
+	defer srv.Close()
+
+	checker, err := tenant.NewChecker(tenant.Config{
+		URL:    srv.URL,
+		Prefix: "tnntten",
+		OwnerQueries: map[string]string{
+			"loadbal": `query($id: ID!) { loadBalancer(id: $id) { owner { id } deletedAt } }`,
+		},
+		DeletedField: "deletedAt",
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		TestName string
+		tenant   string
+		id       gidx.PrefixedID
+		err      error
+	}{
+		{
+			TestName: "own tenant",
+			tenant:   "tnntten-child",
+			id:       "tnntten-child",
+		},
+		{
+			TestName: "descendant tenant",
+			tenant:   "tnntten-root",
+			id:       "tnntten-grandchild",
+		},
+		{
+			TestName: "ancestor tenant",
+			tenant:   "tnntten-grandchild",
+			id:       "tnntten-root",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName: "node owned by descendant",
+			tenant:   "tnntten-child",
+			id:       "loadbal-123",
+		},
+		{
+			TestName: "node owned by another tenant",
+			tenant:   "tnntten-root",
+			id:       "loadbal-456",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName: "deleted node owned by descendant",
+			tenant:   "tnntten-child",
+			id:       "loadbal-789",
+			err:      authz.ErrDeleted,
+		},
+		{
+			TestName: "deleted node owned by another tenant",
+			tenant:   "tnntten-other",
+			id:       "loadbal-789",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName: "no owner query for prefix",
+			tenant:   "tnntten-root",
+			id:       "testsrv-123",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName: "caller without tenant",
+			id:       "tnntten-root",
+			err:      authz.ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.tenant != "" {
+				ctx = tenant.WithTenant(ctx, tt.tenant)
+			}
+
+			err := checker.CanResolve(ctx, "idntusr-123", tt.id)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+
+	err = checker.CanResolve(tenant.WithTenant(context.Background(), "tnntten-root"), "idntusr-123", "loadbal-789")
+
+	var deleted *authz.DeletedError
+
+	require.ErrorAs(t, err, &deleted)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), deleted.DeletedAt)
+}
+
+func TestCheckerMultipleOwners(t *testing.T) {
+	// the owner query returns a load balancer owned by another tenant, its
+	// parent owned by the caller's descendant and a sibling owned by a third
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "tenant-api") {
+			_, _ = w.Write([]byte(`{"data":{"tenant":null}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data":{
+			"zone":{"owner":{"id":"tnntten-third"}},
+			"loadBalancer":{"owner":{"id":"tnntten-other"},"parent":{"owner":{"id":"tnntten-grandchild"}}},
+			"account":{"settings":{"owner":{"id":"tnntten-grandchild"}}}
+		}}`))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		TestName string
+		cfg      tenant.Config
+		tenant   string
+		err      error
+	}{
+		{
+			TestName: "first owned object in key order",
+			tenant:   "tnntten-grandchild",
+		},
+		{
+			TestName: "owner path",
+			cfg:      tenant.Config{OwnerPath: "loadBalancer"},
+			tenant:   "tnntten-other",
+		},
+		{
+			TestName: "owner path denies other owners",
+			cfg:      tenant.Config{OwnerPath: "loadBalancer"},
+			tenant:   "tnntten-grandchild",
+			err:      authz.ErrUnauthorized,
+		},
+		{
+			TestName: "nested owner path",
+			cfg:      tenant.Config{OwnerPath: "loadBalancer.parent"},
+			tenant:   "tnntten-grandchild",
+		},
+		{
+			TestName: "owner path by prefix",
+			cfg:      tenant.Config{OwnerPath: "loadBalancer", OwnerPaths: map[string]string{"loadbal": "zone"}},
+			tenant:   "tnntten-third",
+		},
+		{
+			TestName: "owner path without owner",
+			cfg:      tenant.Config{OwnerPath: "loadBalancer.missing"},
+			tenant:   "tnntten-other",
+			err:      authz.ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.TestName, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.URL = srv.URL + "/tenant-api"
+			cfg.GatewayURL = srv.URL
+			cfg.Prefix = "tnntten"
+			cfg.OwnerQuery = `query($id: ID!) { zone loadBalancer account }`
+
+			checker, err := tenant.NewChecker(cfg, zap.NewNop().Sugar())
+			require.NoError(t, err)
+
+			// the same owner is used every time, whatever the map order
+			for i := 0; i < 20; i++ {
+				err := checker.CanResolve(tenant.WithTenant(context.Background(), tt.tenant), "idntusr-123", "loadbal-123")
+				if tt.err != nil {
+					require.ErrorIs(t, err, tt.err)
+
+					continue
+				}
+
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/query", nil), httptest.NewRecorder())
+	c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"sub": "idntusr-123", "tenant_id": "tnntten-root"}})
+
+	var got string
+
+	err := tenant.Middleware("tenant_id")(func(c echo.Context) error {
+		got = tenant.FromContext(c.Request().Context())
+
+		return nil
+	})(c)
+	require.NoError(t, err)
+
+	assert.Equal(t, "tnntten-root", got)
+}
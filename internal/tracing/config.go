@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/otelx"
+)
+
+const (
+	defaultDatadogAgentHost = "localhost"
+	defaultDatadogOTLPPort  = "4318"
+	defaultZipkinEndpoint   = "http://localhost:9411/api/v2/spans"
+)
+
+// Config extends the otelx tracing config with the settings for exporters
+// node-resolver supports in addition to the otelx ones
+type Config struct {
+	otelx.Config `mapstructure:",squash"`
+
+	Datadog struct {
+		AgentHost string   `mapstructure:"agent_host"`
+		OTLPPort  string   `mapstructure:"otlp_port"`
+		Service   string   `mapstructure:"service"`
+		Version   string   `mapstructure:"version"`
+		Tags      []string `mapstructure:"tags"`
+	} `mapstructure:"datadog"`
+
+	Zipkin struct {
+		Endpoint       string `mapstructure:"endpoint"`
+		B3SingleHeader bool   `mapstructure:"b3_single_header"`
+	} `mapstructure:"zipkin"`
+}
+
+// MustViperFlags binds the settings for the additional exporters. The shared
+// tracing flags are registered by otelx.MustViperFlags.
+func MustViperFlags(v *viper.Viper, _ *pflag.FlagSet) {
+	v.MustBindEnv("tracing.datadog.agent_host", "NODERESOLVER_TRACING_DATADOG_AGENT_HOST", "DD_AGENT_HOST")
+	v.MustBindEnv("tracing.datadog.otlp_port")
+	v.MustBindEnv("tracing.datadog.service", "NODERESOLVER_TRACING_DATADOG_SERVICE", "DD_SERVICE")
+	v.MustBindEnv("tracing.datadog.version", "NODERESOLVER_TRACING_DATADOG_VERSION", "DD_VERSION")
+	v.MustBindEnv("tracing.datadog.tags")
+
+	v.MustBindEnv("tracing.zipkin.endpoint")
+	v.MustBindEnv("tracing.zipkin.b3_single_header")
+
+	v.SetDefault("tracing.datadog.agent_host", defaultDatadogAgentHost)
+	v.SetDefault("tracing.datadog.otlp_port", defaultDatadogOTLPPort)
+	v.SetDefault("tracing.zipkin.endpoint", defaultZipkinEndpoint)
+}
@@ -0,0 +1,127 @@
+// Package tracing initializes the trace exporter selected in the config. The
+// exporters provided by otelx are passed through to it, while the ones it
+// doesn't support are set up here.
+package tracing
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"go.infratographer.com/x/otelx"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap"
+)
+
+// ExporterDatadog sends traces to the OTLP intake of a Datadog agent, for
+// deployments running the agent instead of an OTel collector.
+//
+//	tracing.datadog.agent_host    DD_AGENT_HOST    host of the datadog agent (defaults to localhost)
+//	tracing.datadog.otlp_port                      port of the agent's OTLP HTTP intake (defaults to 4318)
+//	tracing.datadog.service       DD_SERVICE       service name reported to datadog (defaults to the app name)
+//	tracing.datadog.version       DD_VERSION       version reported to datadog
+//	tracing.datadog.tags                           additional tags in the form key:value
+const ExporterDatadog otelx.TraceExporter = "datadog"
+
+// ExporterZipkin sends traces to a Zipkin collector and accepts B3 headers on
+// inbound requests in addition to W3C trace context.
+//
+//	tracing.zipkin.endpoint                       url of the zipkin spans api (defaults to http://localhost:9411/api/v2/spans)
+//	tracing.zipkin.b3_single_header               inject the single b3 header instead of the multiple X-B3-* headers
+const ExporterZipkin otelx.TraceExporter = "zipkin"
+
+// InitTracer sets up the global tracer provider for the configured exporter
+func InitTracer(tc Config, appName string, logger *zap.SugaredLogger) error {
+	if !tc.Enabled {
+		return nil
+	}
+
+	switch tc.Provider {
+	case ExporterDatadog:
+		return initDatadog(tc, appName)
+	case ExporterZipkin:
+		return initZipkin(tc, appName)
+	default:
+		return otelx.InitTracer(tc.Config, appName, logger)
+	}
+}
+
+func initDatadog(tc Config, appName string) error {
+	exp, err := otlptrace.New(context.Background(), otlptracehttp.NewClient(
+		otlptracehttp.WithEndpoint(net.JoinHostPort(tc.Datadog.AgentHost, tc.Datadog.OTLPPort)),
+		otlptracehttp.WithInsecure(),
+	))
+	if err != nil {
+		return err
+	}
+
+	service := tc.Datadog.Service
+	if service == "" {
+		service = appName
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(service),
+		semconv.DeploymentEnvironmentKey.String(tc.Environment),
+		attribute.String("environment", tc.Environment),
+	}
+
+	if tc.Datadog.Version != "" {
+		attrs = append(attrs, semconv.ServiceVersionKey.String(tc.Datadog.Version))
+	}
+
+	for _, tag := range tc.Datadog.Tags {
+		if k, v, ok := strings.Cut(tag, ":"); ok {
+			attrs = append(attrs, attribute.String(k, v))
+		}
+	}
+
+	registerProvider(exp, attrs)
+
+	return nil
+}
+
+func initZipkin(tc Config, appName string) error {
+	exp, err := zipkin.New(tc.Zipkin.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(appName),
+		semconv.DeploymentEnvironmentKey.String(tc.Environment),
+		attribute.String("environment", tc.Environment),
+	}
+
+	encoding := b3.B3MultipleHeader
+	if tc.Zipkin.B3SingleHeader {
+		encoding = b3.B3SingleHeader
+	}
+
+	// b3 is extracted from either header format regardless of the encoding
+	registerProvider(exp, attrs, b3.New(b3.WithInjectEncoding(encoding)))
+
+	return nil
+}
+
+func registerProvider(exp sdktrace.SpanExporter, attrs []attribute.KeyValue, propagators ...propagation.TextMapPropagator) {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+		sdktrace.WithBatcher(exp),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		append([]propagation.TextMapPropagator{propagation.TraceContext{}, propagation.Baggage{}}, propagators...)...,
+	))
+}
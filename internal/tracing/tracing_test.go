@@ -0,0 +1,38 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/tracing"
+)
+
+func TestZipkinB3Propagation(t *testing.T) {
+	var tc tracing.Config
+
+	tc.Enabled = true
+	tc.Provider = tracing.ExporterZipkin
+	tc.Zipkin.Endpoint = "http://localhost:9411/api/v2/spans"
+
+	require.NoError(t, tracing.InitTracer(tc, "node-resolver", zap.NewNop().Sugar()))
+
+	headers := http.Header{}
+	headers.Set("X-B3-TraceId", "463ac35c9f6413ad48485a3953bb6124")
+	headers.Set("X-B3-SpanId", "a2fb4a1d1a96d312")
+	headers.Set("X-B3-Sampled", "1")
+
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(headers))
+	sc := trace.SpanContextFromContext(ctx)
+
+	assert.Equal(t, "463ac35c9f6413ad48485a3953bb6124", sc.TraceID().String())
+	assert.Equal(t, "a2fb4a1d1a96d312", sc.SpanID().String())
+	assert.True(t, sc.IsSampled())
+}
@@ -0,0 +1,240 @@
+// Package vault fetches secrets and tls certificates from HashiCorp Vault
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrMissingAddress is returned when vault is enabled without an address
+	ErrMissingAddress = errors.New("missing vault address")
+
+	// ErrMissingAuth is returned when neither a token or kubernetes role is configured
+	ErrMissingAuth = errors.New("missing vault auth; you must pass a token or kubernetes role")
+)
+
+type secretResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Auth   *authResponse          `json:"auth"`
+	Errors []string               `json:"errors"`
+}
+
+type authResponse struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+// Client is a minimal Vault api client that keeps its token renewed
+type Client struct {
+	cfg    Config
+	logger *zap.SugaredLogger
+	http   *http.Client
+
+	mu    sync.RWMutex
+	token string
+}
+
+// NewClient authenticates with Vault and returns a client
+func NewClient(ctx context.Context, cfg Config, logger *zap.SugaredLogger) (*Client, error) {
+	if cfg.Address == "" {
+		return nil, ErrMissingAddress
+	}
+
+	if cfg.Token == "" && cfg.Kubernetes.Role == "" {
+		return nil, ErrMissingAuth
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	c := &Client{
+		cfg:    cfg,
+		logger: logger,
+		http:   &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		token:  cfg.Token,
+	}
+
+	if cfg.Token == "" {
+		if _, err := c.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// login exchanges the kubernetes service account token for a vault token
+func (c *Client) login(ctx context.Context) (*authResponse, error) {
+	jwt, err := os.ReadFile(c.cfg.Kubernetes.TokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp secretResponse
+
+	err = c.do(ctx, http.MethodPost, "auth/"+c.cfg.Kubernetes.MountPath+"/login", map[string]string{
+		"role": c.cfg.Kubernetes.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("vault kubernetes login: %w", err)
+	}
+
+	if resp.Auth == nil {
+		return nil, ErrMissingAuth
+	}
+
+	c.setToken(resp.Auth.ClientToken)
+
+	return resp.Auth, nil
+}
+
+// RenewToken keeps the client token valid until ctx is canceled, renewing it
+// at half of its lease and logging back in when renewal isn't possible.
+// Static tokens that aren't renewable are used as they are until they
+// expire, since there's no role to log in with instead.
+func (c *Client) RenewToken(ctx context.Context) {
+	if c.cfg.Kubernetes.Role == "" {
+		if renewable, err := c.tokenRenewable(ctx); err == nil && !renewable {
+			c.logger.Warn("vault token isn't renewable, it won't be renewed")
+
+			return
+		}
+	}
+
+	for {
+		var resp secretResponse
+
+		auth := &authResponse{}
+
+		err := c.do(ctx, http.MethodPost, "auth/token/renew-self", struct{}{}, &resp)
+		if err == nil && resp.Auth != nil {
+			auth = resp.Auth
+		}
+
+		if (err != nil || !auth.Renewable) && c.cfg.Kubernetes.Role != "" {
+			auth, err = c.login(ctx)
+		}
+
+		wait := time.Duration(auth.LeaseDuration) * time.Second / 2 //nolint:gomnd
+
+		switch {
+		case err != nil:
+			c.logger.Errorw("failed to renew vault token", "error", err)
+
+			wait = time.Minute
+		case wait <= 0:
+			// tokens without a lease, e.g. root tokens, never need renewal
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// tokenRenewable reports whether the client token can be renewed
+func (c *Client) tokenRenewable(ctx context.Context) (bool, error) {
+	var resp secretResponse
+
+	if err := c.do(ctx, http.MethodGet, "auth/token/lookup-self", nil, &resp); err != nil {
+		return false, err
+	}
+
+	renewable, _ := resp.Data["renewable"].(bool)
+
+	return renewable, nil
+}
+
+// ReadSecret returns the data stored at path. Data from KV version 2 mounts is
+// unwrapped so both versions can be read the same way.
+func (c *Client) ReadSecret(ctx context.Context, path string) (map[string]interface{}, error) {
+	var resp secretResponse
+
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	if data, ok := resp.Data["data"].(map[string]interface{}); ok {
+		if _, ok := resp.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+
+	return resp.Data, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body interface{}, out *secretResponse) error {
+	u, err := url.JoinPath(c.cfg.Address, "v1", path)
+	if err != nil {
+		return err
+	}
+
+	var reqBody io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+
+	if token := c.getToken(); token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("vault %s %s: %s %s", method, path, resp.Status, strings.Join(out.Errors, ", "))
+	}
+
+	return nil
+}
+
+func (c *Client) getToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.token
+}
+
+func (c *Client) setToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+}
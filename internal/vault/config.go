@@ -0,0 +1,64 @@
+package vault
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+)
+
+var defaultTimeout = 10 * time.Second
+
+// Config stores the settings for connecting to Vault
+type Config struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Address string        `mapstructure:"address"`
+	Token   string        `mapstructure:"token"`
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	Kubernetes struct {
+		Role      string `mapstructure:"role"`
+		MountPath string `mapstructure:"mount-path"`
+		TokenPath string `mapstructure:"token-path"`
+	} `mapstructure:"kubernetes"`
+
+	TLS struct {
+		PKIPath    string        `mapstructure:"pki-path"`
+		CommonName string        `mapstructure:"common-name"`
+		AltNames   []string      `mapstructure:"alt-names"`
+		TTL        time.Duration `mapstructure:"ttl"`
+	} `mapstructure:"tls"`
+
+	// Transport is used for requests to Vault, defaulting to http.DefaultTransport
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// MustViperFlags returns the cobra flags and wires them up with viper to prevent code duplication
+func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Bool("vault", false, "resolve vault: secret references and tls certificates from Vault")
+	viperx.MustBindFlag(v, "vault.enabled", flags.Lookup("vault"))
+
+	flags.String("vault-address", "", "address of the Vault server")
+	viperx.MustBindFlag(v, "vault.address", flags.Lookup("vault-address"))
+
+	flags.String("vault-tls-pki-path", "", "Vault PKI issue path for the server certificate, e.g. pki/issue/node-resolver")
+	viperx.MustBindFlag(v, "vault.tls.pki-path", flags.Lookup("vault-tls-pki-path"))
+
+	flags.String("vault-tls-common-name", "", "common name of the server certificate issued by Vault")
+	viperx.MustBindFlag(v, "vault.tls.common-name", flags.Lookup("vault-tls-common-name"))
+
+	v.MustBindEnv("vault.token", "NODERESOLVER_VAULT_TOKEN", "VAULT_TOKEN")
+	v.MustBindEnv("vault.timeout")
+	v.MustBindEnv("vault.kubernetes.role")
+	v.MustBindEnv("vault.kubernetes.mount-path")
+	v.MustBindEnv("vault.kubernetes.token-path")
+	v.MustBindEnv("vault.tls.alt-names")
+	v.MustBindEnv("vault.tls.ttl")
+
+	v.SetDefault("vault.timeout", defaultTimeout)
+	v.SetDefault("vault.kubernetes.mount-path", "kubernetes")
+	v.SetDefault("vault.kubernetes.token-path", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+	v.SetDefault("vault.tls.ttl", 24*time.Hour)
+}
@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// ReferencePrefix marks a config value as a reference to a Vault secret in the
+// form vault:<path>#<field>, e.g. vault:secret/data/node-resolver#admin-token
+const ReferencePrefix = "vault:"
+
+var (
+	// ErrInvalidReference is returned when a secret reference is missing its field
+	ErrInvalidReference = errors.New("invalid vault reference; expected vault:<path>#<field>")
+
+	// ErrMissingField is returned when the referenced secret doesn't contain the field
+	ErrMissingField = errors.New("vault secret missing field")
+)
+
+// ResolveReferences replaces every config value in v that references a Vault
+// secret with the secret's value, so tokens and credentials can be configured
+// without storing them in files or the environment
+func (c *Client) ResolveReferences(ctx context.Context, v *viper.Viper) error {
+	for _, key := range v.AllKeys() {
+		switch val := v.Get(key).(type) {
+		case string:
+			if !strings.HasPrefix(val, ReferencePrefix) {
+				continue
+			}
+
+			secret, err := c.resolve(ctx, val)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+
+			v.Set(key, secret)
+		case []string:
+			list := make([]interface{}, len(val))
+			for i, s := range val {
+				list[i] = s
+			}
+
+			resolved, ok, err := c.resolveList(ctx, list)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+
+			if ok {
+				v.Set(key, cast.ToStringSlice(resolved))
+			}
+		case []interface{}:
+			resolved, ok, err := c.resolveList(ctx, val)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+
+			if ok {
+				v.Set(key, resolved)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveList returns a copy of vals with the references to Vault secrets
+// replaced by their values, and whether any were. Elements that aren't
+// strings, such as the maps of lists of tables, are left as they are.
+func (c *Client) resolveList(ctx context.Context, vals []interface{}) ([]interface{}, bool, error) {
+	resolved := make([]interface{}, len(vals))
+	found := false
+
+	for i, val := range vals {
+		resolved[i] = val
+
+		s, ok := val.(string)
+		if !ok || !strings.HasPrefix(s, ReferencePrefix) {
+			continue
+		}
+
+		secret, err := c.resolve(ctx, s)
+		if err != nil {
+			return nil, false, err
+		}
+
+		resolved[i] = secret
+		found = true
+	}
+
+	return resolved, found, nil
+}
+
+func (c *Client) resolve(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(strings.TrimPrefix(ref, ReferencePrefix), "#")
+	if !ok || path == "" || field == "" {
+		return "", ErrInvalidReference
+	}
+
+	data, err := c.ReadSecret(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	val, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrMissingField, field)
+	}
+
+	return fmt.Sprint(val), nil
+}
@@ -0,0 +1,136 @@
+package vault_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/vault"
+)
+
+func TestResolveReferences(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "testtoken", r.Header.Get("X-Vault-Token"))
+
+		switch r.URL.Path {
+		case "/v1/secret/data/node-resolver":
+			_, _ = w.Write([]byte(`{"data":{"data":{"token":"kv2-secret"},"metadata":{"version":1}}}`))
+		case "/v1/kv/node-resolver":
+			_, _ = w.Write([]byte(`{"data":{"header":"Authorization: Bearer kv1-secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := vault.NewClient(context.Background(), vault.Config{
+		Address: srv.URL,
+		Token:   "testtoken",
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	t.Run("resolves references", func(t *testing.T) {
+		v := viper.New()
+		v.Set("authz.openfga.token", "vault:secret/data/node-resolver#token")
+		v.Set("supergraph.headers", []string{"X-Static: true", "vault:kv/node-resolver#header"})
+		v.Set("supergraph.url", "http://gateway")
+
+		require.NoError(t, client.ResolveReferences(context.Background(), v))
+
+		assert.Equal(t, "kv2-secret", v.GetString("authz.openfga.token"))
+		assert.Equal(t, []string{"X-Static: true", "Authorization: Bearer kv1-secret"}, v.GetStringSlice("supergraph.headers"))
+		assert.Equal(t, "http://gateway", v.GetString("supergraph.url"))
+	})
+
+	t.Run("lists without references are left as they are", func(t *testing.T) {
+		schemas := []interface{}{map[string]interface{}{"name": "default", "path": "schema.graphql"}}
+		ports := []interface{}{7904, 7905}
+
+		v := viper.New()
+		v.Set("graphs.schemas", schemas)
+		v.Set("server.ports", ports)
+		v.Set("supergraph.headers", []string{"X-Static: true"})
+
+		require.NoError(t, client.ResolveReferences(context.Background(), v))
+
+		assert.Equal(t, schemas, v.Get("graphs.schemas"))
+		assert.Equal(t, ports, v.Get("server.ports"))
+		assert.Equal(t, []string{"X-Static: true"}, v.Get("supergraph.headers"))
+	})
+
+	t.Run("references in lists of other values", func(t *testing.T) {
+		v := viper.New()
+		v.Set("supergraph.headers", []interface{}{"vault:kv/node-resolver#header", 7904})
+
+		require.NoError(t, client.ResolveReferences(context.Background(), v))
+
+		assert.Equal(t, []interface{}{"Authorization: Bearer kv1-secret", 7904}, v.Get("supergraph.headers"))
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		v := viper.New()
+		v.Set("authz.openfga.token", "vault:secret/data/node-resolver#missing")
+
+		assert.ErrorIs(t, client.ResolveReferences(context.Background(), v), vault.ErrMissingField)
+	})
+
+	t.Run("invalid reference", func(t *testing.T) {
+		v := viper.New()
+		v.Set("authz.openfga.token", "vault:secret/data/node-resolver")
+
+		assert.ErrorIs(t, client.ResolveReferences(context.Background(), v), vault.ErrInvalidReference)
+	})
+
+	t.Run("missing secret", func(t *testing.T) {
+		v := viper.New()
+		v.Set("authz.openfga.token", "vault:secret/data/missing#token")
+
+		assert.Error(t, client.ResolveReferences(context.Background(), v))
+	})
+}
+
+func TestRenewTokenNotRenewable(t *testing.T) {
+	renewals := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data":{"renewable":false,"ttl":3600}}`))
+		default:
+			renewals++
+
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["lease is not renewable"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := vault.NewClient(context.Background(), vault.Config{
+		Address: srv.URL,
+		Token:   "testtoken",
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+
+	go func() {
+		client.RenewToken(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RenewToken kept renewing a token that isn't renewable")
+	}
+
+	assert.Zero(t, renewals)
+}
@@ -0,0 +1,120 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInvalidCertificate is returned when Vault doesn't return a usable certificate
+var ErrInvalidCertificate = errors.New("vault returned an invalid certificate")
+
+// minRenewWait avoids hammering vault when certificates are issued with very short ttls
+const minRenewWait = 10 * time.Second
+
+// Certificates issues the server certificate from a Vault PKI mount and
+// re-issues it before it expires
+type Certificates struct {
+	client *Client
+	cert   atomic.Pointer[tls.Certificate]
+}
+
+// NewCertificates issues the initial certificate from the configured PKI path
+func NewCertificates(ctx context.Context, client *Client) (*Certificates, error) {
+	c := &Certificates{client: client}
+
+	if err := c.issue(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Certificates) issue(ctx context.Context) error {
+	cfg := c.client.cfg.TLS
+
+	var resp secretResponse
+
+	err := c.client.do(ctx, http.MethodPost, cfg.PKIPath, map[string]string{
+		"common_name": cfg.CommonName,
+		"alt_names":   strings.Join(cfg.AltNames, ","),
+		"ttl":         cfg.TTL.String(),
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	certPEM, _ := resp.Data["certificate"].(string)
+	keyPEM, _ := resp.Data["private_key"].(string)
+
+	if chain, ok := resp.Data["ca_chain"].([]interface{}); ok {
+		for _, ca := range chain {
+			certPEM += "\n" + fmt.Sprint(ca)
+		}
+	}
+
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCertificate, err)
+	}
+
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCertificate, err)
+	}
+
+	c.cert.Store(&cert)
+
+	c.client.logger.Infow("issued tls certificate from vault", "common_name", cfg.CommonName)
+
+	return nil
+}
+
+// Renew re-issues the certificate once two thirds of its lifetime has passed
+// until ctx is canceled
+func (c *Certificates) Renew(ctx context.Context) {
+	for {
+		wait := minRenewWait
+
+		if leaf := c.leafNotAfter(); !leaf.IsZero() {
+			if w := time.Until(leaf) * 2 / 3; w > wait { //nolint:gomnd
+				wait = w
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if err := c.issue(ctx); err != nil {
+			c.client.logger.Errorw("failed to renew tls certificate from vault", "error", err)
+		}
+	}
+}
+
+func (c *Certificates) leafNotAfter() time.Time {
+	cert := c.cert.Load()
+	if cert == nil || cert.Leaf == nil {
+		return time.Time{}
+	}
+
+	return cert.Leaf.NotAfter
+}
+
+// ServerTLSConfig returns a tls config which always serves the latest certificate
+func (c *Certificates) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.cert.Load(), nil
+		},
+	}
+}
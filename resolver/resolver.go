@@ -0,0 +1,263 @@
+// Package resolver embeds node resolution in a go service, serving the same
+// graphql and resolve api routes as node-resolver in-process instead of
+// running it as a sidecar.
+//
+// A Resolver is built from a schema whose object types declare their id
+// prefix with the @prefixedID directive, like the schemas node-resolver
+// serves:
+//
+//	r, err := resolver.NewResolver(logger, schema)
+//	if err != nil {
+//		return err
+//	}
+//
+//	mux.Handle("/query", r.Handler())
+//
+// Types can also be added while serving with RegisterPrefix.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/parser"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/internal/graphapi"
+)
+
+// nodeInterface is the interface every registered type implements
+const nodeInterface = "Node"
+
+var (
+	// ErrUnknownPrefix is returned when resolving an id whose prefix isn't
+	// declared by any type
+	ErrUnknownPrefix = graphapi.ErrUnknownPrefix
+	// ErrDuplicatePrefix is returned when a prefix is declared by several
+	// types with WithStrictPrefixes, or registered for a second type
+	ErrDuplicatePrefix = graphapi.ErrDuplicatePrefix
+	// ErrInvalidPrefix is returned by RegisterPrefix for prefixes gidx ids
+	// can't have
+	ErrInvalidPrefix = graphapi.ErrInvalidPrefix
+	// ErrTypeExists is returned by RegisterPrefix for a type the schema
+	// already has under another prefix, or an interface that's another kind
+	// of type
+	ErrTypeExists = errors.New("type already exists")
+)
+
+// PrefixType is a prefix served by a Resolver and the type its ids resolve to
+type PrefixType = graphapi.PrefixType
+
+// Option configures optional behavior of a Resolver
+type Option = graphapi.Option
+
+// WithWildcardPrefixes allows prefixes ending in a wildcard, such as
+// "loadb*", which resolve every id whose prefix starts with "loadb" and has
+// no exact match
+func WithWildcardPrefixes() Option {
+	return graphapi.WithWildcardPrefixes()
+}
+
+// WithUnknownPrefixType resolves well-formed ids whose prefix no type
+// declares to a type called name implementing Node, such as UnknownNode,
+// rather than failing them with ErrUnknownPrefix
+func WithUnknownPrefixType(name string) Option {
+	return graphapi.WithUnknownPrefixType(name)
+}
+
+// WithStrictPrefixes rejects schemas in which several types declare the same
+// prefix, rather than resolving the prefix to the last of them
+func WithStrictPrefixes() Option {
+	return graphapi.WithStrictPrefixes()
+}
+
+// WithStrictSchema rejects schemas with types missing a prefix or declaring
+// one gidx ids can't have, rather than only logging them
+func WithStrictSchema() Option {
+	return graphapi.WithStrictSchema()
+}
+
+// WithMaxIDLength sets the maximum length of ids in bytes. Zero disables the
+// limit.
+func WithMaxIDLength(n int) Option {
+	return graphapi.WithMaxIDLength(n)
+}
+
+// WithMiddleware adds echo middleware to the routes served by Handler, such
+// as authentication
+func WithMiddleware(mw ...echo.MiddlewareFunc) Option {
+	return graphapi.WithMiddleware(mw...)
+}
+
+// Resolver resolves prefixed ids to the types declaring their prefix. It's
+// safe for concurrent use, and its schema can be replaced while it's serving.
+type Resolver struct {
+	handler *graphapi.Handler
+	routes  *echo.Echo
+
+	// mu serializes changes to the schema, so concurrent registrations
+	// aren't lost
+	mu sync.Mutex
+}
+
+// NewResolver returns a Resolver serving schema
+func NewResolver(logger *zap.SugaredLogger, schema string, opts ...Option) (*Resolver, error) {
+	r, err := graphapi.NewResolver(logger, schema, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	h := graphapi.NewHandler(r)
+
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	h.Routes(e.Group(""))
+
+	return &Resolver{handler: h, routes: e}, nil
+}
+
+// Handler returns the http handler serving graphql requests at /query and
+// the resolve api at /api/v1/resolve
+func (r *Resolver) Handler() http.Handler {
+	return r.routes
+}
+
+// Resolve returns the name of the type id resolves to, authorized and
+// audited like ids resolved over http
+func (r *Resolver) Resolve(ctx context.Context, id string) (string, error) {
+	node, err := r.handler.Resolver().ResolveID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	return node.GraphType.Name(), nil
+}
+
+// Prefixes returns the prefixes being served, sorted by prefix
+func (r *Resolver) Prefixes() []PrefixType {
+	return r.handler.Resolver().Prefixes()
+}
+
+// Reload replaces the schema being served. Invalid schemas are rejected and
+// the current schema keeps being served.
+func (r *Resolver) Reload(schema string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.handler.Reload(schema)
+}
+
+// RegisterPrefix adds typeName implementing Node, and the interfaces given,
+// to the schema being served, resolving ids with prefix to it. Interfaces
+// the schema doesn't have yet are added too. Registering the same prefix for
+// the same type again does nothing, while registering a type the schema
+// already has under another prefix fails with ErrTypeExists.
+func (r *Resolver) RegisterPrefix(prefix, typeName string, interfaces ...string) error {
+	if err := graphapi.ValidatePrefix(prefix); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.handler.Resolver()
+
+	for _, p := range current.Prefixes() {
+		if p.Prefix != prefix {
+			continue
+		}
+
+		if p.Type == typeName {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s is declared by %s and %s", ErrDuplicatePrefix, prefix, p.Type, typeName)
+	}
+
+	// the type is added to the parsed SDL rather than its text, so the
+	// definitions already there are found however they're formatted
+	doc, err := parser.ParseSchema(&ast.Source{Input: current.SDL()})
+	if err != nil {
+		return err
+	}
+
+	if def := definitionNamed(doc, typeName); def != nil {
+		return fmt.Errorf("%w: %s", ErrTypeExists, typeName)
+	}
+
+	ifaces := []string{nodeInterface}
+	seen := map[string]bool{nodeInterface: true}
+
+	for _, iface := range interfaces {
+		if seen[iface] {
+			continue
+		}
+
+		seen[iface] = true
+		ifaces = append(ifaces, iface)
+
+		switch def := definitionNamed(doc, iface); {
+		case def == nil:
+			doc.Definitions = append(doc.Definitions, keyedDefinition(ast.Interface, iface))
+		case def.Kind != ast.Interface:
+			return fmt.Errorf("%w: %s isn't an interface", ErrTypeExists, iface)
+		}
+	}
+
+	obj := keyedDefinition(ast.Object, typeName)
+	obj.Interfaces = ifaces
+	obj.Directives = append(obj.Directives, &ast.Directive{
+		Name:      "prefixedID",
+		Arguments: ast.ArgumentList{stringArgument("prefix", prefix)},
+	})
+
+	doc.Definitions = append(doc.Definitions, obj)
+
+	// the formatter can't write the federation link, a schema extension
+	// without operation types, and the SDL of the new schema adds it again
+	doc.SchemaExtension = nil
+
+	var sb strings.Builder
+
+	formatter.NewFormatter(&sb).FormatSchemaDocument(doc)
+
+	return r.handler.Reload(sb.String())
+}
+
+// definitionNamed returns the definition of the type called name in doc, or
+// nil when there's none
+func definitionNamed(doc *ast.SchemaDocument, name string) *ast.Definition {
+	for _, def := range doc.Definitions {
+		if def.Name == name {
+			return def
+		}
+	}
+
+	return nil
+}
+
+// keyedDefinition returns a definition of kind called name with only an id
+// field, keyed by it for federation
+func keyedDefinition(kind ast.DefinitionKind, name string) *ast.Definition {
+	return &ast.Definition{
+		Kind: kind,
+		Name: name,
+		Directives: ast.DirectiveList{{
+			Name:      "key",
+			Arguments: ast.ArgumentList{stringArgument("fields", "id")},
+		}},
+		Fields: ast.FieldList{{Name: "id", Type: ast.NonNullNamedType("ID", nil)}},
+	}
+}
+
+func stringArgument(name, value string) *ast.Argument {
+	return &ast.Argument{Name: name, Value: &ast.Value{Kind: ast.StringValue, Raw: value}}
+}
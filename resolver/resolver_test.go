@@ -0,0 +1,144 @@
+package resolver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/node-resolver/resolver"
+)
+
+const testSchema = `directive @prefixedID(prefix: String!) on OBJECT
+
+type User implements Node & Actor @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}
+interface Actor @key(fields: "id") {
+	id: ID!
+}
+interface Node @key(fields: "id") {
+	id: ID!
+}
+type Query {
+	node(id: ID!): Node!
+}`
+
+func TestResolver(t *testing.T) {
+	r, err := resolver.NewResolver(zap.NewNop().Sugar(), testSchema)
+	require.NoError(t, err)
+
+	typeName, err := r.Resolve(context.Background(), "testusr-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "User", typeName)
+
+	_, err = r.Resolve(context.Background(), "testsrv-abc")
+	assert.ErrorIs(t, err, resolver.ErrUnknownPrefix)
+
+	require.NoError(t, r.RegisterPrefix("testsrv", "Server"))
+	require.NoError(t, r.RegisterPrefix("testtkn", "Token", "Actor", "Credential"))
+
+	// registering the same type again is fine, another type isn't
+	require.NoError(t, r.RegisterPrefix("testsrv", "Server"))
+	assert.ErrorIs(t, r.RegisterPrefix("testsrv", "Host"), resolver.ErrDuplicatePrefix)
+	assert.ErrorIs(t, r.RegisterPrefix("host", "Host"), resolver.ErrInvalidPrefix)
+
+	assert.Equal(t, []resolver.PrefixType{
+		{Prefix: "testsrv", Type: "Server"},
+		{Prefix: "testtkn", Type: "Token"},
+		{Prefix: "testusr", Type: "User"},
+	}, r.Prefixes())
+
+	typeName, err = r.Resolve(context.Background(), "testsrv-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "Server", typeName)
+
+	// registered types are served over http too
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query":"{ node(id: \"testtkn-abc\") { __typename ... on Actor { id } } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	r.Handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Data map[string]map[string]string `json:"data"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]string{"__typename": "Token", "id": "testtkn-abc"}, resp.Data["node"])
+}
+
+func TestResolverInvalidSchema(t *testing.T) {
+	_, err := resolver.NewResolver(zap.NewNop().Sugar(), testSchema+`
+type Account implements Node @key(fields: "id") @prefixedID(prefix: "testusr") {
+	id: ID!
+}`, resolver.WithStrictPrefixes())
+	assert.ErrorIs(t, err, resolver.ErrDuplicatePrefix)
+}
+
+// serviceSDL returns the subgraph sdl served by r
+func serviceSDL(t *testing.T, r *resolver.Resolver) string {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query":"{ _service { sdl } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	r.Handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Data struct {
+			Service struct {
+				SDL string `json:"sdl"`
+			} `json:"_service"`
+		} `json:"data"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	return resp.Data.Service.SDL
+}
+
+func TestRegisterPrefixInterfaces(t *testing.T) {
+	// interfaces are found however they're declared
+	r, err := resolver.NewResolver(zap.NewNop().Sugar(), testSchema+`
+interface Credential{
+	id: ID!
+}
+interface Owned @key(fields: "id") @deprecated {
+	id: ID!
+}`)
+	require.NoError(t, err)
+
+	require.NoError(t, r.RegisterPrefix("testtkn", "Token", "Node", "Actor", "Credential", "Owned", "Actor", "Secret", "Secret"))
+
+	sdl := serviceSDL(t, r)
+	assert.Contains(t, sdl, `type Token implements Node & Actor & Credential & Owned & Secret @key(fields: "id") @prefixedID(prefix: "testtkn") {`)
+
+	for _, iface := range []string{"Node", "Actor", "Credential", "Owned", "Secret"} {
+		assert.Equal(t, 1, strings.Count(sdl, "interface "+iface+" "), iface)
+	}
+
+	typeName, err := r.Resolve(context.Background(), "testtkn-abc")
+	require.NoError(t, err)
+	assert.Equal(t, "Token", typeName)
+}
+
+func TestRegisterPrefixExistingType(t *testing.T) {
+	r, err := resolver.NewResolver(zap.NewNop().Sugar(), testSchema)
+	require.NoError(t, err)
+
+	// a type can't be registered again under another prefix, and only
+	// interfaces can be implemented
+	assert.ErrorIs(t, r.RegisterPrefix("testacc", "User"), resolver.ErrTypeExists)
+	assert.ErrorIs(t, r.RegisterPrefix("testacc", "Actor"), resolver.ErrTypeExists)
+	assert.ErrorIs(t, r.RegisterPrefix("testacc", "Account", "User"), resolver.ErrTypeExists)
+
+	assert.Equal(t, []resolver.PrefixType{{Prefix: "testusr", Type: "User"}}, r.Prefixes())
+}